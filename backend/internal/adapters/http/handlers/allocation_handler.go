@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +21,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
 	"github.com/phuc/cmms-backend/internal/config"
+	"github.com/phuc/cmms-backend/internal/core/errors"
 	"github.com/phuc/cmms-backend/internal/core/services"
 	"github.com/phuc/cmms-backend/internal/domain"
 	"github.com/phuc/cmms-backend/internal/infrastructure/messaging"
@@ -38,8 +41,12 @@ type AssignHandler struct {
 	workRepo         domain.WorkRepository
 	subWorkRepo      domain.SubWorkRepository
 	templateRepo     domain.TemplateRepository
+	forms            domain.FormSchemaRepository
+	deps             domain.ConfigDependencyRepository
+	availability     *services.AvailabilityService
 	hub              *websocket.Hub
 	larkSvc          *services.LarkService
+	shareSvc         *services.ShareLinkService
 	// Extracted services (Phase 1 refactor)
 	mediaSvc    *services.AllocationMediaService
 	workflowSvc *services.AllocationWorkflowService
@@ -59,6 +66,12 @@ func NewAssignHandler(
 	templateRepo domain.TemplateRepository,
 	hub *websocket.Hub,
 	larkSvc *services.LarkService,
+	shareSvc *services.ShareLinkService,
+	chains domain.ApprovalChainRepository,
+	events domain.DetailAssignEventRepository,
+	forms domain.FormSchemaRepository,
+	deps domain.ConfigDependencyRepository,
+	availability *services.AvailabilityService,
 	cfg config.Config,
 ) *AssignHandler {
 	mediaSvc := services.NewAllocationMediaService(detailAssignRepo)
@@ -66,7 +79,7 @@ func NewAssignHandler(
 	if hub != nil {
 		bFn = hub.BroadcastAll
 	}
-	workflowSvc := services.NewAllocationWorkflowService(db, detailAssignRepo, larkSvc, bFn, cfg)
+	workflowSvc := services.NewAllocationWorkflowService(db, detailAssignRepo, larkSvc, bFn, cfg, shareSvc, chains, events, forms, deps, assignRepo)
 
 	// Best-effort: connect publisher (nil-safe if RABBITMQ_URL not set)
 	mqPub, mqErr := messaging.NewPublisher()
@@ -83,8 +96,12 @@ func NewAssignHandler(
 		workRepo:         workRepo,
 		subWorkRepo:      subWorkRepo,
 		templateRepo:     templateRepo,
+		forms:            forms,
+		deps:             deps,
+		availability:     availability,
 		hub:              hub,
 		larkSvc:          larkSvc,
+		shareSvc:         shareSvc,
 		mediaSvc:         mediaSvc,
 		workflowSvc:      workflowSvc,
 		mqPublisher:      mqPub,
@@ -157,7 +174,7 @@ func (h *AssignHandler) ListAssigns(c *gin.Context) {
 
 	if userID != "" {
 		// Secure per-user filter using JSONB contains
-		assigns, err := h.assignRepo.FindByUserID(userID)
+		assigns, err := h.assignRepo.FindByUserID(userID, visibilityScope(c))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assigns"})
 			return
@@ -166,7 +183,7 @@ func (h *AssignHandler) ListAssigns(c *gin.Context) {
 		return
 	}
 
-	assigns, err := h.assignRepo.FindAll()
+	assigns, err := h.assignRepo.FindAll(visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assigns"})
 		return
@@ -182,7 +199,7 @@ func (h *AssignHandler) GetAssign(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assign ID"})
 		return
 	}
-	assign, err := h.assignRepo.FindByID(id)
+	assign, err := h.assignRepo.FindByID(id, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Assign not found"})
 		return
@@ -197,7 +214,7 @@ func (h *AssignHandler) GetAssignWithTasks(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assign ID"})
 		return
 	}
-	assign, err := h.assignRepo.FindByID(id)
+	assign, err := h.assignRepo.FindByID(id, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Assign not found"})
 		return
@@ -242,7 +259,28 @@ func (h *AssignHandler) GetAssignWithTasks(c *gin.Context) {
 		}
 	}
 	
-	// Inject assigned_user_names and has_guide into each DetailAssign JSON
+	// Tasks not yet pinned to a checklist version get their config's current form
+	var latestForms []domain.FormSchema
+	if h.forms != nil {
+		var cfgIDs, swIDs []uuid.UUID
+		for _, detail := range assign.DetailAssigns {
+			if detail.FormSchemaID == nil && detail.Config != nil {
+				cfgIDs = append(cfgIDs, detail.Config.ID)
+				swIDs = append(swIDs, detail.Config.SubWorkID)
+			}
+		}
+		latestForms, _ = h.forms.FindLatest(cfgIDs, swIDs)
+	}
+
+	// Tasks waiting on predecessors under the template's step order
+	var blocked map[uuid.UUID][]domain.TaskBlocker
+	if h.deps != nil && assign.TemplateID != nil {
+		if deps, err := h.deps.FindByTemplate(*assign.TemplateID); err == nil {
+			blocked = domain.BlockedBy(deps, assign.DetailAssigns)
+		}
+	}
+
+	// Inject assigned_user_names, has_guide, form and blocked_by into each DetailAssign JSON
 	var response []map[string]interface{}
 	for _, detail := range assign.DetailAssigns {
 		var detailMap map[string]interface{}
@@ -255,6 +293,16 @@ func (h *AssignHandler) GetAssignWithTasks(c *gin.Context) {
 		} else {
 			detailMap["has_guide"] = false
 		}
+		if detail.FormSchema != nil {
+			detailMap["form"] = detail.FormSchema
+		} else {
+			detailMap["form"] = domain.CurrentForm(latestForms, detail.Config)
+		}
+		blockers := blocked[detail.ID]
+		if blockers == nil {
+			blockers = []domain.TaskBlocker{}
+		}
+		detailMap["blocked_by"] = blockers
 		
 		response = append(response, detailMap)
 	}
@@ -342,6 +390,9 @@ func (h *AssignHandler) CreateAssign(c *gin.Context) {
 		StartTime:      startTime,
 		EndTime:        endTime,
 	}
+	if !h.checkSchedule(c, &newAssign, nil) {
+		return
+	}
 
 	// Create the main Assign record
 	if err := h.assignRepo.Create(&newAssign); err != nil {
@@ -453,57 +504,19 @@ func (h *AssignHandler) CreateAssign(c *gin.Context) {
 			_ = h.templateRepo.Create(&newTemplate)
 		}
 	} else if len(body.ConfigIDs) > 0 {
+		var cfgIDs []uuid.UUID
 		for _, cfgStr := range body.ConfigIDs {
-			cfgID, err := uuid.Parse(cfgStr)
-			if err != nil {
-				continue // Skip invalid uuid
-			}
-			// Load config with SubWork to get ProcessIDs
-			var cfg domain.Config
-			loadErr := h.db.Preload("SubWork").Where("id = ?", cfgID).First(&cfg).Error
-			if loadErr != nil {
-				// Config not found, create row without process
-				detail := domain.DetailAssign{
-					ID:       uuid.New(),
-					AssignID: newAssign.ID,
-					ConfigID: &cfgID,
-				}
-				_ = h.detailAssignRepo.Create(&detail)
-				continue
-			}
-			// Extract ProcessIDs from SubWork
-			var processIDs []string
-			if cfg.SubWork != nil && len(cfg.SubWork.ProcessIDs) > 0 {
-				_ = json.Unmarshal(cfg.SubWork.ProcessIDs, &processIDs)
-			}
-			if len(processIDs) > 0 {
-				for _, procStr := range processIDs {
-					procID, parseErr := uuid.Parse(procStr)
-					if parseErr != nil {
-						continue
-					}
-					procIDCopy := procID
-					detail := domain.DetailAssign{
-						ID:        uuid.New(),
-						AssignID:  newAssign.ID,
-						ConfigID:  &cfgID,
-						ProcessID: &procIDCopy,
-					}
-					_ = h.detailAssignRepo.Create(&detail)
-				}
-			} else {
-				// No process configured - create 1 row without ProcessID
-				detail := domain.DetailAssign{
-					ID:       uuid.New(),
-					AssignID: newAssign.ID,
-					ConfigID: &cfgID,
-				}
-				_ = h.detailAssignRepo.Create(&detail)
+			if cfgID, err := uuid.Parse(cfgStr); err == nil { // Skip invalid uuid
+				cfgIDs = append(cfgIDs, cfgID)
 			}
 		}
+		for _, detail := range services.BuildDetailAssigns(h.configRepo, newAssign.ID, cfgIDs) {
+			detail := detail
+			_ = h.detailAssignRepo.Create(&detail)
+		}
 	} else {
 		// Fallback: if no configs sent, fallback to auto-attaching ALL configs of project
-		assets, err := h.assetRepo.FindByProjectID(projectID)
+		assets, err := h.assetRepo.FindByProjectID(projectID, visibilityScope(c))
 		if err == nil {
 			for _, asset := range assets {
 				configs, err := h.configRepo.FindByAssetID(asset.ID)
@@ -562,16 +575,21 @@ func (h *AssignHandler) UpdateAssign(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assign ID"})
 		return
 	}
-	assign, err := h.assignRepo.FindByID(id)
+	assign, err := h.assignRepo.FindByID(id, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Assign not found"})
 		return
 	}
+	before := scheduleKey(assign)
 	if err := c.ShouldBindJSON(assign); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	assign.ID = id
+	// Only moving the assign or changing its users can cause new conflicts
+	if scheduleKey(assign) != before && !h.checkSchedule(c, assign, &id) {
+		return
+	}
 	if err := h.assignRepo.Update(assign); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update assign"})
 		return
@@ -579,6 +597,38 @@ func (h *AssignHandler) UpdateAssign(c *gin.Context) {
 	c.JSON(http.StatusOK, assign)
 }
 
+// checkSchedule refuses, with 409 and the overlapping assigns and time away,
+// to schedule an assign over time its users are already taken - unless the
+// caller knowingly books them anyway with ?override=true
+func (h *AssignHandler) checkSchedule(c *gin.Context, assign *domain.Assign, existingID *uuid.UUID) bool {
+	if h.availability == nil || c.Query("override") == "true" {
+		return true
+	}
+	err := h.availability.CheckConflicts(assign.Users(), assign.StartTime, assign.EndTime, existingID)
+	if err == nil {
+		return true
+	}
+	var conflictErr *services.ScheduleConflictError
+	if stderrors.As(err, &conflictErr) {
+		c.JSON(http.StatusConflict, gin.H{"error": conflictErr.Error(), "conflicts": conflictErr.Conflicts})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check schedule conflicts"})
+	return false
+}
+
+// scheduleKey sums up when and by whom an assign is worked
+func scheduleKey(a *domain.Assign) string {
+	key := fmt.Sprint(a.Users())
+	for _, t := range []*time.Time{a.StartTime, a.EndTime} {
+		key += "|"
+		if t != nil {
+			key += t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return key
+}
+
 // DELETE /assigns/:id
 func (h *AssignHandler) DeleteAssign(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
@@ -602,6 +652,10 @@ func (h *AssignHandler) ListDetailAssigns(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assign ID"})
 		return
 	}
+	if _, err := h.assignRepo.FindByID(assignID, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assign not found"})
+		return
+	}
 	details, err := h.detailAssignRepo.FindByAssignID(assignID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch details"})
@@ -624,6 +678,11 @@ func (h *AssignHandler) CreateDetailAssign(c *gin.Context) {
 	}
 	detail.ID = uuid.New()
 	detail.AssignID = assignID
+	// New tasks always start the workflow; status changes go through submit/approve/reject
+	detail.State = domain.DetailStateDraft
+	detail.StatusWork, detail.StatusSubmit, detail.StatusReject, detail.StatusApprove = 0, 0, 0, 0
+	detail.Measurements = nil // Readings only come in with a submit
+	detail.FormSchemaID, detail.FormAnswers = nil, nil // As do checklist answers
 	if err := h.detailAssignRepo.Create(&detail); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create detail"})
 		return
@@ -631,83 +690,50 @@ func (h *AssignHandler) CreateDetailAssign(c *gin.Context) {
 	c.JSON(http.StatusCreated, detail)
 }
 
-// POST /details/:id/submit - Worker submits evidence
+// POST /details/:id/submit - Worker submits evidence (?draft=true only saves it)
 func (h *AssignHandler) SubmitDetail(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid detail ID"})
 		return
 	}
-	detail, err := h.detailAssignRepo.FindByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
-		return
-	}
 
 	var body struct {
-		Data     []string `json:"data"`
-		NoteData string   `json:"note_data"`
+		Data         []string           `json:"data"`
+		Measurements map[string]float64         `json:"measurements"` // Field key -> reading
+		FormAnswers  map[string]json.RawMessage `json:"form_answers"` // Checklist field key -> answer
+		NoteData     string                     `json:"note_data"`
+		Version      *int                       `json:"version"` // Or If-Match
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// RACE-CONDITION SAFE MERGE:
-	// Read existing URLs from DB, then take the UNION of (existing ∪ incoming).
-	// This prevents User B's outdated client from overwriting images already uploaded by User A.
-	existingURLs := []string{}
-	if len(detail.Data) > 0 && string(detail.Data) != "null" {
-		_ = json.Unmarshal(detail.Data, &existingURLs)
-	}
-	// Deduplicate via map, keeping insertion order (existing first, then new)
-	seen := make(map[string]struct{})
-	mergedURLs := make([]string, 0, len(existingURLs)+len(body.Data))
-	for _, u := range existingURLs {
-		if _, ok := seen[u]; !ok {
-			seen[u] = struct{}{}
-			mergedURLs = append(mergedURLs, u)
-		}
-	}
-	for _, u := range body.Data {
-		if _, ok := seen[u]; !ok {
-			seen[u] = struct{}{}
-			mergedURLs = append(mergedURLs, u)
-		}
+	version, ok := detailVersion(c, body.Version)
+	if !ok {
+		return
 	}
 
 	isDraft := c.Query("draft") == "true"
-
-	dataJSON, _ := json.Marshal(mergedURLs)
-	detail.Data = datatypes.JSON(dataJSON)
-	if body.NoteData != "" {
-		detail.NoteData = body.NoteData
-	}
-
-	if !isDraft {
-		detail.StatusSubmit = 1
-		detail.StatusWork = 1
-		var timestamps []time.Time
-		if len(detail.SubmittedAt) > 0 && string(detail.SubmittedAt) != "null" {
-			_ = json.Unmarshal(detail.SubmittedAt, &timestamps)
+	detail, err := h.workflowSvc.SubmitDetail(id, version, body.Data, body.Measurements, body.FormAnswers, body.NoteData, c.GetString("user_id"), isDraft, visibilityScope(c))
+	if err != nil {
+		var missing *services.MissingEvidenceError
+		if stderrors.As(err, &missing) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": missing.Error(), "missing_slots": missing.Missing})
+			return
 		}
-		timestamps = append(timestamps, time.Now())
-		tsJSON, _ := json.Marshal(timestamps)
-		detail.SubmittedAt = datatypes.JSON(tsJSON)
-	}
-
-	if err := h.detailAssignRepo.Update(detail); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit detail"})
+		var blockedErr *services.BlockedTaskError
+		if stderrors.As(err, &blockedErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": blockedErr.Error(), "blocked_by": blockedErr.BlockedBy})
+			return
+		}
+		workflowError(c, err, "Failed to submit detail")
 		return
 	}
 
 	// Async: upload note.txt to MinIO via media service
 	h.mediaSvc.UploadNoteAsync(id, body.NoteData)
 
-	if h.hub != nil {
-		h.hub.BroadcastAll([]byte(`{"event":"task_updated"}`))
-	}
-
 	// Sync to Lark Base asynchronously on final submission
 	if !isDraft {
 		var submitterName string
@@ -724,10 +750,9 @@ func (h *AssignHandler) SubmitDetail(c *gin.Context) {
 		h.workflowSvc.PostSubmitAsync(id, submitterName, frontendURL)
 	}
 
-	c.JSON(http.StatusOK, detail)
+	respondDetail(c, detail)
 }
 
-
 // PUT /details/:id/note - LƯU TỨC THÌ GHI CHÚ
 func (h *AssignHandler) SaveDetailNote(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
@@ -735,34 +760,30 @@ func (h *AssignHandler) SaveDetailNote(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid detail ID"})
 		return
 	}
-	detail, err := h.detailAssignRepo.FindByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
-		return
-	}
 
 	var body struct {
-		Note string `json:"note"`
+		Note    string `json:"note"`
+		Version *int   `json:"version"` // Or If-Match
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
 		return
 	}
+	version, ok := detailVersion(c, body.Version)
+	if !ok {
+		return
+	}
 
-	detail.NoteData = body.Note
-	if err := h.detailAssignRepo.Update(detail); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save note"})
+	detail, err := h.workflowSvc.SaveNote(id, version, body.Note, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to save note")
 		return
 	}
 
 	// Async: upload note.txt to MinIO via media service
 	h.mediaSvc.UploadNoteAsync(id, body.Note)
 
-	if h.hub != nil {
-		h.hub.BroadcastAll([]byte(`{"event":"task_updated"}`))
-	}
-
-	c.JSON(http.StatusOK, detail)
+	respondDetail(c, detail)
 }
 
 
@@ -778,6 +799,10 @@ func (h *AssignHandler) DeleteDetailImage(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
 		return
 	}
+	if _, err := h.assignRepo.FindByID(detail.AssignID, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
+		return
+	}
 
 	var body struct {
 		Url string `json:"url"`
@@ -839,9 +864,10 @@ func (h *AssignHandler) DeleteDetailImage(c *gin.Context) {
 	}
 
 	dataJSON, _ := json.Marshal(newData)
+	detail.DetachURL(body.Url)
 	
 	// Ép GORM cập nhật đích danh cột data
-	if err := h.db.Model(detail).UpdateColumn("data", datatypes.JSON(dataJSON)).Error; err != nil {
+	if err := h.db.Model(detail).UpdateColumns(map[string]interface{}{"data": datatypes.JSON(dataJSON), "slot_evidence": detail.SlotEvidence, "version": gorm.Expr("version + 1")}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update db after deleting picture"})
 		return
 	}
@@ -893,6 +919,35 @@ func (h *AssignHandler) UploadDetailImage(c *gin.Context) {
 		}
 	}
 
+	// Configs with named evidence slots need every upload to name one that fits
+	slot := c.PostForm("slot")
+	detail, err := h.detailAssignRepo.FindByID(detailAssignID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
+		return
+	}
+	if _, err := h.assignRepo.FindByID(detail.AssignID, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
+		return
+	}
+	if detail.Config != nil && detail.Config.HasNamedSlots() {
+		evidenceSlot, found := detail.Config.FindSlot(slot)
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "slot is required", "slots": detail.Config.Slots()})
+			return
+		}
+		if !evidenceSlot.AllowsMedia(contentType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s does not accept %s files", evidenceSlot.Name, ext)})
+			return
+		}
+		if evidenceSlot.Max > 0 && len(detail.SlotURLs()[slot]) >= evidenceSlot.Max {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s already has its maximum of %d files", evidenceSlot.Name, evidenceSlot.Max)})
+			return
+		}
+	} else {
+		slot = ""
+	}
+
 	// Build the deterministic MinIO object path (same regardless of sync/async path)
 	objectPath, err := h.mediaSvc.BuildMinioObjectPath(detailAssignID, fileHeader.Filename, ext, time.Now())
 	if err != nil {
@@ -962,9 +1017,15 @@ func (h *AssignHandler) UploadDetailImage(c *gin.Context) {
 				previewURL = objectPath
 			}
 			log.Printf("[UploadDetailImage] Queued async upload: detail=%s path=%s", detailAssignID, objectPath)
+			if slot != "" {
+				if err := h.attachToSlot(c.Request.Context(), detailAssignID, slot, previewURL); err != nil {
+					log.Printf("[UploadDetailImage] attach to slot %q failed: %v", slot, err)
+				}
+			}
 			c.JSON(http.StatusOK, gin.H{
 				"url":         previewURL,
 				"object_name": objectPath,
+				"slot":        slot,
 				"queued":      true,
 				"async":       true,
 			})
@@ -1030,13 +1091,38 @@ syncPath:
 			_ = h.mqPublisher.Publish(dbEvent) // best-effort
 		}
 
+		if slot != "" {
+			if err := h.attachToSlot(c.Request.Context(), detailAssignID, slot, url); err != nil {
+				log.Printf("[UploadDetailImage] attach to slot %q failed: %v", slot, err)
+			}
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"url":         url,
 			"object_name": syncObjPath,
+			"slot":        slot,
 		})
 	}
 }
 
+// attachToSlot records an uploaded URL under its evidence slot. The row is
+// locked like in the upload consumer so concurrent uploads don't drop entries,
+// and its version bumped so saves from an older read fail as stale.
+// The write runs with the request context so the audit log names the uploader.
+func (h *AssignHandler) attachToSlot(ctx context.Context, detailID uuid.UUID, slot, url string) error {
+	return h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var detail domain.DetailAssign
+		if err := tx.Raw(`SELECT id, slot_evidence FROM detail_assigns WHERE id = ? FOR UPDATE`, detailID).Scan(&detail).Error; err != nil {
+			return err
+		}
+		detail.AttachToSlot(slot, url)
+		return tx.Model(&domain.DetailAssign{}).Where("id = ?", detailID).UpdateColumns(map[string]interface{}{
+			"slot_evidence": detail.SlotEvidence,
+			"version":       gorm.Expr("version + 1"),
+		}).Error
+	})
+}
+
 
 
 // DELETE /details/:id/images - Xóa toàn bộ hình ảnh của 1 quy trình (folder MinIO + DB)
@@ -1052,6 +1138,10 @@ func (h *AssignHandler) DeleteDetailImages(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
 		return
 	}
+	if _, err := h.assignRepo.FindByID(detail.AssignID, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
+		return
+	}
 
 	// Build folder prefix (same logic as UploadDetailImage path, minus filename)
 	ctxNames, err := h.detailAssignRepo.GetNamesForMinioPath(id)
@@ -1097,7 +1187,7 @@ func (h *AssignHandler) DeleteDetailImages(c *gin.Context) {
 	}
 
 	// Clear DB data array directly via UpdateColumn to avoid silent GORM skips
-	if err := h.db.Model(detail).UpdateColumn("data", datatypes.JSON([]byte("[]"))).Error; err != nil {
+	if err := h.db.Model(detail).UpdateColumns(map[string]interface{}{"data": datatypes.JSON([]byte("[]")), "slot_evidence": datatypes.JSON([]byte("{}")), "version": gorm.Expr("version + 1")}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "MinIO cleared but failed to update DB"})
 		return
 	}
@@ -1115,291 +1205,231 @@ func (h *AssignHandler) DeleteDetailImages(c *gin.Context) {
 	})
 }
 
-// POST /details/:id/approve - Manager approves
+// POST /details/:id/approve - Sign off a submitted task (final unless the project has an approval chain)
 func (h *AssignHandler) ApproveDetail(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid detail ID"})
 		return
 	}
-	detail, err := h.detailAssignRepo.FindByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
-		return
-	}
 
 	var body struct {
 		NoteApproval string `json:"note_approval"`
 		FrontendURL  string `json:"frontend_url"`
+		Version      *int   `json:"version"` // Or If-Match
 	}
 	_ = c.ShouldBindJSON(&body)
-
-	detail.StatusApprove = 1
-	detail.StatusReject = 0
-	detail.NoteApproval = body.NoteApproval
-
-	var timestamps []time.Time
-	_ = json.Unmarshal(detail.ApprovalAt, &timestamps)
-	timestamps = append(timestamps, time.Now())
-	tsJSON, _ := json.Marshal(timestamps)
-	detail.ApprovalAt = datatypes.JSON(tsJSON)
-
-	var actorID string
-	if authID, exists := c.Get("user_id"); exists {
-		actorID = fmt.Sprintf("%v", authID)
-	}
-
-	// Track who approved
-	if actorID != "" {
-		var personIDs []string
-		_ = json.Unmarshal(detail.IdPersonApprove, &personIDs)
-		personIDs = append(personIDs, actorID)
-		pJSON, _ := json.Marshal(personIDs)
-		detail.IdPersonApprove = datatypes.JSON(pJSON)
-	}
-
-	if err := h.detailAssignRepo.Update(detail); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve detail"})
+	version, ok := detailVersion(c, body.Version)
+	if !ok {
 		return
 	}
 
-	if h.hub != nil {
-		h.hub.BroadcastAll([]byte(`{"event":"task_updated"}`))
+	detail, err := h.workflowSvc.ApproveDetail(id, version, body.NoteApproval, c.GetString("user_id"), c.GetString("role"), body.FrontendURL, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to approve detail")
+		return
 	}
-
-	// Async: Lark sync
-	detailCopy := *detail
-	go func() {
-		// Load the assign to find who submitted
-		var assign domain.Assign
-		if err := h.db.First(&assign, "id = ?", detailCopy.AssignID).Error; err != nil {
-			return
-		}
-		var userIDs []string
-		_ = json.Unmarshal(assign.UserIDs, &userIDs)
-		ctxNames, err := h.detailAssignRepo.GetNamesForMinioPath(detailCopy.ID)
-		if err != nil {
-			return
-		}
-
-		// LARK SYNC
-		if body.FrontendURL != "" && h.larkSvc != nil {
-			h.syncCompletedTaskToLark(detailCopy, assign, userIDs, actorID, body.FrontendURL, ctxNames)
-		}
-	}()
-
-	c.JSON(http.StatusOK, detail)
+	respondDetail(c, detail)
 }
 
-// POST /details/:id/reject - Manager rejects
+// POST /details/:id/reject - Manager sends a submitted or approved task back
 func (h *AssignHandler) RejectDetail(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid detail ID"})
 		return
 	}
-	detail, err := h.detailAssignRepo.FindByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
-		return
-	}
 
 	var body struct {
 		NoteReject  string `json:"note_reject"`
 		FrontendURL string `json:"frontend_url"`
+		Version     *int   `json:"version"` // Or If-Match
 	}
 	_ = c.ShouldBindJSON(&body)
-
-	detail.StatusReject = 1
-	detail.StatusApprove = 0
-	detail.StatusSubmit = 0
-	detail.NoteReject = body.NoteReject
-
-	var timestamps []time.Time
-	_ = json.Unmarshal(detail.RejectedAt, &timestamps)
-	timestamps = append(timestamps, time.Now())
-	tsJSON, _ := json.Marshal(timestamps)
-	detail.RejectedAt = datatypes.JSON(tsJSON)
-
-	var actorID string
-	if authID, exists := c.Get("user_id"); exists {
-		actorID = fmt.Sprintf("%v", authID)
-	}
-
-	// Track who rejected
-	if actorID != "" {
-		var personIDs []string
-		_ = json.Unmarshal(detail.IdPersonReject, &personIDs)
-		personIDs = append(personIDs, actorID)
-		pJSON, _ := json.Marshal(personIDs)
-		detail.IdPersonReject = datatypes.JSON(pJSON)
-	}
-
-	if err := h.detailAssignRepo.Update(detail); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject detail"})
+	version, ok := detailVersion(c, body.Version)
+	if !ok {
 		return
 	}
 
-	if h.hub != nil {
-		h.hub.BroadcastAll([]byte(`{"event":"task_updated"}`))
+	detail, err := h.workflowSvc.RejectDetail(id, version, body.NoteReject, c.GetString("user_id"), body.FrontendURL, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to reject detail")
+		return
 	}
-
-	// Async: Lark sync
-	detailCopy := *detail
-	go func() {
-		var assign domain.Assign
-		if err := h.db.First(&assign, "id = ?", detailCopy.AssignID).Error; err != nil {
-			return
-		}
-		var userIDs []string
-		_ = json.Unmarshal(assign.UserIDs, &userIDs)
-		ctxNames, err := h.detailAssignRepo.GetNamesForMinioPath(detailCopy.ID)
-		if err != nil {
-			return
-		}
-
-		// LARK SYNC — push to "TỪ CHỐI TASK" table
-		if body.FrontendURL != "" && h.larkSvc != nil {
-			h.syncRejectedTaskToLark(detailCopy, assign, userIDs, actorID, body.FrontendURL, ctxNames)
-		}
-	}()
-
-	c.JSON(http.StatusOK, detail)
+	respondDetail(c, detail)
 }
 
-// PUT /task-details/bulk/status - Bulk Approve/Reject
+// Modes of a bulk status update
+const (
+	bulkModeBestEffort = "best_effort" // Each task saved on its own (default)
+	bulkModeAtomic     = "atomic"      // All or nothing, in one transaction
+)
+
+// PUT /task-details/bulk/status - Bulk Approve (1) / Reject (-1) / Reopen (0)
 func (h *AssignHandler) BulkUpdateDetailStatus(c *gin.Context) {
 	var body struct {
-		IDs         []string `json:"ids"`
-		Accept      int      `json:"accept"`
-		Note        string   `json:"note"`
-		FrontendURL string   `json:"frontend_url"`
+		IDs         []string       `json:"ids"`
+		Versions    map[string]int `json:"versions"` // ID -> version the client last saw
+		Accept      int            `json:"accept"`
+		Note        string         `json:"note"`
+		FrontendURL string         `json:"frontend_url"`
+		Mode        string         `json:"mode"` // bulkModeBestEffort or bulkModeAtomic
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
+	if body.Mode == "" {
+		body.Mode = bulkModeBestEffort
+	}
+	if body.Mode != bulkModeBestEffort && body.Mode != bulkModeAtomic {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be best_effort or atomic"})
+		return
+	}
 
 	if len(body.IDs) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no ids provided"})
 		return
 	}
-
-	var successCount int
-	for _, idStr := range body.IDs {
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			continue // Skip invalid UUIDs
-		}
-
-		detail, err := h.detailAssignRepo.FindByID(id)
-		if err != nil {
-			continue
+	for _, id := range body.IDs {
+		if _, ok := body.Versions[id]; !ok {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "versions must list every id", "id": id})
+			return
 		}
+	}
 
-		actorIDStr, _ := c.Get("user_id")
-		if body.Accept == 1 {
-			// Approve logic
-			detail.StatusApprove = 1
-			detail.StatusReject = 0
-			detail.NoteApproval = body.Note
-
-			var timestamps []time.Time
-			_ = json.Unmarshal(detail.ApprovalAt, &timestamps)
-			timestamps = append(timestamps, time.Now())
-			tsJSON, _ := json.Marshal(timestamps)
-			detail.ApprovalAt = datatypes.JSON(tsJSON)
-
-			// Track who approved
-			if actorIDStr != nil {
-				var personIDs []string
-				_ = json.Unmarshal(detail.IdPersonApprove, &personIDs)
-				personIDs = append(personIDs, fmt.Sprintf("%v", actorIDStr))
-				pJSON, _ := json.Marshal(personIDs)
-				detail.IdPersonApprove = datatypes.JSON(pJSON)
-			}
+	result, err := h.workflowSvc.BulkUpdateStatus(body.IDs, body.Versions, body.Accept, body.Note, c.GetString("user_id"), c.GetString("role"), body.FrontendURL, body.Mode == bulkModeAtomic, visibilityScope(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-			// LARK SYNC
-			if body.FrontendURL != "" && h.larkSvc != nil {
-				detailCopy := *detail
-				go func(d domain.DetailAssign) {
-					var assign domain.Assign
-					if err := h.db.First(&assign, "id = ?", d.AssignID).Error; err != nil {
-						return
-					}
-					var userIDs []string
-					_ = json.Unmarshal(assign.UserIDs, &userIDs)
-					ctxNames, err := h.detailAssignRepo.GetNamesForMinioPath(d.ID)
-					if err != nil {
-						return
-					}
-					h.syncCompletedTaskToLark(d, assign, userIDs, fmt.Sprintf("%v", actorIDStr), body.FrontendURL, ctxNames)
-				}(detailCopy)
-			}
-		} else if body.Accept == -1 {
-			// Reject logic
-			detail.StatusReject = 1
-			detail.StatusApprove = 0
-			detail.StatusSubmit = 0
-			detail.NoteReject = body.Note
-
-			var timestamps []time.Time
-			_ = json.Unmarshal(detail.RejectedAt, &timestamps)
-			timestamps = append(timestamps, time.Now())
-			tsJSON, _ := json.Marshal(timestamps)
-			detail.RejectedAt = datatypes.JSON(tsJSON)
-
-			// Track who rejected
-			if actorIDStr != nil {
-				var personIDs []string
-				_ = json.Unmarshal(detail.IdPersonReject, &personIDs)
-				personIDs = append(personIDs, fmt.Sprintf("%v", actorIDStr))
-				pJSON, _ := json.Marshal(personIDs)
-				detail.IdPersonReject = datatypes.JSON(pJSON)
-			}
+	// Nothing applied because the batch was rolled back or every task had moved on
+	status := http.StatusOK
+	message := "Bulk update completed"
+	if result.RolledBack {
+		status, message = http.StatusConflict, "Bulk update rolled back"
+	} else if result.SuccessCount == 0 && len(result.Conflicts) > 0 {
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"message":           message,
+		"mode":              body.Mode,
+		"items":             result.Items,
+		"success_count":     result.SuccessCount,
+		"total_requested":   result.TotalRequested,
+		"invalid_state_ids": result.InvalidStateIDs,
+		"not_approver_ids":  result.NotApproverIDs,
+		"signed_off_ids":    result.SignedOffIDs,
+		"conflicts":         result.Conflicts,
+	})
+}
 
-			// LARK SYNC (REJECTION)
-			log.Printf("[DEBUG] BULK REJECT TRIGGERED. FrontendURL='%s', h.larkSvc=%v", body.FrontendURL, h.larkSvc != nil)
-			if body.FrontendURL != "" && h.larkSvc != nil {
-				log.Printf("[DEBUG] BULK REJECT ENTERED GOROUTINE!")
-				detailCopy := *detail
-				go func(d domain.DetailAssign) {
-					var assign domain.Assign
-					if err := h.db.First(&assign, "id = ?", d.AssignID).Error; err != nil {
-						return
-					}
-					var userIDs []string
-					_ = json.Unmarshal(assign.UserIDs, &userIDs)
-					ctxNames, err := h.detailAssignRepo.GetNamesForMinioPath(d.ID)
-					if err != nil {
-						return
-					}
-					h.syncRejectedTaskToLark(d, assign, userIDs, fmt.Sprintf("%v", actorIDStr), body.FrontendURL, ctxNames)
-				}(detailCopy)
-			}
-		} else if body.Accept == 0 {
-			// Reset logic
-			detail.StatusReject = 0
-			detail.StatusApprove = 0
-			detail.StatusSubmit = 0
-		}
+// GET /details/:id/approvals - approval chain and sign-offs of the current submission
+func (h *AssignHandler) GetDetailApprovals(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid detail ID"})
+		return
+	}
+	detail, err := h.detailAssignRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
+		return
+	}
+	if _, err := h.assignRepo.FindByID(detail.AssignID, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
+		return
+	}
+	progress, err := h.workflowSvc.GetApprovalProgress(id)
+	if err != nil {
+		workflowError(c, err, "Failed to fetch approvals")
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
 
-		if err := h.detailAssignRepo.Update(detail); err == nil {
-			successCount++
-		}
+// GET /details/:id/timeline - history of a task, oldest first
+func (h *AssignHandler) GetDetailTimeline(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid detail ID"})
+		return
+	}
+	detail, err := h.detailAssignRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
+		return
 	}
+	if _, err := h.assignRepo.FindByID(detail.AssignID, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Detail not found"})
+		return
+	}
+	events, err := h.workflowSvc.GetDetailTimeline(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline"})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
 
-	if h.hub != nil && successCount > 0 {
-		h.hub.BroadcastAll([]byte(`{"event":"task_updated"}`))
+// GET /assigns/:id/timeline - history of every task of an assign, oldest first
+func (h *AssignHandler) GetAssignTimeline(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assign ID"})
+		return
+	}
+	if _, err := h.assignRepo.FindByID(id, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assign not found"})
+		return
 	}
+	events, err := h.workflowSvc.GetAssignTimeline(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline"})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":         "Bulk update completed",
-		"success_count":   successCount,
-		"total_requested": len(body.IDs),
-	})
+// GET /assigns/:id/measurements - readings of every task (?out_of_range=true for flagged ones)
+func (h *AssignHandler) GetAssignMeasurements(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assign ID"})
+		return
+	}
+	if _, err := h.assignRepo.FindByID(id, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assign not found"})
+		return
+	}
+	measurements, err := h.workflowSvc.ListAssignMeasurements(id, c.Query("out_of_range") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch measurements"})
+		return
+	}
+	c.JSON(http.StatusOK, measurements)
 }
 
+// workflowError reports a workflow service failure: AppErrors (not found, invalid
+// state transition) keep their status, version conflicts are a 409 carrying the
+// current task, anything else is a 500 with fallback. The service only reports
+// conflicts on tasks in the caller's scope, so the current task is never hidden.
+func workflowError(c *gin.Context, err error, fallback string) {
+	var conflict *services.VersionConflictError
+	if stderrors.As(err, &conflict) {
+		// The current state lets the client reconcile and retry with its version
+		c.Header("ETag", strconv.Quote(strconv.Itoa(conflict.Current.Version)))
+		c.JSON(http.StatusConflict, gin.H{"error": conflict.Error(), "current": conflict.Current})
+		return
+	}
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		c.Error(appErr)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
 
 // GET /assigns/history - list soft-deleted assigns
 func (h *AssignHandler) ListDeletedAssigns(c *gin.Context) {
@@ -1439,8 +1469,9 @@ func (h *AssignHandler) PermanentDeleteAssign(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Assign permanently deleted"})
 }
 
-// GET /api/public/report/:id  — no auth required
+// GET /api/public/report/:id  — requires a share token for the assign (or a generic report of it)
 // Returns fully-hydrated assign data (project, owner, users, details) for public report view.
+// Asset / sub-work filters signed into the token narrow the returned details.
 func (h *AssignHandler) GetPublicReport(c *gin.Context) {
 	idStr := c.Param("id")
 	assignID, err := uuid.Parse(idStr)
@@ -1448,6 +1479,11 @@ func (h *AssignHandler) GetPublicReport(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assign ID"})
 		return
 	}
+	claims := shareClaims(c)
+	if claims == nil || !claims.GrantsAssign(assignID.String()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Share link does not cover this report"})
+		return
+	}
 
 	// 1. Load assign with all relations needed for the report
 	var assign domain.Assign
@@ -1462,12 +1498,16 @@ func (h *AssignHandler) GetPublicReport(c *gin.Context) {
 		Preload("DetailAssigns.Config.SubWork").
 		Preload("DetailAssigns.Config.SubWork.Work").
 		Preload("DetailAssigns.Process").
+		Preload("DetailAssigns.Measurements", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
 		Where("id = ?", assignID).
 		First(&assign).Error
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Assign not found"})
 		return
 	}
+	if claims.ResourceType == domain.ShareAssignReport {
+		assign.DetailAssigns = filterSharedDetails(assign.DetailAssigns, claims.Filters)
+	}
 
 	// 2. Resolve owner from project
 	type OwnerDTO struct {
@@ -1529,207 +1569,42 @@ func (h *AssignHandler) GetPublicReport(c *gin.Context) {
 			Scan(&approvers)
 	}
 
-	// 5. Return combined DTO
-	c.JSON(http.StatusOK, gin.H{
-		"assign":    assign,
-		"owner":     owner,
-		"users":     users,
-		"approvers": approvers,
-	})
-}
-
-// syncCompletedTaskToLark automatically sends task data to the configured Lark Base table.
-func (h *AssignHandler) syncCompletedTaskToLark(
-	detail domain.DetailAssign,
-	assign domain.Assign,
-	assigneeIDs []string,
-	approverID string,
-	frontendURL string,
-	ctxNames *domain.MinioPathContext,
-) {
-	appToken := os.Getenv("LARK_APP_TOKEN")
-	tableID := os.Getenv("LARK_COMPLETED_TASK_TABLE_ID")
-	if appToken == "" || tableID == "" {
-		log.Println("[Lark Sync] Skipped: LARK_APP_TOKEN or LARK_COMPLETED_TASK_TABLE_ID not set")
-		return
-	}
-
-	// Resolve assignee names
-	var assignees []domain.User
-	if len(assigneeIDs) > 0 {
-		h.db.Where("id IN ?", assigneeIDs).Find(&assignees)
-	}
-	if len(assignees) == 0 {
-		assignees = append(assignees, domain.User{Name: "Kỹ thuật viên"})
-	}
-
-	// Resolve approver name
-	approverName := "Quản lý"
-	if approverID != "" {
-		var approver domain.User
-		if h.db.First(&approver, "id = ?", approverID).Error == nil {
-			approverName = approver.Name
-		}
+	// 5. Photos grouped by evidence slot, per detail
+	evidenceGroups := make(map[string][]domain.EvidenceGroup, len(assign.DetailAssigns))
+	for i := range assign.DetailAssigns {
+		d := &assign.DetailAssigns[i]
+		evidenceGroups[d.ID.String()] = d.GroupEvidence(d.Config)
 	}
 
-	// Build link
-	queryParams := []string{}
-	if detail.Config != nil {
-		queryParams = append(queryParams, "asset="+detail.Config.AssetID.String(), "sub="+detail.Config.SubWorkID.String())
-	}
-	reportLink := fmt.Sprintf("%s/share/report/%s", frontendURL, assign.ID.String())
-	if len(queryParams) > 0 {
-		reportLink += "?" + strings.Join(queryParams, "&")
-	}
-
-	// Prepare Time
-	var submittedAt string
-	var approvalAt string
-	
-	var subTimes []time.Time
-	if err := json.Unmarshal(detail.SubmittedAt, &subTimes); err == nil && len(subTimes) > 0 {
-		submittedAt = subTimes[len(subTimes)-1].Format("02/01/2006 15:04:05")
-	}
-
-	var appTimes []time.Time
-	if err := json.Unmarshal(detail.ApprovalAt, &appTimes); err == nil && len(appTimes) > 0 {
-		approvalAt = appTimes[len(appTimes)-1].Format("02/01/2006 15:04:05")
-	}
-
-	for _, assignee := range assignees {
-		fields := map[string]interface{}{
-			"Họ và tên nhân sự phụ trách": assignee.Name,
-			"Họ và tên Quản lý":           approverName,
-			"Work":                        ctxNames.WorkName,
-			"Sub - work":                  ctxNames.SubWorkName,
-			"Asset":                       ctxNames.AssetName,
-		}
-
-		if reportLink != "" {
-			fields["Đường dẫn"] = map[string]string{
-				"link": reportLink,
-				"text": "Xem Báo Cáo",
-			}
-		}
-
-		if submittedAt != "" {
-			fields["Thời gian Nhân sự nộp"] = submittedAt
-		}
-		if approvalAt != "" {
-			fields["Thời gian Quản lý duyệt"] = approvalAt
-		}
-
-		err := h.larkSvc.PushReportToBitable(appToken, tableID, fields)
-		if err != nil {
-			log.Printf("[Lark Sync] Failed to push complete task for %s: %v\n", assignee.Name, err)
-		} else {
-			log.Printf("[Lark Sync] Successfully pushed complete task to Lark for %s\n", assignee.Name)
-		}
-	}
-
-	// Also update the NỘP DỮ LIỆU Submit record for this assign
-	submitAppToken := os.Getenv("LARK_SUBMIT_APP_TOKEN")
-	submitTableID := os.Getenv("LARK_SUBMIT_TABLE_ID")
-	if submitAppToken != "" && submitTableID != "" && h.larkSvc != nil {
-		if err := h.larkSvc.UpdateSubmitRecord(submitAppToken, submitTableID, assign.ID.String(), ctxNames.SubWorkName, ctxNames.AssetName, ctxNames.ProcessName, approverName, approvalAt, true); err != nil {
-			log.Printf("[Lark Sync] UpdateSubmitRecord (approve) failed for assign %s: %v\n", assign.ID, err)
-		} else {
-			log.Printf("[Lark Sync] UpdateSubmitRecord (approve) succeeded for assign %s\n", assign.ID)
-		}
-	}
+	// 6. Return combined DTO
+	c.JSON(http.StatusOK, gin.H{
+		"assign":          assign,
+		"owner":           owner,
+		"users":           users,
+		"approvers":       approvers,
+		"evidence_groups": evidenceGroups,
+	})
 }
 
-// syncRejectedTaskToLark pushes rejected task data to the dedicated "TỪ CHỐI TASK" Lark Bitable table.
-func (h *AssignHandler) syncRejectedTaskToLark(
-	detail domain.DetailAssign,
-	assign domain.Assign,
-	assigneeIDs []string,
-	rejectorID string,
-	frontendURL string,
-	ctxNames *domain.MinioPathContext,
-) {
-	const (
-		rejectAppToken = "JbTBbo3QQaz7r5smJZilen5EgXg"
-		rejectTableID  = "tblkCQwQANfv8E7g"
-	)
-
-	// Resolve assignees
-	var assignees []domain.User
-	if len(assigneeIDs) > 0 {
-		h.db.Where("id IN ?", assigneeIDs).Find(&assignees)
+// filterSharedDetails keeps only the details matching the asset / sub-work a share link was scoped to
+func filterSharedDetails(details []domain.DetailAssign, filters map[string]string) []domain.DetailAssign {
+	assetID := filters[domain.ShareFilterAsset]
+	subWorkID := filters[domain.ShareFilterSubWork]
+	if assetID == "" && subWorkID == "" {
+		return details
 	}
-	if len(assignees) == 0 {
-		assignees = append(assignees, domain.User{Name: "Kỹ thuật viên"})
-	}
-
-	// Resolve rejector (manager) name
-	rejectorName := "Quản lý"
-	if rejectorID != "" {
-		var rejector domain.User
-		if h.db.First(&rejector, "id = ?", rejectorID).Error == nil {
-			rejectorName = rejector.Name
-		}
-	}
-
-	// Build report link pointing to this detail's individual report
-	queryParams := []string{}
-	if detail.Config != nil {
-		queryParams = append(queryParams, "asset="+detail.Config.AssetID.String(), "sub="+detail.Config.SubWorkID.String())
-	}
-	queryParams = append(queryParams, "type=reject") // Signal frontend to use reject template
-
-	reportLink := fmt.Sprintf("%s/share/report/%s", frontendURL, assign.ID.String())
-	if len(queryParams) > 0 {
-		reportLink += "?" + strings.Join(queryParams, "&")
-	}
-
-	// Parse timestamps
-	var submittedAt, rejectedAt string
-	var subTimes []time.Time
-	if err := json.Unmarshal(detail.SubmittedAt, &subTimes); err == nil && len(subTimes) > 0 {
-		submittedAt = subTimes[len(subTimes)-1].Format("02/01/2006 15:04:05")
-	}
-	var rejTimes []time.Time
-	if err := json.Unmarshal(detail.RejectedAt, &rejTimes); err == nil && len(rejTimes) > 0 {
-		rejectedAt = rejTimes[len(rejTimes)-1].Format("02/01/2006 15:04:05")
-	}
-
-	for _, assignee := range assignees {
-		fields := map[string]interface{}{
-			"Họ và tên nhân sự phụ trách": assignee.Name,
-			"Họ và tên Quản lý":           rejectorName,
-			"Work":                        ctxNames.WorkName,
-			"Sub - work":                  ctxNames.SubWorkName,
-			"Asset":                       ctxNames.AssetName,
-		}
-
-		if reportLink != "" {
-			fields["Đường dẫn"] = map[string]string{
-				"link": reportLink,
-				"text": "Xem Báo Cáo",
-			}
-		}
-		if submittedAt != "" {
-			fields["Thời gian Nhân sự nộp"] = submittedAt
-		}
-		if rejectedAt != "" {
-			fields["Thời gian Quản lý từ chối"] = rejectedAt
+	kept := make([]domain.DetailAssign, 0, len(details))
+	for _, d := range details {
+		if d.Config == nil {
+			continue
 		}
-		if err := h.larkSvc.PushReportToBitable(rejectAppToken, rejectTableID, fields); err != nil {
-			log.Printf("[Lark Sync/Reject] Failed to push rejected task for %s: %v\n", assignee.Name, err)
-		} else {
-			log.Printf("[Lark Sync/Reject] Successfully pushed rejected task to Lark for %s\n", assignee.Name)
+		if assetID != "" && d.Config.AssetID.String() != assetID {
+			continue
 		}
-	}
-
-	// Also update the NỘP DỮ LIỆU Submit record for this assign
-	submitAppToken := os.Getenv("LARK_SUBMIT_APP_TOKEN")
-	submitTableID := os.Getenv("LARK_SUBMIT_TABLE_ID")
-	if submitAppToken != "" && submitTableID != "" && h.larkSvc != nil {
-		if err := h.larkSvc.UpdateSubmitRecord(submitAppToken, submitTableID, assign.ID.String(), ctxNames.SubWorkName, ctxNames.AssetName, ctxNames.ProcessName, rejectorName, rejectedAt, false); err != nil {
-			log.Printf("[Lark Sync/Reject] UpdateSubmitRecord failed for assign %s: %v\n", assign.ID, err)
-		} else {
-			log.Printf("[Lark Sync/Reject] UpdateSubmitRecord succeeded for assign %s\n", assign.ID)
+		if subWorkID != "" && d.Config.SubWorkID.String() != subWorkID {
+			continue
 		}
+		kept = append(kept, d)
 	}
+	return kept
 }
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// ApprovalChainHandler configures the multi-level approval chains of projects
+// and templates
+type ApprovalChainHandler struct {
+	Svc *services.ApprovalChainService
+}
+
+func NewApprovalChainHandler(svc *services.ApprovalChainService) *ApprovalChainHandler {
+	return &ApprovalChainHandler{Svc: svc}
+}
+
+type ApprovalChainRequest struct {
+	Levels []services.ApprovalLevelInput `json:"levels" binding:"required"`
+}
+
+// GET /projects/:id/approval-chain
+func (h *ApprovalChainHandler) GetProjectChain(c *gin.Context) {
+	h.get(c, h.Svc.GetProjectChain)
+}
+
+// PUT /projects/:id/approval-chain
+func (h *ApprovalChainHandler) SetProjectChain(c *gin.Context) {
+	h.set(c, h.Svc.SetProjectChain)
+}
+
+// DELETE /projects/:id/approval-chain
+func (h *ApprovalChainHandler) DeleteProjectChain(c *gin.Context) {
+	h.delete(c, h.Svc.DeleteProjectChain)
+}
+
+// GET /templates/:id/approval-chain
+func (h *ApprovalChainHandler) GetTemplateChain(c *gin.Context) {
+	h.get(c, h.Svc.GetTemplateChain)
+}
+
+// PUT /templates/:id/approval-chain
+func (h *ApprovalChainHandler) SetTemplateChain(c *gin.Context) {
+	h.set(c, h.Svc.SetTemplateChain)
+}
+
+// DELETE /templates/:id/approval-chain
+func (h *ApprovalChainHandler) DeleteTemplateChain(c *gin.Context) {
+	h.delete(c, h.Svc.DeleteTemplateChain)
+}
+
+func (h *ApprovalChainHandler) get(c *gin.Context, find func(uuid.UUID) (*domain.ApprovalChain, error)) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	chain, err := find(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approval chain"})
+		return
+	}
+	if chain == nil {
+		// No chain configured: approvals are single-step
+		c.JSON(http.StatusOK, gin.H{"levels": []domain.ApprovalLevel{}})
+		return
+	}
+	c.JSON(http.StatusOK, chain)
+}
+
+func (h *ApprovalChainHandler) set(c *gin.Context, save func(uuid.UUID, []services.ApprovalLevelInput) (*domain.ApprovalChain, error)) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req ApprovalChainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	chain, err := save(id, req.Levels)
+	if err != nil {
+		workflowError(c, err, "Failed to save approval chain")
+		return
+	}
+	c.JSON(http.StatusOK, chain)
+}
+
+func (h *ApprovalChainHandler) delete(c *gin.Context, remove func(uuid.UUID) error) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := remove(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete approval chain"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Approval chain removed"})
+}
@@ -32,7 +32,7 @@ func (h *AssetHandler) ListAssets(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project_id"})
 			return
 		}
-		assets, err := h.assetRepo.FindByProjectID(projectID)
+		assets, err := h.assetRepo.FindByProjectID(projectID, visibilityScope(c))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assets"})
 			return
@@ -40,7 +40,7 @@ func (h *AssetHandler) ListAssets(c *gin.Context) {
 		c.JSON(http.StatusOK, assets)
 		return
 	}
-	assets, err := h.assetRepo.FindAll()
+	assets, err := h.assetRepo.FindAll(visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assets"})
 		return
@@ -54,7 +54,7 @@ func (h *AssetHandler) GetAsset(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid asset ID"})
 		return
 	}
-	asset, err := h.assetRepo.FindByID(id)
+	asset, err := h.assetRepo.FindByID(id, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
 		return
@@ -82,7 +82,7 @@ func (h *AssetHandler) UpdateAsset(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid asset ID"})
 		return
 	}
-	asset, err := h.assetRepo.FindByID(id)
+	asset, err := h.assetRepo.FindByID(id, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
 		return
@@ -367,24 +367,31 @@ func (h *AssetHandler) GetSubWork(c *gin.Context) {
 
 func (h *AssetHandler) CreateSubWork(c *gin.Context) {
 	var req struct {
-		Name      string    `json:"name"`
-		IDWork    uuid.UUID `json:"id_work"`
-		IDProcess []string  `json:"id_process"` // expecting string UUIDs
+		Name              string                    `json:"name"`
+		IDWork            uuid.UUID                 `json:"id_work"`
+		IDProcess         []string                  `json:"id_process"` // expecting string UUIDs
+		MeasurementFields []domain.MeasurementField `json:"measurement_fields"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	fieldsJSON, err := measurementFieldsJSON(req.MeasurementFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	idProcessBytes, err := json.Marshal(req.IDProcess)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse id_process"})
 		return
 	}
 	subWork := domain.SubWork{
-		ID:         uuid.New(),
-		Name:       req.Name,
-		WorkID:     req.IDWork,
-		ProcessIDs: datatypes.JSON(idProcessBytes),
+		ID:                uuid.New(),
+		Name:              req.Name,
+		WorkID:            req.IDWork,
+		ProcessIDs:        datatypes.JSON(idProcessBytes),
+		MeasurementFields: fieldsJSON,
 	}
 	if err := h.subWorkRepo.Create(&subWork); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sub-work"})
@@ -405,14 +412,23 @@ func (h *AssetHandler) UpdateSubWork(c *gin.Context) {
 		return
 	}
 	var req struct {
-		Name      string    `json:"name"`
-		IDWork    uuid.UUID `json:"id_work"`
-		IDProcess []string  `json:"id_process"`
+		Name              string                    `json:"name"`
+		IDWork            uuid.UUID                 `json:"id_work"`
+		IDProcess         []string                  `json:"id_process"`
+		MeasurementFields []domain.MeasurementField `json:"measurement_fields"` // nil = unchanged
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.MeasurementFields != nil {
+		fieldsJSON, err := measurementFieldsJSON(req.MeasurementFields)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		subWork.MeasurementFields = fieldsJSON
+	}
 
 	idProcessBytes, _ := json.Marshal(req.IDProcess)
 	subWork.Name = req.Name
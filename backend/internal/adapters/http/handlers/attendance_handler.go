@@ -16,11 +16,12 @@ import (
 
 type AttendanceHandler struct {
 	service      *services.AttendanceService
+	assignRepo   domain.AssignRepository
 	statsHandler *StatsHandler // For cache invalidation
 }
 
-func NewAttendanceHandler(service *services.AttendanceService, statsHandler *StatsHandler) *AttendanceHandler {
-	return &AttendanceHandler{service: service, statsHandler: statsHandler}
+func NewAttendanceHandler(service *services.AttendanceService, assignRepo domain.AssignRepository, statsHandler *StatsHandler) *AttendanceHandler {
+	return &AttendanceHandler{service: service, assignRepo: assignRepo, statsHandler: statsHandler}
 }
 
 // CheckInWithPhotos handles POST /api/attendance/checkin-with-photos
@@ -198,6 +199,10 @@ func (h *AttendanceHandler) GetTodayAttendance(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"data": nil, "message": "No attendance record found for today"})
 		return
 	}
+	if _, err := h.service.GetVisibleByID(attendance.ID, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attendance record not found"})
+		return
+	}
 
 	c.JSON(http.StatusOK, attendance)
 }
@@ -217,7 +222,7 @@ func (h *AttendanceHandler) GetUserHistory(c *gin.Context) {
 		limit = 30
 	}
 
-	attendances, err := h.service.GetUserHistory(userID, limit)
+	attendances, err := h.service.GetUserHistory(userID, limit, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -228,7 +233,7 @@ func (h *AttendanceHandler) GetUserHistory(c *gin.Context) {
 
 // GetAllTodayAttendances handles GET /api/attendance/today/all
 func (h *AttendanceHandler) GetAllTodayAttendances(c *gin.Context) {
-	attendances, err := h.service.GetAllTodayAttendances()
+	attendances, err := h.service.GetAllTodayAttendances(visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -239,7 +244,7 @@ func (h *AttendanceHandler) GetAllTodayAttendances(c *gin.Context) {
 
 // GetUsersOnSite handles GET /api/attendance/onsite
 func (h *AttendanceHandler) GetUsersOnSite(c *gin.Context) {
-	attendances, err := h.service.GetUsersOnSite()
+	attendances, err := h.service.GetUsersOnSite(visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -415,7 +420,7 @@ func (h *AttendanceHandler) GetAllHistory(c *gin.Context) {
 		limit = 100
 	}
 
-	attendances, err := h.service.GetAllAttendanceHistory(limit)
+	attendances, err := h.service.GetAllAttendanceHistory(limit, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -433,7 +438,7 @@ func (h *AttendanceHandler) GetDetail(c *gin.Context) {
 		return
 	}
 
-	attendance, err := h.service.GetByID(id)
+	attendance, err := h.service.GetVisibleByID(id, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Attendance record not found"})
 		return
@@ -485,23 +490,55 @@ func (h *AttendanceHandler) Lookup(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Attendance record not found"})
 		return
 	}
+	if _, err := h.service.GetVisibleByID(attendance.ID, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attendance record not found"})
+		return
+	}
 
 	c.JSON(http.StatusOK, attendance)
 }
 
 // GetByAssignDates handles GET /api/attendance/by-assign-dates?assign_id=...&dates[]=YYYY-MM-DD&dates[]=...
 func (h *AttendanceHandler) GetByAssignDates(c *gin.Context) {
+	assignID, ok := assignIDQuery(c)
+	if !ok {
+		return
+	}
+	if _, err := h.assignRepo.FindByID(assignID, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assign not found"})
+		return
+	}
+	h.serveAssignDates(c, assignID)
+}
+
+// GetPublicByAssignDates handles GET /api/public/attendance-by-assign for share links of the assign
+func (h *AttendanceHandler) GetPublicByAssignDates(c *gin.Context) {
+	assignID, ok := assignIDQuery(c)
+	if !ok {
+		return
+	}
+	if claims := shareClaims(c); claims == nil || !claims.GrantsAssign(assignID.String()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Share link does not cover this assign"})
+		return
+	}
+	h.serveAssignDates(c, assignID)
+}
+
+func assignIDQuery(c *gin.Context) (uuid.UUID, bool) {
 	assignIDStr := c.Query("assign_id")
 	if assignIDStr == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "assign_id is required"})
-		return
+		return uuid.Nil, false
 	}
 	assignID, err := uuid.Parse(assignIDStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assign_id"})
-		return
+		return uuid.Nil, false
 	}
+	return assignID, true
+}
 
+func (h *AttendanceHandler) serveAssignDates(c *gin.Context, assignID uuid.UUID) {
 	dates := c.QueryArray("dates[]")
 	if len(dates) == 0 {
 		// Try without brackets (some clients omit them)
@@ -516,4 +553,3 @@ func (h *AttendanceHandler) GetByAssignDates(c *gin.Context) {
 
 	c.JSON(http.StatusOK, attendances)
 }
-
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// AuditHandler serves the audit log written by middleware.Audit
+type AuditHandler struct {
+	Svc *services.AuditService
+}
+
+func NewAuditHandler(svc *services.AuditService) *AuditHandler {
+	return &AuditHandler{Svc: svc}
+}
+
+// GET /audit-logs?entity_type=&entity_id=&actor_type=&actor_id=&action=&from=&to=&limit=&offset=
+// from/to accept RFC3339 or YYYY-MM-DD (to is exclusive).
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logs, total, err := h.Svc.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit logs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": logs, "total": total})
+}
+
+// GET /audit-logs/export (same filters as ListAuditLogs) - CSV download
+func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filename := fmt.Sprintf("audit-logs-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	if err := h.Svc.ExportCSV(c.Writer, filter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export audit logs"})
+	}
+}
+
+func parseAuditFilter(c *gin.Context) (domain.AuditFilter, error) {
+	filter := domain.AuditFilter{
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		ActorType:  c.Query("actor_type"),
+		Action:     c.Query("action"),
+	}
+	if v := c.Query("actor_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid actor_id")
+		}
+		filter.ActorID = &id
+	}
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(name); v != "" {
+			t, err := parseAuditTime(v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s: use RFC3339 or YYYY-MM-DD", name)
+			}
+			*dst = &t
+		}
+	}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	filter.Offset, _ = strconv.Atoi(c.Query("offset"))
+	return filter, nil
+}
+
+func parseAuditTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
+}
@@ -1,20 +1,25 @@
 package handlers
 
 import (
+	stderrors "errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/phuc/cmms-backend/internal/core/errors"
 	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
 	"github.com/phuc/cmms-backend/internal/domain/dtos"
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
+	authService  *services.AuthService
+	resetService *services.PasswordResetService
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *services.AuthService, resetService *services.PasswordResetService) *AuthHandler {
+	return &AuthHandler{authService: authService, resetService: resetService}
 }
 
 // Register godoc
@@ -35,6 +40,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	err := h.authService.Register(req.Email, req.Password, req.FullName)
+	if stderrors.Is(err, services.ErrWeakPassword) {
+		c.Error(errors.NewAppError(1006, err.Error(), http.StatusBadRequest))
+		return
+	}
 	if err != nil {
 		c.Error(errors.NewAppError(400, err.Error(), http.StatusBadRequest)) // Wrap service error
 		return
@@ -45,7 +54,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 // Login godoc
 // @Summary      Login
-// @Description  Authenticate user and return JWT token
+// @Description  Authenticate user and return JWT token. When the account has two-factor login (or its role requires it) the response carries "mfa_required" and a "challenge" for /auth/2fa/verify instead of tokens.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -53,6 +62,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Success      200  {object}  map[string]interface{}
 // @Failure      400  {object}  map[string]string
 // @Failure      401  {object}  map[string]string
+// @Failure      423  {object}  map[string]string "Account locked after repeated failures"
 // @Router       /users/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req dtos.LoginRequest
@@ -62,27 +72,105 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, user, err := h.authService.Login(req.Email, req.Password)
+	device := domain.DeviceInfo{
+		DeviceID:   req.DeviceID,
+		DeviceName: req.DeviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  c.ClientIP(),
+	}
+	tokens, user, err := h.authService.Login(req.Email, req.Password, device)
+	if abortIfLocked(c, err) {
+		return
+	}
+	var mfa *services.MFARequiredError
+	if stderrors.As(err, &mfa) {
+		// Password accepted; tokens are issued by POST /auth/2fa/verify
+		c.JSON(http.StatusOK, gin.H{
+			"message":             "Two-factor verification required",
+			"mfa_required":        true,
+			"enrollment_required": mfa.Enroll,
+			"challenge":           mfa.Challenge,
+			"expires_in":          mfa.ExpiresIn,
+		})
+		return
+	}
 	if err != nil {
 		c.Error(errors.ErrUnauthorized)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
+	c.JSON(http.StatusOK, loginResponse(tokens, user))
+}
+
+// abortIfLocked answers 423 with Retry-After when err is an account lockout
+func abortIfLocked(c *gin.Context, err error) bool {
+	var locked *services.AccountLockedError
+	if !stderrors.As(err, &locked) {
+		return false
+	}
+	retryAfter := int(time.Until(locked.Until).Seconds()) + 1
+	c.Header("Retry-After", fmt.Sprint(retryAfter))
+	c.Error(errors.NewAppError(1012,
+		fmt.Sprintf("Tài khoản tạm khóa do đăng nhập sai nhiều lần. Thử lại sau %s.", locked.Until.Format("15:04 02/01/2006")),
+		http.StatusLocked))
+	return true
+}
+
+// loginResponse is the body returned once a login is complete (password, and 2FA when required)
+func loginResponse(tokens *services.TokenPair, user *domain.User) gin.H {
+	return gin.H{
+		"message":       "Login successful",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"session_id":    tokens.SessionID,
+		// Admin-created accounts must change their password before using the app
+		"must_change_password": user.MustChangePassword,
 		"user": gin.H{
 			"id":        user.ID,
 			"email":     user.Email,
 			"full_name": user.Name,
 			"role":      user.RoleName,
 		},
+	}
+}
+
+// Refresh godoc
+// @Summary      Refresh tokens
+// @Description  Exchange a refresh token for a new access token and a new refresh token. Each refresh token is single-use; replaying an old one revokes the session.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dtos.RefreshRequest true "Refresh Request"
+// @Success      200  {object}  dtos.AuthResponse
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req dtos.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewAppError(1006, "Invalid input: "+err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	tokens, err := h.authService.Refresh(req.RefreshToken, domain.DeviceInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	})
+	if err != nil {
+		c.Error(errors.ErrUnauthorized)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.AuthResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
 	})
 }
 
 // Logout godoc
 // @Summary      Logout
-// @Description  Logout user and mark as offline
+// @Description  Revoke the current session and mark user as offline
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -94,9 +182,59 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if exists {
 		if id, ok := userID.(string); ok {
-			// Best-effort: update status to offline, ignore error
-			_ = h.authService.Logout(id)
+			// Best-effort: revoke session and update status to offline, ignore error
+			_ = h.authService.Logout(id, c.GetString("session_id"))
 		}
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Email a single-use password reset link. Always succeeds so the response does not reveal whether the email is registered.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dtos.ForgotPasswordRequest true "Forgot Password Request"
+// @Success      200  {object}  map[string]string
+// @Router       /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req dtos.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewAppError(1006, "Invalid input: "+err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	// Mail failures are logged by the service; the answer stays the same either way
+	_ = h.resetService.RequestReset(req.Email, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"message": "Nếu email tồn tại trong hệ thống, liên kết đặt lại mật khẩu đã được gửi."})
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with the emailed token. Unlocks the account and logs it out of every device.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dtos.ResetPasswordRequest true "Reset Password Request"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Router       /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req dtos.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewAppError(1006, "Invalid input: "+err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	err := h.resetService.ResetPassword(req.Token, req.NewPassword)
+	if stderrors.Is(err, services.ErrWeakPassword) || stderrors.Is(err, services.ErrResetTokenInvalid) {
+		c.Error(errors.NewAppError(1006, err.Error(), http.StatusBadRequest))
+		return
+	}
+	if err != nil {
+		c.Error(errors.ErrInternalServer)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Mật khẩu đã được đặt lại. Vui lòng đăng nhập lại."})
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// calendarFeedTTL is how long a subscribed calendar feed link stays valid
+const calendarFeedTTL = 365 * 24 * time.Hour
+
+// AvailabilityHandler serves engineers' time away, their calendars and the
+// iCalendar feeds of their assigns
+type AvailabilityHandler struct {
+	Svc      *services.AvailabilityService
+	ShareSvc *services.ShareLinkService
+}
+
+func NewAvailabilityHandler(svc *services.AvailabilityService, shareSvc *services.ShareLinkService) *AvailabilityHandler {
+	return &AvailabilityHandler{Svc: svc, ShareSvc: shareSvc}
+}
+
+// calendarRange reads ?from=&to= of a calendar query
+func (h *AvailabilityHandler) calendarRange(c *gin.Context) (time.Time, time.Time, bool) {
+	from, to, err := h.Svc.CalendarRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		workflowError(c, err, "Invalid period")
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// GET /availability?user_id=&from=&to= - time away of a user (default: the caller)
+func (h *AvailabilityHandler) ListUnavailability(c *gin.Context) {
+	userID, ok := queryUUID(c, "user_id")
+	if !ok {
+		return
+	}
+	if userID == nil {
+		userID = callerUserID(c)
+	}
+	if userID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+	from, to, ok := h.calendarRange(c)
+	if !ok {
+		return
+	}
+	entries, err := h.Svc.List(*userID, from, to, callerUserID(c), canManageAssigns(c))
+	if err != nil {
+		workflowError(c, err, "Failed to fetch availability")
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// POST /availability
+func (h *AvailabilityHandler) CreateUnavailability(c *gin.Context) {
+	var input services.UnavailabilityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entry, err := h.Svc.Create(input, callerUserID(c), canManageAssigns(c))
+	if err != nil {
+		workflowError(c, err, "Failed to record availability")
+		return
+	}
+	c.JSON(http.StatusCreated, entry)
+}
+
+// PUT /availability/:id
+func (h *AvailabilityHandler) UpdateUnavailability(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var input services.UnavailabilityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entry, err := h.Svc.Update(id, input, callerUserID(c), canManageAssigns(c))
+	if err != nil {
+		workflowError(c, err, "Failed to update availability")
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// DELETE /availability/:id
+func (h *AvailabilityHandler) DeleteUnavailability(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.Svc.Delete(id, callerUserID(c), canManageAssigns(c)); err != nil {
+		workflowError(c, err, "Failed to delete availability")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Availability deleted"})
+}
+
+// GET /users/:id/calendar?from=&to= - the user's assigns and time away
+func (h *AvailabilityHandler) GetUserCalendar(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	from, to, ok := h.calendarRange(c)
+	if !ok {
+		return
+	}
+	schedule, err := h.Svc.UserCalendar(id, from, to, callerUserID(c), canManageAssigns(c))
+	if err != nil {
+		workflowError(c, err, "Failed to fetch calendar")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "calendar": schedule})
+}
+
+// GET /teams/:id/calendar?from=&to= - the calendar of every member of the team
+func (h *AvailabilityHandler) GetTeamCalendar(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	from, to, ok := h.calendarRange(c)
+	if !ok {
+		return
+	}
+	schedules, err := h.Svc.TeamCalendar(id, from, to)
+	if err != nil {
+		workflowError(c, err, "Failed to fetch team calendar")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "calendars": schedules})
+}
+
+// GET /users/:id/calendar.ics - download the user's assigns as iCalendar
+func (h *AvailabilityHandler) DownloadUserFeed(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if caller := callerUserID(c); (caller == nil || *caller != id) && !canManageAssigns(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only managers may read someone else's calendar"})
+		return
+	}
+	h.serveFeed(c, id)
+}
+
+// POST /users/:id/calendar/feed - issue a link calendar apps can subscribe to
+// (see GetPublicFeed); revoked like any other share link
+func (h *AvailabilityHandler) CreateFeedLink(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	caller := callerUserID(c)
+	if (caller == nil || *caller != id) && !canManageAssigns(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only managers may share someone else's calendar"})
+		return
+	}
+	link, token, err := h.ShareSvc.Issue(domain.ShareUserCalendar, id.String(), nil, calendarFeedTTL, caller)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         link.ID,
+		"token":      token,
+		"path":       "/api/public/calendar/" + id.String() + "?token=" + token,
+		"expires_at": link.ExpiresAt,
+	})
+}
+
+// GET /public/calendar/:id?token= - the subscribed iCalendar feed
+func (h *AvailabilityHandler) GetPublicFeed(c *gin.Context) {
+	if claims := shareClaims(c); claims == nil || !claims.Grants(domain.ShareUserCalendar, c.Param("id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This link does not grant access to this calendar"})
+		return
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	h.serveFeed(c, id)
+}
+
+func (h *AvailabilityHandler) serveFeed(c *gin.Context, userID uuid.UUID) {
+	feed, err := h.Svc.Feed(userID)
+	if err != nil {
+		workflowError(c, err, "Failed to build calendar")
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="assigns-`+userID.String()+`.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", feed)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phuc/cmms-backend/internal/core/services"
+)
+
+// CertificationHandler serves the certification catalog, the certifications
+// engineers hold and those works require
+type CertificationHandler struct {
+	Svc *services.CertificationService
+}
+
+func NewCertificationHandler(svc *services.CertificationService) *CertificationHandler {
+	return &CertificationHandler{Svc: svc}
+}
+
+// GET /certifications
+func (h *CertificationHandler) ListCertifications(c *gin.Context) {
+	certs, err := h.Svc.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch certifications"})
+		return
+	}
+	c.JSON(http.StatusOK, certs)
+}
+
+// POST /certifications
+func (h *CertificationHandler) CreateCertification(c *gin.Context) {
+	var input services.CertificationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cert, err := h.Svc.Create(input)
+	if err != nil {
+		workflowError(c, err, "Failed to create certification")
+		return
+	}
+	c.JSON(http.StatusCreated, cert)
+}
+
+// DELETE /certifications/:id
+func (h *CertificationHandler) DeleteCertification(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.Svc.Delete(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete certification"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Certification deleted"})
+}
+
+// GET /users/:id/certifications
+func (h *CertificationHandler) ListUserCertifications(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	certs, err := h.Svc.UserCertifications(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch certifications"})
+		return
+	}
+	c.JSON(http.StatusOK, certs)
+}
+
+// PUT /users/:id/certifications/:cert_id - grant a certification, or change its dates
+func (h *CertificationHandler) GrantUserCertification(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	certID, ok := parseIDParam(c, "cert_id")
+	if !ok {
+		return
+	}
+	var input services.UserCertificationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cert, err := h.Svc.Grant(id, certID, input)
+	if err != nil {
+		workflowError(c, err, "Failed to grant certification")
+		return
+	}
+	c.JSON(http.StatusOK, cert)
+}
+
+// DELETE /users/:id/certifications/:cert_id
+func (h *CertificationHandler) RevokeUserCertification(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	certID, ok := parseIDParam(c, "cert_id")
+	if !ok {
+		return
+	}
+	if err := h.Svc.Revoke(id, certID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke certification"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Certification revoked"})
+}
+
+// GET /works/:id/certifications
+func (h *CertificationHandler) GetWorkRequirements(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	reqs, err := h.Svc.WorkRequirements(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch required certifications"})
+		return
+	}
+	c.JSON(http.StatusOK, reqs)
+}
+
+// PUT /works/:id/certifications - replace the certifications the work requires
+func (h *CertificationHandler) SetWorkRequirements(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var input services.WorkRequirementsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reqs, err := h.Svc.SetWorkRequirements(id, input)
+	if err != nil {
+		workflowError(c, err, "Failed to set required certifications")
+		return
+	}
+	c.JSON(http.StatusOK, reqs)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
+)
+
+// CommentHandler serves the discussion threads of assigns and tasks
+type CommentHandler struct {
+	Svc *services.CommentService
+}
+
+func NewCommentHandler(svc *services.CommentService) *CommentHandler {
+	return &CommentHandler{Svc: svc}
+}
+
+// threadParam reads the thread of an /assigns/:id/... or /details/:id/... route
+func threadParam(c *gin.Context, task bool) (services.CommentThread, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return services.CommentThread{}, false
+	}
+	if task {
+		return services.CommentThread{DetailID: &id}, true
+	}
+	return services.CommentThread{AssignID: id}, true
+}
+
+// GET /assigns/:id/comments
+func (h *CommentHandler) ListAssignComments(c *gin.Context) { h.list(c, false) }
+
+// GET /details/:id/comments
+func (h *CommentHandler) ListDetailComments(c *gin.Context) { h.list(c, true) }
+
+func (h *CommentHandler) list(c *gin.Context, task bool) {
+	thread, ok := threadParam(c, task)
+	if !ok {
+		return
+	}
+	comments, err := h.Svc.Thread(thread, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to fetch comments")
+		return
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+// POST /assigns/:id/comments
+func (h *CommentHandler) PostAssignComment(c *gin.Context) { h.post(c, false) }
+
+// POST /details/:id/comments
+func (h *CommentHandler) PostDetailComment(c *gin.Context) { h.post(c, true) }
+
+// post takes JSON, or a multipart form with the fields body and id_mentions
+// (repeated) and the files as "attachments"
+func (h *CommentHandler) post(c *gin.Context, task bool) {
+	thread, ok := threadParam(c, task)
+	if !ok {
+		return
+	}
+
+	var input services.CommentInput
+	var files []services.CommentFile
+	if c.ContentType() == "multipart/form-data" {
+		form, err := c.MultipartForm()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form"})
+			return
+		}
+		input.Body = c.PostForm("body")
+		for _, raw := range c.PostFormArray("id_mentions") {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id_mentions"})
+				return
+			}
+			input.MentionIDs = append(input.MentionIDs, id)
+		}
+		for _, fh := range form.File["attachments"] {
+			f, err := fh.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read " + fh.Filename})
+				return
+			}
+			defer f.Close()
+			files = append(files, services.CommentFile{Name: fh.Filename, Size: fh.Size, Reader: f})
+		}
+	} else if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.Svc.Post(thread, input, files, callerUserID(c), visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to post comment")
+		return
+	}
+	c.JSON(http.StatusCreated, comment)
+}
+
+// PUT /comments/:id - edit one's own comment
+func (h *CommentHandler) EditComment(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var input services.CommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	comment, err := h.Svc.Edit(id, input, callerUserID(c))
+	if err != nil {
+		workflowError(c, err, "Failed to edit comment")
+		return
+	}
+	c.JSON(http.StatusOK, comment)
+}
+
+// DELETE /comments/:id
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	comment, err := h.Svc.Delete(id, callerUserID(c), canManageAssigns(c))
+	if err != nil {
+		workflowError(c, err, "Failed to delete comment")
+		return
+	}
+	c.JSON(http.StatusOK, comment)
+}
+
+// GET /comments/:id/history - former contents, oldest first
+func (h *CommentHandler) GetCommentHistory(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	revisions, err := h.Svc.History(id, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to fetch comment history")
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
+// POST /assigns/:id/comments/read
+func (h *CommentHandler) MarkAssignRead(c *gin.Context) { h.markRead(c, false) }
+
+// POST /details/:id/comments/read
+func (h *CommentHandler) MarkDetailRead(c *gin.Context) { h.markRead(c, true) }
+
+func (h *CommentHandler) markRead(c *gin.Context, task bool) {
+	thread, ok := threadParam(c, task)
+	if !ok {
+		return
+	}
+	if err := h.Svc.MarkRead(thread, callerUserID(c), visibilityScope(c)); err != nil {
+		workflowError(c, err, "Failed to mark comments read")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Marked as read"})
+}
+
+// GET /comments/unread?assign_id= - the caller's unread comments per thread
+func (h *CommentHandler) GetUnread(c *gin.Context) {
+	assignID, ok := queryUUID(c, "assign_id")
+	if !ok {
+		return
+	}
+	threads, err := h.Svc.Unread(callerUserID(c), assignID)
+	if err != nil {
+		workflowError(c, err, "Failed to count unread comments")
+		return
+	}
+	var total int64
+	for _, t := range threads {
+		total += t.Unread
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "threads": threads})
+}
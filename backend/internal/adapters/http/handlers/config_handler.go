@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -77,17 +79,29 @@ func (h *ConfigHandler) GetConfig(c *gin.Context) {
 // POST /configs
 func (h *ConfigHandler) CreateConfig(c *gin.Context) {
 	var body struct {
-		AssetID             string   `json:"id_asset" binding:"required"`
-		SubWorkID           string   `json:"id_sub_work" binding:"required"`
-		StatusSetImageCount bool     `json:"status_set_image_count"`
-		ImageCount          int      `json:"image_count"`
-		GuideText           string   `json:"guide_text"`
-		GuideImages         []string `json:"guide_images"`
+		AssetID             string                    `json:"id_asset" binding:"required"`
+		SubWorkID           string                    `json:"id_sub_work" binding:"required"`
+		StatusSetImageCount bool                      `json:"status_set_image_count"`
+		ImageCount          int                       `json:"image_count"`
+		GuideText           string                    `json:"guide_text"`
+		GuideImages         []string                  `json:"guide_images"`
+		EvidenceSlots       []domain.EvidenceSlot     `json:"evidence_slots"`
+		MeasurementFields   []domain.MeasurementField `json:"measurement_fields"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	slotsJSON, err := evidenceSlotsJSON(body.EvidenceSlots)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fieldsJSON, err := measurementFieldsJSON(body.MeasurementFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	assetID, err := uuid.Parse(body.AssetID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id_asset"})
@@ -99,7 +113,6 @@ func (h *ConfigHandler) CreateConfig(c *gin.Context) {
 		return
 	}
 
-
 	guideImagesJSON, _ := json.Marshal(body.GuideImages)
 	if len(body.GuideImages) == 0 {
 		guideImagesJSON = []byte("[]")
@@ -113,6 +126,8 @@ func (h *ConfigHandler) CreateConfig(c *gin.Context) {
 		ImageCount:          body.ImageCount,
 		GuideText:           body.GuideText,
 		GuideImages:         datatypes.JSON(guideImagesJSON),
+		EvidenceSlots:       slotsJSON,
+		MeasurementFields:   fieldsJSON,
 	}
 	if err := h.configRepo.Create(config); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create config"})
@@ -138,16 +153,34 @@ func (h *ConfigHandler) UpdateConfig(c *gin.Context) {
 	}
 
 	var body struct {
-		StatusSetImageCount *bool    `json:"status_set_image_count"`
-		ImageCount          *int     `json:"image_count"`
-		GuideText           *string  `json:"guide_text"`
-		GuideImages         []string `json:"guide_images"`
+		StatusSetImageCount *bool                     `json:"status_set_image_count"`
+		ImageCount          *int                      `json:"image_count"`
+		GuideText           *string                   `json:"guide_text"`
+		GuideImages         []string                  `json:"guide_images"`
+		EvidenceSlots       []domain.EvidenceSlot     `json:"evidence_slots"`     // nil = unchanged, [] = none
+		MeasurementFields   []domain.MeasurementField `json:"measurement_fields"` // nil = unchanged, [] = use the sub-work's
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if body.EvidenceSlots != nil {
+		slotsJSON, err := evidenceSlotsJSON(body.EvidenceSlots)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		config.EvidenceSlots = slotsJSON
+	}
+	if body.MeasurementFields != nil {
+		fieldsJSON, err := measurementFieldsJSON(body.MeasurementFields)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		config.MeasurementFields = fieldsJSON
+	}
 	if body.GuideImages != nil {
 		b, _ := json.Marshal(body.GuideImages)
 		config.GuideImages = datatypes.JSON(b)
@@ -184,3 +217,65 @@ func (h *ConfigHandler) DeleteConfig(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Config deleted"})
 }
+
+// evidenceSlotsJSON validates named evidence slots: unique non-empty keys,
+// 0 <= min <= max (max 0 = unlimited) and known media types.
+func evidenceSlotsJSON(slots []domain.EvidenceSlot) (datatypes.JSON, error) {
+	seen := make(map[string]bool)
+	for i := range slots {
+		s := &slots[i]
+		s.Key = strings.TrimSpace(s.Key)
+		if s.Key == "" || s.Key == domain.LegacySlotKey || seen[s.Key] {
+			return nil, fmt.Errorf("evidence slot %d: key must be unique and not %q", i+1, domain.LegacySlotKey)
+		}
+		seen[s.Key] = true
+		if s.Name == "" {
+			s.Name = s.Key
+		}
+		if s.Min < 0 || s.Max < 0 || (s.Max > 0 && s.Min > s.Max) {
+			return nil, fmt.Errorf("evidence slot %q: invalid min/max", s.Key)
+		}
+		for _, t := range s.MediaTypes {
+			if t != domain.MediaImage && t != domain.MediaVideo {
+				return nil, fmt.Errorf("evidence slot %q: unknown media type %q", s.Key, t)
+			}
+		}
+	}
+	if slots == nil {
+		slots = []domain.EvidenceSlot{}
+	}
+	b, _ := json.Marshal(slots)
+	return datatypes.JSON(b), nil
+}
+
+// maxMeasurementPrecision bounds the decimals a measurement field may keep
+const maxMeasurementPrecision = 6
+
+// measurementFieldsJSON validates measurement fields: unique non-empty keys,
+// min <= max and a precision between 0 and maxMeasurementPrecision. Configs
+// and sub-works share it.
+func measurementFieldsJSON(fields []domain.MeasurementField) (datatypes.JSON, error) {
+	seen := make(map[string]bool)
+	for i := range fields {
+		f := &fields[i]
+		f.Key = strings.TrimSpace(f.Key)
+		if f.Key == "" || seen[f.Key] {
+			return nil, fmt.Errorf("measurement field %d: key must be unique and non-empty", i+1)
+		}
+		seen[f.Key] = true
+		if f.Label == "" {
+			f.Label = f.Key
+		}
+		if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+			return nil, fmt.Errorf("measurement field %q: min is above max", f.Key)
+		}
+		if f.Precision < 0 || f.Precision > maxMeasurementPrecision {
+			return nil, fmt.Errorf("measurement field %q: precision must be 0-%d", f.Key, maxMeasurementPrecision)
+		}
+	}
+	if fields == nil {
+		fields = []domain.MeasurementField{}
+	}
+	b, _ := json.Marshal(fields)
+	return datatypes.JSON(b), nil
+}
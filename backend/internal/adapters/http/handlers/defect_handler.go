@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// DefectHandler manages defects raised on assets and their conversion into
+// corrective assigns
+type DefectHandler struct {
+	Svc *services.DefectService
+}
+
+func NewDefectHandler(svc *services.DefectService) *DefectHandler {
+	return &DefectHandler{Svc: svc}
+}
+
+type DefectStatusRequest struct {
+	Status domain.DefectStatus `json:"status" binding:"required"`
+	Note   string              `json:"note"`
+}
+
+// queryUUID parses an optional UUID query parameter, answering 400 when it is malformed
+func queryUUID(c *gin.Context, name string) (*uuid.UUID, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return nil, false
+	}
+	return &id, true
+}
+
+// GET /defects?project_id=&asset_id=&assignee_id=&status=&severity=
+func (h *DefectHandler) ListDefects(c *gin.Context) {
+	filter := domain.DefectFilter{
+		Status:   domain.DefectStatus(c.Query("status")),
+		Severity: domain.DefectSeverity(c.Query("severity")),
+	}
+	var ok bool
+	if filter.ProjectID, ok = queryUUID(c, "project_id"); !ok {
+		return
+	}
+	if filter.AssetID, ok = queryUUID(c, "asset_id"); !ok {
+		return
+	}
+	if filter.AssigneeID, ok = queryUUID(c, "assignee_id"); !ok {
+		return
+	}
+	h.list(c, filter)
+}
+
+// GET /assets/:id/defects - the asset's defect history, with the corrective
+// assign that closed each one
+func (h *DefectHandler) ListAssetDefects(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	h.list(c, domain.DefectFilter{AssetID: &id})
+}
+
+func (h *DefectHandler) list(c *gin.Context, filter domain.DefectFilter) {
+	defects, err := h.Svc.List(filter, visibilityScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch defects"})
+		return
+	}
+	c.JSON(http.StatusOK, defects)
+}
+
+// GET /defects/:id
+func (h *DefectHandler) GetDefect(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	defect, err := h.Svc.Get(id, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to fetch defect")
+		return
+	}
+	c.JSON(http.StatusOK, defect)
+}
+
+// POST /defects
+func (h *DefectHandler) CreateDefect(c *gin.Context) {
+	var input services.DefectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defect, err := h.Svc.Create(input, callerUserID(c), visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to create defect")
+		return
+	}
+	c.JSON(http.StatusCreated, defect)
+}
+
+// PUT /defects/:id
+func (h *DefectHandler) UpdateDefect(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var input services.DefectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defect, err := h.Svc.Update(id, input, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to update defect")
+		return
+	}
+	c.JSON(http.StatusOK, defect)
+}
+
+// POST /defects/:id/status
+func (h *DefectHandler) ChangeStatus(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req DefectStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defect, err := h.Svc.ChangeStatus(id, req.Status, req.Note, callerUserID(c), canManageAssigns(c), visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to change defect status")
+		return
+	}
+	c.JSON(http.StatusOK, defect)
+}
+
+// POST /defects/:id/convert - create the corrective assign
+func (h *DefectHandler) ConvertDefect(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var input services.ConvertDefectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defect, err := h.Svc.Convert(id, input, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to convert defect")
+		return
+	}
+	c.JSON(http.StatusCreated, defect)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phuc/cmms-backend/internal/core/services"
+)
+
+// DependencyHandler manages the step order between the configs of a template
+type DependencyHandler struct {
+	Svc *services.DependencyService
+}
+
+func NewDependencyHandler(svc *services.DependencyService) *DependencyHandler {
+	return &DependencyHandler{Svc: svc}
+}
+
+type DependenciesRequest struct {
+	Dependencies []services.DependencyInput `json:"dependencies" binding:"dive"`
+}
+
+// GET /templates/:id/dependencies
+func (h *DependencyHandler) GetTemplateDependencies(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	deps, err := h.Svc.GetTemplateDependencies(id)
+	if err != nil {
+		workflowError(c, err, "Failed to fetch dependencies")
+		return
+	}
+	c.JSON(http.StatusOK, deps)
+}
+
+// PUT /templates/:id/dependencies - replace the template's step order; an
+// empty list lets its tasks be done in any order
+func (h *DependencyHandler) SetTemplateDependencies(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req DependenciesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	deps, err := h.Svc.SetTemplateDependencies(id, req.Dependencies)
+	if err != nil {
+		workflowError(c, err, "Failed to save dependencies")
+		return
+	}
+	c.JSON(http.StatusOK, deps)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// detailVersion reads the task version the client last saw, from the If-Match
+// header ("3" or W/"3") or else the version field of the body. Workflow writes
+// require one; without it the request is refused with 428.
+func detailVersion(c *gin.Context, bodyVersion *int) (int, bool) {
+	if tag := c.GetHeader("If-Match"); tag != "" {
+		tag = strings.Trim(strings.TrimPrefix(tag, "W/"), `"`)
+		if v, err := strconv.Atoi(tag); err == nil {
+			return v, true
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match must be a task version"})
+		return 0, false
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, true
+	}
+	c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header or version is required"})
+	return 0, false
+}
+
+// respondDetail returns a task with its version as ETag, for the next If-Match.
+func respondDetail(c *gin.Context, detail *domain.DetailAssign) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(detail.Version)))
+	c.JSON(http.StatusOK, detail)
+}
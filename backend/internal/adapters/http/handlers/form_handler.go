@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// FormHandler manages the versioned checklist forms of sub-works and configs
+type FormHandler struct {
+	Svc *services.FormService
+}
+
+func NewFormHandler(svc *services.FormService) *FormHandler {
+	return &FormHandler{Svc: svc}
+}
+
+type FormVersionRequest struct {
+	Fields []domain.FormField `json:"fields"`
+}
+
+// GET /sub-works/:id/forms - every version, newest first
+func (h *FormHandler) ListSubWorkForms(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	h.list(c, &id, nil)
+}
+
+// GET /configs/:id/forms - the config's own versions, newest first
+func (h *FormHandler) ListConfigForms(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	h.list(c, nil, &id)
+}
+
+func (h *FormHandler) list(c *gin.Context, subWorkID, configID *uuid.UUID) {
+	forms, err := h.Svc.Versions(subWorkID, configID)
+	if err != nil {
+		workflowError(c, err, "Failed to fetch forms")
+		return
+	}
+	c.JSON(http.StatusOK, forms)
+}
+
+// POST /sub-works/:id/forms - publish the next version of the form
+func (h *FormHandler) CreateSubWorkForm(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	h.create(c, &id, nil)
+}
+
+// POST /configs/:id/forms - override the sub-work's form for one asset; an
+// empty version goes back to the sub-work's
+func (h *FormHandler) CreateConfigForm(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	h.create(c, nil, &id)
+}
+
+func (h *FormHandler) create(c *gin.Context, subWorkID, configID *uuid.UUID) {
+	var req FormVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	form, err := h.Svc.CreateVersion(subWorkID, configID, req.Fields, callerUserID(c))
+	if err != nil {
+		workflowError(c, err, "Failed to save form")
+		return
+	}
+	c.JSON(http.StatusCreated, form)
+}
+
+// GET /forms/:id - one version, e.g. the one a task was filled against
+func (h *FormHandler) GetForm(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	form, err := h.Svc.Get(id)
+	if err != nil {
+		workflowError(c, err, "Failed to fetch form")
+		return
+	}
+	c.JSON(http.StatusOK, form)
+}
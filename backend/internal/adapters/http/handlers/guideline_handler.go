@@ -26,7 +26,7 @@ func (h *GuideLineHandler) GetBySubWorkID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sub_work ID"})
 		return
 	}
-	g, err := h.repo.FindBySubWorkID(subWorkID)
+	g, err := h.repo.FindBySubWorkID(subWorkID, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch guideline"})
 		return
@@ -81,6 +81,6 @@ func (h *GuideLineHandler) Upsert(c *gin.Context) {
 		return
 	}
 
-	result, _ := h.repo.FindBySubWorkID(subWorkID)
+	result, _ := h.repo.FindBySubWorkID(subWorkID, visibilityScope(c))
 	c.JSON(http.StatusOK, result)
 }
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// HandoverHandler moves tasks between engineers and reports who held them when
+type HandoverHandler struct {
+	Svc *services.HandoverService
+}
+
+func NewHandoverHandler(svc *services.HandoverService) *HandoverHandler {
+	return &HandoverHandler{Svc: svc}
+}
+
+// canManageAssigns reports whether the caller may act on other people's tasks
+func canManageAssigns(c *gin.Context) bool {
+	for _, perm := range c.GetStringSlice("permissions") {
+		if perm == domain.PermAssignManage {
+			return true
+		}
+	}
+	return false
+}
+
+// POST /details/:id/reassign
+func (h *HandoverHandler) ReassignDetail(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var input services.HandoverInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	detail, err := h.Svc.Reassign(id, input, callerUserID(c), visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to reassign task")
+		return
+	}
+	respondDetail(c, detail)
+}
+
+// POST /details/:id/handover
+func (h *HandoverHandler) HandoverDetail(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var input services.HandoverInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	detail, err := h.Svc.Handover(id, input, callerUserID(c), canManageAssigns(c), visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to hand over task")
+		return
+	}
+	respondDetail(c, detail)
+}
+
+// GET /details/:id/owners - ownership history of the task, oldest first
+func (h *HandoverHandler) GetDetailOwners(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	handovers, err := h.Svc.History(id, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to fetch ownership history")
+		return
+	}
+	c.JSON(http.StatusOK, handovers)
+}
+
+// POST /assigns/:id/handover - hand over everything left to do on the assign
+func (h *HandoverHandler) HandoverAssign(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var input services.AssignHandoverInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	handovers, err := h.Svc.HandoverAssign(id, input, callerUserID(c), canManageAssigns(c), visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to hand over assign")
+		return
+	}
+	c.JSON(http.StatusOK, handovers)
+}
+
+// GET /assigns/:id/handovers
+func (h *HandoverHandler) GetAssignHandovers(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	handovers, err := h.Svc.AssignHistory(id, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to fetch handovers")
+		return
+	}
+	c.JSON(http.StatusOK, handovers)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
+)
+
+// MaintenancePlanHandler manages preventive maintenance plans, whose
+// occurrences the scheduler turns into assigns
+type MaintenancePlanHandler struct {
+	Svc *services.MaintenancePlanService
+}
+
+func NewMaintenancePlanHandler(svc *services.MaintenancePlanService) *MaintenancePlanHandler {
+	return &MaintenancePlanHandler{Svc: svc}
+}
+
+type SkipOccurrenceRequest struct {
+	DueAt time.Time `json:"due_at" binding:"required"`
+}
+
+// GET /maintenance-plans?project_id=
+func (h *MaintenancePlanHandler) ListPlans(c *gin.Context) {
+	var projectID *uuid.UUID
+	if raw := c.Query("project_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project_id"})
+			return
+		}
+		projectID = &id
+	}
+	plans, err := h.Svc.List(projectID, visibilityScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch maintenance plans"})
+		return
+	}
+	c.JSON(http.StatusOK, plans)
+}
+
+// GET /maintenance-plans/:id
+func (h *MaintenancePlanHandler) GetPlan(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	plan, err := h.Svc.Get(id, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to fetch maintenance plan")
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// POST /maintenance-plans
+func (h *MaintenancePlanHandler) CreatePlan(c *gin.Context) {
+	var input services.MaintenancePlanInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	plan, err := h.Svc.Create(input, callerUserID(c), visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to create maintenance plan")
+		return
+	}
+	c.JSON(http.StatusCreated, plan)
+}
+
+// PUT /maintenance-plans/:id
+func (h *MaintenancePlanHandler) UpdatePlan(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var input services.MaintenancePlanInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	plan, err := h.Svc.Update(id, input, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to update maintenance plan")
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// DELETE /maintenance-plans/:id
+// Assigns already generated are kept.
+func (h *MaintenancePlanHandler) DeletePlan(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.Svc.Delete(id, visibilityScope(c)); err != nil {
+		workflowError(c, err, "Failed to delete maintenance plan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance plan deleted"})
+}
+
+// GET /maintenance-plans/:id/preview?count=10
+func (h *MaintenancePlanHandler) PreviewPlan(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	count, _ := strconv.Atoi(c.Query("count"))
+	occurrences, err := h.Svc.Preview(id, count, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to preview maintenance plan")
+		return
+	}
+	c.JSON(http.StatusOK, occurrences)
+}
+
+// POST /maintenance-plans/:id/skip
+func (h *MaintenancePlanHandler) SkipOccurrence(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req SkipOccurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.Svc.Skip(id, req.DueAt, visibilityScope(c)); err != nil {
+		workflowError(c, err, "Failed to skip occurrence")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Occurrence skipped", "due_at": req.DueAt})
+}
+
+// POST /maintenance-plans/:id/pause
+func (h *MaintenancePlanHandler) PausePlan(c *gin.Context) {
+	h.setPaused(c, true)
+}
+
+// POST /maintenance-plans/:id/resume
+func (h *MaintenancePlanHandler) ResumePlan(c *gin.Context) {
+	h.setPaused(c, false)
+}
+
+func (h *MaintenancePlanHandler) setPaused(c *gin.Context, paused bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	plan, err := h.Svc.SetPaused(id, paused, visibilityScope(c))
+	if err != nil {
+		workflowError(c, err, "Failed to update maintenance plan")
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
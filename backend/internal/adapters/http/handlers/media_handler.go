@@ -14,20 +14,56 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
 	"github.com/phuc/cmms-backend/internal/infrastructure/storage"
 	"golang.org/x/text/unicode/norm"
 )
 
 type MediaHandler struct {
 	MinioClient *storage.MinioClient
+	details     domain.DetailAssignRepository // Resolves which report links reach an object
 }
 
-func NewMediaHandler(minioClient *storage.MinioClient) *MediaHandler {
+func NewMediaHandler(minioClient *storage.MinioClient, details domain.DetailAssignRepository) *MediaHandler {
 	return &MediaHandler{
 		MinioClient: minioClient,
+		details:     details,
 	}
 }
 
+// shareCoversKey reports whether share claims reach an object: a media folder
+// link through its prefix and signed filters, a report link through the
+// evidence of its assign's tasks.
+func (h *MediaHandler) shareCoversKey(claims *domain.ShareClaims, key string) bool {
+	switch claims.ResourceType {
+	case domain.ShareMediaFolder:
+		return claims.GrantsMediaPrefix(key) && matchesSignedFilters(claims, key)
+	case domain.ShareAssignReport, domain.ShareGenericReport:
+		assignID := claims.ResourceID
+		if claims.ResourceType == domain.ShareGenericReport {
+			assignID = claims.Filters[domain.ShareFilterAssign]
+		}
+		id, err := uuid.Parse(assignID)
+		if err != nil || h.details == nil {
+			return false
+		}
+		found, err := h.details.HasMedia(id, key)
+		return err == nil && found
+	}
+	return false
+}
+
+// matchesSignedFilters applies the path fragments signed into a media folder link
+func matchesSignedFilters(claims *domain.ShareClaims, key string) bool {
+	for _, f := range strings.Split(claims.Filters[domain.ShareFilterMedia], ",") {
+		if f != "" && !strings.Contains(key, f) {
+			return false
+		}
+	}
+	return true
+}
+
 // GetLibraryImages returns a list of all images in the MinIO bucket
 // @Summary      Get Image Library
 // @Description  Get list of all images available in MinIO storage
@@ -59,7 +95,17 @@ func (h *MediaHandler) PublicGetLibraryImages(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Prefix is required for public access"})
 		return
 	}
-	urls, err := h.MinioClient.ListObjectKeys(prefix)
+	claims := shareClaims(c)
+	if claims == nil || !claims.GrantsMediaPrefix(prefix) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Share link does not cover this folder"})
+		return
+	}
+	// Filters signed into the link always apply on top of the requested ones
+	if signed := claims.Filters[domain.ShareFilterMedia]; signed != "" {
+		filters = append(filters, strings.Split(signed, ",")...)
+	}
+	// List as a folder so "proj" cannot reach "proj-other/..."
+	urls, err := h.MinioClient.ListObjectKeys(strings.TrimSuffix(prefix, "/") + "/")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list images"})
 		return
@@ -82,11 +128,13 @@ func (h *MediaHandler) PublicGetLibraryImages(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": filtered})
 }
 
-// ProxyImage streams an image from MinIO via the backend
+// ProxyImage streams an image from MinIO via the backend.
+// Open to signed-in users and to share links covering the key.
 // @Summary      Proxy Image
 // @Description  Stream image from MinIO by key
 // @Tags         media
 // @Param        key  query     string  true  "Object Key"
+// @Param        token  query   string  false "Share token of a public page embedding the image"
 // @Success      200  {file}    binary
 // @Router       /media/proxy [get]
 func (h *MediaHandler) ProxyImage(c *gin.Context) {
@@ -103,6 +151,11 @@ func (h *MediaHandler) ProxyImage(c *gin.Context) {
 		decodedKey = key
 	}
 
+	if claims := shareClaims(c); claims != nil && !h.shareCoversKey(claims, decodedKey) && !h.shareCoversKey(claims, key) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Share link does not cover this file"})
+		return
+	}
+
 	log.Printf("[ProxyImage] Original key: %s", key)
 	log.Printf("[ProxyImage] Decoded key: %s", decodedKey)
 
@@ -233,7 +286,8 @@ func (h *MediaHandler) DeleteFolder(c *gin.Context) {
 	})
 }
 
-// DownloadFolder zips and downloads a folder from MinIO
+// DownloadFolder zips and downloads a folder from MinIO.
+// Open to signed-in users and to media folder share links covering the prefix.
 // @Summary      Download Folder as Zip
 // @Description  Download a folder from MinIO as a Zip archive
 // @Tags         media
@@ -246,6 +300,15 @@ func (h *MediaHandler) DownloadFolder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Prefix is required"})
 		return
 	}
+	claims := shareClaims(c)
+	if claims != nil {
+		if !claims.GrantsMediaPrefix(prefix) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Share link does not cover this folder"})
+			return
+		}
+		// List as a folder so "proj" cannot reach "proj-other/..."
+		prefix = strings.TrimSuffix(prefix, "/") + "/"
+	}
 
 	c.Header("Content-Type", "application/zip")
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", filepath.Base(filepath.Clean(prefix))))
@@ -262,8 +325,8 @@ func (h *MediaHandler) DownloadFolder(c *gin.Context) {
 	}
 
 	for _, key := range keys {
-		// Skip directory markers
-		if strings.HasSuffix(key, "/") {
+		// Skip directory markers and, for share links, keys outside the signed filters
+		if strings.HasSuffix(key, "/") || (claims != nil && !matchesSignedFilters(claims, key)) {
 			continue
 		}
 
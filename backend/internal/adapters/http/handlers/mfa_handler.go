@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/domain/dtos"
+)
+
+// MFAHandler serves the second login step and two-factor self-service
+type MFAHandler struct {
+	authService *services.AuthService
+	mfa         *services.MFAService
+}
+
+func NewMFAHandler(authService *services.AuthService, mfa *services.MFAService) *MFAHandler {
+	return &MFAHandler{authService: authService, mfa: mfa}
+}
+
+// VerifyLogin godoc
+// @Summary      Complete a two-factor login
+// @Description  Exchange the login challenge and a TOTP or recovery code for tokens. For an enrolment challenge the code confirms the new authenticator and the response includes one-time "recovery_codes".
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dtos.MFAVerifyRequest true "Verify Request"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      423  {object}  map[string]string "Account locked after repeated failures"
+// @Router       /auth/2fa/verify [post]
+func (h *MFAHandler) VerifyLogin(c *gin.Context) {
+	var req dtos.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewAppError(1006, "Invalid input: "+err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	device := domain.DeviceInfo{
+		DeviceID:   req.DeviceID,
+		DeviceName: req.DeviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  c.ClientIP(),
+	}
+	tokens, user, recoveryCodes, err := h.authService.VerifyMFA(req.Challenge, req.Code, device)
+	if abortIfLocked(c, err) {
+		return
+	}
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	resp := loginResponse(tokens, user)
+	if recoveryCodes != nil {
+		resp["recovery_codes"] = recoveryCodes
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// EnrollLogin godoc
+// @Summary      Start 2FA enrolment during login
+// @Description  For a login challenge with "enrollment_required", generate the authenticator secret. Confirm it with /auth/2fa/verify.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dtos.MFAChallengeRequest true "Challenge"
+// @Success      200  {object}  services.MFAEnrollment
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/2fa/enroll [post]
+func (h *MFAHandler) EnrollLogin(c *gin.Context) {
+	var req dtos.MFAChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewAppError(1006, "Invalid input: "+err.Error(), http.StatusBadRequest))
+		return
+	}
+	enrollment, err := h.authService.BeginMFAEnrollment(req.Challenge)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// GET /auth/2fa - two-factor status of the caller
+func (h *MFAHandler) Status(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	status, err := h.mfa.Status(userID)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// POST /auth/2fa/setup - start optional enrolment; confirm with POST /auth/2fa/enable
+func (h *MFAHandler) Setup(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	enrollment, err := h.mfa.BeginEnrollment(userID)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// POST /auth/2fa/enable - confirm enrolment with a code; returns the recovery codes once
+func (h *MFAHandler) Enable(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req dtos.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewAppError(1006, "Invalid input: "+err.Error(), http.StatusBadRequest))
+		return
+	}
+	codes, err := h.mfa.ConfirmEnrollment(userID, req.Code)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã bật xác thực hai lớp", "recovery_codes": codes})
+}
+
+// POST /auth/2fa/recovery-codes - replace the recovery codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req dtos.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewAppError(1006, "Invalid input: "+err.Error(), http.StatusBadRequest))
+		return
+	}
+	codes, err := h.mfa.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// POST /auth/2fa/disable - opt out (not allowed when the role requires 2FA)
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req dtos.MFADisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewAppError(1006, "Invalid input: "+err.Error(), http.StatusBadRequest))
+		return
+	}
+	if err := h.mfa.Disable(userID, req.Password, req.Code); err != nil {
+		h.mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã tắt xác thực hai lớp"})
+}
+
+// DELETE /admin/users/:id/2fa - clear a user's enrolment after a lost device
+func (h *MFAHandler) ResetUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if err := h.mfa.Reset(userID, c.GetString("role")); err != nil {
+		h.mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor login reset"})
+}
+
+func (h *MFAHandler) mfaError(c *gin.Context, err error) {
+	switch {
+	case stderrors.Is(err, services.ErrMFAChallengeInvalid), stderrors.Is(err, services.ErrMFACodeInvalid),
+		stderrors.Is(err, services.ErrMFAPasswordInvalid):
+		c.Error(errors.NewAppError(1013, err.Error(), http.StatusUnauthorized))
+	case stderrors.Is(err, services.ErrMFAAlreadyEnabled), stderrors.Is(err, services.ErrMFANotEnabled),
+		stderrors.Is(err, services.ErrMFANotEnrolling):
+		c.Error(errors.NewAppError(errors.ErrConflict.Code, err.Error(), http.StatusConflict))
+	case stderrors.Is(err, services.ErrMFARequiredByRole), stderrors.Is(err, services.ErrAdminOnly):
+		c.Error(errors.NewAppError(errors.ErrForbidden.Code, err.Error(), http.StatusForbidden))
+	case stderrors.Is(err, services.ErrMFAUserNotFound):
+		c.Error(errors.ErrNotFound)
+	default:
+		c.Error(errors.ErrInternalServer)
+	}
+}
+
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.Error(errors.ErrUnauthorized)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
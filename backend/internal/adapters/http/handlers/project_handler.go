@@ -11,6 +11,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
 	"github.com/phuc/cmms-backend/internal/domain"
 	"github.com/phuc/cmms-backend/internal/utils"
 	"github.com/signintech/gopdf"
@@ -22,15 +23,35 @@ type ProjectHandlerV2 struct {
 	db          *gorm.DB
 	projectRepo domain.ProjectRepository
 	ownerRepo   domain.OwnerRepository
+	memberRepo  domain.ProjectMemberRepository
+	shareSvc    *services.ShareLinkService
 }
 
-func NewProjectHandlerV2(db *gorm.DB, projectRepo domain.ProjectRepository, ownerRepo domain.OwnerRepository) *ProjectHandlerV2 {
-	return &ProjectHandlerV2{db: db, projectRepo: projectRepo, ownerRepo: ownerRepo}
+func NewProjectHandlerV2(db *gorm.DB, projectRepo domain.ProjectRepository, ownerRepo domain.OwnerRepository, memberRepo domain.ProjectMemberRepository, shareSvc *services.ShareLinkService) *ProjectHandlerV2 {
+	return &ProjectHandlerV2{db: db, projectRepo: projectRepo, ownerRepo: ownerRepo, memberRepo: memberRepo, shareSvc: shareSvc}
+}
+
+// mediaFolderToken signs a read-only link to the project's photo folder,
+// appended to every redirect-folder URL of an export. Exports reuse the
+// folder's current link rather than issuing one each time.
+func (h *ProjectHandlerV2) mediaFolderToken(c *gin.Context, project domain.Project) string {
+	if h.shareSvc == nil {
+		return ""
+	}
+	var createdBy *uuid.UUID
+	if uid, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		createdBy = &uid
+	}
+	_, token, err := h.shareSvc.IssueShared(domain.ShareMediaFolder, utils.SlugifyName(project.Name), nil, 0, createdBy)
+	if err != nil {
+		return ""
+	}
+	return token
 }
 
 // GET /projects
 func (h *ProjectHandlerV2) ListProjects(c *gin.Context) {
-	projects, err := h.projectRepo.FindAll()
+	projects, err := h.projectRepo.FindAll(visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
 		return
@@ -45,7 +66,7 @@ func (h *ProjectHandlerV2) GetProject(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
 		return
 	}
-	project, err := h.projectRepo.FindByID(id)
+	project, err := h.projectRepo.FindByID(id, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
@@ -108,11 +129,12 @@ func (h *ProjectHandlerV2) GetProjectExportPreview(c *gin.Context) {
 	}
 	baseURL := fmt.Sprintf("%s://%s", scheme, c.Request.Host)
 
-	var project domain.Project
-	if err := h.db.First(&project, "id = ?", id).Error; err != nil {
+	project, err := h.projectRepo.FindByID(id, visibilityScope(c))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
+	mediaToken := h.mediaFolderToken(c, *project)
 
 	var configs []domain.Config
 	if err := h.db.Preload("Asset.Parent").Preload("SubWork.Work").
@@ -153,12 +175,12 @@ func (h *ProjectHandlerV2) GetProjectExportPreview(c *gin.Context) {
 		StatusSubmit  int
 		StatusReject  int
 		StatusApprove int
-		SubmittedAt   string `gorm:"column:submitted_at"`
-		ApprovalAt    string `gorm:"column:approval_at"`
+		SubmittedAt   *time.Time `gorm:"column:submitted_at"` // Latest submit / approve in the task history
+		ApprovalAt    *time.Time `gorm:"column:approval_at"`
 	}
 
 	h.db.Table("detail_assigns").
-		Select("CAST(detail_assigns.id_config AS VARCHAR) as config_id, CAST(assigns.id_template AS VARCHAR) as template_id, detail_assigns.status_work, detail_assigns.status_submit, detail_assigns.status_reject, detail_assigns.status_approve, CAST(detail_assigns.submitted_at AS VARCHAR) as submitted_at, CAST(detail_assigns.approval_at AS VARCHAR) as approval_at").
+		Select("CAST(detail_assigns.id_config AS VARCHAR) as config_id, CAST(assigns.id_template AS VARCHAR) as template_id, detail_assigns.status_work, detail_assigns.status_submit, detail_assigns.status_reject, detail_assigns.status_approve, (SELECT MAX(e.created_at) FROM detail_assign_events e WHERE e.id_detail_assign = detail_assigns.id AND e.event_type = 'submit') as submitted_at, (SELECT MAX(e.created_at) FROM detail_assign_events e WHERE e.id_detail_assign = detail_assigns.id AND e.event_type = 'approve') as approval_at").
 		Joins("JOIN configs ON configs.id = detail_assigns.id_config").
 		Joins("JOIN assets ON assets.id = configs.id_asset").
 		Joins("JOIN assigns ON assigns.id = detail_assigns.id_assign").
@@ -166,19 +188,11 @@ func (h *ProjectHandlerV2) GetProjectExportPreview(c *gin.Context) {
 		Order("detail_assigns.created_at DESC").
 		Scan(&rawStatuses)
 
-	getLastDate := func(jsonStr string) string {
-		if jsonStr == "" || jsonStr == "null" {
+	getLastDate := func(t *time.Time) string {
+		if t == nil {
 			return ""
 		}
-		var arr []string
-		if err := json.Unmarshal([]byte(jsonStr), &arr); err == nil && len(arr) > 0 {
-			t, err := time.Parse(time.RFC3339, arr[len(arr)-1])
-			if err == nil {
-				return t.Format("02/01 15:04")
-			}
-			return arr[len(arr)-1]
-		}
-		return ""
+		return t.Format("02/01 15:04")
 	}
 
 	type TplStatusData struct {
@@ -275,8 +289,8 @@ func (h *ProjectHandlerV2) GetProjectExportPreview(c *gin.Context) {
 				assetSlug := utils.SlugifyName(assetName)
 				filters := fmt.Sprintf("%s,%s,%s,%s", tplSlug, workSlug, subWorkSlug, assetSlug)
 				b64Title := base64.URLEncoding.EncodeToString([]byte(tpl.Name))
-				imgURL := fmt.Sprintf("%s/api/redirect-folder?prefix=%s&filters=%s&title_b64=%s",
-					baseURL, url.QueryEscape(projSlug), url.QueryEscape(filters), b64Title)
+				imgURL := fmt.Sprintf("%s/api/redirect-folder?prefix=%s&filters=%s&title_b64=%s&token=%s",
+					baseURL, url.QueryEscape(projSlug), url.QueryEscape(filters), b64Title, mediaToken)
 				tplRows = append(tplRows, PreviewTemplate{
 					TemplateName: tpl.Name,
 					Status:       stData.Status,
@@ -294,8 +308,8 @@ func (h *ProjectHandlerV2) GetProjectExportPreview(c *gin.Context) {
 		assetSlug := utils.SlugifyName(assetName)
 		folderFilters := fmt.Sprintf("%s,%s,%s", workSlug, swSlug, assetSlug)
 		b64Title := base64.URLEncoding.EncodeToString([]byte(assetName))
-		folderURL := fmt.Sprintf("%s/api/redirect-folder?prefix=%s&filters=%s&title_b64=%s",
-			baseURL, url.QueryEscape(projSlug), url.QueryEscape(folderFilters), b64Title)
+		folderURL := fmt.Sprintf("%s/api/redirect-folder?prefix=%s&filters=%s&title_b64=%s&token=%s",
+			baseURL, url.QueryEscape(projSlug), url.QueryEscape(folderFilters), b64Title, mediaToken)
 
 		swAssets[sk] = append(swAssets[sk], PreviewAsset{
 			ParentName:     parentName,
@@ -322,8 +336,22 @@ func (h *ProjectHandlerV2) GetProjectExportPreview(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// GET|POST /public/export/:id — same PDF as ExportProject, for holders of a project_export share link
+func (h *ProjectHandlerV2) PublicExportProject(c *gin.Context) {
+	if claims := shareClaims(c); claims == nil || !claims.Grants(domain.ShareProjectExport, c.Param("id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Share link does not cover this project"})
+		return
+	}
+	h.exportProject(c, nil)
+}
+
 // POST /projects/:id/export
 func (h *ProjectHandlerV2) ExportProject(c *gin.Context) {
+	h.exportProject(c, visibilityScope(c))
+}
+
+// exportProject renders the project PDF; scope hides projects the caller may not see (nil for share links)
+func (h *ProjectHandlerV2) exportProject(c *gin.Context, scope *domain.VisibilityScope) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
@@ -337,11 +365,12 @@ func (h *ProjectHandlerV2) ExportProject(c *gin.Context) {
 		payload.SubWorkComments = map[string]string{}
 	}
 
-	var project domain.Project
-	if err := h.db.First(&project, "id = ?", id).Error; err != nil {
+	project, err := h.projectRepo.FindByID(id, scope)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
 	}
+	mediaToken := h.mediaFolderToken(c, *project)
 
 	// Determine base URL dynamically
 	scheme := "http"
@@ -389,15 +418,15 @@ func (h *ProjectHandlerV2) ExportProject(c *gin.Context) {
 		StatusSubmit  int
 		StatusReject  int
 		StatusApprove int
-		SubmittedAt   string `gorm:"column:submitted_at"`
-		ApprovalAt    string `gorm:"column:approval_at"`
+		SubmittedAt   *time.Time `gorm:"column:submitted_at"` // Latest submit / approve in the task history
+		ApprovalAt    *time.Time `gorm:"column:approval_at"`
 		NoteData      string `gorm:"column:note_data"`
 		NoteApproval  string `gorm:"column:note_approval"`
 		NoteReject    string `gorm:"column:note_reject"`
 	}
 
 	h.db.Table("detail_assigns").
-		Select("CAST(detail_assigns.id_config AS VARCHAR) as config_id, CAST(assigns.id_template AS VARCHAR) as template_id, detail_assigns.status_work, detail_assigns.status_submit, detail_assigns.status_reject, detail_assigns.status_approve, CAST(detail_assigns.submitted_at AS VARCHAR) as submitted_at, CAST(detail_assigns.approval_at AS VARCHAR) as approval_at, detail_assigns.note_data, detail_assigns.note_approval, detail_assigns.note_reject").
+		Select("CAST(detail_assigns.id_config AS VARCHAR) as config_id, CAST(assigns.id_template AS VARCHAR) as template_id, detail_assigns.status_work, detail_assigns.status_submit, detail_assigns.status_reject, detail_assigns.status_approve, (SELECT MAX(e.created_at) FROM detail_assign_events e WHERE e.id_detail_assign = detail_assigns.id AND e.event_type = 'submit') as submitted_at, (SELECT MAX(e.created_at) FROM detail_assign_events e WHERE e.id_detail_assign = detail_assigns.id AND e.event_type = 'approve') as approval_at, detail_assigns.note_data, detail_assigns.note_approval, detail_assigns.note_reject").
 		Joins("JOIN configs ON configs.id = detail_assigns.id_config").
 		Joins("JOIN assets ON assets.id = configs.id_asset").
 		Joins("JOIN assigns ON assigns.id = detail_assigns.id_assign").
@@ -405,19 +434,11 @@ func (h *ProjectHandlerV2) ExportProject(c *gin.Context) {
 		Order("detail_assigns.created_at DESC").
 		Scan(&rawStatuses)
 
-	getLastDate := func(jsonStr string) string {
-		if jsonStr == "" || jsonStr == "null" {
+	getLastDate := func(t *time.Time) string {
+		if t == nil {
 			return ""
 		}
-		var arr []string
-		if err := json.Unmarshal([]byte(jsonStr), &arr); err == nil && len(arr) > 0 {
-			t, err := time.Parse(time.RFC3339, arr[len(arr)-1])
-			if err == nil {
-				return t.Format("02/01 15:04")
-			}
-			return arr[len(arr)-1]
-		}
-		return ""
+		return t.Format("02/01 15:04")
 	}
 
 	type TplStatusData struct {
@@ -702,8 +723,8 @@ func (h *ProjectHandlerV2) ExportProject(c *gin.Context) {
 			filters := fmt.Sprintf("%s,%s,%s,%s", tplSlug, workSlug, subWorkSlug, assetSlug)
 
 			b64Title := base64.URLEncoding.EncodeToString([]byte(l))
-			redirectURL := fmt.Sprintf("%s/api/redirect-folder?prefix=%s&filters=%s&title_b64=%s",
-				baseURL, url.QueryEscape(projSlug), url.QueryEscape(filters), b64Title)
+			redirectURL := fmt.Sprintf("%s/api/redirect-folder?prefix=%s&filters=%s&title_b64=%s&token=%s",
+				baseURL, url.QueryEscape(projSlug), url.QueryEscape(filters), b64Title, mediaToken)
 
 			pdf.AddExternalLink(redirectURL, x+4, lineY, w-8, lineH)
 		}
@@ -1049,9 +1070,92 @@ func (h *ProjectHandlerV2) CreateProject(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
 		return
 	}
+	// Creator becomes a member so the project stays visible to them
+	if creatorID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		_ = h.memberRepo.Add(project.ID, []uuid.UUID{creatorID})
+	}
 	c.JSON(http.StatusCreated, project)
 }
 
+// ---- Project Members ----
+
+// GET /projects/:id/members
+func (h *ProjectHandlerV2) ListProjectMembers(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	if _, err := h.projectRepo.FindByID(id, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	members, err := h.memberRepo.FindByProjectID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project members"})
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// POST /projects/:id/members
+func (h *ProjectHandlerV2) AddProjectMembers(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	var req struct {
+		UserIDs []string `json:"user_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userIDs := make([]uuid.UUID, 0, len(req.UserIDs))
+	for _, s := range req.UserIDs {
+		uid, err := uuid.Parse(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID: " + s})
+			return
+		}
+		userIDs = append(userIDs, uid)
+	}
+	if _, err := h.projectRepo.FindByID(id, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	if err := h.memberRepo.Add(id, userIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add project members"})
+		return
+	}
+	members, _ := h.memberRepo.FindByProjectID(id)
+	c.JSON(http.StatusOK, members)
+}
+
+// DELETE /projects/:id/members/:user_id
+func (h *ProjectHandlerV2) RemoveProjectMember(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if _, err := h.projectRepo.FindByID(id, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	if err := h.memberRepo.Remove(id, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove project member"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Project member removed"})
+}
+
 // PUT /projects/:id
 func (h *ProjectHandlerV2) UpdateProject(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
@@ -1059,7 +1163,7 @@ func (h *ProjectHandlerV2) UpdateProject(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
 		return
 	}
-	project, err := h.projectRepo.FindByID(id)
+	project, err := h.projectRepo.FindByID(id, visibilityScope(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 		return
@@ -1300,6 +1404,8 @@ func (h *ProjectHandlerV2) CloneProject(c *gin.Context) {
 				ImageCount:          oldCfg.ImageCount,
 				GuideText:           oldCfg.GuideText,
 				GuideImages:         oldCfg.GuideImages,
+				EvidenceSlots:       oldCfg.EvidenceSlots,
+				MeasurementFields:   oldCfg.MeasurementFields,
 			}
 			if err := tx.Create(&newCfg).Error; err != nil {
 				return err
@@ -1390,6 +1496,8 @@ func (h *ProjectHandlerV2) CloneProject(c *gin.Context) {
 func (h *ProjectHandlerV2) RedirectFolder(c *gin.Context) {
 	prefix := c.Query("prefix")
 	filters := c.Query("filters")
+	// Share token for /public/media/library and /media/proxy, passed through from the export link
+	token := url.QueryEscape(c.Query("token"))
 	title := c.Query("title")
 	titleB64 := c.Query("title_b64")
 	if titleB64 != "" {
@@ -1441,12 +1549,13 @@ func (h *ProjectHandlerV2) RedirectFolder(c *gin.Context) {
     <script>
         const prefix = "%s";
         const filters = "%s";
+        const token = "%s";
         
         async function fetchImages() {
             try {
                 if (!prefix) throw new Error("Không có thông tin đường dẫn thư mục.");
                 
-                const url = '/api/public/media/library?prefix=' + encodeURIComponent(prefix) + '&filters=' + encodeURIComponent(filters);
+                const url = '/api/public/media/library?prefix=' + encodeURIComponent(prefix) + '&filters=' + encodeURIComponent(filters) + '&token=' + token;
                 const res = await fetch(url);
                 if (!res.ok) throw new Error('Lỗi khi truy xuất dữ liệu từ Cloud (Mã: ' + res.status + ')');
                 
@@ -1484,7 +1593,7 @@ func (h *ProjectHandlerV2) RedirectFolder(c *gin.Context) {
                     html += '<h3>Quy trình: ' + niceProc + '</h3>';
                     html += '<div class="gallery">';
                     html += groupedImages[proc].map(key => {
-                        const proxyUrl = '/api/media/proxy?key=' + encodeURIComponent(key);
+                        const proxyUrl = '/api/media/proxy?key=' + encodeURIComponent(key) + '&token=' + token;
                         return '<div class="gallery-item" onclick="openModal(\'' + proxyUrl + '\')">' +
                                '<img src="' + proxyUrl + '" loading="lazy" alt="Ảnh tải lên">' +
                                '</div>';
@@ -1516,7 +1625,7 @@ func (h *ProjectHandlerV2) RedirectFolder(c *gin.Context) {
         window.onload = fetchImages;
     </script>
 </body>
-</html>`, title, title, prefix, filters)
+</html>`, title, title, prefix, filters, token)
 
 	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
 }
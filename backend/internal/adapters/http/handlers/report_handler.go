@@ -69,7 +69,7 @@ func (h *ReportHandler) CreateReport(c *gin.Context) {
 	response.Created(c, report)
 }
 
-// GET /api/public/generic-report/:id
+// GET /api/public/generic-report/:id  — requires a share token for the report
 func (h *ReportHandler) GetGenericReport(c *gin.Context) {
 	idStr := c.Param("id")
 	uid, err := uuid.Parse(idStr)
@@ -77,6 +77,10 @@ func (h *ReportHandler) GetGenericReport(c *gin.Context) {
 		response.BadRequest(c, "Invalid Report ID")
 		return
 	}
+	if claims := shareClaims(c); claims == nil || !claims.Grants(domain.ShareGenericReport, uid.String()) {
+		response.Forbidden(c, "Share link does not cover this report")
+		return
+	}
 
 	report, err := h.ReportService.GetReport(uid)
 	if err != nil {
@@ -4,20 +4,42 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/phuc/cmms-backend/internal/adapters/storage/postgres"
-	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/core/services"
 )
 
 type RoleHandler struct {
-	Repo postgres.RoleRepository
+	Svc *services.RoleService
 }
 
-func NewRoleHandler(repo postgres.RoleRepository) *RoleHandler {
-	return &RoleHandler{Repo: repo}
+func NewRoleHandler(svc *services.RoleService) *RoleHandler {
+	return &RoleHandler{Svc: svc}
 }
 
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+	RequireMFA  *bool    `json:"require_mfa"` // Force two-factor login for holders of the role
+}
+
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// GET /permissions
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	perms, err := h.Svc.ListPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, perms)
+}
+
+// GET /roles
 func (h *RoleHandler) GetAllRoles(c *gin.Context) {
-	roles, err := h.Repo.FindAll()
+	roles, err := h.Svc.ListRoles()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
 		return
@@ -25,15 +47,92 @@ func (h *RoleHandler) GetAllRoles(c *gin.Context) {
 	c.JSON(http.StatusOK, roles)
 }
 
+// GET /roles/:id
+func (h *RoleHandler) GetRole(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	role, err := h.Svc.GetRole(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// POST /roles
 func (h *RoleHandler) CreateRole(c *gin.Context) {
-	var role domain.Role
-	if err := c.ShouldBindJSON(&role); err != nil {
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.Repo.Create(&role); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+	role, err := h.Svc.CreateRole(req.Name, req.Permissions, req.RequireMFA != nil && *req.RequireMFA)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, role)
 }
+
+// PUT /roles/:id
+// Omitting "permissions" keeps the current grants; an empty list revokes them all.
+// Omitting "require_mfa" keeps the current two-factor policy.
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	role, err := h.Svc.UpdateRole(id, req.Name, req.Permissions, req.RequireMFA)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// PUT /roles/:id/permissions
+func (h *RoleHandler) SetRolePermissions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	var req RolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Permissions == nil {
+		c.Error(errors.NewAppError(errors.ErrValidation.Code, "permissions is required", http.StatusBadRequest))
+		return
+	}
+	role, err := h.Svc.SetRolePermissions(id, req.Permissions)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// DELETE /roles/:id
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if err := h.Svc.DeleteRole(id); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/core/services"
+)
+
+// ServiceAccountHandler manages service accounts and their API keys
+type ServiceAccountHandler struct {
+	Svc *services.ServiceAccountService
+}
+
+func NewServiceAccountHandler(svc *services.ServiceAccountService) *ServiceAccountHandler {
+	return &ServiceAccountHandler{Svc: svc}
+}
+
+type ServiceAccountRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Disabled    *bool   `json:"disabled"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes" binding:"required"` // Permission codes; must be held by the caller
+	RateLimit int        `json:"rate_limit_per_minute"`     // 0 = server default
+	ExpiresAt *time.Time `json:"expires_at"`                // nil = server default lifetime
+}
+
+type RotateAPIKeyRequest struct {
+	GraceHours int        `json:"grace_hours"` // How long the old key keeps working (max 168)
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// GET /service-accounts
+func (h *ServiceAccountHandler) ListServiceAccounts(c *gin.Context) {
+	accounts, err := h.Svc.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service accounts"})
+		return
+	}
+	c.JSON(http.StatusOK, accounts)
+}
+
+// GET /service-accounts/:id
+func (h *ServiceAccountHandler) GetServiceAccount(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	account, err := h.Svc.Get(id)
+	if err != nil {
+		serviceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// POST /service-accounts
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	var req ServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name, description := "", ""
+	if req.Name != nil {
+		name = *req.Name
+	}
+	if req.Description != nil {
+		description = *req.Description
+	}
+	account, err := h.Svc.Create(name, description, callerUserID(c))
+	if err != nil {
+		serviceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, account)
+}
+
+// PUT /service-accounts/:id - rename, describe, or enable/disable (omitted fields are kept)
+func (h *ServiceAccountHandler) UpdateServiceAccount(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req ServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	account, err := h.Svc.Update(id, req.Name, req.Description, req.Disabled)
+	if err != nil {
+		serviceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// DELETE /service-accounts/:id - also revokes every key of the account
+func (h *ServiceAccountHandler) DeleteServiceAccount(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.Svc.Delete(id); err != nil {
+		serviceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Service account deleted"})
+}
+
+// POST /service-accounts/:id/keys - the plain key is only returned in this response
+func (h *ServiceAccountHandler) CreateAPIKey(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, err := h.Svc.CreateKey(id, services.APIKeyInput{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		RateLimit: req.RateLimit,
+		ExpiresAt: req.ExpiresAt,
+	}, c.GetStringSlice("permissions"), callerUserID(c))
+	if err != nil {
+		serviceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// POST /service-accounts/:id/keys/:key_id/rotate - issue a replacement; the old key
+// keeps working for grace_hours so the integration can switch over
+func (h *ServiceAccountHandler) RotateAPIKey(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	keyID, ok := parseIDParam(c, "key_id")
+	if !ok {
+		return
+	}
+	var req RotateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, err := h.Svc.RotateKey(id, keyID, time.Duration(req.GraceHours)*time.Hour, req.ExpiresAt,
+		c.GetStringSlice("permissions"), callerUserID(c))
+	if err != nil {
+		serviceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// DELETE /service-accounts/:id/keys/:key_id - revoke immediately
+func (h *ServiceAccountHandler) RevokeAPIKey(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	keyID, ok := parseIDParam(c, "key_id")
+	if !ok {
+		return
+	}
+	if err := h.Svc.RevokeKey(id, keyID); err != nil {
+		serviceAccountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+func serviceAccountError(c *gin.Context, err error) {
+	switch {
+	case stderrors.Is(err, services.ErrServiceAccountNotFound), stderrors.Is(err, services.ErrAPIKeyNotFound):
+		c.Error(errors.NewAppError(errors.ErrNotFound.Code, err.Error(), http.StatusNotFound))
+	case stderrors.Is(err, services.ErrServiceAccountInvalid), stderrors.Is(err, services.ErrAPIKeyExpiry):
+		c.Error(errors.NewAppError(1006, err.Error(), http.StatusBadRequest))
+	case stderrors.Is(err, services.ErrAPIKeyScope):
+		c.Error(errors.NewAppError(errors.ErrForbidden.Code, err.Error(), http.StatusForbidden))
+	case stderrors.Is(err, services.ErrAPIKeyInvalid):
+		c.Error(errors.NewAppError(errors.ErrConflict.Code, err.Error(), http.StatusConflict))
+	default:
+		c.Error(errors.ErrInternalServer)
+	}
+}
+
+func parseIDParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// callerUserID returns the acting user, or nil for service accounts
+func callerUserID(c *gin.Context) *uuid.UUID {
+	if uid, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		return &uid
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// SessionHandler lets admins inspect and kill a user's login sessions
+type SessionHandler struct {
+	Svc *services.SessionService
+}
+
+func NewSessionHandler(svc *services.SessionService) *SessionHandler {
+	return &SessionHandler{Svc: svc}
+}
+
+// GET /admin/users/:id/sessions
+func (h *SessionHandler) ListUserSessions(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	sessions, err := h.Svc.ListActive(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// DELETE /admin/users/:id/sessions
+// Logs the user out of every device.
+func (h *SessionHandler) RevokeAllUserSessions(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if err := h.Svc.RevokeAll(userID, domain.RevokeReasonAdmin); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked"})
+}
+
+// DELETE /admin/users/:id/sessions/:session_id
+func (h *SessionHandler) RevokeUserSession(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	if err := h.Svc.Revoke(userID, sessionID, domain.RevokeReasonAdmin); err != nil {
+		if err == services.ErrSessionInvalid {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// DELETE /admin/users/:id/devices/:device_id
+// Revokes every session opened from the device.
+func (h *SessionHandler) RevokeUserDevice(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if err := h.Svc.RevokeDevice(userID, c.Param("device_id"), domain.RevokeReasonAdmin); err != nil {
+		if err == services.ErrSessionInvalid {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No active session for this device"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke device"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Device sessions revoked"})
+}
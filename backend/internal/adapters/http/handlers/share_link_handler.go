@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// ShareLinkHandler issues and revokes signed links to /api/public/* resources
+type ShareLinkHandler struct {
+	Svc *services.ShareLinkService
+}
+
+func NewShareLinkHandler(svc *services.ShareLinkService) *ShareLinkHandler {
+	return &ShareLinkHandler{Svc: svc}
+}
+
+type CreateShareLinkRequest struct {
+	ResourceType string            `json:"resource_type" binding:"required"`
+	ResourceID   string            `json:"resource_id" binding:"required"`
+	Filters      map[string]string `json:"filters"`
+	TTLHours     int               `json:"ttl_hours"` // 0 = server default
+}
+
+type RevokeShareResourceRequest struct {
+	ResourceType string `json:"resource_type" binding:"required"`
+	ResourceID   string `json:"resource_id" binding:"required"`
+}
+
+// POST /share-links
+func (h *ShareLinkHandler) CreateShareLink(c *gin.Context) {
+	var req CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var createdBy *uuid.UUID
+	if uid, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		createdBy = &uid
+	}
+
+	link, token, err := h.Svc.Issue(req.ResourceType, req.ResourceID, req.Filters, time.Duration(req.TTLHours)*time.Hour, createdBy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         link.ID,
+		"token":      token,
+		"expires_at": link.ExpiresAt,
+	})
+}
+
+// GET /share-links?resource_type=...&resource_id=...
+func (h *ShareLinkHandler) ListShareLinks(c *gin.Context) {
+	resourceType := c.Query("resource_type")
+	resourceID := c.Query("resource_id")
+	if resourceType == "" || resourceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource_type and resource_id are required"})
+		return
+	}
+	links, err := h.Svc.ListByResource(resourceType, resourceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch share links"})
+		return
+	}
+	c.JSON(http.StatusOK, links)
+}
+
+// DELETE /share-links/:id
+func (h *ShareLinkHandler) RevokeShareLink(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if err := h.Svc.Revoke(id); err != nil {
+		if err == services.ErrShareLinkInvalid {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Share link revoked"})
+}
+
+// POST /share-links/revoke
+// Revokes every link issued for a resource (e.g. after a report was shared by mistake).
+func (h *ShareLinkHandler) RevokeResourceLinks(c *gin.Context) {
+	var req RevokeShareResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	count, err := h.Svc.RevokeResource(req.ResourceType, req.ResourceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share links"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Share links revoked", "revoked": count})
+}
+
+// shareClaims returns the claims verified by middleware.ShareLinkAuth, or nil
+func shareClaims(c *gin.Context) *domain.ShareClaims {
+	if v, ok := c.Get("share_claims"); ok {
+		if claims, ok := v.(*domain.ShareClaims); ok {
+			return claims
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// SLAHandler manages SLA policies and the holiday calendar, and reports the
+// SLA of an assign's tasks
+type SLAHandler struct {
+	Svc        *services.SLAService
+	assignRepo domain.AssignRepository
+}
+
+func NewSLAHandler(svc *services.SLAService, assignRepo domain.AssignRepository) *SLAHandler {
+	return &SLAHandler{Svc: svc, assignRepo: assignRepo}
+}
+
+// GET /sla-policies
+func (h *SLAHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.Svc.ListPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch SLA policies"})
+		return
+	}
+	c.JSON(http.StatusOK, policies)
+}
+
+// POST /sla-policies
+func (h *SLAHandler) CreatePolicy(c *gin.Context) {
+	var input services.SLAPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy, err := h.Svc.CreatePolicy(input)
+	if err != nil {
+		workflowError(c, err, "Failed to create SLA policy")
+		return
+	}
+	c.JSON(http.StatusCreated, policy)
+}
+
+// PUT /sla-policies/:id
+func (h *SLAHandler) UpdatePolicy(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var input services.SLAPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy, err := h.Svc.UpdatePolicy(id, input)
+	if err != nil {
+		workflowError(c, err, "Failed to update SLA policy")
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// DELETE /sla-policies/:id
+func (h *SLAHandler) DeletePolicy(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.Svc.DeletePolicy(id); err != nil {
+		workflowError(c, err, "Failed to delete SLA policy")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "SLA policy deleted"})
+}
+
+// GET /holidays
+func (h *SLAHandler) ListHolidays(c *gin.Context) {
+	holidays, err := h.Svc.ListHolidays()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holidays"})
+		return
+	}
+	c.JSON(http.StatusOK, holidays)
+}
+
+// POST /holidays
+func (h *SLAHandler) CreateHoliday(c *gin.Context) {
+	var input services.HolidayInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	holiday, err := h.Svc.CreateHoliday(input)
+	if err != nil {
+		workflowError(c, err, "Failed to create holiday")
+		return
+	}
+	c.JSON(http.StatusCreated, holiday)
+}
+
+// DELETE /holidays/:id
+func (h *SLAHandler) DeleteHoliday(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.Svc.DeleteHoliday(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete holiday"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Holiday deleted"})
+}
+
+// GET /assigns/:id/sla - deadlines of every task of the assign, as of now
+func (h *SLAHandler) GetAssignSLA(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if _, err := h.assignRepo.FindByID(id, visibilityScope(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assign not found"})
+		return
+	}
+	tasks, err := h.Svc.ForAssign(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate SLA"})
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
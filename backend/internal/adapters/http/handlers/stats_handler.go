@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
 )
 
 type StatsHandler struct {
@@ -53,6 +54,64 @@ func (h *StatsHandler) GetAdminStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// slaFilter reads ?project_id=&status=&clock= for the SLA endpoints
+func slaFilter(c *gin.Context) (domain.SLAFilter, bool) {
+	filter := domain.SLAFilter{
+		Status: domain.SLAStatus(c.Query("status")),
+		Clock:  domain.SLAClock(c.Query("clock")),
+		Scope:  visibilityScope(c),
+	}
+	if raw := c.Query("project_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project_id"})
+			return filter, false
+		}
+		filter.ProjectID = &id
+	}
+	switch filter.Status {
+	case "", domain.SLAOnTrack, domain.SLAAtRisk, domain.SLAOverdue:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be on_track, at_risk or overdue"})
+		return filter, false
+	}
+	switch filter.Clock {
+	case "", domain.SLAClockResponse, domain.SLAClockCompletion, domain.SLAClockReview:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "clock must be response, completion or review"})
+		return filter, false
+	}
+	return filter, true
+}
+
+// GET /manager/stats/sla?project_id=&status=&clock= - counters of open work by SLA status
+func (h *StatsHandler) GetSLAStats(c *gin.Context) {
+	filter, ok := slaFilter(c)
+	if !ok {
+		return
+	}
+	counts, err := h.statsService.GetSLACounts(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch SLA stats"})
+		return
+	}
+	c.JSON(http.StatusOK, counts)
+}
+
+// GET /manager/stats/sla/tasks?project_id=&status=overdue&clock= - open tasks by SLA status
+func (h *StatsHandler) ListSLATasks(c *gin.Context) {
+	filter, ok := slaFilter(c)
+	if !ok {
+		return
+	}
+	tasks, err := h.statsService.FindSLATasks(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch SLA tasks"})
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
 // GET /stats/user - no-op placeholder for now
 func (h *StatsHandler) GetUserStats(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User stats endpoint - coming soon"})
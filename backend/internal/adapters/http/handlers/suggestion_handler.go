@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phuc/cmms-backend/internal/core/services"
+)
+
+// SuggestionHandler ranks engineers for new assigns
+type SuggestionHandler struct {
+	Svc *services.SuggestionService
+}
+
+func NewSuggestionHandler(svc *services.SuggestionService) *SuggestionHandler {
+	return &SuggestionHandler{Svc: svc}
+}
+
+// POST /assigns/suggestions - takes the body of POST /assigns (without
+// id_users) and returns it with id_users filled in, plus every candidate's
+// score broken down by factor
+func (h *SuggestionHandler) SuggestAssignees(c *gin.Context) {
+	var input services.SuggestionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	suggestion, err := h.Svc.Suggest(input)
+	if err != nil {
+		workflowError(c, err, "Failed to suggest assignees")
+		return
+	}
+	c.JSON(http.StatusOK, suggestion)
+}
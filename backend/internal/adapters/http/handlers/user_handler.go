@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -65,7 +66,7 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 
 type CreateUserRequest struct {
 	Email          string `json:"email" binding:"required,email"`
-	Password       string `json:"password" binding:"required,min=6"`
+	Password       string `json:"password" binding:"required"` // Checked against the password policy
 	FullName       string `json:"full_name" binding:"required"`
 	RoleID         string `json:"role_id" binding:"required"`
 	TeamID         string `json:"team_id"`
@@ -85,7 +86,11 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		creatorIDStr = fmt.Sprintf("%v", uid)
 	}
 
-	user, err := h.userService.CreateUser(req.Email, req.Password, req.FullName, req.RoleID, req.TeamID, req.NumberPhone, creatorIDStr)
+	user, err := h.userService.CreateUser(req.Email, req.Password, req.FullName, req.RoleID, req.TeamID, req.NumberPhone, creatorIDStr, c.GetString("role"))
+	if errors.Is(err, services.ErrAdminOnly) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("[CreateUser] Backend Error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -127,7 +132,12 @@ func (h *UserHandler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.UpdateUserRole(id, req.RoleID); err != nil {
+	err := h.userService.UpdateUserRole(id, req.RoleID, c.GetString("role"))
+	if errors.Is(err, services.ErrAdminOnly) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role", "details": err.Error()})
 		return
 	}
@@ -157,7 +167,15 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(id, req.Email, req.FullName, req.NumberPhone, req.RoleID, req.TeamID, req.Password)
+	user, err := h.userService.UpdateUser(id, req.Email, req.FullName, req.NumberPhone, req.RoleID, req.TeamID, req.Password, c.GetString("role"))
+	if errors.Is(err, services.ErrAdminOnly) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrWeakPassword) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user", "details": err.Error()})
 		return
@@ -169,7 +187,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required"` // Checked against the password policy
 }
 
 func (h *UserHandler) ChangePassword(c *gin.Context) {
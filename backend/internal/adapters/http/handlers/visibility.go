@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// visibilityScope builds the caller's data visibility from the values set by AuthMiddleware.
+// An unparsable user_id yields an assignee scope on uuid.Nil, which matches nothing.
+func visibilityScope(c *gin.Context) *domain.VisibilityScope {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+	return domain.NewVisibilityScope(userID, c.GetString("role"), c.GetStringSlice("permissions"))
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// Request/response bodies larger than this are not kept in the audit log
+const auditMaxBody = 64 << 10
+
+// Audit attributes every mutating request (POST/PUT/PATCH/DELETE) to its caller.
+// It puts the caller into the request context (domain.WithAuditActor), so row changes
+// made with db.WithContext(c.Request.Context()) carry the actor and request ID, and
+// records the call itself: actor, role, IP, route, the ":id" param (or the created ID
+// read from the response, "id" or "data.id") and the JSON request body.
+// Row state before and after is captured for every caller by the audit GORM callbacks
+// (AuditService.RegisterCallbacks), so no route needs registering here.
+// Must run after AuthMiddleware. Failed requests (status >= 400) are not logged.
+func Audit(basePath string, auditService *services.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		actor := domain.AuditActor{
+			RequestID: uuid.New(),
+			Type:      c.GetString("principal_type"),
+			Role:      c.GetString("role"),
+			IPAddress: c.ClientIP(),
+		}
+		if actor.Type == "" {
+			actor.Type = domain.PrincipalUser
+		}
+		actorID := c.GetString("user_id")
+		if actor.Type == domain.PrincipalServiceAccount {
+			actorID = c.GetString("service_account_id")
+		}
+		if uid, err := uuid.Parse(actorID); err == nil {
+			actor.ID = &uid
+		}
+		c.Request = c.Request.WithContext(domain.WithAuditActor(c.Request.Context(), actor))
+
+		body := readAuditBody(c)
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= http.StatusBadRequest {
+			return
+		}
+		route := strings.TrimPrefix(c.FullPath(), basePath)
+		entity, action := routeAuditAction(c.Request.Method, route)
+		entityID := c.Param("id")
+		if entityID == "" && c.Request.Method == http.MethodPost {
+			entityID = responseID(writer.body.Bytes())
+		}
+
+		auditService.Record(domain.AuditLog{
+			RequestID:  actor.RequestID,
+			ActorType:  actor.Type,
+			ActorID:    actor.ID,
+			ActorRole:  actor.Role,
+			IPAddress:  actor.IPAddress,
+			Method:     c.Request.Method,
+			Route:      route,
+			Action:     action,
+			EntityType: entity,
+			EntityID:   entityID,
+			StatusCode: status,
+		}, nil, body)
+	}
+}
+
+// routeAuditAction names a call after its route: the first segment is the entity,
+// a trailing static segment the action ("/details/:id/approve" -> details, approve);
+// otherwise the action follows the method.
+func routeAuditAction(method, route string) (entity, action string) {
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+	entity = segments[0]
+	if last := segments[len(segments)-1]; len(segments) > 1 && !strings.HasPrefix(last, ":") && !strings.HasPrefix(last, "*") {
+		return entity, strings.ReplaceAll(last, "-", "_")
+	}
+	return entity, defaultAuditAction(method)
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func defaultAuditAction(method string) string {
+	switch method {
+	case http.MethodPost:
+		return domain.AuditActionCreate
+	case http.MethodDelete:
+		return domain.AuditActionDelete
+	}
+	return domain.AuditActionUpdate
+}
+
+// readAuditBody returns a JSON request body and puts it back for the handler.
+// Multipart uploads and oversized bodies are skipped.
+func readAuditBody(c *gin.Context) []byte {
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return nil
+	}
+	if c.Request.ContentLength > auditMaxBody {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, auditMaxBody+1))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil || len(body) > auditMaxBody {
+		return nil
+	}
+	return body
+}
+
+func jsonNumber(v float64) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+// responseID finds the created entity's ID in {"id": ...} or {"data": {"id": ...}}
+func responseID(body []byte) string {
+	var resp struct {
+		ID   interface{}     `json:"id"`
+		Data json.RawMessage `json:"data"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &resp) != nil {
+		return ""
+	}
+	var data struct {
+		ID interface{} `json:"id"`
+	}
+	json.Unmarshal(resp.Data, &data) // data may be absent or not an object
+
+	for _, id := range []interface{}{resp.ID, data.ID} {
+		switch v := id.(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return jsonNumber(v)
+		}
+	}
+	return ""
+}
+
+// auditResponseWriter keeps the first 64KB of the response so created IDs can be read
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.body.Len() < auditMaxBody {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	if w.body.Len() < auditMaxBody {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
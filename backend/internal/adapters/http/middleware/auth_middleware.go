@@ -3,14 +3,26 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/phuc/cmms-backend/internal/core/errors"
 	"github.com/phuc/cmms-backend/internal/core/services"
+	"github.com/phuc/cmms-backend/internal/domain"
 )
 
-// AuthMiddleware creates a gin middleware for authentication
-func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
+// AuthMiddleware creates a gin middleware for authentication.
+// Callers present either a user JWT ("Authorization: Bearer <jwt>") or a service
+// account API key ("X-API-Key: omk_..." or "Authorization: Bearer omk_...").
+// apiKeys may be nil to accept JWTs only.
+func AuthMiddleware(authService *services.AuthService, apiKeys *services.ServiceAccountService) gin.HandlerFunc {
+	keyLimiter := NewRateLimiter(0, time.Minute) // Limits are per key, see APIKey.RateLimit
 	return func(c *gin.Context) {
+		if apiKey := presentedAPIKey(c); apiKey != "" && apiKeys != nil {
+			authenticateAPIKey(c, apiKeys, keyLimiter, apiKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
@@ -34,13 +46,83 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 		}
 
 		// Set user info to context
+		c.Set("principal_type", domain.PrincipalUser)
 		if userID, ok := claims["user_id"].(string); ok {
 			c.Set("user_id", userID)
 		}
+		if sid, ok := claims["sid"].(string); ok {
+			c.Set("session_id", sid)
+		}
 		if role, ok := claims["role"].(string); ok {
 			c.Set("role", role)
 		}
+		if perms, ok := claims["permissions"].([]string); ok {
+			c.Set("permissions", perms)
+		}
+		if pwdChange, ok := claims["pwd_change"].(bool); ok && pwdChange {
+			c.Set("must_change_password", true)
+		}
+
+		c.Next()
+	}
+}
+
+// presentedAPIKey returns the API key sent with the request, if any
+func presentedAPIKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(bearer, services.APIKeyPrefix) {
+		return bearer
+	}
+	return ""
+}
+
+// authenticateAPIKey admits a service account with the permissions scoped to its key.
+// No user_id is set: handlers that need a human actor treat the caller as unknown.
+func authenticateAPIKey(c *gin.Context, apiKeys *services.ServiceAccountService, limiter *RateLimiter, raw string) {
+	key, err := apiKeys.Authenticate(raw, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked API key"})
+		c.Abort()
+		return
+	}
+	if !limiter.AllowLimit(key.ID.String(), key.RateLimit) {
+		c.Header("Retry-After", "60")
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error": "API key rate limit exceeded. Please try again later.",
+		})
+		return
+	}
 
+	c.Set("principal_type", domain.PrincipalServiceAccount)
+	c.Set("service_account_id", key.ServiceAccountID.String())
+	c.Set("api_key_id", key.ID.String())
+	c.Set("role", domain.PrincipalServiceAccount)
+	c.Set("permissions", key.ScopeCodes())
+	c.Next()
+}
+
+// RequirePasswordChange blocks accounts that must change their password
+// (admin-created or admin-reset) from every route except the allowed route keys
+// (see RouteKey). Must run after AuthMiddleware. The flag is dropped from the
+// token on the first refresh after the password was changed.
+func RequirePasswordChange(basePath string, allowed ...string) gin.HandlerFunc {
+	allow := make(map[string]bool, len(allowed))
+	for _, key := range allowed {
+		allow[key] = true
+	}
+	return func(c *gin.Context) {
+		if !c.GetBool("must_change_password") {
+			c.Next()
+			return
+		}
+		path := strings.TrimPrefix(c.FullPath(), basePath)
+		if !allow[RouteKey(c.Request.Method, path)] {
+			c.Error(errors.ErrPasswordChangeRequired)
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// Policy declares who may call a route. A caller is admitted when:
+//   - Authenticated is set (any logged-in user), or
+//   - its role is listed in Roles, or
+//   - its role grants Permission.
+//
+// The zero Policy admits nobody.
+type Policy struct {
+	Authenticated bool
+	Roles         []domain.UserRole
+	Permission    string
+}
+
+// AllowAuthenticated builds a Policy that admits every logged-in user.
+func AllowAuthenticated() Policy {
+	return Policy{Authenticated: true}
+}
+
+// AllowRoles builds a Policy that admits the given roles.
+func AllowRoles(roles ...domain.UserRole) Policy {
+	return Policy{Roles: roles}
+}
+
+// RequirePermission builds a Policy that admits roles granted the permission code.
+func RequirePermission(code string) Policy {
+	return Policy{Permission: code}
+}
+
+// Allows reports whether a caller with the given role and permissions satisfies the policy.
+func (p Policy) Allows(role string, permissions []string) bool {
+	if p.Authenticated {
+		return true
+	}
+	for _, r := range p.Roles {
+		if string(r) == role {
+			return true
+		}
+	}
+	if p.Permission != "" {
+		for _, perm := range permissions {
+			if perm == p.Permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RoutePolicies maps a route key ("METHOD /path", see RouteKey) to its Policy.
+type RoutePolicies map[string]Policy
+
+// RouteKey builds the lookup key for a route, e.g. "GET /users/:id".
+func RouteKey(method, path string) string {
+	return method + " " + path
+}
+
+// Authorize enforces RoutePolicies on a route group.
+// Must run after AuthMiddleware. basePath is stripped from the matched route
+// (e.g. "/api") so policies are declared with the same paths used at registration.
+// Routes without a policy are denied (fail closed).
+func Authorize(basePath string, policies RoutePolicies) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := strings.TrimPrefix(c.FullPath(), basePath)
+		policy, ok := policies[RouteKey(c.Request.Method, path)]
+		if !ok || !policy.Allows(c.GetString("role"), c.GetStringSlice("permissions")) {
+			c.Error(errors.ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -52,6 +52,11 @@ func (rl *RateLimiter) cleanup() {
 }
 
 func (rl *RateLimiter) Allow(ip string) bool {
+	return rl.AllowLimit(ip, rl.limit)
+}
+
+// AllowLimit is Allow with a per-key limit (e.g. each API key has its own quota)
+func (rl *RateLimiter) AllowLimit(key string, limit int) bool {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
@@ -60,18 +65,18 @@ func (rl *RateLimiter) Allow(ip string) bool {
 
 	// Filter old requests
 	var valid []time.Time
-	for _, t := range rl.requests[ip] {
+	for _, t := range rl.requests[key] {
 		if t.After(windowStart) {
 			valid = append(valid, t)
 		}
 	}
 
-	if len(valid) >= rl.limit {
-		rl.requests[ip] = valid
+	if len(valid) >= limit {
+		rl.requests[key] = valid
 		return false
 	}
 
-	rl.requests[ip] = append(valid, now)
+	rl.requests[key] = append(valid, now)
 	return true
 }
 
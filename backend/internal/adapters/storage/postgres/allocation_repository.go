@@ -1,9 +1,12 @@
 package postgres
 
 import (
+	"strings"
+
 	"github.com/google/uuid"
 	"github.com/phuc/cmms-backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ---- Assign Repository ----
@@ -18,7 +21,7 @@ func (r *assignRepository) Create(assign *domain.Assign) error {
 	return r.db.Create(assign).Error
 }
 
-func (r *assignRepository) FindAll() ([]domain.Assign, error) {
+func (r *assignRepository) FindAll(scope *domain.VisibilityScope) ([]domain.Assign, error) {
 	var assigns []domain.Assign
 	err := r.db.Preload("Project").Preload("ModelProject").Preload("Template").
 		Preload("DetailAssigns", func(db *gorm.DB) *gorm.DB { return db.Order("detail_assigns.created_at ASC, detail_assigns.id ASC") }).
@@ -28,12 +31,13 @@ func (r *assignRepository) FindAll() ([]domain.Assign, error) {
 		Preload("DetailAssigns.Config.SubWork").
 		Preload("DetailAssigns.Config.SubWork.Work").
 		Preload("DetailAssigns.Process").
+		Scopes(scopeAssigns(scope)).
 		Where("deleted_at IS NULL").Order("created_at DESC").Find(&assigns).Error
 	return assigns, err
 }
 
 // FindByUserID filters assignments where the user's UUID is in the id_user JSONB array
-func (r *assignRepository) FindByUserID(userID string) ([]domain.Assign, error) {
+func (r *assignRepository) FindByUserID(userID string, scope *domain.VisibilityScope) ([]domain.Assign, error) {
 	var assigns []domain.Assign
 	err := r.db.Preload("Project").Preload("ModelProject").Preload("Template").
 		Preload("DetailAssigns", func(db *gorm.DB) *gorm.DB { return db.Order("detail_assigns.created_at ASC, detail_assigns.id ASC") }).
@@ -43,6 +47,7 @@ func (r *assignRepository) FindByUserID(userID string) ([]domain.Assign, error)
 		Preload("DetailAssigns.Config.SubWork").
 		Preload("DetailAssigns.Config.SubWork.Work").
 		Preload("DetailAssigns.Process").
+		Scopes(scopeAssigns(scope)).
 		Where("id_user::jsonb @> ? AND deleted_at IS NULL", `"`+userID+`"`).
 		Order("end_time ASC NULLS LAST").
 		Find(&assigns).Error
@@ -50,7 +55,7 @@ func (r *assignRepository) FindByUserID(userID string) ([]domain.Assign, error)
 }
 
 
-func (r *assignRepository) FindByID(id uuid.UUID) (*domain.Assign, error) {
+func (r *assignRepository) FindByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.Assign, error) {
 	var assign domain.Assign
 	err := r.db.Preload("Project").Preload("ModelProject").Preload("Template").
 		Preload("DetailAssigns", func(db *gorm.DB) *gorm.DB { return db.Order("detail_assigns.created_at ASC, detail_assigns.id ASC") }).
@@ -60,6 +65,9 @@ func (r *assignRepository) FindByID(id uuid.UUID) (*domain.Assign, error) {
 		Preload("DetailAssigns.Config.SubWork").
 		Preload("DetailAssigns.Config.SubWork.Work").
 		Preload("DetailAssigns.Process").
+		Preload("DetailAssigns.Measurements", orderMeasurements).
+		Preload("DetailAssigns.FormSchema").
+		Scopes(scopeAssigns(scope)).
 		Where("id = ? AND deleted_at IS NULL", id).First(&assign).Error
 	return &assign, err
 }
@@ -96,13 +104,33 @@ func NewDetailAssignRepository(db *gorm.DB) domain.DetailAssignRepository {
 	return &detailAssignRepository{db: db}
 }
 
+func (r *detailAssignRepository) WithTx(tx *gorm.DB) domain.DetailAssignRepository {
+	return &detailAssignRepository{db: tx}
+}
+
+// likeEscaper makes a value match literally inside a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *detailAssignRepository) HasMedia(assignID uuid.UUID, key string) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+	// Evidence holds MinIO URLs or proxy links, both containing the key
+	pattern := "%" + likeEscaper.Replace(key) + "%"
+	var count int64
+	err := r.db.Model(&domain.DetailAssign{}).
+		Where("id_assign = ? AND (data::text LIKE ? OR slot_evidence::text LIKE ?)", assignID, pattern, pattern).
+		Count(&count).Error
+	return count > 0, err
+}
+
 func (r *detailAssignRepository) Create(detail *domain.DetailAssign) error {
 	return r.db.Create(detail).Error
 }
 
 func (r *detailAssignRepository) FindByAssignID(assignID uuid.UUID) ([]domain.DetailAssign, error) {
 	var details []domain.DetailAssign
-	err := r.db.Preload("Config").Preload("Config.Asset").Preload("Config.SubWork").Preload("Process").
+	err := r.db.Preload("Config").Preload("Config.Asset").Preload("Config.SubWork").Preload("Process").Preload("Measurements", orderMeasurements).Preload("FormSchema").
 		Where("id_assign = ? AND deleted_at IS NULL", assignID).
 		Order("created_at ASC").Find(&details).Error
 	return details, err
@@ -110,13 +138,34 @@ func (r *detailAssignRepository) FindByAssignID(assignID uuid.UUID) ([]domain.De
 
 func (r *detailAssignRepository) FindByID(id uuid.UUID) (*domain.DetailAssign, error) {
 	var detail domain.DetailAssign
-	err := r.db.Preload("Config").Preload("Config.Asset").Preload("Config.SubWork").Preload("Process").Preload("Assign").
+	err := r.db.Preload("Config").Preload("Config.Asset").Preload("Config.SubWork").Preload("Process").Preload("Assign").Preload("Measurements", orderMeasurements).Preload("FormSchema").
 		Where("id = ? AND deleted_at IS NULL", id).First(&detail).Error
 	return &detail, err
 }
 
+// orderMeasurements lists readings in the order of their fields
+func orderMeasurements(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
 func (r *detailAssignRepository) Update(detail *domain.DetailAssign) error {
-	return r.db.Save(detail).Error
+	return saveDetailVersioned(r.db, detail)
+}
+
+// saveDetailVersioned writes every column of detail if its row is still at
+// detail.Version, bumping the version (compare-and-swap on the version column).
+func saveDetailVersioned(db *gorm.DB, detail *domain.DetailAssign) error {
+	read := detail.Version
+	detail.Version = read + 1
+	res := db.Model(detail).Select("*").Omit(clause.Associations, "created_at").
+		Where("version = ?", read).Updates(detail)
+	if res.Error == nil && res.RowsAffected == 0 {
+		res.Error = domain.ErrStaleVersion
+	}
+	if res.Error != nil {
+		detail.Version = read
+	}
+	return res.Error
 }
 
 func (r *detailAssignRepository) Delete(id uuid.UUID) error {
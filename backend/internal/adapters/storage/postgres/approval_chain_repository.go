@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type approvalChainRepository struct{ db *gorm.DB }
+
+func NewApprovalChainRepository(db *gorm.DB) domain.ApprovalChainRepository {
+	return &approvalChainRepository{db: db}
+}
+
+func (r *approvalChainRepository) WithTx(tx *gorm.DB) domain.ApprovalChainRepository {
+	return &approvalChainRepository{db: tx}
+}
+
+func (r *approvalChainRepository) find(query string, args ...interface{}) (*domain.ApprovalChain, error) {
+	var chain domain.ApprovalChain
+	err := r.db.Preload("Levels", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Where(query, args...).First(&chain).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &chain, nil
+}
+
+func (r *approvalChainRepository) FindForAssign(projectID uuid.UUID, templateID *uuid.UUID) (*domain.ApprovalChain, error) {
+	if templateID != nil {
+		chain, err := r.FindByTemplate(*templateID)
+		if err != nil || chain != nil {
+			return chain, err
+		}
+	}
+	return r.FindByProject(projectID)
+}
+
+func (r *approvalChainRepository) FindByProject(projectID uuid.UUID) (*domain.ApprovalChain, error) {
+	return r.find("id_project = ?", projectID)
+}
+
+func (r *approvalChainRepository) FindByTemplate(templateID uuid.UUID) (*domain.ApprovalChain, error) {
+	return r.find("id_template = ?", templateID)
+}
+
+func (r *approvalChainRepository) Save(chain *domain.ApprovalChain) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteChains(tx, chain.ProjectID, chain.TemplateID); err != nil {
+			return err
+		}
+		// Levels are created with the chain through the association
+		return tx.Create(chain).Error
+	})
+}
+
+func (r *approvalChainRepository) DeleteByProject(projectID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteChains(tx, &projectID, nil)
+	})
+}
+
+func (r *approvalChainRepository) DeleteByTemplate(templateID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteChains(tx, nil, &templateID)
+	})
+}
+
+// deleteChains removes the chain of a project or template together with its levels
+func deleteChains(tx *gorm.DB, projectID, templateID *uuid.UUID) error {
+	query := tx.Model(&domain.ApprovalChain{})
+	if projectID != nil {
+		query = query.Where("id_project = ?", *projectID)
+	} else {
+		query = query.Where("id_template = ?", *templateID)
+	}
+	var ids []uuid.UUID
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Where("id_chain IN ?", ids).Delete(&domain.ApprovalLevel{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", ids).Delete(&domain.ApprovalChain{}).Error
+}
+
+func (r *approvalChainRepository) FindApprovals(detailID uuid.UUID, round int) ([]domain.DetailApproval, error) {
+	var approvals []domain.DetailApproval
+	err := r.db.Preload("Approver").
+		Where("id_detail_assign = ? AND round = ?", detailID, round).
+		Order("created_at ASC").Find(&approvals).Error
+	return approvals, err
+}
+
+func (r *approvalChainRepository) RecordApproval(approval *domain.DetailApproval, detail *domain.DetailAssign) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(approval).Error; err != nil {
+			return err
+		}
+		return saveDetailVersioned(tx, detail)
+	})
+}
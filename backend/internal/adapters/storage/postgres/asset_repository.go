@@ -18,21 +18,21 @@ func (r *assetRepository) Create(asset *domain.Asset) error {
 	return r.db.Create(asset).Error
 }
 
-func (r *assetRepository) FindAll() ([]domain.Asset, error) {
+func (r *assetRepository) FindAll(scope *domain.VisibilityScope) ([]domain.Asset, error) {
 	var assets []domain.Asset
-	err := r.db.Preload("Project").Where("deleted_at IS NULL").Order("created_at DESC").Find(&assets).Error
+	err := r.db.Preload("Project").Scopes(scopeAssets(scope)).Where("deleted_at IS NULL").Order("created_at DESC").Find(&assets).Error
 	return assets, err
 }
 
-func (r *assetRepository) FindByProjectID(projectID uuid.UUID) ([]domain.Asset, error) {
+func (r *assetRepository) FindByProjectID(projectID uuid.UUID, scope *domain.VisibilityScope) ([]domain.Asset, error) {
 	var assets []domain.Asset
-	err := r.db.Where("id_project = ? AND deleted_at IS NULL", projectID).Order("name ASC").Find(&assets).Error
+	err := r.db.Scopes(scopeAssets(scope)).Where("id_project = ? AND deleted_at IS NULL", projectID).Order("name ASC").Find(&assets).Error
 	return assets, err
 }
 
-func (r *assetRepository) FindByID(id uuid.UUID) (*domain.Asset, error) {
+func (r *assetRepository) FindByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.Asset, error) {
 	var asset domain.Asset
-	err := r.db.Preload("Project").Where("id = ? AND deleted_at IS NULL", id).First(&asset).Error
+	err := r.db.Preload("Project").Scopes(scopeAssets(scope)).Where("id = ? AND deleted_at IS NULL", id).First(&asset).Error
 	return &asset, err
 }
 
@@ -140,10 +140,10 @@ func (r *AttendanceRepository) GetTodayAttendanceByAssign(userID uuid.UUID, assi
 }
 
 // GetUserAttendanceHistory gets attendance history for a user
-func (r *AttendanceRepository) GetUserAttendanceHistory(userID uuid.UUID, limit int) ([]domain.Attendance, error) {
+func (r *AttendanceRepository) GetUserAttendanceHistory(userID uuid.UUID, limit int, scope *domain.VisibilityScope) ([]domain.Attendance, error) {
 	var attendances []domain.Attendance
 	
-	query := r.db.Preload("Project").Preload("Assign").Preload("Assign.Template").Where("id_user = ?", userID).Order("created_at DESC")
+	query := r.db.Preload("Project").Preload("Assign").Preload("Assign.Template").Scopes(scopeAttendances(scope)).Where("id_user = ?", userID).Order("created_at DESC")
 	
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -157,13 +157,14 @@ func (r *AttendanceRepository) GetUserAttendanceHistory(userID uuid.UUID, limit
 }
 
 // GetAllTodayAttendances gets all attendance records for today (for managers)
-func (r *AttendanceRepository) GetAllTodayAttendances() ([]domain.Attendance, error) {
+func (r *AttendanceRepository) GetAllTodayAttendances(scope *domain.VisibilityScope) ([]domain.Attendance, error) {
 	var attendances []domain.Attendance
 	now := time.Now()
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	tomorrow := today.Add(24 * time.Hour)
 	
 	err := r.db.Preload("User").Preload("Project").Preload("Assign").Preload("Assign.Template").
+		Scopes(scopeAttendances(scope)).
 		Where("created_at >= ? AND created_at < ?", today, tomorrow).
 		Order("created_at DESC").
 		Find(&attendances).Error
@@ -176,10 +177,10 @@ func (r *AttendanceRepository) GetAllTodayAttendances() ([]domain.Attendance, er
 }
 
 // GetAllAttendanceHistory gets all attendance records (for managers) with pagination support via limit
-func (r *AttendanceRepository) GetAllAttendanceHistory(limit int) ([]domain.Attendance, error) {
+func (r *AttendanceRepository) GetAllAttendanceHistory(limit int, scope *domain.VisibilityScope) ([]domain.Attendance, error) {
 	var attendances []domain.Attendance
 	
-	query := r.db.Preload("User").Preload("Project").Preload("Assign").Preload("Assign.Template").Order("created_at DESC")
+	query := r.db.Preload("User").Preload("Project").Preload("Assign").Preload("Assign.Template").Scopes(scopeAttendances(scope)).Order("created_at DESC")
 	
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -193,10 +194,10 @@ func (r *AttendanceRepository) GetAllAttendanceHistory(limit int) ([]domain.Atte
 }
 
 // GetUsersOnSite gets all users currently on site
-func (r *AttendanceRepository) GetUsersOnSite() ([]domain.Attendance, error) {
+func (r *AttendanceRepository) GetUsersOnSite(scope *domain.VisibilityScope) ([]domain.Attendance, error) {
 	var attendances []domain.Attendance
 	
-	err := r.db.Preload("User").Preload("Project").
+	err := r.db.Preload("User").Preload("Project").Scopes(scopeAttendances(scope)).
 		Where("site_status = ?", 1).
 		Order("date_checkin DESC").
 		Find(&attendances).Error
@@ -271,6 +272,17 @@ func (r *AttendanceRepository) GetPendingCheckoutRequests(managerID *uuid.UUID)
 	return attendances, nil
 }
 
+// GetVisibleAttendanceByID gets an attendance record by ID if the scope may see it
+func (r *AttendanceRepository) GetVisibleAttendanceByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.Attendance, error) {
+	var attendance domain.Attendance
+	err := r.db.Preload("User").Preload("Project").Preload("Assign").Preload("Assign.Template").
+		Scopes(scopeAttendances(scope)).First(&attendance, "attendances.id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
 // GetAttendanceByID gets an attendance record by ID
 func (r *AttendanceRepository) GetAttendanceByID(id uuid.UUID) (*domain.Attendance, error) {
 	var attendance domain.Attendance
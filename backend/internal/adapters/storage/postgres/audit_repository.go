@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type auditRepository struct{ db *gorm.DB }
+
+func NewAuditRepository(db *gorm.DB) domain.AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) WithTx(tx *gorm.DB) domain.AuditRepository {
+	return &auditRepository{db: tx}
+}
+
+func (r *auditRepository) Create(logs []domain.AuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return r.db.Create(&logs).Error
+}
+
+func (r *auditRepository) Find(filter domain.AuditFilter) ([]domain.AuditLog, int64, error) {
+	query := r.db.Model(&domain.AuditLog{})
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.ActorType != "" {
+		query = query.Where("actor_type = ?", filter.ActorType)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("id_actor = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []domain.AuditLog
+	query = query.Order("created_at DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	err := query.Find(&logs).Error
+	return logs, total, err
+}
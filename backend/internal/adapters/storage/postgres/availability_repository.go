@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type availabilityRepository struct{ db *gorm.DB }
+
+func NewAvailabilityRepository(db *gorm.DB) domain.AvailabilityRepository {
+	return &availabilityRepository{db: db}
+}
+
+func (r *availabilityRepository) Create(entry *domain.Unavailability) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *availabilityRepository) FindByID(id uuid.UUID) (*domain.Unavailability, error) {
+	var entry domain.Unavailability
+	if err := r.db.Preload("User").First(&entry, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *availabilityRepository) Update(entry *domain.Unavailability) error {
+	return r.db.Model(entry).Select("kind", "start_time", "end_time", "note", "updated_at").Updates(entry).Error
+}
+
+func (r *availabilityRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&domain.Unavailability{}, "id = ?", id).Error
+}
+
+func (r *availabilityRepository) FindUnavailable(userIDs []uuid.UUID, from, to time.Time) ([]domain.Unavailability, error) {
+	var entries []domain.Unavailability
+	if len(userIDs) == 0 {
+		return entries, nil
+	}
+	err := r.db.Where("id_user IN ? AND start_time < ? AND end_time > ?", userIDs, to, from).
+		Order("start_time ASC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *availabilityRepository) FindAssigns(userIDs []uuid.UUID, from, to time.Time, excludeID *uuid.UUID) ([]domain.Assign, error) {
+	var assigns []domain.Assign
+	if len(userIDs) == 0 {
+		return assigns, nil
+	}
+	// One containment test per user keeps the GIN index on id_user usable
+	tests := make([]string, len(userIDs))
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		tests[i] = "assigns.id_user::jsonb @> ?"
+		args[i] = assigneeJSON(id)
+	}
+	q := r.db.Preload("Project").
+		Where("("+strings.Join(tests, " OR ")+")", args...).
+		Where("assigns.start_time < ? AND assigns.end_time > ?", to, from)
+	if excludeID != nil {
+		q = q.Where("assigns.id <> ?", *excludeID)
+	}
+	err := q.Order("assigns.start_time ASC").Find(&assigns).Error
+	return assigns, err
+}
+
+func (r *availabilityRepository) FindTeamUsers(teamID uuid.UUID) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.Where("id_team = ?", teamID).Order("name ASC").Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type certificationRepository struct{ db *gorm.DB }
+
+func NewCertificationRepository(db *gorm.DB) domain.CertificationRepository {
+	return &certificationRepository{db: db}
+}
+
+func (r *certificationRepository) Create(cert *domain.Certification) error {
+	return r.db.Create(cert).Error
+}
+
+func (r *certificationRepository) FindAll() ([]domain.Certification, error) {
+	var certs []domain.Certification
+	err := r.db.Order("name ASC").Find(&certs).Error
+	return certs, err
+}
+
+func (r *certificationRepository) FindByIDs(ids []uuid.UUID) ([]domain.Certification, error) {
+	var certs []domain.Certification
+	if len(ids) == 0 {
+		return certs, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&certs).Error
+	return certs, err
+}
+
+func (r *certificationRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&domain.Certification{}, "id = ?", id).Error
+}
+
+func (r *certificationRepository) FindUserCertifications(userIDs []uuid.UUID) ([]domain.UserCertification, error) {
+	var certs []domain.UserCertification
+	if len(userIDs) == 0 {
+		return certs, nil
+	}
+	err := r.db.Preload("Certification").Where("id_user IN ?", userIDs).Find(&certs).Error
+	return certs, err
+}
+
+func (r *certificationRepository) SaveUserCertification(cert *domain.UserCertification) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id_user"}, {Name: "id_certification"}},
+		DoUpdates: clause.AssignmentColumns([]string{"issued_at", "expires_at", "updated_at"}),
+	}).Create(cert).Error
+}
+
+func (r *certificationRepository) RemoveUserCertification(userID, certificationID uuid.UUID) error {
+	return r.db.Where("id_user = ? AND id_certification = ?", userID, certificationID).
+		Delete(&domain.UserCertification{}).Error
+}
+
+func (r *certificationRepository) FindWorkRequirements(workIDs []uuid.UUID) ([]domain.WorkCertification, error) {
+	var reqs []domain.WorkCertification
+	if len(workIDs) == 0 {
+		return reqs, nil
+	}
+	err := r.db.Preload("Certification").Where("id_work IN ?", workIDs).Find(&reqs).Error
+	return reqs, err
+}
+
+func (r *certificationRepository) SetWorkRequirements(workID uuid.UUID, certificationIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id_work = ?", workID).Delete(&domain.WorkCertification{}).Error; err != nil {
+			return err
+		}
+		if len(certificationIDs) == 0 {
+			return nil
+		}
+		reqs := make([]domain.WorkCertification, len(certificationIDs))
+		for i, id := range certificationIDs {
+			reqs[i] = domain.WorkCertification{WorkID: workID, CertificationID: id}
+		}
+		return tx.Create(&reqs).Error
+	})
+}
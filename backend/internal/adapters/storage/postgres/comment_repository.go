@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type commentRepository struct{ db *gorm.DB }
+
+func NewCommentRepository(db *gorm.DB) domain.CommentRepository {
+	return &commentRepository{db: db}
+}
+
+// commentRead is how far a user has read a thread (a task's or an assign's)
+type commentRead struct {
+	UserID     uuid.UUID `gorm:"column:id_user;type:uuid;primaryKey"`
+	ThreadID   uuid.UUID `gorm:"column:id_thread;type:uuid;primaryKey"`
+	LastReadAt time.Time `gorm:"column:last_read_at"`
+}
+
+func (commentRead) TableName() string {
+	return "comment_reads"
+}
+
+func (r *commentRepository) Create(comment *domain.Comment) error {
+	return r.db.Create(comment).Error
+}
+
+func (r *commentRepository) FindByID(id uuid.UUID) (*domain.Comment, error) {
+	var comment domain.Comment
+	if err := r.db.Preload("Author").First(&comment, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *commentRepository) FindThread(assignID uuid.UUID, detailID *uuid.UUID) ([]domain.Comment, error) {
+	q := r.db.Preload("Author").Where("id_assign = ?", assignID)
+	if detailID != nil {
+		q = q.Where("id_detail_assign = ?", *detailID)
+	} else {
+		q = q.Where("id_detail_assign IS NULL")
+	}
+	var comments []domain.Comment
+	err := q.Order("created_at ASC").Find(&comments).Error
+	return comments, err
+}
+
+func (r *commentRepository) Revise(comment *domain.Comment, revision *domain.CommentRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+		return tx.Model(comment).Select("body", "attachments", "mentions", "edited_at", "deleted_at", "updated_at").
+			Updates(comment).Error
+	})
+}
+
+func (r *commentRepository) FindRevisions(commentID uuid.UUID) ([]domain.CommentRevision, error) {
+	var revisions []domain.CommentRevision
+	err := r.db.Preload("Actor").Where("id_comment = ?", commentID).Order("created_at ASC").Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *commentRepository) MarkRead(userID, threadID uuid.UUID, at time.Time) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id_user"}, {Name: "id_thread"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_read_at"}),
+	}).Create(&commentRead{UserID: userID, ThreadID: threadID, LastReadAt: at}).Error
+}
+
+func (r *commentRepository) CountUnread(userID uuid.UUID, assignID *uuid.UUID) ([]domain.UnreadThread, error) {
+	q := r.db.Table("comments").
+		Select("comments.id_assign AS assign_id, comments.id_detail_assign AS detail_assign_id, COUNT(*) AS unread").
+		Joins("JOIN assigns ON assigns.id = comments.id_assign AND assigns.deleted_at IS NULL").
+		Joins("LEFT JOIN comment_reads ON comment_reads.id_user = ? AND comment_reads.id_thread = COALESCE(comments.id_detail_assign, comments.id_assign)", userID).
+		Where("comments.deleted_at IS NULL AND comments.id_author <> ?", userID).
+		Where("comment_reads.last_read_at IS NULL OR comments.created_at > comment_reads.last_read_at").
+		Where("assigns.id_user::jsonb @> ? OR comments.mentions @> ?", assigneeJSON(userID), "["+assigneeJSON(userID)+"]")
+	if assignID != nil {
+		q = q.Where("comments.id_assign = ?", *assignID)
+	}
+	var threads []domain.UnreadThread
+	err := q.Group("comments.id_assign, comments.id_detail_assign").Scan(&threads).Error
+	return threads, err
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type defectRepository struct{ db *gorm.DB }
+
+func NewDefectRepository(db *gorm.DB) domain.DefectRepository {
+	return &defectRepository{db: db}
+}
+
+func (r *defectRepository) Create(defect *domain.Defect) error {
+	return r.db.Omit(clause.Associations).Create(defect).Error
+}
+
+func (r *defectRepository) FindAll(filter domain.DefectFilter, scope *domain.VisibilityScope) ([]domain.Defect, error) {
+	defects := make([]domain.Defect, 0)
+	q := r.db.Preload("Asset").Preload("Assignee").Preload("Reporter").Preload("Assign").
+		Scopes(scopeDefects(scope))
+	if filter.ProjectID != nil {
+		q = q.Where("defects.id_project = ?", *filter.ProjectID)
+	}
+	if filter.AssetID != nil {
+		q = q.Where("defects.id_asset = ?", *filter.AssetID)
+	}
+	if filter.AssigneeID != nil {
+		q = q.Where("defects.id_assignee = ?", *filter.AssigneeID)
+	}
+	if filter.Status != "" {
+		q = q.Where("defects.status = ?", filter.Status)
+	}
+	if filter.Severity != "" {
+		q = q.Where("defects.severity = ?", filter.Severity)
+	}
+	err := q.Order("defects.created_at DESC").Find(&defects).Error
+	return defects, err
+}
+
+func (r *defectRepository) FindByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.Defect, error) {
+	var defect domain.Defect
+	err := r.db.Preload("Asset").Preload("Assignee").Preload("Reporter").Preload("Assign").Preload("DetailAssign").
+		Scopes(scopeDefects(scope)).Where("defects.id = ?", id).First(&defect).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &defect, nil
+}
+
+func (r *defectRepository) Update(defect *domain.Defect) error {
+	return r.db.Omit(clause.Associations).Save(defect).Error
+}
+
+func (r *defectRepository) Convert(defect *domain.Defect, assign *domain.Assign, details []domain.DetailAssign) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(assign).Error; err != nil {
+			return err
+		}
+		if len(details) > 0 {
+			if err := tx.Omit(clause.Associations).Create(&details).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Omit(clause.Associations).Save(defect).Error
+	})
+}
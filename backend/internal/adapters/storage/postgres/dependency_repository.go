@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type configDependencyRepository struct{ db *gorm.DB }
+
+func NewConfigDependencyRepository(db *gorm.DB) domain.ConfigDependencyRepository {
+	return &configDependencyRepository{db: db}
+}
+
+func (r *configDependencyRepository) FindByTemplate(templateID uuid.UUID) ([]domain.ConfigDependency, error) {
+	var deps []domain.ConfigDependency
+	err := r.db.Where("id_template = ?", templateID).Order("created_at ASC").Find(&deps).Error
+	return deps, err
+}
+
+func (r *configDependencyRepository) ReplaceForTemplate(templateID uuid.UUID, deps []domain.ConfigDependency) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id_template = ?", templateID).Delete(&domain.ConfigDependency{}).Error; err != nil {
+			return err
+		}
+		if len(deps) == 0 {
+			return nil
+		}
+		return tx.Create(&deps).Error
+	})
+}
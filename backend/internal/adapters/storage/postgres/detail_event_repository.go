@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type detailEventRepository struct{ db *gorm.DB }
+
+func NewDetailEventRepository(db *gorm.DB) domain.DetailAssignEventRepository {
+	return &detailEventRepository{db: db}
+}
+
+func (r *detailEventRepository) WithTx(tx *gorm.DB) domain.DetailAssignEventRepository {
+	return &detailEventRepository{db: tx}
+}
+
+func (r *detailEventRepository) Create(event *domain.DetailAssignEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *detailEventRepository) FindByDetail(detailID uuid.UUID) ([]domain.DetailAssignEvent, error) {
+	var events []domain.DetailAssignEvent
+	err := r.db.Preload("Actor").Where("id_detail_assign = ?", detailID).
+		Order("created_at ASC").Find(&events).Error
+	return events, err
+}
+
+func (r *detailEventRepository) FindByAssign(assignID uuid.UUID) ([]domain.DetailAssignEvent, error) {
+	var events []domain.DetailAssignEvent
+	err := r.db.Preload("Actor").Where("id_assign = ?", assignID).
+		Order("created_at ASC").Find(&events).Error
+	return events, err
+}
+
+func (r *detailEventRepository) FindLatest(detailID uuid.UUID, eventType domain.DetailEvent) (*domain.DetailAssignEvent, error) {
+	var event domain.DetailAssignEvent
+	err := r.db.Preload("Actor").Where("id_detail_assign = ? AND event_type = ?", detailID, eventType).
+		Order("created_at DESC").First(&event).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type formSchemaRepository struct{ db *gorm.DB }
+
+func NewFormSchemaRepository(db *gorm.DB) domain.FormSchemaRepository {
+	return &formSchemaRepository{db: db}
+}
+
+// ownedBy limits a form_schemas query to the forms of a sub-work or a config
+func ownedBy(subWorkID, configID *uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if configID != nil {
+			return db.Where("id_config = ?", *configID)
+		}
+		return db.Where("id_sub_work = ?", subWorkID)
+	}
+}
+
+func (r *formSchemaRepository) Create(schema *domain.FormSchema) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&domain.FormSchema{}).Scopes(ownedBy(schema.SubWorkID, schema.ConfigID)).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		schema.Version = latest + 1
+		return tx.Create(schema).Error
+	})
+}
+
+func (r *formSchemaRepository) FindByID(id uuid.UUID) (*domain.FormSchema, error) {
+	var schema domain.FormSchema
+	if err := r.db.First(&schema, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (r *formSchemaRepository) FindVersions(subWorkID, configID *uuid.UUID) ([]domain.FormSchema, error) {
+	var schemas []domain.FormSchema
+	err := r.db.Scopes(ownedBy(subWorkID, configID)).Order("version DESC").Find(&schemas).Error
+	return schemas, err
+}
+
+func (r *formSchemaRepository) FindLatest(configIDs, subWorkIDs []uuid.UUID) ([]domain.FormSchema, error) {
+	if len(configIDs) == 0 && len(subWorkIDs) == 0 {
+		return nil, nil
+	}
+	var all []domain.FormSchema
+	q := r.db.Where("1 = 0")
+	if len(configIDs) > 0 {
+		q = q.Or("id_config IN ?", configIDs)
+	}
+	if len(subWorkIDs) > 0 {
+		q = q.Or("id_sub_work IN ?", subWorkIDs)
+	}
+	if err := q.Order("version DESC").Find(&all).Error; err != nil {
+		return nil, err
+	}
+	// Newest first, so the first version seen of each form is its latest
+	seen := make(map[uuid.UUID]bool)
+	var latest []domain.FormSchema
+	for _, s := range all {
+		owner := s.SubWorkID
+		if s.ConfigID != nil {
+			owner = s.ConfigID
+		}
+		if owner == nil || seen[*owner] {
+			continue
+		}
+		seen[*owner] = true
+		latest = append(latest, s)
+	}
+	return latest, nil
+}
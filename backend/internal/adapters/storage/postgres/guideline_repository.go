@@ -12,10 +12,10 @@ func NewGuideLineRepository(db *gorm.DB) domain.GuideLineRepository {
 	return &guideLineRepository{db: db}
 }
 
-// FindBySubWorkID returns the guideline for a given sub-work, or nil if not found
-func (r *guideLineRepository) FindBySubWorkID(subWorkID uuid.UUID) (*domain.GuideLine, error) {
+// FindBySubWorkID returns the guideline for a given sub-work, or nil if not found (or not visible)
+func (r *guideLineRepository) FindBySubWorkID(subWorkID uuid.UUID, scope *domain.VisibilityScope) (*domain.GuideLine, error) {
 	var g domain.GuideLine
-	err := r.db.Where("id_sub_work = ?", subWorkID).First(&g).Error
+	err := r.db.Scopes(scopeGuideLines(scope)).Where("id_sub_work = ?", subWorkID).First(&g).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -27,7 +27,7 @@ func (r *guideLineRepository) FindBySubWorkID(subWorkID uuid.UUID) (*domain.Guid
 
 // Save creates or updates a guideline (upsert by id_sub_work)
 func (r *guideLineRepository) Save(g *domain.GuideLine) error {
-	existing, err := r.FindBySubWorkID(g.SubWorkID)
+	existing, err := r.FindBySubWorkID(g.SubWorkID, nil)
 	if err != nil {
 		return err
 	}
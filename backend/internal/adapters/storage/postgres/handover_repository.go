@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type handoverRepository struct{ db *gorm.DB }
+
+func NewDetailHandoverRepository(db *gorm.DB) domain.DetailHandoverRepository {
+	return &handoverRepository{db: db}
+}
+
+func (r *handoverRepository) FindByDetail(detailID uuid.UUID) ([]domain.DetailHandover, error) {
+	var handovers []domain.DetailHandover
+	err := r.db.Preload("FromUser").Preload("ToUser").Preload("Actor").
+		Where("id_detail_assign = ?", detailID).Order("created_at ASC").Find(&handovers).Error
+	return handovers, err
+}
+
+func (r *handoverRepository) FindByAssign(assignID uuid.UUID) ([]domain.DetailHandover, error) {
+	var handovers []domain.DetailHandover
+	err := r.db.Preload("FromUser").Preload("ToUser").Preload("Actor").
+		Where("id_assign = ?", assignID).Order("created_at ASC").Find(&handovers).Error
+	return handovers, err
+}
+
+func (r *handoverRepository) Apply(assign *domain.Assign, details []*domain.DetailAssign, handovers []domain.DetailHandover) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, detail := range details {
+			if err := saveDetailVersioned(tx, detail); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&domain.Assign{}).Where("id = ?", assign.ID).
+			Update("id_user", assign.UserIDs).Error; err != nil {
+			return err
+		}
+		if len(handovers) == 0 {
+			return nil
+		}
+		return tx.Omit("FromUser", "ToUser", "Actor").Create(&handovers).Error
+	})
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errOccurrenceRecorded rolls back a Materialise that lost to an earlier run
+var errOccurrenceRecorded = errors.New("occurrence already recorded")
+
+type maintenancePlanRepository struct{ db *gorm.DB }
+
+func NewMaintenancePlanRepository(db *gorm.DB) domain.MaintenancePlanRepository {
+	return &maintenancePlanRepository{db: db}
+}
+
+func (r *maintenancePlanRepository) Create(plan *domain.MaintenancePlan) error {
+	return r.db.Create(plan).Error
+}
+
+func (r *maintenancePlanRepository) FindAll(projectID *uuid.UUID, scope *domain.VisibilityScope) ([]domain.MaintenancePlan, error) {
+	var plans []domain.MaintenancePlan
+	q := r.db.Preload("Project").Preload("Template").Scopes(scopeMaintenancePlans(scope))
+	if projectID != nil {
+		q = q.Where("maintenance_plans.id_project = ?", *projectID)
+	}
+	err := q.Order("created_at DESC").Find(&plans).Error
+	return plans, err
+}
+
+func (r *maintenancePlanRepository) FindByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.MaintenancePlan, error) {
+	var plan domain.MaintenancePlan
+	err := r.db.Preload("Project").Preload("Template").Scopes(scopeMaintenancePlans(scope)).
+		Where("maintenance_plans.id = ?", id).First(&plan).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *maintenancePlanRepository) FindActive() ([]domain.MaintenancePlan, error) {
+	var plans []domain.MaintenancePlan
+	err := r.db.Preload("Template").
+		Joins("JOIN projects p ON p.id = maintenance_plans.id_project AND p.deleted_at IS NULL").
+		Where("maintenance_plans.paused = ?", false).Find(&plans).Error
+	return plans, err
+}
+
+func (r *maintenancePlanRepository) Update(plan *domain.MaintenancePlan) error {
+	return r.db.Omit(clause.Associations).Save(plan).Error
+}
+
+func (r *maintenancePlanRepository) Delete(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&domain.MaintenancePlan{}).Error
+}
+
+func (r *maintenancePlanRepository) FindOccurrences(planID uuid.UUID, from, to time.Time) ([]domain.MaintenanceOccurrence, error) {
+	var occurrences []domain.MaintenanceOccurrence
+	err := r.db.Where("id_plan = ? AND due_at BETWEEN ? AND ?", planID, from, to).
+		Order("due_at ASC").Find(&occurrences).Error
+	return occurrences, err
+}
+
+func (r *maintenancePlanRepository) Skip(planID uuid.UUID, dueAt time.Time) (bool, error) {
+	occurrence := domain.MaintenanceOccurrence{ID: uuid.New(), PlanID: planID, DueAt: dueAt, Skipped: true}
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&occurrence)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *maintenancePlanRepository) Materialise(planID uuid.UUID, dueAt time.Time, assign *domain.Assign, details []domain.DetailAssign) (bool, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(assign).Error; err != nil {
+			return err
+		}
+		if len(details) > 0 {
+			if err := tx.Omit(clause.Associations).Create(&details).Error; err != nil {
+				return err
+			}
+		}
+		occurrence := domain.MaintenanceOccurrence{ID: uuid.New(), PlanID: planID, DueAt: dueAt, AssignID: &assign.ID}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&occurrence)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errOccurrenceRecorded
+		}
+		return nil
+	})
+	if errors.Is(err, errOccurrenceRecorded) {
+		return false, nil
+	}
+	return err == nil, err
+}
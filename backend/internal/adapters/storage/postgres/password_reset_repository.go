@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type passwordResetRepository struct{ db *gorm.DB }
+
+func NewPasswordResetRepository(db *gorm.DB) domain.PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(token *domain.PasswordResetToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *passwordResetRepository) FindByTokenHash(hash string) (*domain.PasswordResetToken, error) {
+	var token domain.PasswordResetToken
+	err := r.db.First(&token, "token_hash = ?", hash).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *passwordResetRepository) MarkUsed(id uuid.UUID) (bool, error) {
+	// Conditional update so two concurrent resets cannot both consume the token
+	res := r.db.Model(&domain.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *passwordResetRepository) InvalidateForUser(userID uuid.UUID) error {
+	return r.db.Model(&domain.PasswordResetToken{}).
+		Where("id_user = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
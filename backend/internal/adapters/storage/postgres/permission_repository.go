@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type permissionRepository struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) domain.PermissionRepository {
+	return &permissionRepository{db: db}
+}
+
+func (r *permissionRepository) FindAll() ([]domain.Permission, error) {
+	var perms []domain.Permission
+	err := r.db.Order("code ASC").Find(&perms).Error
+	return perms, err
+}
+
+func (r *permissionRepository) FindByCodes(codes []string) ([]domain.Permission, error) {
+	var perms []domain.Permission
+	if len(codes) == 0 {
+		return perms, nil
+	}
+	err := r.db.Where("code IN ?", codes).Find(&perms).Error
+	return perms, err
+}
+
+func (r *permissionRepository) FindByRoleID(roleID uuid.UUID) ([]domain.Permission, error) {
+	var perms []domain.Permission
+	err := r.db.
+		Joins("JOIN role_permissions ON role_permissions.id_permission = permissions.id").
+		Where("role_permissions.id_role = ?", roleID).
+		Order("permissions.code ASC").
+		Find(&perms).Error
+	return perms, err
+}
+
+// FindCodesByRoleName resolves the permission codes granted to a (non-deleted) role by name
+func (r *permissionRepository) FindCodesByRoleName(roleName string) ([]string, error) {
+	var codes []string
+	err := r.db.Table("permissions").
+		Joins("JOIN role_permissions ON role_permissions.id_permission = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.id_role AND roles.deleted_at IS NULL").
+		Where("roles.name = ?", roleName).
+		Order("permissions.code ASC").
+		Pluck("permissions.code", &codes).Error
+	return codes, err
+}
+
+// ReplaceRolePermissions swaps the whole grant set of a role in one transaction
+func (r *permissionRepository) ReplaceRolePermissions(roleID uuid.UUID, permissionIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id_role = ?", roleID).Delete(&domain.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if len(permissionIDs) == 0 {
+			return nil
+		}
+		grants := make([]domain.RolePermission, 0, len(permissionIDs))
+		for _, pid := range permissionIDs {
+			grants = append(grants, domain.RolePermission{RoleID: roleID, PermissionID: pid})
+		}
+		return tx.Create(&grants).Error
+	})
+}
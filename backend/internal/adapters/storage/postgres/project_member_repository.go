@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type projectMemberRepository struct{ db *gorm.DB }
+
+func NewProjectMemberRepository(db *gorm.DB) domain.ProjectMemberRepository {
+	return &projectMemberRepository{db: db}
+}
+
+func (r *projectMemberRepository) FindByProjectID(projectID uuid.UUID) ([]domain.ProjectMember, error) {
+	var members []domain.ProjectMember
+	err := r.db.Preload("User").Where("id_project = ?", projectID).Order("created_at ASC").Find(&members).Error
+	return members, err
+}
+
+// Add grants membership to the given users; existing members are left untouched
+func (r *projectMemberRepository) Add(projectID uuid.UUID, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	members := make([]domain.ProjectMember, 0, len(userIDs))
+	for _, uid := range userIDs {
+		members = append(members, domain.ProjectMember{ID: uuid.New(), ProjectID: projectID, UserID: uid})
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id_project"}, {Name: "id_user"}},
+		DoNothing: true,
+	}).Create(&members).Error
+}
+
+func (r *projectMemberRepository) Remove(projectID, userID uuid.UUID) error {
+	return r.db.Where("id_project = ? AND id_user = ?", projectID, userID).Delete(&domain.ProjectMember{}).Error
+}
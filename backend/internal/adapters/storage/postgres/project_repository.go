@@ -18,15 +18,15 @@ func (r *projectRepositoryV2) Create(project *domain.Project) error {
 	return r.db.Create(project).Error
 }
 
-func (r *projectRepositoryV2) FindAll() ([]domain.Project, error) {
+func (r *projectRepositoryV2) FindAll(scope *domain.VisibilityScope) ([]domain.Project, error) {
 	var projects []domain.Project
-	err := r.db.Preload("Owner").Where("deleted_at IS NULL").Order("created_at DESC").Find(&projects).Error
+	err := r.db.Preload("Owner").Scopes(scopeProjects(scope)).Where("deleted_at IS NULL").Order("created_at DESC").Find(&projects).Error
 	return projects, err
 }
 
-func (r *projectRepositoryV2) FindByID(id uuid.UUID) (*domain.Project, error) {
+func (r *projectRepositoryV2) FindByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.Project, error) {
 	var project domain.Project
-	err := r.db.Preload("Owner").Where("id = ? AND deleted_at IS NULL", id).First(&project).Error
+	err := r.db.Preload("Owner").Scopes(scopeProjects(scope)).Where("id = ? AND deleted_at IS NULL", id).First(&project).Error
 	return &project, err
 }
 
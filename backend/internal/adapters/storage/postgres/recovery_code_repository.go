@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type recoveryCodeRepository struct{ db *gorm.DB }
+
+func NewRecoveryCodeRepository(db *gorm.DB) domain.RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+func (r *recoveryCodeRepository) Replace(userID uuid.UUID, codes []domain.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id_user = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *recoveryCodeRepository) Consume(userID uuid.UUID, codeHash string) (bool, error) {
+	res := r.db.Model(&domain.RecoveryCode{}).
+		Where("id_user = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *recoveryCodeRepository) CountUnused(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.RecoveryCode{}).Where("id_user = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
@@ -1,13 +1,18 @@
 package postgres
 
 import (
+	"github.com/google/uuid"
 	"github.com/phuc/cmms-backend/internal/domain"
 	"gorm.io/gorm"
 )
 
 type RoleRepository interface {
 	FindAll() ([]domain.Role, error)
+	FindByID(id uuid.UUID) (*domain.Role, error)
 	Create(role *domain.Role) error
+	Update(role *domain.Role) error
+	Delete(id uuid.UUID) error
+	CountUsers(id uuid.UUID) (int64, error)
 }
 
 type roleRepository struct {
@@ -24,6 +29,32 @@ func (r *roleRepository) FindAll() ([]domain.Role, error) {
 	return roles, err
 }
 
+func (r *roleRepository) FindByID(id uuid.UUID) (*domain.Role, error) {
+	var role domain.Role
+	if err := r.db.First(&role, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
 func (r *roleRepository) Create(role *domain.Role) error {
 	return r.db.Create(role).Error
 }
+
+func (r *roleRepository) Update(role *domain.Role) error {
+	return r.db.Model(role).Updates(map[string]interface{}{
+		"name":        role.Name,
+		"require_mfa": role.RequireMFA,
+	}).Error
+}
+
+func (r *roleRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&domain.Role{}, "id = ?", id).Error
+}
+
+// CountUsers counts active users currently holding the role
+func (r *roleRepository) CountUsers(id uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.User{}).Where("id_role = ?", id).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type serviceAccountRepository struct{ db *gorm.DB }
+
+func NewServiceAccountRepository(db *gorm.DB) domain.ServiceAccountRepository {
+	return &serviceAccountRepository{db: db}
+}
+
+func (r *serviceAccountRepository) Create(account *domain.ServiceAccount) error {
+	return r.db.Omit("Keys").Create(account).Error
+}
+
+func (r *serviceAccountRepository) FindAll() ([]domain.ServiceAccount, error) {
+	var accounts []domain.ServiceAccount
+	err := r.db.Preload("Keys", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	}).Order("name").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *serviceAccountRepository) FindByID(id uuid.UUID) (*domain.ServiceAccount, error) {
+	var account domain.ServiceAccount
+	err := r.db.Preload("Keys", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	}).First(&account, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *serviceAccountRepository) Update(account *domain.ServiceAccount) error {
+	return r.db.Model(account).Updates(map[string]interface{}{
+		"name":        account.Name,
+		"description": account.Description,
+		"disabled":    account.Disabled,
+	}).Error
+}
+
+func (r *serviceAccountRepository) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.APIKey{}).
+			Where("id_service_account = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.ServiceAccount{}, "id = ?", id).Error
+	})
+}
+
+func (r *serviceAccountRepository) CreateKey(key *domain.APIKey) error {
+	return r.db.Omit("ServiceAccount").Create(key).Error
+}
+
+func (r *serviceAccountRepository) FindKey(id uuid.UUID) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.First(&key, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *serviceAccountRepository) FindKeyByHash(hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.db.Preload("ServiceAccount").First(&key, "key_hash = ?", hash).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *serviceAccountRepository) UpdateKey(key *domain.APIKey) error {
+	return r.db.Model(key).Updates(map[string]interface{}{
+		"expires_at":     key.ExpiresAt,
+		"revoked_at":     key.RevokedAt,
+		"id_replaced_by": key.ReplacedByID,
+	}).Error
+}
+
+func (r *serviceAccountRepository) TouchKey(id uuid.UUID, at time.Time, ip string) error {
+	return r.db.Model(&domain.APIKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": at,
+		"last_used_ip": ip,
+	}).Error
+}
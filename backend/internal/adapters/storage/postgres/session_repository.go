@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type sessionRepository struct{ db *gorm.DB }
+
+func NewSessionRepository(db *gorm.DB) domain.SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *domain.UserSession) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) FindByID(id uuid.UUID) (*domain.UserSession, error) {
+	var session domain.UserSession
+	err := r.db.First(&session, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) FindByTokenHash(hash string) (*domain.UserSession, error) {
+	var session domain.UserSession
+	err := r.db.Where("refresh_token_hash = ? OR previous_token_hash = ?", hash, hash).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) Update(session *domain.UserSession) error {
+	return r.db.Save(session).Error
+}
+
+func (r *sessionRepository) FindActiveByUserID(userID uuid.UUID) ([]domain.UserSession, error) {
+	var sessions []domain.UserSession
+	err := r.db.Where("id_user = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) Revoke(ids []uuid.UUID, reason string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&domain.UserSession{}).
+		Where("id IN ? AND revoked_at IS NULL", ids).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type shareLinkRepository struct{ db *gorm.DB }
+
+func NewShareLinkRepository(db *gorm.DB) domain.ShareLinkRepository {
+	return &shareLinkRepository{db: db}
+}
+
+func (r *shareLinkRepository) Create(link *domain.ShareLink) error {
+	return r.db.Create(link).Error
+}
+
+func (r *shareLinkRepository) FindByID(id uuid.UUID) (*domain.ShareLink, error) {
+	var link domain.ShareLink
+	err := r.db.First(&link, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *shareLinkRepository) FindByResource(resourceType, resourceID string) ([]domain.ShareLink, error) {
+	var links []domain.ShareLink
+	err := r.db.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Order("created_at DESC").Find(&links).Error
+	return links, err
+}
+
+func (r *shareLinkRepository) Revoke(id uuid.UUID) error {
+	return r.db.Model(&domain.ShareLink{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *shareLinkRepository) RevokeByResource(resourceType, resourceID string) (int64, error) {
+	res := r.db.Model(&domain.ShareLink{}).
+		Where("resource_type = ? AND resource_id = ? AND revoked_at IS NULL", resourceType, resourceID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected, res.Error
+}
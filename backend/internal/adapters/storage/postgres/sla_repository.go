@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type slaRepository struct{ db *gorm.DB }
+
+func NewSLARepository(db *gorm.DB) domain.SLARepository {
+	return &slaRepository{db: db}
+}
+
+func (r *slaRepository) CreatePolicy(policy *domain.SLAPolicy) error {
+	return r.db.Create(policy).Error
+}
+
+func (r *slaRepository) FindPolicies() ([]domain.SLAPolicy, error) {
+	var policies []domain.SLAPolicy
+	err := r.db.Preload("Project").Preload("Work").Order("created_at ASC").Find(&policies).Error
+	return policies, err
+}
+
+func (r *slaRepository) FindPolicyByID(id uuid.UUID) (*domain.SLAPolicy, error) {
+	var policy domain.SLAPolicy
+	err := r.db.Where("id = ?", id).First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *slaRepository) UpdatePolicy(policy *domain.SLAPolicy) error {
+	return r.db.Omit(clause.Associations).Save(policy).Error
+}
+
+func (r *slaRepository) DeletePolicy(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&domain.SLAPolicy{}).Error
+}
+
+func (r *slaRepository) CreateHoliday(holiday *domain.Holiday) error {
+	return r.db.Create(holiday).Error
+}
+
+func (r *slaRepository) FindHolidays() ([]domain.Holiday, error) {
+	var holidays []domain.Holiday
+	err := r.db.Order("date ASC").Find(&holidays).Error
+	return holidays, err
+}
+
+func (r *slaRepository) DeleteHoliday(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&domain.Holiday{}).Error
+}
+
+func (r *slaRepository) FindOpenDetails() ([]domain.DetailAssign, error) {
+	var details []domain.DetailAssign
+	err := r.db.Preload("Assign").Preload("Config.SubWork").
+		Joins("JOIN assigns a ON a.id = detail_assigns.id_assign AND a.deleted_at IS NULL").
+		Where("detail_assigns.state <> ?", domain.DetailStateApproved).
+		Find(&details).Error
+	return details, err
+}
+
+func (r *slaRepository) FindAssignDetails(assignID uuid.UUID) ([]domain.DetailAssign, error) {
+	var details []domain.DetailAssign
+	err := r.db.Preload("Assign").Preload("Config.SubWork").
+		Where("id_assign = ?", assignID).Order("created_at ASC").Find(&details).Error
+	return details, err
+}
+
+func (r *slaRepository) FindEvents(detailIDs []uuid.UUID) ([]domain.DetailAssignEvent, error) {
+	var events []domain.DetailAssignEvent
+	if len(detailIDs) == 0 {
+		return events, nil
+	}
+	err := r.db.Where("id_detail_assign IN ?", detailIDs).Order("created_at ASC").Find(&events).Error
+	return events, err
+}
+
+func (r *slaRepository) FindTracking(detailIDs []uuid.UUID) ([]domain.DetailSLA, error) {
+	var rows []domain.DetailSLA
+	if len(detailIDs) == 0 {
+		return rows, nil
+	}
+	err := r.db.Where("id_detail_assign IN ?", detailIDs).Find(&rows).Error
+	return rows, err
+}
+
+func (r *slaRepository) SaveTracking(rows []domain.DetailSLA) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return r.db.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id_detail_assign"}},
+		UpdateAll: true,
+	}).CreateInBatches(&rows, 500).Error
+}
+
+func (r *slaRepository) DeleteTracking(detailIDs []uuid.UUID) error {
+	if len(detailIDs) == 0 {
+		return nil
+	}
+	return r.db.Where("id_detail_assign IN ?", detailIDs).Delete(&domain.DetailSLA{}).Error
+}
+
+func (r *slaRepository) PruneTracking() error {
+	return r.db.Exec(`
+		DELETE FROM detail_sla WHERE id_detail_assign NOT IN (
+			SELECT d.id FROM detail_assigns d
+			JOIN assigns a ON a.id = d.id_assign AND a.deleted_at IS NULL
+			WHERE d.deleted_at IS NULL AND d.state <> ?
+		)`, domain.DetailStateApproved).Error
+}
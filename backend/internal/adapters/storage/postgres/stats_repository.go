@@ -42,13 +42,17 @@ func (r *statsRepository) GetManagerDashboardStats(managerID uuid.UUID) (*domain
 		stats.CompletionRate = float64(stats.CompletedTasks) / float64(stats.TotalTasks) * 100
 	}
 
-	// Top performers (most approved tasks)
+	// Top performers (most approved tasks), credited to whoever submitted them.
+	// Submits backfilled from before event history have no actor, so those
+	// fall back to the users on the assign.
 	var performers []domain.TopPerformer
 	r.db.Raw(`
-		SELECT u.id AS user_id, u.name, COUNT(da.id) AS task_count
+		SELECT u.id AS user_id, u.name, COUNT(DISTINCT da.id) AS task_count
 		FROM detail_assigns da
 		JOIN assigns a ON a.id = da.id_assign AND a.deleted_at IS NULL
-		JOIN users u ON u.deleted_at IS NULL
+		JOIN detail_assign_events e ON e.id_detail_assign = da.id AND e.event_type = 'submit'
+		JOIN users u ON u.deleted_at IS NULL AND (u.id = e.id_actor
+			OR (e.id_actor IS NULL AND a.id_user::jsonb @> to_jsonb(u.id::text)))
 		WHERE da.status_approve = 1 AND da.deleted_at IS NULL
 		GROUP BY u.id, u.name
 		ORDER BY task_count DESC
@@ -56,5 +60,68 @@ func (r *statsRepository) GetManagerDashboardStats(managerID uuid.UUID) (*domain
 	`).Scan(&performers)
 	stats.TopPerformers = performers
 
+	// Open work behind its SLA
+	r.db.Model(&domain.DetailSLA{}).Where("status = ?", domain.SLAAtRisk).Count(&stats.AtRiskTasks)
+	r.db.Model(&domain.DetailSLA{}).Where("status = ?", domain.SLAOverdue).Count(&stats.OverdueTasks)
+
 	return stats, nil
 }
+
+func slaScope(db *gorm.DB, filter domain.SLAFilter) *gorm.DB {
+	q := db.Model(&domain.DetailSLA{})
+	if filter.ProjectID != nil {
+		q = q.Where("detail_sla.id_project = ?", *filter.ProjectID)
+	}
+	if filter.Status != "" {
+		q = q.Where("detail_sla.status = ?", filter.Status)
+	}
+	if filter.Clock != "" {
+		q = q.Where("detail_sla.clock = ?", filter.Clock)
+	}
+	if filter.Scope != nil {
+		q = q.Where("detail_sla.id_project IN (?)", visibleProjectIDs(db, filter.Scope))
+	}
+	return q
+}
+
+// GetSLACounts counts tracked open tasks by status, and per project those at risk or overdue
+func (r *statsRepository) GetSLACounts(filter domain.SLAFilter) (*domain.SLACounts, error) {
+	var rows []struct {
+		Status domain.SLAStatus
+		Count  int64
+	}
+	if err := slaScope(r.db, filter).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := &domain.SLACounts{ByProject: []domain.SLAProjectCount{}}
+	for _, row := range rows {
+		switch row.Status {
+		case domain.SLAOnTrack:
+			counts.OnTrack = row.Count
+		case domain.SLAAtRisk:
+			counts.AtRisk = row.Count
+		case domain.SLAOverdue:
+			counts.Overdue = row.Count
+		}
+	}
+
+	err := slaScope(r.db, filter).
+		Select(`detail_sla.id_project AS project_id, p.name,
+			COUNT(*) FILTER (WHERE detail_sla.status = ?) AS at_risk,
+			COUNT(*) FILTER (WHERE detail_sla.status = ?) AS overdue`, domain.SLAAtRisk, domain.SLAOverdue).
+		Joins("JOIN projects p ON p.id = detail_sla.id_project").
+		Where("detail_sla.status IN ?", []domain.SLAStatus{domain.SLAAtRisk, domain.SLAOverdue}).
+		Group("detail_sla.id_project, p.name").
+		Order("overdue DESC, at_risk DESC").
+		Scan(&counts.ByProject).Error
+	return counts, err
+}
+
+// FindSLATasks lists tracked open tasks, most overdue first
+func (r *statsRepository) FindSLATasks(filter domain.SLAFilter) ([]domain.DetailSLA, error) {
+	tasks := make([]domain.DetailSLA, 0)
+	err := slaScope(r.db, filter).
+		Preload("DetailAssign.Assign.Project").Preload("DetailAssign.Config.Asset").Preload("DetailAssign.Config.SubWork").
+		Order("detail_sla.due_at ASC").Limit(500).Find(&tasks).Error
+	return tasks, err
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type suggestionRepository struct{ db *gorm.DB }
+
+func NewSuggestionRepository(db *gorm.DB) domain.SuggestionRepository {
+	return &suggestionRepository{db: db}
+}
+
+func (r *suggestionRepository) FindCandidates() ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.Preload("Team").Order("name ASC").Find(&users).Error
+	return users, err
+}
+
+func (r *suggestionRepository) FindConfigWorks(configIDs []uuid.UUID) ([]domain.Work, error) {
+	var works []domain.Work
+	if len(configIDs) == 0 {
+		return works, nil
+	}
+	subWorks := r.db.Session(&gorm.Session{NewDB: true}).Table("configs").Select("sub_works.id_work").
+		Joins("JOIN sub_works ON sub_works.id = configs.id_sub_work").
+		Where("configs.id IN ?", configIDs)
+	err := r.db.Where("id IN (?)", subWorks).Order("name ASC").Find(&works).Error
+	return works, err
+}
+
+func (r *suggestionRepository) FindOpenTasks() ([]domain.OpenTask, error) {
+	var tasks []domain.OpenTask
+	err := r.db.Table("detail_assigns").Select("detail_assigns.id_owner, assigns.id_user").
+		Joins("JOIN assigns ON assigns.id = detail_assigns.id_assign AND assigns.deleted_at IS NULL").
+		Where("detail_assigns.deleted_at IS NULL AND detail_assigns.state <> ?", domain.DetailStateApproved).
+		Scan(&tasks).Error
+	return tasks, err
+}
+
+func (r *suggestionRepository) FindReviewStats(workIDs []uuid.UUID) ([]domain.WorkReviewStats, error) {
+	var stats []domain.WorkReviewStats
+	if len(workIDs) == 0 {
+		return stats, nil
+	}
+	// A task counts once it has been approved or rejected; it passed first
+	// time if it was never rejected. It is credited to everyone who submitted it.
+	err := r.db.Raw(`
+		WITH reviewed AS (
+			SELECT d.id, sw.id_work,
+				BOOL_OR(e.event_type = ?) AS rejected
+			FROM detail_assigns d
+			JOIN configs c ON c.id = d.id_config
+			JOIN sub_works sw ON sw.id = c.id_sub_work
+			JOIN detail_assign_events e ON e.id_detail_assign = d.id
+			WHERE d.deleted_at IS NULL AND sw.id_work IN ?
+			GROUP BY d.id, sw.id_work
+			HAVING BOOL_OR(e.event_type IN (?, ?))
+		), submitters AS (
+			SELECT DISTINCT id_detail_assign, id_actor FROM detail_assign_events
+			WHERE event_type = ? AND id_actor IS NOT NULL
+		)
+		SELECT s.id_actor AS user_id, r.id_work AS work_id,
+			COUNT(*) AS reviewed,
+			COUNT(*) FILTER (WHERE NOT r.rejected) AS first_pass
+		FROM reviewed r
+		JOIN submitters s ON s.id_detail_assign = r.id
+		GROUP BY s.id_actor, r.id_work`,
+		domain.DetailEventReject, workIDs, domain.DetailEventApprove, domain.DetailEventReject, domain.DetailEventSubmit).
+		Scan(&stats).Error
+	return stats, err
+}
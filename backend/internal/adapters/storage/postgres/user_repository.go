@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/phuc/cmms-backend/internal/domain"
@@ -95,6 +96,29 @@ func (r *userRepository) UpdateStatus(userID uuid.UUID, status int) error {
 	return r.db.Model(&domain.User{}).Where("id = ?", userID).Update("status_user", status).Error
 }
 
+func (r *userRepository) UpdateLoginState(userID uuid.UUID, failedCount int, lockedUntil *time.Time) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_count": failedCount,
+		"locked_until":       lockedUntil,
+	}).Error
+}
+
+func (r *userRepository) SetTOTP(userID uuid.UUID, secret string, enabled bool) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_enabled":   enabled,
+		"totp_last_step": 0,
+	}).Error
+}
+
+func (r *userRepository) ClaimTOTPStep(userID uuid.UUID, step int64) (bool, error) {
+	// Conditional update so the same code cannot be accepted twice, even concurrently
+	res := r.db.Model(&domain.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return res.RowsAffected == 1, res.Error
+}
+
 func (r *userRepository) GetUserCount() (int64, error) {
 	var count int64
 	if err := r.db.Model(&domain.User{}).Where("deleted_at IS NULL").Count(&count).Error; err != nil {
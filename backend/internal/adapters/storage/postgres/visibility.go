@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+// Visibility scopes shared by every list/get path that takes a *domain.VisibilityScope.
+// A nil scope leaves the query untouched (unrestricted).
+
+// assigneeJSON is the JSONB literal matched against assigns.id_user
+func assigneeJSON(userID uuid.UUID) string {
+	return `"` + userID.String() + `"`
+}
+
+// visibleProjectIDs returns a subquery selecting the project IDs visible to a scope
+func visibleProjectIDs(db *gorm.DB, scope *domain.VisibilityScope) *gorm.DB {
+	fresh := db.Session(&gorm.Session{NewDB: true})
+	if scope.Kind == domain.VisibilityMember {
+		return fresh.Table("project_members").Select("id_project").Where("id_user = ?", scope.UserID)
+	}
+	return fresh.Table("assigns").Select("id_project").
+		Where("id_user::jsonb @> ? AND deleted_at IS NULL", assigneeJSON(scope.UserID))
+}
+
+// assignedAssetIDs returns a subquery selecting assets referenced by the user's assigns, and their parents
+func assignedAssetIDs(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	fresh := db.Session(&gorm.Session{NewDB: true})
+	referenced := fresh.Table("configs").Select("configs.id_asset").
+		Joins("JOIN detail_assigns ON detail_assigns.id_config = configs.id AND detail_assigns.deleted_at IS NULL").
+		Joins("JOIN assigns ON assigns.id = detail_assigns.id_assign AND assigns.deleted_at IS NULL").
+		Where("assigns.id_user::jsonb @> ?", assigneeJSON(userID))
+	parents := fresh.Table("assets").Select("parent_id").
+		Where("parent_id IS NOT NULL AND id IN (?)", referenced)
+	return fresh.Raw("(?) UNION (?)", referenced, parents)
+}
+
+// scopeProjects limits a projects query
+func scopeProjects(scope *domain.VisibilityScope) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope == nil {
+			return db
+		}
+		return db.Where("projects.id IN (?)", visibleProjectIDs(db, scope))
+	}
+}
+
+// scopeAssigns limits an assigns query
+func scopeAssigns(scope *domain.VisibilityScope) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope == nil {
+			return db
+		}
+		if scope.Kind == domain.VisibilityMember {
+			return db.Where("assigns.id_project IN (?)", visibleProjectIDs(db, scope))
+		}
+		return db.Where("assigns.id_user::jsonb @> ?", assigneeJSON(scope.UserID))
+	}
+}
+
+// scopeAssets limits an assets query
+func scopeAssets(scope *domain.VisibilityScope) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope == nil {
+			return db
+		}
+		if scope.Kind == domain.VisibilityMember {
+			return db.Where("assets.id_project IN (?)", visibleProjectIDs(db, scope))
+		}
+		return db.Where("assets.id IN (?)", assignedAssetIDs(db, scope.UserID))
+	}
+}
+
+// scopeGuideLines limits a guidelines query. Members see every guideline
+// (sub-works are shared catalog data); assignees only those of their tasks.
+func scopeGuideLines(scope *domain.VisibilityScope) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope == nil || scope.Kind == domain.VisibilityMember {
+			return db
+		}
+		subWorks := db.Session(&gorm.Session{NewDB: true}).Table("configs").Select("configs.id_sub_work").
+			Joins("JOIN detail_assigns ON detail_assigns.id_config = configs.id AND detail_assigns.deleted_at IS NULL").
+			Joins("JOIN assigns ON assigns.id = detail_assigns.id_assign AND assigns.deleted_at IS NULL").
+			Where("assigns.id_user::jsonb @> ?", assigneeJSON(scope.UserID))
+		return db.Where("guidelines.id_sub_work IN (?)", subWorks)
+	}
+}
+
+// scopeAttendances limits an attendances query. Everyone sees their own records;
+// members also see records logged against their projects.
+func scopeAttendances(scope *domain.VisibilityScope) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope == nil {
+			return db
+		}
+		if scope.Kind == domain.VisibilityMember {
+			return db.Where("attendances.id_user = ? OR attendances.id_project IN (?)", scope.UserID, visibleProjectIDs(db, scope))
+		}
+		return db.Where("attendances.id_user = ?", scope.UserID)
+	}
+}
+
+// scopeDefects limits a defects query. Everyone sees the defects they raised or
+// were given; members also see those of their projects, assignees those of the
+// projects they are assigned to.
+func scopeDefects(scope *domain.VisibilityScope) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope == nil {
+			return db
+		}
+		return db.Where("defects.id_reporter = ? OR defects.id_assignee = ? OR defects.id_project IN (?)",
+			scope.UserID, scope.UserID, visibleProjectIDs(db, scope))
+	}
+}
+
+// scopeMaintenancePlans limits a maintenance plans query to the projects the scope may see
+func scopeMaintenancePlans(scope *domain.VisibilityScope) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope == nil {
+			return db
+		}
+		return db.Where("maintenance_plans.id_project IN (?)", visibleProjectIDs(db, scope))
+	}
+}
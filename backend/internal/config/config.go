@@ -4,7 +4,11 @@
 // service files are consolidated here into a single typed Config struct.
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 // Config is the root configuration object for the entire application.
 type Config struct {
@@ -14,6 +18,7 @@ type Config struct {
 	RabbitMQ RabbitMQConfig
 	Lark     LarkConfig
 	Auth     AuthConfig
+	SMTP     SMTPConfig
 	CORS     CORSConfig
 	App      AppConfig
 }
@@ -67,7 +72,40 @@ type LarkConfig struct {
 
 // AuthConfig holds JWT and session settings.
 type AuthConfig struct {
-	JWTSecret string
+	JWTSecret       string
+	AccessTokenTTL  time.Duration // Lifetime of access JWTs
+	RefreshTokenTTL time.Duration // Lifetime of a session / refresh token chain
+	ShareLinkSecret string        // HMAC key for public share links (falls back to JWTSecret)
+	ShareLinkTTL    time.Duration // Default lifetime of a share link
+
+	Password         PasswordPolicyConfig
+	LoginMaxAttempts int           // Failed logins allowed before the account is locked
+	LoginLockout     time.Duration // First lockout; doubles with every further failure (max 24h)
+	PasswordResetTTL time.Duration // Lifetime of an emailed password reset token
+
+	MFASecretKey    string        // Encrypts stored TOTP secrets (falls back to JWTSecret)
+	MFAChallengeTTL time.Duration // Time allowed between the password step and the 2FA code
+
+	APIKeyTTL       time.Duration // Default lifetime of a service account API key
+	APIKeyMaxTTL    time.Duration // Longest lifetime a key may be issued with
+	APIKeyRateLimit int           // Default requests per minute per key
+}
+
+// PasswordPolicyConfig is the password policy enforced on create, change and reset.
+type PasswordPolicyConfig struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// SMTPConfig holds the outgoing mail server used by EmailService.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Email    string // Sender address and login
+	Password string
 }
 
 // CORSConfig holds allowed origins for Cross-Origin Resource Sharing.
@@ -78,6 +116,7 @@ type CORSConfig struct {
 // AppConfig holds miscellaneous application-level settings.
 type AppConfig struct {
 	UploadStageDir string // Temporary staging directory for MinIO uploads
+	FrontendURL    string // Base URL of the web app, used in emailed links
 }
 
 // Load reads all environment variables and returns a populated Config.
@@ -114,13 +153,39 @@ func Load() Config {
 			SubmitTableID:  getEnvOrDefault("LARK_SUBMIT_TABLE_ID", "tblUjGzPhLDSNGq8"),
 		},
 		Auth: AuthConfig{
-			JWTSecret: getEnvOrDefault("JWT_SECRET", "changeme-in-production"),
+			JWTSecret:       getEnvOrDefault("JWT_SECRET", "changeme-in-production"),
+			AccessTokenTTL:  getDurationOrDefault("ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL: getDurationOrDefault("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			ShareLinkSecret: os.Getenv("SHARE_LINK_SECRET"),
+			ShareLinkTTL:    getDurationOrDefault("SHARE_LINK_TTL", 30*24*time.Hour),
+			Password: PasswordPolicyConfig{
+				MinLength:     getIntOrDefault("PASSWORD_MIN_LENGTH", 8),
+				RequireUpper:  getBoolOrDefault("PASSWORD_REQUIRE_UPPER", false),
+				RequireLower:  getBoolOrDefault("PASSWORD_REQUIRE_LOWER", true),
+				RequireDigit:  getBoolOrDefault("PASSWORD_REQUIRE_DIGIT", true),
+				RequireSymbol: getBoolOrDefault("PASSWORD_REQUIRE_SYMBOL", false),
+			},
+			LoginMaxAttempts: getIntOrDefault("LOGIN_MAX_ATTEMPTS", 5),
+			LoginLockout:     getDurationOrDefault("LOGIN_LOCKOUT", 5*time.Minute),
+			PasswordResetTTL: getDurationOrDefault("PASSWORD_RESET_TTL", 30*time.Minute),
+			MFASecretKey:     os.Getenv("MFA_SECRET_KEY"),
+			MFAChallengeTTL:  getDurationOrDefault("MFA_CHALLENGE_TTL", 5*time.Minute),
+			APIKeyTTL:        getDurationOrDefault("API_KEY_TTL", 90*24*time.Hour),
+			APIKeyMaxTTL:     getDurationOrDefault("API_KEY_MAX_TTL", 365*24*time.Hour),
+			APIKeyRateLimit:  getIntOrDefault("API_KEY_RATE_LIMIT", 60),
+		},
+		SMTP: SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     getEnvOrDefault("SMTP_PORT", "587"),
+			Email:    os.Getenv("SMTP_EMAIL"),
+			Password: os.Getenv("SMTP_PASSWORD"),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: os.Getenv("ALLOWED_ORIGINS"),
 		},
 		App: AppConfig{
 			UploadStageDir: getEnvOrDefault("UPLOAD_STAGE_DIR", "/tmp/om_uploads"),
+			FrontendURL:    os.Getenv("FRONTEND_URL"),
 		},
 	}
 }
@@ -133,3 +198,36 @@ func getEnvOrDefault(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getDurationOrDefault parses a Go duration (e.g. "15m", "720h") from the
+// environment, falling back when unset or invalid.
+func getDurationOrDefault(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
+// getIntOrDefault parses an integer from the environment, falling back when
+// unset or invalid.
+func getIntOrDefault(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+// getBoolOrDefault parses a boolean ("true", "1", "false", ...) from the
+// environment, falling back when unset or invalid.
+func getBoolOrDefault(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
@@ -32,4 +32,5 @@ var (
     ErrInvalidInput        = NewAppError(1008, "Invalid input", http.StatusBadRequest)
 	ErrConflict            = NewAppError(1009, "Resource conflict", http.StatusConflict)
 	ErrInvalidState        = NewAppError(1010, "Invalid state", http.StatusPreconditionFailed)
+	ErrPasswordChangeRequired = NewAppError(1011, "Password change required", http.StatusForbidden)
 )
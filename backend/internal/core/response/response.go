@@ -66,3 +66,12 @@ func NotFound(c *gin.Context, message string) {
 		ErrorCode: 1001,
 	})
 }
+
+// Forbidden sends a 403 error.
+func Forbidden(c *gin.Context, message string) {
+	c.JSON(http.StatusForbidden, APIResponse{
+		Success:   false,
+		Message:   message,
+		ErrorCode: 1003,
+	})
+}
@@ -2,11 +2,15 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
 	"github.com/phuc/cmms-backend/internal/domain"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
@@ -27,6 +31,12 @@ type AllocationWorkflowService struct {
 	larkSvc          *LarkService
 	broadcast        BroadcastFunc
 	cfg              config.Config
+	shareSvc         *ShareLinkService // Signs the report links pushed to Lark
+	chains           domain.ApprovalChainRepository // nil: every approval is final
+	events           domain.DetailAssignEventRepository // nil: no history is recorded
+	forms            domain.FormSchemaRepository // nil: tasks have no checklist forms
+	deps             domain.ConfigDependencyRepository // nil: tasks may be done in any order
+	assigns          domain.AssignRepository // nil: every task is visible to every caller
 }
 
 func NewAllocationWorkflowService(
@@ -35,6 +45,12 @@ func NewAllocationWorkflowService(
 	larkSvc *LarkService,
 	broadcastFn BroadcastFunc,
 	cfg config.Config,
+	shareSvc *ShareLinkService,
+	chains domain.ApprovalChainRepository,
+	events domain.DetailAssignEventRepository,
+	forms domain.FormSchemaRepository,
+	deps domain.ConfigDependencyRepository,
+	assigns domain.AssignRepository,
 ) *AllocationWorkflowService {
 	return &AllocationWorkflowService{
 		db:               db,
@@ -42,6 +58,12 @@ func NewAllocationWorkflowService(
 		larkSvc:          larkSvc,
 		broadcast:        broadcastFn,
 		cfg:              cfg,
+		shareSvc:         shareSvc,
+		chains:           chains,
+		events:           events,
+		forms:            forms,
+		deps:             deps,
+		assigns:          assigns,
 	}
 }
 
@@ -52,46 +74,393 @@ func (s *AllocationWorkflowService) broadcastEvent() {
 	}
 }
 
-// ApproveDetail approves a single detail task. Returns the updated detail.
-func (s *AllocationWorkflowService) ApproveDetail(
-	detailID uuid.UUID,
-	noteApproval string,
-	actorID string,
-	frontendURL string,
-) (*domain.DetailAssign, error) {
+// findDetail loads a detail task, mapping a missing row to errors.ErrNotFound.
+func (s *AllocationWorkflowService) findDetail(detailID uuid.UUID) (*domain.DetailAssign, error) {
 	detail, err := s.detailAssignRepo.FindByID(detailID)
 	if err != nil {
-		return nil, fmt.Errorf("detail not found: %w", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrNotFound
+		}
+		return nil, err
+	}
+	return detail, nil
+}
+
+// VersionConflictError refuses a write made against a stale copy of a task;
+// Current is the task as stored, for the client to reconcile with.
+type VersionConflictError struct {
+	Current *domain.DetailAssign
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("Task was changed by someone else (now at version %d)", e.Current.Version)
+}
+
+// findDetailAt loads a task the caller last saw at version, refusing with
+// VersionConflictError if it has moved on since. Tasks of assigns outside
+// scope are not found, before their state is compared or revealed.
+func (s *AllocationWorkflowService) findDetailAt(detailID uuid.UUID, version int, scope *domain.VisibilityScope) (*domain.DetailAssign, error) {
+	detail, err := s.findDetail(detailID)
+	if err != nil {
+		return nil, err
+	}
+	if scope != nil && s.assigns != nil {
+		if _, err := s.assigns.FindByID(detail.AssignID, scope); err != nil {
+			return nil, apperrors.ErrNotFound
+		}
+	}
+	if detail.Version != version {
+		return nil, &VersionConflictError{Current: detail}
+	}
+	return detail, nil
+}
+
+// saveError wraps a failed save of a task; losing a race to another write
+// becomes a VersionConflictError carrying the winner's state.
+func (s *AllocationWorkflowService) saveError(detailID uuid.UUID, action string, err error) error {
+	if errors.Is(err, domain.ErrStaleVersion) {
+		if current, findErr := s.findDetail(detailID); findErr == nil {
+			return &VersionConflictError{Current: current}
+		}
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// transition moves detail through the workflow state machine, refusing steps
+// the current state does not allow (e.g. approving work that was never submitted).
+// It returns the history event of the step, saved by recordEvent in the
+// transaction persisting the detail.
+func transition(detail *domain.DetailAssign, event domain.DetailEvent, actorID, note string) (*domain.DetailAssignEvent, error) {
+	from := detail.CurrentState()
+	if !detail.Transition(event) {
+		return nil, apperrors.NewAppError(apperrors.ErrInvalidState.Code,
+			fmt.Sprintf("Cannot %s a task in state %q", event, from), http.StatusConflict)
+	}
+	ev := &domain.DetailAssignEvent{
+		DetailAssignID: detail.ID,
+		AssignID:       detail.AssignID,
+		EventType:      event,
+		FromState:      from,
+		ToState:        detail.State,
+		Note:           note,
+	}
+	if id, err := uuid.Parse(actorID); err == nil {
+		ev.ActorID = &id
+	}
+	return ev, nil
+}
+
+// recordEvent appends a step to the task history. Run it on the withTx copy
+// saving the detail, so a step is never kept without its history.
+func (s *AllocationWorkflowService) recordEvent(ev *domain.DetailAssignEvent) error {
+	if s.events == nil || ev == nil {
+		return nil
+	}
+	if err := s.events.Create(ev); err != nil {
+		return fmt.Errorf("failed to record %s: %w", ev.EventType, err)
 	}
+	return nil
+}
 
-	detail.StatusApprove = 1
-	detail.StatusReject = 0
-	detail.NoteApproval = noteApproval
+// saveStep saves the detail and the history event of its step in one transaction.
+func (s *AllocationWorkflowService) saveStep(detail *domain.DetailAssign, ev *domain.DetailAssignEvent, action string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		svc := s.withTx(tx)
+		if err := svc.detailAssignRepo.Update(detail); err != nil {
+			return svc.saveError(detail.ID, action, err)
+		}
+		return svc.recordEvent(ev)
+	})
+}
+
+// GetDetailTimeline returns the history of a task, oldest first.
+func (s *AllocationWorkflowService) GetDetailTimeline(detailID uuid.UUID) ([]domain.DetailAssignEvent, error) {
+	if s.events == nil {
+		return []domain.DetailAssignEvent{}, nil
+	}
+	return s.events.FindByDetail(detailID)
+}
+
+// GetAssignTimeline returns the history of every task of an assign, oldest first.
+func (s *AllocationWorkflowService) GetAssignTimeline(assignID uuid.UUID) ([]domain.DetailAssignEvent, error) {
+	if s.events == nil {
+		return []domain.DetailAssignEvent{}, nil
+	}
+	return s.events.FindByAssign(assignID)
+}
 
-	// Append timestamp
+// latestEventTime formats the time of the task's latest event of a type for
+// Lark, or returns "" when there is none.
+func latestEventTime(events domain.DetailAssignEventRepository, detailID uuid.UUID, eventType domain.DetailEvent) string {
+	if events == nil {
+		return ""
+	}
+	ev, err := events.FindLatest(detailID, eventType)
+	if err != nil || ev == nil {
+		return ""
+	}
+	return ev.CreatedAt.Format("02/01/2006 15:04:05")
+}
+
+// appendJSONTime appends t to a JSONB timestamp history array.
+func appendJSONTime(history datatypes.JSON, t time.Time) datatypes.JSON {
 	var timestamps []time.Time
-	_ = json.Unmarshal(detail.ApprovalAt, &timestamps)
-	timestamps = append(timestamps, time.Now())
-	tsJSON, _ := json.Marshal(timestamps)
-	detail.ApprovalAt = datatypes.JSON(tsJSON)
+	if len(history) > 0 && string(history) != "null" {
+		_ = json.Unmarshal(history, &timestamps)
+	}
+	out, _ := json.Marshal(append(timestamps, t))
+	return datatypes.JSON(out)
+}
+
+// appendJSONString appends v to a JSONB string array (person history).
+func appendJSONString(history datatypes.JSON, v string) datatypes.JSON {
+	var values []string
+	_ = json.Unmarshal(history, &values)
+	out, _ := json.Marshal(append(values, v))
+	return datatypes.JSON(out)
+}
 
-	// Track actor
+// approve applies the approve transition and records note, time and actor.
+// The JSONB history arrays are still filled for the frontend.
+func approve(detail *domain.DetailAssign, note, actorID string) (*domain.DetailAssignEvent, error) {
+	ev, err := transition(detail, domain.DetailEventApprove, actorID, note)
+	if err != nil {
+		return nil, err
+	}
+	detail.NoteApproval = note
+	detail.ApprovalAt = appendJSONTime(detail.ApprovalAt, time.Now())
+	if actorID != "" {
+		detail.IdPersonApprove = appendJSONString(detail.IdPersonApprove, actorID)
+	}
+	return ev, nil
+}
+
+// reject applies the reject transition and records note, time and actor.
+func reject(detail *domain.DetailAssign, note, actorID string) (*domain.DetailAssignEvent, error) {
+	ev, err := transition(detail, domain.DetailEventReject, actorID, note)
+	if err != nil {
+		return nil, err
+	}
+	detail.NoteReject = note
+	detail.RejectedAt = appendJSONTime(detail.RejectedAt, time.Now())
 	if actorID != "" {
-		var personIDs []string
-		_ = json.Unmarshal(detail.IdPersonApprove, &personIDs)
-		personIDs = append(personIDs, actorID)
-		pJSON, _ := json.Marshal(personIDs)
-		detail.IdPersonApprove = datatypes.JSON(pJSON)
+		detail.IdPersonReject = appendJSONString(detail.IdPersonReject, actorID)
+	}
+	return ev, nil
+}
+
+// MissingEvidenceError refuses a submit that lacks the evidence its config's
+// slots require; Missing lists every slot that is short.
+type MissingEvidenceError struct {
+	Missing []domain.MissingSlot
+}
+
+func (e *MissingEvidenceError) Error() string {
+	parts := make([]string, len(e.Missing))
+	for i, m := range e.Missing {
+		parts[i] = fmt.Sprintf("%s (%d/%d)", m.Name, m.Have, m.Min)
+	}
+	return "Missing required evidence: " + strings.Join(parts, ", ")
+}
+
+// BlockedTaskError refuses to start or submit a task whose predecessors (see
+// domain.ConfigDependency) have not yet reached their required state.
+type BlockedTaskError struct {
+	BlockedBy []domain.TaskBlocker
+}
+
+func (e *BlockedTaskError) Error() string {
+	parts := make([]string, len(e.BlockedBy))
+	for i, b := range e.BlockedBy {
+		name := b.Name
+		if name == "" {
+			name = b.DetailAssignID.String()
+		}
+		parts[i] = fmt.Sprintf("%s (%s, needs %s)", name, b.State, b.Requires)
+	}
+	return "Task is waiting for: " + strings.Join(parts, ", ")
+}
+
+// blockersOf returns the predecessor tasks holding detail back under its
+// template's dependencies
+func (s *AllocationWorkflowService) blockersOf(detail *domain.DetailAssign) ([]domain.TaskBlocker, error) {
+	if s.deps == nil || detail.ConfigID == nil {
+		return nil, nil
+	}
+	assign := detail.Assign
+	if assign == nil {
+		assign = &domain.Assign{}
+		if err := s.db.First(assign, "id = ?", detail.AssignID).Error; err != nil {
+			return nil, err
+		}
+	}
+	if assign.TemplateID == nil {
+		return nil, nil
+	}
+	deps, err := s.deps.FindByTemplate(*assign.TemplateID)
+	if err != nil || len(deps) == 0 {
+		return nil, err
+	}
+	siblings, err := s.detailAssignRepo.FindByAssignID(detail.AssignID)
+	if err != nil {
+		return nil, err
+	}
+	return domain.BlockedBy(deps, siblings)[detail.ID], nil
+}
+
+// SubmitDetail merges the uploaded evidence into the task and, unless draft is
+// set, submits it for review (a resubmission when the task was rejected).
+// A submit is refused with MissingEvidenceError until every evidence slot of
+// the task's config has its minimum number of files, and with BlockedTaskError
+// while predecessors hold the task back; a draft save that starts the work is
+// refused too, but work already under way may still be saved. Measurement readings are
+// merged into the stored ones, as are checklist form answers; required fields
+// of both are only enforced on submit. The form version answered is pinned on
+// the task. version is the task's version as the caller last saw it (see findDetailAt).
+func (s *AllocationWorkflowService) SubmitDetail(detailID uuid.UUID, version int, data []string, readings map[string]float64, answers map[string]json.RawMessage, noteData, actorID string, draft bool, scope *domain.VisibilityScope) (*domain.DetailAssign, error) {
+	detail, err := s.findDetailAt(detailID, version, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	measurements, err := measureReadings(detail, readings, !draft)
+	if err != nil {
+		return nil, err
+	}
+	if !draft || detail.CurrentState() == domain.DetailStateDraft {
+		blockers, err := s.blockersOf(detail)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check task order: %w", err)
+		}
+		if len(blockers) > 0 {
+			return nil, &BlockedTaskError{BlockedBy: blockers}
+		}
+	}
+	form, err := s.taskForm(detail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checklist form: %w", err)
+	}
+	formAnswers, err := fillForm(detail, form, answers, !draft)
+	if err != nil {
+		return nil, err
+	}
+
+	event := domain.DetailEventSubmit
+	if draft {
+		event = domain.DetailEventSaveDraft
+	}
+	ev, err := transition(detail, event, actorID, noteData)
+	if err != nil {
+		return nil, err
+	}
+
+	// RACE-CONDITION SAFE MERGE:
+	// Read existing URLs from DB, then take the UNION of (existing ∪ incoming).
+	// This prevents User B's outdated client from overwriting images already uploaded by User A.
+	existingURLs := []string{}
+	if len(detail.Data) > 0 && string(detail.Data) != "null" {
+		_ = json.Unmarshal(detail.Data, &existingURLs)
 	}
+	// Deduplicate via map, keeping insertion order (existing first, then new)
+	seen := make(map[string]struct{})
+	mergedURLs := make([]string, 0, len(existingURLs)+len(data))
+	for _, u := range append(existingURLs, data...) {
+		if _, ok := seen[u]; !ok {
+			seen[u] = struct{}{}
+			mergedURLs = append(mergedURLs, u)
+		}
+	}
+	dataJSON, _ := json.Marshal(mergedURLs)
+	detail.Data = datatypes.JSON(dataJSON)
+	if noteData != "" {
+		detail.NoteData = noteData
+	}
+	if form != nil {
+		detail.FormSchemaID = &form.ID
+		detail.FormSchema = form
+		detail.FormAnswers = formAnswers
+	}
+	if !draft {
+		if missing := detail.MissingEvidence(detail.Config); len(missing) > 0 {
+			return nil, &MissingEvidenceError{Missing: missing}
+		}
+		detail.SubmittedAt = appendJSONTime(detail.SubmittedAt, time.Now())
+	}
+
+	// The task, its readings and its history event are written together, so a
+	// failed write doesn't leave a submitted task without them
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		svc := s.withTx(tx)
+		if err := svc.detailAssignRepo.Update(detail); err != nil {
+			return svc.saveError(detailID, "submit", err)
+		}
+		if measurements != nil {
+			if err := svc.replaceMeasurements(detail.ID, measurements); err != nil {
+				return fmt.Errorf("failed to save measurements: %w", err)
+			}
+		}
+		// Repeated draft saves are not history; starting the work is
+		if !draft || ev.FromState != ev.ToState {
+			ev.Evidence = detail.Data
+			return svc.recordEvent(ev)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if measurements != nil {
+		detail.Measurements = measurements
+	}
+	s.broadcastEvent()
+	return detail, nil
+}
 
+// SaveNote updates the worker's note on a task without changing its state.
+func (s *AllocationWorkflowService) SaveNote(detailID uuid.UUID, version int, note string, scope *domain.VisibilityScope) (*domain.DetailAssign, error) {
+	detail, err := s.findDetailAt(detailID, version, scope)
+	if err != nil {
+		return nil, err
+	}
+	detail.NoteData = note
 	if err := s.detailAssignRepo.Update(detail); err != nil {
-		return nil, fmt.Errorf("failed to approve: %w", err)
+		return nil, s.saveError(detailID, "save note", err)
+	}
+	s.broadcastEvent()
+	return detail, nil
+}
+
+var (
+	ErrApproverRequired = apperrors.NewAppError(apperrors.ErrForbidden.Code, "Approval chains need a signed-in approver", http.StatusForbidden)
+	ErrAlreadySignedOff = apperrors.NewAppError(apperrors.ErrConflict.Code, "You have already signed off this submission", http.StatusConflict)
+)
+
+// ApproveDetail records the caller's sign-off on a submitted (or resubmitted) task.
+// Without an approval chain the task is approved at once; with one it stays
+// partially approved until every level reaches its quorum. Lark sync only runs
+// on the final approval. Returns the updated detail.
+func (s *AllocationWorkflowService) ApproveDetail(
+	detailID uuid.UUID,
+	version int,
+	noteApproval string,
+	actorID string,
+	actorRole string,
+	frontendURL string,
+	scope *domain.VisibilityScope,
+) (*domain.DetailAssign, error) {
+	detail, err := s.findDetailAt(detailID, version, scope)
+	if err != nil {
+		return nil, err
+	}
+	final, err := s.signOff(detail, noteApproval, actorID, actorRole)
+	if err != nil {
+		return nil, err
 	}
 
 	s.broadcastEvent()
 
 	// Async: Lark sync
-	if s.larkSvc != nil {
+	if final && s.larkSvc != nil {
 		detailCopy := *detail
 		go s.postApproveAsync(detailCopy, actorID, frontendURL)
 	}
@@ -99,6 +468,138 @@ func (s *AllocationWorkflowService) ApproveDetail(
 	return detail, nil
 }
 
+// chainFor returns the approval chain of the detail's template or project, or nil.
+func (s *AllocationWorkflowService) chainFor(detail *domain.DetailAssign) (*domain.ApprovalChain, error) {
+	if s.chains == nil {
+		return nil, nil
+	}
+	assign := detail.Assign
+	if assign == nil {
+		assign = &domain.Assign{}
+		if err := s.db.First(assign, "id = ?", detail.AssignID).Error; err != nil {
+			return nil, err
+		}
+	}
+	return s.chains.FindForAssign(assign.ProjectID, assign.TemplateID)
+}
+
+// signOff applies one approval and saves the detail. It reports whether the
+// approval was final (no chain, or the last level reached its quorum).
+func (s *AllocationWorkflowService) signOff(detail *domain.DetailAssign, note, actorID, actorRole string) (bool, error) {
+	chain, err := s.chainFor(detail)
+	if err != nil {
+		return false, err
+	}
+	if chain == nil || len(chain.Levels) == 0 {
+		ev, err := approve(detail, note, actorID)
+		if err != nil {
+			return false, err
+		}
+		if err := s.saveStep(detail, ev, "approve"); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	// Refuse sign-offs on tasks that are not under review before checking approvers
+	if _, ok := domain.NextDetailState(detail.CurrentState(), domain.DetailEventApproveLevel); !ok {
+		_, err := transition(detail, domain.DetailEventApproveLevel, actorID, note)
+		return false, err
+	}
+	approverID, err := uuid.Parse(actorID)
+	if err != nil {
+		return false, ErrApproverRequired
+	}
+
+	idx := detail.ApprovalLevel
+	if idx >= len(chain.Levels) { // The chain was shortened after earlier sign-offs
+		idx = len(chain.Levels) - 1
+	}
+	level := chain.Levels[idx]
+	if !level.Allows(approverID, actorRole) {
+		return false, apperrors.NewAppError(apperrors.ErrForbidden.Code,
+			fmt.Sprintf("You are not an approver for level %d (%s)", idx+1, level.Name), http.StatusForbidden)
+	}
+
+	approvals, err := s.chains.FindApprovals(detail.ID, detail.ApprovalRound)
+	if err != nil {
+		return false, err
+	}
+	count := 1 // This sign-off
+	for _, a := range approvals {
+		if a.ApproverID == approverID {
+			return false, ErrAlreadySignedOff // One person signs one level per submission
+		}
+		if a.Level == idx {
+			count++
+		}
+	}
+
+	final := false
+	if count >= level.Quorum {
+		if idx == len(chain.Levels)-1 {
+			final = true
+		}
+		detail.ApprovalLevel = idx + 1
+	}
+	var ev *domain.DetailAssignEvent
+	if final {
+		ev, err = approve(detail, note, actorID)
+	} else {
+		ev, err = transition(detail, domain.DetailEventApproveLevel, actorID, note)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	approval := &domain.DetailApproval{
+		DetailAssignID: detail.ID,
+		ChainID:        chain.ID,
+		Round:          detail.ApprovalRound,
+		Level:          idx,
+		ApproverID:     approverID,
+		Note:           note,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		svc := s.withTx(tx)
+		if err := svc.chains.RecordApproval(approval, detail); err != nil {
+			return svc.saveError(detail.ID, "approve", err)
+		}
+		return svc.recordEvent(ev)
+	})
+	if err != nil {
+		return false, err
+	}
+	return final, nil
+}
+
+// ApprovalProgress is the chain of a task and the sign-offs of its current submission
+type ApprovalProgress struct {
+	State     domain.DetailState      `json:"state"`
+	Level     int                     `json:"approval_level"` // Levels completed
+	Chain     *domain.ApprovalChain   `json:"chain"`          // nil: single-step approval
+	Approvals []domain.DetailApproval `json:"approvals"`
+}
+
+// GetApprovalProgress reports how far a task is through its approval chain.
+func (s *AllocationWorkflowService) GetApprovalProgress(detailID uuid.UUID) (*ApprovalProgress, error) {
+	detail, err := s.findDetail(detailID)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := s.chainFor(detail)
+	if err != nil {
+		return nil, err
+	}
+	progress := &ApprovalProgress{State: detail.CurrentState(), Level: detail.ApprovalLevel, Chain: chain, Approvals: []domain.DetailApproval{}}
+	if s.chains != nil {
+		if progress.Approvals, err = s.chains.FindApprovals(detail.ID, detail.ApprovalRound); err != nil {
+			return nil, err
+		}
+	}
+	return progress, nil
+}
+
 // postApproveAsync syncs completed task to Lark after approval.
 func (s *AllocationWorkflowService) postApproveAsync(detail domain.DetailAssign, actorID string, frontendURL string) {
 	var assign domain.Assign
@@ -115,44 +616,30 @@ func (s *AllocationWorkflowService) postApproveAsync(detail domain.DetailAssign,
 
 	// Lark sync
 	if frontendURL != "" && s.larkSvc != nil {
-		syncCompletedTaskToLark(s.db, s.larkSvc, s.detailAssignRepo, s.cfg, detail, assign, userIDs, actorID, frontendURL, ctxNames)
+		syncCompletedTaskToLark(s.db, s.larkSvc, s.shareSvc, s.detailAssignRepo, s.cfg, s.events, detail, assign, userIDs, actorID, frontendURL, ctxNames)
 	}
 }
 
-// RejectDetail rejects a single detail task. Returns the updated detail.
+// RejectDetail sends a submitted or approved detail task back to the worker. Returns the updated detail.
 func (s *AllocationWorkflowService) RejectDetail(
 	detailID uuid.UUID,
+	version int,
 	noteReject string,
 	actorID string,
 	frontendURL string,
+	scope *domain.VisibilityScope,
 ) (*domain.DetailAssign, error) {
-	detail, err := s.detailAssignRepo.FindByID(detailID)
+	detail, err := s.findDetailAt(detailID, version, scope)
 	if err != nil {
-		return nil, fmt.Errorf("detail not found: %w", err)
+		return nil, err
 	}
-
-	detail.StatusReject = 1
-	detail.StatusApprove = 0
-	detail.StatusSubmit = 0
-	detail.NoteReject = noteReject
-
-	var timestamps []time.Time
-	_ = json.Unmarshal(detail.RejectedAt, &timestamps)
-	timestamps = append(timestamps, time.Now())
-	tsJSON, _ := json.Marshal(timestamps)
-	detail.RejectedAt = datatypes.JSON(tsJSON)
-
-	// Track actor
-	if actorID != "" {
-		var personIDs []string
-		_ = json.Unmarshal(detail.IdPersonReject, &personIDs)
-		personIDs = append(personIDs, actorID)
-		pJSON, _ := json.Marshal(personIDs)
-		detail.IdPersonReject = datatypes.JSON(pJSON)
+	ev, err := reject(detail, noteReject, actorID)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.detailAssignRepo.Update(detail); err != nil {
-		return nil, fmt.Errorf("failed to reject: %w", err)
+	if err := s.saveStep(detail, ev, "reject"); err != nil {
+		return nil, err
 	}
 
 	s.broadcastEvent()
@@ -181,131 +668,214 @@ func (s *AllocationWorkflowService) postRejectAsync(detail domain.DetailAssign,
 
 	// Lark sync for rejected tasks
 	if frontendURL != "" && s.larkSvc != nil {
-		syncRejectedTaskToLark(s.db, s.larkSvc, s.cfg, detail, assign, userIDs, actorID, frontendURL, ctxNames)
+		syncRejectedTaskToLark(s.db, s.larkSvc, s.shareSvc, s.cfg, s.events, detail, assign, userIDs, actorID, frontendURL, ctxNames)
 	}
 }
 
+// Per-task outcomes of a bulk status update
+const (
+	BulkItemOK           = "ok"
+	BulkItemNotFound     = "not_found"     // Unknown or malformed ID
+	BulkItemInvalidState = "invalid_state" // The workflow does not allow the step from the task's state
+	BulkItemConflict     = "conflict"      // Changed since the caller's version
+	BulkItemNotApprover  = "not_approver"  // The caller cannot sign off the task's approval level
+	BulkItemSignedOff    = "signed_off"    // The caller already signed off the task's current submission
+	BulkItemFailed       = "error"         // Could not be saved
+	BulkItemRolledBack   = "rolled_back"   // Would have applied, but an atomic batch was aborted
+)
+
+// BulkItemResult is the outcome for one task of a bulk status update.
+type BulkItemResult struct {
+	ID     string               `json:"id"`
+	Status string               `json:"status"`
+	Error  string               `json:"error,omitempty"`
+	Detail *domain.DetailAssign `json:"detail,omitempty"` // Updated task, or the current one on a conflict
+}
+
 // BulkUpdateResult holds the result of a bulk status update operation.
 type BulkUpdateResult struct {
-	SuccessCount   int
-	TotalRequested int
+	SuccessCount    int
+	TotalRequested  int
+	RolledBack      bool // Atomic mode: nothing was applied because an item failed
+	Items           []BulkItemResult
+	InvalidStateIDs []string              // Skipped: the workflow does not allow the step from their current state
+	NotApproverIDs  []string              // Skipped: the caller cannot sign off their current approval level
+	SignedOffIDs    []string              // Skipped: the caller already signed off their current submission
+	Conflicts       []domain.DetailAssign // Skipped: changed since the caller's version (their current state)
 }
 
-// BulkUpdateStatus applies approve (1), reject (-1), or reset (0) to a list of task IDs.
+// ErrBulkAccept is returned for an accept value other than 1, -1 or 0.
+var ErrBulkAccept = apperrors.NewAppError(apperrors.ErrValidation.Code, "accept must be 1 (approve), -1 (reject) or 0 (reopen)", http.StatusBadRequest)
+
+// errBulkAborted rolls back an atomic bulk update
+var errBulkAborted = errors.New("bulk update aborted")
+
+// BulkUpdateStatus applies approve (1), reject (-1), or reopen (0) to a list of task IDs.
+// Every task goes through the state machine and gets a BulkItemResult.
+// Approvals are sign-offs as in ApproveDetail. versions maps each ID to the
+// version the caller last saw; tasks changed since are conflicts.
+//
+// In atomic mode the batch runs in one transaction and is rolled back unless
+// every task succeeds; otherwise each task is saved on its own (best effort).
+// Tasks outside scope are reported as not found and left unchanged.
+// Lark sync and the WebSocket broadcast run once, after the changes are committed.
 func (s *AllocationWorkflowService) BulkUpdateStatus(
 	ids []string,
+	versions map[string]int,
 	accept int,
 	note string,
 	actorID string,
+	actorRole string,
 	frontendURL string,
-) BulkUpdateResult {
+	atomic bool,
+	scope *domain.VisibilityScope,
+) (BulkUpdateResult, error) {
 	result := BulkUpdateResult{TotalRequested: len(ids)}
+	if accept < -1 || accept > 1 {
+		return result, ErrBulkAccept
+	}
 
-	for _, idStr := range ids {
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			continue
+	var approved, rejected []domain.DetailAssign
+	run := func(svc *AllocationWorkflowService) error {
+		approved, rejected = nil, nil
+		result.Items = make([]BulkItemResult, 0, len(ids))
+		failed := false
+		for _, idStr := range ids {
+			item, final := svc.bulkApply(idStr, versions[idStr], accept, note, actorID, actorRole, scope)
+			if item.Status != BulkItemOK {
+				failed = true
+			} else if final {
+				approved = append(approved, *item.Detail)
+			} else if accept == -1 {
+				rejected = append(rejected, *item.Detail)
+			}
+			result.Items = append(result.Items, item)
 		}
-		detail, err := s.detailAssignRepo.FindByID(id)
-		if err != nil {
-			continue
+		if atomic && failed {
+			return errBulkAborted
 		}
+		return nil
+	}
 
-		switch accept {
-		case 1:
-			detail.StatusApprove = 1
-			detail.StatusReject = 0
-			detail.NoteApproval = note
-
-			var timestamps []time.Time
-			_ = json.Unmarshal(detail.ApprovalAt, &timestamps)
-			timestamps = append(timestamps, time.Now())
-			tsJSON, _ := json.Marshal(timestamps)
-			detail.ApprovalAt = datatypes.JSON(tsJSON)
-
-			if actorID != "" {
-				var personIDs []string
-				_ = json.Unmarshal(detail.IdPersonApprove, &personIDs)
-				personIDs = append(personIDs, actorID)
-				pJSON, _ := json.Marshal(personIDs)
-				detail.IdPersonApprove = datatypes.JSON(pJSON)
-			}
-
-			// Async Lark sync per task
-			if frontendURL != "" && s.larkSvc != nil {
-				detailCopy := *detail
-				go func(d domain.DetailAssign) {
-					var assign domain.Assign
-					if err := s.db.First(&assign, "id = ?", d.AssignID).Error; err != nil {
-						return
-					}
-					var userIDs []string
-					_ = json.Unmarshal(assign.UserIDs, &userIDs)
-					ctxNames, err := s.detailAssignRepo.GetNamesForMinioPath(d.ID)
-					if err != nil {
-						return
-					}
-					syncCompletedTaskToLark(s.db, s.larkSvc, s.detailAssignRepo, s.cfg, d, assign, userIDs, actorID, frontendURL, ctxNames)
-				}(detailCopy)
-			}
-
-		case -1:
-			detail.StatusReject = 1
-			detail.StatusApprove = 0
-			detail.StatusSubmit = 0
-			detail.NoteReject = note
-
-			var timestamps []time.Time
-			_ = json.Unmarshal(detail.RejectedAt, &timestamps)
-			timestamps = append(timestamps, time.Now())
-			tsJSON, _ := json.Marshal(timestamps)
-			detail.RejectedAt = datatypes.JSON(tsJSON)
-
-			if actorID != "" {
-				var personIDs []string
-				_ = json.Unmarshal(detail.IdPersonReject, &personIDs)
-				personIDs = append(personIDs, actorID)
-				pJSON, _ := json.Marshal(personIDs)
-				detail.IdPersonReject = datatypes.JSON(pJSON)
-			}
-
-			// Async Lark sync per task for rejection
-			if frontendURL != "" && s.larkSvc != nil {
-				detailCopy := *detail
-				go func(d domain.DetailAssign) {
-					var assign domain.Assign
-					if err := s.db.First(&assign, "id = ?", d.AssignID).Error; err != nil {
-						return
-					}
-					var userIDs []string
-					_ = json.Unmarshal(assign.UserIDs, &userIDs)
-					ctxNames, err := s.detailAssignRepo.GetNamesForMinioPath(d.ID)
-					if err != nil {
-						return
-					}
-					syncRejectedTaskToLark(s.db, s.larkSvc, s.cfg, d, assign, userIDs, actorID, frontendURL, ctxNames)
-				}(detailCopy)
+	if atomic {
+		err := s.db.Transaction(func(tx *gorm.DB) error { return run(s.withTx(tx)) })
+		if errors.Is(err, errBulkAborted) {
+			result.RolledBack = true
+			approved, rejected = nil, nil
+			for i := range result.Items {
+				if result.Items[i].Status == BulkItemOK {
+					result.Items[i].Status, result.Items[i].Detail = BulkItemRolledBack, nil
+				}
 			}
-
-		case 0:
-			detail.StatusReject = 0
-			detail.StatusApprove = 0
-			detail.StatusSubmit = 0
+		} else if err != nil {
+			return result, err
 		}
+	} else {
+		_ = run(s)
+	}
 
-		if err := s.detailAssignRepo.Update(detail); err == nil {
+	for _, item := range result.Items {
+		switch item.Status {
+		case BulkItemOK:
 			result.SuccessCount++
+		case BulkItemInvalidState:
+			result.InvalidStateIDs = append(result.InvalidStateIDs, item.ID)
+		case BulkItemNotApprover:
+			result.NotApproverIDs = append(result.NotApproverIDs, item.ID)
+		case BulkItemSignedOff:
+			result.SignedOffIDs = append(result.SignedOffIDs, item.ID)
+		case BulkItemConflict:
+			result.Conflicts = append(result.Conflicts, *item.Detail)
 		}
 	}
 
 	if result.SuccessCount > 0 {
 		s.broadcastEvent()
+		// Async: one Lark sync job for the whole batch (approvals only once the chain completes)
+		if frontendURL != "" && s.larkSvc != nil {
+			go s.postBulkAsync(approved, rejected, actorID, frontendURL)
+		}
+	}
+
+	return result, nil
+}
+
+// bulkApply applies one step of a bulk update. final reports an approval that
+// completed its chain.
+func (s *AllocationWorkflowService) bulkApply(idStr string, version, accept int, note, actorID, actorRole string, scope *domain.VisibilityScope) (BulkItemResult, bool) {
+	item := BulkItemResult{ID: idStr}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		item.Status, item.Error = BulkItemNotFound, "invalid id"
+		return item, false
+	}
+	detail, err := s.findDetailAt(id, version, scope)
+	if err == nil {
+		final := false
+		var ev *domain.DetailAssignEvent
+		switch accept {
+		case 1:
+			final, err = s.signOff(detail, note, actorID, actorRole)
+		case -1:
+			if ev, err = reject(detail, note, actorID); err == nil {
+				err = s.saveStep(detail, ev, "reject")
+			}
+		case 0:
+			if ev, err = transition(detail, domain.DetailEventReopen, actorID, note); err == nil {
+				err = s.saveStep(detail, ev, "reopen")
+			}
+		}
+		if err == nil {
+			item.Status, item.Detail = BulkItemOK, detail
+			return item, final
+		}
 	}
 
-	return result
+	item.Error = err.Error()
+	var conflict *VersionConflictError
+	var appErr *apperrors.AppError
+	switch {
+	case errors.As(err, &conflict):
+		item.Status, item.Detail = BulkItemConflict, conflict.Current
+	case errors.As(err, &appErr) && appErr.Code == apperrors.ErrNotFound.Code:
+		item.Status = BulkItemNotFound
+	case errors.As(err, &appErr) && appErr.Code == apperrors.ErrInvalidState.Code:
+		item.Status = BulkItemInvalidState
+	case errors.Is(err, ErrAlreadySignedOff):
+		item.Status = BulkItemSignedOff
+	case errors.As(err, &appErr) && appErr.Code == apperrors.ErrForbidden.Code:
+		item.Status = BulkItemNotApprover
+	default:
+		item.Status = BulkItemFailed
+	}
+	return item, false
 }
 
+// withTx returns a copy of the service whose repositories run in tx. Side
+// effects (broadcast, Lark) are left to the caller, to run after commit.
+func (s *AllocationWorkflowService) withTx(tx *gorm.DB) *AllocationWorkflowService {
+	txSvc := *s
+	txSvc.db = tx
+	txSvc.detailAssignRepo = s.detailAssignRepo.WithTx(tx)
+	if s.chains != nil {
+		txSvc.chains = s.chains.WithTx(tx)
+	}
+	if s.events != nil {
+		txSvc.events = s.events.WithTx(tx)
+	}
+	txSvc.broadcast, txSvc.larkSvc = nil, nil
+	return &txSvc
+}
 
+// postBulkAsync syncs the tasks a bulk update approved or rejected to Lark, one after another.
+func (s *AllocationWorkflowService) postBulkAsync(approved, rejected []domain.DetailAssign, actorID string, frontendURL string) {
+	for _, detail := range approved {
+		s.postApproveAsync(detail, actorID, frontendURL)
+	}
+	for _, detail := range rejected {
+		s.postRejectAsync(detail, actorID, frontendURL)
+	}
+}
 
 // PostSubmitAsync wraps all post-submit side-effects (Lark sync)
 // into a single non-blocking goroutine, called from the SubmitDetail API handler.
@@ -333,17 +903,33 @@ func (s *AllocationWorkflowService) PostSubmitAsync(detailID uuid.UUID, submitte
 			}
 			var userIDs []string
 			_ = json.Unmarshal(assign.UserIDs, &userIDs)
-			syncSubmittedTaskToLark(s.db, s.larkSvc, s.cfg, *detail, assign, userIDs, submitterName, frontendURL, ctxNames)
+			syncSubmittedTaskToLark(s.db, s.larkSvc, s.shareSvc, s.cfg, s.events, *detail, assign, userIDs, submitterName, frontendURL, ctxNames)
 		}
 	}()
 }
 
+// detailReportLink signs a report link scoped to the detail's asset and sub-work.
+// reportType ("submit", "reject" or "" for approve) only selects the frontend template.
+func detailReportLink(shareSvc *ShareLinkService, frontendURL string, assign domain.Assign, detail domain.DetailAssign, reportType string) string {
+	var assetID, subWorkID *uuid.UUID
+	if detail.Config != nil {
+		assetID, subWorkID = &detail.Config.AssetID, &detail.Config.SubWorkID
+	}
+	extra := url.Values{}
+	if reportType != "" {
+		extra.Set("type", reportType)
+	}
+	return shareSvc.AssignReportURL(frontendURL, assign.ID, assetID, subWorkID, extra)
+}
+
 // syncSubmittedTaskToLark pushes a newly-submitted task record to the
 // "RAITEK | NỘP DỮ LIỆU" Bitable table using a public, no-login report link.
 func syncSubmittedTaskToLark(
 	db *gorm.DB,
 	larkSvc *LarkService,
+	shareSvc *ShareLinkService,
 	cfg config.Config,
+	events domain.DetailAssignEventRepository,
 	detail domain.DetailAssign,
 	assign domain.Assign,
 	userIDs []string,
@@ -359,24 +945,10 @@ func syncSubmittedTaskToLark(
 		return
 	}
 
-	// Build public no-login report link: /share/report/{assignID}?sub={subWorkID}&asset={assetID}&type=submit
-	reportLink := fmt.Sprintf("%s/share/report/%s", frontendURL, assign.ID.String())
-	queryParts := []string{}
-	if detail.Config != nil {
-		queryParts = append(queryParts, "asset="+detail.Config.AssetID.String())
-		queryParts = append(queryParts, "sub="+detail.Config.SubWorkID.String())
-	}
-	queryParts = append(queryParts, "type=submit")
-	if len(queryParts) > 0 {
-		reportLink += "?" + strings.Join(queryParts, "&")
-	}
+	// Build signed no-login report link: /share/report/{assignID}?asset=..&sub=..&type=submit&token=..
+	reportLink := detailReportLink(shareSvc, frontendURL, assign, detail, "submit")
 
-	// Parse submitted_at timestamp
-	submittedAt := ""
-	var subTimes []time.Time
-	if err := json.Unmarshal(detail.SubmittedAt, &subTimes); err == nil && len(subTimes) > 0 {
-		submittedAt = subTimes[len(subTimes)-1].Format("02/01/2006 15:04:05")
-	}
+	submittedAt := latestEventTime(events, detail.ID, domain.DetailEventSubmit)
 
 	// Resolve assignees
 	var assignees []domain.User
@@ -446,8 +1018,10 @@ func syncSubmittedTaskToLark(
 func syncCompletedTaskToLark(
 	db *gorm.DB,
 	larkSvc *LarkService,
+	shareSvc *ShareLinkService,
 	detailAssignRepo domain.DetailAssignRepository,
 	cfg config.Config,
+	events domain.DetailAssignEventRepository,
 	detail domain.DetailAssign,
 	assign domain.Assign,
 	userIDs []string,
@@ -477,8 +1051,8 @@ func syncCompletedTaskToLark(
 		approverName = "Quản lý"
 	}
 
-	// Build report link
-	reportLink := fmt.Sprintf("%s/public/report/%s", frontendURL, detail.ID.String())
+	// Build signed report link
+	reportLink := detailReportLink(shareSvc, frontendURL, assign, detail, "")
 
 	// Get assignees
 	var assignees []domain.User
@@ -493,17 +1067,9 @@ func syncCompletedTaskToLark(
 		}
 	}
 
-	// Parse timestamps
-	submittedAt := ""
-	var subTimes []time.Time
-	if err := json.Unmarshal(detail.SubmittedAt, &subTimes); err == nil && len(subTimes) > 0 {
-		submittedAt = subTimes[len(subTimes)-1].Format("02/01/2006 15:04:05")
-	}
-	approvalAt := ""
-	var appTimes []time.Time
-	if err := json.Unmarshal(detail.ApprovalAt, &appTimes); err == nil && len(appTimes) > 0 {
-		approvalAt = appTimes[len(appTimes)-1].Format("02/01/2006 15:04:05")
-	}
+	// Timestamps from the task history
+	submittedAt := latestEventTime(events, detail.ID, domain.DetailEventSubmit)
+	approvalAt := latestEventTime(events, detail.ID, domain.DetailEventApprove)
 
 	for _, assignee := range assignees {
 		fields := map[string]interface{}{
@@ -512,10 +1078,12 @@ func syncCompletedTaskToLark(
 			"Work":                        ctxNames.WorkName,
 			"Sub - work":                  ctxNames.SubWorkName,
 			"Asset":                       ctxNames.AssetName,
-			"Đường dẫn": map[string]string{
+		}
+		if reportLink != "" {
+			fields["Đường dẫn"] = map[string]string{
 				"link": reportLink,
 				"text": "Xem Báo Cáo",
-			},
+			}
 		}
 		if submittedAt != "" {
 			fields["Thời gian Nhân sự nộp"] = submittedAt
@@ -537,7 +1105,9 @@ func syncCompletedTaskToLark(
 func syncRejectedTaskToLark(
 	db *gorm.DB,
 	larkSvc *LarkService,
+	shareSvc *ShareLinkService,
 	cfg config.Config,
+	events domain.DetailAssignEventRepository,
 	detail domain.DetailAssign,
 	assign domain.Assign,
 	userIDs []string,
@@ -566,17 +1136,8 @@ func syncRejectedTaskToLark(
 		rejectorName = "Quản lý"
 	}
 
-	// Build public repair-report link (note: same route, different query param)
-	queryParams := []string{}
-	if detail.Config != nil {
-		queryParams = append(queryParams, "asset="+detail.Config.AssetID.String(), "sub="+detail.Config.SubWorkID.String())
-	}
-	queryParams = append(queryParams, "type=reject")
-
-	reportLink := fmt.Sprintf("%s/share/report/%s", frontendURL, assign.ID.String())
-	if len(queryParams) > 0 {
-		reportLink += "?" + strings.Join(queryParams, "&")
-	}
+	// Build signed repair-report link (note: same route, different query param)
+	reportLink := detailReportLink(shareSvc, frontendURL, assign, detail, "reject")
 
 	// Get assignees
 	var assignees []domain.User
@@ -591,18 +1152,9 @@ func syncRejectedTaskToLark(
 		}
 	}
 
-	// Parse timestamps
-	submittedAt := ""
-	var subTimes []time.Time
-	if err := json.Unmarshal(detail.SubmittedAt, &subTimes); err == nil && len(subTimes) > 0 {
-		submittedAt = subTimes[len(subTimes)-1].Format("02/01/2006 15:04:05")
-	}
-
-	rejectedAt := ""
-	var rejTimes []time.Time
-	if err := json.Unmarshal(detail.RejectedAt, &rejTimes); err == nil && len(rejTimes) > 0 {
-		rejectedAt = rejTimes[len(rejTimes)-1].Format("02/01/2006 15:04:05")
-	}
+	// Timestamps from the task history
+	submittedAt := latestEventTime(events, detail.ID, domain.DetailEventSubmit)
+	rejectedAt := latestEventTime(events, detail.ID, domain.DetailEventReject)
 
 	for _, assignee := range assignees {
 		fields := map[string]interface{}{
@@ -611,10 +1163,12 @@ func syncRejectedTaskToLark(
 			"Work":                        ctxNames.WorkName,
 			"Sub - work":                  ctxNames.SubWorkName,
 			"Asset":                       ctxNames.AssetName,
-			"Đường dẫn": map[string]string{
+		}
+		if reportLink != "" {
+			fields["Đường dẫn"] = map[string]string{
 				"link": reportLink,
 				"text": "Xem Báo Cáo",
-			},
+			}
 		}
 		if submittedAt != "" {
 			fields["Thời gian Nhân sự nộp"] = submittedAt
@@ -2,12 +2,16 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/glebarez/sqlite"
 	"github.com/google/uuid"
 	"github.com/phuc/cmms-backend/internal/config"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
 	"github.com/phuc/cmms-backend/internal/domain"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
@@ -17,6 +21,21 @@ import (
 type MockDetailAssignRepository struct {
 	Details map[string]*domain.DetailAssign
 	UpdateCalled bool
+	Stale        bool // Next Update loses the race to a concurrent write
+}
+
+// Version returns the stored version of a detail, as a client would have it.
+func (m *MockDetailAssignRepository) Version(id uuid.UUID) int {
+	return m.Details[id.String()].Version
+}
+
+// Versions returns the stored versions of every detail, keyed by ID.
+func (m *MockDetailAssignRepository) Versions() map[string]int {
+	versions := make(map[string]int, len(m.Details))
+	for id, d := range m.Details {
+		versions[id] = d.Version
+	}
+	return versions
 }
 
 func (m *MockDetailAssignRepository) FindByID(id uuid.UUID) (*domain.DetailAssign, error) {
@@ -26,15 +45,36 @@ func (m *MockDetailAssignRepository) FindByID(id uuid.UUID) (*domain.DetailAssig
 	return nil, gorm.ErrRecordNotFound
 }
 func (m *MockDetailAssignRepository) FindByAssignID(assignID uuid.UUID) ([]domain.DetailAssign, error) {
-	return nil, nil
+	var details []domain.DetailAssign
+	for _, d := range m.Details {
+		if d.AssignID == assignID {
+			details = append(details, *d)
+		}
+	}
+	return details, nil
 }
 func (m *MockDetailAssignRepository) Create(detail *domain.DetailAssign) error { return nil }
 func (m *MockDetailAssignRepository) Update(detail *domain.DetailAssign) error {
+	if m.Stale {
+		m.Stale = false
+		detail.Version++ // The other write's bump
+		return domain.ErrStaleVersion
+	}
+	detail.Version++
 	m.Details[detail.ID.String()] = detail
 	m.UpdateCalled = true
 	return nil
 }
 func (m *MockDetailAssignRepository) Delete(id uuid.UUID) error { return nil }
+func (m *MockDetailAssignRepository) WithTx(tx *gorm.DB) domain.DetailAssignRepository { return m }
+func (m *MockDetailAssignRepository) HasMedia(assignID uuid.UUID, key string) (bool, error) {
+	for _, d := range m.Details {
+		if d.AssignID == assignID && (strings.Contains(string(d.Data), key) || strings.Contains(string(d.SlotEvidence), key)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
 func (m *MockDetailAssignRepository) GetNamesForMinioPath(id uuid.UUID) (*domain.MinioPathContext, error) {
 	return &domain.MinioPathContext{
 		ProjectName: "Test Proj",
@@ -63,16 +103,17 @@ func TestApproveDetail(t *testing.T) {
 	// No panic broadcast func
 	broadcastFn := func(msg []byte) {}
 
-	svc := NewAllocationWorkflowService(db, mockRepo, nil, broadcastFn, config.Config{})
+	svc := NewAllocationWorkflowService(db, mockRepo, nil, broadcastFn, config.Config{}, nil, nil, nil, nil, nil, nil)
 
 	id := uuid.New()
 	mockRepo.Details[id.String()] = &domain.DetailAssign{
 		ID:         id,
 		AssignID:   uuid.New(),
+		State:      domain.DetailStateSubmitted,
 		ApprovalAt: datatypes.JSON("[]"), // Start empty JSON array
 	}
 
-	result, err := svc.ApproveDetail(id, "Good job", "u1", "http://front")
+	result, err := svc.ApproveDetail(id, mockRepo.Version(id), "Good job", "u1", "manager", "http://front", nil)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -105,7 +146,7 @@ func TestRejectDetail(t *testing.T) {
 		Details: make(map[string]*domain.DetailAssign),
 	}
 
-	svc := NewAllocationWorkflowService(db, mockRepo, nil, func(msg []byte) {}, config.Config{})
+	svc := NewAllocationWorkflowService(db, mockRepo, nil, func(msg []byte) {}, config.Config{}, nil, nil, nil, nil, nil, nil)
 
 	id := uuid.New()
 	mockRepo.Details[id.String()] = &domain.DetailAssign{
@@ -115,7 +156,7 @@ func TestRejectDetail(t *testing.T) {
 		RejectedAt:  datatypes.JSON("[]"),
 	}
 
-	result, err := svc.RejectDetail(id, "Redo this", "u2", "http://front")
+	result, err := svc.RejectDetail(id, mockRepo.Version(id), "Redo this", "u2", "http://front", nil)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -139,12 +180,15 @@ func TestBulkUpdateStatus(t *testing.T) {
 	
 	id1 := uuid.New()
 	id2 := uuid.New()
-	mockRepo.Details[id1.String()] = &domain.DetailAssign{ID: id1, ApprovalAt: datatypes.JSON("[]")}
-	mockRepo.Details[id2.String()] = &domain.DetailAssign{ID: id2, ApprovalAt: datatypes.JSON("[]")}
+	mockRepo.Details[id1.String()] = &domain.DetailAssign{ID: id1, State: domain.DetailStateSubmitted, ApprovalAt: datatypes.JSON("[]")}
+	mockRepo.Details[id2.String()] = &domain.DetailAssign{ID: id2, State: domain.DetailStateResubmitted, ApprovalAt: datatypes.JSON("[]")}
 	
-	svc := NewAllocationWorkflowService(db, mockRepo, nil, func(m []byte) {}, config.Config{})
+	svc := NewAllocationWorkflowService(db, mockRepo, nil, func(m []byte) {}, config.Config{}, nil, nil, nil, nil, nil, nil)
 	
-	result := svc.BulkUpdateStatus([]string{id1.String(), id2.String(), "invalid-uuid"}, 1, "Bulk ok", "", "")
+	result, err := svc.BulkUpdateStatus([]string{id1.String(), id2.String(), "invalid-uuid"}, mockRepo.Versions(), 1, "Bulk ok", "", "", "", false, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
 	
 	if result.TotalRequested != 3 {
 		t.Errorf("Expected total 3, got %d", result.TotalRequested)
@@ -156,4 +200,630 @@ func TestBulkUpdateStatus(t *testing.T) {
 	if mockRepo.Details[id1.String()].StatusApprove != 1 {
 		t.Error("Expected detail 1 to be approved")
 	}
+	if len(result.Items) != 3 || result.Items[0].Status != BulkItemOK || result.Items[2].Status != BulkItemNotFound {
+		t.Errorf("Expected per-item results with the malformed ID not found, got %+v", result.Items)
+	}
+}
+
+func TestAtomicBulkRollsBack(t *testing.T) {
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	broadcasts := 0
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, func(m []byte) { broadcasts++ }, config.Config{}, nil, nil, nil, nil, nil, nil)
+
+	submitted, draft := uuid.New(), uuid.New()
+	mockRepo.Details[submitted.String()] = &domain.DetailAssign{ID: submitted, State: domain.DetailStateSubmitted}
+	mockRepo.Details[draft.String()] = &domain.DetailAssign{ID: draft, State: domain.DetailStateDraft}
+	ids := []string{submitted.String(), draft.String(), uuid.New().String()}
+	versions := mockRepo.Versions()
+
+	result, err := svc.BulkUpdateStatus(ids, versions, -1, "redo", "", "", "", true, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.RolledBack || result.SuccessCount != 0 {
+		t.Fatalf("Expected the batch to be rolled back, got %+v", result)
+	}
+	want := []string{BulkItemRolledBack, BulkItemInvalidState, BulkItemNotFound}
+	for i, item := range result.Items {
+		if item.Status != want[i] {
+			t.Errorf("Item %d: expected %s, got %s (%s)", i, want[i], item.Status, item.Error)
+		}
+	}
+	if broadcasts != 0 {
+		t.Error("Expected no broadcast for a rolled back batch")
+	}
+
+	// The mock repository does not roll back, so commit a fresh task
+	fresh := uuid.New()
+	mockRepo.Details[fresh.String()] = &domain.DetailAssign{ID: fresh, State: domain.DetailStateSubmitted}
+	result, _ = svc.BulkUpdateStatus([]string{fresh.String()}, mockRepo.Versions(), -1, "redo", "", "", "", true, nil)
+	if result.RolledBack || result.SuccessCount != 1 || broadcasts != 1 {
+		t.Errorf("Expected a clean batch to commit with one broadcast, got %+v after %d broadcasts", result, broadcasts)
+	}
+}
+
+func TestApproveRequiresSubmission(t *testing.T) {
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, nil, config.Config{}, nil, nil, nil, nil, nil, nil)
+
+	id := uuid.New()
+	mockRepo.Details[id.String()] = &domain.DetailAssign{ID: id, State: domain.DetailStateInProgress}
+
+	_, err := svc.ApproveDetail(id, mockRepo.Version(id), "", "u1", "manager", "", nil)
+	appErr, ok := err.(*apperrors.AppError)
+	if !ok || appErr.Code != apperrors.ErrInvalidState.Code {
+		t.Fatalf("Expected ErrInvalidState for an unsubmitted task, got %v", err)
+	}
+	if mockRepo.UpdateCalled {
+		t.Error("Expected a refused transition not to be saved")
+	}
+	if _, err := svc.ApproveDetail(uuid.New(), 0, "", "u1", "manager", "", nil); err != apperrors.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a missing task, got %v", err)
+	}
+}
+
+// MockDetailEventRepository implements domain.DetailAssignEventRepository in memory
+type MockDetailEventRepository struct {
+	Events []domain.DetailAssignEvent
+	Err    error // returned by Create when set
+}
+
+func (m *MockDetailEventRepository) Create(event *domain.DetailAssignEvent) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now()
+	m.Events = append(m.Events, *event)
+	return nil
+}
+func (m *MockDetailEventRepository) FindByDetail(detailID uuid.UUID) ([]domain.DetailAssignEvent, error) {
+	var out []domain.DetailAssignEvent
+	for _, e := range m.Events {
+		if e.DetailAssignID == detailID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+func (m *MockDetailEventRepository) FindByAssign(assignID uuid.UUID) ([]domain.DetailAssignEvent, error) {
+	var out []domain.DetailAssignEvent
+	for _, e := range m.Events {
+		if e.AssignID == assignID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+func (m *MockDetailEventRepository) FindLatest(detailID uuid.UUID, eventType domain.DetailEvent) (*domain.DetailAssignEvent, error) {
+	for i := len(m.Events) - 1; i >= 0; i-- {
+		if m.Events[i].DetailAssignID == detailID && m.Events[i].EventType == eventType {
+			return &m.Events[i], nil
+		}
+	}
+	return nil, nil
+}
+func (m *MockDetailEventRepository) WithTx(tx *gorm.DB) domain.DetailAssignEventRepository { return m }
+
+func TestSubmitRejectResubmitApprove(t *testing.T) {
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	events := &MockDetailEventRepository{}
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, nil, config.Config{}, nil, nil, events, nil, nil, nil)
+
+	id, manager := uuid.New(), uuid.New().String()
+	mockRepo.Details[id.String()] = &domain.DetailAssign{ID: id, Data: datatypes.JSON(`["a.jpg"]`)}
+
+	detail, err := svc.SubmitDetail(id, mockRepo.Version(id), []string{"a.jpg", "b.jpg"}, nil, nil, "draft note", "w1", true, nil)
+	if err != nil || detail.State != domain.DetailStateInProgress || detail.StatusSubmit != 0 {
+		t.Fatalf("Expected a draft save to leave the task in progress, got %s (%v)", detail.State, err)
+	}
+	var urls []string
+	json.Unmarshal(detail.Data, &urls)
+	if len(urls) != 2 {
+		t.Errorf("Expected evidence to be merged without duplicates, got %v", urls)
+	}
+
+	steps := []struct {
+		run  func() (*domain.DetailAssign, error)
+		want domain.DetailState
+	}{
+		{func() (*domain.DetailAssign, error) { return svc.SubmitDetail(id, mockRepo.Version(id), nil, nil, nil, "", "w1", false, nil) }, domain.DetailStateSubmitted},
+		{func() (*domain.DetailAssign, error) { return svc.RejectDetail(id, mockRepo.Version(id), "blurry", manager, "", nil) }, domain.DetailStateRejected},
+		{func() (*domain.DetailAssign, error) { return svc.SubmitDetail(id, mockRepo.Version(id), []string{"c.jpg"}, nil, nil, "", "w1", false, nil) }, domain.DetailStateResubmitted},
+		{func() (*domain.DetailAssign, error) { return svc.ApproveDetail(id, mockRepo.Version(id), "ok", "m1", "manager", "", nil) }, domain.DetailStateApproved},
+	}
+	for i, step := range steps {
+		detail, err := step.run()
+		if err != nil || detail.State != step.want {
+			t.Fatalf("Step %d: expected %s, got %v", i+1, step.want, err)
+		}
+	}
+	if detail := mockRepo.Details[id.String()]; detail.StatusApprove != 1 || detail.StatusReject != 1 {
+		t.Errorf("Expected approved rework to keep status_reject = 1, got approve=%d reject=%d", detail.StatusApprove, detail.StatusReject)
+	}
+	if _, err := svc.SubmitDetail(id, mockRepo.Version(id), nil, nil, nil, "", "w1", true, nil); err == nil {
+		t.Error("Expected edits to approved work to be refused")
+	}
+
+	// Every persisted step is in the timeline, refused ones are not
+	timeline, _ := svc.GetDetailTimeline(id)
+	want := []domain.DetailEvent{domain.DetailEventSaveDraft, domain.DetailEventSubmit, domain.DetailEventReject, domain.DetailEventSubmit, domain.DetailEventApprove}
+	if len(timeline) != len(want) {
+		t.Fatalf("Expected %d timeline events, got %d", len(want), len(timeline))
+	}
+	for i, ev := range timeline {
+		if ev.EventType != want[i] {
+			t.Errorf("Event %d: expected %s, got %s", i+1, want[i], ev.EventType)
+		}
+	}
+	if resubmit := timeline[3]; resubmit.FromState != domain.DetailStateRejected || resubmit.ToState != domain.DetailStateResubmitted || string(resubmit.Evidence) != `["a.jpg","b.jpg","c.jpg"]` {
+		t.Errorf("Expected the resubmission to snapshot its evidence, got %s -> %s %s", resubmit.FromState, resubmit.ToState, resubmit.Evidence)
+	}
+	if reject := timeline[2]; reject.ActorID == nil || reject.ActorID.String() != manager || reject.Note != "blurry" {
+		t.Errorf("Expected the rejection to record its actor and note, got %v %q", reject.ActorID, reject.Note)
+	}
+}
+
+func TestFailedHistoryWriteFailsTheStep(t *testing.T) {
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	events := &MockDetailEventRepository{Err: errors.New("disk full")}
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, nil, config.Config{}, nil, nil, events, nil, nil, nil)
+
+	id := uuid.New()
+	mockRepo.Details[id.String()] = &domain.DetailAssign{ID: id, StatusWork: 1, StatusSubmit: 1}
+	if _, err := svc.RejectDetail(id, mockRepo.Version(id), "blurry", "m1", "", nil); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Expected the history write error to fail the rejection, got %v", err)
+	}
+	if _, err := svc.SubmitDetail(id, mockRepo.Version(id), nil, nil, nil, "", "w1", false, nil); err == nil {
+		t.Error("Expected the history write error to fail the submission")
+	}
+}
+
+func TestBulkReopenSkipsApprovedWork(t *testing.T) {
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, nil, config.Config{}, nil, nil, nil, nil, nil, nil)
+
+	approved, submitted := uuid.New(), uuid.New()
+	mockRepo.Details[approved.String()] = &domain.DetailAssign{ID: approved, StatusWork: 1, StatusSubmit: 1, StatusApprove: 1}
+	mockRepo.Details[submitted.String()] = &domain.DetailAssign{ID: submitted, StatusWork: 1, StatusSubmit: 1}
+
+	result, err := svc.BulkUpdateStatus([]string{approved.String(), submitted.String()}, mockRepo.Versions(), 0, "", "", "", "", false, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.SuccessCount != 1 || len(result.InvalidStateIDs) != 1 || result.InvalidStateIDs[0] != approved.String() {
+		t.Fatalf("Expected only the submitted task to be reopened, got %+v", result)
+	}
+	if mockRepo.Details[approved.String()].StatusApprove != 1 {
+		t.Error("Expected approved work to stay approved")
+	}
+	if d := mockRepo.Details[submitted.String()]; d.State != domain.DetailStateInProgress || d.StatusSubmit != 0 {
+		t.Errorf("Expected the submitted task back in progress, got %s", d.State)
+	}
+
+	if _, err := svc.BulkUpdateStatus([]string{submitted.String()}, mockRepo.Versions(), 2, "", "", "", "", false, nil); err != ErrBulkAccept {
+		t.Errorf("Expected ErrBulkAccept for an unknown accept value, got %v", err)
+	}
+}
+
+// MockApprovalChainRepository implements domain.ApprovalChainRepository with a single chain
+type MockApprovalChainRepository struct {
+	Chain     *domain.ApprovalChain
+	Approvals []domain.DetailApproval
+}
+
+func (m *MockApprovalChainRepository) FindForAssign(projectID uuid.UUID, templateID *uuid.UUID) (*domain.ApprovalChain, error) {
+	return m.Chain, nil
+}
+func (m *MockApprovalChainRepository) FindByProject(projectID uuid.UUID) (*domain.ApprovalChain, error) {
+	return m.Chain, nil
+}
+func (m *MockApprovalChainRepository) FindByTemplate(templateID uuid.UUID) (*domain.ApprovalChain, error) {
+	return nil, nil
+}
+func (m *MockApprovalChainRepository) Save(chain *domain.ApprovalChain) error {
+	m.Chain = chain
+	return nil
+}
+func (m *MockApprovalChainRepository) DeleteByProject(projectID uuid.UUID) error {
+	m.Chain = nil
+	return nil
+}
+func (m *MockApprovalChainRepository) DeleteByTemplate(templateID uuid.UUID) error { return nil }
+func (m *MockApprovalChainRepository) FindApprovals(detailID uuid.UUID, round int) ([]domain.DetailApproval, error) {
+	var out []domain.DetailApproval
+	for _, a := range m.Approvals {
+		if a.DetailAssignID == detailID && a.Round == round {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+func (m *MockApprovalChainRepository) RecordApproval(approval *domain.DetailApproval, detail *domain.DetailAssign) error {
+	m.Approvals = append(m.Approvals, *approval)
+	detail.Version++
+	return nil
+}
+func (m *MockApprovalChainRepository) WithTx(tx *gorm.DB) domain.ApprovalChainRepository { return m }
+
+func TestApprovalChainSignOff(t *testing.T) {
+	pm1, pm2, owner := uuid.New(), uuid.New(), uuid.New()
+	chains := &MockApprovalChainRepository{}
+	chainSvc := NewApprovalChainService(chains)
+	// Site lead (any manager) -> two of the named PMs -> owner engineer
+	if _, err := chainSvc.SetProjectChain(uuid.New(), []ApprovalLevelInput{
+		{Name: "Site lead", Roles: []string{"manager"}},
+		{Name: "PM", UserIDs: []string{pm1.String(), pm2.String()}, Quorum: 2},
+		{Name: "Owner", UserIDs: []string{owner.String()}},
+	}); err != nil {
+		t.Fatalf("SetProjectChain failed: %v", err)
+	}
+	if _, err := chainSvc.SetProjectChain(uuid.New(), []ApprovalLevelInput{{UserIDs: []string{pm1.String()}, Quorum: 2}}); err == nil {
+		t.Error("Expected an unreachable quorum to be rejected")
+	}
+
+	id := uuid.New()
+	mockRepo := &MockDetailAssignRepository{Details: map[string]*domain.DetailAssign{
+		id.String(): {ID: id, State: domain.DetailStateSubmitted, ApprovalRound: 1, Assign: &domain.Assign{}},
+	}}
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, nil, config.Config{}, nil, chains, nil, nil, nil, nil)
+
+	lead := uuid.New().String()
+	if _, err := svc.ApproveDetail(id, mockRepo.Version(id), "", pm1.String(), "engineer", "", nil); err == nil {
+		t.Error("Expected a PM to be refused at the site lead level")
+	}
+	detail, err := svc.ApproveDetail(id, mockRepo.Version(id), "site ok", lead, "manager", "", nil)
+	if err != nil {
+		t.Fatalf("Site lead sign-off failed: %v", err)
+	}
+	if detail.State != domain.DetailStatePartiallyApproved || detail.ApprovalLevel != 1 || detail.StatusApprove != 0 {
+		t.Fatalf("Expected partially_approved at level 1, got %s at %d", detail.State, detail.ApprovalLevel)
+	}
+
+	// The PM level needs two distinct PMs
+	svc.ApproveDetail(id, mockRepo.Version(id), "", pm1.String(), "engineer", "", nil)
+	if detail.ApprovalLevel != 1 {
+		t.Errorf("Expected the PM level to wait for its quorum, got level %d", detail.ApprovalLevel)
+	}
+	if _, err := svc.ApproveDetail(id, mockRepo.Version(id), "", pm1.String(), "engineer", "", nil); err != ErrAlreadySignedOff {
+		t.Errorf("Expected a second sign-off by the same PM to be refused, got %v", err)
+	}
+	// In bulk, a repeated sign-off and a refused approver get their own outcomes
+	bulk, _ := svc.BulkUpdateStatus([]string{id.String()}, map[string]int{id.String(): mockRepo.Version(id)}, 1, "", pm1.String(), "engineer", "", false, nil)
+	if len(bulk.Items) != 1 || bulk.Items[0].Status != BulkItemSignedOff || len(bulk.SignedOffIDs) != 1 {
+		t.Errorf("Expected the repeated bulk sign-off to be reported as signed_off, got %+v", bulk.Items)
+	}
+	bulk, _ = svc.BulkUpdateStatus([]string{id.String()}, map[string]int{id.String(): mockRepo.Version(id)}, 1, "", owner.String(), "engineer", "", false, nil)
+	if len(bulk.Items) != 1 || bulk.Items[0].Status != BulkItemNotApprover {
+		t.Errorf("Expected the owner to be refused at the PM level, got %+v", bulk.Items)
+	}
+	svc.ApproveDetail(id, mockRepo.Version(id), "", pm2.String(), "engineer", "", nil)
+	if detail.ApprovalLevel != 2 || detail.State != domain.DetailStatePartiallyApproved {
+		t.Fatalf("Expected the PM level to complete, got %s at %d", detail.State, detail.ApprovalLevel)
+	}
+
+	// A rejection restarts the chain on the next submission
+	if _, err := svc.RejectDetail(id, mockRepo.Version(id), "fix it", owner.String(), "", nil); err != nil {
+		t.Fatalf("RejectDetail failed: %v", err)
+	}
+	if _, err := svc.SubmitDetail(id, mockRepo.Version(id), []string{"a.jpg"}, nil, nil, "", "w1", false, nil); err != nil {
+		t.Fatalf("SubmitDetail failed: %v", err)
+	}
+	if detail.State != domain.DetailStateResubmitted || detail.ApprovalLevel != 0 || detail.ApprovalRound != 2 {
+		t.Fatalf("Expected a fresh round at level 0, got %s round %d level %d", detail.State, detail.ApprovalRound, detail.ApprovalLevel)
+	}
+
+	for _, step := range []struct{ user, role string }{{lead, "manager"}, {pm1.String(), ""}, {pm2.String(), ""}} {
+		if _, err := svc.ApproveDetail(id, mockRepo.Version(id), "", step.user, step.role, "", nil); err != nil {
+			t.Fatalf("Sign-off by %s failed: %v", step.user, err)
+		}
+	}
+	detail, err = svc.ApproveDetail(id, mockRepo.Version(id), "owner ok", owner.String(), "", "", nil)
+	if err != nil {
+		t.Fatalf("Owner sign-off failed: %v", err)
+	}
+	if detail.State != domain.DetailStateApproved || detail.StatusApprove != 1 || detail.StatusReject != 1 {
+		t.Errorf("Expected final approval counted as rework, got %s approve=%d reject=%d", detail.State, detail.StatusApprove, detail.StatusReject)
+	}
+	if len(chains.Approvals) != 7 {
+		t.Errorf("Expected 7 recorded sign-offs, got %d", len(chains.Approvals))
+	}
+}
+
+func TestSubmitRequiresEvidenceSlots(t *testing.T) {
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, nil, config.Config{}, nil, nil, nil, nil, nil, nil)
+
+	cfg := &domain.Config{EvidenceSlots: datatypes.JSON(`[
+		{"key":"before","name":"Before cleaning","min":1},
+		{"key":"after","name":"After cleaning","min":2,"max":4,"media_types":["image"]}
+	]`)}
+	id := uuid.New()
+	detail := &domain.DetailAssign{ID: id, Config: cfg}
+	detail.AttachToSlot("before", "b1.jpg")
+	detail.AttachToSlot("after", "a1.jpg")
+	detail.AttachToSlot("after", "a2.jpg")
+	mockRepo.Details[id.String()] = detail
+
+	// a2.jpg was uploaded but not kept, so "after" is one photo short
+	_, err := svc.SubmitDetail(id, mockRepo.Version(id), []string{"b1.jpg", "a1.jpg"}, nil, nil, "", "w1", false, nil)
+	missing, ok := err.(*MissingEvidenceError)
+	if !ok || len(missing.Missing) != 1 || missing.Missing[0].Key != "after" || missing.Missing[0].Have != 1 {
+		t.Fatalf("Expected the after slot to be reported missing, got %v", err)
+	}
+	if mockRepo.UpdateCalled {
+		t.Error("Expected a refused submit not to be saved")
+	}
+
+	detail.State, detail.StatusSubmit = domain.DetailStateDraft, 0
+	if _, err := svc.SubmitDetail(id, mockRepo.Version(id), []string{"a2.jpg", "extra.jpg"}, nil, nil, "", "w1", false, nil); err != nil {
+		t.Fatalf("Expected the submit to pass once every slot is filled, got %v", err)
+	}
+	groups := detail.GroupEvidence(cfg)
+	if len(groups) != 3 || groups[0].Name != "Before cleaning" || len(groups[1].URLs) != 2 || groups[2].URLs[0] != "extra.jpg" {
+		t.Errorf("Expected photos grouped by slot with loose photos last, got %+v", groups)
+	}
+
+	if slot, _ := cfg.FindSlot("after"); slot.AllowsMedia("video/mp4") || !slot.AllowsMedia("image/png") {
+		t.Error("Expected the after slot to accept images only")
+	}
+	legacy := &domain.Config{StatusSetImageCount: true, ImageCount: 3}
+	if m := detail.MissingEvidence(legacy); len(m) != 0 {
+		t.Errorf("Expected the legacy image count to be met by 4 photos, got %+v", m)
+	}
+}
+
+func TestSubmitValidatesMeasurements(t *testing.T) {
+	db := setupTestDB(t)
+	// AutoMigrate can't create the gen_random_uuid() default on sqlite
+	db.Exec(`CREATE TABLE IF NOT EXISTS detail_measurements (id text PRIMARY KEY, id_detail_assign text, field_key text,
+		position integer, label text, unit text, value real, min_value real, max_value real, out_of_range numeric,
+		created_at datetime, updated_at datetime)`)
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	svc := NewAllocationWorkflowService(db, mockRepo, nil, nil, config.Config{}, nil, nil, nil, nil, nil, nil)
+
+	// The sub-work declares the fields; the config has no override
+	cfg := &domain.Config{SubWork: &domain.SubWork{MeasurementFields: datatypes.JSON(`[
+		{"key":"voc","label":"String Voc","unit":"V","min":600,"max":800,"required":true,"precision":1},
+		{"key":"riso","label":"Insulation","unit":"MΩ","min":1,"precision":0}
+	]`)}}
+	id := uuid.New()
+	mockRepo.Details[id.String()] = &domain.DetailAssign{ID: id, Config: cfg}
+
+	if _, err := svc.SubmitDetail(id, mockRepo.Version(id), nil, map[string]float64{"torque": 12}, nil, "", "w1", true, nil); err == nil {
+		t.Fatal("Expected an unknown measurement field to be refused")
+	}
+	// Drafts may leave required fields empty
+	if _, err := svc.SubmitDetail(id, mockRepo.Version(id), nil, map[string]float64{"riso": 0.4}, nil, "", "w1", true, nil); err != nil {
+		t.Fatalf("Expected the draft to be saved, got %v", err)
+	}
+	if _, err := svc.SubmitDetail(id, mockRepo.Version(id), nil, nil, nil, "", "w1", false, nil); err == nil || !strings.Contains(err.Error(), "String Voc") {
+		t.Fatalf("Expected the submit to require String Voc, got %v", err)
+	}
+
+	detail, err := svc.SubmitDetail(id, mockRepo.Version(id), nil, map[string]float64{"voc": 712.46}, nil, "", "w1", false, nil)
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if len(detail.Measurements) != 2 {
+		t.Fatalf("Expected the draft reading to be kept alongside the new one, got %+v", detail.Measurements)
+	}
+	voc, riso := detail.Measurements[0], detail.Measurements[1]
+	if voc.Value != 712.5 || voc.OutOfRange {
+		t.Errorf("Expected Voc rounded to 712.5 and in range, got %+v", voc)
+	}
+	if riso.Value != 0 || !riso.OutOfRange {
+		t.Errorf("Expected insulation rounded to 0 and flagged, got %+v", riso)
+	}
+
+	var stored []domain.DetailMeasurement
+	db.Where("id_detail_assign = ? AND out_of_range = ?", id, true).Find(&stored)
+	if len(stored) != 1 || stored[0].FieldKey != "riso" {
+		t.Errorf("Expected one flagged reading stored, got %+v", stored)
+	}
+}
+
+// MockFormSchemaRepository serves the latest form versions it holds
+type MockFormSchemaRepository struct {
+	Forms []domain.FormSchema
+}
+
+func (m *MockFormSchemaRepository) Create(schema *domain.FormSchema) error {
+	m.Forms = append([]domain.FormSchema{*schema}, m.Forms...)
+	return nil
+}
+func (m *MockFormSchemaRepository) FindByID(id uuid.UUID) (*domain.FormSchema, error) {
+	for i := range m.Forms {
+		if m.Forms[i].ID == id {
+			return &m.Forms[i], nil
+		}
+	}
+	return nil, nil
+}
+func (m *MockFormSchemaRepository) FindVersions(_, _ *uuid.UUID) ([]domain.FormSchema, error) {
+	return m.Forms, nil
+}
+func (m *MockFormSchemaRepository) FindLatest(_, _ []uuid.UUID) ([]domain.FormSchema, error) {
+	return m.Forms[:1], nil
+}
+
+func TestSubmitValidatesFormAnswers(t *testing.T) {
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	subWorkID := uuid.New()
+	forms := &MockFormSchemaRepository{Forms: []domain.FormSchema{{ID: uuid.New(), SubWorkID: &subWorkID, Version: 1, Fields: datatypes.JSON(`[
+		{"key":"clean","label":"Panels cleaned","type":"check","required":true},
+		{"key":"soiling","label":"Soiling","type":"select","options":[{"value":"light","label":"Light"},{"value":"heavy","label":"Heavy"}]},
+		{"key":"faults","label":"Faults","type":"multi_select","options":[{"value":"crack","label":"Crack"},{"value":"hotspot","label":"Hotspot"}]},
+		{"key":"serial","label":"Serial","type":"text","pattern":"SN-[0-9]{4}"}
+	]`)}}}
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, nil, config.Config{}, nil, nil, nil, forms, nil, nil)
+
+	id := uuid.New()
+	mockRepo.Details[id.String()] = &domain.DetailAssign{ID: id, Config: &domain.Config{ID: uuid.New(), SubWorkID: subWorkID}}
+	answers := func(raw string) map[string]json.RawMessage {
+		var m map[string]json.RawMessage
+		_ = json.Unmarshal([]byte(raw), &m)
+		return m
+	}
+
+	for _, bad := range []string{
+		`{"torque":"12"}`,
+		`{"clean":"yes"}`,
+		`{"soiling":"medium"}`,
+		`{"faults":["crack","crack"]}`,
+		`{"serial":"SN-12"}`,
+	} {
+		if _, err := svc.SubmitDetail(id, mockRepo.Version(id), nil, nil, answers(bad), "", "w1", true, nil); err == nil {
+			t.Errorf("Expected %s to be refused", bad)
+		}
+	}
+	// Drafts may leave required fields empty, and pin the form version
+	detail, err := svc.SubmitDetail(id, mockRepo.Version(id), nil, nil, answers(`{"soiling":"heavy","serial":"SN-0042"}`), "", "w1", true, nil)
+	if err != nil {
+		t.Fatalf("Expected the draft to be saved, got %v", err)
+	}
+	if detail.FormSchemaID == nil || *detail.FormSchemaID != forms.Forms[0].ID {
+		t.Fatalf("Expected the task to be pinned to version 1, got %v", detail.FormSchemaID)
+	}
+	if _, err := svc.SubmitDetail(id, mockRepo.Version(id), nil, nil, nil, "", "w1", false, nil); err == nil || !strings.Contains(err.Error(), "Panels cleaned") {
+		t.Fatalf("Expected the submit to require Panels cleaned, got %v", err)
+	}
+
+	// A later version does not apply to the pinned task
+	_ = forms.Create(&domain.FormSchema{ID: uuid.New(), SubWorkID: &subWorkID, Version: 2, Fields: datatypes.JSON(`[{"key":"torque","label":"Torque","type":"text"}]`)})
+	detail, err = svc.SubmitDetail(id, mockRepo.Version(id), nil, nil, answers(`{"clean":true,"faults":["hotspot"],"serial":null}`), "", "w1", false, nil)
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	got := detail.Answers()
+	if string(got["clean"]) != "true" || string(got["soiling"]) != `"heavy"` || got["serial"] != nil || len(got) != 3 {
+		t.Errorf("Expected draft answers merged and serial cleared, got %s", detail.FormAnswers)
+	}
+	if rows := formAnswersForPDF(detail); len(rows) != 4 || rows[0][1] != "Có" || rows[1][1] != "Heavy" || rows[3][1] != "—" {
+		t.Errorf("Expected answers rendered by label, got %v", rows)
+	}
+}
+
+// MockConfigDependencyRepository holds the dependencies of one template
+type MockConfigDependencyRepository struct {
+	Deps []domain.ConfigDependency
+}
+
+func (m *MockConfigDependencyRepository) FindByTemplate(uuid.UUID) ([]domain.ConfigDependency, error) {
+	return m.Deps, nil
+}
+func (m *MockConfigDependencyRepository) ReplaceForTemplate(_ uuid.UUID, deps []domain.ConfigDependency) error {
+	m.Deps = deps
+	return nil
+}
+
+func TestSubmitWaitsForPredecessors(t *testing.T) {
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	lockout, reenergize := uuid.New(), uuid.New()
+	deps := &MockConfigDependencyRepository{Deps: []domain.ConfigDependency{
+		{ConfigID: reenergize, DependsOnID: lockout, Requires: domain.GateApproved},
+	}}
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, nil, config.Config{}, nil, nil, nil, nil, deps, nil)
+
+	templateID := uuid.New()
+	assign := &domain.Assign{ID: uuid.New(), TemplateID: &templateID}
+	first, second := uuid.New(), uuid.New()
+	mockRepo.Details[first.String()] = &domain.DetailAssign{ID: first, AssignID: assign.ID, Assign: assign, ConfigID: &lockout}
+	mockRepo.Details[second.String()] = &domain.DetailAssign{ID: second, AssignID: assign.ID, Assign: assign, ConfigID: &reenergize}
+
+	_, err := svc.SubmitDetail(second, mockRepo.Version(second), nil, nil, nil, "", "w1", true, nil)
+	blocked, ok := err.(*BlockedTaskError)
+	if !ok || len(blocked.BlockedBy) != 1 || blocked.BlockedBy[0].DetailAssignID != first {
+		t.Fatalf("Expected starting the work to wait for lock-out, got %v", err)
+	}
+
+	if _, err := svc.SubmitDetail(first, mockRepo.Version(first), nil, nil, nil, "", "w1", false, nil); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	// Submitted is not enough: the gate is approval
+	if _, err := svc.SubmitDetail(second, mockRepo.Version(second), nil, nil, nil, "", "w1", false, nil); err == nil {
+		t.Fatal("Expected the submit to wait for lock-out to be approved")
+	}
+	if _, err := svc.ApproveDetail(first, mockRepo.Version(first), "", "a1", "manager", "", nil); err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if _, err := svc.SubmitDetail(second, mockRepo.Version(second), nil, nil, nil, "", "w1", false, nil); err != nil {
+		t.Fatalf("Expected the submit to pass once lock-out is approved, got %v", err)
+	}
+
+	cycle := domain.DependencyCycle(append(deps.Deps, domain.ConfigDependency{ConfigID: lockout, DependsOnID: reenergize}))
+	if len(cycle) != 3 || cycle[0] != cycle[2] {
+		t.Errorf("Expected the cycle to be found, got %v", cycle)
+	}
+}
+
+func TestStaleVersionsConflict(t *testing.T) {
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, nil, config.Config{}, nil, nil, nil, nil, nil, nil)
+
+	id := uuid.New()
+	mockRepo.Details[id.String()] = &domain.DetailAssign{ID: id, Version: 3, Data: datatypes.JSON(`["a.jpg"]`)}
+
+	// An offline client still holding version 2
+	_, err := svc.SubmitDetail(id, 2, []string{"b.jpg"}, nil, nil, "", "w1", false, nil)
+	conflict, ok := err.(*VersionConflictError)
+	if !ok || conflict.Current.Version != 3 {
+		t.Fatalf("Expected a conflict carrying version 3, got %v", err)
+	}
+	if mockRepo.UpdateCalled {
+		t.Error("Expected a stale submit not to be saved")
+	}
+
+	// Another write lands between the read and the save
+	mockRepo.Stale = true
+	if _, err := svc.SaveNote(id, 3, "note", nil); err == nil {
+		t.Fatal("Expected the lost race to be a conflict")
+	} else if _, ok := err.(*VersionConflictError); !ok {
+		t.Fatalf("Expected VersionConflictError, got %v", err)
+	}
+
+	detail, err := svc.SubmitDetail(id, mockRepo.Version(id), []string{"b.jpg"}, nil, nil, "", "w1", false, nil)
+	if err != nil {
+		t.Fatalf("Submit at the current version failed: %v", err)
+	}
+	if detail.Version != 5 || string(detail.Data) != `["a.jpg","b.jpg"]` {
+		t.Errorf("Expected version 5 with merged evidence, got %d %s", detail.Version, detail.Data)
+	}
+
+	result, err := svc.BulkUpdateStatus([]string{id.String()}, map[string]int{id.String(): 4}, -1, "", "", "", "", false, nil)
+	if err != nil {
+		t.Fatalf("Bulk failed: %v", err)
+	}
+	if result.SuccessCount != 0 || len(result.Conflicts) != 1 || result.Conflicts[0].State != domain.DetailStateSubmitted {
+		t.Errorf("Expected the stale bulk reject to be reported as a conflict, got %+v", result)
+	}
+}
+
+func TestWritesOutsideScopeNotFound(t *testing.T) {
+	mockRepo := &MockDetailAssignRepository{Details: make(map[string]*domain.DetailAssign)}
+	worker, stranger := uuid.New(), uuid.New()
+	assign := &domain.Assign{ID: uuid.New(), UserIDs: uuidsJSON([]uuid.UUID{worker})}
+	assigns := &MockAssignRepository{Assigns: map[uuid.UUID]*domain.Assign{assign.ID: assign}}
+	svc := NewAllocationWorkflowService(setupTestDB(t), mockRepo, nil, nil, config.Config{}, nil, nil, nil, nil, nil, assigns)
+
+	id := uuid.New()
+	mockRepo.Details[id.String()] = &domain.DetailAssign{ID: id, AssignID: assign.ID, Version: 2, State: domain.DetailStateSubmitted}
+	outside := &domain.VisibilityScope{UserID: stranger, Kind: domain.VisibilityAssignee}
+
+	// A stale version must not reveal the task either
+	if _, err := svc.SaveNote(id, 1, "note", outside); appStatus(err) != http.StatusNotFound {
+		t.Fatalf("Expected a hidden task to be not found, got %v", err)
+	}
+	if _, err := svc.RejectDetail(id, 2, "redo", stranger.String(), "", outside); appStatus(err) != http.StatusNotFound {
+		t.Fatalf("Expected a hidden task to be not found, got %v", err)
+	}
+	result, _ := svc.BulkUpdateStatus([]string{id.String()}, mockRepo.Versions(), -1, "", "", "", "", false, outside)
+	if result.Items[0].Status != BulkItemNotFound || mockRepo.UpdateCalled {
+		t.Fatalf("Expected the hidden task to be reported not found and left alone, got %+v", result.Items)
+	}
+
+	own := &domain.VisibilityScope{UserID: worker, Kind: domain.VisibilityAssignee}
+	if _, err := svc.SaveNote(id, 2, "note", own); err != nil {
+		t.Fatalf("Expected the assignee to save the note, got %v", err)
+	}
 }
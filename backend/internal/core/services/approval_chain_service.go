@@ -0,0 +1,113 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// Upper bound on chain length; longer chains are almost certainly a mistake
+const maxApprovalLevels = 10
+
+// ApprovalLevelInput describes one level of a chain to save
+type ApprovalLevelInput struct {
+	Name    string   `json:"name"`
+	Roles   []string `json:"roles"`    // Role names allowed to sign off
+	UserIDs []string `json:"id_users"` // Users allowed to sign off
+	Quorum  int      `json:"quorum"`   // Distinct sign-offs needed; 0 = 1
+}
+
+// ApprovalChainService configures the approval chains of projects and templates
+type ApprovalChainService struct {
+	repo domain.ApprovalChainRepository
+}
+
+func NewApprovalChainService(repo domain.ApprovalChainRepository) *ApprovalChainService {
+	return &ApprovalChainService{repo: repo}
+}
+
+func (s *ApprovalChainService) GetProjectChain(projectID uuid.UUID) (*domain.ApprovalChain, error) {
+	return s.repo.FindByProject(projectID)
+}
+
+func (s *ApprovalChainService) GetTemplateChain(templateID uuid.UUID) (*domain.ApprovalChain, error) {
+	return s.repo.FindByTemplate(templateID)
+}
+
+// SetProjectChain replaces the chain of a project. Sign-offs already given to
+// tasks under review are kept and count against the new levels by position.
+func (s *ApprovalChainService) SetProjectChain(projectID uuid.UUID, levels []ApprovalLevelInput) (*domain.ApprovalChain, error) {
+	return s.save(&domain.ApprovalChain{ProjectID: &projectID}, levels)
+}
+
+// SetTemplateChain replaces the chain of a template (overriding its project's chain)
+func (s *ApprovalChainService) SetTemplateChain(templateID uuid.UUID, levels []ApprovalLevelInput) (*domain.ApprovalChain, error) {
+	return s.save(&domain.ApprovalChain{TemplateID: &templateID}, levels)
+}
+
+// DeleteProjectChain returns the project to single-step approval
+func (s *ApprovalChainService) DeleteProjectChain(projectID uuid.UUID) error {
+	return s.repo.DeleteByProject(projectID)
+}
+
+// DeleteTemplateChain makes the template fall back to its project's chain
+func (s *ApprovalChainService) DeleteTemplateChain(templateID uuid.UUID) error {
+	return s.repo.DeleteByTemplate(templateID)
+}
+
+func (s *ApprovalChainService) save(chain *domain.ApprovalChain, levels []ApprovalLevelInput) (*domain.ApprovalChain, error) {
+	if len(levels) == 0 || len(levels) > maxApprovalLevels {
+		return nil, invalidChain(fmt.Sprintf("A chain needs between 1 and %d levels", maxApprovalLevels))
+	}
+	for i, in := range levels {
+		roles := make([]string, 0, len(in.Roles))
+		for _, r := range in.Roles {
+			if r = strings.TrimSpace(r); r != "" {
+				roles = append(roles, r)
+			}
+		}
+		users := make([]string, 0, len(in.UserIDs))
+		for _, u := range in.UserIDs {
+			id, err := uuid.Parse(u)
+			if err != nil {
+				return nil, invalidChain(fmt.Sprintf("Level %d: invalid user ID %q", i+1, u))
+			}
+			users = append(users, id.String())
+		}
+		quorum := in.Quorum
+		if quorum <= 0 {
+			quorum = 1
+		}
+		// With named users only, the quorum must be reachable
+		if len(roles) == 0 && len(users) > 0 && quorum > len(users) {
+			return nil, invalidChain(fmt.Sprintf("Level %d: quorum %d exceeds its %d approvers", i+1, quorum, len(users)))
+		}
+
+		rolesJSON, _ := json.Marshal(roles)
+		usersJSON, _ := json.Marshal(users)
+		name := strings.TrimSpace(in.Name)
+		if name == "" {
+			name = fmt.Sprintf("Level %d", i+1)
+		}
+		chain.Levels = append(chain.Levels, domain.ApprovalLevel{
+			Position: i,
+			Name:     name,
+			Roles:    rolesJSON,
+			UserIDs:  usersJSON,
+			Quorum:   quorum,
+		})
+	}
+	if err := s.repo.Save(chain); err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+func invalidChain(msg string) error {
+	return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// BuildDetailAssigns returns the tasks of an assign covering the given configs:
+// one per process of the config's sub-work, or a single task without a process
+// when the sub-work has none (or the config cannot be loaded).
+func BuildDetailAssigns(configRepo domain.ConfigRepository, assignID uuid.UUID, configIDs []uuid.UUID) []domain.DetailAssign {
+	var details []domain.DetailAssign
+	for _, cfgID := range configIDs {
+		cfgID := cfgID
+		var processIDs []string
+		if cfg, err := configRepo.FindByID(cfgID); err == nil && cfg.SubWork != nil && len(cfg.SubWork.ProcessIDs) > 0 {
+			_ = json.Unmarshal(cfg.SubWork.ProcessIDs, &processIDs)
+		}
+		added := false
+		for _, procStr := range processIDs {
+			procID, err := uuid.Parse(procStr)
+			if err != nil {
+				continue
+			}
+			details = append(details, domain.DetailAssign{ID: uuid.New(), AssignID: assignID, ConfigID: &cfgID, ProcessID: &procID})
+			added = true
+		}
+		if !added {
+			details = append(details, domain.DetailAssign{ID: uuid.New(), AssignID: assignID, ConfigID: &cfgID})
+		}
+	}
+	return details
+}
@@ -126,6 +126,11 @@ func (s *AttendanceService) GetByID(id uuid.UUID) (*domain.Attendance, error) {
 	return s.repo.GetAttendanceByID(id)
 }
 
+// GetVisibleByID returns an attendance record by ID if the scope may see it
+func (s *AttendanceService) GetVisibleByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.Attendance, error) {
+	return s.repo.GetVisibleAttendanceByID(id, scope)
+}
+
 // GetByDate returns an attendance record by user ID and date
 func (s *AttendanceService) GetByDate(userID uuid.UUID, date time.Time) (*domain.Attendance, error) {
 	return s.repo.GetAttendanceByDate(userID, date)
@@ -363,23 +368,23 @@ func (s *AttendanceService) GetTodayAttendanceByAssign(userID uuid.UUID, assignI
 }
 
 // GetUserHistory gets attendance history for a user
-func (s *AttendanceService) GetUserHistory(userID uuid.UUID, limit int) ([]domain.Attendance, error) {
-	return s.repo.GetUserAttendanceHistory(userID, limit)
+func (s *AttendanceService) GetUserHistory(userID uuid.UUID, limit int, scope *domain.VisibilityScope) ([]domain.Attendance, error) {
+	return s.repo.GetUserAttendanceHistory(userID, limit, scope)
 }
 
 // GetAllTodayAttendances gets all today's attendances (for managers)
-func (s *AttendanceService) GetAllTodayAttendances() ([]domain.Attendance, error) {
-	return s.repo.GetAllTodayAttendances()
+func (s *AttendanceService) GetAllTodayAttendances(scope *domain.VisibilityScope) ([]domain.Attendance, error) {
+	return s.repo.GetAllTodayAttendances(scope)
 }
 
 // GetUsersOnSite gets all users currently on site
-func (s *AttendanceService) GetUsersOnSite() ([]domain.Attendance, error) {
-	return s.repo.GetUsersOnSite()
+func (s *AttendanceService) GetUsersOnSite(scope *domain.VisibilityScope) ([]domain.Attendance, error) {
+	return s.repo.GetUsersOnSite(scope)
 }
 
 // GetAllAttendanceHistory gets all attendance records for managers
-func (s *AttendanceService) GetAllAttendanceHistory(limit int) ([]domain.Attendance, error) {
-	return s.repo.GetAllAttendanceHistory(limit)
+func (s *AttendanceService) GetAllAttendanceHistory(limit int, scope *domain.VisibilityScope) ([]domain.Attendance, error) {
+	return s.repo.GetAllAttendanceHistory(limit, scope)
 }
 
 // GetByAssignAndDates returns attendances for a specific assign on a set of dates (for report generation)
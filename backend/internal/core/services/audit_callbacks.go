@@ -0,0 +1,253 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/platform/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rows read before an update or delete, kept on the statement for the after callback
+const auditBeforeKey = "audit:before"
+
+// Tables whose writes are not logged: the log itself
+var auditSkippedTables = map[string]bool{"audit_logs": true}
+
+// RegisterCallbacks hooks the audit log into db: every create, update and delete
+// made through GORM, by API handlers, schedulers and queue consumers alike, logs
+// the affected rows before and after. Logs are written in the statement's
+// transaction, so a rolled-back write leaves no trace. Raw SQL (db.Exec) is not seen.
+func (s *AuditService) RegisterCallbacks(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Before("gorm:commit_or_rollback_transaction").Register("audit:after_create", s.afterWrite(domain.AuditActionCreate)); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").After("gorm:setup_reflect_value").Register("audit:before_update", s.beforeWrite); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Before("gorm:commit_or_rollback_transaction").Register("audit:after_update", s.afterWrite(domain.AuditActionUpdate)); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("audit:before_delete", s.beforeWrite); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Before("gorm:commit_or_rollback_transaction").Register("audit:after_delete", s.afterWrite(domain.AuditActionDelete))
+}
+
+func (s *AuditService) beforeWrite(db *gorm.DB) {
+	if !auditable(db) {
+		return
+	}
+	query, ok := auditTargetQuery(db)
+	if !ok {
+		return
+	}
+	var rows []map[string]interface{}
+	if err := query.Find(&rows).Error; err != nil {
+		logger.Get().Warn("Audit snapshot failed", zap.String("table", db.Statement.Table), zap.Error(err))
+		return
+	}
+	db.InstanceSet(auditBeforeKey, rows)
+}
+
+func (s *AuditService) afterWrite(action string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if !auditable(db) || db.Statement.RowsAffected == 0 {
+			return
+		}
+		var before, after []map[string]interface{}
+		if v, ok := db.InstanceGet(auditBeforeKey); ok {
+			before, _ = v.([]map[string]interface{})
+		}
+		if action == domain.AuditActionCreate {
+			after = createdRows(db.Statement)
+		} else if len(before) > 0 {
+			after = s.reread(db, before)
+		}
+
+		changes := pairAuditRows(auditKeyColumns(db.Statement), before, after)
+		if len(changes) == 0 {
+			return
+		}
+		base := domain.AuditLog{
+			RequestID:  uuid.New(),
+			ActorType:  domain.AuditActorSystem,
+			Action:     action,
+			EntityType: db.Statement.Table,
+		}
+		if actor, ok := domain.AuditActorFrom(db.Statement.Context); ok {
+			base.RequestID = actor.RequestID
+			base.ActorType = actor.Type
+			base.ActorID = actor.ID
+			base.ActorRole = actor.Role
+			base.IPAddress = actor.IPAddress
+		}
+
+		logs := auditLogs(base, changes, nil)
+		// A savepoint inside the caller's transaction: a failed log must not abort the write
+		err := auditSession(db).Transaction(func(tx *gorm.DB) error {
+			return s.repo.WithTx(tx).Create(logs)
+		})
+		if err != nil {
+			logger.Get().Error("Failed to write audit log", zap.String("entity_type", base.EntityType), zap.Error(err))
+		}
+	}
+}
+
+// reread loads the rows captured before the write again, by primary key when the
+// table has a single one (the write may have changed the columns it filtered on)
+func (s *AuditService) reread(db *gorm.DB, before []map[string]interface{}) []map[string]interface{} {
+	query, ok := auditTargetQuery(db)
+	if keys := auditKeyColumns(db.Statement); len(keys) == 1 {
+		ids := make([]interface{}, 0, len(before))
+		for _, row := range before {
+			ids = append(ids, row[keys[0]])
+		}
+		query = auditSession(db).Table(db.Statement.Table).Where(clause.IN{Column: clause.Column{Name: keys[0]}, Values: ids})
+		ok = true
+	}
+	if !ok {
+		return nil
+	}
+	var rows []map[string]interface{}
+	if err := query.Find(&rows).Error; err != nil {
+		logger.Get().Warn("Audit snapshot failed", zap.String("table", db.Statement.Table), zap.Error(err))
+		return nil
+	}
+	return rows
+}
+
+func auditable(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Table != "" && !auditSkippedTables[db.Statement.Table]
+}
+
+// auditSession is a fresh statement on the same connection (and transaction) as db
+func auditSession(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true})
+}
+
+// auditTargetQuery selects the rows a pending update or delete will touch: its WHERE
+// conditions plus the primary key of the model, as GORM adds it when writing.
+// Table() without a model applies no soft-delete scope, so deleted rows are still read.
+// Writes without any condition are not snapshotted.
+func auditTargetQuery(db *gorm.DB) (*gorm.DB, bool) {
+	stmt := db.Statement
+	var conds []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			conds = append(conds, where.Exprs...)
+		}
+	}
+	if stmt.Schema != nil && stmt.Schema.PrioritizedPrimaryField != nil {
+		field := stmt.Schema.PrioritizedPrimaryField
+		var ids []interface{}
+		eachModel(stmt.ReflectValue, func(v reflect.Value) {
+			if v.Kind() != reflect.Struct {
+				return
+			}
+			if id, zero := field.ValueOf(stmt.Context, v); !zero {
+				ids = append(ids, id)
+			}
+		})
+		if len(ids) > 0 {
+			conds = append(conds, clause.IN{Column: clause.Column{Name: field.DBName}, Values: ids})
+		}
+	}
+	if len(conds) == 0 {
+		return nil, false
+	}
+	return auditSession(db).Table(stmt.Table).Clauses(clause.Where{Exprs: conds}).Limit(auditSnapshotLimit), true
+}
+
+// createdRows reads the inserted values back from the created struct(s) or map(s)
+func createdRows(stmt *gorm.Statement) []map[string]interface{} {
+	var rows []map[string]interface{}
+	eachModel(stmt.ReflectValue, func(v reflect.Value) {
+		if v.Kind() == reflect.Map {
+			row := make(map[string]interface{}, v.Len())
+			for _, k := range v.MapKeys() {
+				row[fmt.Sprint(k.Interface())] = v.MapIndex(k).Interface()
+			}
+			rows = append(rows, row)
+			return
+		}
+		if stmt.Schema == nil || v.Kind() != reflect.Struct {
+			return
+		}
+		row := make(map[string]interface{}, len(stmt.Schema.DBNames))
+		for _, name := range stmt.Schema.DBNames {
+			value, _ := stmt.Schema.FieldsByDBName[name].ValueOf(stmt.Context, v)
+			row[name] = value
+		}
+		rows = append(rows, row)
+	})
+	return rows
+}
+
+// eachModel calls fn for the struct or map in rv, or for each element of a slice of them
+func eachModel(rv reflect.Value, fn func(reflect.Value)) {
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map:
+		fn(rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if elem := reflect.Indirect(rv.Index(i)); elem.IsValid() {
+				if elem.Kind() == reflect.Interface {
+					elem = reflect.Indirect(elem.Elem())
+				}
+				fn(elem)
+			}
+		}
+	}
+}
+
+// auditKeyColumns names the columns identifying a row: the primary key, else "id"
+func auditKeyColumns(stmt *gorm.Statement) []string {
+	if stmt.Schema != nil && len(stmt.Schema.PrimaryFieldDBNames) > 0 {
+		return stmt.Schema.PrimaryFieldDBNames
+	}
+	return []string{"id"}
+}
+
+// pairAuditRows matches before and after rows by key into one change per entity,
+// dropping rows the write left untouched
+func pairAuditRows(keys []string, before, after []map[string]interface{}) []AuditChange {
+	rowKey := func(row map[string]interface{}) string {
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = fmt.Sprint(row[k])
+		}
+		return strings.Join(parts, "/")
+	}
+
+	var changes []AuditChange
+	index := make(map[string]int)
+	for _, row := range before {
+		key := rowKey(row)
+		index[key] = len(changes)
+		changes = append(changes, AuditChange{EntityID: key, Before: []map[string]interface{}{row}})
+	}
+	for _, row := range after {
+		key := rowKey(row)
+		if i, ok := index[key]; ok {
+			changes[i].After = []map[string]interface{}{row}
+			continue
+		}
+		changes = append(changes, AuditChange{EntityID: key, After: []map[string]interface{}{row}})
+	}
+
+	out := changes[:0]
+	for _, ch := range changes {
+		if len(ch.Before) == 1 && len(ch.After) == 1 && diffJSON(ch.Before[0], ch.After[0]) == nil {
+			continue
+		}
+		out = append(out, ch)
+	}
+	return out
+}
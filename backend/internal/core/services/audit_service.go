@@ -0,0 +1,246 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/platform/logger"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+)
+
+const (
+	auditDefaultLimit  = 100
+	auditMaxLimit      = 1000
+	auditExportLimit   = 10000
+	auditSnapshotLimit = 200
+	auditRedacted      = "[REDACTED]"
+)
+
+// Columns never worth diffing: they change on every write
+var auditIgnoredColumns = map[string]bool{"updated_at": true}
+
+// AuditChange is the state of one entity around a write.
+// Before/After hold the entity's rows (empty when it did not exist).
+type AuditChange struct {
+	EntityID string
+	Before   []map[string]interface{}
+	After    []map[string]interface{}
+}
+
+// AuditService records and queries the append-only audit log
+type AuditService struct {
+	repo domain.AuditRepository
+}
+
+func NewAuditService(repo domain.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record writes one log per change, copying actor and request fields from base.
+// A call without changes (e.g. a Lark push) is still logged once with its request body.
+func (s *AuditService) Record(base domain.AuditLog, changes []AuditChange, requestBody []byte) {
+	if len(changes) == 0 {
+		changes = []AuditChange{{}}
+	}
+	if err := s.repo.Create(auditLogs(base, changes, requestBody)); err != nil {
+		logger.Get().Error("Failed to write audit log",
+			zap.String("route", base.Route), zap.String("entity_type", base.EntityType), zap.Error(err))
+	}
+}
+
+func auditLogs(base domain.AuditLog, changes []AuditChange, requestBody []byte) []domain.AuditLog {
+	if body := redactedJSON(requestBody); body != nil {
+		base.RequestBody = body
+	}
+	logs := make([]domain.AuditLog, 0, len(changes))
+	for _, ch := range changes {
+		entry := base
+		entry.ID = uuid.New()
+		if ch.EntityID != "" {
+			entry.EntityID = ch.EntityID
+		}
+		entry.Before = snapshotJSON(ch.Before)
+		entry.After = snapshotJSON(ch.After)
+		if len(ch.Before) == 1 && len(ch.After) == 1 {
+			entry.Changes = diffJSON(ch.Before[0], ch.After[0])
+		}
+		logs = append(logs, entry)
+	}
+	return logs
+}
+
+// List returns matching logs newest first and the total count.
+// Limit defaults to 100 and is capped at 1000.
+func (s *AuditService) List(filter domain.AuditFilter) ([]domain.AuditLog, int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = auditDefaultLimit
+	}
+	if filter.Limit > auditMaxLimit {
+		filter.Limit = auditMaxLimit
+	}
+	return s.repo.Find(filter)
+}
+
+// ExportCSV writes up to 10000 matching logs as CSV
+func (s *AuditService) ExportCSV(w io.Writer, filter domain.AuditFilter) error {
+	filter.Limit = auditExportLimit
+	filter.Offset = 0
+	logs, _, err := s.repo.Find(filter)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"created_at", "request_id", "actor_type", "actor_id", "actor_role", "ip_address", "method", "route", "action", "entity_type", "entity_id", "status_code", "changes"})
+	for _, l := range logs {
+		actor := ""
+		if l.ActorID != nil {
+			actor = l.ActorID.String()
+		}
+		cw.Write([]string{
+			l.CreatedAt.Format(time.RFC3339),
+			l.RequestID.String(),
+			l.ActorType,
+			actor,
+			l.ActorRole,
+			l.IPAddress,
+			l.Method,
+			l.Route,
+			l.Action,
+			l.EntityType,
+			l.EntityID,
+			fmt.Sprint(l.StatusCode),
+			string(l.Changes),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// snapshotJSON stores a single row as an object and several rows as an array
+func snapshotJSON(rows []map[string]interface{}) datatypes.JSON {
+	if len(rows) == 0 {
+		return nil
+	}
+	var v interface{} = normalizeRows(rows)
+	if len(rows) == 1 {
+		v = normalizeRow(rows[0])
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return datatypes.JSON(raw)
+}
+
+// diffJSON returns {"column": {"old": ..., "new": ...}} for every changed column
+func diffJSON(before, after map[string]interface{}) datatypes.JSON {
+	b, a := normalizeRow(before), normalizeRow(after)
+	changes := map[string]map[string]interface{}{}
+	for k, nv := range a {
+		if auditIgnoredColumns[k] {
+			continue
+		}
+		if ov, ok := b[k]; !ok || !sameJSON(ov, nv) {
+			changes[k] = map[string]interface{}{"old": b[k], "new": nv}
+		}
+	}
+	for k, ov := range b {
+		if _, ok := a[k]; !ok && !auditIgnoredColumns[k] {
+			changes[k] = map[string]interface{}{"old": ov, "new": nil}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return nil
+	}
+	return datatypes.JSON(raw)
+}
+
+func normalizeRows(rows []map[string]interface{}) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(rows))
+	for i, r := range rows {
+		out[i] = normalizeRow(r)
+	}
+	return out
+}
+
+// normalizeRow redacts secrets and turns raw jsonb/bytea values into JSON-friendly ones
+func normalizeRow(row map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		if isSecretKey(k) {
+			out[k] = auditRedacted
+			continue
+		}
+		if b, ok := v.([]byte); ok {
+			if json.Valid(b) {
+				v = json.RawMessage(b)
+			} else {
+				v = string(b)
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func sameJSON(a, b interface{}) bool {
+	ra, errA := json.Marshal(a)
+	rb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(ra) == string(rb)
+}
+
+// redactedJSON returns the request body with secret fields masked, or nil if it is not JSON
+func redactedJSON(body []byte) datatypes.JSON {
+	if len(body) == 0 || !json.Valid(body) {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil
+	}
+	raw, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return nil
+	}
+	return datatypes.JSON(raw)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if isSecretKey(k) {
+				t[k] = auditRedacted
+			} else {
+				t[k] = redactValue(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = redactValue(child)
+		}
+	}
+	return v
+}
+
+func isSecretKey(key string) bool {
+	k := strings.ToLower(key)
+	return strings.Contains(k, "password") || strings.Contains(k, "token") ||
+		strings.Contains(k, "secret") || strings.HasSuffix(k, "_hash")
+}
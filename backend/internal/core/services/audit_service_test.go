@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+// MockAuditRepository implements domain.AuditRepository in memory
+type MockAuditRepository struct {
+	Logs []domain.AuditLog
+}
+
+func (m *MockAuditRepository) WithTx(tx *gorm.DB) domain.AuditRepository {
+	return m
+}
+
+func (m *MockAuditRepository) Create(logs []domain.AuditLog) error {
+	m.Logs = append(m.Logs, logs...)
+	return nil
+}
+func (m *MockAuditRepository) Find(filter domain.AuditFilter) ([]domain.AuditLog, int64, error) {
+	var out []domain.AuditLog
+	for _, l := range m.Logs {
+		if filter.EntityType != "" && l.EntityType != filter.EntityType {
+			continue
+		}
+		if filter.EntityID != "" && l.EntityID != filter.EntityID {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out, int64(len(out)), nil
+}
+func TestAuditRecordDiffsChangedColumns(t *testing.T) {
+	repo := &MockAuditRepository{}
+	svc := NewAuditService(repo)
+	actor := uuid.New()
+
+	before := []map[string]interface{}{{"id": "c1", "image_count": 3, "name": "Panel", "updated_at": "t1", "password_hash": "old"}}
+	after := []map[string]interface{}{{"id": "c1", "image_count": 5, "name": "Panel", "updated_at": "t2", "password_hash": "new"}}
+	svc.Record(domain.AuditLog{ActorID: &actor, EntityType: "configs", Action: domain.AuditActionUpdate},
+		[]AuditChange{{EntityID: "c1", Before: before, After: after}}, []byte(`{"image_count":5,"password":"secret"}`))
+
+	if len(repo.Logs) != 1 {
+		t.Fatalf("Expected 1 audit log, got %d", len(repo.Logs))
+	}
+	entry := repo.Logs[0]
+	if entry.EntityID != "c1" || *entry.ActorID != actor {
+		t.Errorf("Expected entity c1 by %s, got %s by %v", actor, entry.EntityID, entry.ActorID)
+	}
+
+	var changes map[string]map[string]interface{}
+	if err := json.Unmarshal(entry.Changes, &changes); err != nil {
+		t.Fatalf("Invalid changes JSON: %v", err)
+	}
+	if len(changes) != 1 || changes["image_count"]["old"] != float64(3) || changes["image_count"]["new"] != float64(5) {
+		t.Errorf("Expected only image_count 3 -> 5, got %v", changes)
+	}
+	if strings.Contains(string(entry.Before), "old") || strings.Contains(string(entry.RequestBody), "secret") {
+		t.Errorf("Expected secrets to be redacted, got before=%s body=%s", entry.Before, entry.RequestBody)
+	}
+}
+
+func TestAuditRecordOneLogPerEntity(t *testing.T) {
+	repo := &MockAuditRepository{}
+	svc := NewAuditService(repo)
+
+	svc.Record(domain.AuditLog{RequestID: uuid.New(), EntityType: "assets", Action: "restore"}, []AuditChange{
+		{EntityID: "a1", Before: []map[string]interface{}{{"id": "a1"}}},
+		{EntityID: "a2", After: []map[string]interface{}{{"id": "a2"}}},
+	}, nil)
+	if len(repo.Logs) != 2 {
+		t.Fatalf("Expected 2 audit logs, got %d", len(repo.Logs))
+	}
+	if repo.Logs[0].RequestID != repo.Logs[1].RequestID || repo.Logs[0].ID == repo.Logs[1].ID {
+		t.Error("Expected distinct logs sharing the request ID")
+	}
+	if repo.Logs[0].After != nil || repo.Logs[1].Before != nil {
+		t.Error("Expected missing rows to leave before/after empty")
+	}
+
+	// A call without entities (e.g. a Lark push) is still logged once
+	svc.Record(domain.AuditLog{EntityType: "lark", Action: "push_report"}, nil, []byte(`{"chat_id":"x"}`))
+	if len(repo.Logs) != 3 || string(repo.Logs[2].RequestBody) != `{"chat_id":"x"}` {
+		t.Errorf("Expected a call-only log with its body, got %+v", repo.Logs)
+	}
+}
+
+// auditedPanel is a throwaway table for the callback tests
+type auditedPanel struct {
+	ID         string `gorm:"primaryKey"`
+	Name       string
+	ImageCount int
+}
+
+func TestAuditCallbacksLogEveryWrite(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:audit_callbacks?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test memory db: %v", err)
+	}
+	db.AutoMigrate(&auditedPanel{})
+	repo := &MockAuditRepository{}
+	svc := NewAuditService(repo)
+	if err := svc.RegisterCallbacks(db); err != nil {
+		t.Fatalf("RegisterCallbacks failed: %v", err)
+	}
+
+	// A write without an actor in its context (e.g. a scheduler) is logged as the system
+	db.Create(&auditedPanel{ID: "p1", Name: "Panel", ImageCount: 3})
+	if len(repo.Logs) != 1 || repo.Logs[0].Action != domain.AuditActionCreate || repo.Logs[0].EntityID != "p1" ||
+		repo.Logs[0].ActorType != domain.AuditActorSystem {
+		t.Fatalf("Expected a system create log for p1, got %+v", repo.Logs)
+	}
+
+	// A write run with the request context is attributed to its caller
+	actor := uuid.New()
+	ctx := domain.WithAuditActor(context.Background(), domain.AuditActor{RequestID: uuid.New(), Type: domain.PrincipalUser, ID: &actor})
+	db.WithContext(ctx).Model(&auditedPanel{}).Where("name = ?", "Panel").Update("image_count", 5)
+	if len(repo.Logs) != 2 {
+		t.Fatalf("Expected an update log, got %d logs", len(repo.Logs))
+	}
+	update := repo.Logs[1]
+	if update.ActorID == nil || *update.ActorID != actor || update.EntityID != "p1" {
+		t.Errorf("Expected the update of p1 by %s, got %s by %v", actor, update.EntityID, update.ActorID)
+	}
+	var changes map[string]map[string]interface{}
+	if err := json.Unmarshal(update.Changes, &changes); err != nil || len(changes) != 1 || changes["image_count"]["new"] != float64(5) {
+		t.Errorf("Expected only image_count -> 5, got %s", update.Changes)
+	}
+
+	// A write that changes nothing is not logged; a delete keeps the row it removed
+	db.Model(&auditedPanel{ID: "p1"}).Update("image_count", 5)
+	db.Delete(&auditedPanel{ID: "p1"})
+	if len(repo.Logs) != 3 || repo.Logs[2].Action != domain.AuditActionDelete || repo.Logs[2].Before == nil || repo.Logs[2].After != nil {
+		t.Errorf("Expected one delete log with the removed row, got %+v", repo.Logs[2:])
+	}
+}
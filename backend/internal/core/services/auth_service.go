@@ -7,18 +7,93 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/config"
 	"github.com/phuc/cmms-backend/internal/domain"
 	"github.com/phuc/cmms-backend/internal/platform/logger"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Upper bound of the progressive login lockout
+const maxLoginLockout = 24 * time.Hour
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// AccountLockedError is returned by Login while the account is locked after repeated failures
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return "account is locked until " + e.Until.Format(time.RFC3339)
+}
+
+var ErrMFAChallengeInvalid = errors.New("two-factor challenge is invalid or has expired")
+
+// MFARequiredError is returned by Login when the password is correct but a second
+// factor is needed. The challenge is exchanged for tokens by VerifyMFA; with
+// Enroll set the user has no authenticator yet and must enrol first.
+type MFARequiredError struct {
+	Challenge string
+	ExpiresIn int64
+	Enroll    bool
+}
+
+func (e *MFARequiredError) Error() string {
+	return "two-factor verification required"
+}
+
 type AuthService struct {
-	userRepo domain.UserRepository
+	userRepo    domain.UserRepository
+	permRepo    domain.PermissionRepository
+	sessions    *SessionService
+	accessTTL   time.Duration
+	maxAttempts int
+	lockout     time.Duration
+	policy      PasswordPolicy
+	mfa         *MFAService
+	mfaTTL      time.Duration
+	now         func() time.Time
 }
 
-func NewAuthService(userRepo domain.UserRepository) *AuthService {
-	return &AuthService{userRepo: userRepo}
+func NewAuthService(userRepo domain.UserRepository, permRepo domain.PermissionRepository, sessions *SessionService, mfa *MFAService, cfg config.AuthConfig) *AuthService {
+	ttl := cfg.AccessTokenTTL
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	maxAttempts := cfg.LoginMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	lockout := cfg.LoginLockout
+	if lockout <= 0 {
+		lockout = 5 * time.Minute
+	}
+	mfaTTL := cfg.MFAChallengeTTL
+	if mfaTTL <= 0 {
+		mfaTTL = 5 * time.Minute
+	}
+	return &AuthService{
+		userRepo:    userRepo,
+		permRepo:    permRepo,
+		sessions:    sessions,
+		accessTTL:   ttl,
+		maxAttempts: maxAttempts,
+		lockout:     lockout,
+		policy:      NewPasswordPolicy(cfg.Password),
+		mfa:         mfa,
+		mfaTTL:      mfaTTL,
+		now:         time.Now,
+	}
+}
+
+// TokenPair is issued on login and on every refresh.
+// The access token is a short-lived JWT; the refresh token is opaque and single-use.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // Access token lifetime in seconds
+	SessionID    uuid.UUID
 }
 
 func (s *AuthService) Register(email, password, fullName string) error {
@@ -29,6 +104,9 @@ func (s *AuthService) Register(email, password, fullName string) error {
 	if existingUser != nil {
 		return errors.New("email already registered")
 	}
+	if err := s.policy.Validate(password); err != nil {
+		return err
+	}
 
 	// Hash password with bcrypt
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -45,58 +123,264 @@ func (s *AuthService) Register(email, password, fullName string) error {
 	return s.userRepo.Create(newUser)
 }
 
-func (s *AuthService) Login(email, password string) (string, *domain.User, error) {
+func (s *AuthService) Login(email, password string, device domain.DeviceInfo) (*TokenPair, *domain.User, error) {
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 	if user == nil {
-		return "", nil, errors.New("invalid credentials")
+		return nil, nil, ErrInvalidCredentials
+	}
+	if user.LockedUntil != nil && s.now().Before(*user.LockedUntil) {
+		return nil, nil, &AccountLockedError{Until: *user.LockedUntil}
 	}
 
 	// Compare password using bcrypt
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
-		return "", nil, errors.New("invalid credentials")
+		if lockedUntil := s.recordLoginFailure(user); lockedUntil != nil {
+			return nil, nil, &AccountLockedError{Until: *lockedUntil}
+		}
+		return nil, nil, ErrInvalidCredentials
+	}
+	// Second step: enrolled users, and users whose role enforces 2FA, get a challenge instead of tokens.
+	// Failures are only cleared once the code is verified, so codes cannot be guessed between logins.
+	if s.mfa != nil && (user.TOTPEnabled || s.mfa.Required(user)) {
+		challenge, err := s.newMFAChallenge(user.ID, !user.TOTPEnabled)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, user, &MFARequiredError{Challenge: challenge, ExpiresIn: int64(s.mfaTTL.Seconds()), Enroll: !user.TOTPEnabled}
+	}
+	s.clearLoginFailures(user)
+
+	pair, err := s.startSession(user, device)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, user, nil
+}
+
+// BeginMFAEnrollment starts authenticator enrolment for a login challenge issued
+// with Enroll set (role requires 2FA but the user has not enrolled yet).
+func (s *AuthService) BeginMFAEnrollment(challenge string) (*MFAEnrollment, error) {
+	userID, enroll, err := s.parseMFAChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+	if !enroll {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return s.mfa.BeginEnrollment(userID)
+}
+
+// VerifyMFA completes a two-step login. For an enrolment challenge the code
+// confirms the new authenticator and the fresh recovery codes are returned.
+// Wrong codes count towards the same lockout as wrong passwords.
+func (s *AuthService) VerifyMFA(challenge, code string, device domain.DeviceInfo) (*TokenPair, *domain.User, []string, error) {
+	userID, enroll, err := s.parseMFAChallenge(challenge)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, nil, ErrMFAChallengeInvalid
+	}
+	if user.LockedUntil != nil && s.now().Before(*user.LockedUntil) {
+		return nil, nil, nil, &AccountLockedError{Until: *user.LockedUntil}
 	}
 
+	var recoveryCodes []string
+	if enroll {
+		recoveryCodes, err = s.mfa.ConfirmEnrollment(user.ID, code)
+	} else {
+		err = s.mfa.Verify(user, code)
+	}
+	if errors.Is(err, ErrMFACodeInvalid) {
+		if lockedUntil := s.recordLoginFailure(user); lockedUntil != nil {
+			return nil, nil, nil, &AccountLockedError{Until: *lockedUntil}
+		}
+		return nil, nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	s.clearLoginFailures(user)
+	if enroll {
+		user.TOTPEnabled = true
+	}
+
+	pair, err := s.startSession(user, device)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pair, user, recoveryCodes, nil
+}
+
+// startSession opens a session and issues the first token pair for an authenticated user
+func (s *AuthService) startSession(user *domain.User, device domain.DeviceInfo) (*TokenPair, error) {
 	// Populate RoleName from relationship
 	if user.RoleModel != nil {
 		user.RoleName = domain.UserRole(user.RoleModel.Name)
 	}
 
+	session, refreshToken, err := s.sessions.Start(user.ID, device)
+	if err != nil {
+		return nil, err
+	}
+
 	// Mark user as online
 	if err := s.userRepo.UpdateStatus(user.ID, 1); err != nil {
 		logger.Get().Warn("Failed to update user status on login", zap.Error(err))
 	}
 
-	// Generate JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID.String(),
-		"role":    user.RoleName,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	return s.issueTokens(user, session.ID, refreshToken)
+}
 
-	secret := getJWTSecret()
+// newMFAChallenge signs a short-lived token naming the user who passed the password step.
+// It has no session, so AuthMiddleware never accepts it as an access token.
+func (s *AuthService) newMFAChallenge(userID uuid.UUID, enroll bool) (string, error) {
+	now := s.now()
+	claims := jwt.MapClaims{
+		"sub":    userID.String(),
+		"typ":    "mfa",
+		"enroll": enroll,
+		"iat":    now.Unix(),
+		"exp":    now.Add(s.mfaTTL).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(getJWTSecret()))
+}
 
-	tokenString, err := token.SignedString([]byte(secret))
+func (s *AuthService) parseMFAChallenge(challenge string) (uuid.UUID, bool, error) {
+	if s.mfa == nil {
+		return uuid.Nil, false, ErrMFAChallengeInvalid
+	}
+	token, err := jwt.Parse(challenge, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(getJWTSecret()), nil
+	}, jwt.WithTimeFunc(s.now))
+	if err != nil || !token.Valid {
+		return uuid.Nil, false, ErrMFAChallengeInvalid
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	if typ, _ := claims["typ"].(string); typ != "mfa" {
+		return uuid.Nil, false, ErrMFAChallengeInvalid
+	}
+	sub, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(sub)
 	if err != nil {
-		return "", nil, err
+		return uuid.Nil, false, ErrMFAChallengeInvalid
 	}
+	enroll, _ := claims["enroll"].(bool)
+	return userID, enroll, nil
+}
 
-	return tokenString, user, nil
+func (s *AuthService) clearLoginFailures(user *domain.User) {
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.UpdateLoginState(user.ID, 0, nil); err != nil {
+			logger.Get().Warn("Failed to reset login failures", zap.Error(err))
+		}
+	}
 }
 
-// Logout marks the user as offline (status_user = 0)
-func (s *AuthService) Logout(userID string) error {
+// recordLoginFailure counts a failed login and returns the lockout deadline once
+// the account is locked. The lockout doubles with every failure past the limit.
+func (s *AuthService) recordLoginFailure(user *domain.User) *time.Time {
+	failures := user.FailedLoginCount + 1
+	var lockedUntil *time.Time
+	if failures >= s.maxAttempts {
+		d := s.lockout
+		for i := s.maxAttempts; i < failures && d < maxLoginLockout; i++ {
+			d *= 2
+		}
+		if d > maxLoginLockout {
+			d = maxLoginLockout
+		}
+		until := s.now().Add(d)
+		lockedUntil = &until
+	}
+	if err := s.userRepo.UpdateLoginState(user.ID, failures, lockedUntil); err != nil {
+		logger.Get().Warn("Failed to record login failure", zap.String("user_id", user.ID.String()), zap.Error(err))
+	}
+	return lockedUntil
+}
+
+// Refresh rotates the refresh token and issues a new access token for the same session.
+// The user's current role is reloaded, so role changes apply on the next refresh.
+func (s *AuthService) Refresh(refreshToken string, device domain.DeviceInfo) (*TokenPair, error) {
+	session, newRefreshToken, err := s.sessions.Rotate(refreshToken, device)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		_ = s.sessions.Revoke(session.UserID, session.ID, domain.RevokeReasonUserDeleted)
+		return nil, ErrSessionInvalid
+	}
+	if user.RoleModel != nil {
+		user.RoleName = domain.UserRole(user.RoleModel.Name)
+	}
+
+	return s.issueTokens(user, session.ID, newRefreshToken)
+}
+
+// Logout revokes the caller's session and marks the user as offline (status_user = 0)
+func (s *AuthService) Logout(userID, sessionID string) error {
 	parsedID, err := uuid.Parse(userID)
 	if err != nil {
 		return err
 	}
+	if sid, err := uuid.Parse(sessionID); err == nil {
+		if err := s.sessions.Revoke(parsedID, sid, domain.RevokeReasonLogout); err != nil {
+			logger.Get().Warn("Failed to revoke session on logout", zap.Error(err))
+		}
+	}
 	return s.userRepo.UpdateStatus(parsedID, 0)
 }
 
-// VerifyToken validates the JWT token strings
+func (s *AuthService) issueTokens(user *domain.User, sessionID uuid.UUID, refreshToken string) (*TokenPair, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id": user.ID.String(),
+		"role":    user.RoleName,
+		"sid":     sessionID.String(),
+		"iat":     now.Unix(),
+		"exp":     now.Add(s.accessTTL).Unix(),
+	}
+	if user.MustChangePassword {
+		// Cleared on the next refresh after the password is changed
+		claims["pwd_change"] = true
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	secret := getJWTSecret()
+
+	tokenString, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+		SessionID:    sessionID,
+	}, nil
+}
+
+// VerifyToken validates the JWT token strings.
+// The token's session ("sid") must still be active, so logout and revocation take
+// effect immediately. The returned claims carry "permissions" ([]string) resolved
+// from the role's current grants, so permission changes apply without re-login.
 func (s *AuthService) VerifyToken(tokenString string) (jwt.MapClaims, error) {
 	secret := getJWTSecret()
 
@@ -112,12 +396,43 @@ func (s *AuthService) VerifyToken(tokenString string) (jwt.MapClaims, error) {
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		if err := s.checkSession(claims); err != nil {
+			return nil, err
+		}
+		role, _ := claims["role"].(string)
+		perms, err := resolveRolePermissions(s.permRepo, role)
+		if err != nil {
+			return nil, err
+		}
+		claims["permissions"] = perms
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
 
+// checkSession rejects tokens whose session was revoked, expired or never existed
+func (s *AuthService) checkSession(claims jwt.MapClaims) error {
+	rawSID, _ := claims["sid"].(string)
+	rawUID, _ := claims["user_id"].(string)
+	sid, err := uuid.Parse(rawSID)
+	if err != nil {
+		return errors.New("token has no session")
+	}
+	uid, err := uuid.Parse(rawUID)
+	if err != nil {
+		return errors.New("invalid token")
+	}
+	active, err := s.sessions.IsActive(sid, uid)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return ErrSessionInvalid
+	}
+	return nil
+}
+
 // getJWTSecret retrieves JWT_SECRET from environment.
 // Panics if not set — prevents running with an insecure default.
 func getJWTSecret() string {
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+const (
+	// calendarDefaultSpan is shown when a calendar query gives no end
+	calendarDefaultSpan = 31 * 24 * time.Hour
+	// calendarMaxSpan bounds a calendar query
+	calendarMaxSpan = 366 * 24 * time.Hour
+	// feedPast and feedFuture bound the assigns published in a calendar feed
+	feedPast   = 90 * 24 * time.Hour
+	feedFuture = 366 * 24 * time.Hour
+)
+
+// AvailabilityService keeps engineers' time away (leave, training, ...) and
+// answers when they are free: the calendar of a user or a team, the conflicts
+// a new or moved assign would cause, and an iCalendar feed of their assigns.
+type AvailabilityService struct {
+	repo     domain.AvailabilityRepository
+	userRepo domain.UserRepository
+	now      func() time.Time
+}
+
+func NewAvailabilityService(repo domain.AvailabilityRepository, userRepo domain.UserRepository) *AvailabilityService {
+	return &AvailabilityService{repo: repo, userRepo: userRepo, now: time.Now}
+}
+
+// UnavailabilityInput records time away. UserID defaults to the caller.
+type UnavailabilityInput struct {
+	UserID    *uuid.UUID                `json:"id_user"`
+	Kind      domain.UnavailabilityKind `json:"kind"`
+	StartTime time.Time                 `json:"start_time" binding:"required"`
+	EndTime   time.Time                 `json:"end_time" binding:"required"`
+	Note      string                    `json:"note"`
+}
+
+// ScheduleConflictError refuses to schedule an assign over time its users are
+// already taken: by other assigns, or away
+type ScheduleConflictError struct {
+	Conflicts []domain.UserSchedule
+}
+
+func (e *ScheduleConflictError) Error() string {
+	parts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		name := c.UserID.String()
+		if c.User != nil && c.User.Name != "" {
+			name = c.User.Name
+		}
+		parts[i] = fmt.Sprintf("%s (%d assigns, %d absences)", name, len(c.Assigns), len(c.Unavailable))
+	}
+	return "Schedule conflicts for: " + strings.Join(parts, ", ")
+}
+
+func availabilityInvalid(msg string) error {
+	return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+}
+
+func availabilityForbidden() error {
+	return apperrors.NewAppError(apperrors.ErrForbidden.Code, "Only managers may manage someone else's calendar", http.StatusForbidden)
+}
+
+func calendarForbidden() error {
+	return apperrors.NewAppError(apperrors.ErrForbidden.Code, "Only managers may read someone else's calendar", http.StatusForbidden)
+}
+
+// CalendarRange parses the from/to of a calendar query, as RFC 3339 times or
+// YYYY-MM-DD dates (a date "to" includes that whole day). from defaults to
+// the start of today, to to a month after from.
+func (s *AvailabilityService) CalendarRange(from, to string) (time.Time, time.Time, error) {
+	now := s.now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if from != "" {
+		t, _, err := parseCalendarTime(from)
+		if err != nil {
+			return time.Time{}, time.Time{}, availabilityInvalid("from must be a date (YYYY-MM-DD) or RFC 3339 time")
+		}
+		start = t
+	}
+	end := start.Add(calendarDefaultSpan)
+	if to != "" {
+		t, dateOnly, err := parseCalendarTime(to)
+		if err != nil {
+			return time.Time{}, time.Time{}, availabilityInvalid("to must be a date (YYYY-MM-DD) or RFC 3339 time")
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		end = t
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, availabilityInvalid("to must be after from")
+	}
+	if end.Sub(start) > calendarMaxSpan {
+		return time.Time{}, time.Time{}, availabilityInvalid("A calendar query may span at most 366 days")
+	}
+	return start, end, nil
+}
+
+func parseCalendarTime(raw string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	return t, true, err
+}
+
+// ---- Time away ----
+
+// List returns a user's time away overlapping [from, to); only managers may
+// list someone else's
+func (s *AvailabilityService) List(userID uuid.UUID, from, to time.Time, actor *uuid.UUID, canManage bool) ([]domain.Unavailability, error) {
+	if actor == nil || (userID != *actor && !canManage) {
+		return nil, calendarForbidden()
+	}
+	return s.repo.FindUnavailable([]uuid.UUID{userID}, from, to)
+}
+
+// Create records time away, for the caller or - for managers - anyone
+func (s *AvailabilityService) Create(input UnavailabilityInput, actor *uuid.UUID, canManage bool) (*domain.Unavailability, error) {
+	if actor == nil {
+		return nil, availabilityForbidden()
+	}
+	userID := *actor
+	if input.UserID != nil {
+		userID = *input.UserID
+	}
+	if userID != *actor && !canManage {
+		return nil, availabilityForbidden()
+	}
+	if user, err := s.userRepo.FindByID(userID); err != nil || user == nil {
+		return nil, availabilityInvalid("User not found")
+	}
+	entry := &domain.Unavailability{ID: uuid.New(), UserID: userID, CreatedByID: actor}
+	if err := applyUnavailability(entry, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Update changes the kind, period or note of time away; who it belongs to stays
+func (s *AvailabilityService) Update(id uuid.UUID, input UnavailabilityInput, actor *uuid.UUID, canManage bool) (*domain.Unavailability, error) {
+	entry, err := s.editable(id, actor, canManage)
+	if err != nil {
+		return nil, err
+	}
+	if input.UserID != nil && *input.UserID != entry.UserID {
+		return nil, availabilityInvalid("id_user cannot be changed")
+	}
+	if err := applyUnavailability(entry, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *AvailabilityService) Delete(id uuid.UUID, actor *uuid.UUID, canManage bool) error {
+	if _, err := s.editable(id, actor, canManage); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// editable loads an entry the actor may change: their own, or any for managers
+func (s *AvailabilityService) editable(id uuid.UUID, actor *uuid.UUID, canManage bool) (*domain.Unavailability, error) {
+	entry, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	if actor == nil || (entry.UserID != *actor && !canManage) {
+		return nil, availabilityForbidden()
+	}
+	return entry, nil
+}
+
+func applyUnavailability(entry *domain.Unavailability, input UnavailabilityInput) error {
+	kind := input.Kind
+	if kind == "" {
+		kind = domain.UnavailableOther
+	}
+	if !kind.Valid() {
+		return availabilityInvalid("kind must be leave, training or other")
+	}
+	if !input.EndTime.After(input.StartTime) {
+		return availabilityInvalid("end_time must be after start_time")
+	}
+	entry.Kind = kind
+	entry.StartTime = input.StartTime
+	entry.EndTime = input.EndTime
+	entry.Note = strings.TrimSpace(input.Note)
+	return nil
+}
+
+// ---- Calendars & conflicts ----
+
+// Schedules returns, for each user in order, what keeps them busy over [from, to)
+func (s *AvailabilityService) Schedules(userIDs []uuid.UUID, from, to time.Time, excludeAssignID *uuid.UUID) ([]domain.UserSchedule, error) {
+	assigns, err := s.repo.FindAssigns(userIDs, from, to, excludeAssignID)
+	if err != nil {
+		return nil, err
+	}
+	away, err := s.repo.FindUnavailable(userIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	schedules := make([]domain.UserSchedule, len(userIDs))
+	for i, userID := range userIDs {
+		schedule := domain.UserSchedule{UserID: userID, Assigns: []domain.Assign{}, Unavailable: []domain.Unavailability{}}
+		for _, a := range assigns {
+			if containsUUID(a.Users(), userID) {
+				schedule.Assigns = append(schedule.Assigns, a)
+			}
+		}
+		for _, u := range away {
+			if u.UserID == userID {
+				schedule.Unavailable = append(schedule.Unavailable, u)
+			}
+		}
+		schedules[i] = schedule
+	}
+	return schedules, nil
+}
+
+// UserCalendar returns a user's calendar; only managers may read someone else's
+func (s *AvailabilityService) UserCalendar(userID uuid.UUID, from, to time.Time, actor *uuid.UUID, canManage bool) (*domain.UserSchedule, error) {
+	if actor == nil || (userID != *actor && !canManage) {
+		return nil, calendarForbidden()
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	schedules, err := s.Schedules([]uuid.UUID{userID}, from, to, nil)
+	if err != nil {
+		return nil, err
+	}
+	schedules[0].User = user
+	return &schedules[0], nil
+}
+
+// TeamCalendar returns the calendar of every member of a team
+func (s *AvailabilityService) TeamCalendar(teamID uuid.UUID, from, to time.Time) ([]domain.UserSchedule, error) {
+	users, err := s.repo.FindTeamUsers(teamID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]uuid.UUID, len(users))
+	for i, u := range users {
+		userIDs[i] = u.ID
+	}
+	schedules, err := s.Schedules(userIDs, from, to, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range schedules {
+		schedules[i].User = &users[i]
+	}
+	return schedules, nil
+}
+
+// CheckConflicts returns a ScheduleConflictError when any of the users is
+// already taken during [start, end): on another assign than excludeAssignID,
+// or away. An assign without both times is not scheduled and cannot conflict.
+func (s *AvailabilityService) CheckConflicts(userIDs []uuid.UUID, start, end *time.Time, excludeAssignID *uuid.UUID) error {
+	if start == nil || end == nil || !end.After(*start) || len(userIDs) == 0 {
+		return nil
+	}
+	schedules, err := s.Schedules(userIDs, *start, *end, excludeAssignID)
+	if err != nil {
+		return err
+	}
+	var conflicts []domain.UserSchedule
+	for _, schedule := range schedules {
+		if !schedule.Busy() {
+			continue
+		}
+		if user, err := s.userRepo.FindByID(schedule.UserID); err == nil {
+			schedule.User = user
+		}
+		conflicts = append(conflicts, schedule)
+	}
+	if len(conflicts) > 0 {
+		return &ScheduleConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// Feed returns the iCalendar (RFC 5545) feed of a user's assigns, from three
+// months back to a year ahead
+func (s *AvailabilityService) Feed(userID uuid.UUID) ([]byte, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	now := s.now()
+	assigns, err := s.repo.FindAssigns([]uuid.UUID{userID}, now.Add(-feedPast), now.Add(feedFuture), nil)
+	if err != nil {
+		return nil, err
+	}
+	return encodeAssignCalendar(user.Name, assigns, now), nil
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// MockAvailabilityRepository filters in memory the way the SQL does
+type MockAvailabilityRepository struct {
+	Entries []domain.Unavailability
+	Assigns []domain.Assign
+}
+
+func (m *MockAvailabilityRepository) Create(entry *domain.Unavailability) error {
+	m.Entries = append(m.Entries, *entry)
+	return nil
+}
+func (m *MockAvailabilityRepository) FindByID(id uuid.UUID) (*domain.Unavailability, error) {
+	for _, e := range m.Entries {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, nil
+}
+func (m *MockAvailabilityRepository) Update(*domain.Unavailability) error { return nil }
+func (m *MockAvailabilityRepository) Delete(uuid.UUID) error              { return nil }
+func (m *MockAvailabilityRepository) FindUnavailable(userIDs []uuid.UUID, from, to time.Time) ([]domain.Unavailability, error) {
+	var found []domain.Unavailability
+	for _, e := range m.Entries {
+		if containsUUID(userIDs, e.UserID) && e.StartTime.Before(to) && e.EndTime.After(from) {
+			found = append(found, e)
+		}
+	}
+	return found, nil
+}
+func (m *MockAvailabilityRepository) FindAssigns(userIDs []uuid.UUID, from, to time.Time, excludeID *uuid.UUID) ([]domain.Assign, error) {
+	var found []domain.Assign
+	for _, a := range m.Assigns {
+		if excludeID != nil && a.ID == *excludeID || a.StartTime == nil || a.EndTime == nil {
+			continue
+		}
+		shared := false
+		for _, u := range a.Users() {
+			shared = shared || containsUUID(userIDs, u)
+		}
+		if shared && a.StartTime.Before(to) && a.EndTime.After(from) {
+			found = append(found, a)
+		}
+	}
+	return found, nil
+}
+func (m *MockAvailabilityRepository) FindTeamUsers(uuid.UUID) ([]domain.User, error) {
+	return nil, nil
+}
+
+func marchAt(day, hour int) *time.Time {
+	t := time.Date(2026, 3, day, hour, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestCheckConflictsFindsOverlaps(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	existing := domain.Assign{ID: uuid.New(), UserIDs: uuidsJSON([]uuid.UUID{alice}), StartTime: marchAt(10, 8), EndTime: marchAt(12, 17)}
+	repo := &MockAvailabilityRepository{
+		Assigns: []domain.Assign{existing},
+		Entries: []domain.Unavailability{{ID: uuid.New(), UserID: bob, Kind: domain.UnavailableLeave, StartTime: *marchAt(20, 0), EndTime: *marchAt(22, 0)}},
+	}
+	svc := NewAvailabilityService(repo, NewMockUserRepository(&domain.User{ID: alice, Name: "Alice"}, &domain.User{ID: bob, Name: "Bob"}))
+	users := []uuid.UUID{alice, bob}
+
+	err := svc.CheckConflicts(users, marchAt(11, 8), marchAt(11, 17), nil)
+	var conflict *ScheduleConflictError
+	if !errors.As(err, &conflict) || len(conflict.Conflicts) != 1 || conflict.Conflicts[0].UserID != alice ||
+		conflict.Conflicts[0].Assigns[0].ID != existing.ID || conflict.Conflicts[0].User.Name != "Alice" {
+		t.Fatalf("double booking: err = %v", err)
+	}
+	if err := svc.CheckConflicts(users, marchAt(21, 8), marchAt(21, 17), nil); !errors.As(err, &conflict) || conflict.Conflicts[0].UserID != bob ||
+		len(conflict.Conflicts[0].Unavailable) != 1 {
+		t.Errorf("booking over leave: err = %v", err)
+	}
+	// Back to back is not an overlap, nor is the assign being moved itself
+	if err := svc.CheckConflicts(users, marchAt(12, 17), marchAt(13, 17), nil); err != nil {
+		t.Errorf("back to back: err = %v", err)
+	}
+	if err := svc.CheckConflicts(users, marchAt(11, 8), marchAt(11, 17), &existing.ID); err != nil {
+		t.Errorf("moving the assign itself: err = %v", err)
+	}
+	if err := svc.CheckConflicts(users, marchAt(11, 8), nil, nil); err != nil {
+		t.Errorf("unscheduled assign: err = %v", err)
+	}
+
+	if _, err := svc.Create(UnavailabilityInput{UserID: &bob, StartTime: *marchAt(1, 0), EndTime: *marchAt(2, 0)}, &alice, false); appStatus(err) != http.StatusForbidden {
+		t.Errorf("recording someone else's leave: err = %v", err)
+	}
+	if _, err := svc.Create(UnavailabilityInput{Kind: "holiday", StartTime: *marchAt(1, 0), EndTime: *marchAt(2, 0)}, &alice, false); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("unknown kind: err = %v", err)
+	}
+	if _, err := svc.Create(UnavailabilityInput{Kind: domain.UnavailableTraining, StartTime: *marchAt(2, 0), EndTime: *marchAt(1, 0)}, &alice, false); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("reversed period: err = %v", err)
+	}
+}
+
+func TestCalendarFeed(t *testing.T) {
+	alice := uuid.New()
+	note := "Kiểm tra inverter; string 3, 4\nMang đồng hồ đo " + strings.Repeat("x", 80)
+	assign := domain.Assign{
+		ID: uuid.New(), UserIDs: uuidsJSON([]uuid.UUID{alice}), StartTime: marchAt(10, 1), EndTime: marchAt(10, 10),
+		Project: &domain.Project{Name: "Solar Farm Ninh Thuận", Location: "Phan Rang"}, NoteAssign: note,
+	}
+	svc := NewAvailabilityService(&MockAvailabilityRepository{Assigns: []domain.Assign{assign}}, NewMockUserRepository(&domain.User{ID: alice, Name: "Alice"}))
+	svc.now = func() time.Time { return *marchAt(1, 0) }
+
+	feed, err := svc.Feed(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ics := string(feed)
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n", "UID:" + assign.ID.String() + "@cmms\r\n",
+		"DTSTART:20260310T010000Z\r\n", "DTEND:20260310T100000Z\r\n", "SUMMARY:Solar Farm Ninh Thuận\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("feed lacks %q:\n%s", want, ics)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line not folded: %q", line)
+		}
+	}
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	if !strings.Contains(unfolded, `DESCRIPTION:Kiểm tra inverter\; string 3\, 4\nMang đồng hồ đo xxx`) {
+		t.Errorf("description not escaped:\n%s", unfolded)
+	}
+
+	if _, _, err := svc.CalendarRange("2026-03-01", "2027-06-01"); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("over a year: err = %v", err)
+	}
+	if from, to, err := svc.CalendarRange("2026-03-01", "2026-03-07"); err != nil || to.Sub(from) != 7*24*time.Hour {
+		t.Errorf("a week: %v - %v, err = %v", from, to, err)
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// icsTime is the UTC date-time form of RFC 5545
+const icsTime = "20060102T150405Z"
+
+// icsTextEscaper escapes TEXT property values (RFC 5545 §3.3.11)
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// encodeAssignCalendar writes one VEVENT per scheduled assign. Event UIDs are
+// the assign IDs, so calendar clients update moved assigns in place.
+func encodeAssignCalendar(name string, assigns []domain.Assign, stamp time.Time) []byte {
+	var b bytes.Buffer
+	line := func(prop, value string) { writeICSLine(&b, prop+":"+value) }
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//CMMS//Assign calendar//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", icsTextEscaper.Replace("CMMS - "+name))
+	for _, a := range assigns {
+		if a.StartTime == nil || a.EndTime == nil {
+			continue
+		}
+		summary, location := "Assign", ""
+		if a.Project != nil {
+			summary, location = a.Project.Name, a.Project.Location
+		}
+		line("BEGIN", "VEVENT")
+		line("UID", a.ID.String()+"@cmms")
+		line("DTSTAMP", stamp.UTC().Format(icsTime))
+		line("LAST-MODIFIED", a.UpdatedAt.UTC().Format(icsTime))
+		line("DTSTART", a.StartTime.UTC().Format(icsTime))
+		line("DTEND", a.EndTime.UTC().Format(icsTime))
+		line("SUMMARY", icsTextEscaper.Replace(summary))
+		if location != "" {
+			line("LOCATION", icsTextEscaper.Replace(location))
+		}
+		if note := strings.TrimSpace(a.NoteAssign); note != "" {
+			line("DESCRIPTION", icsTextEscaper.Replace(note))
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return b.Bytes()
+}
+
+// writeICSLine writes a content line folded at 75 octets, without splitting a
+// UTF-8 character, each continuation starting with a space
+func writeICSLine(b *bytes.Buffer, content string) {
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		limit = 74 // The leading space counts
+	}
+	b.WriteString(content)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// CertificationService keeps the certification catalog, who holds which, and
+// which ones each work requires
+type CertificationService struct {
+	repo     domain.CertificationRepository
+	userRepo domain.UserRepository
+	workRepo domain.WorkRepository
+}
+
+func NewCertificationService(repo domain.CertificationRepository, userRepo domain.UserRepository, workRepo domain.WorkRepository) *CertificationService {
+	return &CertificationService{repo: repo, userRepo: userRepo, workRepo: workRepo}
+}
+
+// CertificationInput adds a certification to the catalog
+type CertificationInput struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// UserCertificationInput dates a certification held by a user
+type UserCertificationInput struct {
+	IssuedAt  *time.Time `json:"issued_at"`
+	ExpiresAt *time.Time `json:"expires_at"` // nil: does not expire
+}
+
+// WorkRequirementsInput lists the certifications a work requires
+type WorkRequirementsInput struct {
+	CertificationIDs []uuid.UUID `json:"id_certifications"`
+}
+
+func certificationInvalid(msg string) error {
+	return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+}
+
+func (s *CertificationService) List() ([]domain.Certification, error) {
+	return s.repo.FindAll()
+}
+
+func (s *CertificationService) Create(input CertificationInput) (*domain.Certification, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, certificationInvalid("name is required")
+	}
+	existing, err := s.repo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range existing {
+		if strings.EqualFold(c.Name, name) {
+			return nil, apperrors.NewAppError(apperrors.ErrConflict.Code, "A certification with this name already exists", http.StatusConflict)
+		}
+	}
+	cert := &domain.Certification{ID: uuid.New(), Name: name, Description: strings.TrimSpace(input.Description)}
+	if err := s.repo.Create(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+func (s *CertificationService) Delete(id uuid.UUID) error {
+	return s.repo.Delete(id)
+}
+
+// UserCertifications returns the certifications a user holds, expired ones included
+func (s *CertificationService) UserCertifications(userID uuid.UUID) ([]domain.UserCertification, error) {
+	return s.repo.FindUserCertifications([]uuid.UUID{userID})
+}
+
+// Grant records that a user holds a certification, or updates its dates
+func (s *CertificationService) Grant(userID, certificationID uuid.UUID, input UserCertificationInput) (*domain.UserCertification, error) {
+	if user, err := s.userRepo.FindByID(userID); err != nil || user == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	if err := s.checkExist([]uuid.UUID{certificationID}); err != nil {
+		return nil, err
+	}
+	if input.IssuedAt != nil && input.ExpiresAt != nil && !input.ExpiresAt.After(*input.IssuedAt) {
+		return nil, certificationInvalid("expires_at must be after issued_at")
+	}
+	cert := &domain.UserCertification{
+		ID:              uuid.New(),
+		UserID:          userID,
+		CertificationID: certificationID,
+		IssuedAt:        input.IssuedAt,
+		ExpiresAt:       input.ExpiresAt,
+	}
+	if err := s.repo.SaveUserCertification(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+func (s *CertificationService) Revoke(userID, certificationID uuid.UUID) error {
+	return s.repo.RemoveUserCertification(userID, certificationID)
+}
+
+// WorkRequirements returns the certifications a work requires
+func (s *CertificationService) WorkRequirements(workID uuid.UUID) ([]domain.WorkCertification, error) {
+	return s.repo.FindWorkRequirements([]uuid.UUID{workID})
+}
+
+// SetWorkRequirements replaces the certifications a work requires
+func (s *CertificationService) SetWorkRequirements(workID uuid.UUID, input WorkRequirementsInput) ([]domain.WorkCertification, error) {
+	if work, err := s.workRepo.FindByID(workID); err != nil || work == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	var ids []uuid.UUID
+	for _, id := range input.CertificationIDs {
+		if !containsUUID(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if err := s.checkExist(ids); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetWorkRequirements(workID, ids); err != nil {
+		return nil, err
+	}
+	return s.repo.FindWorkRequirements([]uuid.UUID{workID})
+}
+
+// checkExist refuses unknown certification IDs
+func (s *CertificationService) checkExist(ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	found, err := s.repo.FindByIDs(ids)
+	if err != nil {
+		return err
+	}
+	if len(found) != len(ids) {
+		return certificationInvalid("Unknown certification")
+	}
+	return nil
+}
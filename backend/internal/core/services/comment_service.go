@@ -0,0 +1,382 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/infrastructure/storage"
+	"gorm.io/datatypes"
+)
+
+const (
+	maxCommentLength      = 5000     // Runes
+	maxCommentAttachments = 5        // Files per comment
+	maxCommentFileSize    = 20 << 20 // Bytes per file
+)
+
+// commentFileTypes are the attachments accepted besides what mime knows
+var commentFileTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".mp4":  "video/mp4",
+	".webm": "video/webm",
+	".pdf":  "application/pdf",
+}
+
+// CommentService runs the discussion threads of assigns and their tasks:
+// comments with attachments, @mentions, edit/delete history and unread counts.
+type CommentService struct {
+	repo       domain.CommentRepository
+	assignRepo domain.AssignRepository
+	detailRepo domain.DetailAssignRepository
+	userRepo   domain.UserRepository
+	minio      *storage.MinioClient
+	notify     NotifyFunc
+	broadcast  BroadcastFunc
+	now        func() time.Time
+}
+
+func NewCommentService(repo domain.CommentRepository, assignRepo domain.AssignRepository, detailRepo domain.DetailAssignRepository, userRepo domain.UserRepository, minio *storage.MinioClient, notify NotifyFunc, broadcast BroadcastFunc) *CommentService {
+	return &CommentService{
+		repo:       repo,
+		assignRepo: assignRepo,
+		detailRepo: detailRepo,
+		userRepo:   userRepo,
+		minio:      minio,
+		notify:     notify,
+		broadcast:  broadcast,
+		now:        time.Now,
+	}
+}
+
+// CommentThread names a thread: a task's when DetailID is set, else the
+// assign's own
+type CommentThread struct {
+	AssignID uuid.UUID
+	DetailID *uuid.UUID
+}
+
+// CommentInput is the text of a comment and the users it mentions. Mentions
+// are sent as user IDs, the client rendering "@name" in the body.
+type CommentInput struct {
+	Body       string      `json:"body"`
+	MentionIDs []uuid.UUID `json:"id_mentions"`
+}
+
+// CommentFile is an attachment uploaded with a comment
+type CommentFile struct {
+	Name   string
+	Size   int64
+	Reader io.Reader
+}
+
+// ErrCommenterRequired refuses comment actions by callers that are not users
+// (e.g. service accounts)
+var ErrCommenterRequired = apperrors.NewAppError(apperrors.ErrForbidden.Code, "Comments need a signed-in user", http.StatusForbidden)
+
+func commentInvalid(msg string) error {
+	return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+}
+
+// resolve checks the thread exists and is visible in scope, filling in the
+// assign of a task thread
+func (s *CommentService) resolve(thread CommentThread, scope *domain.VisibilityScope) (CommentThread, error) {
+	if thread.DetailID != nil {
+		detail, err := s.detailRepo.FindByID(*thread.DetailID)
+		if err != nil || detail == nil {
+			return thread, apperrors.NewAppError(apperrors.ErrNotFound.Code, "Task not found", http.StatusNotFound)
+		}
+		thread.AssignID = detail.AssignID
+	}
+	if assign, err := s.assignRepo.FindByID(thread.AssignID, scope); err != nil || assign == nil {
+		return thread, apperrors.NewAppError(apperrors.ErrNotFound.Code, "Assign not found", http.StatusNotFound)
+	}
+	return thread, nil
+}
+
+// Thread returns the comments of a thread, oldest first
+func (s *CommentService) Thread(thread CommentThread, scope *domain.VisibilityScope) ([]domain.Comment, error) {
+	thread, err := s.resolve(thread, scope)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindThread(thread.AssignID, thread.DetailID)
+}
+
+// Post adds a comment to a thread, uploading its attachments to MinIO and
+// notifying the users it mentions
+func (s *CommentService) Post(thread CommentThread, input CommentInput, files []CommentFile, author *uuid.UUID, scope *domain.VisibilityScope) (*domain.Comment, error) {
+	if author == nil {
+		return nil, ErrCommenterRequired
+	}
+	thread, err := s.resolve(thread, scope)
+	if err != nil {
+		return nil, err
+	}
+	body := strings.TrimSpace(input.Body)
+	if body == "" && len(files) == 0 {
+		return nil, commentInvalid("A comment needs text or an attachment")
+	}
+	if err := s.checkBody(body); err != nil {
+		return nil, err
+	}
+	mentions, err := s.mentions(input.MentionIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > maxCommentAttachments {
+		return nil, commentInvalid(fmt.Sprintf("A comment takes at most %d attachments", maxCommentAttachments))
+	}
+
+	comment := &domain.Comment{
+		ID:             uuid.New(),
+		AssignID:       thread.AssignID,
+		DetailAssignID: thread.DetailID,
+		AuthorID:       *author,
+		Body:           body,
+		Mentions:       uuidsJSON(mentions),
+	}
+	attachments, err := s.upload(comment, files)
+	if err != nil {
+		return nil, err
+	}
+	attachmentsJSON, _ := json.Marshal(attachments)
+	comment.Attachments = datatypes.JSON(attachmentsJSON)
+	if err := s.repo.Create(comment); err != nil {
+		return nil, err
+	}
+	s.announce(comment, mentions)
+	return comment, nil
+}
+
+// Edit changes the text and mentions of one's own comment; attachments stay.
+// The former content is kept as a revision, and only newly mentioned users
+// are notified.
+func (s *CommentService) Edit(id uuid.UUID, input CommentInput, actor *uuid.UUID) (*domain.Comment, error) {
+	if actor == nil {
+		return nil, ErrCommenterRequired
+	}
+	comment, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != *actor {
+		return nil, apperrors.NewAppError(apperrors.ErrForbidden.Code, "Only the author may edit a comment", http.StatusForbidden)
+	}
+	body := strings.TrimSpace(input.Body)
+	if body == "" && len(comment.Files()) == 0 {
+		return nil, commentInvalid("A comment needs text or an attachment")
+	}
+	if err := s.checkBody(body); err != nil {
+		return nil, err
+	}
+	mentions, err := s.mentions(input.MentionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := comment.MentionedUsers()
+	revision := s.revision(comment, domain.CommentEdited, actor)
+	now := s.now()
+	comment.Body = body
+	comment.Mentions = uuidsJSON(mentions)
+	comment.EditedAt = &now
+	if err := s.repo.Revise(comment, revision); err != nil {
+		return nil, err
+	}
+	var added []uuid.UUID
+	for _, uid := range mentions {
+		if !containsUUID(previous, uid) {
+			added = append(added, uid)
+		}
+	}
+	s.announce(comment, added)
+	return comment, nil
+}
+
+// Delete empties a comment, keeping its content as a revision. Authors may
+// delete their own comments, managers anyone's.
+func (s *CommentService) Delete(id uuid.UUID, actor *uuid.UUID, canManage bool) (*domain.Comment, error) {
+	if actor == nil {
+		return nil, ErrCommenterRequired
+	}
+	comment, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != *actor && !canManage {
+		return nil, apperrors.NewAppError(apperrors.ErrForbidden.Code, "Only the author or a manager may delete a comment", http.StatusForbidden)
+	}
+	revision := s.revision(comment, domain.CommentDeleted, actor)
+	now := s.now()
+	comment.Body = ""
+	comment.Attachments = datatypes.JSON(`[]`)
+	comment.Mentions = datatypes.JSON(`[]`)
+	comment.DeletedAt = &now
+	if err := s.repo.Revise(comment, revision); err != nil {
+		return nil, err
+	}
+	s.announce(comment, nil)
+	return comment, nil
+}
+
+// History returns the former contents of a comment, oldest first, to those
+// who may see its thread
+func (s *CommentService) History(id uuid.UUID, scope *domain.VisibilityScope) ([]domain.CommentRevision, error) {
+	comment, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound.Code, "Comment not found", http.StatusNotFound)
+	}
+	if _, err := s.resolve(CommentThread{AssignID: comment.AssignID, DetailID: comment.DetailAssignID}, scope); err != nil {
+		return nil, err
+	}
+	return s.repo.FindRevisions(id)
+}
+
+// MarkRead records that the user has read the thread up to now
+func (s *CommentService) MarkRead(thread CommentThread, userID *uuid.UUID, scope *domain.VisibilityScope) error {
+	if userID == nil {
+		return ErrCommenterRequired
+	}
+	thread, err := s.resolve(thread, scope)
+	if err != nil {
+		return err
+	}
+	threadID := thread.AssignID
+	if thread.DetailID != nil {
+		threadID = *thread.DetailID
+	}
+	return s.repo.MarkRead(*userID, threadID, s.now())
+}
+
+// Unread counts the user's unread comments per thread, optionally within one assign
+func (s *CommentService) Unread(userID *uuid.UUID, assignID *uuid.UUID) ([]domain.UnreadThread, error) {
+	if userID == nil {
+		return nil, ErrCommenterRequired
+	}
+	threads, err := s.repo.CountUnread(*userID, assignID)
+	if threads == nil {
+		threads = []domain.UnreadThread{}
+	}
+	return threads, err
+}
+
+// find loads a comment that can still be changed
+func (s *CommentService) find(id uuid.UUID) (*domain.Comment, error) {
+	comment, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound.Code, "Comment not found", http.StatusNotFound)
+	}
+	if comment.DeletedAt != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrInvalidState.Code, "The comment was deleted", http.StatusConflict)
+	}
+	return comment, nil
+}
+
+func (s *CommentService) checkBody(body string) error {
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return commentInvalid(fmt.Sprintf("A comment is at most %d characters", maxCommentLength))
+	}
+	return nil
+}
+
+// mentions checks the mentioned users exist, dropping repeats
+func (s *CommentService) mentions(ids []uuid.UUID) ([]uuid.UUID, error) {
+	var users []uuid.UUID
+	for _, id := range ids {
+		if containsUUID(users, id) {
+			continue
+		}
+		if user, err := s.userRepo.FindByID(id); err != nil || user == nil {
+			return nil, commentInvalid("Mentioned user not found: " + id.String())
+		}
+		users = append(users, id)
+	}
+	return users, nil
+}
+
+// upload stores the files of a new comment under Comments/<assign>/<thread>/
+func (s *CommentService) upload(comment *domain.Comment, files []CommentFile) ([]domain.CommentAttachment, error) {
+	attachments := make([]domain.CommentAttachment, 0, len(files))
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f.Name))
+		contentType, ok := commentFileTypes[ext]
+		if !ok {
+			if contentType = mime.TypeByExtension(ext); contentType == "" {
+				return nil, commentInvalid("Unsupported file format: " + f.Name)
+			}
+		}
+		if f.Size > maxCommentFileSize {
+			return nil, commentInvalid(fmt.Sprintf("%s is larger than %d MB", f.Name, maxCommentFileSize>>20))
+		}
+		objectName := fmt.Sprintf("Comments/%s/%s/%s%s", comment.AssignID, comment.ThreadID(), uuid.New(), ext)
+		url, err := s.minio.UploadStream(f.Reader, f.Size, objectName, contentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", f.Name, err)
+		}
+		attachments = append(attachments, domain.CommentAttachment{URL: url, Name: f.Name, ContentType: contentType, Size: f.Size})
+	}
+	return attachments, nil
+}
+
+// revision captures the current content of a comment before it changes
+func (s *CommentService) revision(comment *domain.Comment, action domain.CommentAction, actor *uuid.UUID) *domain.CommentRevision {
+	return &domain.CommentRevision{
+		ID:          uuid.New(),
+		CommentID:   comment.ID,
+		Action:      action,
+		Body:        comment.Body,
+		Attachments: comment.Attachments,
+		Mentions:    comment.Mentions,
+		ActorID:     actor,
+	}
+}
+
+// announce notifies the mentioned users, except the author, and refreshes
+// open threads
+func (s *CommentService) announce(comment *domain.Comment, mentioned []uuid.UUID) {
+	if s.notify != nil && len(mentioned) > 0 {
+		excerpt := comment.Body
+		if runes := []rune(excerpt); len(runes) > 140 {
+			excerpt = string(runes[:140]) + "…"
+		}
+		msg, _ := json.Marshal(map[string]interface{}{
+			"event":            "comment_mention",
+			"id_comment":       comment.ID,
+			"id_assign":        comment.AssignID,
+			"id_detail_assign": comment.DetailAssignID,
+			"id_author":        comment.AuthorID,
+			"excerpt":          excerpt,
+		})
+		for _, uid := range mentioned {
+			if uid != comment.AuthorID {
+				s.notify(uid, msg)
+			}
+		}
+	}
+	if s.broadcast != nil {
+		msg, _ := json.Marshal(map[string]interface{}{
+			"event":            "comments_updated",
+			"id_assign":        comment.AssignID,
+			"id_detail_assign": comment.DetailAssignID,
+		})
+		s.broadcast(msg)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// MockCommentRepository keeps comments and revisions in memory
+type MockCommentRepository struct {
+	Comments  map[uuid.UUID]*domain.Comment
+	Revisions []domain.CommentRevision
+	Reads     map[uuid.UUID]time.Time // Thread -> last read
+}
+
+func (m *MockCommentRepository) Create(comment *domain.Comment) error {
+	m.Comments[comment.ID] = comment
+	return nil
+}
+func (m *MockCommentRepository) FindByID(id uuid.UUID) (*domain.Comment, error) {
+	if c, ok := m.Comments[id]; ok {
+		copied := *c
+		return &copied, nil
+	}
+	return nil, nil
+}
+func (m *MockCommentRepository) FindThread(uuid.UUID, *uuid.UUID) ([]domain.Comment, error) {
+	return nil, nil
+}
+func (m *MockCommentRepository) Revise(comment *domain.Comment, revision *domain.CommentRevision) error {
+	m.Comments[comment.ID] = comment
+	m.Revisions = append(m.Revisions, *revision)
+	return nil
+}
+func (m *MockCommentRepository) FindRevisions(uuid.UUID) ([]domain.CommentRevision, error) {
+	return m.Revisions, nil
+}
+func (m *MockCommentRepository) MarkRead(_, threadID uuid.UUID, at time.Time) error {
+	m.Reads[threadID] = at
+	return nil
+}
+func (m *MockCommentRepository) CountUnread(uuid.UUID, *uuid.UUID) ([]domain.UnreadThread, error) {
+	return nil, nil
+}
+
+func TestCommentMentionsAndHistory(t *testing.T) {
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	assign := &domain.Assign{ID: uuid.New()}
+	taskID := uuid.New()
+	details := &MockDetailAssignRepository{Details: map[string]*domain.DetailAssign{
+		taskID.String(): {ID: taskID, AssignID: assign.ID},
+	}}
+	repo := &MockCommentRepository{Comments: map[uuid.UUID]*domain.Comment{}, Reads: map[uuid.UUID]time.Time{}}
+	notified := map[uuid.UUID]int{}
+	svc := NewCommentService(repo, &MockAssignRepository{Assigns: map[uuid.UUID]*domain.Assign{assign.ID: assign}}, details,
+		NewMockUserRepository(&domain.User{ID: alice}, &domain.User{ID: bob}, &domain.User{ID: carol}), nil,
+		func(uid uuid.UUID, _ []byte) { notified[uid]++ }, nil)
+	thread := CommentThread{DetailID: &taskID}
+
+	if _, err := svc.Post(thread, CommentInput{Body: "  "}, nil, &alice, nil); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("empty comment: err = %v", err)
+	}
+	if _, err := svc.Post(thread, CommentInput{Body: "hi", MentionIDs: []uuid.UUID{uuid.New()}}, nil, &alice, nil); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("mentioning an unknown user: err = %v", err)
+	}
+
+	comment, err := svc.Post(thread, CommentInput{Body: "@Bob why was string 3 rejected?", MentionIDs: []uuid.UUID{bob, bob, alice}}, nil, &alice, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.AssignID != assign.ID || len(comment.MentionedUsers()) != 2 {
+		t.Errorf("comment = %+v", comment)
+	}
+	// The author is not notified of mentioning themselves
+	if notified[bob] != 1 || notified[alice] != 0 {
+		t.Errorf("notified = %v", notified)
+	}
+
+	if _, err := svc.Edit(comment.ID, CommentInput{Body: "x"}, &bob); appStatus(err) != http.StatusForbidden {
+		t.Errorf("editing someone else's comment: err = %v", err)
+	}
+	edited, err := svc.Edit(comment.ID, CommentInput{Body: "@Bob @Carol why was string 3 rejected?", MentionIDs: []uuid.UUID{bob, carol}}, &alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.EditedAt == nil || notified[bob] != 1 || notified[carol] != 1 {
+		t.Errorf("after edit: edited_at = %v, notified = %v", edited.EditedAt, notified)
+	}
+
+	if _, err := svc.Delete(comment.ID, &bob, false); appStatus(err) != http.StatusForbidden {
+		t.Errorf("deleting someone else's comment: err = %v", err)
+	}
+	deleted, err := svc.Delete(comment.ID, &bob, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.DeletedAt == nil || deleted.Body != "" {
+		t.Errorf("deleted = %+v", deleted)
+	}
+	if _, err := svc.Edit(comment.ID, CommentInput{Body: "again"}, &alice); appStatus(err) != http.StatusConflict {
+		t.Errorf("editing a deleted comment: err = %v", err)
+	}
+	if r := repo.Revisions; len(r) != 2 || r[0].Action != domain.CommentEdited || r[0].Body != "@Bob why was string 3 rejected?" ||
+		r[1].Action != domain.CommentDeleted || *r[1].ActorID != bob {
+		t.Errorf("revisions = %+v", r)
+	}
+
+	if history, err := svc.History(comment.ID, nil); err != nil || len(history) != 2 {
+		t.Errorf("history = %+v, err = %v", history, err)
+	}
+	if _, err := svc.History(comment.ID, &domain.VisibilityScope{UserID: carol}); appStatus(err) != http.StatusNotFound {
+		t.Errorf("history of a hidden assign: err = %v", err)
+	}
+
+	if err := svc.MarkRead(thread, &bob, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.Reads[taskID]; !ok {
+		t.Errorf("reads = %v", repo.Reads)
+	}
+}
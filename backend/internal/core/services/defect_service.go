@@ -0,0 +1,301 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/datatypes"
+)
+
+// DefectService raises defects found on assets, moves them through their
+// workflow and turns them into corrective assigns.
+type DefectService struct {
+	repo       domain.DefectRepository
+	assetRepo  domain.AssetRepository
+	configRepo domain.ConfigRepository
+	detailRepo domain.DetailAssignRepository
+	broadcast  BroadcastFunc
+	now        func() time.Time
+}
+
+func NewDefectService(repo domain.DefectRepository, assetRepo domain.AssetRepository, configRepo domain.ConfigRepository, detailRepo domain.DetailAssignRepository, broadcast BroadcastFunc) *DefectService {
+	return &DefectService{
+		repo:       repo,
+		assetRepo:  assetRepo,
+		configRepo: configRepo,
+		detailRepo: detailRepo,
+		broadcast:  broadcast,
+		now:        time.Now,
+	}
+}
+
+// DefectInput raises or edits a defect. The asset may be left out when the
+// defect is raised from a task: it is then the task's asset.
+type DefectInput struct {
+	AssetID        *uuid.UUID            `json:"id_asset"`
+	DetailAssignID *uuid.UUID            `json:"id_detail_assign"`
+	Title          string                `json:"title"`
+	Description    string                `json:"description"`
+	Category       string                `json:"category"`
+	Severity       domain.DefectSeverity `json:"severity"`
+	Photos         []string              `json:"photos"`
+	AssigneeID     *uuid.UUID            `json:"id_assignee"`
+}
+
+// ConvertDefectInput sets up the corrective assign of a defect. Without
+// configs, every config of the defect's asset is included; without users, the
+// defect's assignee does the work.
+type ConvertDefectInput struct {
+	ConfigIDs []uuid.UUID `json:"id_config"`
+	UserIDs   []uuid.UUID `json:"id_user"`
+	StartTime *time.Time  `json:"start_time"`
+	EndTime   *time.Time  `json:"end_time"`
+	Note      string      `json:"note_assign"`
+}
+
+func defectInvalid(msg string) error {
+	return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+}
+
+func (s *DefectService) List(filter domain.DefectFilter, scope *domain.VisibilityScope) ([]domain.Defect, error) {
+	return s.repo.FindAll(filter, scope)
+}
+
+func (s *DefectService) Get(id uuid.UUID, scope *domain.VisibilityScope) (*domain.Defect, error) {
+	defect, err := s.repo.FindByID(id, scope)
+	if err != nil {
+		return nil, err
+	}
+	if defect == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound.Code, "Defect not found", http.StatusNotFound)
+	}
+	return defect, nil
+}
+
+// Create raises a defect on an asset the scope may see, open until someone takes it on
+func (s *DefectService) Create(input DefectInput, reporter *uuid.UUID, scope *domain.VisibilityScope) (*domain.Defect, error) {
+	assetID := input.AssetID
+	if input.DetailAssignID != nil {
+		detail, err := s.detailRepo.FindByID(*input.DetailAssignID)
+		if err != nil || detail == nil {
+			return nil, defectInvalid("Task not found")
+		}
+		if detail.Config != nil {
+			if assetID == nil {
+				assetID = &detail.Config.AssetID
+			} else if *assetID != detail.Config.AssetID {
+				return nil, defectInvalid("The asset is not the one of the task")
+			}
+		}
+	}
+	if assetID == nil {
+		return nil, defectInvalid("id_asset is required")
+	}
+	asset, err := s.assetRepo.FindByID(*assetID, scope)
+	if err != nil || asset == nil {
+		return nil, defectInvalid("Asset not found")
+	}
+
+	defect := &domain.Defect{
+		ID:             uuid.New(),
+		AssetID:        asset.ID,
+		ProjectID:      asset.ProjectID,
+		DetailAssignID: input.DetailAssignID,
+		Status:         domain.DefectStatusOpen,
+		ReporterID:     reporter,
+	}
+	if err := applyDefect(defect, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(defect); err != nil {
+		return nil, err
+	}
+	return defect, nil
+}
+
+// Update edits the description of a defect; its asset and origin are fixed
+func (s *DefectService) Update(id uuid.UUID, input DefectInput, scope *domain.VisibilityScope) (*domain.Defect, error) {
+	defect, err := s.Get(id, scope)
+	if err != nil {
+		return nil, err
+	}
+	if defect.Status == domain.DefectStatusClosed || defect.Status == domain.DefectStatusCancelled {
+		return nil, apperrors.NewAppError(apperrors.ErrInvalidState.Code,
+			fmt.Sprintf("Cannot edit a %s defect", defect.Status), http.StatusConflict)
+	}
+	if err := applyDefect(defect, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(defect); err != nil {
+		return nil, err
+	}
+	return defect, nil
+}
+
+func applyDefect(defect *domain.Defect, input DefectInput) error {
+	input.Title = strings.TrimSpace(input.Title)
+	if input.Title == "" {
+		return defectInvalid("title is required")
+	}
+	if input.Category == "" {
+		input.Category = "other"
+	}
+	if !containsString(domain.DefectCategories, input.Category) {
+		return defectInvalid("category must be one of " + strings.Join(domain.DefectCategories, ", "))
+	}
+	if input.Severity == "" {
+		input.Severity = domain.DefectSeverityMedium
+	}
+	valid := false
+	for _, sev := range domain.DefectSeverities {
+		valid = valid || sev == input.Severity
+	}
+	if !valid {
+		return defectInvalid("severity must be low, medium, high or critical")
+	}
+	photos := input.Photos
+	if photos == nil {
+		photos = []string{}
+	}
+	photosJSON, _ := json.Marshal(photos)
+
+	defect.Title = input.Title
+	defect.Description = input.Description
+	defect.Category = input.Category
+	defect.Severity = input.Severity
+	defect.Photos = datatypes.JSON(photosJSON)
+	defect.AssigneeID = input.AssigneeID
+	defect.Assignee = nil
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeStatus moves a defect through its workflow. Managers may make any
+// allowed move; the assignee may only start, hand back or resolve the work.
+// The note is recorded as the resolution when resolving.
+func (s *DefectService) ChangeStatus(id uuid.UUID, status domain.DefectStatus, note string, actor *uuid.UUID, canManage bool, scope *domain.VisibilityScope) (*domain.Defect, error) {
+	defect, err := s.Get(id, scope)
+	if err != nil {
+		return nil, err
+	}
+	if !defect.Status.CanMoveTo(status) {
+		return nil, apperrors.NewAppError(apperrors.ErrInvalidState.Code,
+			fmt.Sprintf("Cannot move a %s defect to %s", defect.Status, status), http.StatusConflict)
+	}
+	if !canManage {
+		isAssignee := actor != nil && defect.AssigneeID != nil && *actor == *defect.AssigneeID
+		if !isAssignee || status == domain.DefectStatusClosed || status == domain.DefectStatusCancelled {
+			return nil, apperrors.NewAppError(apperrors.ErrForbidden.Code,
+				"Only managers may close or cancel a defect, and only its assignee may work on it", http.StatusForbidden)
+		}
+	}
+
+	now := s.now()
+	switch status {
+	case domain.DefectStatusResolved:
+		if strings.TrimSpace(note) == "" {
+			return nil, defectInvalid("A resolution note is required")
+		}
+		defect.Resolution = note
+		defect.ResolvedAt = &now
+	case domain.DefectStatusClosed:
+		defect.ClosedAt = &now
+		defect.ClosedByID = actor
+	case domain.DefectStatusInProgress, domain.DefectStatusOpen:
+		// Reopened: the previous fix no longer stands
+		defect.ResolvedAt = nil
+	}
+	defect.Status = status
+	if err := s.repo.Update(defect); err != nil {
+		return nil, err
+	}
+	return defect, nil
+}
+
+// Convert creates the corrective assign of a defect, one task per process of
+// each config (see BuildDetailAssigns), and puts the defect in progress. The
+// configs must be those of the defect's asset.
+func (s *DefectService) Convert(id uuid.UUID, input ConvertDefectInput, scope *domain.VisibilityScope) (*domain.Defect, error) {
+	defect, err := s.Get(id, scope)
+	if err != nil {
+		return nil, err
+	}
+	if defect.AssignID != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrConflict.Code, "Defect already has a corrective assign", http.StatusConflict)
+	}
+	if defect.Status != domain.DefectStatusOpen && defect.Status != domain.DefectStatusInProgress {
+		return nil, apperrors.NewAppError(apperrors.ErrInvalidState.Code,
+			fmt.Sprintf("Cannot convert a %s defect", defect.Status), http.StatusConflict)
+	}
+	if input.StartTime != nil && input.EndTime != nil && input.EndTime.Before(*input.StartTime) {
+		return nil, defectInvalid("end_time must not be before start_time")
+	}
+
+	configs, err := s.configRepo.FindByAssetID(defect.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	var assetConfigIDs []uuid.UUID
+	for _, cfg := range configs {
+		assetConfigIDs = append(assetConfigIDs, cfg.ID)
+	}
+	configIDs := input.ConfigIDs
+	for _, cid := range configIDs {
+		if !containsUUID(assetConfigIDs, cid) {
+			return nil, defectInvalid("id_config must be configs of the defect's asset")
+		}
+	}
+	if len(configIDs) == 0 {
+		configIDs = assetConfigIDs
+	}
+	if len(configIDs) == 0 {
+		return nil, defectInvalid("The asset has no configs")
+	}
+	userIDs := input.UserIDs
+	if len(userIDs) == 0 && defect.AssigneeID != nil {
+		userIDs = []uuid.UUID{*defect.AssigneeID}
+	}
+	users := make([]string, 0, len(userIDs))
+	for _, uid := range userIDs {
+		users = append(users, uid.String())
+	}
+	usersJSON, _ := json.Marshal(users)
+	note := strings.TrimSpace(input.Note)
+	if note == "" {
+		note = "Defect: " + defect.Title
+	}
+
+	assign := &domain.Assign{
+		ID:         uuid.New(),
+		ProjectID:  defect.ProjectID,
+		UserIDs:    datatypes.JSON(usersJSON),
+		StartTime:  input.StartTime,
+		EndTime:    input.EndTime,
+		NoteAssign: note,
+	}
+	details := BuildDetailAssigns(s.configRepo, assign.ID, configIDs)
+	defect.AssignID = &assign.ID
+	defect.Status = domain.DefectStatusInProgress
+	if err := s.repo.Convert(defect, assign, details); err != nil {
+		return nil, err
+	}
+	defect.Assign = assign
+	if s.broadcast != nil {
+		s.broadcast([]byte(`{"event":"assign_created"}`))
+	}
+	return defect, nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// MockDefectRepository implements domain.DefectRepository in memory
+type MockDefectRepository struct {
+	Defects map[uuid.UUID]*domain.Defect
+	Assigns []domain.Assign
+	Details []domain.DetailAssign
+}
+
+func (m *MockDefectRepository) Create(d *domain.Defect) error {
+	m.Defects[d.ID] = d
+	return nil
+}
+func (m *MockDefectRepository) FindAll(domain.DefectFilter, *domain.VisibilityScope) ([]domain.Defect, error) {
+	return nil, nil
+}
+func (m *MockDefectRepository) FindByID(id uuid.UUID, _ *domain.VisibilityScope) (*domain.Defect, error) {
+	if d, ok := m.Defects[id]; ok {
+		copied := *d
+		return &copied, nil
+	}
+	return nil, nil
+}
+func (m *MockDefectRepository) Update(d *domain.Defect) error {
+	m.Defects[d.ID] = d
+	return nil
+}
+func (m *MockDefectRepository) Convert(d *domain.Defect, assign *domain.Assign, details []domain.DetailAssign) error {
+	m.Assigns = append(m.Assigns, *assign)
+	m.Details = append(m.Details, details...)
+	m.Defects[d.ID] = d
+	return nil
+}
+
+// MockAssetRepository implements the lookups of domain.AssetRepository
+type MockAssetRepository struct {
+	domain.AssetRepository
+	Assets map[uuid.UUID]*domain.Asset
+}
+
+func (m *MockAssetRepository) FindByID(id uuid.UUID, _ *domain.VisibilityScope) (*domain.Asset, error) {
+	if a, ok := m.Assets[id]; ok {
+		return a, nil
+	}
+	return nil, nil
+}
+
+// appStatus is the HTTP status of an AppError, or 0 for any other error
+func appStatus(err error) int {
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		return appErr.Status
+	}
+	return 0
+}
+
+func newDefectFixture() (*DefectService, *MockDefectRepository, *domain.Asset, *domain.DetailAssign) {
+	asset := &domain.Asset{ID: uuid.New(), ProjectID: uuid.New()}
+	cfg := &domain.Config{ID: uuid.New(), AssetID: asset.ID}
+	detail := &domain.DetailAssign{ID: uuid.New(), ConfigID: &cfg.ID, Config: cfg}
+	repo := &MockDefectRepository{Defects: map[uuid.UUID]*domain.Defect{}}
+	svc := NewDefectService(repo,
+		&MockAssetRepository{Assets: map[uuid.UUID]*domain.Asset{asset.ID: asset}},
+		&MockConfigRepository{Configs: map[uuid.UUID]*domain.Config{cfg.ID: cfg}},
+		&MockDetailAssignRepository{Details: map[string]*domain.DetailAssign{detail.ID.String(): detail}},
+		nil)
+	return svc, repo, asset, detail
+}
+
+func TestCreateDefectFromTask(t *testing.T) {
+	svc, _, asset, detail := newDefectFixture()
+	reporter := uuid.New()
+
+	defect, err := svc.Create(DefectInput{DetailAssignID: &detail.ID, Title: " Broken MC4 connector ", Severity: domain.DefectSeverityHigh}, &reporter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defect.AssetID != asset.ID || defect.ProjectID != asset.ProjectID || defect.Status != domain.DefectStatusOpen ||
+		defect.Category != "other" || defect.Title != "Broken MC4 connector" {
+		t.Fatalf("defect = %+v", defect)
+	}
+
+	other := uuid.New()
+	if _, err := svc.Create(DefectInput{AssetID: &other, DetailAssignID: &detail.ID, Title: "x"}, &reporter, nil); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("asset not matching the task: err = %v", err)
+	}
+	if _, err := svc.Create(DefectInput{AssetID: &asset.ID, Title: "x", Severity: "urgent"}, &reporter, nil); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("unknown severity: err = %v", err)
+	}
+}
+
+func TestDefectStatusWorkflow(t *testing.T) {
+	svc, _, asset, _ := newDefectFixture()
+	worker, manager := uuid.New(), uuid.New()
+	defect, err := svc.Create(DefectInput{AssetID: &asset.ID, Title: "String 4 tripped", AssigneeID: &worker}, &manager, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stranger := uuid.New()
+	if _, err := svc.ChangeStatus(defect.ID, domain.DefectStatusInProgress, "", &stranger, false, nil); appStatus(err) != http.StatusForbidden {
+		t.Errorf("non-assignee start: err = %v", err)
+	}
+	if _, err := svc.ChangeStatus(defect.ID, domain.DefectStatusClosed, "", &manager, true, nil); appStatus(err) != http.StatusConflict {
+		t.Errorf("closing an open defect: err = %v", err)
+	}
+	if _, err := svc.ChangeStatus(defect.ID, domain.DefectStatusResolved, "", &worker, false, nil); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("resolving without a note: err = %v", err)
+	}
+	resolved, err := svc.ChangeStatus(defect.ID, domain.DefectStatusResolved, "Replaced the fuse", &worker, false, nil)
+	if err != nil || resolved.ResolvedAt == nil || resolved.Resolution != "Replaced the fuse" {
+		t.Fatalf("resolve: %+v, %v", resolved, err)
+	}
+	if _, err := svc.ChangeStatus(defect.ID, domain.DefectStatusClosed, "", &worker, false, nil); appStatus(err) != http.StatusForbidden {
+		t.Errorf("assignee closing: err = %v", err)
+	}
+	closed, err := svc.ChangeStatus(defect.ID, domain.DefectStatusClosed, "", &manager, true, nil)
+	if err != nil || closed.ClosedAt == nil || closed.ClosedByID == nil || *closed.ClosedByID != manager {
+		t.Fatalf("close: %+v, %v", closed, err)
+	}
+	if _, err := svc.Update(defect.ID, DefectInput{Title: "edited"}, nil); appStatus(err) != http.StatusConflict {
+		t.Errorf("editing a closed defect: err = %v", err)
+	}
+}
+
+func TestConvertDefectCreatesCorrectiveAssign(t *testing.T) {
+	svc, repo, asset, _ := newDefectFixture()
+	worker := uuid.New()
+	defect, err := svc.Create(DefectInput{AssetID: &asset.ID, Title: "Inverter fan noise", AssigneeID: &worker}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foreign := uuid.New()
+	if _, err := svc.Convert(defect.ID, ConvertDefectInput{ConfigIDs: []uuid.UUID{foreign}}, nil); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("converting with another asset's config: err = %v", err)
+	}
+
+	converted, err := svc.Convert(defect.ID, ConvertDefectInput{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted.Status != domain.DefectStatusInProgress || converted.AssignID == nil || len(repo.Assigns) != 1 {
+		t.Fatalf("converted = %+v", converted)
+	}
+	assign := repo.Assigns[0]
+	if assign.ProjectID != asset.ProjectID || assign.NoteAssign != "Defect: Inverter fan noise" ||
+		string(assign.UserIDs) != `["`+worker.String()+`"]` {
+		t.Errorf("assign = %+v", assign)
+	}
+	// The asset's single config without processes gives one task
+	if len(repo.Details) != 1 || repo.Details[0].AssignID != assign.ID {
+		t.Errorf("details = %+v", repo.Details)
+	}
+
+	if _, err := svc.Convert(defect.ID, ConvertDefectInput{}, nil); appStatus(err) != http.StatusConflict {
+		t.Errorf("converting twice: err = %v", err)
+	}
+}
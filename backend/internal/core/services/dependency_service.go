@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// DependencyInput declares that the tasks of ConfigID wait for the tasks of
+// DependsOnID to reach Requires (approved when left out)
+type DependencyInput struct {
+	ConfigID    uuid.UUID             `json:"id_config" binding:"required"`
+	DependsOnID uuid.UUID             `json:"id_depends_on" binding:"required"`
+	Requires    domain.DependencyGate `json:"required_state"`
+}
+
+// DependencyService configures the step order between the configs of a template
+type DependencyService struct {
+	repo         domain.ConfigDependencyRepository
+	templateRepo domain.TemplateRepository
+}
+
+func NewDependencyService(repo domain.ConfigDependencyRepository, templateRepo domain.TemplateRepository) *DependencyService {
+	return &DependencyService{repo: repo, templateRepo: templateRepo}
+}
+
+func (s *DependencyService) GetTemplateDependencies(templateID uuid.UUID) ([]domain.ConfigDependency, error) {
+	if _, err := s.templateConfigs(templateID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByTemplate(templateID)
+}
+
+// SetTemplateDependencies replaces the dependencies of a template. Both ends
+// must be configs of the template and together they must not form a cycle.
+// Assigns already running pick the new order up on their next submit.
+func (s *DependencyService) SetTemplateDependencies(templateID uuid.UUID, inputs []DependencyInput) ([]domain.ConfigDependency, error) {
+	configs, err := s.templateConfigs(templateID)
+	if err != nil {
+		return nil, err
+	}
+	deps := make([]domain.ConfigDependency, 0, len(inputs))
+	seen := make(map[[2]uuid.UUID]bool, len(inputs))
+	for i, in := range inputs {
+		if in.Requires == "" {
+			in.Requires = domain.GateApproved
+		}
+		switch {
+		case in.Requires != domain.GateApproved && in.Requires != domain.GateSubmitted:
+			return nil, invalidDependency(fmt.Sprintf("Dependency %d: required_state must be submitted or approved", i+1))
+		case !configs[in.ConfigID] || !configs[in.DependsOnID]:
+			return nil, invalidDependency(fmt.Sprintf("Dependency %d: both configs must belong to the template", i+1))
+		case in.ConfigID == in.DependsOnID:
+			return nil, invalidDependency(fmt.Sprintf("Dependency %d: a config cannot depend on itself", i+1))
+		case seen[[2]uuid.UUID{in.ConfigID, in.DependsOnID}]:
+			return nil, invalidDependency(fmt.Sprintf("Dependency %d: declared twice", i+1))
+		}
+		seen[[2]uuid.UUID{in.ConfigID, in.DependsOnID}] = true
+		deps = append(deps, domain.ConfigDependency{
+			ID:          uuid.New(),
+			TemplateID:  templateID,
+			ConfigID:    in.ConfigID,
+			DependsOnID: in.DependsOnID,
+			Requires:    in.Requires,
+		})
+	}
+	if cycle := domain.DependencyCycle(deps); cycle != nil {
+		ids := make([]string, len(cycle))
+		for i, id := range cycle {
+			ids[i] = id.String()
+		}
+		return nil, invalidDependency("Dependencies form a cycle: " + strings.Join(ids, " -> "))
+	}
+	if err := s.repo.ReplaceForTemplate(templateID, deps); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// templateConfigs returns the set of configs of a template
+func (s *DependencyService) templateConfigs(templateID uuid.UUID) (map[uuid.UUID]bool, error) {
+	template, err := s.templateRepo.FindByID(templateID)
+	if err != nil || template == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound.Code, "Template not found", http.StatusNotFound)
+	}
+	var ids []string
+	if len(template.ConfigIDs) > 0 {
+		_ = json.Unmarshal(template.ConfigIDs, &ids)
+	}
+	configs := make(map[uuid.UUID]bool, len(ids))
+	for _, raw := range ids {
+		if id, err := uuid.Parse(raw); err == nil {
+			configs[id] = true
+		}
+	}
+	return configs, nil
+}
+
+func invalidDependency(msg string) error {
+	return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+}
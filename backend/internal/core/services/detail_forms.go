@@ -0,0 +1,166 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/datatypes"
+)
+
+// taskForm returns the checklist form a task is filled against: the version
+// pinned on it, else the current form of its config (see domain.CurrentForm).
+// Returns nil when the task has no form.
+func (s *AllocationWorkflowService) taskForm(detail *domain.DetailAssign) (*domain.FormSchema, error) {
+	if detail.FormSchemaID != nil {
+		if detail.FormSchema != nil {
+			return detail.FormSchema, nil
+		}
+		if s.forms != nil {
+			return s.forms.FindByID(*detail.FormSchemaID)
+		}
+		return nil, nil
+	}
+	if s.forms == nil || detail.Config == nil {
+		return nil, nil
+	}
+	cfg := detail.Config
+	latest, err := s.forms.FindLatest([]uuid.UUID{cfg.ID}, []uuid.UUID{cfg.SubWorkID})
+	if err != nil {
+		return nil, err
+	}
+	return domain.CurrentForm(latest, cfg), nil
+}
+
+// fillForm merges the submitted answers into the task's stored ones and
+// validates them against form: unknown fields are refused, and so are missing
+// required ones when final is set (a real submit, not a draft). A null answer
+// clears the field. Returns the answers to store.
+func fillForm(detail *domain.DetailAssign, form *domain.FormSchema, answers map[string]json.RawMessage, final bool) (datatypes.JSON, error) {
+	if form == nil {
+		if len(answers) > 0 {
+			return nil, apperrors.NewAppError(apperrors.ErrValidation.Code, "This task has no checklist form", http.StatusBadRequest)
+		}
+		return detail.FormAnswers, nil
+	}
+	fields := form.FormFields()
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.Key] = true
+	}
+
+	var problems []string
+	var unknown []string
+	merged := detail.Answers()
+	for key, raw := range answers {
+		if !known[key] {
+			unknown = append(unknown, key)
+			continue
+		}
+		if string(raw) == "null" {
+			delete(merged, key)
+		} else {
+			merged[key] = raw
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problems = append(problems, fmt.Sprintf("%s: unknown field", key))
+	}
+
+	kept := make(map[string]json.RawMessage, len(fields))
+	for _, f := range fields {
+		raw, ok := merged[f.Key]
+		empty := !ok
+		if ok {
+			var problem string
+			if empty, problem = checkAnswer(f, raw); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: %s", f.Label, problem))
+				continue
+			}
+			kept[f.Key] = raw
+		}
+		if empty && final && f.Required {
+			problems = append(problems, fmt.Sprintf("%s: required", f.Label))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, apperrors.NewAppError(apperrors.ErrValidation.Code,
+			"Invalid checklist: "+strings.Join(problems, "; "), http.StatusBadRequest)
+	}
+	out, _ := json.Marshal(kept)
+	return datatypes.JSON(out), nil
+}
+
+// checkAnswer validates one answer against its field, reporting whether it
+// is empty (counts as unanswered) or else what is wrong with it
+func checkAnswer(f domain.FormField, raw json.RawMessage) (empty bool, problem string) {
+	isOption := func(v string) bool {
+		for _, o := range f.Options {
+			if o.Value == v {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch f.Type {
+	case domain.FormFieldCheck:
+		var v bool
+		if json.Unmarshal(raw, &v) != nil {
+			return false, "must be yes or no"
+		}
+		return false, ""
+	case domain.FormFieldSelect:
+		var v string
+		if json.Unmarshal(raw, &v) != nil {
+			return false, "must be one of the options"
+		}
+		if v == "" {
+			return true, ""
+		}
+		if !isOption(v) {
+			return false, fmt.Sprintf("%q is not an option", v)
+		}
+		return false, ""
+	case domain.FormFieldMultiSelect:
+		var vs []string
+		if json.Unmarshal(raw, &vs) != nil {
+			return false, "must be a list of options"
+		}
+		seen := make(map[string]bool, len(vs))
+		for _, v := range vs {
+			if !isOption(v) {
+				return false, fmt.Sprintf("%q is not an option", v)
+			}
+			if seen[v] {
+				return false, fmt.Sprintf("%q is chosen twice", v)
+			}
+			seen[v] = true
+		}
+		return len(vs) == 0, ""
+	case domain.FormFieldText:
+		var v string
+		if json.Unmarshal(raw, &v) != nil {
+			return false, "must be text"
+		}
+		if strings.TrimSpace(v) == "" {
+			return true, ""
+		}
+		if f.Pattern != "" {
+			re, err := regexp.Compile(`^(?:` + f.Pattern + `)$`)
+			if err != nil || !re.MatchString(v) {
+				return false, "does not match the expected format"
+			}
+		}
+		return false, ""
+	}
+	return false, "unsupported field type"
+}
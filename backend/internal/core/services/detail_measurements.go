@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+// measureReadings merges the submitted readings into the task's stored ones and
+// validates them against the measurement fields of its config: unknown keys are
+// refused and, when final is set (a real submit, not a draft), so are missing
+// required fields. Values are rounded to their field's precision and flagged
+// when outside its thresholds. Returns the rows to store, or nil when there is
+// nothing to change.
+func measureReadings(detail *domain.DetailAssign, readings map[string]float64, final bool) ([]domain.DetailMeasurement, error) {
+	var fields []domain.MeasurementField
+	if detail.Config != nil {
+		fields = detail.Config.Measurements()
+	}
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.Key] = true
+	}
+
+	var problems []string
+	var unknown []string
+	for key := range readings {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problems = append(problems, fmt.Sprintf("%s: unknown field", key))
+	}
+
+	values := make(map[string]float64)
+	for _, m := range detail.Measurements {
+		values[m.FieldKey] = m.Value
+	}
+	for key, v := range readings {
+		values[key] = v
+	}
+
+	rows := make([]domain.DetailMeasurement, 0, len(fields))
+	for i, f := range fields {
+		v, ok := values[f.Key]
+		if !ok {
+			if final && f.Required {
+				problems = append(problems, fmt.Sprintf("%s: required", f.Label))
+			}
+			continue
+		}
+		v = f.Round(v)
+		rows = append(rows, domain.DetailMeasurement{
+			ID:             uuid.New(),
+			DetailAssignID: detail.ID,
+			FieldKey:       f.Key,
+			Position:       i,
+			Label:          f.Label,
+			Unit:           f.Unit,
+			Value:          v,
+			Min:            f.Min,
+			Max:            f.Max,
+			OutOfRange:     !f.InRange(v),
+		})
+	}
+
+	if len(problems) > 0 {
+		return nil, apperrors.NewAppError(apperrors.ErrValidation.Code,
+			"Invalid measurements: "+strings.Join(problems, "; "), http.StatusBadRequest)
+	}
+	if len(readings) == 0 {
+		return nil, nil
+	}
+	return rows, nil
+}
+
+// replaceMeasurements swaps the stored readings of a task for rows.
+func (s *AllocationWorkflowService) replaceMeasurements(detailID uuid.UUID, rows []domain.DetailMeasurement) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id_detail_assign = ?", detailID).Delete(&domain.DetailMeasurement{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// ListAssignMeasurements returns the readings of every task of an assign,
+// optionally only the out-of-range ones, for review.
+func (s *AllocationWorkflowService) ListAssignMeasurements(assignID uuid.UUID, outOfRangeOnly bool) ([]domain.DetailMeasurement, error) {
+	q := s.db.Joins("JOIN detail_assigns d ON d.id = detail_measurements.id_detail_assign").
+		Where("d.id_assign = ? AND d.deleted_at IS NULL", assignID)
+	if outOfRangeOnly {
+		q = q.Where("detail_measurements.out_of_range = ?", true)
+	}
+	measurements := make([]domain.DetailMeasurement, 0)
+	err := q.Order("detail_measurements.id_detail_assign, detail_measurements.position").Find(&measurements).Error
+	return measurements, err
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"net/smtp"
-	"os"
+	"time"
+
+	"github.com/phuc/cmms-backend/internal/config"
 )
 
 type EmailService struct {
@@ -14,35 +16,22 @@ type EmailService struct {
 	from string
 }
 
-func NewEmailService() *EmailService {
-	host := os.Getenv("SMTP_HOST")
-	port := os.Getenv("SMTP_PORT")
-	email := os.Getenv("SMTP_EMAIL")
-	password := os.Getenv("SMTP_PASSWORD")
-
-	if host == "" || email == "" || password == "" {
+func NewEmailService(cfg config.SMTPConfig) *EmailService {
+	if cfg.Host == "" || cfg.Email == "" || cfg.Password == "" {
 		log.Println("Internal Email Service: Missing SMTP configuration")
 		return nil
 	}
 
-	auth := smtp.PlainAuth("", email, password, host)
+	auth := smtp.PlainAuth("", cfg.Email, cfg.Password, cfg.Host)
 	return &EmailService{
 		auth: auth,
-		host: host,
-		port: port,
-		from: email,
+		host: cfg.Host,
+		port: cfg.Port,
+		from: cfg.Email,
 	}
 }
 
 func (s *EmailService) SendAssignmentNotification(toEmail, userName, projectName string) error {
-	if s == nil {
-		return fmt.Errorf("email service not configured")
-	}
-
-	// Headers
-	subject := "Subject: [Raitek O&M] Thông báo phân công dự án mới\n" 
-	mime := "MIME-version: 1.0;\nContent-Type: text/plain; charset=\"UTF-8\";\n\n"
-	
 	body := fmt.Sprintf(`Xin chào %s,
 
 Bạn vừa nhận được một phân công mới cho dự án: %s.
@@ -50,8 +39,39 @@ Vui lòng truy cập ứng dụng để xem chi tiết và thực hiện công v
 
 Trân trọng,
 Đội ngũ Raitek O&M`, userName, projectName)
-	
-	msg := []byte(subject + mime + body)
+
+	return s.send(toEmail, "[Raitek O&M] Thông báo phân công dự án mới", body)
+}
+
+// SendPasswordReset emails a one-time password reset link.
+// token is included verbatim so it can be pasted when the link cannot be opened.
+func (s *EmailService) SendPasswordReset(toEmail, userName, resetURL, token string, ttl time.Duration) error {
+	body := fmt.Sprintf(`Xin chào %s,
+
+Chúng tôi nhận được yêu cầu đặt lại mật khẩu cho tài khoản của bạn.
+Mở liên kết sau để đặt mật khẩu mới (hiệu lực trong %d phút, chỉ dùng một lần):
+
+%s
+
+Mã đặt lại: %s
+
+Nếu bạn không yêu cầu đặt lại mật khẩu, hãy bỏ qua email này.
+
+Trân trọng,
+Đội ngũ Raitek O&M`, userName, int(ttl.Minutes()), resetURL, token)
+
+	return s.send(toEmail, "[Raitek O&M] Đặt lại mật khẩu", body)
+}
+
+func (s *EmailService) send(toEmail, subject, body string) error {
+	if s == nil {
+		return fmt.Errorf("email service not configured")
+	}
+
+	// Headers
+	header := "Subject: " + subject + "\n"
+	mime := "MIME-version: 1.0;\nContent-Type: text/plain; charset=\"UTF-8\";\n\n"
+	msg := []byte(header + mime + body)
 
 	addr := fmt.Sprintf("%s:%s", s.host, s.port)
 	if err := smtp.SendMail(addr, s.auth, s.from, []string{toEmail}, msg); err != nil {
@@ -66,7 +66,7 @@ func (s *EvidenceService) UploadDetailEvidence(detailID uuid.UUID, fileContent [
 	urls = append(urls, urlStr)
 	newData, _ := json.Marshal(urls)
 
-	s.DB.Model(&detail).Update("data", newData)
+	s.DB.Model(&detail).UpdateColumns(map[string]interface{}{"data": newData, "version": gorm.Expr("version + 1")})
 	return urlStr, nil
 }
 
@@ -102,7 +102,7 @@ func (s *EvidenceService) DeleteEvidence(detailID uuid.UUID, objectKey string) e
 	}
 
 	newData, _ := json.Marshal(newURLs)
-	s.DB.Model(&detail).Update("data", newData)
+	s.DB.Model(&detail).UpdateColumns(map[string]interface{}{"data": newData, "version": gorm.Expr("version + 1")})
 	return nil
 }
 
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/datatypes"
+)
+
+// FormService versions the checklist forms of sub-works and of configs
+// overriding them for one asset.
+type FormService struct {
+	repo        domain.FormSchemaRepository
+	configRepo  domain.ConfigRepository
+	subWorkRepo domain.SubWorkRepository
+}
+
+func NewFormService(repo domain.FormSchemaRepository, configRepo domain.ConfigRepository, subWorkRepo domain.SubWorkRepository) *FormService {
+	return &FormService{repo: repo, configRepo: configRepo, subWorkRepo: subWorkRepo}
+}
+
+func formInvalid(msg string) error {
+	return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+}
+
+// Versions lists every version of the form of a sub-work, or of a config
+// when configID is set, newest first
+func (s *FormService) Versions(subWorkID, configID *uuid.UUID) ([]domain.FormSchema, error) {
+	if err := s.checkOwner(subWorkID, configID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindVersions(subWorkID, configID)
+}
+
+func (s *FormService) Get(id uuid.UUID) (*domain.FormSchema, error) {
+	form, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if form == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound.Code, "Form not found", http.StatusNotFound)
+	}
+	return form, nil
+}
+
+// CreateVersion stores fields as the next version of the form. Tasks already
+// pinned to an earlier version keep it; the others are filled against this one.
+// An empty config version makes its tasks fall back to the sub-work's form.
+func (s *FormService) CreateVersion(subWorkID, configID *uuid.UUID, fields []domain.FormField, actor *uuid.UUID) (*domain.FormSchema, error) {
+	if err := s.checkOwner(subWorkID, configID); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		fields = []domain.FormField{}
+	}
+	if err := validateFormFields(fields); err != nil {
+		return nil, err
+	}
+	fieldsJSON, _ := json.Marshal(fields)
+	form := &domain.FormSchema{
+		ID:          uuid.New(),
+		Fields:      datatypes.JSON(fieldsJSON),
+		CreatedByID: actor,
+	}
+	if configID != nil {
+		form.ConfigID = configID
+	} else {
+		form.SubWorkID = subWorkID
+	}
+	if err := s.repo.Create(form); err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+func (s *FormService) checkOwner(subWorkID, configID *uuid.UUID) error {
+	notFound := func(what string) error {
+		return apperrors.NewAppError(apperrors.ErrNotFound.Code, what+" not found", http.StatusNotFound)
+	}
+	if configID != nil {
+		if cfg, err := s.configRepo.FindByID(*configID); err != nil || cfg == nil {
+			return notFound("Config")
+		}
+		return nil
+	}
+	if subWorkID == nil {
+		return notFound("Sub-work")
+	}
+	if sw, err := s.subWorkRepo.FindByID(*subWorkID); err != nil || sw == nil {
+		return notFound("Sub-work")
+	}
+	return nil
+}
+
+// validateFormFields checks a form definition: keys and labels are required
+// and unique, select fields need options, and text patterns must compile
+func validateFormFields(fields []domain.FormField) error {
+	var problems []string
+	keys := make(map[string]bool, len(fields))
+	for i, f := range fields {
+		name := fmt.Sprintf("field %d", i+1)
+		if f.Key == "" {
+			problems = append(problems, name+": key is required")
+		} else {
+			name = f.Key
+			if keys[f.Key] {
+				problems = append(problems, name+": duplicate key")
+			}
+			keys[f.Key] = true
+		}
+		if strings.TrimSpace(f.Label) == "" {
+			problems = append(problems, name+": label is required")
+		}
+		switch f.Type {
+		case domain.FormFieldCheck, domain.FormFieldText:
+		case domain.FormFieldSelect, domain.FormFieldMultiSelect:
+			if len(f.Options) == 0 {
+				problems = append(problems, name+": options are required")
+			}
+			values := make(map[string]bool, len(f.Options))
+			for _, o := range f.Options {
+				if o.Value == "" || values[o.Value] {
+					problems = append(problems, fmt.Sprintf("%s: option values must be set and unique", name))
+					break
+				}
+				values[o.Value] = true
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s: unknown type %q", name, f.Type))
+		}
+		if f.Pattern != "" {
+			if f.Type != domain.FormFieldText {
+				problems = append(problems, name+": only text fields take a pattern")
+			} else if _, err := regexp.Compile(f.Pattern); err != nil {
+				problems = append(problems, name+": invalid pattern")
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return formInvalid("Invalid form: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
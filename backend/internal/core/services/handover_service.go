@@ -0,0 +1,317 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// HandoverService moves tasks between engineers: managers reassign them, and
+// engineers rotating off a site hand their tasks over with a note. Every move
+// is kept as ownership history, and Assign.UserIDs is kept in step so clients
+// reading it still see who works the assign.
+type HandoverService struct {
+	repo       domain.DetailHandoverRepository
+	assignRepo domain.AssignRepository
+	detailRepo domain.DetailAssignRepository
+	userRepo   domain.UserRepository
+	notify     NotifyFunc
+	broadcast  BroadcastFunc
+}
+
+func NewHandoverService(repo domain.DetailHandoverRepository, assignRepo domain.AssignRepository, detailRepo domain.DetailAssignRepository, userRepo domain.UserRepository, notify NotifyFunc, broadcast BroadcastFunc) *HandoverService {
+	return &HandoverService{
+		repo:       repo,
+		assignRepo: assignRepo,
+		detailRepo: detailRepo,
+		userRepo:   userRepo,
+		notify:     notify,
+		broadcast:  broadcast,
+	}
+}
+
+// HandoverInput moves one task to another engineer
+type HandoverInput struct {
+	ToUserID uuid.UUID `json:"id_user" binding:"required"`
+	Note     string    `json:"note"`
+}
+
+// AssignHandoverInput moves everything an engineer still has to do on an
+// assign to another one. FromUserID defaults to the caller.
+type AssignHandoverInput struct {
+	FromUserID *uuid.UUID `json:"id_from_user"`
+	ToUserID   uuid.UUID  `json:"id_user" binding:"required"`
+	Note       string     `json:"note"`
+}
+
+func handoverInvalid(msg string) error {
+	return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+}
+
+// History returns the ownership history of a task, oldest first
+func (s *HandoverService) History(detailID uuid.UUID, scope *domain.VisibilityScope) ([]domain.DetailHandover, error) {
+	detail, err := s.detailRepo.FindByID(detailID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrNotFound
+		}
+		return nil, err
+	}
+	if _, err := s.assignRepo.FindByID(detail.AssignID, scope); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+	return s.repo.FindByDetail(detailID)
+}
+
+// AssignHistory returns the ownership history of every task of an assign, oldest first
+func (s *HandoverService) AssignHistory(assignID uuid.UUID, scope *domain.VisibilityScope) ([]domain.DetailHandover, error) {
+	if _, err := s.assignRepo.FindByID(assignID, scope); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+	return s.repo.FindByAssign(assignID)
+}
+
+// Reassign gives a task to another engineer (manager action)
+func (s *HandoverService) Reassign(detailID uuid.UUID, input HandoverInput, actor *uuid.UUID, scope *domain.VisibilityScope) (*domain.DetailAssign, error) {
+	return s.moveDetail(detailID, input, actor, domain.HandoverReassign, true, scope)
+}
+
+// Handover passes a task on. Its holder must explain where the work stands;
+// managers may hand over on an absent engineer's behalf.
+func (s *HandoverService) Handover(detailID uuid.UUID, input HandoverInput, actor *uuid.UUID, canManage bool, scope *domain.VisibilityScope) (*domain.DetailAssign, error) {
+	if strings.TrimSpace(input.Note) == "" {
+		return nil, handoverInvalid("A handover note is required")
+	}
+	return s.moveDetail(detailID, input, actor, domain.HandoverShift, canManage, scope)
+}
+
+func (s *HandoverService) moveDetail(detailID uuid.UUID, input HandoverInput, actor *uuid.UUID, kind domain.HandoverKind, canManage bool, scope *domain.VisibilityScope) (*domain.DetailAssign, error) {
+	detail, err := s.detailRepo.FindByID(detailID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.ErrNotFound
+		}
+		return nil, err
+	}
+	if detail.CurrentState() == domain.DetailStateApproved {
+		return nil, apperrors.NewAppError(apperrors.ErrInvalidState.Code, "Cannot hand over an approved task", http.StatusConflict)
+	}
+	assign, err := s.assignRepo.FindByID(detail.AssignID, scope)
+	if err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+	if err := s.checkTarget(input.ToUserID); err != nil {
+		return nil, err
+	}
+
+	users := assign.Users()
+	holders := detail.Holders(users)
+	if !canManage && (actor == nil || !containsUUID(holders, *actor)) {
+		return nil, apperrors.NewAppError(apperrors.ErrForbidden.Code, "Only the engineer holding the task may hand it over", http.StatusForbidden)
+	}
+	// A shared task is handed over by one of its holders: they are the one
+	// giving it away. Reassigning it takes it from the assign as a whole.
+	from := ownerOf(detail, users)
+	if from == nil && kind == domain.HandoverShift && actor != nil && containsUUID(holders, *actor) {
+		from = actor
+	}
+	if from != nil && *from == input.ToUserID {
+		return nil, handoverInvalid("The task is already held by this user")
+	}
+
+	detail.OwnerID = &input.ToUserID
+	handover := domain.DetailHandover{
+		ID:             uuid.New(),
+		DetailAssignID: detail.ID,
+		AssignID:       assign.ID,
+		FromUserID:     from,
+		ToUserID:       input.ToUserID,
+		Kind:           kind,
+		Note:           strings.TrimSpace(input.Note),
+		ActorID:        actor,
+	}
+
+	// The new holder joins the assign; the previous one leaves it once they
+	// hold nothing left to do there
+	users = addUUID(users, input.ToUserID)
+	if from != nil && !holdsOpenWork(*from, assign.DetailAssigns, detail, users) {
+		users = removeUUID(users, *from)
+	}
+	assign.UserIDs = uuidsJSON(users)
+
+	if err := s.repo.Apply(assign, []*domain.DetailAssign{detail}, []domain.DetailHandover{handover}); err != nil {
+		return nil, handoverSaveError(err)
+	}
+	s.announce(handover, []uuid.UUID{detail.ID}, holders)
+	return detail, nil
+}
+
+// HandoverAssign moves every unfinished task of an engineer on an assign to
+// another engineer, who also takes their place among the assign's users (and
+// so their share of the tasks nobody owns). Approved tasks stay with whoever did them.
+func (s *HandoverService) HandoverAssign(assignID uuid.UUID, input AssignHandoverInput, actor *uuid.UUID, canManage bool, scope *domain.VisibilityScope) ([]domain.DetailHandover, error) {
+	if strings.TrimSpace(input.Note) == "" {
+		return nil, handoverInvalid("A handover note is required")
+	}
+	from := input.FromUserID
+	if from == nil {
+		from = actor
+	}
+	if from == nil {
+		return nil, handoverInvalid("id_from_user is required")
+	}
+	if !canManage && (actor == nil || *actor != *from) {
+		return nil, apperrors.NewAppError(apperrors.ErrForbidden.Code, "Only managers may hand over someone else's tasks", http.StatusForbidden)
+	}
+	if *from == input.ToUserID {
+		return nil, handoverInvalid("Cannot hand over to the same user")
+	}
+	assign, err := s.assignRepo.FindByID(assignID, scope)
+	if err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+	users := assign.Users()
+	if !containsUUID(users, *from) {
+		return nil, handoverInvalid("This user is not working on the assign")
+	}
+	if err := s.checkTarget(input.ToUserID); err != nil {
+		return nil, err
+	}
+
+	var moved []*domain.DetailAssign
+	var handovers []domain.DetailHandover
+	var movedIDs []uuid.UUID
+	for i := range assign.DetailAssigns {
+		detail := &assign.DetailAssigns[i]
+		if detail.CurrentState() == domain.DetailStateApproved || !containsUUID(detail.Holders(users), *from) {
+			continue
+		}
+		// Owned tasks change owner; shared ones stay shared, the new
+		// engineer holding them in place of the old one
+		if detail.OwnerID != nil && *detail.OwnerID == *from {
+			detail.OwnerID = &input.ToUserID
+			moved = append(moved, detail)
+		}
+		handovers = append(handovers, domain.DetailHandover{
+			ID:             uuid.New(),
+			DetailAssignID: detail.ID,
+			AssignID:       assign.ID,
+			FromUserID:     from,
+			ToUserID:       input.ToUserID,
+			Kind:           domain.HandoverShift,
+			Note:           strings.TrimSpace(input.Note),
+			ActorID:        actor,
+		})
+		movedIDs = append(movedIDs, detail.ID)
+	}
+
+	assign.UserIDs = uuidsJSON(addUUID(removeUUID(users, *from), input.ToUserID))
+	if err := s.repo.Apply(assign, moved, handovers); err != nil {
+		return nil, handoverSaveError(err)
+	}
+	summary := domain.DetailHandover{AssignID: assign.ID, FromUserID: from, ToUserID: input.ToUserID, Kind: domain.HandoverShift, Note: strings.TrimSpace(input.Note)}
+	s.announce(summary, movedIDs, []uuid.UUID{*from})
+	return handovers, nil
+}
+
+// checkTarget refuses handing work to a user who does not exist
+func (s *HandoverService) checkTarget(userID uuid.UUID) error {
+	if user, err := s.userRepo.FindByID(userID); err != nil || user == nil {
+		return handoverInvalid("User not found")
+	}
+	return nil
+}
+
+func handoverSaveError(err error) error {
+	if errors.Is(err, domain.ErrStaleVersion) {
+		return apperrors.NewAppError(apperrors.ErrConflict.Code, "A task was changed by someone else meanwhile, try again", http.StatusConflict)
+	}
+	return err
+}
+
+// announce tells the new holder and the previous ones about the handover, and
+// refreshes task lists
+func (s *HandoverService) announce(h domain.DetailHandover, detailIDs []uuid.UUID, previous []uuid.UUID) {
+	if s.notify != nil {
+		msg, _ := json.Marshal(map[string]interface{}{
+			"event":             "task_" + string(h.Kind),
+			"id_assign":         h.AssignID,
+			"id_detail_assigns": detailIDs,
+			"id_from_user":      h.FromUserID,
+			"id_to_user":        h.ToUserID,
+			"note":              h.Note,
+		})
+		for _, uid := range addUUID(previous, h.ToUserID) {
+			s.notify(uid, msg)
+		}
+	}
+	if s.broadcast != nil {
+		s.broadcast([]byte(`{"event":"task_updated"}`))
+	}
+}
+
+// ownerOf returns the engineer owning the task, or nil when it is shared
+func ownerOf(detail *domain.DetailAssign, users []uuid.UUID) *uuid.UUID {
+	if holders := detail.Holders(users); detail.OwnerID != nil && len(holders) == 1 && holders[0] == *detail.OwnerID {
+		return detail.OwnerID
+	}
+	return nil
+}
+
+// holdsOpenWork reports whether user still holds an unfinished task of the
+// assign once changed replaces its stored copy
+func holdsOpenWork(user uuid.UUID, details []domain.DetailAssign, changed *domain.DetailAssign, users []uuid.UUID) bool {
+	for i := range details {
+		d := &details[i]
+		if d.ID == changed.ID {
+			d = changed
+		}
+		if d.CurrentState() != domain.DetailStateApproved && containsUUID(d.Holders(users), user) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func addUUID(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	if containsUUID(ids, id) {
+		return ids
+	}
+	return append(ids, id)
+}
+
+func removeUUID(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	out := make([]uuid.UUID, 0, len(ids))
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// uuidsJSON encodes users the way Assign.UserIDs stores them
+func uuidsJSON(ids []uuid.UUID) datatypes.JSON {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id.String())
+	}
+	raw, _ := json.Marshal(out)
+	return datatypes.JSON(raw)
+}
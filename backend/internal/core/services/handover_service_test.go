@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// MockAssignRepository implements the lookups of domain.AssignRepository
+type MockAssignRepository struct {
+	domain.AssignRepository
+	Assigns map[uuid.UUID]*domain.Assign
+}
+
+// FindByID shows a scoped caller only the assigns they are a user of
+func (m *MockAssignRepository) FindByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.Assign, error) {
+	if a, ok := m.Assigns[id]; ok && (scope == nil || containsUUID(a.Users(), scope.UserID)) {
+		copied := *a
+		copied.DetailAssigns = append([]domain.DetailAssign(nil), a.DetailAssigns...)
+		return &copied, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+// MockDetailHandoverRepository applies handovers to the assign it was built with
+type MockDetailHandoverRepository struct {
+	Assign    *domain.Assign
+	Handovers []domain.DetailHandover
+}
+
+func (m *MockDetailHandoverRepository) FindByDetail(uuid.UUID) ([]domain.DetailHandover, error) {
+	return m.Handovers, nil
+}
+func (m *MockDetailHandoverRepository) FindByAssign(uuid.UUID) ([]domain.DetailHandover, error) {
+	return m.Handovers, nil
+}
+func (m *MockDetailHandoverRepository) Apply(assign *domain.Assign, details []*domain.DetailAssign, handovers []domain.DetailHandover) error {
+	m.Assign.UserIDs = assign.UserIDs
+	for _, d := range details {
+		for i := range m.Assign.DetailAssigns {
+			if m.Assign.DetailAssigns[i].ID == d.ID {
+				m.Assign.DetailAssigns[i].OwnerID = d.OwnerID
+			}
+		}
+	}
+	m.Handovers = append(m.Handovers, handovers...)
+	return nil
+}
+
+type handoverFixture struct {
+	svc                 *HandoverService
+	repo                *MockDetailHandoverRepository
+	assign              *domain.Assign
+	alice, bob, carol   uuid.UUID
+	taskA, taskB, taskC uuid.UUID
+	notified            map[uuid.UUID]int
+}
+
+// newHandoverFixture builds an assign shared by alice and bob with two open
+// tasks and an approved one
+func newHandoverFixture() *handoverFixture {
+	f := &handoverFixture{alice: uuid.New(), bob: uuid.New(), carol: uuid.New(), notified: map[uuid.UUID]int{}}
+	f.taskA, f.taskB, f.taskC = uuid.New(), uuid.New(), uuid.New()
+	f.assign = &domain.Assign{ID: uuid.New(), UserIDs: uuidsJSON([]uuid.UUID{f.alice, f.bob})}
+	f.assign.DetailAssigns = []domain.DetailAssign{
+		{ID: f.taskA, AssignID: f.assign.ID, State: domain.DetailStateInProgress},
+		{ID: f.taskB, AssignID: f.assign.ID, State: domain.DetailStateDraft},
+		{ID: f.taskC, AssignID: f.assign.ID, State: domain.DetailStateApproved},
+	}
+	details := &MockDetailAssignRepository{Details: map[string]*domain.DetailAssign{}}
+	for i := range f.assign.DetailAssigns {
+		d := f.assign.DetailAssigns[i]
+		details.Details[d.ID.String()] = &d
+	}
+	users := NewMockUserRepository(&domain.User{ID: f.alice}, &domain.User{ID: f.bob}, &domain.User{ID: f.carol})
+	f.repo = &MockDetailHandoverRepository{Assign: f.assign}
+	f.svc = NewHandoverService(f.repo, &MockAssignRepository{Assigns: map[uuid.UUID]*domain.Assign{f.assign.ID: f.assign}},
+		details, users, func(uid uuid.UUID, _ []byte) { f.notified[uid]++ }, nil)
+	return f
+}
+
+func TestReassignKeepsAssignUsersInStep(t *testing.T) {
+	f := newHandoverFixture()
+	manager := uuid.New()
+
+	detail, err := f.svc.Reassign(f.taskA, HandoverInput{ToUserID: f.carol}, &manager, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.OwnerID == nil || *detail.OwnerID != f.carol {
+		t.Fatalf("owner = %v", detail.OwnerID)
+	}
+	// Task B is still shared, so alice and bob stay; carol joins
+	if users := f.assign.Users(); len(users) != 3 || !containsUUID(users, f.carol) {
+		t.Errorf("assign users = %v", users)
+	}
+	if h := f.repo.Handovers; len(h) != 1 || h[0].FromUserID != nil || h[0].Kind != domain.HandoverReassign {
+		t.Errorf("handovers = %+v", h)
+	}
+	if f.notified[f.alice] != 1 || f.notified[f.bob] != 1 || f.notified[f.carol] != 1 {
+		t.Errorf("notified = %v", f.notified)
+	}
+	// Approved work is not moved
+	if _, err := f.svc.Reassign(f.taskC, HandoverInput{ToUserID: f.carol}, &manager, nil); appStatus(err) != http.StatusConflict {
+		t.Errorf("reassigning approved task: err = %v", err)
+	}
+	// Only those who can see the assign read its ownership history
+	if h, err := f.svc.History(f.taskA, &domain.VisibilityScope{UserID: f.carol}); err != nil || len(h) != 1 {
+		t.Errorf("history for carol = %+v, %v", h, err)
+	}
+	stranger := &domain.VisibilityScope{UserID: uuid.New()}
+	if _, err := f.svc.History(f.taskA, stranger); appStatus(err) != http.StatusNotFound {
+		t.Errorf("history of a hidden task: err = %v", err)
+	}
+	if _, err := f.svc.AssignHistory(f.assign.ID, stranger); appStatus(err) != http.StatusNotFound {
+		t.Errorf("handovers of a hidden assign: err = %v", err)
+	}
+}
+
+func TestHandoverNeedsHolderAndNote(t *testing.T) {
+	f := newHandoverFixture()
+	manager := uuid.New()
+	if _, err := f.svc.Reassign(f.taskA, HandoverInput{ToUserID: f.bob}, &manager, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.svc.Handover(f.taskA, HandoverInput{ToUserID: f.carol}, &f.bob, false, nil); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("handover without a note: err = %v", err)
+	}
+	if _, err := f.svc.Handover(f.taskA, HandoverInput{ToUserID: f.carol, Note: "Strings 1-4 cleaned"}, &f.alice, false, nil); appStatus(err) != http.StatusForbidden {
+		t.Errorf("handover by a non-holder: err = %v", err)
+	}
+	if _, err := f.svc.Handover(f.taskA, HandoverInput{ToUserID: f.carol, Note: "Strings 1-4 cleaned"}, &f.bob, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	last := f.repo.Handovers[len(f.repo.Handovers)-1]
+	if last.FromUserID == nil || *last.FromUserID != f.bob || last.ToUserID != f.carol || last.Note != "Strings 1-4 cleaned" {
+		t.Errorf("handover = %+v", last)
+	}
+}
+
+func TestHandoverAssignReplacesEngineer(t *testing.T) {
+	f := newHandoverFixture()
+	manager := uuid.New()
+	if _, err := f.svc.Reassign(f.taskA, HandoverInput{ToUserID: f.alice}, &manager, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.repo.Handovers = nil
+
+	if _, err := f.svc.HandoverAssign(f.assign.ID, AssignHandoverInput{FromUserID: &f.carol, ToUserID: f.bob, Note: "x"}, &manager, true, nil); appStatus(err) != http.StatusBadRequest {
+		t.Errorf("handing over tasks of someone not on the assign: err = %v", err)
+	}
+	if _, err := f.svc.HandoverAssign(f.assign.ID, AssignHandoverInput{FromUserID: &f.bob, ToUserID: f.carol, Note: "x"}, &f.alice, false, nil); appStatus(err) != http.StatusForbidden {
+		t.Errorf("handing over someone else's tasks: err = %v", err)
+	}
+
+	handovers, err := f.svc.HandoverAssign(f.assign.ID, AssignHandoverInput{ToUserID: f.carol, Note: "Rotating to site B"}, &f.alice, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Alice owned A and shared B; the approved task C is left alone
+	if len(handovers) != 2 {
+		t.Fatalf("handovers = %+v", handovers)
+	}
+	if owner := f.assign.DetailAssigns[0].OwnerID; owner == nil || *owner != f.carol {
+		t.Errorf("task A owner = %v", owner)
+	}
+	if users := f.assign.Users(); len(users) != 2 || containsUUID(users, f.alice) || !containsUUID(users, f.carol) {
+		t.Errorf("assign users = %v", users)
+	}
+}
@@ -0,0 +1,566 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/platform/logger"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+)
+
+const (
+	// maintenanceCatchUp is how far back the scheduler still generates
+	// occurrences it missed (e.g. while the server was down)
+	maintenanceCatchUp = 7 * 24 * time.Hour
+	// maintenanceScanLimit bounds the occurrences walked per plan and call
+	maintenanceScanLimit = 10000
+	maxPreviewCount      = 100
+)
+
+// Preview statuses of an occurrence
+const (
+	OccurrenceScheduled = "scheduled"
+	OccurrenceGenerated = "generated"
+	OccurrenceSkipped   = "skipped"
+)
+
+// MaintenancePlanService manages preventive maintenance plans and runs the
+// scheduler that turns their occurrences into assigns.
+type MaintenancePlanService struct {
+	repo         domain.MaintenancePlanRepository
+	projectRepo  domain.ProjectRepository
+	templateRepo domain.TemplateRepository
+	configRepo   domain.ConfigRepository
+	broadcast    BroadcastFunc
+	cronRunner   *cron.Cron
+	now          func() time.Time
+}
+
+func NewMaintenancePlanService(repo domain.MaintenancePlanRepository, projectRepo domain.ProjectRepository, templateRepo domain.TemplateRepository, configRepo domain.ConfigRepository, broadcast BroadcastFunc) *MaintenancePlanService {
+	return &MaintenancePlanService{
+		repo:         repo,
+		projectRepo:  projectRepo,
+		templateRepo: templateRepo,
+		configRepo:   configRepo,
+		broadcast:    broadcast,
+		cronRunner:   cron.New(),
+		now:          time.Now,
+	}
+}
+
+// MaintenancePlanInput is the editable part of a plan
+type MaintenancePlanInput struct {
+	Name           string      `json:"name"`
+	ProjectID      uuid.UUID   `json:"id_project"`
+	ModelProjectID *uuid.UUID  `json:"id_model_project"`
+	TemplateID     *uuid.UUID  `json:"id_template"`
+	ConfigIDs      []uuid.UUID `json:"id_config"`
+	Recurrence     string      `json:"recurrence"`
+	StartDate      time.Time   `json:"start_date"`
+	EndDate        *time.Time  `json:"end_date"`
+	LeadDays       int         `json:"lead_days"`
+	DurationDays   int         `json:"duration_days"`
+	UserIDs        []uuid.UUID `json:"id_user"`
+	NoteAssign     string      `json:"note_assign"`
+}
+
+// PlannedOccurrence is one occurrence of a plan as shown by Preview
+type PlannedOccurrence struct {
+	DueAt      time.Time  `json:"due_at"`
+	GenerateAt time.Time  `json:"generate_at"` // When the scheduler creates its assign
+	Status     string     `json:"status"`
+	AssignID   *uuid.UUID `json:"id_assign,omitempty"`
+}
+
+// Start runs the scheduler hourly, and once right away to catch up
+func (s *MaintenancePlanService) Start() {
+	log := logger.Get()
+	if _, err := s.cronRunner.AddFunc("@hourly", s.runScheduled); err != nil {
+		log.Fatal("Failed to setup maintenance plan cron job", zap.Error(err))
+	}
+	go s.runScheduled()
+	s.cronRunner.Start()
+	log.Info("Maintenance plan scheduler started. Scheduled to run hourly.")
+}
+
+// Stop gracefully stops the scheduler
+func (s *MaintenancePlanService) Stop() {
+	if s.cronRunner != nil {
+		s.cronRunner.Stop()
+	}
+}
+
+func (s *MaintenancePlanService) runScheduled() {
+	created, err := s.RunDue()
+	if err != nil {
+		logger.Get().Error("Maintenance plan scan failed", zap.Error(err))
+		return
+	}
+	if created > 0 {
+		logger.Get().Info("Generated maintenance assigns", zap.Int("count", created))
+	}
+}
+
+func (s *MaintenancePlanService) List(projectID *uuid.UUID, scope *domain.VisibilityScope) ([]domain.MaintenancePlan, error) {
+	return s.repo.FindAll(projectID, scope)
+}
+
+// Get returns a plan of a project the scope may see
+func (s *MaintenancePlanService) Get(id uuid.UUID, scope *domain.VisibilityScope) (*domain.MaintenancePlan, error) {
+	plan, err := s.repo.FindByID(id, scope)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound.Code, "Maintenance plan not found", http.StatusNotFound)
+	}
+	return plan, nil
+}
+
+// checkProject refuses plans for a project the scope may not see, as the
+// scheduler would otherwise generate assigns there
+func (s *MaintenancePlanService) checkProject(projectID uuid.UUID, scope *domain.VisibilityScope) error {
+	if scope == nil || s.projectRepo == nil {
+		return nil
+	}
+	if project, err := s.projectRepo.FindByID(projectID, scope); err != nil || project == nil {
+		return apperrors.NewAppError(apperrors.ErrNotFound.Code, "Project not found", http.StatusNotFound)
+	}
+	return nil
+}
+
+func (s *MaintenancePlanService) Create(input MaintenancePlanInput, creator *uuid.UUID, scope *domain.VisibilityScope) (*domain.MaintenancePlan, error) {
+	if err := s.checkProject(input.ProjectID, scope); err != nil {
+		return nil, err
+	}
+	plan := &domain.MaintenancePlan{ID: uuid.New(), PersonCreatedID: creator}
+	if err := s.apply(plan, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Update replaces the plan's settings. Occurrences already generated or
+// skipped are kept; the new rule only applies to what is still scheduled.
+func (s *MaintenancePlanService) Update(id uuid.UUID, input MaintenancePlanInput, scope *domain.VisibilityScope) (*domain.MaintenancePlan, error) {
+	plan, err := s.Get(id, scope)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkProject(input.ProjectID, scope); err != nil {
+		return nil, err
+	}
+	if err := s.apply(plan, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (s *MaintenancePlanService) Delete(id uuid.UUID, scope *domain.VisibilityScope) error {
+	if _, err := s.Get(id, scope); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// SetPaused pauses or resumes a plan. Occurrences that fall due while a plan
+// is paused are not generated on resume, beyond the usual catch-up window.
+func (s *MaintenancePlanService) SetPaused(id uuid.UUID, paused bool, scope *domain.VisibilityScope) (*domain.MaintenancePlan, error) {
+	plan, err := s.Get(id, scope)
+	if err != nil {
+		return nil, err
+	}
+	plan.Paused = paused
+	if err := s.repo.Update(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Preview lists the next count occurrences of a plan from now, with what has
+// already happened to them.
+func (s *MaintenancePlanService) Preview(id uuid.UUID, count int, scope *domain.VisibilityScope) ([]PlannedOccurrence, error) {
+	plan, err := s.Get(id, scope)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		count = 10
+	}
+	if count > maxPreviewCount {
+		count = maxPreviewCount
+	}
+	rule, err := parseRecurrence(plan.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	var dues []time.Time
+	rule.each(plan, s.now(), func(due time.Time) bool {
+		dues = append(dues, due)
+		return len(dues) < count
+	})
+	planned := make([]PlannedOccurrence, 0, len(dues))
+	if len(dues) == 0 {
+		return planned, nil
+	}
+	recorded, err := s.recorded(plan.ID, dues[0], dues[len(dues)-1])
+	if err != nil {
+		return nil, err
+	}
+	for _, due := range dues {
+		p := PlannedOccurrence{DueAt: due, GenerateAt: due.AddDate(0, 0, -plan.LeadDays), Status: OccurrenceScheduled}
+		if occ, ok := recorded[due.Unix()]; ok {
+			p.Status, p.AssignID = OccurrenceGenerated, occ.AssignID
+			if occ.Skipped {
+				p.Status = OccurrenceSkipped
+			}
+		}
+		planned = append(planned, p)
+	}
+	return planned, nil
+}
+
+// Skip cancels one upcoming occurrence so no assign is generated for it
+func (s *MaintenancePlanService) Skip(id uuid.UUID, dueAt time.Time, scope *domain.VisibilityScope) error {
+	plan, err := s.Get(id, scope)
+	if err != nil {
+		return err
+	}
+	rule, err := parseRecurrence(plan.Recurrence)
+	if err != nil {
+		return err
+	}
+	matches := false
+	rule.each(plan, dueAt, func(due time.Time) bool {
+		matches = due.Equal(dueAt)
+		return false
+	})
+	if !matches {
+		return apperrors.NewAppError(apperrors.ErrValidation.Code, "due_at is not an occurrence of this plan", http.StatusBadRequest)
+	}
+	skipped, err := s.repo.Skip(plan.ID, dueAt)
+	if err != nil {
+		return err
+	}
+	if !skipped {
+		return apperrors.NewAppError(apperrors.ErrConflict.Code, "Occurrence was already generated or skipped", http.StatusConflict)
+	}
+	return nil
+}
+
+// RunDue generates the assigns of every active plan whose occurrences are
+// within their lead time, and returns how many it created. Running it again
+// creates nothing new: each occurrence is recorded once.
+func (s *MaintenancePlanService) RunDue() (int, error) {
+	plans, err := s.repo.FindActive()
+	if err != nil {
+		return 0, err
+	}
+	now := s.now()
+	created := 0
+	for i := range plans {
+		plan := &plans[i]
+		n, err := s.generate(plan, now)
+		created += n
+		if err != nil {
+			logger.Get().Error("Failed to generate maintenance assigns",
+				zap.String("plan_id", plan.ID.String()), zap.Error(err))
+		}
+	}
+	if created > 0 && s.broadcast != nil {
+		msg, _ := json.Marshal(map[string]interface{}{"event": "assign_created", "count": created})
+		s.broadcast(msg)
+	}
+	return created, nil
+}
+
+func (s *MaintenancePlanService) generate(plan *domain.MaintenancePlan, now time.Time) (int, error) {
+	rule, err := parseRecurrence(plan.Recurrence)
+	if err != nil {
+		return 0, err
+	}
+	horizon := now.AddDate(0, 0, plan.LeadDays)
+	var dues []time.Time
+	rule.each(plan, now.Add(-maintenanceCatchUp), func(due time.Time) bool {
+		if due.After(horizon) {
+			return false
+		}
+		dues = append(dues, due)
+		return true
+	})
+	if len(dues) == 0 {
+		return 0, nil
+	}
+	recorded, err := s.recorded(plan.ID, dues[0], dues[len(dues)-1])
+	if err != nil {
+		return 0, err
+	}
+
+	configIDs := s.planConfigIDs(plan)
+	created := 0
+	for _, due := range dues {
+		if _, ok := recorded[due.Unix()]; ok {
+			continue
+		}
+		start, end := due, due.AddDate(0, 0, plan.DurationDays)
+		note := plan.NoteAssign
+		if note == "" {
+			note = plan.Name
+		}
+		assign := &domain.Assign{
+			ID:             uuid.New(),
+			ProjectID:      plan.ProjectID,
+			ModelProjectID: plan.ModelProjectID,
+			TemplateID:     plan.TemplateID,
+			UserIDs:        plan.UserIDs,
+			StartTime:      &start,
+			EndTime:        &end,
+			NoteAssign:     note,
+		}
+		details := BuildDetailAssigns(s.configRepo, assign.ID, configIDs)
+		ok, err := s.repo.Materialise(plan.ID, due, assign, details)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// planConfigIDs returns the configs to generate: the template's current ones,
+// so edits to the template carry over, else the plan's own set
+func (s *MaintenancePlanService) planConfigIDs(plan *domain.MaintenancePlan) []uuid.UUID {
+	raw := plan.ConfigIDs
+	if plan.Template != nil {
+		raw = plan.Template.ConfigIDs
+	}
+	var ids []string
+	_ = json.Unmarshal(raw, &ids)
+	configIDs := make([]uuid.UUID, 0, len(ids))
+	for _, s := range ids {
+		if id, err := uuid.Parse(s); err == nil {
+			configIDs = append(configIDs, id)
+		}
+	}
+	return configIDs
+}
+
+// recorded returns the occurrences of a plan due in [from, to] by due time
+func (s *MaintenancePlanService) recorded(planID uuid.UUID, from, to time.Time) (map[int64]domain.MaintenanceOccurrence, error) {
+	occurrences, err := s.repo.FindOccurrences(planID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byDue := make(map[int64]domain.MaintenanceOccurrence, len(occurrences))
+	for _, occ := range occurrences {
+		byDue[occ.DueAt.Unix()] = occ
+	}
+	return byDue, nil
+}
+
+func (s *MaintenancePlanService) apply(plan *domain.MaintenancePlan, input MaintenancePlanInput) error {
+	invalid := func(msg string) error {
+		return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	input.Recurrence = strings.TrimSpace(input.Recurrence)
+	switch {
+	case input.Name == "":
+		return invalid("name is required")
+	case input.ProjectID == uuid.Nil:
+		return invalid("id_project is required")
+	case input.StartDate.IsZero():
+		return invalid("start_date is required")
+	case input.EndDate != nil && input.EndDate.Before(input.StartDate):
+		return invalid("end_date must not be before start_date")
+	case input.LeadDays < 0:
+		return invalid("lead_days must not be negative")
+	case input.DurationDays < 0:
+		return invalid("duration_days must not be negative")
+	case input.TemplateID == nil && len(input.ConfigIDs) == 0:
+		return invalid("id_template or id_config is required")
+	}
+	if _, err := parseRecurrence(input.Recurrence); err != nil {
+		return err
+	}
+	if input.TemplateID != nil {
+		tmpl, err := s.templateRepo.FindByID(*input.TemplateID)
+		if err != nil || tmpl == nil {
+			return invalid("Template not found")
+		}
+		if tmpl.ProjectID != input.ProjectID {
+			return invalid("Template belongs to another project")
+		}
+	}
+	if input.DurationDays == 0 {
+		input.DurationDays = 1
+	}
+
+	configIDs := make([]string, 0, len(input.ConfigIDs))
+	for _, id := range input.ConfigIDs {
+		configIDs = append(configIDs, id.String())
+	}
+	userIDs := make([]string, 0, len(input.UserIDs))
+	for _, id := range input.UserIDs {
+		userIDs = append(userIDs, id.String())
+	}
+	configJSON, _ := json.Marshal(configIDs)
+	userJSON, _ := json.Marshal(userIDs)
+
+	plan.Name = input.Name
+	plan.ProjectID = input.ProjectID
+	plan.ModelProjectID = input.ModelProjectID
+	plan.TemplateID = input.TemplateID
+	plan.Template = nil
+	plan.Project = nil
+	plan.ConfigIDs = datatypes.JSON(configJSON)
+	plan.Recurrence = input.Recurrence
+	plan.StartDate = input.StartDate
+	plan.EndDate = input.EndDate
+	plan.LeadDays = input.LeadDays
+	plan.DurationDays = input.DurationDays
+	plan.UserIDs = datatypes.JSON(userJSON)
+	plan.NoteAssign = input.NoteAssign
+	return nil
+}
+
+// recurrence yields the due times of a plan. Two forms are accepted: a
+// subset of RFC 5545 RRULE (FREQ, INTERVAL, COUNT) whose occurrences are
+// counted from the plan's start date, or a standard 5-field cron expression.
+type recurrence struct {
+	freq     string // DAILY, WEEKLY, MONTHLY or YEARLY; "" for cron
+	interval int
+	count    int // 0 = unbounded
+	schedule cron.Schedule
+}
+
+func parseRecurrence(expr string) (*recurrence, error) {
+	invalid := func(msg string) error {
+		return apperrors.NewAppError(apperrors.ErrValidation.Code, "Invalid recurrence: "+msg, http.StatusBadRequest)
+	}
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, invalid("recurrence is required")
+	}
+	upper := strings.ToUpper(expr)
+	if !strings.HasPrefix(upper, "RRULE:") && !strings.HasPrefix(upper, "FREQ=") {
+		schedule, err := cron.ParseStandard(expr)
+		if err != nil {
+			return nil, invalid(err.Error())
+		}
+		return &recurrence{schedule: schedule}, nil
+	}
+
+	r := &recurrence{interval: 1}
+	for _, part := range strings.Split(strings.TrimPrefix(upper, "RRULE:"), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, invalid(fmt.Sprintf("%q is not KEY=VALUE", part))
+		}
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = value
+			default:
+				return nil, invalid("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL", "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, invalid(key + " must be a positive number")
+			}
+			if key == "INTERVAL" {
+				r.interval = n
+			} else {
+				r.count = n
+			}
+		default:
+			return nil, invalid(key + " is not supported")
+		}
+	}
+	if r.freq == "" {
+		return nil, invalid("FREQ is required")
+	}
+	return r, nil
+}
+
+// nth returns the n-th (0-based) occurrence of an RRULE from start
+func (r *recurrence) nth(start time.Time, n int) time.Time {
+	step := n * r.interval
+	switch r.freq {
+	case "DAILY":
+		return start.AddDate(0, 0, step)
+	case "WEEKLY":
+		return start.AddDate(0, 0, 7*step)
+	case "MONTHLY":
+		return start.AddDate(0, step, 0)
+	default:
+		return start.AddDate(step, 0, 0)
+	}
+}
+
+// skipTo returns an occurrence index at or before the first one after from,
+// so long-running plans need not be walked from their start
+func (r *recurrence) skipTo(start, from time.Time) int {
+	longest := map[string]int{"DAILY": 1, "WEEKLY": 7, "MONTHLY": 31, "YEARLY": 366}[r.freq]
+	period := time.Duration(longest*r.interval) * 24 * time.Hour
+	n := int(from.Sub(start)/period) - 1
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// each calls fn with the plan's occurrences at or after from, in order, until
+// fn returns false or the plan ends.
+func (r *recurrence) each(plan *domain.MaintenancePlan, from time.Time, fn func(time.Time) bool) {
+	if from.Before(plan.StartDate) {
+		from = plan.StartDate
+	}
+	ended := func(t time.Time) bool { return plan.EndDate != nil && t.After(*plan.EndDate) }
+
+	if r.schedule != nil {
+		// Next is strictly after its argument
+		due := r.schedule.Next(from.Add(-time.Second))
+		for i := 0; i < maintenanceScanLimit && !due.IsZero() && !ended(due); i++ {
+			if !fn(due) {
+				return
+			}
+			due = r.schedule.Next(due)
+		}
+		return
+	}
+
+	first := r.skipTo(plan.StartDate, from)
+	for n := first; n < first+maintenanceScanLimit && (r.count == 0 || n < r.count); n++ {
+		due := r.nth(plan.StartDate, n)
+		if ended(due) {
+			return
+		}
+		if due.Before(from) {
+			continue
+		}
+		if !fn(due) {
+			return
+		}
+	}
+}
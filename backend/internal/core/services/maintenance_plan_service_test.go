@@ -0,0 +1,294 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/datatypes"
+)
+
+// MockMaintenancePlanRepository implements domain.MaintenancePlanRepository in memory
+type MockMaintenancePlanRepository struct {
+	Plans       map[uuid.UUID]*domain.MaintenancePlan
+	Occurrences []domain.MaintenanceOccurrence
+	Assigns     []domain.Assign
+	Details     []domain.DetailAssign
+	Visible     map[uuid.UUID]bool // Projects a scoped caller may see
+}
+
+// mockProjectRepository implements the project lookup of domain.ProjectRepository
+type mockProjectRepository struct {
+	domain.ProjectRepository
+	Visible map[uuid.UUID]bool // Projects a scoped caller may see
+}
+
+func (m mockProjectRepository) FindByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.Project, error) {
+	if scope != nil && !m.Visible[id] {
+		return nil, errors.New("record not found")
+	}
+	return &domain.Project{ID: id}, nil
+}
+
+func (m *MockMaintenancePlanRepository) Create(plan *domain.MaintenancePlan) error {
+	m.Plans[plan.ID] = plan
+	return nil
+}
+func (m *MockMaintenancePlanRepository) FindAll(projectID *uuid.UUID, _ *domain.VisibilityScope) ([]domain.MaintenancePlan, error) {
+	var out []domain.MaintenancePlan
+	for _, p := range m.Plans {
+		if projectID == nil || p.ProjectID == *projectID {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
+func (m *MockMaintenancePlanRepository) FindByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.MaintenancePlan, error) {
+	plan := m.Plans[id]
+	if plan == nil || (scope != nil && !m.Visible[plan.ProjectID]) {
+		return nil, nil
+	}
+	return plan, nil
+}
+func (m *MockMaintenancePlanRepository) FindActive() ([]domain.MaintenancePlan, error) {
+	var out []domain.MaintenancePlan
+	for _, p := range m.Plans {
+		if !p.Paused {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
+func (m *MockMaintenancePlanRepository) Update(plan *domain.MaintenancePlan) error {
+	m.Plans[plan.ID] = plan
+	return nil
+}
+func (m *MockMaintenancePlanRepository) Delete(id uuid.UUID) error {
+	delete(m.Plans, id)
+	return nil
+}
+func (m *MockMaintenancePlanRepository) FindOccurrences(planID uuid.UUID, from, to time.Time) ([]domain.MaintenanceOccurrence, error) {
+	var out []domain.MaintenanceOccurrence
+	for _, o := range m.Occurrences {
+		if o.PlanID == planID && !o.DueAt.Before(from) && !o.DueAt.After(to) {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+func (m *MockMaintenancePlanRepository) recorded(planID uuid.UUID, dueAt time.Time) bool {
+	for _, o := range m.Occurrences {
+		if o.PlanID == planID && o.DueAt.Equal(dueAt) {
+			return true
+		}
+	}
+	return false
+}
+func (m *MockMaintenancePlanRepository) Skip(planID uuid.UUID, dueAt time.Time) (bool, error) {
+	if m.recorded(planID, dueAt) {
+		return false, nil
+	}
+	m.Occurrences = append(m.Occurrences, domain.MaintenanceOccurrence{ID: uuid.New(), PlanID: planID, DueAt: dueAt, Skipped: true})
+	return true, nil
+}
+func (m *MockMaintenancePlanRepository) Materialise(planID uuid.UUID, dueAt time.Time, assign *domain.Assign, details []domain.DetailAssign) (bool, error) {
+	if m.recorded(planID, dueAt) {
+		return false, nil
+	}
+	m.Assigns = append(m.Assigns, *assign)
+	m.Details = append(m.Details, details...)
+	m.Occurrences = append(m.Occurrences, domain.MaintenanceOccurrence{ID: uuid.New(), PlanID: planID, DueAt: dueAt, AssignID: &assign.ID})
+	return true, nil
+}
+
+// MockConfigRepository implements domain.ConfigRepository for detail generation
+type MockConfigRepository struct {
+	Configs map[uuid.UUID]*domain.Config
+}
+
+func (m *MockConfigRepository) Create(config *domain.Config) error                 { return nil }
+func (m *MockConfigRepository) FindAll() ([]domain.Config, error)                  { return nil, nil }
+func (m *MockConfigRepository) FindByProjectID(uuid.UUID) ([]domain.Config, error) { return nil, nil }
+func (m *MockConfigRepository) Update(config *domain.Config) error                 { return nil }
+func (m *MockConfigRepository) Delete(id uuid.UUID) error                          { return nil }
+func (m *MockConfigRepository) FindByAssetID(assetID uuid.UUID) ([]domain.Config, error) {
+	var configs []domain.Config
+	for _, cfg := range m.Configs {
+		if cfg.AssetID == assetID {
+			configs = append(configs, *cfg)
+		}
+	}
+	return configs, nil
+}
+func (m *MockConfigRepository) FindByID(id uuid.UUID) (*domain.Config, error) {
+	if cfg, ok := m.Configs[id]; ok {
+		return cfg, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	start := time.Date(2026, 1, 31, 7, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	plan := &domain.MaintenancePlan{StartDate: start, EndDate: &end}
+
+	collect := func(expr string, from time.Time, limit int) []time.Time {
+		rule, err := parseRecurrence(expr)
+		if err != nil {
+			t.Fatalf("parseRecurrence(%q): %v", expr, err)
+		}
+		var dues []time.Time
+		rule.each(plan, from, func(due time.Time) bool {
+			dues = append(dues, due)
+			return len(dues) < limit
+		})
+		return dues
+	}
+
+	// Quarterly, counted from the start date
+	dues := collect("RRULE:FREQ=MONTHLY;INTERVAL=3", start, 10)
+	if len(dues) != 4 || !dues[1].Equal(start.AddDate(0, 3, 0)) {
+		t.Fatalf("quarterly occurrences = %v", dues)
+	}
+	// COUNT bounds the series; from skips the ones already past
+	dues = collect("FREQ=WEEKLY;COUNT=3", start.AddDate(0, 0, 1), 10)
+	if len(dues) != 2 || !dues[0].Equal(start.AddDate(0, 0, 7)) {
+		t.Fatalf("weekly occurrences = %v", dues)
+	}
+	// Cron: 07:00 on the 1st of each month, from the start date on
+	dues = collect("0 7 1 * *", start, 2)
+	if len(dues) != 2 || !dues[0].Equal(time.Date(2026, 2, 1, 7, 0, 0, 0, time.UTC)) {
+		t.Fatalf("cron occurrences = %v", dues)
+	}
+
+	for _, bad := range []string{"", "RRULE:FREQ=HOURLY", "FREQ=DAILY;INTERVAL=0", "RRULE:BYDAY=MO", "not a cron"} {
+		if _, err := parseRecurrence(bad); err == nil {
+			t.Errorf("parseRecurrence(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRunDueGeneratesOccurrencesOnce(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	cfgID := uuid.New()
+	procA, procB := uuid.New(), uuid.New()
+	configs := &MockConfigRepository{Configs: map[uuid.UUID]*domain.Config{
+		cfgID: {ID: cfgID, SubWork: &domain.SubWork{ProcessIDs: datatypes.JSON(`["` + procA.String() + `","` + procB.String() + `"]`)}},
+	}}
+	repo := &MockMaintenancePlanRepository{Plans: map[uuid.UUID]*domain.MaintenancePlan{}}
+	svc := NewMaintenancePlanService(repo, nil, nil, configs, nil)
+	svc.now = func() time.Time { return now }
+
+	// Weekly from 02-24, generated 3 days ahead: on 03-10 the window holds 03-03
+	// (caught up, but skipped below) and 03-10; 03-17 is beyond the lead time
+	plan, err := svc.Create(MaintenancePlanInput{
+		Name:       "Module cleaning",
+		ProjectID:  uuid.New(),
+		ConfigIDs:  []uuid.UUID{cfgID},
+		Recurrence: "RRULE:FREQ=WEEKLY",
+		StartDate:  time.Date(2026, 2, 24, 7, 0, 0, 0, time.UTC),
+		LeadDays:   3,
+		UserIDs:    []uuid.UUID{uuid.New()},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if plan.DurationDays != 1 {
+		t.Errorf("DurationDays defaults to 1, got %d", plan.DurationDays)
+	}
+
+	// Skip the occurrence of 03-03 before the scheduler runs
+	if err := svc.Skip(plan.ID, time.Date(2026, 3, 3, 7, 0, 0, 0, time.UTC), nil); err != nil {
+		t.Fatalf("Skip: %v", err)
+	}
+	err = svc.Skip(plan.ID, time.Date(2026, 3, 4, 7, 0, 0, 0, time.UTC), nil)
+	if appErr, ok := err.(*apperrors.AppError); !ok || appErr.Status != http.StatusBadRequest {
+		t.Errorf("skipping a non-occurrence should be a validation error, got %v", err)
+	}
+
+	created, err := svc.RunDue()
+	if err != nil || created != 1 {
+		t.Fatalf("RunDue = %d, %v; want 1", created, err)
+	}
+	assign := repo.Assigns[0]
+	if !assign.StartTime.Equal(time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC)) || !assign.EndTime.Equal(assign.StartTime.AddDate(0, 0, 1)) {
+		t.Errorf("assign window = %v - %v", assign.StartTime, assign.EndTime)
+	}
+	if assign.NoteAssign != "Module cleaning" || string(assign.UserIDs) != string(plan.UserIDs) {
+		t.Errorf("assign = %+v", assign)
+	}
+	if len(repo.Details) != 2 || *repo.Details[0].ProcessID != procA || repo.Details[0].AssignID != assign.ID {
+		t.Errorf("details = %+v", repo.Details)
+	}
+
+	// A second run is a no-op
+	if created, _ := svc.RunDue(); created != 0 {
+		t.Errorf("second RunDue created %d", created)
+	}
+	err = svc.Skip(plan.ID, time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC), nil)
+	if appErr, ok := err.(*apperrors.AppError); !ok || appErr.Status != http.StatusConflict {
+		t.Errorf("skipping a generated occurrence should conflict, got %v", err)
+	}
+
+	// Paused plans are not generated
+	now = now.AddDate(0, 0, 7)
+	if _, err := svc.SetPaused(plan.ID, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if created, _ := svc.RunDue(); created != 0 {
+		t.Errorf("paused plan generated %d", created)
+	}
+
+	preview, err := svc.Preview(plan.ID, 2, nil)
+	if err != nil || len(preview) != 2 {
+		t.Fatalf("Preview = %v, %v", preview, err)
+	}
+	if preview[0].Status != OccurrenceScheduled || !preview[0].GenerateAt.Equal(preview[0].DueAt.AddDate(0, 0, -3)) {
+		t.Errorf("preview = %+v", preview[0])
+	}
+}
+
+func TestPlanWritesOutsideScopeNotFound(t *testing.T) {
+	cfgID, mine, theirs := uuid.New(), uuid.New(), uuid.New()
+	configs := &MockConfigRepository{Configs: map[uuid.UUID]*domain.Config{cfgID: {ID: cfgID, SubWork: &domain.SubWork{}}}}
+	visible := map[uuid.UUID]bool{mine: true}
+	repo := &MockMaintenancePlanRepository{Plans: map[uuid.UUID]*domain.MaintenancePlan{}, Visible: visible}
+	svc := NewMaintenancePlanService(repo, mockProjectRepository{Visible: visible}, nil, configs, nil)
+	scope := &domain.VisibilityScope{UserID: uuid.New(), Kind: domain.VisibilityMember}
+	input := MaintenancePlanInput{
+		Name:       "Module cleaning",
+		ProjectID:  theirs,
+		ConfigIDs:  []uuid.UUID{cfgID},
+		Recurrence: "RRULE:FREQ=WEEKLY",
+		StartDate:  time.Date(2026, 2, 24, 7, 0, 0, 0, time.UTC),
+		UserIDs:    []uuid.UUID{uuid.New()},
+	}
+
+	if _, err := svc.Create(input, nil, scope); appStatus(err) != http.StatusNotFound {
+		t.Fatalf("creating a plan in another project should be not found, got %v", err)
+	}
+	foreign, err := svc.Create(input, nil, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := svc.SetPaused(foreign.ID, true, scope); appStatus(err) != http.StatusNotFound {
+		t.Errorf("pausing a hidden plan should be not found, got %v", err)
+	}
+	if err := svc.Delete(foreign.ID, scope); appStatus(err) != http.StatusNotFound || repo.Plans[foreign.ID] == nil {
+		t.Errorf("deleting a hidden plan should be not found, got %v", err)
+	}
+
+	input.ProjectID = mine
+	own, err := svc.Create(input, nil, scope)
+	if err != nil {
+		t.Fatalf("Create in a visible project: %v", err)
+	}
+	input.ProjectID = theirs
+	if _, err := svc.Update(own.ID, input, scope); appStatus(err) != http.StatusNotFound {
+		t.Errorf("moving a plan into another project should be not found, got %v", err)
+	}
+}
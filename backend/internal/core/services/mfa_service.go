@@ -0,0 +1,282 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/config"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Issuer label shown by authenticator apps
+const mfaIssuer = "Raitek O&M"
+
+const recoveryCodeCount = 10
+
+var (
+	ErrMFACodeInvalid     = errors.New("two-factor code is invalid")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor login is already enabled")
+	ErrMFANotEnrolling    = errors.New("two-factor enrolment has not been started")
+	ErrMFANotEnabled      = errors.New("two-factor login is not enabled")
+	ErrMFARequiredByRole  = errors.New("two-factor login is required for this role")
+	ErrMFAPasswordInvalid = errors.New("password is incorrect")
+	ErrMFAUserNotFound    = errors.New("user not found")
+)
+
+// MFAStatus describes a user's two-factor enrolment
+type MFAStatus struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"` // Enforced by the user's role
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// MFAEnrollment is returned when enrolment starts; the URI is rendered as a QR code
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// MFAService manages TOTP enrolment, code verification and recovery codes.
+// TOTP secrets are encrypted at rest with AES-GCM.
+type MFAService struct {
+	userRepo domain.UserRepository
+	codes    domain.RecoveryCodeRepository
+	aead     cipher.AEAD
+	now      func() time.Time
+}
+
+func NewMFAService(userRepo domain.UserRepository, codes domain.RecoveryCodeRepository, cfg config.AuthConfig) *MFAService {
+	key := cfg.MFASecretKey
+	if key == "" {
+		key = cfg.JWTSecret
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		panic(err) // unreachable: a SHA-256 digest is always a valid AES-256 key
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &MFAService{userRepo: userRepo, codes: codes, aead: aead, now: time.Now}
+}
+
+// Required reports whether the user's role enforces two-factor login
+func (s *MFAService) Required(user *domain.User) bool {
+	return user.RoleModel != nil && user.RoleModel.RequireMFA
+}
+
+func (s *MFAService) Status(userID uuid.UUID) (*MFAStatus, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &MFAStatus{Enabled: user.TOTPEnabled, Required: s.Required(user)}
+	if user.TOTPEnabled {
+		if status.RecoveryCodesRemaining, err = s.codes.CountUnused(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginEnrollment generates a new pending secret. It only becomes active once
+// ConfirmEnrollment sees a valid code, so an abandoned enrolment changes nothing.
+func (s *MFAService) BeginEnrollment(userID uuid.UUID) (*MFAEnrollment, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetTOTP(userID, sealed, false); err != nil {
+		return nil, err
+	}
+	return &MFAEnrollment{Secret: secret, URI: totpURI(mfaIssuer, user.Email, secret)}, nil
+}
+
+// ConfirmEnrollment enables two-factor login once the user proves the
+// authenticator works, and returns a fresh set of recovery codes.
+func (s *MFAService) ConfirmEnrollment(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolling
+	}
+	secret, err := s.open(user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := matchTOTP(secret, code, s.now())
+	if !ok {
+		return nil, ErrMFACodeInvalid
+	}
+	if err := s.userRepo.SetTOTP(userID, user.TOTPSecret, true); err != nil {
+		return nil, err
+	}
+	if _, err := s.userRepo.ClaimTOTPStep(userID, step); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(userID)
+}
+
+// Verify checks a login code: a TOTP code from the authenticator or an unused
+// recovery code. Each TOTP step and each recovery code is accepted only once.
+func (s *MFAService) Verify(user *domain.User, code string) error {
+	if !user.TOTPEnabled || user.TOTPSecret == "" {
+		return ErrMFANotEnabled
+	}
+	secret, err := s.open(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	if step, ok := matchTOTP(secret, code, s.now()); ok {
+		claimed, err := s.userRepo.ClaimTOTPStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return ErrMFACodeInvalid
+		}
+		return nil
+	}
+
+	used, err := s.codes.Consume(user.ID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrMFACodeInvalid
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after a valid TOTP or recovery code
+func (s *MFAService) RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Verify(user, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(userID)
+}
+
+// Disable turns two-factor login off after re-checking the password and a code.
+// Users whose role enforces 2FA cannot opt out.
+func (s *MFAService) Disable(userID uuid.UUID, password, code string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if s.Required(user) {
+		return ErrMFARequiredByRole
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return ErrMFAPasswordInvalid
+	}
+	if err := s.Verify(user, code); err != nil {
+		return err
+	}
+	return s.clearEnrolment(userID)
+}
+
+// Reset removes the enrolment and recovery codes (admin action for a lost device).
+// Users of a role that enforces 2FA enrol again at their next login. Only an
+// admin (callerRole) may reset another admin.
+func (s *MFAService) Reset(userID uuid.UUID, callerRole string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.IsAdmin() && domain.UserRole(callerRole) != domain.RoleAdmin {
+		return ErrAdminOnly
+	}
+	return s.clearEnrolment(userID)
+}
+
+func (s *MFAService) clearEnrolment(userID uuid.UUID) error {
+	if err := s.userRepo.SetTOTP(userID, "", false); err != nil {
+		return err
+	}
+	return s.codes.Replace(userID, nil)
+}
+
+func (s *MFAService) issueRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	plain := make([]string, recoveryCodeCount)
+	rows := make([]domain.RecoveryCode, recoveryCodeCount)
+	for i := range plain {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(b)) // 8 characters
+		plain[i] = code[:4] + "-" + code[4:]
+		rows[i] = domain.RecoveryCode{UserID: userID, CodeHash: hashToken(code)}
+	}
+	if err := s.codes.Replace(userID, rows); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+// normalizeRecoveryCode accepts codes typed with or without the dash and in any case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func (s *MFAService) findUser(userID uuid.UUID) (*domain.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrMFAUserNotFound
+	}
+	return user, nil
+}
+
+func (s *MFAService) seal(secret string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func (s *MFAService) open(sealed string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < s.aead.NonceSize() {
+		return "", errors.New("stored two-factor secret is corrupt")
+	}
+	plain, err := s.aead.Open(nil, raw[:s.aead.NonceSize()], raw[s.aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("stored two-factor secret cannot be decrypted")
+	}
+	return string(plain), nil
+}
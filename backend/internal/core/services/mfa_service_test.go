@@ -0,0 +1,176 @@
+package services
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// MockRecoveryCodeRepository implements domain.RecoveryCodeRepository in memory
+type MockRecoveryCodeRepository struct {
+	Codes []domain.RecoveryCode
+}
+
+func (m *MockRecoveryCodeRepository) Replace(userID uuid.UUID, codes []domain.RecoveryCode) error {
+	kept := m.Codes[:0]
+	for _, c := range m.Codes {
+		if c.UserID != userID {
+			kept = append(kept, c)
+		}
+	}
+	m.Codes = append(kept, codes...)
+	return nil
+}
+func (m *MockRecoveryCodeRepository) Consume(userID uuid.UUID, codeHash string) (bool, error) {
+	for i := range m.Codes {
+		c := &m.Codes[i]
+		if c.UserID == userID && c.CodeHash == codeHash && c.UsedAt == nil {
+			now := time.Now()
+			c.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+func (m *MockRecoveryCodeRepository) CountUnused(userID uuid.UUID) (int64, error) {
+	var n int64
+	for _, c := range m.Codes {
+		if c.UserID == userID && c.UsedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestTOTPMatchesRFC6238Vectors(t *testing.T) {
+	// RFC 6238 Appendix B, SHA-1 seed; the 6-digit codes are the last 6 digits of the 8-digit vectors
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := totpCode(secret, totpStep(time.Unix(unix, 0)))
+		if err != nil || got != want {
+			t.Errorf("T=%d: expected %s, got %s (%v)", unix, want, got, err)
+		}
+	}
+
+	now := time.Unix(1111111111, 0)
+	previous, _ := totpCode(secret, totpStep(now)-1)
+	if _, ok := matchTOTP(secret, previous, now); !ok {
+		t.Error("Expected the previous step to be accepted for clock drift")
+	}
+	stale, _ := totpCode(secret, totpStep(now)-3)
+	if _, ok := matchTOTP(secret, stale, now); ok {
+		t.Error("Expected a code three steps old to be rejected")
+	}
+}
+
+// newMFATestAuth wires AuthService with in-memory repositories for a user of a role requiring 2FA
+func newMFATestAuth(t *testing.T) (*AuthService, *MFAService, *domain.User) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	user := newTestUser(t, "correct-pass1")
+	user.RoleModel = &domain.Role{Name: string(domain.RoleManager), RequireMFA: true}
+	users := NewMockUserRepository(user)
+	mfa := NewMFAService(users, &MockRecoveryCodeRepository{}, testAuthConfig)
+	auth := NewAuthService(users, nil, NewSessionService(NewMockSessionRepository(), testAuthConfig), mfa, testAuthConfig)
+	return auth, mfa, user
+}
+
+func currentCode(t *testing.T, enrollment *MFAEnrollment, at time.Time) string {
+	code, err := totpCode(enrollment.Secret, totpStep(at))
+	if err != nil {
+		t.Fatalf("Failed to compute TOTP: %v", err)
+	}
+	return code
+}
+
+func TestTwoStepLoginWithEnrolment(t *testing.T) {
+	auth, mfa, user := newMFATestAuth(t)
+	now := time.Now()
+	mfa.now = func() time.Time { return now }
+
+	// Role requires 2FA and the user has not enrolled: the password step yields an enrolment challenge
+	tokens, _, err := auth.Login(user.Email, "correct-pass1", domain.DeviceInfo{})
+	challenge, ok := err.(*MFARequiredError)
+	if tokens != nil || !ok || !challenge.Enroll {
+		t.Fatalf("Expected an enrolment challenge instead of tokens, got %v", err)
+	}
+	if _, err := auth.VerifyToken(challenge.Challenge); err == nil {
+		t.Fatal("Expected the challenge to be rejected as an access token")
+	}
+
+	enrollment, err := auth.BeginMFAEnrollment(challenge.Challenge)
+	if err != nil {
+		t.Fatalf("BeginMFAEnrollment failed: %v", err)
+	}
+	if user.TOTPSecret == "" || user.TOTPSecret == enrollment.Secret {
+		t.Error("Expected the secret to be stored encrypted")
+	}
+
+	code := currentCode(t, enrollment, now)
+	tokens, _, recoveryCodes, err := auth.VerifyMFA(challenge.Challenge, code, domain.DeviceInfo{})
+	if err != nil || tokens == nil {
+		t.Fatalf("VerifyMFA failed: %v", err)
+	}
+	if !user.TOTPEnabled || len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("Expected 2FA enabled with %d recovery codes, got %v / %d", recoveryCodeCount, user.TOTPEnabled, len(recoveryCodes))
+	}
+
+	// Next login: a normal challenge, and the already used code cannot be replayed
+	_, _, err = auth.Login(user.Email, "correct-pass1", domain.DeviceInfo{})
+	challenge, ok = err.(*MFARequiredError)
+	if !ok || challenge.Enroll {
+		t.Fatalf("Expected a verification challenge, got %v", err)
+	}
+	if _, _, _, err := auth.VerifyMFA(challenge.Challenge, code, domain.DeviceInfo{}); err != ErrMFACodeInvalid {
+		t.Errorf("Expected a replayed code to be rejected, got %v", err)
+	}
+
+	// Recovery codes work once, with or without the dash
+	if _, _, _, err := auth.VerifyMFA(challenge.Challenge, recoveryCodes[0], domain.DeviceInfo{}); err != nil {
+		t.Errorf("Expected the recovery code to be accepted, got %v", err)
+	}
+	reused := recoveryCodes[0][:4] + recoveryCodes[0][5:]
+	if _, _, _, err := auth.VerifyMFA(challenge.Challenge, reused, domain.DeviceInfo{}); err != ErrMFACodeInvalid {
+		t.Errorf("Expected a used recovery code to be rejected, got %v", err)
+	}
+
+	// Enforced by the role: the user cannot opt out
+	if err := mfa.Disable(user.ID, "correct-pass1", currentCode(t, enrollment, now.Add(time.Minute))); err != ErrMFARequiredByRole {
+		t.Errorf("Expected ErrMFARequiredByRole, got %v", err)
+	}
+}
+
+func TestWrongMFACodesLockTheAccount(t *testing.T) {
+	auth, mfa, user := newMFATestAuth(t)
+	enrollment, _ := mfa.BeginEnrollment(user.ID)
+	if _, err := mfa.ConfirmEnrollment(user.ID, currentCode(t, enrollment, time.Now())); err != nil {
+		t.Fatalf("ConfirmEnrollment failed: %v", err)
+	}
+
+	_, _, err := auth.Login(user.Email, "correct-pass1", domain.DeviceInfo{})
+	challenge := err.(*MFARequiredError)
+	for i := 0; i < testAuthConfig.LoginMaxAttempts-1; i++ {
+		if _, _, _, err := auth.VerifyMFA(challenge.Challenge, "000000", domain.DeviceInfo{}); err != ErrMFACodeInvalid {
+			t.Fatalf("Attempt %d: expected ErrMFACodeInvalid, got %v", i+1, err)
+		}
+	}
+	// A fresh password step does not reset the counter while the code is still unverified
+	if _, _, err := auth.Login(user.Email, "correct-pass1", domain.DeviceInfo{}); err == nil {
+		t.Fatal("Expected another challenge")
+	}
+	if _, _, _, err := auth.VerifyMFA(challenge.Challenge, "000000", domain.DeviceInfo{}); err == nil {
+		t.Fatal("Expected the last wrong code to lock the account")
+	} else if _, ok := err.(*AccountLockedError); !ok {
+		t.Fatalf("Expected AccountLockedError, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/phuc/cmms-backend/internal/config"
+)
+
+// ErrWeakPassword wraps every password policy violation so handlers can map it to 400
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+// PasswordPolicy validates new passwords on create, change and reset
+type PasswordPolicy struct {
+	cfg config.PasswordPolicyConfig
+}
+
+func NewPasswordPolicy(cfg config.PasswordPolicyConfig) PasswordPolicy {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 8
+	}
+	return PasswordPolicy{cfg: cfg}
+}
+
+// Validate returns an error wrapping ErrWeakPassword that lists every unmet rule
+func (p PasswordPolicy) Validate(password string) error {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	var missing []string
+	if len([]rune(password)) < p.cfg.MinLength {
+		missing = append(missing, fmt.Sprintf("at least %d characters", p.cfg.MinLength))
+	}
+	if p.cfg.RequireUpper && !upper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if p.cfg.RequireLower && !lower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if p.cfg.RequireDigit && !digit {
+		missing = append(missing, "a digit")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		missing = append(missing, "a symbol")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: needs %s", ErrWeakPassword, strings.Join(missing, ", "))
+	}
+	return nil
+}
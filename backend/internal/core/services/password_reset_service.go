@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/phuc/cmms-backend/internal/config"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/platform/logger"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrResetTokenInvalid = errors.New("password reset token is invalid or has expired")
+
+// PasswordResetService runs the forgot-password flow: a single-use token is
+// emailed to the account and exchanged for a new password.
+type PasswordResetService struct {
+	userRepo    domain.UserRepository
+	repo        domain.PasswordResetRepository
+	sessions    *SessionService
+	email       *EmailService
+	policy      PasswordPolicy
+	ttl         time.Duration
+	frontendURL string
+	now         func() time.Time
+}
+
+func NewPasswordResetService(userRepo domain.UserRepository, repo domain.PasswordResetRepository, sessions *SessionService, email *EmailService, cfg config.AuthConfig, frontendURL string) *PasswordResetService {
+	ttl := cfg.PasswordResetTTL
+	if ttl <= 0 {
+		ttl = 30 * time.Minute
+	}
+	return &PasswordResetService{
+		userRepo:    userRepo,
+		repo:        repo,
+		sessions:    sessions,
+		email:       email,
+		policy:      NewPasswordPolicy(cfg.Password),
+		ttl:         ttl,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+		now:         time.Now,
+	}
+}
+
+// RequestReset emails a reset token when the address belongs to an account.
+// Unknown addresses return nil as well so the endpoint does not reveal which emails exist.
+func (s *PasswordResetService) RequestReset(email, ip string) error {
+	user, err := s.userRepo.FindByEmail(strings.TrimSpace(email))
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	// A new request supersedes older links
+	if err := s.repo.InvalidateForUser(user.ID); err != nil {
+		return err
+	}
+	if err := s.repo.Create(&domain.PasswordResetToken{
+		UserID:      user.ID,
+		TokenHash:   hashToken(token),
+		RequestedIP: ip,
+		ExpiresAt:   s.now().Add(s.ttl),
+	}); err != nil {
+		return err
+	}
+
+	resetURL := fmt.Sprintf("%s/reset-password?token=%s", s.frontendURL, url.QueryEscape(token))
+	if err := s.email.SendPasswordReset(user.Email, user.Name, resetURL, token, s.ttl); err != nil {
+		logger.Get().Error("Failed to send password reset email", zap.String("user_id", user.ID.String()), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ResetPassword consumes the token and sets the new password.
+// It also clears any login lockout and logs the user out of every device.
+func (s *PasswordResetService) ResetPassword(token, newPassword string) error {
+	if err := s.policy.Validate(newPassword); err != nil {
+		return err
+	}
+
+	reset, err := s.repo.FindByTokenHash(hashToken(token))
+	if err != nil {
+		return err
+	}
+	if reset == nil || reset.UsedAt != nil || !s.now().Before(reset.ExpiresAt) {
+		return ErrResetTokenInvalid
+	}
+	user, err := s.userRepo.FindByID(reset.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrResetTokenInvalid
+	}
+	if ok, err := s.repo.MarkUsed(reset.ID); err != nil {
+		return err
+	} else if !ok {
+		return ErrResetTokenInvalid
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+	now := s.now()
+	user.PasswordHash = string(hashed)
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = false
+	user.FailedLoginCount = 0
+	user.LockedUntil = nil
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if s.sessions != nil {
+		if err := s.sessions.RevokeAll(user.ID, domain.RevokeReasonPasswordReset); err != nil {
+			logger.Get().Warn("Failed to revoke sessions after password reset", zap.String("user_id", user.ID.String()), zap.Error(err))
+		}
+	}
+	return nil
+}
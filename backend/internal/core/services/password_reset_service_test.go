@@ -0,0 +1,377 @@
+package services
+
+import (
+	"bufio"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/config"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// MockUserRepository implements domain.UserRepository in memory
+type MockUserRepository struct {
+	Users map[uuid.UUID]*domain.User
+}
+
+func NewMockUserRepository(users ...*domain.User) *MockUserRepository {
+	m := &MockUserRepository{Users: make(map[uuid.UUID]*domain.User)}
+	for _, u := range users {
+		m.Users[u.ID] = u
+	}
+	return m
+}
+
+func (m *MockUserRepository) Create(user *domain.User) error {
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	m.Users[user.ID] = user
+	return nil
+}
+func (m *MockUserRepository) FindByEmail(email string) (*domain.User, error) {
+	for _, u := range m.Users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+func (m *MockUserRepository) FindByID(id uuid.UUID) (*domain.User, error) {
+	return m.Users[id], nil
+}
+func (m *MockUserRepository) FindByIDUnscoped(id uuid.UUID) (*domain.User, error) {
+	return m.Users[id], nil
+}
+func (m *MockUserRepository) GetUserByID(id uuid.UUID) (*domain.User, error) {
+	return m.Users[id], nil
+}
+func (m *MockUserRepository) FindAll() ([]domain.User, error) { return nil, nil }
+func (m *MockUserRepository) Delete(id uuid.UUID) error       { delete(m.Users, id); return nil }
+func (m *MockUserRepository) UpdateRole(userID uuid.UUID, roleID uuid.UUID) error {
+	return nil
+}
+func (m *MockUserRepository) Update(user *domain.User) error {
+	m.Users[user.ID] = user
+	return nil
+}
+func (m *MockUserRepository) UpdateStatus(userID uuid.UUID, status int) error { return nil }
+func (m *MockUserRepository) UpdateLoginState(userID uuid.UUID, failedCount int, lockedUntil *time.Time) error {
+	if u, ok := m.Users[userID]; ok {
+		u.FailedLoginCount = failedCount
+		u.LockedUntil = lockedUntil
+	}
+	return nil
+}
+func (m *MockUserRepository) SetTOTP(userID uuid.UUID, secret string, enabled bool) error {
+	if u, ok := m.Users[userID]; ok {
+		u.TOTPSecret, u.TOTPEnabled, u.TOTPLastStep = secret, enabled, 0
+	}
+	return nil
+}
+func (m *MockUserRepository) ClaimTOTPStep(userID uuid.UUID, step int64) (bool, error) {
+	u, ok := m.Users[userID]
+	if !ok || u.TOTPLastStep >= step {
+		return false, nil
+	}
+	u.TOTPLastStep = step
+	return true, nil
+}
+func (m *MockUserRepository) GetUserCount() (int64, error)              { return int64(len(m.Users)), nil }
+func (m *MockUserRepository) GetTeamCount() (int64, error)              { return 0, nil }
+func (m *MockUserRepository) GetDB() *gorm.DB                           { return nil }
+func (m *MockUserRepository) FindAllDeleted() ([]domain.User, error)    { return nil, nil }
+func (m *MockUserRepository) Restore(id uuid.UUID) error                { return nil }
+func (m *MockUserRepository) PermanentDelete(id uuid.UUID) error        { return nil }
+func (m *MockUserRepository) BulkRestore(ids []uuid.UUID) error         { return nil }
+func (m *MockUserRepository) BulkPermanentDelete(ids []uuid.UUID) error { return nil }
+
+// MockPasswordResetRepository implements domain.PasswordResetRepository in memory
+type MockPasswordResetRepository struct {
+	Tokens map[uuid.UUID]*domain.PasswordResetToken
+}
+
+func (m *MockPasswordResetRepository) Create(token *domain.PasswordResetToken) error {
+	token.ID = uuid.New()
+	m.Tokens[token.ID] = token
+	return nil
+}
+func (m *MockPasswordResetRepository) FindByTokenHash(hash string) (*domain.PasswordResetToken, error) {
+	for _, t := range m.Tokens {
+		if t.TokenHash == hash {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+func (m *MockPasswordResetRepository) MarkUsed(id uuid.UUID) (bool, error) {
+	t := m.Tokens[id]
+	if t == nil || t.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	return true, nil
+}
+func (m *MockPasswordResetRepository) InvalidateForUser(userID uuid.UUID) error {
+	now := time.Now()
+	for _, t := range m.Tokens {
+		if t.UserID == userID && t.UsedAt == nil {
+			t.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// startFakeSMTP runs a minimal local SMTP server that accepts AUTH PLAIN and
+// forwards every message body to the returned channel.
+func startFakeSMTP(t *testing.T) (string, string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start fake SMTP server: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	messages := make(chan string, 10)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+				reply("220 localhost ESMTP")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					cmd := strings.ToUpper(strings.TrimSpace(line))
+					switch {
+					case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+						reply("250-localhost")
+						reply("250 AUTH PLAIN")
+					case strings.HasPrefix(cmd, "AUTH"):
+						reply("235 Authentication successful")
+					case strings.HasPrefix(cmd, "DATA"):
+						reply("354 End data with <CR><LF>.<CR><LF>")
+						var body strings.Builder
+						for {
+							l, err := r.ReadString('\n')
+							if err != nil || l == ".\r\n" {
+								break
+							}
+							body.WriteString(l)
+						}
+						messages <- body.String()
+						reply("250 OK")
+					case strings.HasPrefix(cmd, "QUIT"):
+						reply("221 Bye")
+						return
+					default:
+						reply("250 OK")
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return host, port, messages
+}
+
+func newTestUser(t *testing.T, password string) *domain.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	return &domain.User{ID: uuid.New(), Email: "tech@raitek.vn", Name: "Tech", PasswordHash: string(hash)}
+}
+
+var testAuthConfig = config.AuthConfig{
+	RefreshTokenTTL:  time.Hour,
+	LoginMaxAttempts: 3,
+	LoginLockout:     time.Minute,
+	PasswordResetTTL: 15 * time.Minute,
+	Password:         config.PasswordPolicyConfig{MinLength: 8, RequireDigit: true},
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := NewPasswordPolicy(config.PasswordPolicyConfig{MinLength: 10, RequireUpper: true, RequireDigit: true, RequireSymbol: true})
+
+	if err := policy.Validate("Str0ng!Passw"); err != nil {
+		t.Errorf("Expected strong password to pass, got %v", err)
+	}
+	err := policy.Validate("weak")
+	if err == nil {
+		t.Fatal("Expected weak password to fail")
+	}
+	for _, rule := range []string{"10 characters", "uppercase", "digit", "symbol"} {
+		if !strings.Contains(err.Error(), rule) {
+			t.Errorf("Expected error to mention %q, got %v", rule, err)
+		}
+	}
+}
+
+func TestLoginLockoutIsProgressive(t *testing.T) {
+	user := newTestUser(t, "correct-pass1")
+	users := NewMockUserRepository(user)
+	svc := NewAuthService(users, nil, NewSessionService(NewMockSessionRepository(), testAuthConfig), nil, testAuthConfig)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := svc.Login(user.Email, "wrong", domain.DeviceInfo{}); err != ErrInvalidCredentials {
+			t.Fatalf("Attempt %d: expected ErrInvalidCredentials, got %v", i+1, err)
+		}
+	}
+	_, _, err := svc.Login(user.Email, "wrong", domain.DeviceInfo{})
+	locked, ok := err.(*AccountLockedError)
+	if !ok || !locked.Until.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Expected a 1 minute lockout on the 3rd failure, got %v", err)
+	}
+
+	// Even the right password is refused while locked
+	if _, _, err := svc.Login(user.Email, "correct-pass1", domain.DeviceInfo{}); err == nil {
+		t.Fatal("Expected login to be refused while locked")
+	}
+
+	// Each further failure doubles the lockout
+	now = now.Add(2 * time.Minute)
+	_, _, err = svc.Login(user.Email, "wrong", domain.DeviceInfo{})
+	if locked, ok := err.(*AccountLockedError); !ok || !locked.Until.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("Expected a 2 minute lockout on the 4th failure, got %v", err)
+	}
+}
+
+func TestPasswordResetFlowOverSMTP(t *testing.T) {
+	host, port, inbox := startFakeSMTP(t)
+	email := NewEmailService(config.SMTPConfig{Host: host, Port: port, Email: "noreply@raitek.vn", Password: "x"})
+
+	user := newTestUser(t, "old-pass1")
+	user.FailedLoginCount = 5
+	locked := time.Now().Add(time.Hour)
+	user.LockedUntil = &locked
+	users := NewMockUserRepository(user)
+	resets := &MockPasswordResetRepository{Tokens: make(map[uuid.UUID]*domain.PasswordResetToken)}
+	sessions := NewSessionService(NewMockSessionRepository(), testAuthConfig)
+	svc := NewPasswordResetService(users, resets, sessions, email, testAuthConfig, "https://om.example")
+
+	session, _, _ := sessions.Start(user.ID, domain.DeviceInfo{})
+
+	if err := svc.RequestReset("nobody@raitek.vn", "127.0.0.1"); err != nil {
+		t.Fatalf("Expected unknown email to succeed silently, got %v", err)
+	}
+	if err := svc.RequestReset(user.Email, "127.0.0.1"); err != nil {
+		t.Fatalf("RequestReset failed: %v", err)
+	}
+
+	var msg string
+	select {
+	case msg = <-inbox:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a reset email")
+	}
+	link := regexp.MustCompile(`https://om\.example/reset-password\?token=\S+`).FindString(msg)
+	u, err := url.Parse(link)
+	if err != nil || link == "" {
+		t.Fatalf("Expected a reset link in the email, got %q", msg)
+	}
+	token := u.Query().Get("token")
+
+	if err := svc.ResetPassword(token, "short"); err == nil {
+		t.Error("Expected a weak password to be rejected")
+	}
+	if err := svc.ResetPassword(token, "new-pass12"); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("new-pass12")) != nil {
+		t.Error("Expected the new password to be stored")
+	}
+	if user.LockedUntil != nil || user.FailedLoginCount != 0 {
+		t.Error("Expected the reset to clear the lockout")
+	}
+	if active, _ := sessions.IsActive(session.ID, user.ID); active {
+		t.Error("Expected existing sessions to be revoked")
+	}
+	if err := svc.ResetPassword(token, "another-pass3"); err != ErrResetTokenInvalid {
+		t.Errorf("Expected a used token to be rejected, got %v", err)
+	}
+}
+
+func TestCreatedUserMustChangePassword(t *testing.T) {
+	users := NewMockUserRepository()
+	svc := NewUserService(users, nil, nil, NewPasswordPolicy(testAuthConfig.Password))
+
+	if _, err := svc.CreateUser("a@raitek.vn", "123456", "A", uuid.New().String(), "", "", "", ""); err == nil {
+		t.Error("Expected the password policy to reject 123456")
+	}
+	user, err := svc.CreateUser("a@raitek.vn", "temp-pass1", "A", uuid.New().String(), "", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if !user.MustChangePassword {
+		t.Fatal("Expected admin-created user to require a password change")
+	}
+	if err := svc.ChangePassword(user.ID.String(), "temp-pass1", "my-own-pass2"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+	if user.MustChangePassword || user.PasswordChangedAt == nil {
+		t.Error("Expected the password change to clear the flag")
+	}
+}
+
+func TestOnlyAdminsChangeAdminAccounts(t *testing.T) {
+	admin := &domain.User{ID: uuid.New(), Email: "admin@raitek.vn", RoleModel: &domain.Role{Name: string(domain.RoleAdmin)}}
+	users := NewMockUserRepository(admin)
+	svc := NewUserService(users, nil, nil, NewPasswordPolicy(testAuthConfig.Password))
+	manager := string(domain.RoleManager)
+
+	if _, err := svc.UpdateUser(admin.ID.String(), "", "", "", "", "", "taken-over1", manager); err != ErrAdminOnly {
+		t.Errorf("Expected a manager resetting an admin's password to be refused, got %v", err)
+	}
+	if _, err := svc.UpdateUser(admin.ID.String(), "me@evil.test", "", "", "", "", "", manager); err != ErrAdminOnly {
+		t.Errorf("Expected a manager changing an admin's email to be refused, got %v", err)
+	}
+	if err := NewMFAService(users, &MockRecoveryCodeRepository{}, testAuthConfig).Reset(admin.ID, manager); err != ErrAdminOnly {
+		t.Errorf("Expected a manager resetting an admin's 2FA to be refused, got %v", err)
+	}
+	if _, err := svc.UpdateUser(admin.ID.String(), "", "Renamed", "", "", "", "", manager); err != nil {
+		t.Errorf("Expected other fields to stay editable, got %v", err)
+	}
+	if _, err := svc.UpdateUser(admin.ID.String(), "", "", "", "", "", "new-admin-pass1", string(domain.RoleAdmin)); err != nil {
+		t.Errorf("Expected an admin to reset another admin, got %v", err)
+	}
+}
+
+func TestAdminPasswordResetRevokesSessions(t *testing.T) {
+	user := &domain.User{ID: uuid.New(), Email: "worker@raitek.vn"}
+	users := NewMockUserRepository(user)
+	sessions := NewSessionService(NewMockSessionRepository(), testAuthConfig)
+	svc := NewUserService(users, nil, sessions, NewPasswordPolicy(testAuthConfig.Password))
+	session, _, _ := sessions.Start(user.ID, domain.DeviceInfo{})
+
+	if _, err := svc.UpdateUser(user.ID.String(), "", "Renamed", "", "", "", "", string(domain.RoleAdmin)); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if active, _ := sessions.IsActive(session.ID, user.ID); !active {
+		t.Fatal("Expected edits without a new password to keep sessions")
+	}
+
+	if _, err := svc.UpdateUser(user.ID.String(), "", "", "", "", "", "fresh-pass-123", string(domain.RoleAdmin)); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if active, _ := sessions.IsActive(session.ID, user.ID); active {
+		t.Error("Expected an admin password reset to revoke existing sessions")
+	}
+}
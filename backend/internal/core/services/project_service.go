@@ -18,12 +18,12 @@ func NewProjectService(projectRepo domain.ProjectRepository, ownerRepo domain.Ow
 	}
 }
 
-func (s *ProjectService) GetAllProjects() ([]domain.Project, error) {
-	return s.projectRepo.FindAll()
+func (s *ProjectService) GetAllProjects(scope *domain.VisibilityScope) ([]domain.Project, error) {
+	return s.projectRepo.FindAll(scope)
 }
 
-func (s *ProjectService) GetProjectByID(id uuid.UUID) (*domain.Project, error) {
-	return s.projectRepo.FindByID(id)
+func (s *ProjectService) GetProjectByID(id uuid.UUID, scope *domain.VisibilityScope) (*domain.Project, error) {
+	return s.projectRepo.FindByID(id, scope)
 }
 
 func (s *ProjectService) CreateProject(project *domain.Project) error {
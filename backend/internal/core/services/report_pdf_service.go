@@ -7,6 +7,7 @@ import (
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
+	"strconv"
 	"strings"
 	"time"
 
@@ -57,7 +58,7 @@ func (s *ReportPDFService) generateAndUpload(reportID uuid.UUID) error {
 	}
 
 	// 2. Fetch the parent assign (to get project name, template name, users)
-	assign, err := s.assignRepo.FindByID(report.AssignID)
+	assign, err := s.assignRepo.FindByID(report.AssignID, nil)
 	if err != nil {
 		return fmt.Errorf("assign not found: %w", err)
 	}
@@ -112,16 +113,6 @@ func (s *ReportPDFService) generateAndUpload(reportID uuid.UUID) error {
 			}
 		}
 
-		// Parse image URLs from data field
-		var imgURLs []string
-		if d.Data != nil {
-			raw := []byte(d.Data)
-			var arr []string
-			if err := json.Unmarshal(raw, &arr); err == nil {
-				imgURLs = arr
-			}
-		}
-
 		assetName := "—"
 		if d.Config.Asset != nil {
 			if d.Config.Asset.Parent != nil {
@@ -158,7 +149,9 @@ func (s *ReportPDFService) generateAndUpload(reportID uuid.UUID) error {
 			workName:    workName,
 			processName: processName,
 			noteData:    d.NoteData,
-			imageURLs:   imgURLs,
+			imageGroups: d.GroupEvidence(d.Config), // Photos by evidence slot
+			readings:    d.Measurements,
+			formAnswers: formAnswersForPDF(&d),
 			approvedAt:  approvedAt,
 		})
 	}
@@ -204,10 +197,61 @@ type taskEntryForPDF struct {
 	workName    string
 	processName string
 	noteData    string
-	imageURLs   []string
+	imageGroups []domain.EvidenceGroup
+	readings    []domain.DetailMeasurement
+	formAnswers [][2]string // Checklist label, answer
 	approvedAt  string
 }
 
+// formAnswersForPDF lists the task's checklist answers against the form
+// version it was filled in, choices shown by their labels.
+func formAnswersForPDF(d *domain.DetailAssign) [][2]string {
+	if d.FormSchema == nil {
+		return nil
+	}
+	answers := d.Answers()
+	var rows [][2]string
+	for _, f := range d.FormSchema.FormFields() {
+		text := "—"
+		if raw, ok := answers[f.Key]; ok {
+			switch f.Type {
+			case domain.FormFieldCheck:
+				var v bool
+				if json.Unmarshal(raw, &v) == nil {
+					text = "Không"
+					if v {
+						text = "Có"
+					}
+				}
+			case domain.FormFieldMultiSelect:
+				var vs []string
+				if json.Unmarshal(raw, &vs) == nil && len(vs) > 0 {
+					labels := make([]string, len(vs))
+					for i, v := range vs {
+						labels[i] = f.OptionLabel(v)
+					}
+					text = strings.Join(labels, ", ")
+				}
+			default:
+				var v string
+				if json.Unmarshal(raw, &v) == nil && strings.TrimSpace(v) != "" {
+					text = f.OptionLabel(v)
+				}
+			}
+		}
+		rows = append(rows, [2]string{f.Label, text})
+	}
+	return rows
+}
+
+// formatReading renders a measurement value, or "—" for a missing bound.
+func formatReading(v *float64) string {
+	if v == nil {
+		return "—"
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
 func (s *ReportPDFService) buildReportPDF(report *domain.Report, projectName, templateName string, tasks []taskEntryForPDF, isReject bool) ([]byte, error) {
 	pdf := gopdf.GoPdf{}
 	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4})
@@ -475,8 +519,73 @@ func (s *ReportPDFService) buildReportPDF(report *domain.Report, projectName, te
 			curY += 22
 		}
 
+		// ── Measurements ─────────────────────────────────────────────────────
+		if len(task.readings) > 0 {
+			const rowH = 14.0
+			cols := []float64{cW * 0.40, cW * 0.22, cW * 0.26, cW*0.12 - 8}
+			drawRow := func(cells []string, font string, col [3]uint8) {
+				x := mL + 4
+				for i, text := range cells {
+					_ = pdf.SetFont(font, "", 8)
+					setTxt(col)
+					pdf.SetX(x + 4)
+					pdf.SetY(curY + 3)
+					_ = pdf.CellWithOption(&gopdf.Rect{W: cols[i] - 8, H: 10}, text, gopdf.CellOption{Align: gopdf.Left})
+					x += cols[i]
+				}
+				borderRect(mL+4, curY, cW-8, rowH, cBorder)
+				curY += rowH
+			}
+
+			newPageIfNeeded(rowH * 2)
+			fillRect(mL+4, curY, cW-8, rowH, cBg)
+			drawRow([]string{"Thông số", "Giá trị", "Ngưỡng (min – max)", ""}, "bd", cText)
+			for _, m := range task.readings {
+				newPageIfNeeded(rowH)
+				value := m.Value
+				cells := []string{m.Label, strings.TrimSpace(formatReading(&value) + " " + m.Unit),
+					formatReading(m.Min) + " – " + formatReading(m.Max), ""}
+				rowCol := cText
+				if m.OutOfRange {
+					fillRect(mL+4, curY, cW-8, rowH, [3]uint8{255, 241, 242}) // rose-50
+					cells[3] = "⚠ Ngoài ngưỡng"
+					rowCol = cDanger
+				}
+				drawRow(cells, "rg", rowCol)
+			}
+			curY += 6
+		}
+
+		// ── Checklist ────────────────────────────────────────────────────────
+		if len(task.formAnswers) > 0 {
+			const rowH = 14.0
+			cols := []float64{cW * 0.50, cW*0.50 - 8}
+			drawRow := func(cells []string, font string) {
+				x := mL + 4
+				for i, text := range cells {
+					_ = pdf.SetFont(font, "", 8)
+					setTxt(cText)
+					pdf.SetX(x + 4)
+					pdf.SetY(curY + 3)
+					_ = pdf.CellWithOption(&gopdf.Rect{W: cols[i] - 8, H: 10}, text, gopdf.CellOption{Align: gopdf.Left})
+					x += cols[i]
+				}
+				borderRect(mL+4, curY, cW-8, rowH, cBorder)
+				curY += rowH
+			}
+
+			newPageIfNeeded(rowH * 2)
+			fillRect(mL+4, curY, cW-8, rowH, cBg)
+			drawRow([]string{"Hạng mục kiểm tra", "Kết quả"}, "bd")
+			for _, row := range task.formAnswers {
+				newPageIfNeeded(rowH)
+				drawRow(row[:], "rg")
+			}
+			curY += 6
+		}
+
 		// ── Images ───────────────────────────────────────────────────────────
-		if len(task.imageURLs) > 0 && mcErr == nil && mc != nil {
+		if len(task.imageGroups) > 0 && mcErr == nil && mc != nil {
 			// Layout: up to 3 images per row, each 160x120 pt
 			const imgW = 160.0
 			const imgH = 120.0
@@ -486,75 +595,93 @@ func (s *ReportPDFService) buildReportPDF(report *domain.Report, projectName, te
 			row := 0
 			col := 0
 
-			for _, rawURL := range task.imageURLs {
-				if rawURL == "" {
-					continue
+			for _, group := range task.imageGroups {
+				// Each named slot starts on its own row under a caption
+				if group.Name != "" && len(group.URLs) > 0 {
+					if col > 0 {
+						col = 0
+						curY += imgH + imgGap
+					}
+					newPageIfNeeded(14 + imgH + imgGap)
+					_ = pdf.SetFont("bd", "", 9)
+					setTxt(cText)
+					pdf.SetX(mL + 8)
+					pdf.SetY(curY)
+					_ = pdf.Cell(nil, group.Name)
+					curY += 14
 				}
 
-				// Extract MinIO object key from URL
-				objKey := extractMinioKey(rawURL, mc.Bucket)
-				if objKey == "" {
-					continue
-				}
+				for _, rawURL := range group.URLs {
+					if rawURL == "" {
+						continue
+					}
 
-				// New row needed?
-				if col == 0 {
-					newPageIfNeeded(imgH + imgGap + 8)
-				}
+					// Extract MinIO object key from URL
+					objKey := extractMinioKey(rawURL, mc.Bucket)
+					if objKey == "" {
+						continue
+					}
 
-				imgX := mL + float64(col)*(imgW+imgGap)
-				imgY := curY
+					// New row needed?
+					if col == 0 {
+						newPageIfNeeded(imgH + imgGap + 8)
+					}
 
-				// Try to fetch image bytes from MinIO
-				imgBytes, err := mc.GetObject(objKey)
-				if err != nil {
-					col++
-					if col >= imgsPerRow {
-						col = 0
-						curY += imgH + imgGap
-						row++
+					imgX := mL + float64(col)*(imgW+imgGap)
+					imgY := curY
+
+					// Try to fetch image bytes from MinIO
+					imgBytes, err := mc.GetObject(objKey)
+					if err != nil {
+						col++
+						if col >= imgsPerRow {
+							col = 0
+							curY += imgH + imgGap
+							row++
+						}
+						continue
 					}
-					continue
-				}
 
-				// Try to determine image format
-				imgReader := bytes.NewReader(imgBytes)
-				_, imgFormat, err := image.DecodeConfig(imgReader)
-				if err != nil {
-					col++
-					if col >= imgsPerRow {
-						col = 0
-						curY += imgH + imgGap
-						row++
+					// Try to determine image format
+					imgReader := bytes.NewReader(imgBytes)
+					_, imgFormat, err := image.DecodeConfig(imgReader)
+					if err != nil {
+						col++
+						if col >= imgsPerRow {
+							col = 0
+							curY += imgH + imgGap
+							row++
+						}
+						continue
+					}
+
+					// Add image to pdf
+					var holder gopdf.ImageHolder
+					imgReader2 := bytes.NewReader(imgBytes)
+					holder, err = gopdf.ImageHolderByReader(imgReader2)
+					_ = imgFormat // format already detected above
+					if err != nil || holder == nil {
+						col++
+						if col >= imgsPerRow {
+							col = 0
+							curY += imgH + imgGap
+							row++
+						}
+						continue
 					}
-					continue
-				}
 
-				// Add image to pdf
-				var holder gopdf.ImageHolder
-				imgReader2 := bytes.NewReader(imgBytes)
-				holder, err = gopdf.ImageHolderByReader(imgReader2)
-				_ = imgFormat // format already detected above
-				if err != nil || holder == nil {
+					_ = pdf.ImageByHolder(holder, imgX, imgY, &gopdf.Rect{W: imgW, H: imgH})
+					// Border around image
+					borderRect(imgX, imgY, imgW, imgH, cBorder)
+
 					col++
 					if col >= imgsPerRow {
 						col = 0
 						curY += imgH + imgGap
 						row++
 					}
-					continue
 				}
 
-				_ = pdf.ImageByHolder(holder, imgX, imgY, &gopdf.Rect{W: imgW, H: imgH})
-				// Border around image
-				borderRect(imgX, imgY, imgW, imgH, cBorder)
-
-				col++
-				if col >= imgsPerRow {
-					col = 0
-					curY += imgH + imgGap
-					row++
-				}
 			}
 
 			// If last row was partial, advance curY
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/adapters/storage/postgres"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// RoleService manages roles and the permissions granted to them
+type RoleService struct {
+	roleRepo postgres.RoleRepository
+	permRepo domain.PermissionRepository
+}
+
+func NewRoleService(roleRepo postgres.RoleRepository, permRepo domain.PermissionRepository) *RoleService {
+	return &RoleService{roleRepo: roleRepo, permRepo: permRepo}
+}
+
+// ListPermissions returns the permission catalog
+func (s *RoleService) ListPermissions() ([]domain.Permission, error) {
+	return s.permRepo.FindAll()
+}
+
+// ListRoles returns all roles with their granted permissions
+func (s *RoleService) ListRoles() ([]domain.Role, error) {
+	roles, err := s.roleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	for i := range roles {
+		perms, err := s.permRepo.FindByRoleID(roles[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		roles[i].Permissions = perms
+	}
+	return roles, nil
+}
+
+// GetRole returns a role with its granted permissions
+func (s *RoleService) GetRole(id uuid.UUID) (*domain.Role, error) {
+	role, err := s.roleRepo.FindByID(id)
+	if err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+	perms, err := s.permRepo.FindByRoleID(id)
+	if err != nil {
+		return nil, err
+	}
+	role.Permissions = perms
+	return role, nil
+}
+
+// CreateRole creates a role granted with the given permission codes
+func (s *RoleService) CreateRole(name string, codes []string, requireMFA bool) (*domain.Role, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apperrors.NewAppError(apperrors.ErrValidation.Code, "Role name is required", http.StatusBadRequest)
+	}
+	permIDs, err := s.resolvePermissionIDs(codes)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &domain.Role{Name: name, RequireMFA: requireMFA}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrConflict.Code, "Role name already exists", http.StatusConflict)
+	}
+	if err := s.permRepo.ReplaceRolePermissions(role.ID, permIDs); err != nil {
+		return nil, err
+	}
+	return s.GetRole(role.ID)
+}
+
+// UpdateRole renames a role (custom roles only), sets its two-factor policy when
+// requireMFA is non-nil and, when codes is non-nil, replaces its permissions
+func (s *RoleService) UpdateRole(id uuid.UUID, name string, codes []string, requireMFA *bool) (*domain.Role, error) {
+	role, err := s.roleRepo.FindByID(id)
+	if err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	changed := false
+	name = strings.TrimSpace(name)
+	if name != "" && name != role.Name {
+		if role.IsBuiltIn() {
+			return nil, apperrors.NewAppError(apperrors.ErrForbidden.Code, "Built-in roles cannot be renamed", http.StatusForbidden)
+		}
+		role.Name = name
+		changed = true
+	}
+	if requireMFA != nil && *requireMFA != role.RequireMFA {
+		role.RequireMFA = *requireMFA
+		changed = true
+	}
+	if changed {
+		if err := s.roleRepo.Update(role); err != nil {
+			return nil, apperrors.NewAppError(apperrors.ErrConflict.Code, "Role name already exists", http.StatusConflict)
+		}
+	}
+
+	if codes != nil {
+		return s.SetRolePermissions(id, codes)
+	}
+	return s.GetRole(id)
+}
+
+// SetRolePermissions replaces the permission set of a role
+func (s *RoleService) SetRolePermissions(id uuid.UUID, codes []string) (*domain.Role, error) {
+	if _, err := s.roleRepo.FindByID(id); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+	permIDs, err := s.resolvePermissionIDs(codes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.permRepo.ReplaceRolePermissions(id, permIDs); err != nil {
+		return nil, err
+	}
+	return s.GetRole(id)
+}
+
+// DeleteRole soft-deletes a custom role that no user holds anymore
+func (s *RoleService) DeleteRole(id uuid.UUID) error {
+	role, err := s.roleRepo.FindByID(id)
+	if err != nil {
+		return apperrors.ErrNotFound
+	}
+	if role.IsBuiltIn() {
+		return apperrors.NewAppError(apperrors.ErrForbidden.Code, "Built-in roles cannot be deleted", http.StatusForbidden)
+	}
+	count, err := s.roleRepo.CountUsers(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return apperrors.NewAppError(apperrors.ErrConflict.Code, "Role is still assigned to users", http.StatusConflict)
+	}
+	if err := s.permRepo.ReplaceRolePermissions(id, nil); err != nil {
+		return err
+	}
+	return s.roleRepo.Delete(id)
+}
+
+// ResolvePermissions returns the permission codes held by a role name.
+// The built-in admin role always holds the whole catalog.
+func (s *RoleService) ResolvePermissions(roleName string) ([]string, error) {
+	return resolveRolePermissions(s.permRepo, roleName)
+}
+
+func resolveRolePermissions(permRepo domain.PermissionRepository, roleName string) ([]string, error) {
+	if domain.UserRole(roleName) == domain.RoleAdmin {
+		return domain.AllPermissionCodes, nil
+	}
+	return permRepo.FindCodesByRoleName(roleName)
+}
+
+// resolvePermissionIDs maps codes to catalog IDs, rejecting unknown codes
+func (s *RoleService) resolvePermissionIDs(codes []string) ([]uuid.UUID, error) {
+	unique := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, c := range codes {
+		c = strings.TrimSpace(c)
+		if c == "" || seen[c] {
+			continue
+		}
+		seen[c] = true
+		unique = append(unique, c)
+	}
+
+	perms, err := s.permRepo.FindByCodes(unique)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]uuid.UUID, len(perms))
+	for _, p := range perms {
+		found[p.Code] = p.ID
+	}
+
+	ids := make([]uuid.UUID, 0, len(unique))
+	for _, c := range unique {
+		id, ok := found[c]
+		if !ok {
+			return nil, apperrors.NewAppError(apperrors.ErrValidation.Code, "Unknown permission: "+c, http.StatusBadRequest)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
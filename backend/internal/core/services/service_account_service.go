@@ -0,0 +1,311 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/config"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/platform/logger"
+	"go.uber.org/zap"
+)
+
+// APIKeyPrefix starts every API key so AuthMiddleware can tell keys from JWTs
+const APIKeyPrefix = "omk_"
+
+// Last-used tracking is written at most this often per key
+const apiKeyTouchInterval = time.Minute
+
+// Longest overlap a rotated key keeps working next to its replacement
+const maxRotationGrace = 7 * 24 * time.Hour
+
+var (
+	ErrAPIKeyInvalid          = errors.New("API key is invalid, expired or revoked")
+	ErrServiceAccountNotFound = errors.New("service account not found")
+	ErrServiceAccountInvalid  = errors.New("invalid service account")
+	ErrAPIKeyNotFound         = errors.New("API key not found")
+	ErrAPIKeyScope            = errors.New("invalid API key scope")
+	ErrAPIKeyExpiry           = errors.New("invalid API key expiry")
+)
+
+// IssuedAPIKey is returned once when a key is created or rotated; Key is never shown again
+type IssuedAPIKey struct {
+	domain.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyInput describes a key to create
+type APIKeyInput struct {
+	Name      string
+	Scopes    []string
+	RateLimit int        // Requests per minute; 0 uses the default
+	ExpiresAt *time.Time // nil uses the default lifetime
+}
+
+// ServiceAccountService manages service accounts and authenticates their API keys
+type ServiceAccountService struct {
+	repo             domain.ServiceAccountRepository
+	defaultTTL       time.Duration
+	maxTTL           time.Duration
+	defaultRateLimit int
+	now              func() time.Time
+}
+
+func NewServiceAccountService(repo domain.ServiceAccountRepository, cfg config.AuthConfig) *ServiceAccountService {
+	ttl := cfg.APIKeyTTL
+	if ttl <= 0 {
+		ttl = 90 * 24 * time.Hour
+	}
+	maxTTL := cfg.APIKeyMaxTTL
+	if maxTTL < ttl {
+		maxTTL = ttl
+	}
+	rateLimit := cfg.APIKeyRateLimit
+	if rateLimit <= 0 {
+		rateLimit = 60
+	}
+	return &ServiceAccountService{
+		repo:             repo,
+		defaultTTL:       ttl,
+		maxTTL:           maxTTL,
+		defaultRateLimit: rateLimit,
+		now:              time.Now,
+	}
+}
+
+func (s *ServiceAccountService) List() ([]domain.ServiceAccount, error) {
+	return s.repo.FindAll()
+}
+
+func (s *ServiceAccountService) Get(id uuid.UUID) (*domain.ServiceAccount, error) {
+	account, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, ErrServiceAccountNotFound
+	}
+	return account, nil
+}
+
+func (s *ServiceAccountService) Create(name, description string, creatorID *uuid.UUID) (*domain.ServiceAccount, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrServiceAccountInvalid)
+	}
+	account := &domain.ServiceAccount{Name: name, Description: description, PersonCreatedID: creatorID}
+	if err := s.repo.Create(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Update changes the name/description and enables or disables the account (nil fields are kept)
+func (s *ServiceAccountService) Update(id uuid.UUID, name, description *string, disabled *bool) (*domain.ServiceAccount, error) {
+	account, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil && strings.TrimSpace(*name) != "" {
+		account.Name = strings.TrimSpace(*name)
+	}
+	if description != nil {
+		account.Description = *description
+	}
+	if disabled != nil {
+		account.Disabled = *disabled
+	}
+	if err := s.repo.Update(account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Delete removes the account and revokes all of its keys
+func (s *ServiceAccountService) Delete(id uuid.UUID) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// CreateKey issues a key for the account. Scopes must be catalog permission codes
+// that the caller itself holds, so nobody can mint a key stronger than themselves.
+func (s *ServiceAccountService) CreateKey(accountID uuid.UUID, in APIKeyInput, callerPermissions []string, creatorID *uuid.UUID) (*IssuedAPIKey, error) {
+	if _, err := s.Get(accountID); err != nil {
+		return nil, err
+	}
+	scopes, err := validateScopes(in.Scopes, callerPermissions)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	expiresAt := now.Add(s.defaultTTL)
+	if in.ExpiresAt != nil {
+		expiresAt = *in.ExpiresAt
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(s.maxTTL)) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future and within %d days", ErrAPIKeyExpiry, int(s.maxTTL.Hours()/24))
+	}
+	rateLimit := in.RateLimit
+	if rateLimit <= 0 {
+		rateLimit = s.defaultRateLimit
+	}
+
+	raw, prefix, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	scopesJSON, _ := json.Marshal(scopes)
+	key := domain.APIKey{
+		ServiceAccountID: accountID,
+		Name:             strings.TrimSpace(in.Name),
+		Prefix:           prefix,
+		KeyHash:          hashToken(raw),
+		Scopes:           scopesJSON,
+		RateLimit:        rateLimit,
+		ExpiresAt:        expiresAt,
+		PersonCreatedID:  creatorID,
+	}
+	if err := s.repo.CreateKey(&key); err != nil {
+		return nil, err
+	}
+	return &IssuedAPIKey{APIKey: key, Key: raw}, nil
+}
+
+// RotateKey issues a replacement with the same name, scopes and rate limit. The old
+// key keeps working for grace (capped at 7 days) so integrations can switch over.
+func (s *ServiceAccountService) RotateKey(accountID, keyID uuid.UUID, grace time.Duration, expiresAt *time.Time, callerPermissions []string, creatorID *uuid.UUID) (*IssuedAPIKey, error) {
+	old, err := s.findKey(accountID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if !old.Usable(s.now()) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if grace < 0 {
+		grace = 0
+	}
+	if grace > maxRotationGrace {
+		grace = maxRotationGrace
+	}
+
+	issued, err := s.CreateKey(accountID, APIKeyInput{
+		Name:      old.Name,
+		Scopes:    old.ScopeCodes(),
+		RateLimit: old.RateLimit,
+		ExpiresAt: expiresAt,
+	}, callerPermissions, creatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cutoff := s.now().Add(grace); cutoff.Before(old.ExpiresAt) {
+		old.ExpiresAt = cutoff
+	}
+	old.ReplacedByID = &issued.ID
+	if err := s.repo.UpdateKey(old); err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// RevokeKey disables a key immediately
+func (s *ServiceAccountService) RevokeKey(accountID, keyID uuid.UUID) error {
+	key, err := s.findKey(accountID, keyID)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	now := s.now()
+	key.RevokedAt = &now
+	return s.repo.UpdateKey(key)
+}
+
+// Authenticate resolves a raw API key to its key and service account.
+// Disabled or deleted accounts and expired or revoked keys are rejected.
+func (s *ServiceAccountService) Authenticate(raw, ip string) (*domain.APIKey, error) {
+	if !strings.HasPrefix(raw, APIKeyPrefix) {
+		return nil, ErrAPIKeyInvalid
+	}
+	key, err := s.repo.FindKeyByHash(hashToken(raw))
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if key == nil || !key.Usable(now) || key.ServiceAccount == nil || key.ServiceAccount.Disabled {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.TouchKey(key.ID, now, ip); err != nil {
+			logger.Get().Warn("Failed to record API key use", zap.String("key_id", key.ID.String()), zap.Error(err))
+		}
+	}
+	return key, nil
+}
+
+func (s *ServiceAccountService) findKey(accountID, keyID uuid.UUID) (*domain.APIKey, error) {
+	key, err := s.repo.FindKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.ServiceAccountID != accountID {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func validateScopes(scopes, callerPermissions []string) ([]string, error) {
+	catalog := make(map[string]bool, len(domain.AllPermissionCodes))
+	for _, code := range domain.AllPermissionCodes {
+		catalog[code] = true
+	}
+	held := make(map[string]bool, len(callerPermissions))
+	for _, code := range callerPermissions {
+		held[code] = true
+	}
+
+	out := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, code := range scopes {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		if !catalog[code] {
+			return nil, fmt.Errorf("%w: unknown permission %q", ErrAPIKeyScope, code)
+		}
+		if !held[code] {
+			return nil, fmt.Errorf("%w: you do not hold %q", ErrAPIKeyScope, code)
+		}
+		seen[code] = true
+		out = append(out, code)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrAPIKeyScope)
+	}
+	return out, nil
+}
+
+// newAPIKey returns "omk_<8 hex prefix>_<secret>" and its displayable prefix
+func newAPIKey() (string, string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	prefix := APIKeyPrefix + hex.EncodeToString(b)
+	return prefix + "_" + secret, prefix, nil
+}
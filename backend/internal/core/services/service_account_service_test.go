@@ -0,0 +1,202 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/config"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// MockServiceAccountRepository implements domain.ServiceAccountRepository in memory
+type MockServiceAccountRepository struct {
+	Accounts map[uuid.UUID]*domain.ServiceAccount
+	Keys     map[uuid.UUID]*domain.APIKey
+	Touches  int
+}
+
+func NewMockServiceAccountRepository() *MockServiceAccountRepository {
+	return &MockServiceAccountRepository{
+		Accounts: make(map[uuid.UUID]*domain.ServiceAccount),
+		Keys:     make(map[uuid.UUID]*domain.APIKey),
+	}
+}
+
+func (m *MockServiceAccountRepository) Create(account *domain.ServiceAccount) error {
+	account.ID = uuid.New()
+	m.Accounts[account.ID] = account
+	return nil
+}
+func (m *MockServiceAccountRepository) FindAll() ([]domain.ServiceAccount, error) {
+	var out []domain.ServiceAccount
+	for _, a := range m.Accounts {
+		out = append(out, *a)
+	}
+	return out, nil
+}
+func (m *MockServiceAccountRepository) FindByID(id uuid.UUID) (*domain.ServiceAccount, error) {
+	return m.Accounts[id], nil
+}
+func (m *MockServiceAccountRepository) Update(account *domain.ServiceAccount) error {
+	m.Accounts[account.ID] = account
+	return nil
+}
+func (m *MockServiceAccountRepository) Delete(id uuid.UUID) error {
+	delete(m.Accounts, id)
+	now := time.Now()
+	for _, k := range m.Keys {
+		if k.ServiceAccountID == id && k.RevokedAt == nil {
+			k.RevokedAt = &now
+		}
+	}
+	return nil
+}
+func (m *MockServiceAccountRepository) CreateKey(key *domain.APIKey) error {
+	key.ID = uuid.New()
+	stored := *key
+	m.Keys[key.ID] = &stored
+	return nil
+}
+func (m *MockServiceAccountRepository) FindKey(id uuid.UUID) (*domain.APIKey, error) {
+	if k, ok := m.Keys[id]; ok {
+		copied := *k
+		return &copied, nil
+	}
+	return nil, nil
+}
+func (m *MockServiceAccountRepository) FindKeyByHash(hash string) (*domain.APIKey, error) {
+	for _, k := range m.Keys {
+		if k.KeyHash == hash {
+			copied := *k
+			copied.ServiceAccount = m.Accounts[k.ServiceAccountID]
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+func (m *MockServiceAccountRepository) UpdateKey(key *domain.APIKey) error {
+	stored := *key
+	m.Keys[key.ID] = &stored
+	return nil
+}
+func (m *MockServiceAccountRepository) TouchKey(id uuid.UUID, at time.Time, ip string) error {
+	m.Touches++
+	m.Keys[id].LastUsedAt = &at
+	m.Keys[id].LastUsedIP = ip
+	return nil
+}
+
+func newTestServiceAccounts(t *testing.T) (*ServiceAccountService, *MockServiceAccountRepository, *domain.ServiceAccount) {
+	repo := NewMockServiceAccountRepository()
+	svc := NewServiceAccountService(repo, config.AuthConfig{APIKeyTTL: 30 * 24 * time.Hour, APIKeyMaxTTL: 90 * 24 * time.Hour})
+	account, err := svc.Create("SCADA export", "", nil)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return svc, repo, account
+}
+
+func TestAPIKeyScopesCannotExceedCaller(t *testing.T) {
+	svc, _, account := newTestServiceAccounts(t)
+	caller := []string{domain.PermStatsView, domain.PermProjectViewAll}
+
+	if _, err := svc.CreateKey(account.ID, APIKeyInput{Scopes: []string{domain.PermUserManage}}, caller, nil); !errors.Is(err, ErrAPIKeyScope) {
+		t.Errorf("Expected a scope the caller lacks to be rejected, got %v", err)
+	}
+	if _, err := svc.CreateKey(account.ID, APIKeyInput{Scopes: []string{"everything"}}, caller, nil); !errors.Is(err, ErrAPIKeyScope) {
+		t.Errorf("Expected an unknown scope to be rejected, got %v", err)
+	}
+	tooLong := time.Now().Add(365 * 24 * time.Hour)
+	if _, err := svc.CreateKey(account.ID, APIKeyInput{Scopes: []string{domain.PermStatsView}, ExpiresAt: &tooLong}, caller, nil); !errors.Is(err, ErrAPIKeyExpiry) {
+		t.Errorf("Expected an expiry beyond the maximum to be rejected, got %v", err)
+	}
+
+	issued, err := svc.CreateKey(account.ID, APIKeyInput{Scopes: []string{domain.PermStatsView, domain.PermStatsView}}, caller, nil)
+	if err != nil {
+		t.Fatalf("CreateKey failed: %v", err)
+	}
+	if !strings.HasPrefix(issued.Key, issued.Prefix+"_") || issued.KeyHash == issued.Key {
+		t.Errorf("Expected a prefixed key stored as a hash, got %q / %q", issued.Key, issued.KeyHash)
+	}
+	if scopes := issued.ScopeCodes(); len(scopes) != 1 || scopes[0] != domain.PermStatsView {
+		t.Errorf("Expected scopes [%s], got %v", domain.PermStatsView, scopes)
+	}
+	if issued.RateLimit != 60 {
+		t.Errorf("Expected the default rate limit of 60, got %d", issued.RateLimit)
+	}
+}
+
+func TestAPIKeyAuthenticateAndRevoke(t *testing.T) {
+	svc, repo, account := newTestServiceAccounts(t)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	issued, err := svc.CreateKey(account.ID, APIKeyInput{Scopes: []string{domain.PermStatsView}}, []string{domain.PermStatsView}, nil)
+	if err != nil {
+		t.Fatalf("CreateKey failed: %v", err)
+	}
+	key, err := svc.Authenticate(issued.Key, "10.0.0.1")
+	if err != nil || key.ID != issued.ID {
+		t.Fatalf("Expected the key to authenticate, got %v", err)
+	}
+	if _, err := svc.Authenticate(issued.Key+"x", "10.0.0.1"); err != ErrAPIKeyInvalid {
+		t.Errorf("Expected a wrong key to be rejected, got %v", err)
+	}
+
+	// Last use is recorded at most once per interval
+	svc.Authenticate(issued.Key, "10.0.0.1")
+	if repo.Touches != 1 || repo.Keys[issued.ID].LastUsedIP != "10.0.0.1" {
+		t.Errorf("Expected one recorded use, got %d", repo.Touches)
+	}
+
+	disabled := true
+	svc.Update(account.ID, nil, nil, &disabled)
+	if _, err := svc.Authenticate(issued.Key, ""); err != ErrAPIKeyInvalid {
+		t.Errorf("Expected keys of a disabled account to be rejected, got %v", err)
+	}
+	disabled = false
+	svc.Update(account.ID, nil, nil, &disabled)
+
+	if err := svc.RevokeKey(account.ID, issued.ID); err != nil {
+		t.Fatalf("RevokeKey failed: %v", err)
+	}
+	if _, err := svc.Authenticate(issued.Key, ""); err != ErrAPIKeyInvalid {
+		t.Errorf("Expected a revoked key to be rejected, got %v", err)
+	}
+}
+
+func TestAPIKeyRotationKeepsOldKeyForGrace(t *testing.T) {
+	svc, _, account := newTestServiceAccounts(t)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	caller := []string{domain.PermStatsView}
+
+	old, err := svc.CreateKey(account.ID, APIKeyInput{Name: "bi", Scopes: caller, RateLimit: 10}, caller, nil)
+	if err != nil {
+		t.Fatalf("CreateKey failed: %v", err)
+	}
+	replacement, err := svc.RotateKey(account.ID, old.ID, time.Hour, nil, caller, nil)
+	if err != nil {
+		t.Fatalf("RotateKey failed: %v", err)
+	}
+	if replacement.Name != "bi" || replacement.RateLimit != 10 {
+		t.Errorf("Expected the replacement to keep name and rate limit, got %q / %d", replacement.Name, replacement.RateLimit)
+	}
+
+	if _, err := svc.Authenticate(old.Key, ""); err != nil {
+		t.Errorf("Expected the old key to work during the grace period, got %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if _, err := svc.Authenticate(old.Key, ""); err != ErrAPIKeyInvalid {
+		t.Errorf("Expected the old key to expire after the grace period, got %v", err)
+	}
+	if _, err := svc.Authenticate(replacement.Key, ""); err != nil {
+		t.Errorf("Expected the replacement key to work, got %v", err)
+	}
+	if _, err := svc.RotateKey(account.ID, old.ID, 0, nil, caller, nil); err != ErrAPIKeyInvalid {
+		t.Errorf("Expected an expired key not to be rotated again, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/config"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/platform/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrSessionInvalid = errors.New("session is invalid or expired")
+	ErrTokenReused    = errors.New("refresh token reuse detected")
+)
+
+// SessionService manages server-side sessions and their rotating refresh tokens.
+// Refresh tokens are opaque random strings; only their SHA-256 is stored.
+type SessionService struct {
+	repo       domain.SessionRepository
+	refreshTTL time.Duration
+	now        func() time.Time
+
+	// onRevoke is notified with the IDs of revoked sessions (e.g. to drop WebSocket connections)
+	onRevoke func(sessionIDs []uuid.UUID)
+}
+
+func NewSessionService(repo domain.SessionRepository, cfg config.AuthConfig) *SessionService {
+	ttl := cfg.RefreshTokenTTL
+	if ttl <= 0 {
+		ttl = 30 * 24 * time.Hour
+	}
+	return &SessionService{repo: repo, refreshTTL: ttl, now: time.Now}
+}
+
+// SetRevokeListener registers a callback invoked after sessions are revoked
+func (s *SessionService) SetRevokeListener(fn func(sessionIDs []uuid.UUID)) {
+	s.onRevoke = fn
+}
+
+// Start opens a new session for the user and returns it with its first refresh token
+func (s *SessionService) Start(userID uuid.UUID, device domain.DeviceInfo) (*domain.UserSession, string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := s.now()
+	session := &domain.UserSession{
+		ID:               uuid.New(),
+		UserID:           userID,
+		DeviceID:         device.DeviceID,
+		DeviceName:       device.DeviceName,
+		UserAgent:        device.UserAgent,
+		IPAddress:        device.IPAddress,
+		RefreshTokenHash: hashToken(token),
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(s.refreshTTL),
+	}
+	if err := s.repo.Create(session); err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+// Rotate exchanges a refresh token for a new one on the same session.
+// Presenting an already-rotated token revokes the whole session (the token was likely stolen).
+func (s *SessionService) Rotate(refreshToken string, device domain.DeviceInfo) (*domain.UserSession, string, error) {
+	hash := hashToken(refreshToken)
+	session, err := s.repo.FindByTokenHash(hash)
+	if err != nil {
+		return nil, "", err
+	}
+	if session == nil {
+		return nil, "", ErrSessionInvalid
+	}
+
+	if session.RefreshTokenHash != hash {
+		logger.Get().Warn("Refresh token reuse detected, revoking session",
+			zap.String("session_id", session.ID.String()),
+			zap.String("user_id", session.UserID.String()),
+		)
+		_ = s.revoke([]uuid.UUID{session.ID}, domain.RevokeReasonTokenReuse)
+		return nil, "", ErrTokenReused
+	}
+
+	now := s.now()
+	if !session.IsActive(now) {
+		return nil, "", ErrSessionInvalid
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	session.PreviousTokenHash = session.RefreshTokenHash
+	session.RefreshTokenHash = hashToken(token)
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.refreshTTL)
+	if device.UserAgent != "" {
+		session.UserAgent = device.UserAgent
+	}
+	if device.IPAddress != "" {
+		session.IPAddress = device.IPAddress
+	}
+	if err := s.repo.Update(session); err != nil {
+		return nil, "", err
+	}
+	return session, token, nil
+}
+
+// IsActive reports whether the session exists, belongs to the user and is not revoked or expired
+func (s *SessionService) IsActive(sessionID, userID uuid.UUID) (bool, error) {
+	session, err := s.repo.FindByID(sessionID)
+	if err != nil {
+		return false, err
+	}
+	return session != nil && session.UserID == userID && session.IsActive(s.now()), nil
+}
+
+// ListActive returns the user's live sessions (one per logged-in device)
+func (s *SessionService) ListActive(userID uuid.UUID) ([]domain.UserSession, error) {
+	return s.repo.FindActiveByUserID(userID)
+}
+
+// Revoke kills a single session of the user
+func (s *SessionService) Revoke(userID, sessionID uuid.UUID, reason string) error {
+	session, err := s.repo.FindByID(sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID {
+		return ErrSessionInvalid
+	}
+	return s.revoke([]uuid.UUID{sessionID}, reason)
+}
+
+// RevokeAll kills every active session of the user
+func (s *SessionService) RevokeAll(userID uuid.UUID, reason string) error {
+	sessions, err := s.repo.FindActiveByUserID(userID)
+	if err != nil {
+		return err
+	}
+	ids := make([]uuid.UUID, 0, len(sessions))
+	for _, sess := range sessions {
+		ids = append(ids, sess.ID)
+	}
+	return s.revoke(ids, reason)
+}
+
+// RevokeDevice kills every active session of the user opened from the given device
+func (s *SessionService) RevokeDevice(userID uuid.UUID, deviceID, reason string) error {
+	sessions, err := s.repo.FindActiveByUserID(userID)
+	if err != nil {
+		return err
+	}
+	var ids []uuid.UUID
+	for _, sess := range sessions {
+		if sess.DeviceID == deviceID {
+			ids = append(ids, sess.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return ErrSessionInvalid
+	}
+	return s.revoke(ids, reason)
+}
+
+func (s *SessionService) revoke(ids []uuid.UUID, reason string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := s.repo.Revoke(ids, reason); err != nil {
+		return err
+	}
+	if s.onRevoke != nil {
+		s.onRevoke(ids)
+	}
+	return nil
+}
+
+// newOpaqueToken returns 32 random bytes, base64url-encoded (refresh and password reset tokens)
+func newOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is the SHA-256 hex digest stored in place of an opaque token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/config"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// MockSessionRepository implements domain.SessionRepository in memory
+type MockSessionRepository struct {
+	Sessions map[uuid.UUID]*domain.UserSession
+}
+
+func NewMockSessionRepository() *MockSessionRepository {
+	return &MockSessionRepository{Sessions: make(map[uuid.UUID]*domain.UserSession)}
+}
+
+func (m *MockSessionRepository) Create(session *domain.UserSession) error {
+	m.Sessions[session.ID] = session
+	return nil
+}
+func (m *MockSessionRepository) FindByID(id uuid.UUID) (*domain.UserSession, error) {
+	return m.Sessions[id], nil
+}
+func (m *MockSessionRepository) FindByTokenHash(hash string) (*domain.UserSession, error) {
+	for _, s := range m.Sessions {
+		if s.RefreshTokenHash == hash || s.PreviousTokenHash == hash {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+func (m *MockSessionRepository) Update(session *domain.UserSession) error {
+	m.Sessions[session.ID] = session
+	return nil
+}
+func (m *MockSessionRepository) FindActiveByUserID(userID uuid.UUID) ([]domain.UserSession, error) {
+	var out []domain.UserSession
+	for _, s := range m.Sessions {
+		if s.UserID == userID && s.IsActive(time.Now()) {
+			out = append(out, *s)
+		}
+	}
+	return out, nil
+}
+func (m *MockSessionRepository) Revoke(ids []uuid.UUID, reason string) error {
+	now := time.Now()
+	for _, id := range ids {
+		if s, ok := m.Sessions[id]; ok && s.RevokedAt == nil {
+			s.RevokedAt = &now
+			s.RevokedReason = reason
+		}
+	}
+	return nil
+}
+
+func TestSessionRotateIssuesNewToken(t *testing.T) {
+	repo := NewMockSessionRepository()
+	svc := NewSessionService(repo, config.AuthConfig{RefreshTokenTTL: time.Hour})
+	userID := uuid.New()
+
+	session, token, err := svc.Start(userID, domain.DeviceInfo{DeviceID: "phone-1"})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	rotated, newToken, err := svc.Rotate(token, domain.DeviceInfo{})
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if rotated.ID != session.ID {
+		t.Errorf("Expected rotation to keep session %s, got %s", session.ID, rotated.ID)
+	}
+	if newToken == token {
+		t.Error("Expected a new refresh token")
+	}
+	if active, _ := svc.IsActive(session.ID, userID); !active {
+		t.Error("Expected session to stay active after rotation")
+	}
+}
+
+func TestSessionRotateReuseRevokesSession(t *testing.T) {
+	repo := NewMockSessionRepository()
+	svc := NewSessionService(repo, config.AuthConfig{RefreshTokenTTL: time.Hour})
+	var revoked []uuid.UUID
+	svc.SetRevokeListener(func(ids []uuid.UUID) { revoked = append(revoked, ids...) })
+	userID := uuid.New()
+
+	session, token, _ := svc.Start(userID, domain.DeviceInfo{})
+	if _, _, err := svc.Rotate(token, domain.DeviceInfo{}); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+
+	// Replaying the first token means it leaked
+	if _, _, err := svc.Rotate(token, domain.DeviceInfo{}); err != ErrTokenReused {
+		t.Fatalf("Expected ErrTokenReused, got %v", err)
+	}
+	if active, _ := svc.IsActive(session.ID, userID); active {
+		t.Error("Expected session to be revoked after token reuse")
+	}
+	if repo.Sessions[session.ID].RevokedReason != domain.RevokeReasonTokenReuse {
+		t.Errorf("Expected reason %q, got %q", domain.RevokeReasonTokenReuse, repo.Sessions[session.ID].RevokedReason)
+	}
+	if len(revoked) != 1 || revoked[0] != session.ID {
+		t.Errorf("Expected revoke listener to receive %s, got %v", session.ID, revoked)
+	}
+}
+
+func TestSessionRotateRejectsExpired(t *testing.T) {
+	repo := NewMockSessionRepository()
+	svc := NewSessionService(repo, config.AuthConfig{RefreshTokenTTL: time.Hour})
+
+	session, token, _ := svc.Start(uuid.New(), domain.DeviceInfo{})
+	session.ExpiresAt = time.Now().Add(-time.Minute)
+
+	if _, _, err := svc.Rotate(token, domain.DeviceInfo{}); err != ErrSessionInvalid {
+		t.Fatalf("Expected ErrSessionInvalid, got %v", err)
+	}
+}
+
+func TestSessionRevokeDeviceAndAll(t *testing.T) {
+	repo := NewMockSessionRepository()
+	svc := NewSessionService(repo, config.AuthConfig{RefreshTokenTTL: time.Hour})
+	userID := uuid.New()
+
+	phone, _, _ := svc.Start(userID, domain.DeviceInfo{DeviceID: "phone"})
+	laptop, _, _ := svc.Start(userID, domain.DeviceInfo{DeviceID: "laptop"})
+
+	if err := svc.RevokeDevice(userID, "phone", domain.RevokeReasonAdmin); err != nil {
+		t.Fatalf("RevokeDevice failed: %v", err)
+	}
+	if active, _ := svc.IsActive(phone.ID, userID); active {
+		t.Error("Expected phone session to be revoked")
+	}
+	if active, _ := svc.IsActive(laptop.ID, userID); !active {
+		t.Error("Expected laptop session to stay active")
+	}
+
+	if err := svc.RevokeAll(userID, domain.RevokeReasonRoleChanged); err != nil {
+		t.Fatalf("RevokeAll failed: %v", err)
+	}
+	if active, _ := svc.IsActive(laptop.ID, userID); active {
+		t.Error("Expected laptop session to be revoked")
+	}
+}
+
+func TestSessionIsActiveChecksOwner(t *testing.T) {
+	repo := NewMockSessionRepository()
+	svc := NewSessionService(repo, config.AuthConfig{RefreshTokenTTL: time.Hour})
+
+	session, _, _ := svc.Start(uuid.New(), domain.DeviceInfo{})
+	if active, _ := svc.IsActive(session.ID, uuid.New()); active {
+		t.Error("Expected session not to be active for another user")
+	}
+	if err := svc.Revoke(uuid.New(), session.ID, domain.RevokeReasonAdmin); err != ErrSessionInvalid {
+		t.Errorf("Expected ErrSessionInvalid when revoking another user's session, got %v", err)
+	}
+}
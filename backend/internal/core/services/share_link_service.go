@@ -0,0 +1,284 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/config"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/platform/logger"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+)
+
+var (
+	ErrShareLinkInvalid = errors.New("share link is invalid")
+	ErrShareLinkExpired = errors.New("share link has expired")
+	ErrShareLinkRevoked = errors.New("share link has been revoked")
+)
+
+// ShareLinkService issues and verifies signed, expiring links to /api/public/* resources.
+//
+// A token is base64url(JSON claims) + "." + base64url(HMAC-SHA256(claims)).
+// Scope and expiry are checked from the signature alone; the share_links row is
+// only consulted for revocation.
+type ShareLinkService struct {
+	repo       domain.ShareLinkRepository
+	reportRepo domain.ReportRepository
+	secret     []byte
+	defaultTTL time.Duration
+	now        func() time.Time
+}
+
+func NewShareLinkService(repo domain.ShareLinkRepository, reportRepo domain.ReportRepository, cfg config.AuthConfig) *ShareLinkService {
+	secret := cfg.ShareLinkSecret
+	if secret == "" {
+		secret = cfg.JWTSecret
+	}
+	ttl := cfg.ShareLinkTTL
+	if ttl <= 0 {
+		ttl = 30 * 24 * time.Hour
+	}
+	return &ShareLinkService{
+		repo:       repo,
+		reportRepo: reportRepo,
+		secret:     []byte(secret),
+		defaultTTL: ttl,
+		now:        time.Now,
+	}
+}
+
+// Issue records a share link and returns it with its signed token.
+// A zero ttl uses the configured default.
+func (s *ShareLinkService) Issue(resourceType, resourceID string, filters map[string]string, ttl time.Duration, createdBy *uuid.UUID) (*domain.ShareLink, string, error) {
+	if resourceID == "" {
+		return nil, "", errors.New("resource_id is required")
+	}
+	scoped := nonEmptyFilters(filters)
+
+	switch resourceType {
+	case domain.ShareMediaFolder:
+	case domain.ShareAssignReport, domain.ShareProjectExport, domain.ShareUserCalendar:
+		id, err := uuid.Parse(resourceID)
+		if err != nil {
+			return nil, "", errors.New("invalid resource ID")
+		}
+		resourceID = id.String()
+	case domain.ShareGenericReport:
+		// Bind the report's assign into the token so the bulk report page can load it
+		reportID, err := uuid.Parse(resourceID)
+		if err != nil {
+			return nil, "", errors.New("invalid report ID")
+		}
+		resourceID = reportID.String()
+		report, err := s.reportRepo.FindByID(reportID)
+		if err != nil || report == nil {
+			return nil, "", errors.New("report not found")
+		}
+		scoped[domain.ShareFilterAssign] = report.AssignID.String()
+	default:
+		return nil, "", fmt.Errorf("unknown share resource type %q", resourceType)
+	}
+
+	if ttl <= 0 {
+		ttl = s.defaultTTL
+	}
+	filtersJSON, _ := json.Marshal(scoped)
+	link := &domain.ShareLink{
+		ID:              uuid.New(),
+		ResourceType:    resourceType,
+		ResourceID:      resourceID,
+		Filters:         datatypes.JSON(filtersJSON),
+		ExpiresAt:       s.now().Add(ttl),
+		PersonCreatedID: createdBy,
+	}
+	if err := s.repo.Create(link); err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.sign(domain.ShareClaims{
+		LinkID:       link.ID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Filters:      scoped,
+		ExpiresAt:    link.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return link, token, nil
+}
+
+// IssueShared returns a token for an unrevoked link to the same resource and
+// filters that is still valid for at least half of ttl, and only issues a new
+// link when there is none. Links generated on every export or sync reuse one
+// row this way, so they can still be listed and revoked.
+func (s *ShareLinkService) IssueShared(resourceType, resourceID string, filters map[string]string, ttl time.Duration, createdBy *uuid.UUID) (*domain.ShareLink, string, error) {
+	if ttl <= 0 {
+		ttl = s.defaultTTL
+	}
+	links, err := s.repo.FindByResource(resourceType, resourceID)
+	if err != nil {
+		return nil, "", err
+	}
+	wanted := nonEmptyFilters(filters)
+	validUntil := s.now().Add(ttl / 2)
+	for i := range links {
+		link := &links[i]
+		if link.RevokedAt != nil || link.ExpiresAt.Before(validUntil) {
+			continue
+		}
+		stored := map[string]string{}
+		if len(link.Filters) > 0 && json.Unmarshal(link.Filters, &stored) != nil {
+			continue
+		}
+		if !sameFilters(stored, wanted) {
+			continue
+		}
+		token, err := s.sign(domain.ShareClaims{
+			LinkID:       link.ID,
+			ResourceType: link.ResourceType,
+			ResourceID:   link.ResourceID,
+			Filters:      stored,
+			ExpiresAt:    link.ExpiresAt.Unix(),
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		return link, token, nil
+	}
+	return s.Issue(resourceType, resourceID, filters, ttl, createdBy)
+}
+
+func nonEmptyFilters(filters map[string]string) map[string]string {
+	scoped := map[string]string{}
+	for k, v := range filters {
+		if v != "" {
+			scoped[k] = v
+		}
+	}
+	return scoped
+}
+
+func sameFilters(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify checks the token's signature, expiry and revocation status
+func (s *ShareLinkService) Verify(token string) (*domain.ShareClaims, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrShareLinkInvalid
+	}
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, s.mac(payload)) {
+		return nil, ErrShareLinkInvalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+	var claims domain.ShareClaims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, ErrShareLinkInvalid
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrShareLinkExpired
+	}
+
+	link, err := s.repo.FindByID(claims.LinkID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, ErrShareLinkInvalid
+	}
+	if link.RevokedAt != nil {
+		return nil, ErrShareLinkRevoked
+	}
+	return &claims, nil
+}
+
+// ListByResource returns every link issued for a resource, newest first
+func (s *ShareLinkService) ListByResource(resourceType, resourceID string) ([]domain.ShareLink, error) {
+	return s.repo.FindByResource(resourceType, resourceID)
+}
+
+// Revoke invalidates a single link immediately
+func (s *ShareLinkService) Revoke(id uuid.UUID) error {
+	link, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if link == nil {
+		return ErrShareLinkInvalid
+	}
+	return s.repo.Revoke(id)
+}
+
+// RevokeResource invalidates every link issued for a resource and returns how many were revoked
+func (s *ShareLinkService) RevokeResource(resourceType, resourceID string) (int64, error) {
+	return s.repo.RevokeByResource(resourceType, resourceID)
+}
+
+// AssignReportURL issues a link to the frontend report page of an assign.
+// extra carries presentation-only query params (e.g. type=submit).
+// Returns "" when the link cannot be issued so callers can skip it.
+func (s *ShareLinkService) AssignReportURL(frontendURL string, assignID uuid.UUID, assetID, subWorkID *uuid.UUID, extra url.Values) string {
+	if s == nil {
+		return ""
+	}
+	filters := map[string]string{}
+	query := url.Values{}
+	if assetID != nil {
+		filters[domain.ShareFilterAsset] = assetID.String()
+		query.Set(domain.ShareFilterAsset, assetID.String())
+	}
+	if subWorkID != nil {
+		filters[domain.ShareFilterSubWork] = subWorkID.String()
+		query.Set(domain.ShareFilterSubWork, subWorkID.String())
+	}
+	_, token, err := s.IssueShared(domain.ShareAssignReport, assignID.String(), filters, 0, nil)
+	if err != nil {
+		logger.Get().Warn("Failed to issue report share link", zap.String("assign_id", assignID.String()), zap.Error(err))
+		return ""
+	}
+	for k, vs := range extra {
+		for _, v := range vs {
+			query.Add(k, v)
+		}
+	}
+	query.Set("token", token)
+	return fmt.Sprintf("%s/share/report/%s?%s", frontendURL, assignID.String(), query.Encode())
+}
+
+func (s *ShareLinkService) sign(claims domain.ShareClaims) (string, error) {
+	raw, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload)), nil
+}
+
+func (s *ShareLinkService) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
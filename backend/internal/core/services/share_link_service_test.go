@@ -0,0 +1,235 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/config"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// MockShareLinkRepository implements domain.ShareLinkRepository in memory
+type MockShareLinkRepository struct {
+	Links map[uuid.UUID]*domain.ShareLink
+}
+
+func (m *MockShareLinkRepository) Create(link *domain.ShareLink) error {
+	m.Links[link.ID] = link
+	return nil
+}
+func (m *MockShareLinkRepository) FindByID(id uuid.UUID) (*domain.ShareLink, error) {
+	return m.Links[id], nil
+}
+func (m *MockShareLinkRepository) FindByResource(resourceType, resourceID string) ([]domain.ShareLink, error) {
+	var out []domain.ShareLink
+	for _, l := range m.Links {
+		if l.ResourceType == resourceType && l.ResourceID == resourceID {
+			out = append(out, *l)
+		}
+	}
+	return out, nil
+}
+func (m *MockShareLinkRepository) Revoke(id uuid.UUID) error {
+	now := time.Now()
+	m.Links[id].RevokedAt = &now
+	return nil
+}
+func (m *MockShareLinkRepository) RevokeByResource(resourceType, resourceID string) (int64, error) {
+	var n int64
+	now := time.Now()
+	for _, l := range m.Links {
+		if l.ResourceType == resourceType && l.ResourceID == resourceID && l.RevokedAt == nil {
+			l.RevokedAt = &now
+			n++
+		}
+	}
+	return n, nil
+}
+
+// MockReportRepository implements domain.ReportRepository for share link tests
+type MockReportRepository struct {
+	Reports map[uuid.UUID]*domain.Report
+}
+
+func (m *MockReportRepository) Create(r *domain.Report) error { return nil }
+func (m *MockReportRepository) FindByID(id uuid.UUID) (*domain.Report, error) {
+	return m.Reports[id], nil
+}
+func (m *MockReportRepository) FindByAssignID(assignID uuid.UUID) ([]domain.Report, error) {
+	return nil, nil
+}
+func (m *MockReportRepository) Delete(id uuid.UUID) error { return nil }
+
+func newTestShareLinkService() (*ShareLinkService, *MockShareLinkRepository, *MockReportRepository) {
+	repo := &MockShareLinkRepository{Links: make(map[uuid.UUID]*domain.ShareLink)}
+	reports := &MockReportRepository{Reports: make(map[uuid.UUID]*domain.Report)}
+	svc := NewShareLinkService(repo, reports, config.AuthConfig{ShareLinkSecret: "test-secret", ShareLinkTTL: time.Hour})
+	return svc, repo, reports
+}
+
+func TestShareLinkIssueAndVerify(t *testing.T) {
+	svc, _, _ := newTestShareLinkService()
+	assignID := uuid.New()
+	assetID := uuid.New()
+
+	_, token, err := svc.Issue(domain.ShareAssignReport, assignID.String(), map[string]string{domain.ShareFilterAsset: assetID.String()}, 0, nil)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	claims, err := svc.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !claims.GrantsAssign(assignID.String()) {
+		t.Error("Expected claims to grant the assign")
+	}
+	if claims.GrantsAssign(uuid.New().String()) {
+		t.Error("Expected claims not to grant another assign")
+	}
+	if claims.Filters[domain.ShareFilterAsset] != assetID.String() {
+		t.Errorf("Expected asset filter %s, got %q", assetID, claims.Filters[domain.ShareFilterAsset])
+	}
+}
+
+func TestShareLinkRejectsTamperedToken(t *testing.T) {
+	svc, _, _ := newTestShareLinkService()
+	_, token, _ := svc.Issue(domain.ShareAssignReport, uuid.New().String(), nil, 0, nil)
+
+	// Re-sign the payload for another resource with the wrong key
+	other, _, _ := newTestShareLinkService()
+	other.secret = []byte("attacker")
+	_, forged, _ := other.Issue(domain.ShareAssignReport, uuid.New().String(), nil, 0, nil)
+
+	if _, err := svc.Verify(forged); err != ErrShareLinkInvalid {
+		t.Errorf("Expected ErrShareLinkInvalid for a foreign signature, got %v", err)
+	}
+	payload, _, _ := strings.Cut(token, ".")
+	_, sig, _ := strings.Cut(forged, ".")
+	if _, err := svc.Verify(payload + "." + sig); err != ErrShareLinkInvalid {
+		t.Errorf("Expected ErrShareLinkInvalid for a swapped signature, got %v", err)
+	}
+}
+
+func TestShareLinkExpiryAndRevocation(t *testing.T) {
+	svc, _, _ := newTestShareLinkService()
+	assignID := uuid.New().String()
+
+	link, token, _ := svc.Issue(domain.ShareAssignReport, assignID, nil, time.Minute, nil)
+
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := svc.Verify(token); err != ErrShareLinkExpired {
+		t.Errorf("Expected ErrShareLinkExpired, got %v", err)
+	}
+	svc.now = time.Now
+
+	if err := svc.Revoke(link.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := svc.Verify(token); err != ErrShareLinkRevoked {
+		t.Errorf("Expected ErrShareLinkRevoked, got %v", err)
+	}
+
+	_, token2, _ := svc.Issue(domain.ShareAssignReport, assignID, nil, 0, nil)
+	if n, _ := svc.RevokeResource(domain.ShareAssignReport, assignID); n != 1 {
+		t.Errorf("Expected 1 link revoked by resource, got %d", n)
+	}
+	if _, err := svc.Verify(token2); err != ErrShareLinkRevoked {
+		t.Errorf("Expected ErrShareLinkRevoked after resource revoke, got %v", err)
+	}
+}
+
+func TestShareLinkGenericReportGrantsItsAssign(t *testing.T) {
+	svc, _, reports := newTestShareLinkService()
+	report := &domain.Report{ID: uuid.New(), AssignID: uuid.New()}
+	reports.Reports[report.ID] = report
+
+	_, token, err := svc.Issue(domain.ShareGenericReport, report.ID.String(), nil, 0, nil)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	claims, err := svc.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !claims.Grants(domain.ShareGenericReport, report.ID.String()) {
+		t.Error("Expected claims to grant the report")
+	}
+	if !claims.GrantsAssign(report.AssignID.String()) {
+		t.Error("Expected claims to grant the report's assign")
+	}
+}
+
+func TestShareLinkMediaPrefix(t *testing.T) {
+	claims := &domain.ShareClaims{ResourceType: domain.ShareMediaFolder, ResourceID: "du-an-a"}
+	if !claims.GrantsMediaPrefix("du-an-a") || !claims.GrantsMediaPrefix("du-an-a/work") {
+		t.Error("Expected claims to grant the folder and its subfolders")
+	}
+	if claims.GrantsMediaPrefix("du-an-ab") {
+		t.Error("Expected claims not to grant a sibling folder sharing the prefix")
+	}
+}
+
+func TestAssignReportURLCarriesToken(t *testing.T) {
+	svc, _, _ := newTestShareLinkService()
+	assignID := uuid.New()
+	subWorkID := uuid.New()
+
+	link := svc.AssignReportURL("https://om.example", assignID, nil, &subWorkID, url.Values{"type": {"submit"}})
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("Invalid URL %q: %v", link, err)
+	}
+	if u.Path != "/share/report/"+assignID.String() {
+		t.Errorf("Unexpected path %q", u.Path)
+	}
+	if u.Query().Get("type") != "submit" || u.Query().Get("sub") != subWorkID.String() {
+		t.Errorf("Expected type and sub query params, got %q", u.RawQuery)
+	}
+	claims, err := svc.Verify(u.Query().Get("token"))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if claims.Filters[domain.ShareFilterSubWork] != subWorkID.String() {
+		t.Error("Expected sub-work filter to be signed into the token")
+	}
+}
+
+func TestIssueSharedReusesLiveLink(t *testing.T) {
+	svc, repo, _ := newTestShareLinkService()
+	assignID := uuid.New()
+	subWorkID := uuid.New()
+
+	first := svc.AssignReportURL("https://om.example", assignID, nil, &subWorkID, nil)
+	second := svc.AssignReportURL("https://om.example", assignID, nil, &subWorkID, url.Values{"type": {"submit"}})
+	if len(repo.Links) != 1 {
+		t.Fatalf("Expected one link for the same resource and filters, got %d", len(repo.Links))
+	}
+	for _, link := range []string{first, second} {
+		u, _ := url.Parse(link)
+		if _, err := svc.Verify(u.Query().Get("token")); err != nil {
+			t.Errorf("Verify failed for reused link: %v", err)
+		}
+	}
+
+	// Other filters, revoked links and links close to expiry get a new row
+	svc.AssignReportURL("https://om.example", assignID, nil, nil, nil)
+	if len(repo.Links) != 2 {
+		t.Errorf("Expected a new link for different filters, got %d", len(repo.Links))
+	}
+	if _, err := svc.RevokeResource(domain.ShareAssignReport, assignID.String()); err != nil {
+		t.Fatal(err)
+	}
+	svc.AssignReportURL("https://om.example", assignID, nil, &subWorkID, nil)
+	if len(repo.Links) != 3 {
+		t.Errorf("Expected a new link once the old one is revoked, got %d", len(repo.Links))
+	}
+	svc.now = func() time.Time { return time.Now().Add(45 * time.Minute) }
+	svc.AssignReportURL("https://om.example", assignID, nil, &subWorkID, nil)
+	if len(repo.Links) != 4 {
+		t.Errorf("Expected a new link when the old one is about to expire, got %d", len(repo.Links))
+	}
+}
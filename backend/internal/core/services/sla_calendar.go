@@ -0,0 +1,81 @@
+package services
+
+import (
+	"time"
+
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// slaLocation is the time zone working days are counted in (Vietnam has no DST)
+var slaLocation = time.FixedZone("ICT", 7*60*60)
+
+// maxCalendarDays bounds calendar walks (about ten years)
+const maxCalendarDays = 3660
+
+// WorkCalendar knows which days are worked: Monday to Friday, except holidays.
+type WorkCalendar struct {
+	dates     map[string]bool // "2006-01-02"
+	recurring map[string]bool // "01-02"
+}
+
+func NewWorkCalendar(holidays []domain.Holiday) *WorkCalendar {
+	c := &WorkCalendar{dates: map[string]bool{}, recurring: map[string]bool{}}
+	for _, h := range holidays {
+		if h.Recurring {
+			c.recurring[h.Date.Format("01-02")] = true
+		} else {
+			c.dates[h.Date.Format("2006-01-02")] = true
+		}
+	}
+	return c
+}
+
+// IsWorkingDay reports whether the day of t (in Vietnam time) is worked
+func (c *WorkCalendar) IsWorkingDay(t time.Time) bool {
+	t = t.In(slaLocation)
+	if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	return !c.dates[t.Format("2006-01-02")] && !c.recurring[t.Format("01-02")]
+}
+
+func nextMidnight(t time.Time) time.Time {
+	t = t.In(slaLocation)
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, slaLocation)
+}
+
+// AddWorkingHours returns when hours of working-day time have passed since
+// start; time on weekends and holidays does not count.
+func (c *WorkCalendar) AddWorkingHours(start time.Time, hours int) time.Time {
+	remaining := time.Duration(hours) * time.Hour
+	t := start
+	for i := 0; i < maxCalendarDays; i++ {
+		eod := nextMidnight(t)
+		if c.IsWorkingDay(t) {
+			left := eod.Sub(t)
+			if remaining <= left {
+				return t.Add(remaining)
+			}
+			remaining -= left
+		}
+		t = eod
+	}
+	return t.Add(remaining)
+}
+
+// WorkingDuration returns the working-day time between from and to
+func (c *WorkCalendar) WorkingDuration(from, to time.Time) time.Duration {
+	var total time.Duration
+	t := from
+	for i := 0; i < maxCalendarDays && t.Before(to); i++ {
+		end := nextMidnight(t)
+		if end.After(to) {
+			end = to
+		}
+		if c.IsWorkingDay(t) {
+			total += end.Sub(t)
+		}
+		t = end
+	}
+	return total
+}
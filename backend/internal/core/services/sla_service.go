@@ -0,0 +1,520 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/platform/logger"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+)
+
+const defaultAtRiskPercent = 80
+
+// NotifyFunc delivers a message to one user (e.g. websocket.Hub.SendToUser)
+type NotifyFunc func(userID uuid.UUID, msg []byte)
+
+// SLAService manages SLA policies and the holiday calendar, evaluates task
+// deadlines and runs the scanner that tracks overdue work and escalates it.
+type SLAService struct {
+	repo       domain.SLARepository
+	notify     NotifyFunc
+	cronRunner *cron.Cron
+	now        func() time.Time
+}
+
+func NewSLAService(repo domain.SLARepository, notify NotifyFunc) *SLAService {
+	return &SLAService{
+		repo:       repo,
+		notify:     notify,
+		cronRunner: cron.New(),
+		now:        time.Now,
+	}
+}
+
+// SLAPolicyInput is the editable part of a policy
+type SLAPolicyInput struct {
+	Name            string                  `json:"name"`
+	ProjectID       *uuid.UUID              `json:"id_project"`
+	WorkID          *uuid.UUID              `json:"id_work"`
+	ResponseHours   int                     `json:"response_hours"`
+	CompletionHours int                     `json:"completion_hours"`
+	ReviewHours     int                     `json:"review_hours"`
+	AtRiskPercent   int                     `json:"at_risk_percent"` // 0 = 80
+	Escalation      []domain.EscalationStep `json:"escalation"`
+}
+
+// HolidayInput adds a non-working day
+type HolidayInput struct {
+	Date      string `json:"date" binding:"required"` // YYYY-MM-DD
+	Name      string `json:"name"`
+	Recurring bool   `json:"recurring"`
+}
+
+// SLAClockState is one deadline of a task
+type SLAClockState struct {
+	Clock     domain.SLAClock  `json:"clock"`
+	Status    domain.SLAStatus `json:"status"`
+	StartedAt time.Time        `json:"started_at"`
+	DueAt     time.Time        `json:"due_at"`
+	StoppedAt *time.Time       `json:"stopped_at,omitempty"`
+}
+
+// TaskSLA is the SLA picture of a task. Status is that of its most urgent
+// running clock, or empty when none is running.
+type TaskSLA struct {
+	DetailAssignID uuid.UUID        `json:"id_detail_assign"`
+	PolicyID       *uuid.UUID       `json:"id_policy"`
+	Status         domain.SLAStatus `json:"status,omitempty"`
+	Clocks         []SLAClockState  `json:"clocks"`
+	current        *SLAClockState
+}
+
+// Start runs the scanner every 15 minutes
+func (s *SLAService) Start() {
+	log := logger.Get()
+	if _, err := s.cronRunner.AddFunc("*/15 * * * *", s.runScheduled); err != nil {
+		log.Fatal("Failed to setup SLA cron job", zap.Error(err))
+	}
+	s.cronRunner.Start()
+	log.Info("SLA scanner started. Scheduled to run every 15 minutes.")
+}
+
+// Stop gracefully stops the scanner
+func (s *SLAService) Stop() {
+	if s.cronRunner != nil {
+		s.cronRunner.Stop()
+	}
+}
+
+func (s *SLAService) runScheduled() {
+	if err := s.Scan(); err != nil {
+		logger.Get().Error("SLA scan failed", zap.Error(err))
+	}
+}
+
+// ---- Policies & holidays ----
+
+func (s *SLAService) ListPolicies() ([]domain.SLAPolicy, error) {
+	return s.repo.FindPolicies()
+}
+
+func (s *SLAService) CreatePolicy(input SLAPolicyInput) (*domain.SLAPolicy, error) {
+	policy := &domain.SLAPolicy{ID: uuid.New()}
+	if err := s.applyPolicy(policy, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreatePolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (s *SLAService) UpdatePolicy(id uuid.UUID, input SLAPolicyInput) (*domain.SLAPolicy, error) {
+	policy, err := s.findPolicy(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyPolicy(policy, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (s *SLAService) DeletePolicy(id uuid.UUID) error {
+	if _, err := s.findPolicy(id); err != nil {
+		return err
+	}
+	return s.repo.DeletePolicy(id)
+}
+
+func (s *SLAService) findPolicy(id uuid.UUID) (*domain.SLAPolicy, error) {
+	policy, err := s.repo.FindPolicyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound.Code, "SLA policy not found", http.StatusNotFound)
+	}
+	return policy, nil
+}
+
+func sameScope(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func (s *SLAService) applyPolicy(policy *domain.SLAPolicy, input SLAPolicyInput) error {
+	invalid := func(msg string) error {
+		return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+	}
+	if input.ResponseHours < 0 || input.CompletionHours < 0 || input.ReviewHours < 0 {
+		return invalid("SLA hours must not be negative")
+	}
+	if input.AtRiskPercent == 0 {
+		input.AtRiskPercent = defaultAtRiskPercent
+	}
+	if input.AtRiskPercent < 1 || input.AtRiskPercent > 100 {
+		return invalid("at_risk_percent must be between 1 and 100")
+	}
+	for i, step := range input.Escalation {
+		if step.AfterHours < 0 || len(step.UserIDs) == 0 {
+			return invalid(fmt.Sprintf("escalation step %d needs after_hours >= 0 and at least one user", i+1))
+		}
+		if i > 0 && step.AfterHours < input.Escalation[i-1].AfterHours {
+			return invalid("escalation steps must be in order of after_hours")
+		}
+	}
+
+	// One policy per scope, so which one applies is never ambiguous
+	existing, err := s.repo.FindPolicies()
+	if err != nil {
+		return err
+	}
+	for _, p := range existing {
+		if p.ID != policy.ID && sameScope(p.ProjectID, input.ProjectID) && sameScope(p.WorkID, input.WorkID) {
+			return apperrors.NewAppError(apperrors.ErrConflict.Code, "An SLA policy already exists for this project and work", http.StatusConflict)
+		}
+	}
+
+	steps := input.Escalation
+	if steps == nil {
+		steps = []domain.EscalationStep{}
+	}
+	escalation, _ := json.Marshal(steps)
+	policy.Name = strings.TrimSpace(input.Name)
+	policy.ProjectID = input.ProjectID
+	policy.WorkID = input.WorkID
+	policy.Project = nil
+	policy.Work = nil
+	policy.ResponseHours = input.ResponseHours
+	policy.CompletionHours = input.CompletionHours
+	policy.ReviewHours = input.ReviewHours
+	policy.AtRiskPercent = input.AtRiskPercent
+	policy.Escalation = datatypes.JSON(escalation)
+	return nil
+}
+
+func (s *SLAService) ListHolidays() ([]domain.Holiday, error) {
+	return s.repo.FindHolidays()
+}
+
+func (s *SLAService) CreateHoliday(input HolidayInput) (*domain.Holiday, error) {
+	date, err := time.Parse("2006-01-02", input.Date)
+	if err != nil {
+		return nil, apperrors.NewAppError(apperrors.ErrValidation.Code, "date must be YYYY-MM-DD", http.StatusBadRequest)
+	}
+	holiday := &domain.Holiday{ID: uuid.New(), Date: date, Name: strings.TrimSpace(input.Name), Recurring: input.Recurring}
+	if err := s.repo.CreateHoliday(holiday); err != nil {
+		return nil, err
+	}
+	return holiday, nil
+}
+
+func (s *SLAService) DeleteHoliday(id uuid.UUID) error {
+	return s.repo.DeleteHoliday(id)
+}
+
+// ---- Evaluation ----
+
+// ForAssign evaluates the SLA of every task of an assign as of now
+func (s *SLAService) ForAssign(assignID uuid.UUID) ([]TaskSLA, error) {
+	details, err := s.repo.FindAssignDetails(assignID)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := s.repo.FindPolicies()
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluateAll(details, policies)
+}
+
+func (s *SLAService) evaluateAll(details []domain.DetailAssign, policies []domain.SLAPolicy) ([]TaskSLA, error) {
+	holidays, err := s.repo.FindHolidays()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(details))
+	for i, d := range details {
+		ids[i] = d.ID
+	}
+	events, err := s.repo.FindEvents(ids)
+	if err != nil {
+		return nil, err
+	}
+	byDetail := make(map[uuid.UUID][]domain.DetailAssignEvent)
+	for _, e := range events {
+		byDetail[e.DetailAssignID] = append(byDetail[e.DetailAssignID], e)
+	}
+
+	cal := NewWorkCalendar(holidays)
+	now := s.now()
+	tasks := make([]TaskSLA, 0, len(details))
+	for i := range details {
+		d := &details[i]
+		policy := policyFor(policies, d)
+		if policy == nil {
+			continue
+		}
+		tasks = append(tasks, evaluateSLA(d, policy, cal, byDetail[d.ID], now))
+	}
+	return tasks, nil
+}
+
+// policyFor returns the most specific policy that applies to a task, or nil
+func policyFor(policies []domain.SLAPolicy, detail *domain.DetailAssign) *domain.SLAPolicy {
+	if detail.Assign == nil {
+		return nil
+	}
+	var workID *uuid.UUID
+	if detail.Config != nil && detail.Config.SubWork != nil {
+		workID = &detail.Config.SubWork.WorkID
+	}
+	var best *domain.SLAPolicy
+	for i := range policies {
+		p := &policies[i]
+		if p.Matches(detail.Assign.ProjectID, workID) && (best == nil || p.Specificity() > best.Specificity()) {
+			best = p
+		}
+	}
+	return best
+}
+
+// evaluateSLA works out each clock of a task from its history. Deadlines are
+// counted in working-day hours from when the clock started.
+func evaluateSLA(detail *domain.DetailAssign, policy *domain.SLAPolicy, cal *WorkCalendar, events []domain.DetailAssignEvent, now time.Time) TaskSLA {
+	task := TaskSLA{DetailAssignID: detail.ID, PolicyID: &policy.ID, Clocks: []SLAClockState{}}
+
+	var firstWork, lastSubmit, lastApprove, lastReject *domain.DetailAssignEvent
+	for i := range events {
+		e := &events[i]
+		switch e.EventType {
+		case domain.DetailEventSaveDraft, domain.DetailEventSubmit:
+			if firstWork == nil {
+				firstWork = e
+			}
+			if e.EventType == domain.DetailEventSubmit {
+				lastSubmit, lastApprove, lastReject = e, nil, nil
+			}
+		case domain.DetailEventApprove:
+			lastApprove = e
+		case domain.DetailEventReject:
+			lastReject = e
+		}
+	}
+	// Tasks from before event history fall back to their last update
+	at := func(e *domain.DetailAssignEvent) *time.Time {
+		if e != nil {
+			return &e.CreatedAt
+		}
+		return &detail.UpdatedAt
+	}
+
+	start := detail.Assign.CreatedAt
+	if detail.Assign.StartTime != nil {
+		start = *detail.Assign.StartTime
+	}
+	pct := policy.AtRiskPercent
+	if pct <= 0 {
+		pct = defaultAtRiskPercent
+	}
+	add := func(clock domain.SLAClock, from, due time.Time, stopped *time.Time) {
+		state := SLAClockState{Clock: clock, StartedAt: from, DueAt: due, StoppedAt: stopped}
+		switch {
+		case stopped != nil && stopped.After(due):
+			state.Status = domain.SLABreached
+		case stopped != nil:
+			state.Status = domain.SLAMet
+		case now.After(due):
+			state.Status = domain.SLAOverdue
+		default:
+			state.Status = domain.SLAOnTrack
+			total := cal.WorkingDuration(from, due)
+			if total > 0 && now.After(from) && cal.WorkingDuration(from, now)*100 >= total*time.Duration(pct) {
+				state.Status = domain.SLAAtRisk
+			}
+		}
+		task.Clocks = append(task.Clocks, state)
+	}
+
+	if policy.ResponseHours > 0 {
+		var stopped *time.Time
+		if firstWork != nil || detail.State != domain.DetailStateDraft {
+			stopped = at(firstWork)
+		}
+		add(domain.SLAClockResponse, start, cal.AddWorkingHours(start, policy.ResponseHours), stopped)
+	}
+
+	awaitingReview := detail.State == domain.DetailStateSubmitted || detail.State == domain.DetailStateResubmitted ||
+		detail.State == domain.DetailStatePartiallyApproved
+	handedIn := awaitingReview || detail.State == domain.DetailStateApproved
+	if policy.CompletionHours > 0 || detail.Assign.EndTime != nil {
+		due := cal.AddWorkingHours(start, policy.CompletionHours)
+		if policy.CompletionHours == 0 {
+			due = *detail.Assign.EndTime
+		}
+		var stopped *time.Time
+		if handedIn {
+			stopped = at(lastSubmit)
+		}
+		add(domain.SLAClockCompletion, start, due, stopped)
+	}
+
+	if policy.ReviewHours > 0 && lastSubmit != nil {
+		from := lastSubmit.CreatedAt
+		due := cal.AddWorkingHours(from, policy.ReviewHours)
+		switch {
+		case awaitingReview:
+			add(domain.SLAClockReview, from, due, nil)
+		case detail.State == domain.DetailStateApproved:
+			add(domain.SLAClockReview, from, due, at(lastApprove))
+		case detail.State == domain.DetailStateRejected:
+			add(domain.SLAClockReview, from, due, at(lastReject))
+		}
+	}
+
+	for i := range task.Clocks {
+		c := &task.Clocks[i]
+		if c.StoppedAt != nil {
+			continue
+		}
+		if task.current == nil || c.Status.Worse(task.current.Status) ||
+			(c.Status == task.current.Status && c.DueAt.Before(task.current.DueAt)) {
+			task.current = c
+		}
+	}
+	if task.current != nil {
+		task.Status = task.current.Status
+	}
+	return task
+}
+
+// ---- Scanner ----
+
+// Scan re-evaluates every open task, records its most urgent running clock and
+// sends the notifications it is due: its assignees once it turns at risk, then
+// each escalation step of its policy as it stays overdue.
+func (s *SLAService) Scan() error {
+	details, err := s.repo.FindOpenDetails()
+	if err != nil {
+		return err
+	}
+	policies, err := s.repo.FindPolicies()
+	if err != nil {
+		return err
+	}
+	tasks, err := s.evaluateAll(details, policies)
+	if err != nil {
+		return err
+	}
+	policyByID := make(map[uuid.UUID]*domain.SLAPolicy, len(policies))
+	for i := range policies {
+		policyByID[policies[i].ID] = &policies[i]
+	}
+	detailByID := make(map[uuid.UUID]*domain.DetailAssign, len(details))
+	ids := make([]uuid.UUID, len(details))
+	for i := range details {
+		detailByID[details[i].ID] = &details[i]
+		ids[i] = details[i].ID
+	}
+	tracked, err := s.repo.FindTracking(ids)
+	if err != nil {
+		return err
+	}
+	previous := make(map[uuid.UUID]domain.DetailSLA, len(tracked))
+	for _, row := range tracked {
+		previous[row.DetailAssignID] = row
+	}
+
+	now := s.now()
+	rows := make([]domain.DetailSLA, 0, len(tasks))
+	for _, task := range tasks {
+		if task.current == nil {
+			continue
+		}
+		detail := detailByID[task.DetailAssignID]
+		row := domain.DetailSLA{
+			DetailAssignID: detail.ID,
+			AssignID:       detail.AssignID,
+			ProjectID:      detail.Assign.ProjectID,
+			PolicyID:       task.PolicyID,
+			Clock:          task.current.Clock,
+			Status:         task.current.Status,
+			DueAt:          task.current.DueAt,
+		}
+		// A new deadline (next clock, or a resubmission) starts escalation over
+		if prev, ok := previous[detail.ID]; ok && prev.Clock == row.Clock && prev.DueAt.Equal(row.DueAt) {
+			row.EscalationLevel = prev.EscalationLevel
+			row.AtRiskNotified = prev.AtRiskNotified
+		}
+		s.escalate(detail, policyByID[*task.PolicyID], &row, now)
+		rows = append(rows, row)
+		delete(previous, detail.ID)
+	}
+
+	if err := s.repo.SaveTracking(rows); err != nil {
+		return err
+	}
+	// Tasks no longer running a clock (or without a policy any more)
+	stale := make([]uuid.UUID, 0, len(previous))
+	for id := range previous {
+		stale = append(stale, id)
+	}
+	if err := s.repo.DeleteTracking(stale); err != nil {
+		return err
+	}
+	return s.repo.PruneTracking()
+}
+
+func (s *SLAService) escalate(detail *domain.DetailAssign, policy *domain.SLAPolicy, row *domain.DetailSLA, now time.Time) {
+	if s.notify == nil || policy == nil {
+		return
+	}
+	send := func(event string, level int, userIDs []uuid.UUID) {
+		msg, _ := json.Marshal(map[string]interface{}{
+			"event":            event,
+			"id_detail_assign": row.DetailAssignID,
+			"id_assign":        row.AssignID,
+			"id_project":       row.ProjectID,
+			"clock":            row.Clock,
+			"status":           row.Status,
+			"due_at":           row.DueAt,
+			"level":            level,
+		})
+		for _, uid := range userIDs {
+			s.notify(uid, msg)
+		}
+	}
+
+	if (row.Status == domain.SLAAtRisk || row.Status == domain.SLAOverdue) && !row.AtRiskNotified {
+		send("sla_"+string(row.Status), 0, detail.Holders(detail.Assign.Users()))
+		row.AtRiskNotified = true
+	}
+	if row.Status != domain.SLAOverdue {
+		return
+	}
+	overdueFor := now.Sub(row.DueAt)
+	steps := policy.EscalationSteps()
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].AfterHours < steps[j].AfterHours })
+	for i := row.EscalationLevel; i < len(steps); i++ {
+		if overdueFor < time.Duration(steps[i].AfterHours)*time.Hour {
+			break
+		}
+		send("sla_escalation", i+1, steps[i].UserIDs)
+		row.EscalationLevel = i + 1
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+	"gorm.io/datatypes"
+)
+
+// MockSLARepository implements domain.SLARepository in memory
+type MockSLARepository struct {
+	Policies []domain.SLAPolicy
+	Holidays []domain.Holiday
+	Details  []domain.DetailAssign
+	Events   []domain.DetailAssignEvent
+	Tracking map[uuid.UUID]domain.DetailSLA
+}
+
+func (m *MockSLARepository) CreatePolicy(p *domain.SLAPolicy) error {
+	m.Policies = append(m.Policies, *p)
+	return nil
+}
+func (m *MockSLARepository) FindPolicies() ([]domain.SLAPolicy, error) { return m.Policies, nil }
+func (m *MockSLARepository) FindPolicyByID(id uuid.UUID) (*domain.SLAPolicy, error) {
+	for i := range m.Policies {
+		if m.Policies[i].ID == id {
+			return &m.Policies[i], nil
+		}
+	}
+	return nil, nil
+}
+func (m *MockSLARepository) UpdatePolicy(p *domain.SLAPolicy) error          { return nil }
+func (m *MockSLARepository) DeletePolicy(id uuid.UUID) error                 { return nil }
+func (m *MockSLARepository) CreateHoliday(h *domain.Holiday) error           { return nil }
+func (m *MockSLARepository) FindHolidays() ([]domain.Holiday, error)         { return m.Holidays, nil }
+func (m *MockSLARepository) DeleteHoliday(id uuid.UUID) error                { return nil }
+func (m *MockSLARepository) FindOpenDetails() ([]domain.DetailAssign, error) { return m.Details, nil }
+func (m *MockSLARepository) FindAssignDetails(uuid.UUID) ([]domain.DetailAssign, error) {
+	return m.Details, nil
+}
+func (m *MockSLARepository) FindEvents([]uuid.UUID) ([]domain.DetailAssignEvent, error) {
+	return m.Events, nil
+}
+func (m *MockSLARepository) FindTracking([]uuid.UUID) ([]domain.DetailSLA, error) {
+	var rows []domain.DetailSLA
+	for _, r := range m.Tracking {
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
+func (m *MockSLARepository) SaveTracking(rows []domain.DetailSLA) error {
+	for _, r := range rows {
+		m.Tracking[r.DetailAssignID] = r
+	}
+	return nil
+}
+func (m *MockSLARepository) DeleteTracking(ids []uuid.UUID) error {
+	for _, id := range ids {
+		delete(m.Tracking, id)
+	}
+	return nil
+}
+func (m *MockSLARepository) PruneTracking() error { return nil }
+
+func vnTime(year int, month time.Month, day, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, slaLocation)
+}
+
+func TestWorkCalendarSkipsWeekendsAndHolidays(t *testing.T) {
+	cal := NewWorkCalendar([]domain.Holiday{
+		{Date: time.Date(2000, 4, 30, 0, 0, 0, 0, time.UTC), Recurring: true},
+		{Date: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	// Wed 29/4/2026 10:00 + 24 working hours: 30/4 and 1/5 are holidays,
+	// 2-3/5 a weekend, so the deadline lands on Mon 4/5 10:00
+	start := vnTime(2026, 4, 29, 10)
+	if due := cal.AddWorkingHours(start, 24); !due.Equal(vnTime(2026, 5, 4, 10)) {
+		t.Errorf("AddWorkingHours = %v", due)
+	}
+	if got := cal.WorkingDuration(start, vnTime(2026, 5, 4, 10)); got != 24*time.Hour {
+		t.Errorf("WorkingDuration = %v", got)
+	}
+	// A clock started on a weekend only runs from Monday
+	if due := cal.AddWorkingHours(vnTime(2026, 5, 9, 15), 8); !due.Equal(vnTime(2026, 5, 11, 8)) {
+		t.Errorf("AddWorkingHours from Saturday = %v", due)
+	}
+}
+
+func TestEvaluateSLAClocks(t *testing.T) {
+	cal := NewWorkCalendar(nil)
+	start := vnTime(2026, 6, 1, 8) // Monday
+	policy := &domain.SLAPolicy{ID: uuid.New(), ResponseHours: 4, CompletionHours: 48, ReviewHours: 8, AtRiskPercent: 75}
+	assign := &domain.Assign{ID: uuid.New(), ProjectID: uuid.New(), StartTime: &start}
+	detail := &domain.DetailAssign{ID: uuid.New(), AssignID: assign.ID, Assign: assign, State: domain.DetailStateDraft}
+
+	// Untouched for 3.5h: response at risk, completion on track
+	task := evaluateSLA(detail, policy, cal, nil, start.Add(210*time.Minute))
+	if task.Status != domain.SLAAtRisk || task.current.Clock != domain.SLAClockResponse {
+		t.Fatalf("untouched task = %+v", task)
+	}
+	// Untouched for 5h: overdue
+	if task = evaluateSLA(detail, policy, cal, nil, start.Add(5*time.Hour)); task.Status != domain.SLAOverdue {
+		t.Fatalf("late task = %+v", task)
+	}
+
+	// Submitted after 6h: response breached, completion met, review running
+	submitted := start.Add(6 * time.Hour)
+	detail.State = domain.DetailStateSubmitted
+	events := []domain.DetailAssignEvent{{DetailAssignID: detail.ID, EventType: domain.DetailEventSubmit, CreatedAt: submitted}}
+	task = evaluateSLA(detail, policy, cal, events, submitted.Add(time.Hour))
+	statuses := map[domain.SLAClock]domain.SLAStatus{}
+	for _, c := range task.Clocks {
+		statuses[c.Clock] = c.Status
+	}
+	if statuses[domain.SLAClockResponse] != domain.SLABreached || statuses[domain.SLAClockCompletion] != domain.SLAMet ||
+		statuses[domain.SLAClockReview] != domain.SLAOnTrack || task.current.Clock != domain.SLAClockReview {
+		t.Fatalf("submitted task clocks = %+v", task.Clocks)
+	}
+
+	// Rejected: review stops, completion runs again for the rework
+	detail.State = domain.DetailStateRejected
+	events = append(events, domain.DetailAssignEvent{DetailAssignID: detail.ID, EventType: domain.DetailEventReject, CreatedAt: submitted.Add(2 * time.Hour)})
+	task = evaluateSLA(detail, policy, cal, events, submitted.Add(3*time.Hour))
+	if task.current == nil || task.current.Clock != domain.SLAClockCompletion {
+		t.Fatalf("rejected task = %+v", task)
+	}
+}
+
+func TestScanEscalatesOverdueTasks(t *testing.T) {
+	start := vnTime(2026, 6, 1, 8)
+	worker, lead, director := uuid.New(), uuid.New(), uuid.New()
+	steps, _ := json.Marshal([]domain.EscalationStep{
+		{AfterHours: 0, UserIDs: []uuid.UUID{lead}},
+		{AfterHours: 24, UserIDs: []uuid.UUID{director}},
+	})
+	users, _ := json.Marshal([]uuid.UUID{worker})
+	assign := &domain.Assign{ID: uuid.New(), ProjectID: uuid.New(), StartTime: &start, UserIDs: datatypes.JSON(users)}
+	detail := domain.DetailAssign{ID: uuid.New(), AssignID: assign.ID, Assign: assign, State: domain.DetailStateDraft}
+	repo := &MockSLARepository{
+		Policies: []domain.SLAPolicy{{ID: uuid.New(), ProjectID: &assign.ProjectID, ResponseHours: 4, AtRiskPercent: 80, Escalation: datatypes.JSON(steps)}},
+		Details:  []domain.DetailAssign{detail},
+		Tracking: map[uuid.UUID]domain.DetailSLA{},
+	}
+	notified := map[uuid.UUID][]string{}
+	svc := NewSLAService(repo, func(uid uuid.UUID, msg []byte) {
+		var m map[string]interface{}
+		_ = json.Unmarshal(msg, &m)
+		notified[uid] = append(notified[uid], m["event"].(string))
+	})
+
+	now := start.Add(5 * time.Hour) // 1h overdue
+	svc.now = func() time.Time { return now }
+	if err := svc.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	row := repo.Tracking[detail.ID]
+	if row.Status != domain.SLAOverdue || row.EscalationLevel != 1 {
+		t.Fatalf("tracked = %+v", row)
+	}
+	if len(notified[worker]) != 1 || len(notified[lead]) != 1 || len(notified[director]) != 0 {
+		t.Fatalf("notified = %v", notified)
+	}
+
+	// Rescanning sends nothing new until the next step is due
+	if err := svc.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(24 * time.Hour)
+	if err := svc.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if len(notified[worker]) != 1 || len(notified[lead]) != 1 || len(notified[director]) != 1 || repo.Tracking[detail.ID].EscalationLevel != 2 {
+		t.Fatalf("after escalation: notified = %v, tracked = %+v", notified, repo.Tracking[detail.ID])
+	}
+
+	// Once work starts no clock runs and the task is no longer tracked
+	repo.Events = []domain.DetailAssignEvent{{DetailAssignID: detail.ID, EventType: domain.DetailEventSaveDraft, CreatedAt: now}}
+	repo.Details[0].State = domain.DetailStateInProgress
+	if err := svc.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo.Tracking[detail.ID]; ok {
+		t.Error("task with no running clock should not be tracked")
+	}
+}
@@ -16,3 +16,11 @@ func NewStatsService(statsRepo domain.StatsRepository) *StatsService {
 func (s *StatsService) GetManagerStats(managerID uuid.UUID) (*domain.ManagerStats, error) {
 	return s.statsRepo.GetManagerDashboardStats(managerID)
 }
+
+func (s *StatsService) GetSLACounts(filter domain.SLAFilter) (*domain.SLACounts, error) {
+	return s.statsRepo.GetSLACounts(filter)
+}
+
+func (s *StatsService) FindSLATasks(filter domain.SLAFilter) ([]domain.DetailSLA, error) {
+	return s.statsRepo.FindSLATasks(filter)
+}
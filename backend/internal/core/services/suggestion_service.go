@@ -0,0 +1,405 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/phuc/cmms-backend/internal/core/errors"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// Weights of the suggestion factors; together they make a score out of 100
+const (
+	weightWorkload       = 35
+	weightTeam           = 15
+	weightCertifications = 20
+	weightFirstPass      = 20
+	weightAvailability   = 10
+
+	// suggestionFullLoad is the number of open tasks at which the workload factor reaches zero
+	suggestionFullLoad = 20
+	suggestionLimit    = 10
+	suggestionMaxLimit = 50
+)
+
+// Suggestion factor names
+const (
+	FactorWorkload       = "workload"
+	FactorTeam           = "team"
+	FactorCertifications = "certifications"
+	FactorFirstPass      = "first_pass_rate"
+	FactorAvailability   = "availability"
+)
+
+// SuggestionService ranks engineers for a new assign. Every score is the sum
+// of weighted factors, each with its reason, so managers can see why someone
+// is suggested; engineers missing a required certification or already taken
+// during the assign's dates are listed but not eligible.
+type SuggestionService struct {
+	repo         domain.SuggestionRepository
+	certs        domain.CertificationRepository
+	templateRepo domain.TemplateRepository
+	members      domain.ProjectMemberRepository
+	availability *AvailabilityService
+	now          func() time.Time
+}
+
+func NewSuggestionService(repo domain.SuggestionRepository, certs domain.CertificationRepository, templateRepo domain.TemplateRepository, members domain.ProjectMemberRepository, availability *AvailabilityService) *SuggestionService {
+	return &SuggestionService{
+		repo:         repo,
+		certs:        certs,
+		templateRepo: templateRepo,
+		members:      members,
+		availability: availability,
+		now:          time.Now,
+	}
+}
+
+// SuggestionInput describes the assign to staff, in the fields of POST /assigns.
+// The configs default to the template's; the team to those of the project's
+// members. Count is how many engineers to pick (default 1), Limit how many
+// candidates to rank (default 10).
+type SuggestionInput struct {
+	ProjectID  uuid.UUID   `json:"id_project" binding:"required"`
+	TemplateID *uuid.UUID  `json:"id_template"`
+	ConfigIDs  []uuid.UUID `json:"id_configs"`
+	TeamID     *uuid.UUID  `json:"id_team"`
+	StartTime  *time.Time  `json:"start_time"`
+	EndTime    *time.Time  `json:"end_time"`
+	Count      int         `json:"count"`
+	Limit      int         `json:"limit"`
+}
+
+// ScoreFactor is one part of a candidate's score: Value (0-1) times Weight
+type ScoreFactor struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	Value  float64 `json:"value"`
+	Points float64 `json:"points"`
+	Reason string  `json:"reason"`
+}
+
+// CandidateScore ranks one engineer
+type CandidateScore struct {
+	UserID    uuid.UUID            `json:"id_user"`
+	User      *domain.User         `json:"user,omitempty"`
+	Score     float64              `json:"score"`
+	Eligible  bool                 `json:"eligible"`
+	OpenTasks int                  `json:"open_tasks"`
+	Factors   []ScoreFactor        `json:"factors"`
+	Conflicts *domain.UserSchedule `json:"conflicts,omitempty"`
+}
+
+// AssignSuggestion echoes the assign with the best eligible engineers filled
+// in as id_users, so it can be posted to POST /assigns as it is
+type AssignSuggestion struct {
+	ProjectID  uuid.UUID        `json:"id_project"`
+	TemplateID *uuid.UUID       `json:"id_template,omitempty"`
+	ConfigIDs  []uuid.UUID      `json:"id_configs,omitempty"`
+	StartTime  *time.Time       `json:"start_time,omitempty"`
+	EndTime    *time.Time       `json:"end_time,omitempty"`
+	UserIDs    []uuid.UUID      `json:"id_users"`
+	Works      []domain.Work    `json:"works"`
+	Candidates []CandidateScore `json:"candidates"`
+}
+
+func suggestionInvalid(msg string) error {
+	return apperrors.NewAppError(apperrors.ErrValidation.Code, msg, http.StatusBadRequest)
+}
+
+// Suggest ranks the engineers for an assign, best first
+func (s *SuggestionService) Suggest(input SuggestionInput) (*AssignSuggestion, error) {
+	if input.StartTime != nil && input.EndTime != nil && !input.EndTime.After(*input.StartTime) {
+		return nil, suggestionInvalid("end_time must be after start_time")
+	}
+	count := input.Count
+	if count <= 0 {
+		count = 1
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = suggestionLimit
+	}
+	if limit > suggestionMaxLimit {
+		limit = suggestionMaxLimit
+	}
+
+	configIDs, err := s.configsOf(input)
+	if err != nil {
+		return nil, err
+	}
+	works, err := s.repo.FindConfigWorks(configIDs)
+	if err != nil {
+		return nil, err
+	}
+	workIDs := make([]uuid.UUID, len(works))
+	for i, w := range works {
+		workIDs[i] = w.ID
+	}
+	candidates, err := s.repo.FindCandidates()
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]uuid.UUID, len(candidates))
+	for i, u := range candidates {
+		userIDs[i] = u.ID
+	}
+
+	load, err := s.openTaskLoad()
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.projectTeams(input, candidates)
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.certs.FindWorkRequirements(workIDs)
+	if err != nil {
+		return nil, err
+	}
+	held, err := s.certs.FindUserCertifications(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := s.repo.FindReviewStats(workIDs)
+	if err != nil {
+		return nil, err
+	}
+	var schedules []domain.UserSchedule
+	dated := input.StartTime != nil && input.EndTime != nil && s.availability != nil
+	if dated {
+		if schedules, err = s.availability.Schedules(userIDs, *input.StartTime, *input.EndTime, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// Certifications must still be valid when the work starts
+	certAt := s.now()
+	if input.StartTime != nil {
+		certAt = *input.StartTime
+	}
+	requiredCerts := map[uuid.UUID]string{}
+	for _, r := range required {
+		name := r.CertificationID.String()
+		if r.Certification != nil {
+			name = r.Certification.Name
+		}
+		requiredCerts[r.CertificationID] = name
+	}
+
+	scores := make([]CandidateScore, len(candidates))
+	for i := range candidates {
+		user := &candidates[i]
+		score := CandidateScore{UserID: user.ID, User: user, Eligible: true, OpenTasks: load[user.ID]}
+
+		score.Factors = append(score.Factors, workloadFactor(load[user.ID]))
+		score.Factors = append(score.Factors, teamFactor(user, teams))
+
+		certFactor, missing := certificationFactor(user.ID, requiredCerts, held, certAt)
+		score.Factors = append(score.Factors, certFactor)
+		if missing {
+			score.Eligible = false
+		}
+
+		score.Factors = append(score.Factors, firstPassFactor(user.ID, reviews))
+
+		var schedule *domain.UserSchedule
+		if dated {
+			schedule = &schedules[i]
+		}
+		score.Factors = append(score.Factors, availabilityFactor(schedule, dated))
+		if schedule != nil && schedule.Busy() {
+			score.Eligible = false
+			score.Conflicts = schedule
+		}
+
+		for _, f := range score.Factors {
+			score.Score += f.Points
+		}
+		score.Score = math.Round(score.Score*10) / 10
+		scores[i] = score
+	}
+
+	sort.SliceStable(scores, func(a, b int) bool {
+		if scores[a].Eligible != scores[b].Eligible {
+			return scores[a].Eligible
+		}
+		if scores[a].Score != scores[b].Score {
+			return scores[a].Score > scores[b].Score
+		}
+		return scores[a].OpenTasks < scores[b].OpenTasks
+	})
+
+	picked := []uuid.UUID{}
+	for _, sc := range scores {
+		if len(picked) == count || !sc.Eligible {
+			break
+		}
+		picked = append(picked, sc.UserID)
+	}
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+	return &AssignSuggestion{
+		ProjectID:  input.ProjectID,
+		TemplateID: input.TemplateID,
+		ConfigIDs:  configIDs,
+		StartTime:  input.StartTime,
+		EndTime:    input.EndTime,
+		UserIDs:    picked,
+		Works:      works,
+		Candidates: scores,
+	}, nil
+}
+
+// configsOf returns the configs of the assign: those given, or the template's
+func (s *SuggestionService) configsOf(input SuggestionInput) ([]uuid.UUID, error) {
+	if len(input.ConfigIDs) > 0 || input.TemplateID == nil {
+		return input.ConfigIDs, nil
+	}
+	template, err := s.templateRepo.FindByID(*input.TemplateID)
+	if err != nil || template == nil {
+		return nil, apperrors.NewAppError(apperrors.ErrNotFound.Code, "Template not found", http.StatusNotFound)
+	}
+	var raw []string
+	if len(template.ConfigIDs) > 0 {
+		_ = json.Unmarshal(template.ConfigIDs, &raw)
+	}
+	var ids []uuid.UUID
+	for _, r := range raw {
+		if id, err := uuid.Parse(r); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// openTaskLoad counts the unapproved tasks each user holds
+func (s *SuggestionService) openTaskLoad() (map[uuid.UUID]int, error) {
+	tasks, err := s.repo.FindOpenTasks()
+	if err != nil {
+		return nil, err
+	}
+	load := map[uuid.UUID]int{}
+	for i := range tasks {
+		for _, u := range tasks[i].Holders() {
+			load[u]++
+		}
+	}
+	return load, nil
+}
+
+// projectTeams returns the teams the assign belongs to: the one asked for, or
+// those of the project's members
+func (s *SuggestionService) projectTeams(input SuggestionInput, candidates []domain.User) (map[uuid.UUID]bool, error) {
+	teams := map[uuid.UUID]bool{}
+	if input.TeamID != nil {
+		teams[*input.TeamID] = true
+		return teams, nil
+	}
+	members, err := s.members.FindByProjectID(input.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	memberIDs := make([]uuid.UUID, len(members))
+	for i, m := range members {
+		memberIDs[i] = m.UserID
+	}
+	for _, u := range candidates {
+		if u.TeamID != nil && containsUUID(memberIDs, u.ID) {
+			teams[*u.TeamID] = true
+		}
+	}
+	return teams, nil
+}
+
+func factor(name string, weight, value float64, reason string) ScoreFactor {
+	value = math.Max(0, math.Min(1, value))
+	return ScoreFactor{Name: name, Weight: weight, Value: math.Round(value*100) / 100, Points: math.Round(weight*value*10) / 10, Reason: reason}
+}
+
+func workloadFactor(open int) ScoreFactor {
+	value := 1 - float64(open)/suggestionFullLoad
+	return factor(FactorWorkload, weightWorkload, value, fmt.Sprintf("%d open tasks", open))
+}
+
+func teamFactor(user *domain.User, teams map[uuid.UUID]bool) ScoreFactor {
+	if len(teams) == 0 {
+		return factor(FactorTeam, weightTeam, 0.5, "No team set for this project")
+	}
+	if user.TeamID != nil && teams[*user.TeamID] {
+		name := "the project's team"
+		if user.Team != nil {
+			name = "team " + user.Team.Name
+		}
+		return factor(FactorTeam, weightTeam, 1, "Member of "+name)
+	}
+	return factor(FactorTeam, weightTeam, 0, "Not in the project's team")
+}
+
+// certificationFactor scores the share of the required certifications the user
+// holds valid at at; missing reports that any is lacking
+func certificationFactor(userID uuid.UUID, required map[uuid.UUID]string, held []domain.UserCertification, at time.Time) (ScoreFactor, bool) {
+	if len(required) == 0 {
+		return factor(FactorCertifications, weightCertifications, 1, "No certification required"), false
+	}
+	valid := map[uuid.UUID]bool{}
+	for i := range held {
+		if held[i].UserID == userID && held[i].ValidAt(at) {
+			valid[held[i].CertificationID] = true
+		}
+	}
+	var lacking []string
+	for id, name := range required {
+		if !valid[id] {
+			lacking = append(lacking, name)
+		}
+	}
+	if len(lacking) == 0 {
+		return factor(FactorCertifications, weightCertifications, 1, fmt.Sprintf("Holds all %d required certifications", len(required))), false
+	}
+	sort.Strings(lacking)
+	value := float64(len(required)-len(lacking)) / float64(len(required))
+	return factor(FactorCertifications, weightCertifications, value, "Missing or expired: "+strings.Join(lacking, ", ")), true
+}
+
+// firstPassFactor scores how often the user's reviewed work on these works was
+// approved first time. It is smoothed towards 50% so that a single review
+// does not decide the factor.
+func firstPassFactor(userID uuid.UUID, reviews []domain.WorkReviewStats) ScoreFactor {
+	var reviewed, firstPass int64
+	for _, r := range reviews {
+		if r.UserID == userID {
+			reviewed += r.Reviewed
+			firstPass += r.FirstPass
+		}
+	}
+	value := float64(firstPass+1) / float64(reviewed+2)
+	if reviewed == 0 {
+		return factor(FactorFirstPass, weightFirstPass, value, "No reviewed tasks on this work yet")
+	}
+	return factor(FactorFirstPass, weightFirstPass, value, fmt.Sprintf("%d of %d reviewed tasks approved first time", firstPass, reviewed))
+}
+
+func availabilityFactor(schedule *domain.UserSchedule, dated bool) ScoreFactor {
+	if !dated {
+		return factor(FactorAvailability, weightAvailability, 1, "No dates given")
+	}
+	if schedule == nil || !schedule.Busy() {
+		return factor(FactorAvailability, weightAvailability, 1, "Free for the whole period")
+	}
+	var parts []string
+	if n := len(schedule.Assigns); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d overlapping assigns", n))
+	}
+	for _, u := range schedule.Unavailable {
+		parts = append(parts, string(u.Kind))
+	}
+	return factor(FactorAvailability, weightAvailability, 0, "Taken: "+strings.Join(parts, ", "))
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+type MockSuggestionRepository struct {
+	Users   []domain.User
+	Works   []domain.Work
+	Tasks   []domain.OpenTask
+	Reviews []domain.WorkReviewStats
+}
+
+func (m *MockSuggestionRepository) FindCandidates() ([]domain.User, error) { return m.Users, nil }
+func (m *MockSuggestionRepository) FindConfigWorks([]uuid.UUID) ([]domain.Work, error) {
+	return m.Works, nil
+}
+func (m *MockSuggestionRepository) FindOpenTasks() ([]domain.OpenTask, error) { return m.Tasks, nil }
+func (m *MockSuggestionRepository) FindReviewStats([]uuid.UUID) ([]domain.WorkReviewStats, error) {
+	return m.Reviews, nil
+}
+
+type MockCertificationRepository struct {
+	Held     []domain.UserCertification
+	Required []domain.WorkCertification
+}
+
+func (m *MockCertificationRepository) Create(*domain.Certification) error       { return nil }
+func (m *MockCertificationRepository) FindAll() ([]domain.Certification, error) { return nil, nil }
+func (m *MockCertificationRepository) FindByIDs([]uuid.UUID) ([]domain.Certification, error) {
+	return nil, nil
+}
+func (m *MockCertificationRepository) Delete(uuid.UUID) error { return nil }
+func (m *MockCertificationRepository) FindUserCertifications([]uuid.UUID) ([]domain.UserCertification, error) {
+	return m.Held, nil
+}
+func (m *MockCertificationRepository) SaveUserCertification(*domain.UserCertification) error {
+	return nil
+}
+func (m *MockCertificationRepository) RemoveUserCertification(uuid.UUID, uuid.UUID) error {
+	return nil
+}
+func (m *MockCertificationRepository) FindWorkRequirements([]uuid.UUID) ([]domain.WorkCertification, error) {
+	return m.Required, nil
+}
+func (m *MockCertificationRepository) SetWorkRequirements(uuid.UUID, []uuid.UUID) error { return nil }
+
+type mockProjectMembers []domain.ProjectMember
+
+func (m mockProjectMembers) FindByProjectID(uuid.UUID) ([]domain.ProjectMember, error) { return m, nil }
+func (m mockProjectMembers) Add(uuid.UUID, []uuid.UUID) error                          { return nil }
+func (m mockProjectMembers) Remove(uuid.UUID, uuid.UUID) error                         { return nil }
+
+func openTasksFor(user uuid.UUID, n int) []domain.OpenTask {
+	tasks := make([]domain.OpenTask, n)
+	for i := range tasks {
+		owner := user
+		tasks[i] = domain.OpenTask{OwnerID: &owner, UserIDs: uuidsJSON([]uuid.UUID{user})}
+	}
+	return tasks
+}
+
+func candidateOf(t *testing.T, s *AssignSuggestion, id uuid.UUID) CandidateScore {
+	t.Helper()
+	for _, c := range s.Candidates {
+		if c.UserID == id {
+			return c
+		}
+	}
+	t.Fatalf("candidate %s not ranked", id)
+	return CandidateScore{}
+}
+
+func TestSuggestRanksByLoadTeamAndFirstPass(t *testing.T) {
+	team := uuid.New()
+	busy, idle, outsider, careless := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	work := domain.Work{ID: uuid.New(), Name: "Inverter check"}
+	repo := &MockSuggestionRepository{
+		Users: []domain.User{
+			{ID: busy, Name: "Busy", TeamID: &team},
+			{ID: idle, Name: "Idle", TeamID: &team},
+			{ID: outsider, Name: "Outsider"},
+			{ID: careless, Name: "Careless", TeamID: &team},
+		},
+		Works: []domain.Work{work},
+		Tasks: openTasksFor(busy, 10),
+		Reviews: []domain.WorkReviewStats{
+			{UserID: careless, WorkID: work.ID, Reviewed: 8, FirstPass: 0},
+			{UserID: idle, WorkID: work.ID, Reviewed: 8, FirstPass: 8},
+		},
+	}
+	members := mockProjectMembers{{UserID: busy}}
+	svc := NewSuggestionService(repo, &MockCertificationRepository{}, nil, members, nil)
+
+	got, err := svc.Suggest(SuggestionInput{ProjectID: uuid.New(), Count: 2})
+	if err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	order := make([]uuid.UUID, len(got.Candidates))
+	for i, c := range got.Candidates {
+		order[i] = c.UserID
+	}
+	want := []uuid.UUID{idle, careless, outsider, busy}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("ranking = %v, want %v", order, want)
+		}
+	}
+	if len(got.UserIDs) != 2 || got.UserIDs[0] != idle || got.UserIDs[1] != careless {
+		t.Fatalf("id_users = %v, want the two best", got.UserIDs)
+	}
+	if c := candidateOf(t, got, busy); c.OpenTasks != 10 || c.Factors[0].Points != 17.5 {
+		t.Fatalf("busy workload = %d tasks, %.1f points", c.OpenTasks, c.Factors[0].Points)
+	}
+	if c := candidateOf(t, got, outsider); c.Factors[1].Points != 0 {
+		t.Fatalf("outsider should get no team points: %+v", c.Factors[1])
+	}
+}
+
+func TestSuggestExcludesUncertifiedAndTaken(t *testing.T) {
+	certified, uncertified, expired, onLeave := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	cert := domain.Certification{ID: uuid.New(), Name: "HV switching"}
+	start, end := marchAt(10, 8), marchAt(10, 17)
+	certs := &MockCertificationRepository{
+		Required: []domain.WorkCertification{{CertificationID: cert.ID, Certification: &cert}},
+		Held: []domain.UserCertification{
+			{UserID: certified, CertificationID: cert.ID},
+			{UserID: onLeave, CertificationID: cert.ID},
+			{UserID: expired, CertificationID: cert.ID, ExpiresAt: marchAt(1, 0)},
+		},
+	}
+	repo := &MockSuggestionRepository{
+		Users: []domain.User{{ID: uncertified}, {ID: expired}, {ID: onLeave}, {ID: certified}},
+		Works: []domain.Work{{ID: uuid.New()}},
+	}
+	availability := NewAvailabilityService(&MockAvailabilityRepository{
+		Entries: []domain.Unavailability{{ID: uuid.New(), UserID: onLeave, Kind: domain.UnavailableLeave, StartTime: *marchAt(9, 0), EndTime: *marchAt(12, 0)}},
+	}, NewMockUserRepository())
+	svc := NewSuggestionService(repo, certs, nil, mockProjectMembers{}, availability)
+
+	got, err := svc.Suggest(SuggestionInput{ProjectID: uuid.New(), StartTime: start, EndTime: end, Count: 3})
+	if err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	if len(got.UserIDs) != 1 || got.UserIDs[0] != certified {
+		t.Fatalf("id_users = %v, want only the certified engineer", got.UserIDs)
+	}
+	if got.Candidates[0].UserID != certified {
+		t.Fatalf("eligible engineer should rank first")
+	}
+	for _, id := range []uuid.UUID{uncertified, expired} {
+		c := candidateOf(t, got, id)
+		if c.Eligible || c.Factors[2].Reason != "Missing or expired: HV switching" {
+			t.Fatalf("candidate %s: eligible=%v, %q", id, c.Eligible, c.Factors[2].Reason)
+		}
+	}
+	if c := candidateOf(t, got, onLeave); c.Eligible || c.Conflicts == nil || len(c.Conflicts.Unavailable) != 1 {
+		t.Fatalf("engineer on leave should be listed with the conflict: %+v", c)
+	}
+
+	if _, err := svc.Suggest(SuggestionInput{ProjectID: uuid.New(), StartTime: end, EndTime: start}); appStatus(err) != http.StatusBadRequest {
+		t.Fatalf("reversed dates should be refused, got %v", err)
+	}
+}
@@ -2,20 +2,57 @@ package services
 
 import (
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/phuc/cmms-backend/internal/adapters/storage/postgres"
 	"github.com/phuc/cmms-backend/internal/domain"
+	"github.com/phuc/cmms-backend/internal/platform/logger"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// ErrAdminOnly refuses a non-admin caller changes that would hand over admin access:
+// granting the admin role, or changing the role, email, password or two-factor login of an admin
+var ErrAdminOnly = errors.New("only an admin can grant the admin role or change an admin's credentials")
+
 type UserService struct {
 	userRepo domain.UserRepository
+	roleRepo postgres.RoleRepository // nil: role grants are not checked
+	sessions *SessionService
+	policy   PasswordPolicy
 }
 
-func NewUserService(userRepo domain.UserRepository) *UserService {
+func NewUserService(userRepo domain.UserRepository, roleRepo postgres.RoleRepository, sessions *SessionService, policy PasswordPolicy) *UserService {
 	return &UserService{
 		userRepo: userRepo,
+		roleRepo: roleRepo,
+		sessions: sessions,
+		policy:   policy,
+	}
+}
+
+// isAdminRole reports whether roleID is the built-in admin role
+func (s *UserService) isAdminRole(roleID uuid.UUID) bool {
+	if s.roleRepo == nil {
+		return false
+	}
+	role, err := s.roleRepo.FindByID(roleID)
+	return err == nil && domain.UserRole(role.Name) == domain.RoleAdmin
+}
+
+// revokeSessions logs the user out everywhere; best-effort so the main operation still succeeds
+func (s *UserService) revokeSessions(userID uuid.UUID, reason string) {
+	if s.sessions == nil {
+		return
+	}
+	if err := s.sessions.RevokeAll(userID, reason); err != nil {
+		logger.Get().Warn("Failed to revoke user sessions",
+			zap.String("user_id", userID.String()),
+			zap.String("reason", reason),
+			zap.Error(err),
+		)
 	}
 }
 
@@ -146,7 +183,8 @@ func (s *UserService) GetUserByEmail(email string) (*domain.User, error) {
 	return user, nil
 }
 
-func (s *UserService) CreateUser(email, password, fullName, roleIDStr, teamIDStr, phoneNumber, creatorIDStr string) (*domain.User, error) {
+// CreateUser adds an account; only an admin (callerRole) may create another admin.
+func (s *UserService) CreateUser(email, password, fullName, roleIDStr, teamIDStr, phoneNumber, creatorIDStr, callerRole string) (*domain.User, error) {
 	// Check if user already exists
 	existingUser, err := s.userRepo.FindByEmail(email)
 	if err != nil {
@@ -155,6 +193,9 @@ func (s *UserService) CreateUser(email, password, fullName, roleIDStr, teamIDStr
 	if existingUser != nil {
 		return nil, errors.New("user with this email already exists")
 	}
+	if err := s.policy.Validate(password); err != nil {
+		return nil, err
+	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -167,14 +208,18 @@ func (s *UserService) CreateUser(email, password, fullName, roleIDStr, teamIDStr
 	if err != nil {
 		return nil, errors.New("invalid role ID format")
 	}
+	if domain.UserRole(callerRole) != domain.RoleAdmin && s.isAdminRole(roleID) {
+		return nil, ErrAdminOnly
+	}
 
-	// Create user
+	// Create user; the admin-chosen password must be replaced on first login
 	user := &domain.User{
 		Email:          email,
 		PasswordHash:   string(hashedPassword),
 		Name: fullName,
 		RoleID:      &roleID,
 		NumberPhone: phoneNumber,
+		MustChangePassword: true,
 	}
 
 	// Parse team ID if provided
@@ -213,10 +258,14 @@ func (s *UserService) DeleteUser(idStr string) error {
 	if err != nil {
 		return errors.New("invalid user ID format")
 	}
-	return s.userRepo.Delete(id)
+	if err := s.userRepo.Delete(id); err != nil {
+		return err
+	}
+	s.revokeSessions(id, domain.RevokeReasonUserDeleted)
+	return nil
 }
 
-func (s *UserService) UpdateUserRole(userIDStr, roleIDStr string) error {
+func (s *UserService) UpdateUserRole(userIDStr, roleIDStr, callerRole string) error {
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
 		return errors.New("invalid user ID format")
@@ -226,11 +275,28 @@ func (s *UserService) UpdateUserRole(userIDStr, roleIDStr string) error {
 	if err != nil {
 		return errors.New("invalid role ID format")
 	}
+	if domain.UserRole(callerRole) != domain.RoleAdmin {
+		user, err := s.userRepo.FindByID(userID)
+		if err != nil {
+			return err
+		}
+		if (user != nil && user.IsAdmin()) || s.isAdminRole(roleID) {
+			return ErrAdminOnly
+		}
+	}
 
-	return s.userRepo.UpdateRole(userID, roleID)
+	if err := s.userRepo.UpdateRole(userID, roleID); err != nil {
+		return err
+	}
+	s.revokeSessions(userID, domain.RevokeReasonRoleChanged)
+	return nil
 }
 
-func (s *UserService) UpdateUser(idStr, email, fullName, numberPhone, roleIDStr, teamIDStr, password string) (*domain.User, error) {
+// UpdateUser edits an account. A password set here is an admin reset: the user
+// must pick a new one on next login and is signed out everywhere. Callers other
+// than an admin (callerRole) may not grant the admin role, nor change an admin's
+// role, email or password.
+func (s *UserService) UpdateUser(idStr, email, fullName, numberPhone, roleIDStr, teamIDStr, password, callerRole string) (*domain.User, error) {
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		return nil, errors.New("invalid user ID format")
@@ -244,6 +310,20 @@ func (s *UserService) UpdateUser(idStr, email, fullName, numberPhone, roleIDStr,
 		return nil, errors.New("user not found")
 	}
 
+	var roleID *uuid.UUID
+	if roleIDStr != "" {
+		if id, err := uuid.Parse(roleIDStr); err == nil {
+			roleID = &id
+		}
+	}
+	roleChanged := roleID != nil && (user.RoleID == nil || *user.RoleID != *roleID)
+	if domain.UserRole(callerRole) != domain.RoleAdmin {
+		credentialsChanged := roleChanged || password != "" || (email != "" && email != user.Email)
+		if (user.IsAdmin() && credentialsChanged) || (roleChanged && s.isAdminRole(*roleID)) {
+			return nil, ErrAdminOnly
+		}
+	}
+
 	// Update basic fields
 	if email != "" {
 		user.Email = email
@@ -255,11 +335,8 @@ func (s *UserService) UpdateUser(idStr, email, fullName, numberPhone, roleIDStr,
 	user.NumberPhone = numberPhone
 
 	// Update Role
-	if roleIDStr != "" {
-		roleID, err := uuid.Parse(roleIDStr)
-		if err == nil {
-			user.RoleID = &roleID
-		}
+	if roleID != nil {
+		user.RoleID = roleID
 	}
 
 	// Update Team (allow clearing if empty)
@@ -272,17 +349,31 @@ func (s *UserService) UpdateUser(idStr, email, fullName, numberPhone, roleIDStr,
 		user.TeamID = nil
 	}
 
-	// Update Password if provided
+	// Update Password if provided (admin reset: the user must pick a new one on next login)
 	if password != "" {
+		if err := s.policy.Validate(password); err != nil {
+			return nil, err
+		}
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-		if err == nil {
-			user.PasswordHash = string(hashedPassword)
+		if err != nil {
+			return nil, errors.New("failed to hash password")
 		}
+		user.PasswordHash = string(hashedPassword)
+		user.MustChangePassword = true
+		user.FailedLoginCount = 0
+		user.LockedUntil = nil
 	}
 
 	if err := s.userRepo.Update(user); err != nil {
 		return nil, err
 	}
+	// Sessions (and their refresh tokens) opened with the old password or role end here
+	switch {
+	case password != "":
+		s.revokeSessions(user.ID, domain.RevokeReasonPasswordReset)
+	case roleChanged:
+		s.revokeSessions(user.ID, domain.RevokeReasonRoleChanged)
+	}
 
 	return user, nil
 }
@@ -303,6 +394,12 @@ func (s *UserService) ChangePassword(idStr, oldPassword, newPassword string) err
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)); err != nil {
 		return errors.New("mật khẩu cũ không đúng")
 	}
+	if err := s.policy.Validate(newPassword); err != nil {
+		return err
+	}
+	if oldPassword == newPassword {
+		return errors.New("mật khẩu mới phải khác mật khẩu cũ")
+	}
 
 	// Hash new password
 	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
@@ -310,7 +407,10 @@ func (s *UserService) ChangePassword(idStr, oldPassword, newPassword string) err
 		return errors.New("failed to hash password")
 	}
 
+	now := time.Now()
 	user.PasswordHash = string(hashed)
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = false
 	return s.userRepo.Update(user)
 }
 
@@ -333,6 +433,8 @@ func (s *UserService) PermanentDeleteUser(idStr string) error {
 	if err != nil {
 		return errors.New("invalid user ID format")
 	}
+	// Revoke first: the session rows cascade away with the user
+	s.revokeSessions(id, domain.RevokeReasonUserDeleted)
 	return s.userRepo.PermanentDelete(id)
 }
 
@@ -357,5 +459,8 @@ func (s *UserService) BulkPermanentDeleteUsers(idStrs []string) error {
 		}
 		ids = append(ids, id)
 	}
+	for _, id := range ids {
+		s.revokeSessions(id, domain.RevokeReasonUserDeleted)
+	}
 	return s.userRepo.BulkPermanentDelete(ids)
 }
@@ -38,6 +38,22 @@ type Container struct {
 	Auth        *handlers.AuthHandler
 	User        *handlers.UserHandler
 	Role        *handlers.RoleHandler
+	Session     *handlers.SessionHandler
+	MFA         *handlers.MFAHandler
+	ShareLink   *handlers.ShareLinkHandler
+	ServiceAcct *handlers.ServiceAccountHandler
+	ApprovalCh  *handlers.ApprovalChainHandler
+	Maintenance *handlers.MaintenancePlanHandler
+	SLA         *handlers.SLAHandler
+	Defect      *handlers.DefectHandler
+	Handover    *handlers.HandoverHandler
+	Form        *handlers.FormHandler
+	Dependency  *handlers.DependencyHandler
+	Comment     *handlers.CommentHandler
+	Calendar    *handlers.AvailabilityHandler
+	Certs       *handlers.CertificationHandler
+	Suggest     *handlers.SuggestionHandler
+	Audit       *handlers.AuditHandler
 	Team        *handlers.TeamHandler
 	Project     *handlers.ProjectHandlerV2
 	Asset       *handlers.AssetHandler
@@ -56,7 +72,12 @@ type Container struct {
 
 	// Core Services needed for Router logic
 	AuthService    *services.AuthService
+	ShareLinkSvc   *services.ShareLinkService
+	AuditSvc       *services.AuditService
+	APIKeySvc      *services.ServiceAccountService
 	ReminderSvc    *services.ReminderService
+	MaintenanceSvc *services.MaintenancePlanService
+	SLASvc         *services.SLAService
 	MinioWorker    *messaging.MinioWorker
 	RMQConsumer    *messaging.Consumer
 	WSHandler      *infraWS.Handler
@@ -83,9 +104,11 @@ func BuildContainer(cfg config.Config, db *gorm.DB) *Container {
 	// WSHub needs userRepo to sync status_user on connect/disconnect
 	c.WSHub = infraWS.NewHub(userRepo)
 	roleRepo := postgres.NewRoleRepository(db)
+	permissionRepo := postgres.NewPermissionRepository(db)
 	teamRepo := postgres.NewTeamRepository(db)
 	ownerRepo := postgres.NewOwnerRepository(db)
 	projectRepo := postgres.NewProjectRepository(db)
+	projectMemberRepo := postgres.NewProjectMemberRepository(db)
 	assetRepo := postgres.NewAssetRepository(db)
 	workRepo := postgres.NewWorkRepository(db)
 	subWorkRepo := postgres.NewSubWorkRepository(db)
@@ -96,33 +119,91 @@ func BuildContainer(cfg config.Config, db *gorm.DB) *Container {
 	statsRepo := postgres.NewStatsRepository(db)
 	attendanceRepo := postgres.NewAttendanceRepository(db)
 	reportRepo := postgres.NewReportRepository(db)
+	sessionRepo := postgres.NewSessionRepository(db)
+	shareLinkRepo := postgres.NewShareLinkRepository(db)
+	auditRepo := postgres.NewAuditRepository(db)
+	passwordResetRepo := postgres.NewPasswordResetRepository(db)
+	recoveryCodeRepo := postgres.NewRecoveryCodeRepository(db)
+	serviceAccountRepo := postgres.NewServiceAccountRepository(db)
+	approvalChainRepo := postgres.NewApprovalChainRepository(db)
+	detailEventRepo := postgres.NewDetailEventRepository(db)
+	maintenancePlanRepo := postgres.NewMaintenancePlanRepository(db)
+	slaRepo := postgres.NewSLARepository(db)
+	defectRepo := postgres.NewDefectRepository(db)
+	handoverRepo := postgres.NewDetailHandoverRepository(db)
+	formRepo := postgres.NewFormSchemaRepository(db)
+	dependencyRepo := postgres.NewConfigDependencyRepository(db)
+	commentRepo := postgres.NewCommentRepository(db)
+	availabilityRepo := postgres.NewAvailabilityRepository(db)
+	certificationRepo := postgres.NewCertificationRepository(db)
+	suggestionRepo := postgres.NewSuggestionRepository(db)
 
 	// 3. Core Services
-	c.AuthService = services.NewAuthService(userRepo)
-	userService := services.NewUserService(userRepo)
+	sessionService := services.NewSessionService(sessionRepo, cfg.Auth)
+	// Revoked sessions lose their WebSocket connections immediately
+	sessionService.SetRevokeListener(c.WSHub.DisconnectSessions)
+	mfaService := services.NewMFAService(userRepo, recoveryCodeRepo, cfg.Auth)
+	c.AuthService = services.NewAuthService(userRepo, permissionRepo, sessionService, mfaService, cfg.Auth)
+	roleService := services.NewRoleService(roleRepo, permissionRepo)
+	userService := services.NewUserService(userRepo, roleRepo, sessionService, services.NewPasswordPolicy(cfg.Auth.Password))
+	emailService := services.NewEmailService(cfg.SMTP)
+	passwordResetService := services.NewPasswordResetService(userRepo, passwordResetRepo, sessionService, emailService, cfg.Auth, cfg.App.FrontendURL)
+	c.ShareLinkSvc = services.NewShareLinkService(shareLinkRepo, reportRepo, cfg.Auth)
+	c.AuditSvc = services.NewAuditService(auditRepo)
+	if err := c.AuditSvc.RegisterCallbacks(db); err != nil {
+		logger.Get().Error("Failed to register audit callbacks", zap.Error(err))
+	}
+	c.APIKeySvc = services.NewServiceAccountService(serviceAccountRepo, cfg.Auth)
+	approvalChainService := services.NewApprovalChainService(approvalChainRepo)
 	larkService := services.NewLarkService(cfg.Lark.AppID, cfg.Lark.AppSecret)
 	statsService := services.NewStatsService(statsRepo)
 	c.ReminderSvc = services.NewReminderService(db)
+	c.MaintenanceSvc = services.NewMaintenancePlanService(maintenancePlanRepo, projectRepo, templateRepo, configRepo, c.WSHub.BroadcastAll)
+	c.SLASvc = services.NewSLAService(slaRepo, c.WSHub.SendToUser)
+	handoverService := services.NewHandoverService(handoverRepo, assignRepo, detailAssignRepo, userRepo, c.WSHub.SendToUser, c.WSHub.BroadcastAll)
+	formService := services.NewFormService(formRepo, configRepo, subWorkRepo)
+	dependencyService := services.NewDependencyService(dependencyRepo, templateRepo)
+	commentService := services.NewCommentService(commentRepo, assignRepo, detailAssignRepo, userRepo, c.MinioClient, c.WSHub.SendToUser, c.WSHub.BroadcastAll)
+	availabilityService := services.NewAvailabilityService(availabilityRepo, userRepo)
+	certificationService := services.NewCertificationService(certificationRepo, userRepo, workRepo)
+	suggestionService := services.NewSuggestionService(suggestionRepo, certificationRepo, templateRepo, projectMemberRepo, availabilityService)
+	defectService := services.NewDefectService(defectRepo, assetRepo, configRepo, detailAssignRepo, c.WSHub.BroadcastAll)
 	attendanceService := services.NewAttendanceService(attendanceRepo, c.MinioClient)
 	reportService := services.NewReportService(reportRepo)
 	mediaSvcForPDF := services.NewAllocationMediaService(detailAssignRepo)
 	reportPDFSvc := services.NewReportPDFService(assignRepo, detailAssignRepo, reportRepo, mediaSvcForPDF)
 
 	// 4. Handlers
-	c.Auth = handlers.NewAuthHandler(c.AuthService)
+	c.Auth = handlers.NewAuthHandler(c.AuthService, passwordResetService)
 	c.User = handlers.NewUserHandler(userService)
-	c.Role = handlers.NewRoleHandler(roleRepo)
+	c.Role = handlers.NewRoleHandler(roleService)
+	c.Session = handlers.NewSessionHandler(sessionService)
+	c.MFA = handlers.NewMFAHandler(c.AuthService, mfaService)
+	c.ShareLink = handlers.NewShareLinkHandler(c.ShareLinkSvc)
+	c.ServiceAcct = handlers.NewServiceAccountHandler(c.APIKeySvc)
+	c.Audit = handlers.NewAuditHandler(c.AuditSvc)
 	c.Team = handlers.NewTeamHandler(teamRepo)
-	c.Project = handlers.NewProjectHandlerV2(db, projectRepo, ownerRepo)
+	c.Project = handlers.NewProjectHandlerV2(db, projectRepo, ownerRepo, projectMemberRepo, c.ShareLinkSvc)
 	c.Asset = handlers.NewAssetHandler(assetRepo, workRepo, subWorkRepo)
 	c.ConfigH = handlers.NewConfigHandler(configRepo)
 	c.Template = handlers.NewTemplateHandler(templateRepo)
-	c.Assign = handlers.NewAssignHandler(db, assignRepo, detailAssignRepo, configRepo, assetRepo, workRepo, subWorkRepo, templateRepo, c.WSHub, larkService, cfg)
+	c.ApprovalCh = handlers.NewApprovalChainHandler(approvalChainService)
+	c.Maintenance = handlers.NewMaintenancePlanHandler(c.MaintenanceSvc)
+	c.SLA = handlers.NewSLAHandler(c.SLASvc, assignRepo)
+	c.Defect = handlers.NewDefectHandler(defectService)
+	c.Handover = handlers.NewHandoverHandler(handoverService)
+	c.Form = handlers.NewFormHandler(formService)
+	c.Dependency = handlers.NewDependencyHandler(dependencyService)
+	c.Comment = handlers.NewCommentHandler(commentService)
+	c.Calendar = handlers.NewAvailabilityHandler(availabilityService, c.ShareLinkSvc)
+	c.Certs = handlers.NewCertificationHandler(certificationService)
+	c.Suggest = handlers.NewSuggestionHandler(suggestionService)
+	c.Assign = handlers.NewAssignHandler(db, assignRepo, detailAssignRepo, configRepo, assetRepo, workRepo, subWorkRepo, templateRepo, c.WSHub, larkService, c.ShareLinkSvc, approvalChainRepo, detailEventRepo, formRepo, dependencyRepo, availabilityService, cfg)
 	c.Stats = handlers.NewStatsHandler(statsService)
 	c.Station = handlers.NewStationHandler(db)
-	c.Attendance = handlers.NewAttendanceHandler(attendanceService, c.Stats)
+	c.Attendance = handlers.NewAttendanceHandler(attendanceService, assignRepo, c.Stats)
 	c.Admin = handlers.NewAdminHandler(db)
-	c.Media = handlers.NewMediaHandler(c.MinioClient)
+	c.Media = handlers.NewMediaHandler(c.MinioClient, detailAssignRepo)
 	c.Upload = handlers.NewUploadHandler(c.MinioClient)
 	c.Lark = handlers.NewLarkHandler(larkService, reportPDFSvc)
 	c.Report = handlers.NewReportHandler(reportService)
@@ -152,6 +233,8 @@ func BuildContainer(cfg config.Config, db *gorm.DB) *Container {
 // StartBackgroundWorkers initializes any non-HTTP goroutines (Consumers, REMINDERS, RMQ)
 func (c *Container) StartBackgroundWorkers(ctx context.Context) {
 	c.ReminderSvc.Start()
+	c.MaintenanceSvc.Start()
+	c.SLASvc.Start()
 
 	if c.RMQConsumer != nil {
 		logger.Get().Info("RabbitMQ DB Consumer starting (4 workers)")
@@ -169,6 +252,12 @@ func (c *Container) StopBackgroundWorkers() {
 	if c.ReminderSvc != nil {
 		c.ReminderSvc.Stop()
 	}
+	if c.MaintenanceSvc != nil {
+		c.MaintenanceSvc.Stop()
+	}
+	if c.SLASvc != nil {
+		c.SLASvc.Stop()
+	}
 	if c.RMQConsumer != nil {
 		c.RMQConsumer.Stop()
 	}
@@ -196,23 +285,40 @@ func (c *Container) SetupRouter() *gin.Engine {
 	r.GET("/", func(ctx *gin.Context) { ctx.String(http.StatusOK, "CMMS Backend V2 is running!") })
 	api.Any("/health", func(ctx *gin.Context) { ctx.String(http.StatusOK, "OK") })
 
-	api.GET("/media/proxy", c.Media.ProxyImage)
-	api.GET("/media/download-zip", c.Media.DownloadFolder)
-	api.GET("/public/report/:id", c.Assign.GetPublicReport)
-	api.GET("/public/generic-report/:id", c.Report.GetGenericReport)
-	api.GET("/public/attendance-by-assign", c.Attendance.GetByAssignDates)
-	api.GET("/public/media/library", c.Media.PublicGetLibraryImages)
-	api.GET("/public/export/:id", c.Project.ExportProject)
-	api.POST("/public/export/:id", c.Project.ExportProject)
+	// Media embedded in pages: signed-in users, or share links covering the files
+	media := api.Group("/media")
+	media.Use(middleware.ShareLinkOrAuth(c.ShareLinkSvc, middleware.AuthMiddleware(c.AuthService, c.APIKeySvc)))
+	media.GET("/proxy", c.Media.ProxyImage)
+	media.GET("/download-zip", c.Media.DownloadFolder)
+
+	// Public share pages: every request must carry a signed share token
+	public := api.Group("/public")
+	public.Use(middleware.ShareLinkAuth(c.ShareLinkSvc))
+	public.GET("/report/:id", c.Assign.GetPublicReport)
+	public.GET("/generic-report/:id", c.Report.GetGenericReport)
+	public.GET("/attendance-by-assign", c.Attendance.GetPublicByAssignDates)
+	public.GET("/media/library", c.Media.PublicGetLibraryImages)
+	public.GET("/export/:id", c.Project.PublicExportProject)
+	public.POST("/export/:id", c.Project.PublicExportProject)
+	public.GET("/calendar/:id", c.Calendar.GetPublicFeed)
+
 	api.GET("/redirect-folder", c.Project.RedirectFolder)
 
 	api.POST("/auth/login", middleware.RateLimitMiddleware(5, 1*time.Minute), c.Auth.Login)
+	api.POST("/auth/refresh", middleware.RateLimitMiddleware(30, 1*time.Minute), c.Auth.Refresh)
+	api.POST("/auth/forgot-password", middleware.RateLimitMiddleware(5, 1*time.Minute), c.Auth.ForgotPassword)
+	api.POST("/auth/reset-password", middleware.RateLimitMiddleware(10, 1*time.Minute), c.Auth.ResetPassword)
+	api.POST("/auth/2fa/verify", middleware.RateLimitMiddleware(10, 1*time.Minute), c.MFA.VerifyLogin)
+	api.POST("/auth/2fa/enroll", middleware.RateLimitMiddleware(5, 1*time.Minute), c.MFA.EnrollLogin)
 
 	r.GET("/api/ws", func(ctx *gin.Context) { c.WSHandler.ServeWS(ctx.Writer, ctx.Request) })
 
 	// --- PROTECTED ROUTES ---
 	protected := api.Group("/")
-	protected.Use(middleware.AuthMiddleware(c.AuthService))
+	protected.Use(middleware.AuthMiddleware(c.AuthService, c.APIKeySvc))
+	protected.Use(middleware.RequirePasswordChange("/api", passwordChangeRoutes...))
+	protected.Use(middleware.Authorize("/api", protectedRoutePolicies))
+	protected.Use(middleware.Audit("/api", c.AuditSvc))
 	{
 		c.mapProtectedRoutes(protected)
 	}
@@ -220,6 +326,8 @@ func (c *Container) SetupRouter() *gin.Engine {
 	return r
 }
 
+// mapProtectedRoutes registers authenticated routes.
+// Every route added here needs a matching entry in protectedRoutePolicies.
 func (c *Container) mapProtectedRoutes(p *gin.RouterGroup) {
 	// Media
 	p.GET("/media/library", c.Media.GetLibraryImages)
@@ -239,32 +347,133 @@ func (c *Container) mapProtectedRoutes(p *gin.RouterGroup) {
 	p.POST("/users/:id/restore", c.User.RestoreUser)
 	p.DELETE("/users/:id/permanent", c.User.PermanentDeleteUser)
 
+	// User sessions & devices
+	p.GET("/admin/users/:id/sessions", c.Session.ListUserSessions)
+	p.DELETE("/admin/users/:id/sessions", c.Session.RevokeAllUserSessions)
+	p.DELETE("/admin/users/:id/sessions/:session_id", c.Session.RevokeUserSession)
+	p.DELETE("/admin/users/:id/devices/:device_id", c.Session.RevokeUserDevice)
+	p.DELETE("/admin/users/:id/2fa", c.MFA.ResetUser)
+
 	// Auth
 	p.POST("/auth/logout", c.Auth.Logout)
+	p.GET("/auth/2fa", c.MFA.Status)
+	p.POST("/auth/2fa/setup", c.MFA.Setup)
+	p.POST("/auth/2fa/enable", c.MFA.Enable)
+	p.POST("/auth/2fa/disable", c.MFA.Disable)
+	p.POST("/auth/2fa/recovery-codes", c.MFA.RegenerateRecoveryCodes)
 
-	// Roles & Teams
+	// Roles, Permissions & Teams
 	p.GET("/roles", c.Role.GetAllRoles)
 	p.POST("/roles", c.Role.CreateRole)
+	p.GET("/roles/:id", c.Role.GetRole)
+	p.PUT("/roles/:id", c.Role.UpdateRole)
+	p.DELETE("/roles/:id", c.Role.DeleteRole)
+	p.PUT("/roles/:id/permissions", c.Role.SetRolePermissions)
+	p.GET("/permissions", c.Role.ListPermissions)
 	p.GET("/teams", c.Team.GetAllTeams)
 	p.POST("/teams", c.Team.CreateTeam)
 
+	// Availability & calendars
+	p.GET("/availability", c.Calendar.ListUnavailability)
+	p.POST("/availability", c.Calendar.CreateUnavailability)
+	p.PUT("/availability/:id", c.Calendar.UpdateUnavailability)
+	p.DELETE("/availability/:id", c.Calendar.DeleteUnavailability)
+	p.GET("/users/:id/calendar", c.Calendar.GetUserCalendar)
+	p.GET("/users/:id/calendar.ics", c.Calendar.DownloadUserFeed)
+	p.POST("/users/:id/calendar/feed", c.Calendar.CreateFeedLink)
+	p.GET("/teams/:id/calendar", c.Calendar.GetTeamCalendar)
+
+	// Certifications
+	p.GET("/certifications", c.Certs.ListCertifications)
+	p.POST("/certifications", c.Certs.CreateCertification)
+	p.DELETE("/certifications/:id", c.Certs.DeleteCertification)
+	p.GET("/users/:id/certifications", c.Certs.ListUserCertifications)
+	p.PUT("/users/:id/certifications/:cert_id", c.Certs.GrantUserCertification)
+	p.DELETE("/users/:id/certifications/:cert_id", c.Certs.RevokeUserCertification)
+	p.GET("/works/:id/certifications", c.Certs.GetWorkRequirements)
+	p.PUT("/works/:id/certifications", c.Certs.SetWorkRequirements)
+
 	// Templates & Configs
 	p.GET("/templates", c.Template.GetAllTemplates)
 	p.GET("/templates/:id", c.Template.GetTemplateByID)
 	p.POST("/templates", c.Template.CreateTemplate)
 	p.PUT("/templates/:id", c.Template.UpdateTemplate)
 	p.DELETE("/templates/:id", c.Template.DeleteTemplate)
+	p.GET("/templates/:id/approval-chain", c.ApprovalCh.GetTemplateChain)
+	p.PUT("/templates/:id/approval-chain", c.ApprovalCh.SetTemplateChain)
+	p.DELETE("/templates/:id/approval-chain", c.ApprovalCh.DeleteTemplateChain)
+	p.GET("/templates/:id/dependencies", c.Dependency.GetTemplateDependencies)
+	p.PUT("/templates/:id/dependencies", c.Dependency.SetTemplateDependencies)
+
+	// Preventive maintenance plans (scheduled assigns)
+	p.GET("/maintenance-plans", c.Maintenance.ListPlans)
+	p.POST("/maintenance-plans", c.Maintenance.CreatePlan)
+	p.GET("/maintenance-plans/:id", c.Maintenance.GetPlan)
+	p.PUT("/maintenance-plans/:id", c.Maintenance.UpdatePlan)
+	p.DELETE("/maintenance-plans/:id", c.Maintenance.DeletePlan)
+	p.GET("/maintenance-plans/:id/preview", c.Maintenance.PreviewPlan)
+	p.POST("/maintenance-plans/:id/skip", c.Maintenance.SkipOccurrence)
+	p.POST("/maintenance-plans/:id/pause", c.Maintenance.PausePlan)
+	p.POST("/maintenance-plans/:id/resume", c.Maintenance.ResumePlan)
+
+	// SLA policies & working-day calendar
+	p.GET("/sla-policies", c.SLA.ListPolicies)
+	p.POST("/sla-policies", c.SLA.CreatePolicy)
+	p.PUT("/sla-policies/:id", c.SLA.UpdatePolicy)
+	p.DELETE("/sla-policies/:id", c.SLA.DeletePolicy)
+	p.GET("/holidays", c.SLA.ListHolidays)
+	p.POST("/holidays", c.SLA.CreateHoliday)
+	p.DELETE("/holidays/:id", c.SLA.DeleteHoliday)
+
+	// Defects & corrective work
+	p.GET("/defects", c.Defect.ListDefects)
+	p.POST("/defects", c.Defect.CreateDefect)
+	p.GET("/defects/:id", c.Defect.GetDefect)
+	p.PUT("/defects/:id", c.Defect.UpdateDefect)
+	p.POST("/defects/:id/status", c.Defect.ChangeStatus)
+	p.POST("/defects/:id/convert", c.Defect.ConvertDefect)
+	p.GET("/assets/:id/defects", c.Defect.ListAssetDefects)
 	p.GET("/configs", c.ConfigH.ListConfigs)
 	p.GET("/configs/:id", c.ConfigH.GetConfig)
 	p.POST("/configs", c.ConfigH.CreateConfig)
 	p.PUT("/configs/:id", c.ConfigH.UpdateConfig)
 	p.DELETE("/configs/:id", c.ConfigH.DeleteConfig)
 
+	// Checklist forms
+	p.GET("/sub-works/:id/forms", c.Form.ListSubWorkForms)
+	p.POST("/sub-works/:id/forms", c.Form.CreateSubWorkForm)
+	p.GET("/configs/:id/forms", c.Form.ListConfigForms)
+	p.POST("/configs/:id/forms", c.Form.CreateConfigForm)
+	p.GET("/forms/:id", c.Form.GetForm)
+
 	// Reports
 	p.POST("/reports", c.Report.CreateReport)
+
+	// Share links (signed access to /public/*)
+	p.GET("/share-links", c.ShareLink.ListShareLinks)
+	p.POST("/share-links", c.ShareLink.CreateShareLink)
+	p.POST("/share-links/revoke", c.ShareLink.RevokeResourceLinks)
+	p.DELETE("/share-links/:id", c.ShareLink.RevokeShareLink)
+
+	// Service accounts & API keys (machine integrations)
+	p.GET("/service-accounts", c.ServiceAcct.ListServiceAccounts)
+	p.POST("/service-accounts", c.ServiceAcct.CreateServiceAccount)
+	p.GET("/service-accounts/:id", c.ServiceAcct.GetServiceAccount)
+	p.PUT("/service-accounts/:id", c.ServiceAcct.UpdateServiceAccount)
+	p.DELETE("/service-accounts/:id", c.ServiceAcct.DeleteServiceAccount)
+	p.POST("/service-accounts/:id/keys", c.ServiceAcct.CreateAPIKey)
+	p.POST("/service-accounts/:id/keys/:key_id/rotate", c.ServiceAcct.RotateAPIKey)
+	p.DELETE("/service-accounts/:id/keys/:key_id", c.ServiceAcct.RevokeAPIKey)
+
+	// Audit log
+	p.GET("/audit-logs", c.Audit.ListAuditLogs)
+	p.GET("/audit-logs/export", c.Audit.ExportAuditLogs)
+
 	// Stats & Admin
 	p.GET("/admin/stats", c.Stats.GetAdminStats)
 	p.GET("/manager/stats", c.Stats.GetManagerStats)
+	p.GET("/manager/stats/sla", c.Stats.GetSLAStats)
+	p.GET("/manager/stats/sla/tasks", c.Stats.ListSLATasks)
 	p.GET("/user/stats", c.Stats.GetUserStats)
 	p.GET("/admin/tables", c.Admin.GetAllTables)
 	p.GET("/admin/tables/:table", c.Admin.GetTableData)
@@ -307,6 +516,12 @@ func (c *Container) mapProtectedRoutes(p *gin.RouterGroup) {
 	p.POST("/projects/:id/restore", c.Project.RestoreProject)
 	p.DELETE("/projects/:id/permanent", c.Project.PermanentDeleteProject)
 	p.POST("/projects/:id/clone", c.Project.CloneProject)
+	p.GET("/projects/:id/members", c.Project.ListProjectMembers)
+	p.POST("/projects/:id/members", c.Project.AddProjectMembers)
+	p.DELETE("/projects/:id/members/:user_id", c.Project.RemoveProjectMember)
+	p.GET("/projects/:id/approval-chain", c.ApprovalCh.GetProjectChain)
+	p.PUT("/projects/:id/approval-chain", c.ApprovalCh.SetProjectChain)
+	p.DELETE("/projects/:id/approval-chain", c.ApprovalCh.DeleteProjectChain)
 
 	// V2 Asset / Work / SubWork
 	p.GET("/assets/history", c.Asset.ListDeletedAssets)
@@ -358,12 +573,18 @@ func (c *Container) mapProtectedRoutes(p *gin.RouterGroup) {
 	p.GET("/assigns/:id", c.Assign.GetAssign)
 	p.GET("/allocations/:id/tasks", c.Assign.GetAssignWithTasks)
 	p.POST("/assigns", c.Assign.CreateAssign)
+	p.POST("/assigns/suggestions", c.Suggest.SuggestAssignees)
 	p.PUT("/assigns/:id", c.Assign.UpdateAssign)
 	p.DELETE("/assigns/:id", c.Assign.DeleteAssign)
 	p.POST("/assigns/:id/restore", c.Assign.RestoreAssign)
 	p.DELETE("/assigns/:id/permanent", c.Assign.PermanentDeleteAssign)
 
 	p.GET("/assigns/:id/details", c.Assign.ListDetailAssigns)
+	p.GET("/assigns/:id/timeline", c.Assign.GetAssignTimeline)
+	p.GET("/assigns/:id/measurements", c.Assign.GetAssignMeasurements)
+	p.GET("/assigns/:id/sla", c.SLA.GetAssignSLA)
+	p.GET("/assigns/:id/handovers", c.Handover.GetAssignHandovers)
+	p.POST("/assigns/:id/handover", c.Handover.HandoverAssign)
 	p.POST("/assigns/:id/details", c.Assign.CreateDetailAssign)
 	p.POST("/details/:id/upload-image", c.Assign.UploadDetailImage)
 	p.PUT("/details/:id/note", c.Assign.SaveDetailNote)
@@ -372,8 +593,25 @@ func (c *Container) mapProtectedRoutes(p *gin.RouterGroup) {
 	p.POST("/details/:id/submit", c.Assign.SubmitDetail)
 	p.POST("/details/:id/approve", c.Assign.ApproveDetail)
 	p.POST("/details/:id/reject", c.Assign.RejectDetail)
+	p.GET("/details/:id/approvals", c.Assign.GetDetailApprovals)
+	p.GET("/details/:id/timeline", c.Assign.GetDetailTimeline)
+	p.GET("/details/:id/owners", c.Handover.GetDetailOwners)
+	p.POST("/details/:id/reassign", c.Handover.ReassignDetail)
+	p.POST("/details/:id/handover", c.Handover.HandoverDetail)
 	p.PUT("/task-details/bulk/status", c.Assign.BulkUpdateDetailStatus)
 
+	// Discussion threads
+	p.GET("/assigns/:id/comments", c.Comment.ListAssignComments)
+	p.POST("/assigns/:id/comments", c.Comment.PostAssignComment)
+	p.POST("/assigns/:id/comments/read", c.Comment.MarkAssignRead)
+	p.GET("/details/:id/comments", c.Comment.ListDetailComments)
+	p.POST("/details/:id/comments", c.Comment.PostDetailComment)
+	p.POST("/details/:id/comments/read", c.Comment.MarkDetailRead)
+	p.GET("/comments/unread", c.Comment.GetUnread)
+	p.PUT("/comments/:id", c.Comment.EditComment)
+	p.DELETE("/comments/:id", c.Comment.DeleteComment)
+	p.GET("/comments/:id/history", c.Comment.GetCommentHistory)
+
 	// Lark
 	p.POST("/lark/push-report", c.Lark.PushReportLink)
 	p.POST("/lark/push-allocation", c.Lark.PushAllocation)
@@ -411,7 +649,8 @@ func (c *Container) configureCORS() gin.HandlerFunc {
 		return false
 	}
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Share-Token", "If-Match"}
+	corsConfig.ExposeHeaders = []string{"ETag"}
 	corsConfig.AllowCredentials = true
 	return cors.New(corsConfig)
 }
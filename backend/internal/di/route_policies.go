@@ -0,0 +1,319 @@
+package di
+
+import (
+	"net/http"
+
+	"github.com/phuc/cmms-backend/internal/adapters/http/middleware"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// authenticated admits any logged-in user (self-service and read-only routes).
+var authenticated = middleware.AllowAuthenticated()
+
+// can admits roles granted the permission code (see domain.Perm* and role_permissions).
+func can(code string) middleware.Policy {
+	return middleware.RequirePermission(code)
+}
+
+// passwordChangeRoutes stay reachable while an account must change its password
+// (admin-created or admin-reset accounts, see middleware.RequirePasswordChange).
+var passwordChangeRoutes = []string{
+	middleware.RouteKey(http.MethodGet, "/users/:id"),
+	middleware.RouteKey(http.MethodPut, "/users/:id/password"),
+	middleware.RouteKey(http.MethodPost, "/auth/logout"),
+}
+
+// protectedRoutePolicies declares who may call every route registered in mapProtectedRoutes.
+// Every protected route MUST have an entry here: routes without one are denied
+// by middleware.Authorize, and route_policies_test.go fails on missing or stale keys.
+var protectedRoutePolicies = middleware.RoutePolicies{
+	// Media
+	middleware.RouteKey(http.MethodGet, "/media/library"):     authenticated,
+	middleware.RouteKey(http.MethodDelete, "/media/folder"):   can(domain.PermMediaManage),
+	middleware.RouteKey(http.MethodPost, "/upload/guideline"): can(domain.PermTemplateManage),
+
+	// Users
+	middleware.RouteKey(http.MethodGet, "/users"):                   authenticated,
+	middleware.RouteKey(http.MethodGet, "/users/:id"):               authenticated,
+	middleware.RouteKey(http.MethodPost, "/users"):                  can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodPut, "/users/:id"):               can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodPut, "/users/:id/password"):      authenticated,
+	middleware.RouteKey(http.MethodDelete, "/users/:id"):            can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodGet, "/users/history"):           can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodPost, "/users/bulk-restore"):     can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodDelete, "/users/bulk-permanent"): can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodPost, "/users/:id/restore"):      can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodDelete, "/users/:id/permanent"):  can(domain.PermUserManage),
+
+	// User sessions & devices
+	middleware.RouteKey(http.MethodGet, "/admin/users/:id/sessions"):                can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodDelete, "/admin/users/:id/sessions"):             can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodDelete, "/admin/users/:id/sessions/:session_id"): can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodDelete, "/admin/users/:id/devices/:device_id"):   can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodDelete, "/admin/users/:id/2fa"):                  can(domain.PermUserManage),
+
+	// Auth
+	middleware.RouteKey(http.MethodPost, "/auth/logout"):             authenticated,
+	middleware.RouteKey(http.MethodGet, "/auth/2fa"):                 authenticated,
+	middleware.RouteKey(http.MethodPost, "/auth/2fa/setup"):          authenticated,
+	middleware.RouteKey(http.MethodPost, "/auth/2fa/enable"):         authenticated,
+	middleware.RouteKey(http.MethodPost, "/auth/2fa/disable"):        authenticated,
+	middleware.RouteKey(http.MethodPost, "/auth/2fa/recovery-codes"): authenticated,
+
+	// Roles, Permissions & Teams
+	middleware.RouteKey(http.MethodGet, "/roles"):                 authenticated,
+	middleware.RouteKey(http.MethodPost, "/roles"):                can(domain.PermRoleManage),
+	middleware.RouteKey(http.MethodGet, "/roles/:id"):             can(domain.PermRoleManage),
+	middleware.RouteKey(http.MethodPut, "/roles/:id"):             can(domain.PermRoleManage),
+	middleware.RouteKey(http.MethodDelete, "/roles/:id"):          can(domain.PermRoleManage),
+	middleware.RouteKey(http.MethodPut, "/roles/:id/permissions"): can(domain.PermRoleManage),
+	middleware.RouteKey(http.MethodGet, "/permissions"):           can(domain.PermRoleManage),
+	middleware.RouteKey(http.MethodGet, "/teams"):                 authenticated,
+	middleware.RouteKey(http.MethodPost, "/teams"):                can(domain.PermTeamManage),
+
+	// Availability & calendars
+	middleware.RouteKey(http.MethodGet, "/availability"):             authenticated,
+	middleware.RouteKey(http.MethodPost, "/availability"):            authenticated,
+	middleware.RouteKey(http.MethodPut, "/availability/:id"):         authenticated,
+	middleware.RouteKey(http.MethodDelete, "/availability/:id"):      authenticated,
+	middleware.RouteKey(http.MethodGet, "/users/:id/calendar"):       authenticated,
+	middleware.RouteKey(http.MethodGet, "/users/:id/calendar.ics"):   authenticated,
+	middleware.RouteKey(http.MethodPost, "/users/:id/calendar/feed"): authenticated,
+	middleware.RouteKey(http.MethodGet, "/teams/:id/calendar"):       can(domain.PermAssignManage),
+
+	// Certifications
+	middleware.RouteKey(http.MethodGet, "/certifications"):                       authenticated,
+	middleware.RouteKey(http.MethodPost, "/certifications"):                      can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodDelete, "/certifications/:id"):                can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodGet, "/users/:id/certifications"):             authenticated,
+	middleware.RouteKey(http.MethodPut, "/users/:id/certifications/:cert_id"):    can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodDelete, "/users/:id/certifications/:cert_id"): can(domain.PermUserManage),
+	middleware.RouteKey(http.MethodGet, "/works/:id/certifications"):             authenticated,
+	middleware.RouteKey(http.MethodPut, "/works/:id/certifications"):             can(domain.PermAssetManage),
+
+	// Templates & Configs
+	middleware.RouteKey(http.MethodGet, "/templates"):                       authenticated,
+	middleware.RouteKey(http.MethodGet, "/templates/:id"):                   authenticated,
+	middleware.RouteKey(http.MethodPost, "/templates"):                      can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodPut, "/templates/:id"):                   can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodDelete, "/templates/:id"):                can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodGet, "/templates/:id/approval-chain"):    authenticated,
+	middleware.RouteKey(http.MethodPut, "/templates/:id/approval-chain"):    can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodDelete, "/templates/:id/approval-chain"): can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodGet, "/templates/:id/dependencies"):      authenticated,
+	middleware.RouteKey(http.MethodPut, "/templates/:id/dependencies"):      can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodGet, "/configs"):                         authenticated,
+	middleware.RouteKey(http.MethodGet, "/configs/:id"):                     authenticated,
+	middleware.RouteKey(http.MethodPost, "/configs"):                        can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodPut, "/configs/:id"):                     can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodDelete, "/configs/:id"):                  can(domain.PermTemplateManage),
+
+	// Maintenance plans
+	middleware.RouteKey(http.MethodGet, "/maintenance-plans"):             authenticated,
+	middleware.RouteKey(http.MethodPost, "/maintenance-plans"):            can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodGet, "/maintenance-plans/:id"):         authenticated,
+	middleware.RouteKey(http.MethodPut, "/maintenance-plans/:id"):         can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodDelete, "/maintenance-plans/:id"):      can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodGet, "/maintenance-plans/:id/preview"): authenticated,
+	middleware.RouteKey(http.MethodPost, "/maintenance-plans/:id/skip"):   can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodPost, "/maintenance-plans/:id/pause"):  can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodPost, "/maintenance-plans/:id/resume"): can(domain.PermAssignManage),
+
+	// SLA policies & working-day calendar
+	middleware.RouteKey(http.MethodGet, "/sla-policies"):        authenticated,
+	middleware.RouteKey(http.MethodPost, "/sla-policies"):       can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodPut, "/sla-policies/:id"):    can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodDelete, "/sla-policies/:id"): can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodGet, "/holidays"):            authenticated,
+	middleware.RouteKey(http.MethodPost, "/holidays"):           can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodDelete, "/holidays/:id"):     can(domain.PermProjectManage),
+
+	// Defects: anyone may raise one and its assignee works it (checked by the
+	// service); editing and converting are for managers
+	middleware.RouteKey(http.MethodGet, "/defects"):              authenticated,
+	middleware.RouteKey(http.MethodPost, "/defects"):             authenticated,
+	middleware.RouteKey(http.MethodGet, "/defects/:id"):          authenticated,
+	middleware.RouteKey(http.MethodPut, "/defects/:id"):          can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodPost, "/defects/:id/status"):  authenticated,
+	middleware.RouteKey(http.MethodPost, "/defects/:id/convert"): can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodGet, "/assets/:id/defects"):   authenticated,
+
+	// Checklist forms follow the rights on their sub-work or config
+	middleware.RouteKey(http.MethodGet, "/sub-works/:id/forms"):  authenticated,
+	middleware.RouteKey(http.MethodPost, "/sub-works/:id/forms"): can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodGet, "/configs/:id/forms"):    authenticated,
+	middleware.RouteKey(http.MethodPost, "/configs/:id/forms"):   can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodGet, "/forms/:id"):            authenticated,
+
+	// Reports
+	middleware.RouteKey(http.MethodPost, "/reports"): can(domain.PermAssignApprove),
+
+	// Share links
+	middleware.RouteKey(http.MethodGet, "/share-links"):         can(domain.PermShareManage),
+	middleware.RouteKey(http.MethodPost, "/share-links"):        can(domain.PermShareManage),
+	middleware.RouteKey(http.MethodPost, "/share-links/revoke"): can(domain.PermShareManage),
+	middleware.RouteKey(http.MethodDelete, "/share-links/:id"):  can(domain.PermShareManage),
+
+	// Service accounts & API keys
+	middleware.RouteKey(http.MethodGet, "/service-accounts"):                          can(domain.PermServiceAccountManage),
+	middleware.RouteKey(http.MethodPost, "/service-accounts"):                         can(domain.PermServiceAccountManage),
+	middleware.RouteKey(http.MethodGet, "/service-accounts/:id"):                      can(domain.PermServiceAccountManage),
+	middleware.RouteKey(http.MethodPut, "/service-accounts/:id"):                      can(domain.PermServiceAccountManage),
+	middleware.RouteKey(http.MethodDelete, "/service-accounts/:id"):                   can(domain.PermServiceAccountManage),
+	middleware.RouteKey(http.MethodPost, "/service-accounts/:id/keys"):                can(domain.PermServiceAccountManage),
+	middleware.RouteKey(http.MethodPost, "/service-accounts/:id/keys/:key_id/rotate"): can(domain.PermServiceAccountManage),
+	middleware.RouteKey(http.MethodDelete, "/service-accounts/:id/keys/:key_id"):      can(domain.PermServiceAccountManage),
+
+	// Audit log
+	middleware.RouteKey(http.MethodGet, "/audit-logs"):        can(domain.PermAuditView),
+	middleware.RouteKey(http.MethodGet, "/audit-logs/export"): can(domain.PermAuditView),
+
+	// Stats & Admin
+	middleware.RouteKey(http.MethodGet, "/admin/stats"):                      can(domain.PermStatsView),
+	middleware.RouteKey(http.MethodGet, "/manager/stats"):                    can(domain.PermStatsView),
+	middleware.RouteKey(http.MethodGet, "/manager/stats/sla"):                can(domain.PermStatsView),
+	middleware.RouteKey(http.MethodGet, "/manager/stats/sla/tasks"):          can(domain.PermStatsView),
+	middleware.RouteKey(http.MethodGet, "/user/stats"):                       authenticated,
+	middleware.RouteKey(http.MethodGet, "/admin/tables"):                     can(domain.PermAdminTables),
+	middleware.RouteKey(http.MethodGet, "/admin/tables/:table"):              can(domain.PermAdminTables),
+	middleware.RouteKey(http.MethodPost, "/admin/tables/:table"):             can(domain.PermAdminTables),
+	middleware.RouteKey(http.MethodPut, "/admin/tables/:table/:id"):          can(domain.PermAdminTables),
+	middleware.RouteKey(http.MethodDelete, "/admin/tables/:table/:id"):       can(domain.PermAdminTables),
+	middleware.RouteKey(http.MethodPost, "/admin/tables/:table/bulk-delete"): can(domain.PermAdminTables),
+
+	// Attendance
+	middleware.RouteKey(http.MethodPost, "/attendance/checkin-with-photos"):  can(domain.PermAttendanceCheckin),
+	middleware.RouteKey(http.MethodPost, "/attendance/checkin"):              can(domain.PermAttendanceCheckin),
+	middleware.RouteKey(http.MethodPost, "/attendance/checkout"):             can(domain.PermAttendanceCheckin),
+	middleware.RouteKey(http.MethodPost, "/attendance/request-checkout"):     can(domain.PermAttendanceCheckin),
+	middleware.RouteKey(http.MethodPost, "/attendance/approve-checkout/:id"): can(domain.PermAttendanceApproveCheckout),
+	middleware.RouteKey(http.MethodPost, "/attendance/reject-checkout/:id"):  can(domain.PermAttendanceApproveCheckout),
+	middleware.RouteKey(http.MethodGet, "/attendance/pending-checkouts"):     can(domain.PermAttendanceApproveCheckout),
+	middleware.RouteKey(http.MethodGet, "/attendance/today/:user_id"):        authenticated,
+	middleware.RouteKey(http.MethodGet, "/attendance/history/:user_id"):      authenticated,
+	middleware.RouteKey(http.MethodGet, "/attendance/today/all"):             can(domain.PermAttendanceViewAll),
+	middleware.RouteKey(http.MethodGet, "/attendance/history/all"):           can(domain.PermAttendanceViewAll),
+	middleware.RouteKey(http.MethodGet, "/attendance/onsite"):                can(domain.PermAttendanceViewAll),
+	middleware.RouteKey(http.MethodGet, "/attendance/detail/:id"):            authenticated,
+	middleware.RouteKey(http.MethodGet, "/attendance/lookup"):                authenticated,
+	middleware.RouteKey(http.MethodGet, "/attendance/by-assign-dates"):       authenticated,
+
+	// V2 Projects & Owners
+	middleware.RouteKey(http.MethodGet, "/owners"):                           authenticated,
+	middleware.RouteKey(http.MethodPost, "/owners"):                          can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodDelete, "/owners/:id"):                    can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodGet, "/projects/history"):                 can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodPost, "/projects/bulk-restore"):           can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodDelete, "/projects/bulk-permanent"):       can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodGet, "/projects"):                         authenticated,
+	middleware.RouteKey(http.MethodGet, "/projects/:id/export-preview"):      can(domain.PermProjectExport),
+	middleware.RouteKey(http.MethodPost, "/projects/:id/export"):             can(domain.PermProjectExport),
+	middleware.RouteKey(http.MethodGet, "/projects/:id"):                     authenticated,
+	middleware.RouteKey(http.MethodPost, "/projects"):                        can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodPut, "/projects/:id"):                     can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodDelete, "/projects/:id"):                  can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodPost, "/projects/:id/restore"):            can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodDelete, "/projects/:id/permanent"):        can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodPost, "/projects/:id/clone"):              can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodGet, "/projects/:id/members"):             can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodPost, "/projects/:id/members"):            can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodDelete, "/projects/:id/members/:user_id"): can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodGet, "/projects/:id/approval-chain"):      authenticated,
+	middleware.RouteKey(http.MethodPut, "/projects/:id/approval-chain"):      can(domain.PermProjectManage),
+	middleware.RouteKey(http.MethodDelete, "/projects/:id/approval-chain"):   can(domain.PermProjectManage),
+
+	// V2 Asset / Work / SubWork
+	middleware.RouteKey(http.MethodGet, "/assets/history"):              can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodPost, "/assets/bulk-restore"):        can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodDelete, "/assets/bulk-permanent"):    can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodPost, "/assets/:id/restore"):         can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodDelete, "/assets/:id/permanent"):     can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodGet, "/assets"):                      authenticated,
+	middleware.RouteKey(http.MethodGet, "/assets/:id"):                  authenticated,
+	middleware.RouteKey(http.MethodPost, "/assets"):                     can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodPut, "/assets/:id"):                  can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodDelete, "/assets/:id"):               can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodGet, "/works/history"):               can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodPost, "/works/bulk-restore"):         can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodDelete, "/works/bulk-permanent"):     can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodPost, "/works/:id/restore"):          can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodDelete, "/works/:id/permanent"):      can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodGet, "/works"):                       authenticated,
+	middleware.RouteKey(http.MethodGet, "/works/:id"):                   authenticated,
+	middleware.RouteKey(http.MethodPost, "/works"):                      can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodPut, "/works/:id"):                   can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodDelete, "/works/:id"):                can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodGet, "/sub-works/history"):           can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodPost, "/sub-works/bulk-restore"):     can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodDelete, "/sub-works/bulk-permanent"): can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodPost, "/sub-works/:id/restore"):      can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodDelete, "/sub-works/:id/permanent"):  can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodGet, "/sub-works"):                   authenticated,
+	middleware.RouteKey(http.MethodGet, "/sub-works/:id"):               authenticated,
+	middleware.RouteKey(http.MethodPost, "/sub-works"):                  can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodPut, "/sub-works/:id"):               can(domain.PermAssetManage),
+	middleware.RouteKey(http.MethodDelete, "/sub-works/:id"):            can(domain.PermAssetManage),
+
+	// Process & Model Projects
+	middleware.RouteKey(http.MethodGet, "/process"):               authenticated,
+	middleware.RouteKey(http.MethodPost, "/process"):              can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodPut, "/process/:id"):           can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodDelete, "/process/:id"):        can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodGet, "/model-projects"):        authenticated,
+	middleware.RouteKey(http.MethodPost, "/model-projects"):       can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodPut, "/model-projects/:id"):    can(domain.PermTemplateManage),
+	middleware.RouteKey(http.MethodDelete, "/model-projects/:id"): can(domain.PermTemplateManage),
+
+	// V2 Assign & Tasks
+	middleware.RouteKey(http.MethodGet, "/assigns/history"):           can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodGet, "/assigns"):                   authenticated,
+	middleware.RouteKey(http.MethodGet, "/assigns/:id"):               authenticated,
+	middleware.RouteKey(http.MethodGet, "/allocations/:id/tasks"):     authenticated,
+	middleware.RouteKey(http.MethodPost, "/assigns"):                  can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodPost, "/assigns/suggestions"):      can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodPut, "/assigns/:id"):               can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodDelete, "/assigns/:id"):            can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodPost, "/assigns/:id/restore"):      can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodDelete, "/assigns/:id/permanent"):  can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodGet, "/assigns/:id/details"):       authenticated,
+	middleware.RouteKey(http.MethodGet, "/assigns/:id/timeline"):      authenticated,
+	middleware.RouteKey(http.MethodGet, "/assigns/:id/measurements"):  authenticated,
+	middleware.RouteKey(http.MethodGet, "/assigns/:id/sla"):           authenticated,
+	middleware.RouteKey(http.MethodGet, "/assigns/:id/handovers"):     authenticated,
+	middleware.RouteKey(http.MethodPost, "/assigns/:id/handover"):     can(domain.PermTaskExecute),
+	middleware.RouteKey(http.MethodPost, "/assigns/:id/details"):      can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodPost, "/details/:id/upload-image"): can(domain.PermTaskExecute),
+	middleware.RouteKey(http.MethodPut, "/details/:id/note"):          can(domain.PermTaskExecute),
+	middleware.RouteKey(http.MethodDelete, "/details/:id/image"):      can(domain.PermTaskExecute),
+	middleware.RouteKey(http.MethodDelete, "/details/:id/images"):     can(domain.PermTaskExecute),
+	middleware.RouteKey(http.MethodPost, "/details/:id/submit"):       can(domain.PermTaskExecute),
+	middleware.RouteKey(http.MethodPost, "/details/:id/approve"):      can(domain.PermAssignApprove),
+	middleware.RouteKey(http.MethodPost, "/details/:id/reject"):       can(domain.PermAssignApprove),
+	middleware.RouteKey(http.MethodGet, "/details/:id/approvals"):     authenticated,
+	middleware.RouteKey(http.MethodGet, "/details/:id/timeline"):      authenticated,
+	middleware.RouteKey(http.MethodGet, "/details/:id/owners"):        authenticated,
+	middleware.RouteKey(http.MethodPost, "/details/:id/reassign"):     can(domain.PermAssignManage),
+	middleware.RouteKey(http.MethodPost, "/details/:id/handover"):     can(domain.PermTaskExecute),
+	middleware.RouteKey(http.MethodPut, "/task-details/bulk/status"):  can(domain.PermAssignApprove),
+
+	// Discussion threads: open to whoever can see the assign (checked by the
+	// service); only authors edit, authors and managers delete
+	middleware.RouteKey(http.MethodGet, "/assigns/:id/comments"):       authenticated,
+	middleware.RouteKey(http.MethodPost, "/assigns/:id/comments"):      authenticated,
+	middleware.RouteKey(http.MethodPost, "/assigns/:id/comments/read"): authenticated,
+	middleware.RouteKey(http.MethodGet, "/details/:id/comments"):       authenticated,
+	middleware.RouteKey(http.MethodPost, "/details/:id/comments"):      authenticated,
+	middleware.RouteKey(http.MethodPost, "/details/:id/comments/read"): authenticated,
+	middleware.RouteKey(http.MethodGet, "/comments/unread"):            authenticated,
+	middleware.RouteKey(http.MethodPut, "/comments/:id"):               authenticated,
+	middleware.RouteKey(http.MethodDelete, "/comments/:id"):            authenticated,
+	middleware.RouteKey(http.MethodGet, "/comments/:id/history"):       authenticated,
+
+	// Lark
+	middleware.RouteKey(http.MethodPost, "/lark/push-report"):     can(domain.PermLarkPush),
+	middleware.RouteKey(http.MethodPost, "/lark/push-allocation"): can(domain.PermLarkPush),
+
+	// Guidelines
+	middleware.RouteKey(http.MethodGet, "/guidelines/subwork/:id"):  authenticated,
+	middleware.RouteKey(http.MethodPost, "/guidelines/subwork/:id"): can(domain.PermTemplateManage),
+}
@@ -0,0 +1,188 @@
+package di
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phuc/cmms-backend/internal/adapters/http/middleware"
+	"github.com/phuc/cmms-backend/internal/domain"
+)
+
+// engineerPermissions mirrors the engineer grants seeded in 000002_role_permissions.
+var engineerPermissions = []string{domain.PermTaskExecute, domain.PermAttendanceCheckin}
+
+// buildProtectedRouter mounts mapProtectedRoutes behind Authorize, with a stub
+// auth step that injects the given role and permissions instead of parsing a JWT.
+func buildProtectedRouter(role domain.UserRole, permissions []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+
+	protected := r.Group("/api").Group("/")
+	protected.Use(func(ctx *gin.Context) {
+		ctx.Set("role", string(role))
+		ctx.Set("permissions", permissions)
+		ctx.Next()
+	})
+	protected.Use(middleware.Authorize("/api", protectedRoutePolicies))
+
+	c := &Container{}
+	c.mapProtectedRoutes(protected)
+	return r
+}
+
+func TestEveryProtectedRouteHasPolicy(t *testing.T) {
+	r := buildProtectedRouter(domain.RoleEngineer, engineerPermissions)
+
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		key := middleware.RouteKey(route.Method, route.Path[len("/api"):])
+		registered[key] = true
+		if _, ok := protectedRoutePolicies[key]; !ok {
+			t.Errorf("route %q has no authorization policy", key)
+		}
+	}
+
+	for key := range protectedRoutePolicies {
+		if !registered[key] {
+			t.Errorf("policy %q does not match any registered route", key)
+		}
+	}
+}
+
+func TestEveryPolicyAllowsAdmin(t *testing.T) {
+	for key, policy := range protectedRoutePolicies {
+		if !policy.Allows(string(domain.RoleAdmin), domain.AllPermissionCodes) {
+			t.Errorf("policy %q locks out admin", key)
+		}
+	}
+}
+
+func TestEngineerForbiddenOnPrivilegedRoutes(t *testing.T) {
+	r := buildProtectedRouter(domain.RoleEngineer, engineerPermissions)
+
+	cases := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/admin/tables"},
+		{http.MethodDelete, "/api/admin/tables/users/1"},
+		{http.MethodDelete, "/api/users/123/permanent"},
+		{http.MethodPost, "/api/details/123/approve"},
+		{http.MethodPost, "/api/details/123/reject"},
+		{http.MethodDelete, "/api/projects/bulk-permanent"},
+		{http.MethodPut, "/api/task-details/bulk/status"},
+		{http.MethodPut, "/api/roles/123/permissions"},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected 403, got %d", tc.method, tc.path, w.Code)
+		}
+	}
+}
+
+func TestUnknownRoleForbidden(t *testing.T) {
+	r := buildProtectedRouter(domain.UserRole("guest"), nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/projects/123", nil)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for unknown role, got %d", w.Code)
+	}
+}
+
+func TestCustomRoleAdmittedByPermission(t *testing.T) {
+	approve := protectedRoutePolicies[middleware.RouteKey(http.MethodPost, "/details/:id/approve")]
+	if !approve.Allows("QA Inspector", []string{domain.PermAssignApprove}) {
+		t.Error("role granted assign.approve should be able to approve")
+	}
+	if approve.Allows("QA Inspector", []string{domain.PermStatsView}) {
+		t.Error("role without assign.approve must not approve")
+	}
+}
+
+func TestServiceAccountAdmittedOnlyByKeyScopes(t *testing.T) {
+	stats := protectedRoutePolicies[middleware.RouteKey(http.MethodGet, "/manager/stats")]
+	if !stats.Allows(domain.PrincipalServiceAccount, []string{domain.PermStatsView}) {
+		t.Error("key scoped to stats.view should read manager stats")
+	}
+	manage := protectedRoutePolicies[middleware.RouteKey(http.MethodPost, "/service-accounts/:id/keys")]
+	if manage.Allows(domain.PrincipalServiceAccount, []string{domain.PermStatsView}) {
+		t.Error("key without service_account.manage must not mint keys")
+	}
+}
+
+func TestPolicyPermissionsExistInCatalog(t *testing.T) {
+	catalog := make(map[string]bool)
+	for _, code := range domain.AllPermissionCodes {
+		catalog[code] = true
+	}
+	for key, policy := range protectedRoutePolicies {
+		if policy.Permission != "" && !catalog[policy.Permission] {
+			t.Errorf("policy %q uses unknown permission %q", key, policy.Permission)
+		}
+	}
+
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("list migrations: %v", err)
+	}
+	var seed strings.Builder
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("read %s: %v", f, err)
+		}
+		seed.Write(b)
+	}
+	for _, code := range domain.AllPermissionCodes {
+		if !strings.Contains(seed.String(), "'"+code+"'") {
+			t.Errorf("permission %q is not seeded by the migration", code)
+		}
+	}
+}
+
+func TestPasswordChangeRoutesAreProtected(t *testing.T) {
+	for _, key := range passwordChangeRoutes {
+		if _, ok := protectedRoutePolicies[key]; !ok {
+			t.Errorf("password change route %q is not a protected route", key)
+		}
+	}
+}
+
+func TestMustChangePasswordBlocksOtherRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	protected := r.Group("/api").Group("/")
+	protected.Use(func(ctx *gin.Context) {
+		ctx.Set("must_change_password", true)
+		ctx.Next()
+	})
+	protected.Use(middleware.RequirePasswordChange("/api", passwordChangeRoutes...))
+	protected.GET("/projects", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	protected.PUT("/users/:id/password", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	cases := map[string]int{
+		http.MethodGet + " /api/projects":         http.StatusForbidden,
+		http.MethodPut + " /api/users/1/password": http.StatusOK,
+	}
+	for route, want := range cases {
+		parts := strings.SplitN(route, " ", 2)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(parts[0], parts[1], nil))
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d", route, want, w.Code)
+		}
+	}
+}
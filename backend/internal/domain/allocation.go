@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -52,6 +53,8 @@ type Config struct {
 	ImageCount          int            `gorm:"column:image_count;default:0" json:"image_count"`
 	GuideText           string         `gorm:"column:guide_text" json:"guide_text"`
 	GuideImages         datatypes.JSON `gorm:"column:guide_images;type:jsonb;default:'[]'" json:"guide_images"`
+	EvidenceSlots       datatypes.JSON `gorm:"column:evidence_slots;type:jsonb;default:'[]'" json:"evidence_slots"` // []EvidenceSlot, see evidence.go
+	MeasurementFields   datatypes.JSON `gorm:"column:measurement_fields;type:jsonb;default:'[]'" json:"measurement_fields"` // []MeasurementField; empty = the sub-work's
 	CreatedAt           time.Time      `json:"created_at"`
 	UpdatedAt           time.Time      `json:"updated_at"`
 	DeletedAt           gorm.DeletedAt `gorm:"index" json:"deleted_at"`
@@ -96,18 +99,37 @@ type DetailAssign struct {
 	Config    *Config    `gorm:"foreignKey:ConfigID;references:ID" json:"config,omitempty"`
 	ProcessID *uuid.UUID `gorm:"column:id_process;type:uuid" json:"id_process"`
 	Process   *Process   `gorm:"foreignKey:ProcessID;references:ID" json:"process,omitempty"`
+	// Engineer holding the task; nil while it is shared by the assign's users (see Holders)
+	OwnerID *uuid.UUID `gorm:"column:id_owner;type:uuid;index" json:"id_owner"`
 
 	// Evidence (array of image URLs as JSONB)
 	Data     datatypes.JSON `gorm:"column:data;type:jsonb;default:'[]'" json:"data"`
 	NoteData string         `gorm:"column:note_data" json:"note_data"`
-
-	// Status flags (0 = pending, 1 = completed/approved)
+	// Slot key -> URLs uploaded for it (subset of Data), see evidence.go
+	SlotEvidence datatypes.JSON `gorm:"column:slot_evidence;type:jsonb;default:'{}'" json:"slot_evidence"`
+	// Typed readings submitted with the task, see measurement.go
+	Measurements []DetailMeasurement `gorm:"foreignKey:DetailAssignID" json:"measurements,omitempty"`
+	// Checklist answers (field key -> answer) to the form version pinned when work started, see form.go
+	FormSchemaID *uuid.UUID     `gorm:"column:id_form_schema;type:uuid" json:"id_form_schema"`
+	FormSchema   *FormSchema    `gorm:"foreignKey:FormSchemaID;references:ID" json:"form_schema,omitempty"`
+	FormAnswers  datatypes.JSON `gorm:"column:form_answers;type:jsonb;default:'{}'" json:"form_answers"`
+
+	// Workflow state; changed only through Transition (see detail_state.go)
+	State DetailState `gorm:"column:state;type:varchar(20);not null;default:'draft';index" json:"state"`
+	// Approval chain progress: submissions so far and chain levels completed in the current one
+	ApprovalRound int `gorm:"column:approval_round;default:0" json:"approval_round"`
+	ApprovalLevel int `gorm:"column:approval_level;default:0" json:"approval_level"`
+	// Bumped by every workflow write; clients send it back (If-Match) to detect stale copies
+	Version int `gorm:"column:version;not null;default:1" json:"version"`
+
+	// Status flags (0 = pending, 1 = completed/approved), kept in sync with State by Transition
 	StatusWork    int `gorm:"column:status_work;default:0" json:"status_work"`
 	StatusSubmit  int `gorm:"column:status_submit;default:0" json:"status_submit"`
 	StatusReject  int `gorm:"column:status_reject;default:0" json:"status_reject"`
 	StatusApprove int `gorm:"column:status_approve;default:0" json:"status_approve"`
 
-	// Timestamp history (JSONB arrays for full audit trail across multiple actions)
+	// Timestamp history (JSONB arrays). Deprecated: kept for the frontend only;
+	// detail_assign_events (DetailAssignEvent) is the source of truth.
 	SubmittedAt datatypes.JSON `gorm:"column:submitted_at;type:jsonb;default:'[]'" json:"submitted_at"`
 	RejectedAt  datatypes.JSON `gorm:"column:rejected_at;type:jsonb;default:'[]'" json:"rejected_at"`
 	ApprovalAt  datatypes.JSON `gorm:"column:approval_at;type:jsonb;default:'[]'" json:"approval_at"`
@@ -160,9 +182,9 @@ type ConfigRepository interface {
 
 type AssignRepository interface {
 	Create(assign *Assign) error
-	FindAll() ([]Assign, error)
-	FindByUserID(userID string) ([]Assign, error)
-	FindByID(id uuid.UUID) (*Assign, error)
+	FindAll(scope *VisibilityScope) ([]Assign, error)
+	FindByUserID(userID string, scope *VisibilityScope) ([]Assign, error)
+	FindByID(id uuid.UUID, scope *VisibilityScope) (*Assign, error)
 	Update(assign *Assign) error
 	Delete(id uuid.UUID) error
 	FindAllDeleted() ([]Assign, error)
@@ -170,13 +192,23 @@ type AssignRepository interface {
 	PermanentDelete(id uuid.UUID) error
 }
 
+// ErrStaleVersion is returned when saving a DetailAssign whose row was changed
+// since it was read (its version no longer matches).
+var ErrStaleVersion = errors.New("detail assign was changed concurrently")
+
 type DetailAssignRepository interface {
 	Create(detail *DetailAssign) error
 	FindByAssignID(assignID uuid.UUID) ([]DetailAssign, error)
 	FindByID(id uuid.UUID) (*DetailAssign, error)
+	// Update saves detail only if the row is still at detail.Version, then bumps
+	// the version; otherwise it returns ErrStaleVersion.
 	Update(detail *DetailAssign) error
 	Delete(id uuid.UUID) error
 	GetNamesForMinioPath(detailAssignID uuid.UUID) (*MinioPathContext, error)
+	// HasMedia reports whether a task of the assign lists the object key in its evidence
+	HasMedia(assignID uuid.UUID, key string) (bool, error)
+	// WithTx returns the repository running in tx
+	WithTx(tx *gorm.DB) DetailAssignRepository
 }
 
 type TemplateRepository interface {
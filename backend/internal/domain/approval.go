@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ApprovalChain is the ordered list of sign-offs a task of a project or template
+// needs before it counts as approved (e.g. site lead -> PM -> owner engineer).
+// Exactly one of ProjectID / TemplateID is set; a template chain overrides the
+// chain of its project. Projects without a chain keep single-step approval.
+type ApprovalChain struct {
+	ID         uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ProjectID  *uuid.UUID      `gorm:"column:id_project;type:uuid" json:"id_project"`
+	TemplateID *uuid.UUID      `gorm:"column:id_template;type:uuid" json:"id_template"`
+	Levels     []ApprovalLevel `gorm:"foreignKey:ChainID" json:"levels"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+func (ApprovalChain) TableName() string {
+	return "approval_chains"
+}
+
+// ApprovalLevel is one step of a chain. Approvers are the listed users plus the
+// holders of the listed roles; Quorum distinct approvals complete the level.
+type ApprovalLevel struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ChainID   uuid.UUID      `gorm:"column:id_chain;type:uuid;not null;index" json:"id_chain"`
+	Position  int            `gorm:"column:position;not null" json:"position"` // 0-based order in the chain
+	Name      string         `gorm:"column:name" json:"name"`
+	Roles     datatypes.JSON `gorm:"column:roles;type:jsonb;default:'[]'" json:"roles"`       // Role names
+	UserIDs   datatypes.JSON `gorm:"column:id_users;type:jsonb;default:'[]'" json:"id_users"` // User UUIDs
+	Quorum    int            `gorm:"column:quorum;default:1" json:"quorum"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+func (ApprovalLevel) TableName() string {
+	return "approval_levels"
+}
+
+// Allows reports whether a user with the given role may sign off this level.
+// A level listing neither roles nor users accepts any approver.
+func (l *ApprovalLevel) Allows(userID uuid.UUID, role string) bool {
+	var roles, users []string
+	_ = json.Unmarshal(l.Roles, &roles)
+	_ = json.Unmarshal(l.UserIDs, &users)
+	if len(roles) == 0 && len(users) == 0 {
+		return true
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	for _, u := range users {
+		if u == userID.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// DetailApproval is one sign-off of a DetailAssign at a chain level. Round is the
+// detail's ApprovalRound, so sign-offs of an earlier submission no longer count.
+type DetailApproval struct {
+	ID             uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	DetailAssignID uuid.UUID `gorm:"column:id_detail_assign;type:uuid;not null;index" json:"id_detail_assign"`
+	ChainID        uuid.UUID `gorm:"column:id_chain;type:uuid" json:"id_chain"`
+	Round          int       `gorm:"column:round" json:"round"`
+	Level          int       `gorm:"column:level" json:"level"`
+	ApproverID     uuid.UUID `gorm:"column:id_approver;type:uuid" json:"id_approver"`
+	Approver       *User     `gorm:"foreignKey:ApproverID;references:ID" json:"approver,omitempty"`
+	Note           string    `gorm:"column:note" json:"note"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (DetailApproval) TableName() string {
+	return "detail_approvals"
+}
+
+type ApprovalChainRepository interface {
+	// FindForAssign returns the chain applying to an assign: the template's chain,
+	// else the project's, else nil.
+	FindForAssign(projectID uuid.UUID, templateID *uuid.UUID) (*ApprovalChain, error)
+	FindByProject(projectID uuid.UUID) (*ApprovalChain, error)
+	FindByTemplate(templateID uuid.UUID) (*ApprovalChain, error)
+	// Save replaces the chain (and its levels) of chain.ProjectID or chain.TemplateID
+	Save(chain *ApprovalChain) error
+	DeleteByProject(projectID uuid.UUID) error
+	DeleteByTemplate(templateID uuid.UUID) error
+
+	// FindApprovals returns the sign-offs of a detail in a round, oldest first
+	FindApprovals(detailID uuid.UUID, round int) ([]DetailApproval, error)
+	// RecordApproval stores a sign-off and the updated detail in one transaction,
+	// failing with ErrStaleVersion like DetailAssignRepository.Update
+	RecordApproval(approval *DetailApproval, detail *DetailAssign) error
+	// WithTx returns the repository running in tx
+	WithTx(tx *gorm.DB) ApprovalChainRepository
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Audit actions of row changes and of API calls without a workflow verb in their route
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditActorSystem marks row changes made outside an attributed context:
+// scheduled jobs, queue consumers and writes that were not given the request context.
+const AuditActorSystem = "system"
+
+// AuditLog is one append-only audit record, either of an API call (Method and Route
+// set, written by middleware.Audit) or of one row changed through GORM (before/after
+// state, written by the audit callbacks for every caller). Rows changed within an
+// attributed request share its RequestID.
+type AuditLog struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	RequestID   uuid.UUID      `gorm:"column:request_id;type:uuid;index" json:"request_id"`
+	ActorType   string         `gorm:"column:actor_type;default:'user'" json:"actor_type"` // PrincipalUser or PrincipalServiceAccount
+	ActorID     *uuid.UUID     `gorm:"column:id_actor;type:uuid;index" json:"id_actor"`    // User or service account ID
+	ActorRole   string         `gorm:"column:actor_role" json:"actor_role"`
+	IPAddress   string         `gorm:"column:ip_address" json:"ip_address"`
+	Method      string         `gorm:"column:method" json:"method"`
+	Route       string         `gorm:"column:route" json:"route"`
+	Action      string         `gorm:"column:action" json:"action"`
+	EntityType  string         `gorm:"column:entity_type;index:idx_audit_logs_entity" json:"entity_type"`
+	EntityID    string         `gorm:"column:entity_id;index:idx_audit_logs_entity" json:"entity_id"`
+	Before      datatypes.JSON `gorm:"column:before_data;type:jsonb" json:"before,omitempty"`
+	After       datatypes.JSON `gorm:"column:after_data;type:jsonb" json:"after,omitempty"`
+	Changes     datatypes.JSON `gorm:"column:changes;type:jsonb" json:"changes,omitempty"`
+	RequestBody datatypes.JSON `gorm:"column:request_body;type:jsonb" json:"request_body,omitempty"`
+	StatusCode  int            `gorm:"column:status_code" json:"status_code"`
+	CreatedAt   time.Time      `gorm:"index" json:"created_at"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditActor is the caller a write is attributed to
+type AuditActor struct {
+	RequestID uuid.UUID
+	Type      string // PrincipalUser or PrincipalServiceAccount
+	ID        *uuid.UUID
+	Role      string
+	IPAddress string
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor attributes the writes of statements run with ctx (db.WithContext) to actor
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActorFrom returns the actor attached by WithAuditActor, if any
+func AuditActorFrom(ctx context.Context) (AuditActor, bool) {
+	if ctx == nil {
+		return AuditActor{}, false
+	}
+	actor, ok := ctx.Value(auditActorKey{}).(AuditActor)
+	return actor, ok
+}
+
+// AuditFilter narrows an audit log query; zero fields are ignored
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	ActorType  string
+	ActorID    *uuid.UUID
+	Action     string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+// AuditRepository stores audit logs. There is deliberately no Update or Delete:
+// the table is append-only (enforced by a trigger in 000006_audit_logs).
+type AuditRepository interface {
+	Create(logs []AuditLog) error
+	// Find returns matching logs newest first, plus the total match count
+	Find(filter AuditFilter) ([]AuditLog, int64, error)
+	// WithTx returns the repository running in tx
+	WithTx(tx *gorm.DB) AuditRepository
+}
@@ -17,11 +17,24 @@ const (
 
 // Role represents a system role (admin, manager, engineer)
 type Role struct {
-	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	Name      string         `gorm:"uniqueIndex;not null" json:"name"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	ID         uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name       string         `gorm:"uniqueIndex;not null" json:"name"`
+	RequireMFA bool           `gorm:"column:require_mfa;default:false" json:"require_mfa"` // Holders must enrol in two-factor login
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	// Transient: permissions granted through role_permissions
+	Permissions []Permission `gorm:"-" json:"permissions,omitempty"`
+}
+
+// IsBuiltIn reports whether the role is one of the roles the backend ships with
+func (r Role) IsBuiltIn() bool {
+	switch UserRole(r.Name) {
+	case RoleAdmin, RoleManager, RoleEngineer:
+		return true
+	}
+	return false
 }
 
 // Team represents a maintenance team
@@ -42,6 +55,18 @@ type User struct {
 	NumberPhone  string         `gorm:"column:number_phone" json:"number_phone"`
 	StatusUser   int            `gorm:"column:status_user;default:0" json:"status_user"` // 0 = offline, 1 = online
 
+	// Password policy & lockout
+	MustChangePassword bool       `gorm:"column:must_change_password;default:false" json:"must_change_password"` // Set for admin-created accounts until the user picks a password
+	PasswordChangedAt  *time.Time `gorm:"column:password_changed_at" json:"password_changed_at"`
+	FailedLoginCount   int        `gorm:"column:failed_login_count;default:0" json:"failed_login_count"`
+	LockedUntil        *time.Time `gorm:"column:locked_until" json:"locked_until"`
+
+	// Two-factor login (RFC 6238 TOTP). The secret is stored encrypted and is
+	// only active once TOTPEnabled is set by a confirmed enrolment.
+	TOTPSecret   string `gorm:"column:totp_secret" json:"-"`
+	TOTPEnabled  bool   `gorm:"column:totp_enabled;default:false" json:"totp_enabled"`
+	TOTPLastStep int64  `gorm:"column:totp_last_step;default:0" json:"-"` // Last accepted time step, blocks code replay
+
 	// Role relation
 	RoleID    *uuid.UUID `gorm:"column:id_role;type:uuid" json:"role_id"`
 	RoleModel *Role      `gorm:"foreignKey:RoleID;references:ID" json:"role,omitempty"`
@@ -63,6 +88,11 @@ type User struct {
 	AssignedProjects []AssignedProjectDTO `gorm:"-" json:"assigned_projects"`
 }
 
+// IsAdmin reports whether the user holds the built-in admin role (RoleModel must be loaded)
+func (u *User) IsAdmin() bool {
+	return u.RoleModel != nil && UserRole(u.RoleModel.Name) == RoleAdmin
+}
+
 type AssignedProjectDTO struct {
 	ID             uuid.UUID          `json:"id"`
 	Project        *ProjectSimpleDTO  `json:"project,omitempty"`
@@ -91,6 +121,12 @@ type UserRepository interface {
 	UpdateRole(userID uuid.UUID, roleID uuid.UUID) error
 	Update(user *User) error
 	UpdateStatus(userID uuid.UUID, status int) error
+	// UpdateLoginState stores the failed-login counter and lockout deadline
+	UpdateLoginState(userID uuid.UUID, failedCount int, lockedUntil *time.Time) error
+	// SetTOTP stores the (encrypted) TOTP secret and enrolment state; an empty secret disables 2FA
+	SetTOTP(userID uuid.UUID, secret string, enabled bool) error
+	// ClaimTOTPStep records a used TOTP time step; it reports false if that step or a later one was already used
+	ClaimTOTPStep(userID uuid.UUID, step int64) (bool, error)
 	GetUserCount() (int64, error)
 	GetTeamCount() (int64, error)
 	GetDB() *gorm.DB
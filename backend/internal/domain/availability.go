@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UnavailabilityKind says why an engineer cannot be scheduled
+type UnavailabilityKind string
+
+const (
+	UnavailableLeave    UnavailabilityKind = "leave"
+	UnavailableTraining UnavailabilityKind = "training"
+	UnavailableOther    UnavailabilityKind = "other"
+)
+
+// Valid reports whether k is a known kind
+func (k UnavailabilityKind) Valid() bool {
+	switch k {
+	case UnavailableLeave, UnavailableTraining, UnavailableOther:
+		return true
+	}
+	return false
+}
+
+// Unavailability is a period an engineer is away: leave, training or anything
+// else keeping them off site. Together with the assigns they already work on it
+// makes up their calendar; periods are half-open, [StartTime, EndTime).
+type Unavailability struct {
+	ID          uuid.UUID          `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID      uuid.UUID          `gorm:"column:id_user;type:uuid;not null;index" json:"id_user"`
+	User        *User              `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
+	Kind        UnavailabilityKind `gorm:"column:kind;type:varchar(20);not null" json:"kind"`
+	StartTime   time.Time          `gorm:"column:start_time;not null" json:"start_time"`
+	EndTime     time.Time          `gorm:"column:end_time;not null" json:"end_time"`
+	Note        string             `gorm:"column:note" json:"note"`
+	CreatedByID *uuid.UUID         `gorm:"column:id_created_by;type:uuid" json:"id_created_by"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+func (Unavailability) TableName() string {
+	return "user_unavailabilities"
+}
+
+// UserSchedule is what keeps a user busy over a period: the assigns they work
+// on and the time they are away
+type UserSchedule struct {
+	UserID      uuid.UUID        `json:"id_user"`
+	User        *User            `json:"user,omitempty"`
+	Assigns     []Assign         `json:"assigns"`
+	Unavailable []Unavailability `json:"unavailable"`
+}
+
+// Busy reports whether anything is scheduled
+func (s *UserSchedule) Busy() bool {
+	return len(s.Assigns) > 0 || len(s.Unavailable) > 0
+}
+
+type AvailabilityRepository interface {
+	Create(entry *Unavailability) error
+	// FindByID returns the entry, or nil
+	FindByID(id uuid.UUID) (*Unavailability, error)
+	Update(entry *Unavailability) error
+	Delete(id uuid.UUID) error
+	// FindUnavailable returns the entries of the users overlapping [from, to), earliest first
+	FindUnavailable(userIDs []uuid.UUID, from, to time.Time) ([]Unavailability, error)
+	// FindAssigns returns the live assigns of any of the users whose time window
+	// overlaps [from, to), earliest first, with their project. Assigns without
+	// both a start and an end time are not scheduled and never returned.
+	FindAssigns(userIDs []uuid.UUID, from, to time.Time, excludeID *uuid.UUID) ([]Assign, error)
+	// FindTeamUsers returns the active users of a team
+	FindTeamUsers(teamID uuid.UUID) ([]User, error)
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Certification is a qualification an engineer may hold, such as electrical
+// safety or working at height. Works list the ones they require (see
+// WorkCertification).
+type Certification struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name        string    `gorm:"column:name;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"column:description" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Certification) TableName() string {
+	return "certifications"
+}
+
+// UserCertification is a certification held by a user, until ExpiresAt if set
+type UserCertification struct {
+	ID              uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID          uuid.UUID      `gorm:"column:id_user;type:uuid;not null;uniqueIndex:idx_user_certifications_user_cert" json:"id_user"`
+	CertificationID uuid.UUID      `gorm:"column:id_certification;type:uuid;not null;uniqueIndex:idx_user_certifications_user_cert" json:"id_certification"`
+	Certification   *Certification `gorm:"foreignKey:CertificationID;references:ID" json:"certification,omitempty"`
+	IssuedAt        *time.Time     `gorm:"column:issued_at" json:"issued_at"`
+	ExpiresAt       *time.Time     `gorm:"column:expires_at" json:"expires_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+func (UserCertification) TableName() string {
+	return "user_certifications"
+}
+
+// ValidAt reports whether the certification has not expired by t
+func (c *UserCertification) ValidAt(t time.Time) bool {
+	return c.ExpiresAt == nil || c.ExpiresAt.After(t)
+}
+
+// WorkCertification requires a certification of whoever does a work
+type WorkCertification struct {
+	WorkID          uuid.UUID      `gorm:"column:id_work;type:uuid;primaryKey" json:"id_work"`
+	CertificationID uuid.UUID      `gorm:"column:id_certification;type:uuid;primaryKey" json:"id_certification"`
+	Certification   *Certification `gorm:"foreignKey:CertificationID;references:ID" json:"certification,omitempty"`
+}
+
+func (WorkCertification) TableName() string {
+	return "work_certifications"
+}
+
+type CertificationRepository interface {
+	Create(cert *Certification) error
+	FindAll() ([]Certification, error)
+	// FindByIDs returns the certifications that exist among ids
+	FindByIDs(ids []uuid.UUID) ([]Certification, error)
+	Delete(id uuid.UUID) error
+
+	// FindUserCertifications returns the certifications held by the users, with their certification
+	FindUserCertifications(userIDs []uuid.UUID) ([]UserCertification, error)
+	// SaveUserCertification grants a certification, or updates its dates if already held
+	SaveUserCertification(cert *UserCertification) error
+	RemoveUserCertification(userID, certificationID uuid.UUID) error
+
+	// FindWorkRequirements returns the certifications required by the works, with their certification
+	FindWorkRequirements(workIDs []uuid.UUID) ([]WorkCertification, error)
+	// SetWorkRequirements replaces the certifications required by a work
+	SetWorkRequirements(workID uuid.UUID, certificationIDs []uuid.UUID) error
+}
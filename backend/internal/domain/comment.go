@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// CommentAttachment is a file stored in MinIO alongside a comment
+type CommentAttachment struct {
+	URL         string `json:"url"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// Comment is a message in the discussion thread of an assign, or of one of
+// its tasks when DetailAssignID is set. Edits and deletions keep the former
+// content as CommentRevisions; a deleted comment stays in its thread, emptied,
+// so that the replies around it still read in order.
+type Comment struct {
+	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AssignID       uuid.UUID      `gorm:"column:id_assign;type:uuid;not null;index" json:"id_assign"`
+	DetailAssignID *uuid.UUID     `gorm:"column:id_detail_assign;type:uuid;index" json:"id_detail_assign"` // nil: the assign's own thread
+	AuthorID       uuid.UUID      `gorm:"column:id_author;type:uuid;not null" json:"id_author"`
+	Author         *User          `gorm:"foreignKey:AuthorID;references:ID" json:"author,omitempty"`
+	Body           string         `gorm:"column:body;type:text" json:"body"`
+	Attachments    datatypes.JSON `gorm:"column:attachments;type:jsonb;default:'[]'" json:"attachments"` // []CommentAttachment
+	Mentions       datatypes.JSON `gorm:"column:mentions;type:jsonb;default:'[]'" json:"mentions"`       // Mentioned user IDs
+	EditedAt       *time.Time     `gorm:"column:edited_at" json:"edited_at"`
+	DeletedAt      *time.Time     `gorm:"column:deleted_at" json:"deleted_at"`
+	CreatedAt      time.Time      `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+func (Comment) TableName() string {
+	return "comments"
+}
+
+// ThreadID identifies the thread of the comment: its task, or its assign
+func (c *Comment) ThreadID() uuid.UUID {
+	if c.DetailAssignID != nil {
+		return *c.DetailAssignID
+	}
+	return c.AssignID
+}
+
+// MentionedUsers decodes the users mentioned in the comment
+func (c *Comment) MentionedUsers() []uuid.UUID {
+	var ids []uuid.UUID
+	if len(c.Mentions) > 0 {
+		_ = json.Unmarshal(c.Mentions, &ids)
+	}
+	return ids
+}
+
+// Files decodes the attachments of the comment
+func (c *Comment) Files() []CommentAttachment {
+	var files []CommentAttachment
+	if len(c.Attachments) > 0 {
+		_ = json.Unmarshal(c.Attachments, &files)
+	}
+	return files
+}
+
+// CommentAction says what a revision of a comment records
+type CommentAction string
+
+const (
+	CommentEdited  CommentAction = "edit"
+	CommentDeleted CommentAction = "delete"
+)
+
+// CommentRevision is the content a comment had before an edit or deletion
+type CommentRevision struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	CommentID   uuid.UUID      `gorm:"column:id_comment;type:uuid;not null;index" json:"id_comment"`
+	Action      CommentAction  `gorm:"column:action;type:varchar(20);not null" json:"action"`
+	Body        string         `gorm:"column:body;type:text" json:"body"`
+	Attachments datatypes.JSON `gorm:"column:attachments;type:jsonb;default:'[]'" json:"attachments"`
+	Mentions    datatypes.JSON `gorm:"column:mentions;type:jsonb;default:'[]'" json:"mentions"`
+	ActorID     *uuid.UUID     `gorm:"column:id_actor;type:uuid" json:"id_actor"`
+	Actor       *User          `gorm:"foreignKey:ActorID;references:ID" json:"actor,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+func (CommentRevision) TableName() string {
+	return "comment_revisions"
+}
+
+// UnreadThread counts the comments of one thread a user has not read yet
+type UnreadThread struct {
+	AssignID       uuid.UUID  `json:"id_assign"`
+	DetailAssignID *uuid.UUID `json:"id_detail_assign"`
+	Unread         int64      `json:"unread"`
+}
+
+type CommentRepository interface {
+	Create(comment *Comment) error
+	// FindByID returns the comment, or nil
+	FindByID(id uuid.UUID) (*Comment, error)
+	// FindThread returns the comments of a task's thread, or of the assign's
+	// own thread when detailID is nil, oldest first
+	FindThread(assignID uuid.UUID, detailID *uuid.UUID) ([]Comment, error)
+	// Revise saves the comment together with the revision keeping its former content
+	Revise(comment *Comment, revision *CommentRevision) error
+	// FindRevisions returns the history of a comment, oldest first
+	FindRevisions(commentID uuid.UUID) ([]CommentRevision, error)
+	// MarkRead records that the user has read a thread up to at
+	MarkRead(userID, threadID uuid.UUID, at time.Time) error
+	// CountUnread counts, per thread, the comments by others the user has not
+	// read in the threads they take part in: those of assigns they work on,
+	// and any comment mentioning them. assignID limits the count to one assign.
+	CountUnread(userID uuid.UUID, assignID *uuid.UUID) ([]UnreadThread, error)
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// DefectSeverity is how urgently a defect must be corrected
+type DefectSeverity string
+
+const (
+	DefectSeverityLow      DefectSeverity = "low"
+	DefectSeverityMedium   DefectSeverity = "medium"
+	DefectSeverityHigh     DefectSeverity = "high"     // Production loss
+	DefectSeverityCritical DefectSeverity = "critical" // Safety risk or plant down
+)
+
+// DefectSeverities lists the valid severities, least urgent first
+var DefectSeverities = []DefectSeverity{DefectSeverityLow, DefectSeverityMedium, DefectSeverityHigh, DefectSeverityCritical}
+
+// DefectCategories lists the valid categories of a defect
+var DefectCategories = []string{"electrical", "mechanical", "civil", "communication", "safety", "other"}
+
+// DefectStatus is the workflow state of a Defect
+type DefectStatus string
+
+const (
+	DefectStatusOpen       DefectStatus = "open"
+	DefectStatusInProgress DefectStatus = "in_progress" // Being corrected, usually through a corrective assign
+	DefectStatusResolved   DefectStatus = "resolved"    // Fixed, waiting to be verified
+	DefectStatusClosed     DefectStatus = "closed"
+	DefectStatusCancelled  DefectStatus = "cancelled" // Raised by mistake or a duplicate
+)
+
+// defectTransitions is the defect workflow: state -> states it may move to
+var defectTransitions = map[DefectStatus][]DefectStatus{
+	DefectStatusOpen:       {DefectStatusInProgress, DefectStatusResolved, DefectStatusCancelled},
+	DefectStatusInProgress: {DefectStatusOpen, DefectStatusResolved, DefectStatusCancelled},
+	DefectStatusResolved:   {DefectStatusClosed, DefectStatusInProgress}, // Verified, or the fix did not hold
+}
+
+// CanMoveTo reports whether the workflow allows going from s to next
+func (s DefectStatus) CanMoveTo(next DefectStatus) bool {
+	for _, allowed := range defectTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Defect is a fault found on an asset (a broken MC4 connector, a tripped
+// string...), usually raised during a visit. It is corrected either directly
+// or through a corrective Assign created from it.
+type Defect struct {
+	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AssetID        uuid.UUID      `gorm:"column:id_asset;type:uuid;not null;index" json:"id_asset"`
+	Asset          *Asset         `gorm:"foreignKey:AssetID;references:ID" json:"asset,omitempty"`
+	ProjectID      uuid.UUID      `gorm:"column:id_project;type:uuid;not null;index" json:"id_project"` // The asset's, for filtering and visibility
+	DetailAssignID *uuid.UUID     `gorm:"column:id_detail_assign;type:uuid" json:"id_detail_assign"`    // Task it was found during, if any
+	DetailAssign   *DetailAssign  `gorm:"foreignKey:DetailAssignID;references:ID" json:"detail_assign,omitempty"`
+	Title          string         `gorm:"column:title;not null" json:"title"`
+	Description    string         `gorm:"column:description" json:"description"`
+	Category       string         `gorm:"column:category;type:varchar(50);not null" json:"category"`
+	Severity       DefectSeverity `gorm:"column:severity;type:varchar(20);not null" json:"severity"`
+	Status         DefectStatus   `gorm:"column:status;type:varchar(20);not null;default:'open';index" json:"status"`
+	Photos         datatypes.JSON `gorm:"column:photos;type:jsonb;default:'[]'" json:"photos"` // Image URLs
+	AssigneeID     *uuid.UUID     `gorm:"column:id_assignee;type:uuid" json:"id_assignee"`
+	Assignee       *User          `gorm:"foreignKey:AssigneeID;references:ID" json:"assignee,omitempty"`
+	ReporterID     *uuid.UUID     `gorm:"column:id_reporter;type:uuid" json:"id_reporter"`
+	Reporter       *User          `gorm:"foreignKey:ReporterID;references:ID" json:"reporter,omitempty"`
+	AssignID       *uuid.UUID     `gorm:"column:id_assign;type:uuid" json:"id_assign"` // Corrective assign
+	Assign         *Assign        `gorm:"foreignKey:AssignID;references:ID" json:"assign,omitempty"`
+	Resolution     string         `gorm:"column:resolution" json:"resolution"`
+	ResolvedAt     *time.Time     `gorm:"column:resolved_at" json:"resolved_at"`
+	ClosedByID     *uuid.UUID     `gorm:"column:id_closed_by;type:uuid" json:"id_closed_by"`
+	ClosedAt       *time.Time     `gorm:"column:closed_at" json:"closed_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
+
+func (Defect) TableName() string {
+	return "defects"
+}
+
+// DefectFilter narrows a defect listing; zero fields match everything
+type DefectFilter struct {
+	ProjectID  *uuid.UUID
+	AssetID    *uuid.UUID
+	AssigneeID *uuid.UUID
+	Status     DefectStatus
+	Severity   DefectSeverity
+}
+
+type DefectRepository interface {
+	Create(defect *Defect) error
+	FindAll(filter DefectFilter, scope *VisibilityScope) ([]Defect, error)
+	// FindByID returns the defect, or nil when it is missing or hidden from the scope
+	FindByID(id uuid.UUID, scope *VisibilityScope) (*Defect, error)
+	Update(defect *Defect) error
+	// Convert stores the corrective assign with its details and the defect
+	// linked to it, in one transaction
+	Convert(defect *Defect, assign *Assign, details []DetailAssign) error
+}
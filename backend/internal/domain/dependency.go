@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DependencyGate is how far the tasks of a predecessor config must have got
+// before the tasks depending on it may start
+type DependencyGate string
+
+const (
+	GateSubmitted DependencyGate = "submitted" // Handed in for review, or further
+	GateApproved  DependencyGate = "approved"  // Finally approved
+)
+
+// Reached reports whether a task in state s has passed the gate
+func (g DependencyGate) Reached(s DetailState) bool {
+	switch s {
+	case DetailStateApproved:
+		return true
+	case DetailStateSubmitted, DetailStateResubmitted, DetailStatePartiallyApproved:
+		return g == GateSubmitted
+	}
+	return false
+}
+
+// ConfigDependency orders two configs of a template: tasks of ConfigID may
+// not start until every task of DependsOnID in the same assign has reached
+// Requires (e.g. "Re-energize inverter" after "Lock-out / tag-out" is approved).
+// The dependencies of a template form a DAG.
+type ConfigDependency struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TemplateID  uuid.UUID      `gorm:"column:id_template;type:uuid;not null;index" json:"id_template"`
+	ConfigID    uuid.UUID      `gorm:"column:id_config;type:uuid;not null" json:"id_config"`
+	DependsOnID uuid.UUID      `gorm:"column:id_depends_on;type:uuid;not null" json:"id_depends_on"`
+	Requires    DependencyGate `gorm:"column:required_state;type:varchar(20);not null;default:'approved'" json:"required_state"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+func (ConfigDependency) TableName() string {
+	return "config_dependencies"
+}
+
+// TaskBlocker is a predecessor task holding another one back
+type TaskBlocker struct {
+	DetailAssignID uuid.UUID      `json:"id_detail_assign"`
+	ConfigID       uuid.UUID      `json:"id_config"`
+	Name           string         `json:"name"` // Sub-work and asset, when loaded
+	State          DetailState    `json:"state"`
+	Requires       DependencyGate `json:"required_state"`
+}
+
+// BlockedBy returns, for each task of an assign, the predecessor tasks that
+// have not yet reached their gate. Dependencies on configs with no task in
+// the assign are ignored. Tasks that are free to go are left out.
+func BlockedBy(deps []ConfigDependency, details []DetailAssign) map[uuid.UUID][]TaskBlocker {
+	byConfig := make(map[uuid.UUID][]*DetailAssign)
+	for i := range details {
+		if d := &details[i]; d.ConfigID != nil {
+			byConfig[*d.ConfigID] = append(byConfig[*d.ConfigID], d)
+		}
+	}
+	blocked := make(map[uuid.UUID][]TaskBlocker)
+	for _, dep := range deps {
+		var waiting []TaskBlocker
+		for _, pre := range byConfig[dep.DependsOnID] {
+			if state := pre.CurrentState(); !dep.Requires.Reached(state) {
+				waiting = append(waiting, TaskBlocker{
+					DetailAssignID: pre.ID,
+					ConfigID:       dep.DependsOnID,
+					Name:           pre.taskName(),
+					State:          state,
+					Requires:       dep.Requires,
+				})
+			}
+		}
+		if len(waiting) == 0 {
+			continue
+		}
+		for _, d := range byConfig[dep.ConfigID] {
+			blocked[d.ID] = append(blocked[d.ID], waiting...)
+		}
+	}
+	return blocked
+}
+
+// taskName describes a task as "<sub-work> - <asset>" from its preloaded config
+func (d *DetailAssign) taskName() string {
+	if d.Config == nil {
+		return ""
+	}
+	name := ""
+	if d.Config.SubWork != nil {
+		name = d.Config.SubWork.Name
+	}
+	if d.Config.Asset != nil {
+		if name != "" {
+			name += " - "
+		}
+		name += d.Config.Asset.Name
+	}
+	return name
+}
+
+// DependencyCycle returns the configs along a cycle of deps, first config
+// repeated at the end, or nil when deps form a DAG
+func DependencyCycle(deps []ConfigDependency) []uuid.UUID {
+	next := make(map[uuid.UUID][]uuid.UUID)
+	for _, d := range deps {
+		next[d.ConfigID] = append(next[d.ConfigID], d.DependsOnID)
+	}
+	const (
+		unseen = iota
+		onPath
+		done
+	)
+	mark := make(map[uuid.UUID]int)
+	var path []uuid.UUID
+	var visit func(id uuid.UUID) []uuid.UUID
+	visit = func(id uuid.UUID) []uuid.UUID {
+		mark[id] = onPath
+		path = append(path, id)
+		for _, n := range next[id] {
+			switch mark[n] {
+			case onPath:
+				for i, p := range path {
+					if p == n {
+						return append(append([]uuid.UUID(nil), path[i:]...), n)
+					}
+				}
+			case unseen:
+				if cycle := visit(n); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		mark[id] = done
+		return nil
+	}
+	for _, d := range deps {
+		if mark[d.ConfigID] == unseen {
+			if cycle := visit(d.ConfigID); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+type ConfigDependencyRepository interface {
+	// FindByTemplate returns the dependencies between the configs of a template
+	FindByTemplate(templateID uuid.UUID) ([]ConfigDependency, error)
+	// ReplaceForTemplate swaps the template's dependencies for deps
+	ReplaceForTemplate(templateID uuid.UUID, deps []ConfigDependency) error
+}
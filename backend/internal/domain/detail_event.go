@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// DetailAssignEvent is one step in the history of a DetailAssign: who moved it
+// from which state to which, when, and with what evidence. It replaces the
+// parallel submitted_at / approval_at / id_person_* JSONB arrays as the source
+// of truth for timelines, stats and Lark sync.
+type DetailAssignEvent struct {
+	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	DetailAssignID uuid.UUID      `gorm:"column:id_detail_assign;type:uuid;not null;index" json:"id_detail_assign"`
+	AssignID       uuid.UUID      `gorm:"column:id_assign;type:uuid;not null;index" json:"id_assign"` // Denormalised for assign timelines
+	EventType      DetailEvent    `gorm:"column:event_type;type:varchar(20);not null" json:"event_type"`
+	FromState      DetailState    `gorm:"column:from_state;type:varchar(20)" json:"from_state"`
+	ToState        DetailState    `gorm:"column:to_state;type:varchar(20)" json:"to_state"`
+	ActorID        *uuid.UUID     `gorm:"column:id_actor;type:uuid" json:"id_actor"` // nil for backfilled events without a known actor
+	Actor          *User          `gorm:"foreignKey:ActorID;references:ID" json:"actor,omitempty"`
+	Note           string         `gorm:"column:note" json:"note"`
+	Evidence       datatypes.JSON `gorm:"column:evidence;type:jsonb" json:"evidence,omitempty"` // Image URLs at the time of a submit
+	CreatedAt      time.Time      `gorm:"index" json:"created_at"`
+}
+
+func (DetailAssignEvent) TableName() string {
+	return "detail_assign_events"
+}
+
+type DetailAssignEventRepository interface {
+	Create(event *DetailAssignEvent) error
+	// FindByDetail and FindByAssign return events oldest first, with their actors
+	FindByDetail(detailID uuid.UUID) ([]DetailAssignEvent, error)
+	FindByAssign(assignID uuid.UUID) ([]DetailAssignEvent, error)
+	// FindLatest returns the most recent event of the given type, or nil
+	FindLatest(detailID uuid.UUID, eventType DetailEvent) (*DetailAssignEvent, error)
+	// WithTx returns the repository running in tx
+	WithTx(tx *gorm.DB) DetailAssignEventRepository
+}
@@ -0,0 +1,142 @@
+package domain
+
+// DetailState is the workflow state of a DetailAssign
+type DetailState string
+
+const (
+	DetailStateDraft             DetailState = "draft"              // Nothing done yet
+	DetailStateInProgress        DetailState = "in_progress"        // Evidence saved as a draft
+	DetailStateSubmitted         DetailState = "submitted"          // Waiting for review
+	DetailStatePartiallyApproved DetailState = "partially_approved" // Some levels of the approval chain signed off
+	DetailStateApproved          DetailState = "approved"
+	DetailStateRejected          DetailState = "rejected"    // Sent back to the worker
+	DetailStateResubmitted       DetailState = "resubmitted" // Reworked after a rejection, waiting for review
+)
+
+// DetailEvent is an action that moves a DetailAssign between states
+type DetailEvent string
+
+const (
+	DetailEventSaveDraft    DetailEvent = "save_draft"
+	DetailEventSubmit       DetailEvent = "submit"
+	DetailEventApprove      DetailEvent = "approve"       // Final sign-off
+	DetailEventApproveLevel DetailEvent = "approve_level" // Sign-off of a non-final chain level
+	DetailEventReject       DetailEvent = "reject"
+	DetailEventReopen       DetailEvent = "reopen" // Withdraw a submission or rejection back to in_progress
+)
+
+// detailTransitions is the workflow: state -> event -> next state.
+// Anything not listed is refused; in particular approved work can only be
+// reopened by an explicit reject, never by a reset.
+var detailTransitions = map[DetailState]map[DetailEvent]DetailState{
+	DetailStateDraft: {
+		DetailEventSaveDraft: DetailStateInProgress,
+		DetailEventSubmit:    DetailStateSubmitted,
+	},
+	DetailStateInProgress: {
+		DetailEventSaveDraft: DetailStateInProgress,
+		DetailEventSubmit:    DetailStateSubmitted,
+	},
+	DetailStateSubmitted: {
+		DetailEventApprove:      DetailStateApproved,
+		DetailEventApproveLevel: DetailStatePartiallyApproved,
+		DetailEventReject:       DetailStateRejected,
+		DetailEventReopen:       DetailStateInProgress,
+	},
+	DetailStateRejected: {
+		DetailEventSaveDraft: DetailStateRejected,
+		DetailEventSubmit:    DetailStateResubmitted,
+		DetailEventReopen:    DetailStateInProgress,
+	},
+	DetailStateResubmitted: {
+		DetailEventApprove:      DetailStateApproved,
+		DetailEventApproveLevel: DetailStatePartiallyApproved,
+		DetailEventReject:       DetailStateRejected,
+		DetailEventReopen:       DetailStateInProgress,
+	},
+	DetailStatePartiallyApproved: {
+		DetailEventApprove:      DetailStateApproved,
+		DetailEventApproveLevel: DetailStatePartiallyApproved,
+		DetailEventReject:       DetailStateRejected,
+		DetailEventReopen:       DetailStateInProgress,
+	},
+	DetailStateApproved: {
+		DetailEventReject: DetailStateRejected,
+	},
+}
+
+// NextDetailState returns the state reached from s by event, or false when the
+// transition is not allowed. An empty state is treated as draft.
+func NextDetailState(s DetailState, event DetailEvent) (DetailState, bool) {
+	if s == "" {
+		s = DetailStateDraft
+	}
+	next, ok := detailTransitions[s][event]
+	return next, ok
+}
+
+// CurrentState returns the workflow state, deriving it from the legacy status
+// flags for rows written before the state column existed.
+func (d *DetailAssign) CurrentState() DetailState {
+	if d.State != "" {
+		return d.State
+	}
+	switch {
+	case d.StatusApprove == 1:
+		return DetailStateApproved
+	case d.StatusReject == 1 && d.StatusSubmit == 1:
+		return DetailStateResubmitted
+	case d.StatusReject == 1:
+		return DetailStateRejected
+	case d.StatusSubmit == 1:
+		return DetailStateSubmitted
+	case d.StatusWork == 1:
+		return DetailStateInProgress
+	}
+	return DetailStateDraft
+}
+
+// Transition applies event, updating State and the legacy status flags that
+// reports and the frontend still read. It returns false when the event is not
+// allowed in the current state, leaving the detail unchanged.
+func (d *DetailAssign) Transition(event DetailEvent) bool {
+	from := d.CurrentState()
+	next, ok := NextDetailState(from, event)
+	if !ok {
+		return false
+	}
+	d.State = next
+
+	// A new submission restarts the approval chain; so does leaving review
+	switch next {
+	case DetailStateSubmitted, DetailStateResubmitted:
+		d.ApprovalRound++
+		d.ApprovalLevel = 0
+	case DetailStateInProgress, DetailStateRejected:
+		d.ApprovalLevel = 0
+	}
+
+	switch next {
+	case DetailStateDraft:
+		d.StatusWork, d.StatusSubmit, d.StatusReject, d.StatusApprove = 0, 0, 0, 0
+	case DetailStateInProgress:
+		d.StatusWork, d.StatusSubmit, d.StatusReject, d.StatusApprove = 1, 0, 0, 0
+	case DetailStateSubmitted:
+		d.StatusWork, d.StatusSubmit, d.StatusReject, d.StatusApprove = 1, 1, 0, 0
+	case DetailStateResubmitted:
+		d.StatusWork, d.StatusSubmit, d.StatusReject, d.StatusApprove = 1, 1, 1, 0
+	case DetailStateRejected:
+		d.StatusWork, d.StatusSubmit, d.StatusReject, d.StatusApprove = 1, 0, 1, 0
+	case DetailStatePartiallyApproved:
+		// status_reject is kept so the final approval still counts as rework
+		d.StatusWork, d.StatusSubmit, d.StatusApprove = 1, 1, 0
+	case DetailStateApproved:
+		// status_reject stays 1 when approving reworked items ("Điều chỉnh xong")
+		rework := 0
+		if from == DetailStateResubmitted || (from == DetailStatePartiallyApproved && d.StatusReject == 1) {
+			rework = 1
+		}
+		d.StatusWork, d.StatusSubmit, d.StatusReject, d.StatusApprove = 1, 1, rework, 1
+	}
+	return true
+}
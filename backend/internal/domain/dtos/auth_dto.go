@@ -9,12 +9,56 @@ type RegisterRequest struct {
 
 // LoginRequest represents the data needed for login
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"`
+	DeviceID   string `json:"device_id"`   // Stable client identifier, used to revoke a device
+	DeviceName string `json:"device_name"` // Human-readable label, e.g. "Pixel 7"
+}
+
+// RefreshRequest exchanges a refresh token for a new token pair
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// ForgotPasswordRequest asks for a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with an emailed reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// MFAChallengeRequest starts authenticator enrolment during login
+type MFAChallengeRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+}
+
+// MFAVerifyRequest completes a two-step login with a TOTP or recovery code
+type MFAVerifyRequest struct {
+	Challenge  string `json:"challenge" binding:"required"`
+	Code       string `json:"code" binding:"required"`
+	DeviceID   string `json:"device_id"`
+	DeviceName string `json:"device_name"`
+}
+
+// MFACodeRequest confirms a self-service 2FA action with a current code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFADisableRequest turns two-factor login off
+type MFADisableRequest struct {
 	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
 }
 
 // AuthResponse represents the success response for login/register
 type AuthResponse struct {
-	Token   string `json:"token"`
-	Message string `json:"message,omitempty"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	Message      string `json:"message,omitempty"`
 }
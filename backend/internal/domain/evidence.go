@@ -0,0 +1,216 @@
+package domain
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gorm.io/datatypes"
+)
+
+// Media kinds an evidence slot can accept
+const (
+	MediaImage = "image"
+	MediaVideo = "video"
+)
+
+// LegacySlotKey names the implied slot of configs that only set ImageCount
+const LegacySlotKey = "default"
+
+// EvidenceSlot is a named group of photos/videos a task needs, e.g.
+// "Before cleaning", "After cleaning", "Nameplate".
+type EvidenceSlot struct {
+	Key        string   `json:"key"` // Stable ID uploads refer to
+	Name       string   `json:"name"`
+	Min        int      `json:"min"`         // Required on submit
+	Max        int      `json:"max"`         // 0 = unlimited
+	MediaTypes []string `json:"media_types"` // MediaImage / MediaVideo; empty = both
+}
+
+// AllowsMedia reports whether a file of the given MIME type fits the slot.
+func (s EvidenceSlot) AllowsMedia(contentType string) bool {
+	if len(s.MediaTypes) == 0 {
+		return true
+	}
+	kind := MediaKind(contentType)
+	for _, t := range s.MediaTypes {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// MediaKind maps a MIME type to MediaImage or MediaVideo ("" for anything else).
+func MediaKind(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return MediaImage
+	case strings.HasPrefix(contentType, "video/"):
+		return MediaVideo
+	}
+	return ""
+}
+
+// Slots returns the evidence slots of a config. Configs without named slots
+// that set StatusSetImageCount get one implied slot requiring ImageCount photos.
+func (c *Config) Slots() []EvidenceSlot {
+	var slots []EvidenceSlot
+	if len(c.EvidenceSlots) > 0 {
+		_ = json.Unmarshal(c.EvidenceSlots, &slots)
+	}
+	if len(slots) == 0 && c.StatusSetImageCount && c.ImageCount > 0 {
+		slots = []EvidenceSlot{{Key: LegacySlotKey, Name: "Ảnh", Min: c.ImageCount}}
+	}
+	return slots
+}
+
+// HasNamedSlots reports whether uploads must name a slot.
+func (c *Config) HasNamedSlots() bool {
+	slots := c.Slots()
+	return len(slots) > 0 && slots[0].Key != LegacySlotKey
+}
+
+// FindSlot returns the config's slot with the given key.
+func (c *Config) FindSlot(key string) (EvidenceSlot, bool) {
+	for _, s := range c.Slots() {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return EvidenceSlot{}, false
+}
+
+// dataURLs returns the evidence URLs of a task.
+func (d *DetailAssign) dataURLs() []string {
+	var urls []string
+	if len(d.Data) > 0 && string(d.Data) != "null" {
+		_ = json.Unmarshal(d.Data, &urls)
+	}
+	return urls
+}
+
+// rawSlots decodes SlotEvidence, never returning nil.
+func (d *DetailAssign) rawSlots() map[string][]string {
+	var raw map[string][]string
+	if len(d.SlotEvidence) > 0 {
+		_ = json.Unmarshal(d.SlotEvidence, &raw)
+	}
+	if raw == nil {
+		raw = make(map[string][]string)
+	}
+	return raw
+}
+
+func (d *DetailAssign) setSlots(raw map[string][]string) {
+	b, _ := json.Marshal(raw)
+	d.SlotEvidence = datatypes.JSON(b)
+}
+
+// SlotURLs returns, per slot key, the evidence URLs that are also in Data,
+// i.e. uploads the worker kept.
+func (d *DetailAssign) SlotURLs() map[string][]string {
+	raw := d.rawSlots()
+	present := make(map[string]bool)
+	for _, u := range d.dataURLs() {
+		present[u] = true
+	}
+	out := make(map[string][]string, len(raw))
+	for key, urls := range raw {
+		for _, u := range urls {
+			if present[u] {
+				out[key] = append(out[key], u)
+			}
+		}
+	}
+	return out
+}
+
+// AttachToSlot records that url was uploaded for the slot.
+func (d *DetailAssign) AttachToSlot(key, url string) {
+	raw := d.rawSlots()
+	for _, u := range raw[key] {
+		if u == url {
+			return
+		}
+	}
+	raw[key] = append(raw[key], url)
+	d.setSlots(raw)
+}
+
+// DetachURL removes a deleted photo from its slot.
+func (d *DetailAssign) DetachURL(url string) {
+	raw := d.rawSlots()
+	for key, urls := range raw {
+		kept := urls[:0]
+		for _, u := range urls {
+			if u != url {
+				kept = append(kept, u)
+			}
+		}
+		raw[key] = kept
+	}
+	d.setSlots(raw)
+}
+
+// MissingSlot is a slot without enough evidence for a submit.
+type MissingSlot struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+	Have int    `json:"have"`
+	Min  int    `json:"min"`
+}
+
+// MissingEvidence lists the slots of cfg the task has too little evidence for.
+// The implied slot of legacy configs counts every photo of the task.
+func (d *DetailAssign) MissingEvidence(cfg *Config) []MissingSlot {
+	if cfg == nil {
+		return nil
+	}
+	bySlot := d.SlotURLs()
+	var missing []MissingSlot
+	for _, s := range cfg.Slots() {
+		have := len(bySlot[s.Key])
+		if s.Key == LegacySlotKey {
+			have = len(d.dataURLs())
+		}
+		if have < s.Min {
+			missing = append(missing, MissingSlot{Key: s.Key, Name: s.Name, Have: have, Min: s.Min})
+		}
+	}
+	return missing
+}
+
+// EvidenceGroup is the evidence of one slot, for reports.
+type EvidenceGroup struct {
+	Key  string   `json:"key"`
+	Name string   `json:"name"` // "" for photos outside any slot
+	URLs []string `json:"urls"`
+}
+
+// GroupEvidence returns the task's evidence grouped by the slots of cfg, in
+// slot order, followed by any photos not attached to a slot.
+func (d *DetailAssign) GroupEvidence(cfg *Config) []EvidenceGroup {
+	all := d.dataURLs()
+	var groups []EvidenceGroup
+	placed := make(map[string]bool)
+	if cfg != nil && cfg.HasNamedSlots() {
+		bySlot := d.SlotURLs()
+		for _, s := range cfg.Slots() {
+			urls := bySlot[s.Key]
+			for _, u := range urls {
+				placed[u] = true
+			}
+			groups = append(groups, EvidenceGroup{Key: s.Key, Name: s.Name, URLs: append([]string{}, urls...)})
+		}
+	}
+	var rest []string
+	for _, u := range all {
+		if !placed[u] {
+			rest = append(rest, u)
+		}
+	}
+	if len(rest) > 0 {
+		groups = append(groups, EvidenceGroup{URLs: rest})
+	}
+	return groups
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// FormFieldType is the kind of answer a checklist field takes
+type FormFieldType string
+
+const (
+	FormFieldCheck       FormFieldType = "check"        // Yes/no
+	FormFieldSelect      FormFieldType = "select"       // One of Options
+	FormFieldMultiSelect FormFieldType = "multi_select" // Any of Options
+	FormFieldText        FormFieldType = "text"         // Free text, optionally matching Pattern
+)
+
+// FormOption is a choice of a select field, e.g. "Soiling level: light"
+type FormOption struct {
+	Value string `json:"value"` // Stored in answers
+	Label string `json:"label"`
+}
+
+// FormField is one question of a checklist form
+type FormField struct {
+	Key      string        `json:"key"` // Stable ID answers refer to
+	Label    string        `json:"label"`
+	Type     FormFieldType `json:"type"`
+	Required bool          `json:"required"` // Needed on submit
+	Options  []FormOption  `json:"options,omitempty"`
+	Pattern  string        `json:"pattern,omitempty"` // Regex the whole text answer must match
+	Help     string        `json:"help,omitempty"`
+}
+
+// OptionLabel returns the label of the option with the given value, or the
+// value itself when the option is unknown
+func (f FormField) OptionLabel(value string) string {
+	for _, o := range f.Options {
+		if o.Value == value {
+			return o.Label
+		}
+	}
+	return value
+}
+
+// FormSchema is one version of the checklist form of a sub-work, or of a
+// config overriding it for one asset. Versions are never edited: a change
+// creates the next version, and tasks keep the version they were filled against.
+type FormSchema struct {
+	ID          uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SubWorkID   *uuid.UUID     `gorm:"column:id_sub_work;type:uuid;index" json:"id_sub_work"`
+	ConfigID    *uuid.UUID     `gorm:"column:id_config;type:uuid;index" json:"id_config"`
+	Version     int            `gorm:"column:version;not null" json:"version"`
+	Fields      datatypes.JSON `gorm:"column:fields;type:jsonb;not null;default:'[]'" json:"fields"` // []FormField
+	CreatedByID *uuid.UUID     `gorm:"column:id_person_created;type:uuid" json:"id_person_created"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+func (FormSchema) TableName() string {
+	return "form_schemas"
+}
+
+// FormFields decodes the fields of the form
+func (f *FormSchema) FormFields() []FormField {
+	var fields []FormField
+	if len(f.Fields) > 0 {
+		_ = json.Unmarshal(f.Fields, &fields)
+	}
+	return fields
+}
+
+// CurrentForm picks the form a task of cfg is filled against from the latest
+// versions in latest: the config's own if it has fields, else its sub-work's.
+// An empty config version thus falls back to the sub-work, as measurement
+// fields do. Returns nil when neither has a form.
+func CurrentForm(latest []FormSchema, cfg *Config) *FormSchema {
+	if cfg == nil {
+		return nil
+	}
+	var bySubWork *FormSchema
+	for i := range latest {
+		f := &latest[i]
+		if f.ConfigID != nil && *f.ConfigID == cfg.ID && len(f.FormFields()) > 0 {
+			return f
+		}
+		if f.SubWorkID != nil && *f.SubWorkID == cfg.SubWorkID && len(f.FormFields()) > 0 {
+			bySubWork = f
+		}
+	}
+	return bySubWork
+}
+
+// Answers decodes the task's form answers (field key -> answer)
+func (d *DetailAssign) Answers() map[string]json.RawMessage {
+	answers := make(map[string]json.RawMessage)
+	if len(d.FormAnswers) > 0 {
+		_ = json.Unmarshal(d.FormAnswers, &answers)
+	}
+	return answers
+}
+
+type FormSchemaRepository interface {
+	// Create stores schema as the next version of its sub-work's or config's form
+	Create(schema *FormSchema) error
+	// FindByID returns the version, or nil
+	FindByID(id uuid.UUID) (*FormSchema, error)
+	// FindVersions returns every version of a sub-work's or config's form, newest first
+	FindVersions(subWorkID, configID *uuid.UUID) ([]FormSchema, error)
+	// FindLatest returns the latest version of the forms of the given configs and sub-works
+	FindLatest(configIDs, subWorkIDs []uuid.UUID) ([]FormSchema, error)
+}
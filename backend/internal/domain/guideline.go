@@ -25,6 +25,6 @@ func (GuideLine) TableName() string {
 
 // GuideLineRepository defines the interface for guideline data access
 type GuideLineRepository interface {
-	FindBySubWorkID(subWorkID uuid.UUID) (*GuideLine, error)
+	FindBySubWorkID(subWorkID uuid.UUID, scope *VisibilityScope) (*GuideLine, error)
 	Save(g *GuideLine) error
 }
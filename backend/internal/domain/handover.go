@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HandoverKind says how a task changed hands
+type HandoverKind string
+
+const (
+	HandoverReassign HandoverKind = "reassign" // Moved by a manager
+	HandoverShift    HandoverKind = "handover" // Passed on by its holder, e.g. when rotating to another site
+)
+
+// DetailHandover records a task changing owner. Together they are the
+// ownership history of a task: who held it from when to when.
+type DetailHandover struct {
+	ID             uuid.UUID    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	DetailAssignID uuid.UUID    `gorm:"column:id_detail_assign;type:uuid;not null;index" json:"id_detail_assign"`
+	AssignID       uuid.UUID    `gorm:"column:id_assign;type:uuid;not null;index" json:"id_assign"`
+	FromUserID     *uuid.UUID   `gorm:"column:id_from_user;type:uuid" json:"id_from_user"` // nil: the task was shared by the assign's users
+	FromUser       *User        `gorm:"foreignKey:FromUserID;references:ID" json:"from_user,omitempty"`
+	ToUserID       uuid.UUID    `gorm:"column:id_to_user;type:uuid;not null" json:"id_to_user"`
+	ToUser         *User        `gorm:"foreignKey:ToUserID;references:ID" json:"to_user,omitempty"`
+	Kind           HandoverKind `gorm:"column:kind;type:varchar(20);not null" json:"kind"`
+	Note           string       `gorm:"column:note" json:"note"` // Where the work stands, for the next holder
+	ActorID        *uuid.UUID   `gorm:"column:id_actor;type:uuid" json:"id_actor"`
+	Actor          *User        `gorm:"foreignKey:ActorID;references:ID" json:"actor,omitempty"`
+	CreatedAt      time.Time    `gorm:"index" json:"created_at"`
+}
+
+func (DetailHandover) TableName() string {
+	return "detail_assign_handovers"
+}
+
+// Users returns the users of the assign (UserIDs), skipping malformed entries
+func (a *Assign) Users() []uuid.UUID {
+	var raw []string
+	_ = json.Unmarshal(a.UserIDs, &raw)
+	users := make([]uuid.UUID, 0, len(raw))
+	for _, s := range raw {
+		if id, err := uuid.Parse(s); err == nil {
+			users = append(users, id)
+		}
+	}
+	return users
+}
+
+// Holders returns who works the task: its owner, or every user of the assign
+// when it has none. An owner who was since removed from the assign's users
+// no longer holds the task, which falls back to the whole assign.
+func (d *DetailAssign) Holders(assignUsers []uuid.UUID) []uuid.UUID {
+	if d.OwnerID != nil {
+		for _, u := range assignUsers {
+			if u == *d.OwnerID {
+				return []uuid.UUID{u}
+			}
+		}
+	}
+	return assignUsers
+}
+
+type DetailHandoverRepository interface {
+	// FindByDetail and FindByAssign return handovers oldest first, with their users
+	FindByDetail(detailID uuid.UUID) ([]DetailHandover, error)
+	FindByAssign(assignID uuid.UUID) ([]DetailHandover, error)
+	// Apply saves the details' new owners (versioned, as DetailAssignRepository.Update),
+	// the assign's users and the handover records in one transaction
+	Apply(assign *Assign, details []*DetailAssign, handovers []DetailHandover) error
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// MaintenancePlan is recurring preventive work on a project, e.g. monthly
+// module cleaning or yearly IR thermography. The scheduler turns each
+// occurrence of Recurrence into an Assign (with its DetailAssigns) LeadDays
+// before it is due.
+type MaintenancePlan struct {
+	ID             uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name           string         `gorm:"column:name;not null" json:"name"`
+	ProjectID      uuid.UUID      `gorm:"column:id_project;type:uuid;not null;index" json:"id_project"`
+	Project        *Project       `gorm:"foreignKey:ProjectID;references:ID" json:"project,omitempty"`
+	ModelProjectID *uuid.UUID     `gorm:"column:id_model_project;type:uuid" json:"id_model_project"`
+	TemplateID     *uuid.UUID     `gorm:"column:id_template;type:uuid" json:"id_template"` // Work to generate; else ConfigIDs
+	Template       *Template      `gorm:"foreignKey:TemplateID;references:ID" json:"template,omitempty"`
+	ConfigIDs      datatypes.JSON `gorm:"column:id_config;type:jsonb;default:'[]'" json:"id_config"`
+	// "RRULE:FREQ=MONTHLY;INTERVAL=3" (FREQ, INTERVAL and COUNT, counted from
+	// StartDate) or a 5-field cron expression such as "0 7 1 * *"
+	Recurrence   string         `gorm:"column:recurrence;not null" json:"recurrence"`
+	StartDate    time.Time      `gorm:"column:start_date;not null" json:"start_date"` // First possible occurrence
+	EndDate      *time.Time     `gorm:"column:end_date" json:"end_date"`              // nil = open-ended
+	LeadDays     int            `gorm:"column:lead_days;default:0" json:"lead_days"`  // Generate the assign this long before it is due
+	DurationDays int            `gorm:"column:duration_days;default:1" json:"duration_days"`
+	UserIDs      datatypes.JSON `gorm:"column:id_user;type:jsonb;default:'[]'" json:"id_user"` // Default team of the assigns
+	NoteAssign   string         `gorm:"column:note_assign" json:"note_assign"`
+	Paused       bool           `gorm:"column:paused;default:false" json:"paused"`
+
+	PersonCreatedID *uuid.UUID     `gorm:"column:id_person_created;type:uuid" json:"id_person_created,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
+
+func (MaintenancePlan) TableName() string {
+	return "maintenance_plans"
+}
+
+// MaintenanceOccurrence records what happened to one occurrence of a plan:
+// the assign generated for it, or that it was skipped. (plan, due date) is
+// unique, which is what makes generation idempotent.
+type MaintenanceOccurrence struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	PlanID    uuid.UUID  `gorm:"column:id_plan;type:uuid;not null;uniqueIndex:idx_maintenance_occurrences_plan_due" json:"id_plan"`
+	DueAt     time.Time  `gorm:"column:due_at;not null;uniqueIndex:idx_maintenance_occurrences_plan_due" json:"due_at"`
+	AssignID  *uuid.UUID `gorm:"column:id_assign;type:uuid" json:"id_assign"` // nil when skipped
+	Skipped   bool       `gorm:"column:skipped;default:false" json:"skipped"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (MaintenanceOccurrence) TableName() string {
+	return "maintenance_occurrences"
+}
+
+type MaintenancePlanRepository interface {
+	Create(plan *MaintenancePlan) error
+	// FindAll returns the plans of a project, or every plan when projectID is
+	// nil, among those of the projects the scope may see
+	FindAll(projectID *uuid.UUID, scope *VisibilityScope) ([]MaintenancePlan, error)
+	// FindByID returns the plan, or nil when it is missing or hidden from the scope
+	FindByID(id uuid.UUID, scope *VisibilityScope) (*MaintenancePlan, error)
+	FindActive() ([]MaintenancePlan, error)
+	Update(plan *MaintenancePlan) error
+	Delete(id uuid.UUID) error
+
+	// FindOccurrences returns the recorded occurrences of a plan due in [from, to]
+	FindOccurrences(planID uuid.UUID, from, to time.Time) ([]MaintenanceOccurrence, error)
+	// Skip records an occurrence as skipped; false if it was already generated or skipped
+	Skip(planID uuid.UUID, dueAt time.Time) (bool, error)
+	// Materialise stores assign and its details for an occurrence in one
+	// transaction; false (and nothing stored) if the occurrence is already recorded
+	Materialise(planID uuid.UUID, dueAt time.Time, assign *Assign, details []DetailAssign) (bool, error)
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MeasurementField is a typed reading a task asks for, e.g. string Voc in V
+// or insulation resistance in MΩ. Sub-works declare defaults; a Config may
+// override them for one asset.
+type MeasurementField struct {
+	Key       string   `json:"key"`
+	Label     string   `json:"label"`
+	Unit      string   `json:"unit"`
+	Min       *float64 `json:"min,omitempty"` // Readings outside [Min, Max] are flagged, not refused
+	Max       *float64 `json:"max,omitempty"`
+	Required  bool     `json:"required"`  // Needed on submit
+	Precision int      `json:"precision"` // Decimals kept
+}
+
+// Round rounds v to the field's precision.
+func (f MeasurementField) Round(v float64) float64 {
+	p := math.Pow(10, float64(f.Precision))
+	return math.Round(v*p) / p
+}
+
+// InRange reports whether v lies within the field's thresholds.
+func (f MeasurementField) InRange(v float64) bool {
+	return (f.Min == nil || v >= *f.Min) && (f.Max == nil || v <= *f.Max)
+}
+
+// Measurements returns the measurement fields of a config: its own if it
+// declares any, else those of its sub-work.
+func (c *Config) Measurements() []MeasurementField {
+	var fields []MeasurementField
+	if len(c.MeasurementFields) > 0 {
+		_ = json.Unmarshal(c.MeasurementFields, &fields)
+	}
+	if len(fields) == 0 && c.SubWork != nil && len(c.SubWork.MeasurementFields) > 0 {
+		_ = json.Unmarshal(c.SubWork.MeasurementFields, &fields)
+	}
+	return fields
+}
+
+// DetailMeasurement is one submitted reading. Label, unit and thresholds are
+// copied from the field so later config edits don't rewrite history.
+type DetailMeasurement struct {
+	ID             uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	DetailAssignID uuid.UUID `gorm:"column:id_detail_assign;type:uuid;not null;index" json:"id_detail_assign"`
+	FieldKey       string    `gorm:"column:field_key;not null" json:"field_key"`
+	Position       int       `gorm:"column:position" json:"position"` // Order of the field in its config
+	Label          string    `gorm:"column:label" json:"label"`
+	Unit           string    `gorm:"column:unit" json:"unit"`
+	Value          float64   `gorm:"column:value;type:double precision" json:"value"`
+	Min            *float64  `gorm:"column:min_value;type:double precision" json:"min,omitempty"`
+	Max            *float64  `gorm:"column:max_value;type:double precision" json:"max,omitempty"`
+	OutOfRange     bool      `gorm:"column:out_of_range;default:false;index" json:"out_of_range"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (DetailMeasurement) TableName() string {
+	return "detail_measurements"
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryCode is a single-use fallback for a lost authenticator.
+// Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"column:id_user;type:uuid;not null;index" json:"id_user"`
+	CodeHash  string     `gorm:"column:code_hash" json:"-"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+type RecoveryCodeRepository interface {
+	// Replace drops every code of the user and stores the new set (nil just drops them)
+	Replace(userID uuid.UUID, codes []RecoveryCode) error
+	// Consume marks an unused code as used; it reports false if no such code exists
+	Consume(userID uuid.UUID, codeHash string) (bool, error)
+	CountUnused(userID uuid.UUID) (int64, error)
+}
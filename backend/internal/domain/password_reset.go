@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordResetToken is a single-use token emailed by the forgot-password flow.
+// Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID      uuid.UUID  `gorm:"column:id_user;type:uuid;not null;index" json:"id_user"`
+	TokenHash   string     `gorm:"column:token_hash;uniqueIndex" json:"-"`
+	RequestedIP string     `gorm:"column:requested_ip" json:"requested_ip"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	UsedAt      *time.Time `gorm:"column:used_at" json:"used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+type PasswordResetRepository interface {
+	Create(token *PasswordResetToken) error
+	FindByTokenHash(hash string) (*PasswordResetToken, error)
+	// MarkUsed consumes the token; it reports false if it was already used
+	MarkUsed(id uuid.UUID) (bool, error)
+	// InvalidateForUser consumes every outstanding token of the user
+	InvalidateForUser(userID uuid.UUID) error
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Permission codes checked by the backend.
+// The `permissions` table is seeded with exactly these codes; roles are granted
+// permissions through `role_permissions`, so new roles need no code change.
+const (
+	PermUserManage                = "user.manage"
+	PermRoleManage                = "role.manage"
+	PermTeamManage                = "team.manage"
+	PermTemplateManage            = "template.manage"
+	PermProjectManage             = "project.manage"
+	PermProjectExport             = "project.export"
+	PermProjectViewAll            = "project.view_all"
+	PermAssetManage               = "asset.manage"
+	PermAssignManage              = "assign.manage"
+	PermAssignApprove             = "assign.approve"
+	PermTaskExecute               = "task.execute"
+	PermAttendanceCheckin         = "attendance.checkin"
+	PermAttendanceApproveCheckout = "attendance.approve_checkout"
+	PermAttendanceViewAll         = "attendance.view_all"
+	PermStatsView                 = "stats.view"
+	PermAdminTables               = "admin.tables"
+	PermMediaManage               = "media.manage"
+	PermLarkPush                  = "lark.push"
+	PermShareManage               = "share.manage"
+	PermAuditView                 = "audit.view"
+	PermServiceAccountManage      = "service_account.manage"
+)
+
+// AllPermissionCodes lists every code in the catalog.
+// Used to resolve the built-in admin role, which always holds every permission.
+var AllPermissionCodes = []string{
+	PermUserManage,
+	PermRoleManage,
+	PermTeamManage,
+	PermTemplateManage,
+	PermProjectManage,
+	PermProjectExport,
+	PermProjectViewAll,
+	PermAssetManage,
+	PermAssignManage,
+	PermAssignApprove,
+	PermTaskExecute,
+	PermAttendanceCheckin,
+	PermAttendanceApproveCheckout,
+	PermAttendanceViewAll,
+	PermStatsView,
+	PermAdminTables,
+	PermMediaManage,
+	PermLarkPush,
+	PermShareManage,
+	PermAuditView,
+	PermServiceAccountManage,
+}
+
+// Permission is an entry of the permission catalog
+type Permission struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Code        string    `gorm:"uniqueIndex;not null" json:"code"`
+	Description string    `gorm:"column:description" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RolePermission grants a permission to a role
+type RolePermission struct {
+	RoleID       uuid.UUID `gorm:"column:id_role;type:uuid;primaryKey" json:"id_role"`
+	PermissionID uuid.UUID `gorm:"column:id_permission;type:uuid;primaryKey" json:"id_permission"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// PermissionRepository manages the permission catalog and role grants
+type PermissionRepository interface {
+	FindAll() ([]Permission, error)
+	FindByCodes(codes []string) ([]Permission, error)
+	FindByRoleID(roleID uuid.UUID) ([]Permission, error)
+	FindCodesByRoleName(roleName string) ([]string, error)
+	ReplaceRolePermissions(roleID uuid.UUID, permissionIDs []uuid.UUID) error
+}
@@ -31,8 +31,8 @@ type Project struct {
 
 type ProjectRepository interface {
 	Create(project *Project) error
-	FindAll() ([]Project, error)
-	FindByID(id uuid.UUID) (*Project, error)
+	FindAll(scope *VisibilityScope) ([]Project, error)
+	FindByID(id uuid.UUID, scope *VisibilityScope) (*Project, error)
 	Update(project *Project) error
 	Delete(id uuid.UUID) error
 	// Trash-related
@@ -43,6 +43,23 @@ type ProjectRepository interface {
 	BulkPermanentDelete(ids []uuid.UUID) error
 }
 
+// ProjectMember grants a user visibility of a project
+type ProjectMember struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ProjectID uuid.UUID      `gorm:"column:id_project;type:uuid;not null" json:"id_project"`
+	UserID    uuid.UUID      `gorm:"column:id_user;type:uuid;not null" json:"id_user"`
+	User      *User          `gorm:"foreignKey:UserID;references:ID" json:"user,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// ProjectMemberRepository manages project membership
+type ProjectMemberRepository interface {
+	FindByProjectID(projectID uuid.UUID) ([]ProjectMember, error)
+	Add(projectID uuid.UUID, userIDs []uuid.UUID) error
+	Remove(projectID, userID uuid.UUID) error
+}
+
 type OwnerRepository interface {
 	Create(owner *Owner) error
 	FindAll() ([]Owner, error)
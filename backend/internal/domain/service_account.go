@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Principal types recorded for a request (AuthMiddleware sets "principal_type").
+// Service accounts also use PrincipalServiceAccount as their "role", which matches
+// no built-in role, so they are admitted only by the permission scopes of their key.
+const (
+	PrincipalUser           = "user"
+	PrincipalServiceAccount = "service_account"
+)
+
+// ServiceAccount is a non-human caller (SCADA, BI scripts) that authenticates with API keys
+type ServiceAccount struct {
+	ID              uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name            string         `gorm:"column:name;not null" json:"name"`
+	Description     string         `gorm:"column:description" json:"description"`
+	Disabled        bool           `gorm:"column:disabled;default:false" json:"disabled"` // Rejects every key of the account
+	PersonCreatedID *uuid.UUID     `gorm:"column:id_person_created;type:uuid" json:"id_person_created,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	Keys []APIKey `gorm:"foreignKey:ServiceAccountID" json:"keys,omitempty"`
+}
+
+func (ServiceAccount) TableName() string {
+	return "service_accounts"
+}
+
+// APIKey is a credential of a service account. Only the SHA-256 hash of the key
+// is stored; Prefix is kept in clear so a key can be recognised in listings.
+type APIKey struct {
+	ID               uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ServiceAccountID uuid.UUID      `gorm:"column:id_service_account;type:uuid;not null;index" json:"id_service_account"`
+	Name             string         `gorm:"column:name" json:"name"`
+	Prefix           string         `gorm:"column:key_prefix" json:"prefix"`
+	KeyHash          string         `gorm:"column:key_hash;uniqueIndex" json:"-"`
+	Scopes           datatypes.JSON `gorm:"column:scopes;type:jsonb;default:'[]'" json:"scopes"` // Permission codes granted to the key
+	RateLimit        int            `gorm:"column:rate_limit_per_minute" json:"rate_limit_per_minute"`
+	ExpiresAt        time.Time      `gorm:"column:expires_at" json:"expires_at"`
+	LastUsedAt       *time.Time     `gorm:"column:last_used_at" json:"last_used_at"`
+	LastUsedIP       string         `gorm:"column:last_used_ip" json:"last_used_ip"`
+	RevokedAt        *time.Time     `gorm:"column:revoked_at" json:"revoked_at"`
+	ReplacedByID     *uuid.UUID     `gorm:"column:id_replaced_by;type:uuid" json:"id_replaced_by,omitempty"` // Set when rotated
+	PersonCreatedID  *uuid.UUID     `gorm:"column:id_person_created;type:uuid" json:"id_person_created,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+
+	ServiceAccount *ServiceAccount `gorm:"foreignKey:ServiceAccountID" json:"-"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeCodes decodes Scopes; a malformed value grants nothing
+func (k *APIKey) ScopeCodes() []string {
+	var codes []string
+	if err := json.Unmarshal(k.Scopes, &codes); err != nil {
+		return nil
+	}
+	return codes
+}
+
+// Usable reports whether the key may authenticate at now
+func (k *APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && now.Before(k.ExpiresAt)
+}
+
+type ServiceAccountRepository interface {
+	Create(account *ServiceAccount) error
+	// FindAll returns every account with its keys
+	FindAll() ([]ServiceAccount, error)
+	FindByID(id uuid.UUID) (*ServiceAccount, error)
+	Update(account *ServiceAccount) error
+	// Delete soft-deletes the account and revokes its keys
+	Delete(id uuid.UUID) error
+
+	CreateKey(key *APIKey) error
+	FindKey(id uuid.UUID) (*APIKey, error)
+	// FindKeyByHash returns the key with its (non-deleted) ServiceAccount loaded
+	FindKeyByHash(hash string) (*APIKey, error)
+	UpdateKey(key *APIKey) error
+	// TouchKey records the last use of a key
+	TouchKey(id uuid.UUID, at time.Time, ip string) error
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceInfo identifies the client a session was opened from
+type DeviceInfo struct {
+	DeviceID   string
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+// UserSession is a server-side login session backing one refresh token chain.
+// Access tokens carry the session ID ("sid"); revoking the session invalidates them.
+type UserSession struct {
+	ID                uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID            uuid.UUID  `gorm:"column:id_user;type:uuid;not null;index" json:"id_user"`
+	DeviceID          string     `gorm:"column:device_id" json:"device_id"`
+	DeviceName        string     `gorm:"column:device_name" json:"device_name"`
+	UserAgent         string     `gorm:"column:user_agent" json:"user_agent"`
+	IPAddress         string     `gorm:"column:ip_address" json:"ip_address"`
+	RefreshTokenHash  string     `gorm:"column:refresh_token_hash;uniqueIndex" json:"-"`
+	PreviousTokenHash string     `gorm:"column:previous_token_hash;index" json:"-"`
+	LastUsedAt        time.Time  `gorm:"column:last_used_at" json:"last_used_at"`
+	ExpiresAt         time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	RevokedAt         *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+	RevokedReason     string     `gorm:"column:revoked_reason" json:"revoked_reason,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// IsActive reports whether the session is neither revoked nor expired at the given time
+func (s *UserSession) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Reasons recorded when a session is revoked
+const (
+	RevokeReasonLogout        = "logout"
+	RevokeReasonAdmin         = "revoked_by_admin"
+	RevokeReasonUserDeleted   = "user_deleted"
+	RevokeReasonRoleChanged   = "role_changed"
+	RevokeReasonTokenReuse    = "refresh_token_reuse"
+	RevokeReasonPasswordReset = "password_reset"
+)
+
+type SessionRepository interface {
+	Create(session *UserSession) error
+	FindByID(id uuid.UUID) (*UserSession, error)
+	// FindByTokenHash matches either the current or the previous refresh token hash
+	FindByTokenHash(hash string) (*UserSession, error)
+	Update(session *UserSession) error
+	FindActiveByUserID(userID uuid.UUID) ([]UserSession, error)
+	Revoke(ids []uuid.UUID, reason string) error
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// Resources a share link can grant read access to
+const (
+	// ShareAssignReport opens /public/report/:id and the assign's attendance (ResourceID = assign ID)
+	ShareAssignReport = "assign_report"
+	// ShareGenericReport opens /public/generic-report/:id plus the assign it snapshots (ResourceID = report ID)
+	ShareGenericReport = "generic_report"
+	// ShareProjectExport opens /public/export/:id (ResourceID = project ID)
+	ShareProjectExport = "project_export"
+	// ShareMediaFolder opens /public/media/library under a MinIO prefix (ResourceID = prefix)
+	ShareMediaFolder = "media_folder"
+	// ShareUserCalendar opens /public/calendar/:id, the iCalendar feed of a user's assigns (ResourceID = user ID)
+	ShareUserCalendar = "user_calendar"
+)
+
+// Filter keys narrowing a share link below its resource
+const (
+	ShareFilterAsset   = "asset"   // Only details of this asset
+	ShareFilterSubWork = "sub"     // Only details of this sub-work
+	ShareFilterMedia   = "filters" // Comma-separated path fragments every media key must contain
+	ShareFilterAssign  = "assign"  // Assign snapshotted by a generic report (set on issue)
+)
+
+// ShareLink records an issued share token so it can be listed and revoked.
+// The token itself is HMAC-signed and carries the same scope and expiry.
+type ShareLink struct {
+	ID              uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ResourceType    string         `gorm:"column:resource_type;not null;index:idx_share_links_resource" json:"resource_type"`
+	ResourceID      string         `gorm:"column:resource_id;not null;index:idx_share_links_resource" json:"resource_id"`
+	Filters         datatypes.JSON `gorm:"column:filters;type:jsonb;default:'{}'" json:"filters"`
+	ExpiresAt       time.Time      `gorm:"column:expires_at" json:"expires_at"`
+	RevokedAt       *time.Time     `gorm:"column:revoked_at" json:"revoked_at"`
+	PersonCreatedID *uuid.UUID     `gorm:"column:id_person_created;type:uuid" json:"id_person_created,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+func (ShareLink) TableName() string {
+	return "share_links"
+}
+
+// ShareClaims is the signed payload of a share token
+type ShareClaims struct {
+	LinkID       uuid.UUID         `json:"jti"`
+	ResourceType string            `json:"typ"`
+	ResourceID   string            `json:"rid"`
+	Filters      map[string]string `json:"flt,omitempty"`
+	ExpiresAt    int64             `json:"exp"`
+}
+
+// Grants reports whether the claims cover the given resource
+func (c *ShareClaims) Grants(resourceType, resourceID string) bool {
+	return c.ResourceType == resourceType && c.ResourceID == resourceID
+}
+
+// GrantsAssign reports whether the claims open the assign's report data:
+// either a link to the assign itself or to a generic report built from it.
+func (c *ShareClaims) GrantsAssign(assignID string) bool {
+	switch c.ResourceType {
+	case ShareAssignReport:
+		return c.ResourceID == assignID
+	case ShareGenericReport:
+		return c.Filters[ShareFilterAssign] == assignID
+	}
+	return false
+}
+
+// GrantsMediaPrefix reports whether the claims open the media library under prefix
+func (c *ShareClaims) GrantsMediaPrefix(prefix string) bool {
+	if c.ResourceType != ShareMediaFolder {
+		return false
+	}
+	return prefix == c.ResourceID || strings.HasPrefix(prefix, strings.TrimSuffix(c.ResourceID, "/")+"/")
+}
+
+type ShareLinkRepository interface {
+	Create(link *ShareLink) error
+	FindByID(id uuid.UUID) (*ShareLink, error)
+	FindByResource(resourceType, resourceID string) ([]ShareLink, error)
+	Revoke(id uuid.UUID) error
+	RevokeByResource(resourceType, resourceID string) (int64, error)
+}
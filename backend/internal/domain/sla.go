@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// SLAClock is one of the deadlines a task is measured against
+type SLAClock string
+
+const (
+	SLAClockResponse   SLAClock = "response"   // Assign start -> first work saved or submitted
+	SLAClockCompletion SLAClock = "completion" // Assign start -> submitted for review
+	SLAClockReview     SLAClock = "review"     // Submitted -> approved (or sent back)
+)
+
+// SLAStatus is where a clock stands against its deadline
+type SLAStatus string
+
+const (
+	SLAOnTrack  SLAStatus = "on_track"
+	SLAAtRisk   SLAStatus = "at_risk" // Most of the allowed working time is used up
+	SLAOverdue  SLAStatus = "overdue"
+	SLAMet      SLAStatus = "met"      // Stopped before its deadline
+	SLABreached SLAStatus = "breached" // Stopped after its deadline
+)
+
+// slaSeverity orders open statuses, worst last
+var slaSeverity = map[SLAStatus]int{SLAOnTrack: 0, SLAAtRisk: 1, SLAOverdue: 2}
+
+// Worse reports whether s is more urgent than other
+func (s SLAStatus) Worse(other SLAStatus) bool {
+	return slaSeverity[s] > slaSeverity[other]
+}
+
+// EscalationStep notifies UserIDs once a task has been overdue for AfterHours
+type EscalationStep struct {
+	AfterHours int         `json:"after_hours"`
+	UserIDs    []uuid.UUID `json:"id_users"`
+}
+
+// SLAPolicy sets the deadlines of tasks, counted in hours of working days (see
+// Holiday). A policy applies to a project, a work type, both or - with neither
+// set - everything; the most specific one wins. Zero hours means no deadline.
+type SLAPolicy struct {
+	ID              uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name            string         `gorm:"column:name" json:"name"`
+	ProjectID       *uuid.UUID     `gorm:"column:id_project;type:uuid;index" json:"id_project"`
+	Project         *Project       `gorm:"foreignKey:ProjectID;references:ID" json:"project,omitempty"`
+	WorkID          *uuid.UUID     `gorm:"column:id_work;type:uuid" json:"id_work"`
+	Work            *Work          `gorm:"foreignKey:WorkID;references:ID" json:"work,omitempty"`
+	ResponseHours   int            `gorm:"column:response_hours;default:0" json:"response_hours"`
+	CompletionHours int            `gorm:"column:completion_hours;default:0" json:"completion_hours"` // 0: the assign's end time, if any
+	ReviewHours     int            `gorm:"column:review_hours;default:0" json:"review_hours"`
+	AtRiskPercent   int            `gorm:"column:at_risk_percent;default:80" json:"at_risk_percent"`
+	Escalation      datatypes.JSON `gorm:"column:escalation;type:jsonb;default:'[]'" json:"escalation"` // []EscalationStep
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+func (SLAPolicy) TableName() string {
+	return "sla_policies"
+}
+
+// EscalationSteps decodes the escalation chain
+func (p *SLAPolicy) EscalationSteps() []EscalationStep {
+	var steps []EscalationStep
+	if len(p.Escalation) > 0 {
+		_ = json.Unmarshal(p.Escalation, &steps)
+	}
+	return steps
+}
+
+// Matches reports whether the policy applies to a task of the project and work
+func (p *SLAPolicy) Matches(projectID uuid.UUID, workID *uuid.UUID) bool {
+	if p.ProjectID != nil && *p.ProjectID != projectID {
+		return false
+	}
+	if p.WorkID != nil && (workID == nil || *p.WorkID != *workID) {
+		return false
+	}
+	return true
+}
+
+// Specificity ranks matching policies: project and work > project > work > default
+func (p *SLAPolicy) Specificity() int {
+	n := 0
+	if p.ProjectID != nil {
+		n += 2
+	}
+	if p.WorkID != nil {
+		n++
+	}
+	return n
+}
+
+// Holiday is a non-working day. Recurring holidays repeat every year on the
+// same date (e.g. 30/4); lunar ones such as Tết are entered per year.
+type Holiday struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Date      time.Time `gorm:"column:date;type:date;not null" json:"date"`
+	Name      string    `gorm:"column:name" json:"name"`
+	Recurring bool      `gorm:"column:recurring;default:false" json:"recurring"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (Holiday) TableName() string {
+	return "holidays"
+}
+
+// DetailSLA is the tracked SLA state of an open task, refreshed by the SLA
+// scanner: its most urgent running clock and how far it has been escalated.
+type DetailSLA struct {
+	DetailAssignID  uuid.UUID     `gorm:"column:id_detail_assign;type:uuid;primaryKey" json:"id_detail_assign"`
+	DetailAssign    *DetailAssign `gorm:"foreignKey:DetailAssignID;references:ID" json:"detail_assign,omitempty"`
+	AssignID        uuid.UUID     `gorm:"column:id_assign;type:uuid;not null;index" json:"id_assign"`
+	ProjectID       uuid.UUID     `gorm:"column:id_project;type:uuid;not null;index" json:"id_project"`
+	PolicyID        *uuid.UUID    `gorm:"column:id_policy;type:uuid" json:"id_policy"`
+	Clock           SLAClock      `gorm:"column:clock;type:varchar(20);not null" json:"clock"`
+	Status          SLAStatus     `gorm:"column:status;type:varchar(20);not null;index" json:"status"`
+	DueAt           time.Time     `gorm:"column:due_at;not null" json:"due_at"`
+	EscalationLevel int           `gorm:"column:escalation_level;default:0" json:"escalation_level"` // Steps of the chain notified so far
+	AtRiskNotified  bool          `gorm:"column:at_risk_notified;default:false" json:"at_risk_notified"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+func (DetailSLA) TableName() string {
+	return "detail_sla"
+}
+
+// SLAFilter narrows tracked tasks; zero fields match everything
+type SLAFilter struct {
+	ProjectID *uuid.UUID
+	Status    SLAStatus
+	Clock     SLAClock
+	Scope     *VisibilityScope // projects the caller may see; nil: all
+}
+
+// SLAProjectCount is the at-risk and overdue work of one project
+type SLAProjectCount struct {
+	ProjectID uuid.UUID `json:"id_project"`
+	Name      string    `json:"name"`
+	AtRisk    int64     `json:"at_risk"`
+	Overdue   int64     `json:"overdue"`
+}
+
+// SLACounts counts the tracked open tasks by status
+type SLACounts struct {
+	OnTrack   int64             `json:"on_track"`
+	AtRisk    int64             `json:"at_risk"`
+	Overdue   int64             `json:"overdue"`
+	ByProject []SLAProjectCount `json:"by_project"`
+}
+
+type SLARepository interface {
+	CreatePolicy(policy *SLAPolicy) error
+	FindPolicies() ([]SLAPolicy, error)
+	// FindPolicyByID returns the policy, or nil
+	FindPolicyByID(id uuid.UUID) (*SLAPolicy, error)
+	UpdatePolicy(policy *SLAPolicy) error
+	DeletePolicy(id uuid.UUID) error
+
+	CreateHoliday(holiday *Holiday) error
+	FindHolidays() ([]Holiday, error)
+	DeleteHoliday(id uuid.UUID) error
+
+	// FindOpenDetails returns the tasks not yet approved, of live assigns, with
+	// their assign and config sub-work; FindAssignDetails those of one assign
+	FindOpenDetails() ([]DetailAssign, error)
+	FindAssignDetails(assignID uuid.UUID) ([]DetailAssign, error)
+	// FindEvents returns the history of the given tasks, oldest first
+	FindEvents(detailIDs []uuid.UUID) ([]DetailAssignEvent, error)
+
+	// FindTracking returns the tracked state of the given tasks
+	FindTracking(detailIDs []uuid.UUID) ([]DetailSLA, error)
+	SaveTracking(rows []DetailSLA) error
+	DeleteTracking(detailIDs []uuid.UUID) error
+	// PruneTracking drops the tracked state of tasks that are no longer open
+	PruneTracking() error
+}
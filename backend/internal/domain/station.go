@@ -54,6 +54,7 @@ type SubWork struct {
 	WorkID              uuid.UUID      `gorm:"column:id_work;type:uuid;not null" json:"id_work"`
 	Work                *Work          `gorm:"foreignKey:WorkID;references:ID" json:"work,omitempty"`
 	ProcessIDs          datatypes.JSON `gorm:"column:id_process;type:jsonb;default:'[]'" json:"id_process"`
+	MeasurementFields   datatypes.JSON `gorm:"column:measurement_fields;type:jsonb;default:'[]'" json:"measurement_fields"` // []MeasurementField
 	CreatedAt           time.Time      `json:"created_at"`
 	UpdatedAt           time.Time      `json:"updated_at"`
 	DeletedAt           gorm.DeletedAt `gorm:"index" json:"deleted_at"`
@@ -61,9 +62,9 @@ type SubWork struct {
 
 type AssetRepository interface {
 	Create(asset *Asset) error
-	FindAll() ([]Asset, error)
-	FindByProjectID(projectID uuid.UUID) ([]Asset, error)
-	FindByID(id uuid.UUID) (*Asset, error)
+	FindAll(scope *VisibilityScope) ([]Asset, error)
+	FindByProjectID(projectID uuid.UUID, scope *VisibilityScope) ([]Asset, error)
+	FindByID(id uuid.UUID, scope *VisibilityScope) (*Asset, error)
 	Update(asset *Asset) error
 	Delete(id uuid.UUID) error
 	FindDeleted() ([]Asset, error)
@@ -8,6 +8,10 @@ import (
 type StatsRepository interface {
 	// Manager Stats
 	GetManagerDashboardStats(managerID uuid.UUID) (*ManagerStats, error)
+
+	// SLA tracking of open tasks (see DetailSLA)
+	GetSLACounts(filter SLAFilter) (*SLACounts, error)
+	FindSLATasks(filter SLAFilter) ([]DetailSLA, error)
 }
 
 // TopPerformer represents a user with high task approval count
@@ -26,5 +30,7 @@ type ManagerStats struct {
 	TotalTasks        int64          `json:"total_tasks"`
 	SubmittedTasks    int64          `json:"submitted_tasks"`
 	CompletionRate    float64        `json:"completion_rate"`
+	AtRiskTasks       int64          `json:"at_risk_tasks"`
+	OverdueTasks      int64          `json:"overdue_tasks"`
 	TopPerformers     []TopPerformer `json:"top_performers"`
 }
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// OpenTask is who holds an unapproved task of a live assign: its owner, or
+// else the users of its assign (see DetailAssign.Holders)
+type OpenTask struct {
+	OwnerID *uuid.UUID     `gorm:"column:id_owner"`
+	UserIDs datatypes.JSON `gorm:"column:id_user"`
+}
+
+// Holders returns the users working the task
+func (t *OpenTask) Holders() []uuid.UUID {
+	users := (&Assign{UserIDs: t.UserIDs}).Users()
+	return (&DetailAssign{OwnerID: t.OwnerID}).Holders(users)
+}
+
+// WorkReviewStats counts the tasks of a work a user submitted that have been
+// reviewed, and how many of them were approved without ever being rejected
+type WorkReviewStats struct {
+	UserID    uuid.UUID `json:"id_user"`
+	WorkID    uuid.UUID `json:"id_work"`
+	Reviewed  int64     `json:"reviewed"`
+	FirstPass int64     `json:"first_pass"`
+}
+
+// SuggestionRepository reads what assignee suggestions are scored on
+type SuggestionRepository interface {
+	// FindCandidates returns the users who may be suggested, with their team
+	FindCandidates() ([]User, error)
+	// FindConfigWorks returns the distinct works of the configs' sub-works
+	FindConfigWorks(configIDs []uuid.UUID) ([]Work, error)
+	// FindOpenTasks returns the holders of every task not yet approved
+	FindOpenTasks() ([]OpenTask, error)
+	// FindReviewStats returns, per user and work, the review outcome of the
+	// tasks of the works they submitted
+	FindReviewStats(workIDs []uuid.UUID) ([]WorkReviewStats, error)
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "github.com/google/uuid"
+
+// VisibilityKind selects which rule a VisibilityScope applies
+type VisibilityKind int
+
+const (
+	// VisibilityMember limits reads to projects the user is a member of (and their assigns, assets, attendance)
+	VisibilityMember VisibilityKind = iota + 1
+	// VisibilityAssignee limits reads to assigns listing the user in Assign.UserIDs,
+	// plus the projects, assets and guidelines those assigns reference
+	VisibilityAssignee
+)
+
+// VisibilityScope restricts list/get queries in the repositories to what a caller may see.
+// A nil *VisibilityScope means unrestricted (admins, background jobs, internal services).
+type VisibilityScope struct {
+	UserID uuid.UUID
+	Kind   VisibilityKind
+}
+
+// NewVisibilityScope derives the scope of a caller from its role and resolved permissions:
+//   - admin or project.view_all: unrestricted (nil)
+//   - project.manage or assign.manage: project members only
+//   - everyone else: own assigns only
+func NewVisibilityScope(userID uuid.UUID, role string, permissions []string) *VisibilityScope {
+	if UserRole(role) == RoleAdmin || hasPermission(permissions, PermProjectViewAll) {
+		return nil
+	}
+	if hasPermission(permissions, PermProjectManage) || hasPermission(permissions, PermAssignManage) {
+		return &VisibilityScope{UserID: userID, Kind: VisibilityMember}
+	}
+	return &VisibilityScope{UserID: userID, Kind: VisibilityAssignee}
+}
+
+func hasPermission(permissions []string, code string) bool {
+	for _, p := range permissions {
+		if p == code {
+			return true
+		}
+	}
+	return false
+}
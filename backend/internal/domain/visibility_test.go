@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewVisibilityScope(t *testing.T) {
+	uid := uuid.New()
+
+	if s := NewVisibilityScope(uid, string(RoleAdmin), nil); s != nil {
+		t.Errorf("admin should be unrestricted, got %+v", s)
+	}
+	if s := NewVisibilityScope(uid, "Site Lead", []string{PermProjectViewAll}); s != nil {
+		t.Errorf("project.view_all should be unrestricted, got %+v", s)
+	}
+
+	s := NewVisibilityScope(uid, string(RoleManager), []string{PermAssignManage, PermProjectManage})
+	if s == nil || s.Kind != VisibilityMember || s.UserID != uid {
+		t.Errorf("manager should get member scope, got %+v", s)
+	}
+
+	s = NewVisibilityScope(uid, string(RoleEngineer), []string{PermTaskExecute})
+	if s == nil || s.Kind != VisibilityAssignee || s.UserID != uid {
+		t.Errorf("engineer should get assignee scope, got %+v", s)
+	}
+}
@@ -189,12 +189,13 @@ func (c *Consumer) persistURL(event ImageUploadedEvent) error {
 				return fmt.Errorf("marshal merged: %w", err)
 			}
 
-			// 4. Update — only the data column to avoid clobbering other fields
-			if err := tx.Exec(
-				`UPDATE detail_assigns SET data = ? WHERE id = ?`,
-				datatypes.JSON(mergedJSON),
-				event.DetailAssignID,
-			).Error; err != nil {
+			// 4. Update — only the data column to avoid clobbering other fields,
+			// bumping the version so writers holding an older read retry.
+			// Through GORM rather than raw SQL so the audit callbacks log it.
+			if err := tx.Table("detail_assigns").Where("id = ?", event.DetailAssignID).UpdateColumns(map[string]interface{}{
+				"data":    datatypes.JSON(mergedJSON),
+				"version": gorm.Expr("version + 1"),
+			}).Error; err != nil {
 				return fmt.Errorf("update failed: %w", err)
 			}
 			return nil
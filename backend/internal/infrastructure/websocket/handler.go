@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -87,6 +88,9 @@ func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// VerifyToken already rejected revoked sessions; keep the ID so revocation can drop the socket
+	sessionID, _ := uuid.Parse(fmt.Sprint(claims["sid"]))
+
 	// ── 2. Upgrade HTTP → WebSocket ──────────────────────────────────────────
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -95,8 +99,9 @@ func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := &Client{
-		UserID: userID,
-		Send:   make(chan []byte, sendBufSize),
+		UserID:    userID,
+		SessionID: sessionID,
+		Send:      make(chan []byte, sendBufSize),
 	}
 
 	h.hub.Register(client)
@@ -20,8 +20,9 @@ func safeClose(ch chan []byte) {
 
 // Client represents a single active WebSocket connection for a user.
 type Client struct {
-	UserID uuid.UUID
-	Send   chan []byte // Buffered channel of outbound messages
+	UserID    uuid.UUID
+	SessionID uuid.UUID   // Login session the connection was authenticated with
+	Send      chan []byte // Buffered channel of outbound messages
 }
 
 // ─── Hub ──────────────────────────────────────────────────────────────────────
@@ -142,6 +143,40 @@ func (h *Hub) Unregister(c *Client) {
 	}
 }
 
+// DisconnectSessions closes every connection opened with one of the given sessions.
+// Called when sessions are revoked so a killed login cannot keep receiving events.
+// readPump still unregisters the client once the write side closes the socket.
+func (h *Hub) DisconnectSessions(sessionIDs []uuid.UUID) {
+	revoked := make(map[uuid.UUID]bool, len(sessionIDs))
+	for _, id := range sessionIDs {
+		revoked[id] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for userID, clients := range h.clients {
+		kept := clients[:0]
+		for _, c := range clients {
+			if !revoked[c.SessionID] {
+				kept = append(kept, c)
+				continue
+			}
+			// Removed from the map under the write lock, so no sender can hit the closed channel
+			safeClose(c.Send)
+			h.log.Info("[WS Hub] Closed connection of revoked session",
+				zap.String("user_id", c.UserID.String()),
+				zap.String("session_id", c.SessionID.String()),
+			)
+		}
+		if len(kept) == 0 {
+			delete(h.clients, userID)
+		} else {
+			h.clients[userID] = kept
+		}
+	}
+}
+
 // SendToUser pushes a raw JSON message to all active connections for a given user.
 // It is a no-op if the user is not connected.
 func (h *Hub) SendToUser(userID uuid.UUID, message []byte) {
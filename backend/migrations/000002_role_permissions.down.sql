@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS role_permissions CASCADE;
+DROP TABLE IF EXISTS permissions CASCADE;
+
+DELETE FROM roles
+WHERE name IN ('Site Lead', 'QA Inspector', 'Owner Viewer')
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.id_role = roles.id);
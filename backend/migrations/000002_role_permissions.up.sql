@@ -0,0 +1,79 @@
+-- =======================================================================
+-- Permission catalog & role <-> permission mapping
+-- Roles are granted permissions via role_permissions; the backend checks
+-- permission codes, so new roles need no code change.
+-- =======================================================================
+
+-- PERMISSIONS (catalog, codes mirror domain.Perm* constants)
+CREATE TABLE IF NOT EXISTS permissions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code TEXT NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- ROLE_PERMISSIONS
+CREATE TABLE IF NOT EXISTS role_permissions (
+    id_role UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    id_permission UUID NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (id_role, id_permission)
+);
+CREATE INDEX IF NOT EXISTS idx_role_permissions_id_permission ON role_permissions(id_permission);
+
+-- Catalog
+INSERT INTO permissions (code, description) VALUES
+('user.manage', 'Create, update, delete and restore user accounts'),
+('role.manage', 'Manage roles and their permissions'),
+('team.manage', 'Manage teams'),
+('template.manage', 'Manage templates, configs, processes, model projects and guidelines'),
+('project.manage', 'Manage owners and projects'),
+('project.export', 'Export project reports'),
+('asset.manage', 'Manage assets, works and sub-works'),
+('assign.manage', 'Create, update and delete assignments'),
+('assign.approve', 'Approve or reject submitted tasks'),
+('task.execute', 'Upload evidence, take notes and submit tasks'),
+('attendance.checkin', 'Check in and check out'),
+('attendance.approve_checkout', 'Approve or reject checkout requests'),
+('attendance.view_all', 'View attendance of all users'),
+('stats.view', 'View manager and admin dashboards'),
+('admin.tables', 'Raw table access in the admin console'),
+('media.manage', 'Delete media folders'),
+('lark.push', 'Push reports and allocations to Lark')
+ON CONFLICT (code) DO NOTHING;
+
+-- Example custom roles
+INSERT INTO roles (name) VALUES ('Site Lead'), ('QA Inspector'), ('Owner Viewer')
+ON CONFLICT (name) DO NOTHING;
+
+-- Default grants
+-- manager: full catalog except roles and raw tables, which stay admin-only
+INSERT INTO role_permissions (id_role, id_permission)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'manager' AND p.code NOT IN ('role.manage', 'admin.tables')
+ON CONFLICT DO NOTHING;
+
+-- engineer: field work only
+INSERT INTO role_permissions (id_role, id_permission)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.code IN ('task.execute', 'attendance.checkin')
+WHERE r.name = 'engineer'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (id_role, id_permission)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.code IN (
+    'assign.manage', 'assign.approve', 'task.execute', 'attendance.checkin',
+    'attendance.approve_checkout', 'attendance.view_all', 'stats.view'
+)
+WHERE r.name = 'Site Lead'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (id_role, id_permission)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.code IN ('assign.approve', 'stats.view')
+WHERE r.name = 'QA Inspector'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (id_role, id_permission)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.code IN ('project.export')
+WHERE r.name = 'Owner Viewer'
+ON CONFLICT DO NOTHING;
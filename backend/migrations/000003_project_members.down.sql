@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS project_members CASCADE;
+DELETE FROM permissions WHERE code = 'project.view_all';
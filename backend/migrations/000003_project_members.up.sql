@@ -0,0 +1,28 @@
+-- =======================================================================
+-- Project membership (project-scoped data visibility)
+-- Managers see projects they are members of; engineers see what their
+-- assigns reference. Holders of project.view_all see everything.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS project_members (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_project UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    id_user UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (id_project, id_user)
+);
+CREATE INDEX IF NOT EXISTS idx_project_members_id_user ON project_members(id_user);
+
+INSERT INTO permissions (code, description) VALUES
+('project.view_all', 'See every project regardless of membership')
+ON CONFLICT (code) DO NOTHING;
+
+-- Keep current access: existing managers become members of every existing project
+INSERT INTO project_members (id_project, id_user)
+SELECT p.id, u.id
+FROM projects p
+CROSS JOIN users u
+JOIN roles r ON r.id = u.id_role
+WHERE r.name = 'manager' AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+ON CONFLICT (id_project, id_user) DO NOTHING;
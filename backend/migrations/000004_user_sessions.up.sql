@@ -0,0 +1,25 @@
+-- =======================================================================
+-- Server-side login sessions
+-- Each row backs one rotating refresh token chain (one per device login).
+-- Access tokens carry the session id; revoking the row logs the device out.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_user UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_id VARCHAR(255),
+    device_name VARCHAR(255),
+    user_agent TEXT,
+    ip_address VARCHAR(64),
+    refresh_token_hash VARCHAR(64) NOT NULL,
+    previous_token_hash VARCHAR(64),
+    last_used_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_reason VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_sessions_refresh_token_hash ON user_sessions(refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_previous_token_hash ON user_sessions(previous_token_hash);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_id_user ON user_sessions(id_user);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS share_links CASCADE;
+DELETE FROM permissions WHERE code = 'share.manage';
@@ -0,0 +1,27 @@
+-- =======================================================================
+-- Signed share links for /api/public/*
+-- Tokens are HMAC-signed and carry their own scope and expiry; this table
+-- records every issued link so it can be listed and revoked.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id VARCHAR(500) NOT NULL,
+    filters JSONB DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    id_person_created UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_share_links_resource ON share_links(resource_type, resource_id);
+
+INSERT INTO permissions (code, description) VALUES
+('share.manage', 'Issue, list and revoke public share links')
+ON CONFLICT (code) DO NOTHING;
+
+-- Managers already shared reports freely; keep that ability
+INSERT INTO role_permissions (id_role, id_permission)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'manager' AND p.code = 'share.manage'
+ON CONFLICT DO NOTHING;
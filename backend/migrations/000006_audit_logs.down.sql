@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_logs CASCADE;
+DROP FUNCTION IF EXISTS audit_logs_append_only();
+DELETE FROM permissions WHERE code = 'audit.view';
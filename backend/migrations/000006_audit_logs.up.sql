@@ -0,0 +1,46 @@
+-- =======================================================================
+-- Audit log: one row per API call (middleware.Audit: actor, role, IP,
+-- route) and one per row changed through GORM, with its before/after state.
+-- The table is append-only: UPDATE and DELETE are rejected by a trigger.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    request_id UUID NOT NULL,
+    id_actor UUID, -- no FK: entries must outlive the users they name
+    actor_role VARCHAR(50),
+    ip_address VARCHAR(64),
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(100),
+    entity_id VARCHAR(255),
+    before_data JSONB,
+    after_data JSONB,
+    changes JSONB,
+    request_body JSONB,
+    status_code INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_id_actor ON audit_logs(id_actor);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_request_id ON audit_logs(request_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_logs_append_only ON audit_logs;
+CREATE TRIGGER trg_audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+-- Not granted to managers: logs span every project and carry row state
+-- that no project visibility scope filters
+INSERT INTO permissions (code, description) VALUES
+('audit.view', 'View and export the audit log')
+ON CONFLICT (code) DO NOTHING;
+
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_recovery_codes CASCADE;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
+ALTER TABLE roles DROP COLUMN IF EXISTS require_mfa;
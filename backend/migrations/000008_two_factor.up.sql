@@ -0,0 +1,21 @@
+-- =======================================================================
+-- TOTP two-factor login (RFC 6238) with recovery codes
+-- =======================================================================
+
+-- Holders of a role with require_mfa must enrol before they can log in
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS require_mfa BOOLEAN DEFAULT FALSE;
+
+-- totp_secret is AES-GCM encrypted; it is only used once totp_enabled is set
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT DEFAULT 0;
+
+-- Single-use recovery codes (only the SHA-256 hash is stored)
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_user UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_id_user ON user_recovery_codes(id_user);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_audit_logs_actor_type;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS actor_type;
+DROP TABLE IF EXISTS api_keys CASCADE;
+DROP TABLE IF EXISTS service_accounts CASCADE;
+DELETE FROM permissions WHERE code = 'service_account.manage';
@@ -0,0 +1,49 @@
+-- =======================================================================
+-- Service accounts and scoped API keys for machine integrations
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS service_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    disabled BOOLEAN DEFAULT FALSE,
+    id_person_created UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_service_accounts_deleted_at ON service_accounts(deleted_at);
+
+-- Only the SHA-256 hash of a key is stored; scopes are permission codes
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_service_account UUID NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    name VARCHAR(255),
+    key_prefix VARCHAR(32) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]',
+    rate_limit_per_minute INTEGER NOT NULL DEFAULT 60,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    last_used_ip VARCHAR(64),
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    id_replaced_by UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    id_person_created UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_id_service_account ON api_keys(id_service_account);
+
+-- Audit records name the kind of principal; id_actor is a user or a service account
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS actor_type VARCHAR(32) NOT NULL DEFAULT 'user';
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_type ON audit_logs(actor_type);
+
+INSERT INTO permissions (code, description) VALUES
+('service_account.manage', 'Manage service accounts and their API keys')
+ON CONFLICT (code) DO NOTHING;
+
+-- Managers hold every other permission; let them set up integrations too
+INSERT INTO role_permissions (id_role, id_permission)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'manager' AND p.code = 'service_account.manage'
+ON CONFLICT DO NOTHING;
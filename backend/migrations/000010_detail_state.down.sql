@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_detail_assigns_state;
+ALTER TABLE detail_assigns DROP CONSTRAINT IF EXISTS chk_detail_assigns_state;
+ALTER TABLE detail_assigns DROP COLUMN IF EXISTS state;
@@ -0,0 +1,23 @@
+-- =======================================================================
+-- Explicit workflow state for detail_assigns
+-- draft -> in_progress -> submitted -> approved / rejected -> resubmitted ...
+-- The status_* flags are kept in sync by the backend for reports and stats.
+-- =======================================================================
+
+ALTER TABLE detail_assigns ADD COLUMN IF NOT EXISTS state VARCHAR(20) NOT NULL DEFAULT 'draft';
+
+-- Derive the state of existing rows from the legacy flags
+UPDATE detail_assigns SET state = CASE
+    WHEN status_approve = 1 THEN 'approved'
+    WHEN status_reject = 1 AND status_submit = 1 THEN 'resubmitted'
+    WHEN status_reject = 1 THEN 'rejected'
+    WHEN status_submit = 1 THEN 'submitted'
+    WHEN status_work = 1 OR (data IS NOT NULL AND data <> '[]'::jsonb) THEN 'in_progress'
+    ELSE 'draft'
+END;
+
+ALTER TABLE detail_assigns DROP CONSTRAINT IF EXISTS chk_detail_assigns_state;
+ALTER TABLE detail_assigns ADD CONSTRAINT chk_detail_assigns_state
+    CHECK (state IN ('draft', 'in_progress', 'submitted', 'approved', 'rejected', 'resubmitted'));
+
+CREATE INDEX IF NOT EXISTS idx_detail_assigns_state ON detail_assigns(state);
@@ -0,0 +1,13 @@
+-- Partially approved tasks go back to waiting for review
+UPDATE detail_assigns SET state = 'submitted' WHERE state = 'partially_approved';
+
+ALTER TABLE detail_assigns DROP CONSTRAINT IF EXISTS chk_detail_assigns_state;
+ALTER TABLE detail_assigns ADD CONSTRAINT chk_detail_assigns_state
+    CHECK (state IN ('draft', 'in_progress', 'submitted', 'approved', 'rejected', 'resubmitted'));
+
+ALTER TABLE detail_assigns DROP COLUMN IF EXISTS approval_level;
+ALTER TABLE detail_assigns DROP COLUMN IF EXISTS approval_round;
+
+DROP TABLE IF EXISTS detail_approvals;
+DROP TABLE IF EXISTS approval_levels;
+DROP TABLE IF EXISTS approval_chains;
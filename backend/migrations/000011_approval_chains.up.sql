@@ -0,0 +1,53 @@
+-- =======================================================================
+-- Multi-level approval chains (site lead -> PM -> owner sign-off)
+-- A chain belongs to a project or to a template (template overrides project).
+-- Tasks stay 'partially_approved' until every level reaches its quorum.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS approval_chains (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_project UUID REFERENCES projects(id) ON DELETE CASCADE,
+    id_template UUID REFERENCES templates(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT chk_approval_chains_owner CHECK ((id_project IS NULL) <> (id_template IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_approval_chains_project ON approval_chains(id_project) WHERE id_project IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_approval_chains_template ON approval_chains(id_template) WHERE id_template IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS approval_levels (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_chain UUID NOT NULL REFERENCES approval_chains(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    name VARCHAR(255),
+    roles JSONB DEFAULT '[]'::jsonb,
+    id_users JSONB DEFAULT '[]'::jsonb,
+    quorum INT NOT NULL DEFAULT 1 CHECK (quorum >= 1),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (id_chain, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_approval_levels_id_chain ON approval_levels(id_chain);
+
+-- One row per sign-off; round is detail_assigns.approval_round at the time
+CREATE TABLE IF NOT EXISTS detail_approvals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_detail_assign UUID NOT NULL REFERENCES detail_assigns(id) ON DELETE CASCADE,
+    id_chain UUID,
+    round INT NOT NULL DEFAULT 0,
+    level INT NOT NULL DEFAULT 0,
+    id_approver UUID REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (id_detail_assign, round, id_approver)
+);
+
+CREATE INDEX IF NOT EXISTS idx_detail_approvals_id_detail_assign ON detail_approvals(id_detail_assign);
+
+ALTER TABLE detail_assigns ADD COLUMN IF NOT EXISTS approval_round INT NOT NULL DEFAULT 0;
+ALTER TABLE detail_assigns ADD COLUMN IF NOT EXISTS approval_level INT NOT NULL DEFAULT 0;
+
+ALTER TABLE detail_assigns DROP CONSTRAINT IF EXISTS chk_detail_assigns_state;
+ALTER TABLE detail_assigns ADD CONSTRAINT chk_detail_assigns_state
+    CHECK (state IN ('draft', 'in_progress', 'submitted', 'partially_approved', 'approved', 'rejected', 'resubmitted'));
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS detail_assign_events;
@@ -0,0 +1,63 @@
+-- =======================================================================
+-- Normalised task history, replacing the parallel JSONB arrays
+-- (submitted_at / approval_at / rejected_at / id_person_*) as the source
+-- of truth for timelines, stats and Lark sync. The arrays are still
+-- written for the frontend.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS detail_assign_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_detail_assign UUID NOT NULL REFERENCES detail_assigns(id) ON DELETE CASCADE,
+    id_assign UUID NOT NULL REFERENCES assigns(id) ON DELETE CASCADE,
+    event_type VARCHAR(20) NOT NULL,
+    from_state VARCHAR(20),
+    to_state VARCHAR(20),
+    id_actor UUID REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    evidence JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_detail_assign_events_id_detail_assign ON detail_assign_events(id_detail_assign, created_at);
+CREATE INDEX IF NOT EXISTS idx_detail_assign_events_id_assign ON detail_assign_events(id_assign, created_at);
+CREATE INDEX IF NOT EXISTS idx_detail_assign_events_event_type ON detail_assign_events(event_type, created_at);
+
+-- Backfill from the JSONB arrays. Actors are matched by array position, which
+-- is only as good as the arrays were; submissions never recorded an actor and
+-- the states before/after each step are unknown.
+INSERT INTO detail_assign_events (id_detail_assign, id_assign, event_type, created_at)
+SELECT da.id, da.id_assign, 'submit', t.ts::timestamptz
+FROM detail_assigns da
+CROSS JOIN LATERAL jsonb_array_elements_text(
+    CASE WHEN jsonb_typeof(da.submitted_at) = 'array' THEN da.submitted_at ELSE '[]'::jsonb END
+) AS t(ts);
+
+INSERT INTO detail_assign_events (id_detail_assign, id_assign, event_type, id_actor, created_at)
+SELECT da.id, da.id_assign, 'approve',
+    u.id,
+    t.ts::timestamptz
+FROM detail_assigns da
+CROSS JOIN LATERAL jsonb_array_elements_text(
+    CASE WHEN jsonb_typeof(da.approval_at) = 'array' THEN da.approval_at ELSE '[]'::jsonb END
+) WITH ORDINALITY AS t(ts, n)
+LEFT JOIN LATERAL (
+    SELECT value AS actor FROM jsonb_array_elements_text(
+        CASE WHEN jsonb_typeof(da.id_person_approve) = 'array' THEN da.id_person_approve ELSE '[]'::jsonb END
+    ) WITH ORDINALITY AS p(value, n) WHERE p.n = t.n
+) p ON TRUE
+LEFT JOIN users u ON u.id::text = p.actor;
+
+INSERT INTO detail_assign_events (id_detail_assign, id_assign, event_type, id_actor, created_at)
+SELECT da.id, da.id_assign, 'reject',
+    u.id,
+    t.ts::timestamptz
+FROM detail_assigns da
+CROSS JOIN LATERAL jsonb_array_elements_text(
+    CASE WHEN jsonb_typeof(da.rejected_at) = 'array' THEN da.rejected_at ELSE '[]'::jsonb END
+) WITH ORDINALITY AS t(ts, n)
+LEFT JOIN LATERAL (
+    SELECT value AS actor FROM jsonb_array_elements_text(
+        CASE WHEN jsonb_typeof(da.id_person_reject) = 'array' THEN da.id_person_reject ELSE '[]'::jsonb END
+    ) WITH ORDINALITY AS p(value, n) WHERE p.n = t.n
+) p ON TRUE
+LEFT JOIN users u ON u.id::text = p.actor;
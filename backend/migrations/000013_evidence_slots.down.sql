@@ -0,0 +1,2 @@
+ALTER TABLE detail_assigns DROP COLUMN IF EXISTS slot_evidence;
+ALTER TABLE configs DROP COLUMN IF EXISTS evidence_slots;
@@ -0,0 +1,10 @@
+-- =======================================================================
+-- Named evidence slots per config ("Before cleaning", "Nameplate", ...)
+-- with min/max counts and media types, enforced on submit.
+-- Configs without slots keep status_set_image_count / image_count.
+-- =======================================================================
+
+ALTER TABLE configs ADD COLUMN IF NOT EXISTS evidence_slots JSONB DEFAULT '[]'::jsonb;
+
+-- Slot key -> uploaded URLs of a task (subset of detail_assigns.data)
+ALTER TABLE detail_assigns ADD COLUMN IF NOT EXISTS slot_evidence JSONB DEFAULT '{}'::jsonb;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS detail_measurements;
+ALTER TABLE configs DROP COLUMN IF EXISTS measurement_fields;
+ALTER TABLE sub_works DROP COLUMN IF EXISTS measurement_fields;
@@ -0,0 +1,29 @@
+-- =======================================================================
+-- Typed measurement readings per task (string Voc, insulation resistance,
+-- torque, ...). Sub-works declare the fields, configs may override them;
+-- readings outside a field's thresholds are flagged for review.
+-- =======================================================================
+
+ALTER TABLE sub_works ADD COLUMN IF NOT EXISTS measurement_fields JSONB DEFAULT '[]'::jsonb;
+ALTER TABLE configs ADD COLUMN IF NOT EXISTS measurement_fields JSONB DEFAULT '[]'::jsonb;
+
+-- Label, unit and thresholds are copied from the field at submit time
+CREATE TABLE IF NOT EXISTS detail_measurements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_detail_assign UUID NOT NULL REFERENCES detail_assigns(id) ON DELETE CASCADE,
+    field_key VARCHAR(100) NOT NULL,
+    position INTEGER DEFAULT 0,
+    label VARCHAR(255),
+    unit VARCHAR(50),
+    value DOUBLE PRECISION NOT NULL,
+    min_value DOUBLE PRECISION,
+    max_value DOUBLE PRECISION,
+    out_of_range BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (id_detail_assign, field_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_detail_measurements_id_detail_assign ON detail_measurements(id_detail_assign);
+CREATE INDEX IF NOT EXISTS idx_detail_measurements_field_key ON detail_measurements(field_key, value);
+CREATE INDEX IF NOT EXISTS idx_detail_measurements_out_of_range ON detail_measurements(out_of_range) WHERE out_of_range;
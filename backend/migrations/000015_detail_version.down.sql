@@ -0,0 +1 @@
+ALTER TABLE detail_assigns DROP COLUMN IF EXISTS version;
@@ -0,0 +1,7 @@
+-- =======================================================================
+-- Optimistic concurrency on detail_assigns: every workflow write (submit,
+-- approve, reject, note, bulk) bumps version and only applies if the row
+-- is still at the version the client sent (If-Match / version).
+-- =======================================================================
+
+ALTER TABLE detail_assigns ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
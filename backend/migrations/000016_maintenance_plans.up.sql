@@ -0,0 +1,41 @@
+-- =======================================================================
+-- Preventive maintenance plans: recurring work (module cleaning, inverter
+-- inspection, IR thermography) that the scheduler turns into assigns.
+-- Each occurrence is recorded once, generated or skipped.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS maintenance_plans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    id_project UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    id_model_project UUID REFERENCES model_projects(id) ON DELETE SET NULL,
+    id_template UUID REFERENCES templates(id) ON DELETE SET NULL,
+    id_config JSONB DEFAULT '[]'::jsonb,
+    recurrence VARCHAR(255) NOT NULL,
+    start_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_date TIMESTAMP WITH TIME ZONE,
+    lead_days INTEGER DEFAULT 0,
+    duration_days INTEGER DEFAULT 1,
+    id_user JSONB DEFAULT '[]'::jsonb,
+    note_assign TEXT,
+    paused BOOLEAN DEFAULT FALSE,
+    id_person_created UUID,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_plans_id_project ON maintenance_plans(id_project);
+CREATE INDEX IF NOT EXISTS idx_maintenance_plans_deleted_at ON maintenance_plans(deleted_at);
+
+-- (plan, due date) is unique: that is what keeps generation idempotent
+CREATE TABLE IF NOT EXISTS maintenance_occurrences (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_plan UUID NOT NULL REFERENCES maintenance_plans(id) ON DELETE CASCADE,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    id_assign UUID REFERENCES assigns(id) ON DELETE SET NULL,
+    skipped BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_maintenance_occurrences_plan_due ON maintenance_occurrences(id_plan, due_at);
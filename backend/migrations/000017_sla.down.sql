@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS detail_sla;
+DROP TABLE IF EXISTS holidays;
+DROP TABLE IF EXISTS sla_policies;
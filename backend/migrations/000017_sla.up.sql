@@ -0,0 +1,56 @@
+-- =======================================================================
+-- SLA deadlines: policies per project and/or work type, the working-day
+-- calendar they are counted in, and the tracked state of open tasks that
+-- the SLA scanner refreshes and escalates.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS sla_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255),
+    id_project UUID REFERENCES projects(id) ON DELETE CASCADE,
+    id_work UUID REFERENCES works(id) ON DELETE CASCADE,
+    response_hours INTEGER DEFAULT 0,
+    completion_hours INTEGER DEFAULT 0,
+    review_hours INTEGER DEFAULT 0,
+    at_risk_percent INTEGER DEFAULT 80,
+    escalation JSONB DEFAULT '[]'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sla_policies_id_project ON sla_policies(id_project);
+
+CREATE TABLE IF NOT EXISTS holidays (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    date DATE NOT NULL,
+    name VARCHAR(255),
+    recurring BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_holidays_date ON holidays(date);
+
+-- Vietnamese public holidays on fixed solar dates. Lunar ones (Tết Nguyên Đán,
+-- Giỗ Tổ Hùng Vương) and compensatory days move every year: add them via /holidays.
+INSERT INTO holidays (date, name, recurring) VALUES
+    ('2000-01-01', 'Tết Dương lịch', TRUE),
+    ('2000-04-30', 'Ngày Giải phóng miền Nam', TRUE),
+    ('2000-05-01', 'Ngày Quốc tế Lao động', TRUE),
+    ('2000-09-02', 'Quốc khánh', TRUE);
+
+CREATE TABLE IF NOT EXISTS detail_sla (
+    id_detail_assign UUID PRIMARY KEY REFERENCES detail_assigns(id) ON DELETE CASCADE,
+    id_assign UUID NOT NULL,
+    id_project UUID NOT NULL,
+    id_policy UUID REFERENCES sla_policies(id) ON DELETE SET NULL,
+    clock VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    escalation_level INTEGER DEFAULT 0,
+    at_risk_notified BOOLEAN DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_detail_sla_id_assign ON detail_sla(id_assign);
+CREATE INDEX IF NOT EXISTS idx_detail_sla_id_project ON detail_sla(id_project);
+CREATE INDEX IF NOT EXISTS idx_detail_sla_status ON detail_sla(status);
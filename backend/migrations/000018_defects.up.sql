@@ -0,0 +1,33 @@
+-- =======================================================================
+-- Defects: faults found on assets, usually during a visit. A defect is
+-- worked directly or through a corrective assign created from it, and
+-- stays linked to that assign as the asset's history.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS defects (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_asset UUID NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    id_project UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    id_detail_assign UUID REFERENCES detail_assigns(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    category VARCHAR(50) NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    photos JSONB DEFAULT '[]'::jsonb,
+    id_assignee UUID REFERENCES users(id) ON DELETE SET NULL,
+    id_reporter UUID REFERENCES users(id) ON DELETE SET NULL,
+    id_assign UUID REFERENCES assigns(id) ON DELETE SET NULL,
+    resolution TEXT,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    id_closed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_defects_id_asset ON defects(id_asset);
+CREATE INDEX IF NOT EXISTS idx_defects_id_project ON defects(id_project);
+CREATE INDEX IF NOT EXISTS idx_defects_status ON defects(status);
+CREATE INDEX IF NOT EXISTS idx_defects_deleted_at ON defects(deleted_at);
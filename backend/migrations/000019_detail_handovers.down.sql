@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS detail_assign_handovers;
+DROP INDEX IF EXISTS idx_detail_assigns_id_owner;
+ALTER TABLE detail_assigns DROP COLUMN IF EXISTS id_owner;
@@ -0,0 +1,24 @@
+-- =======================================================================
+-- Task ownership: a task may be held by one engineer (id_owner) instead of
+-- being shared by every user of its assign. Reassignments and shift
+-- handovers are recorded, so it is known who held a task when.
+-- =======================================================================
+
+ALTER TABLE detail_assigns ADD COLUMN IF NOT EXISTS id_owner UUID REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_detail_assigns_id_owner ON detail_assigns(id_owner);
+
+CREATE TABLE IF NOT EXISTS detail_assign_handovers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_detail_assign UUID NOT NULL REFERENCES detail_assigns(id) ON DELETE CASCADE,
+    id_assign UUID NOT NULL REFERENCES assigns(id) ON DELETE CASCADE,
+    id_from_user UUID REFERENCES users(id) ON DELETE SET NULL,
+    id_to_user UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    note TEXT,
+    id_actor UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_detail_assign_handovers_id_detail_assign ON detail_assign_handovers(id_detail_assign);
+CREATE INDEX IF NOT EXISTS idx_detail_assign_handovers_id_assign ON detail_assign_handovers(id_assign);
+CREATE INDEX IF NOT EXISTS idx_detail_assign_handovers_created_at ON detail_assign_handovers(created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_detail_assigns_id_form_schema;
+ALTER TABLE detail_assigns DROP COLUMN IF EXISTS form_answers;
+ALTER TABLE detail_assigns DROP COLUMN IF EXISTS id_form_schema;
+DROP TABLE IF EXISTS form_schemas;
@@ -0,0 +1,23 @@
+-- =======================================================================
+-- Checklist forms: a sub-work (or a config, for one asset) may carry a form
+-- of yes/no, choice and text fields. Forms are versioned; a task keeps the
+-- version it was filled against (id_form_schema) next to its answers.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS form_schemas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_sub_work UUID REFERENCES sub_works(id) ON DELETE CASCADE,
+    id_config UUID REFERENCES configs(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    fields JSONB NOT NULL DEFAULT '[]'::jsonb,
+    id_person_created UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((id_sub_work IS NULL) <> (id_config IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_form_schemas_id_sub_work_version ON form_schemas(id_sub_work, version) WHERE id_sub_work IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_form_schemas_id_config_version ON form_schemas(id_config, version) WHERE id_config IS NOT NULL;
+
+ALTER TABLE detail_assigns ADD COLUMN IF NOT EXISTS id_form_schema UUID REFERENCES form_schemas(id) ON DELETE SET NULL;
+ALTER TABLE detail_assigns ADD COLUMN IF NOT EXISTS form_answers JSONB DEFAULT '{}'::jsonb;
+CREATE INDEX IF NOT EXISTS idx_detail_assigns_id_form_schema ON detail_assigns(id_form_schema);
@@ -0,0 +1,18 @@
+-- =======================================================================
+-- Step order: within a template, the tasks of a config may wait for the
+-- tasks of another config to be submitted or approved (e.g. "Re-energize
+-- inverter" after "Lock-out / tag-out"). The dependencies form a DAG.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS config_dependencies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_template UUID NOT NULL REFERENCES templates(id) ON DELETE CASCADE,
+    id_config UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+    id_depends_on UUID NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+    required_state VARCHAR(20) NOT NULL DEFAULT 'approved',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (id_config <> id_depends_on)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_config_dependencies_edge ON config_dependencies(id_template, id_config, id_depends_on);
+CREATE INDEX IF NOT EXISTS idx_config_dependencies_id_template ON config_dependencies(id_template);
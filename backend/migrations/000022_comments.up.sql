@@ -0,0 +1,44 @@
+-- =======================================================================
+-- Discussion threads on assigns and their tasks. Edits and deletions keep
+-- the former content in comment_revisions; comment_reads records how far
+-- each user has read each thread (a task's or an assign's own, by its ID).
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_assign UUID NOT NULL REFERENCES assigns(id) ON DELETE CASCADE,
+    id_detail_assign UUID REFERENCES detail_assigns(id) ON DELETE CASCADE,
+    id_author UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT,
+    attachments JSONB DEFAULT '[]'::jsonb,
+    mentions JSONB DEFAULT '[]'::jsonb,
+    edited_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_id_assign ON comments(id_assign);
+CREATE INDEX IF NOT EXISTS idx_comments_id_detail_assign ON comments(id_detail_assign);
+CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments(created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_mentions ON comments USING GIN (mentions);
+
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_comment UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    body TEXT,
+    attachments JSONB DEFAULT '[]'::jsonb,
+    mentions JSONB DEFAULT '[]'::jsonb,
+    id_actor UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_revisions_id_comment ON comment_revisions(id_comment);
+
+CREATE TABLE IF NOT EXISTS comment_reads (
+    id_user UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    id_thread UUID NOT NULL,
+    last_read_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (id_user, id_thread)
+);
@@ -0,0 +1,21 @@
+-- =======================================================================
+-- Engineer availability: leave, training and other time off site. With
+-- the assigns they already work on, these make up each engineer's calendar
+-- and are checked for overlaps when assigns are scheduled.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS user_unavailabilities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_user UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    note TEXT,
+    id_created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (end_time > start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_unavailabilities_id_user ON user_unavailabilities(id_user);
+CREATE INDEX IF NOT EXISTS idx_user_unavailabilities_start_time ON user_unavailabilities(start_time);
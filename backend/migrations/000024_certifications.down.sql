@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS work_certifications;
+DROP TABLE IF EXISTS user_certifications;
+DROP TABLE IF EXISTS certifications;
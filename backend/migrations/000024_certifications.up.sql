@@ -0,0 +1,31 @@
+-- =======================================================================
+-- Certifications engineers hold (with an optional expiry) and those each
+-- work requires. Assignee suggestions only pick engineers holding every
+-- certification the works of an assign require.
+-- =======================================================================
+
+CREATE TABLE IF NOT EXISTS certifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_certifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    id_user UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    id_certification UUID NOT NULL REFERENCES certifications(id) ON DELETE CASCADE,
+    issued_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_certifications_user_cert ON user_certifications(id_user, id_certification);
+
+CREATE TABLE IF NOT EXISTS work_certifications (
+    id_work UUID NOT NULL REFERENCES works(id) ON DELETE CASCADE,
+    id_certification UUID NOT NULL REFERENCES certifications(id) ON DELETE CASCADE,
+    PRIMARY KEY (id_work, id_certification)
+);
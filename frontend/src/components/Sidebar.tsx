@@ -31,6 +31,7 @@ const Sidebar = ({ role, isOpen, onClose }: SidebarProps) => {
         // Notify server to set status_user = 0
         await authService.logout();
         sessionStorage.removeItem('token');
+        sessionStorage.removeItem('refresh_token');
         sessionStorage.removeItem('user');
         navigate('/login');
     };
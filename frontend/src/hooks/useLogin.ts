@@ -2,47 +2,105 @@ import { useState } from 'react';
 import { useNavigate } from 'react-router-dom';
 import { authService } from '../services/auth.service';
 
+// Second login step: the password was accepted and a TOTP/recovery code is needed
+export interface MfaStep {
+ challenge: string;
+ // Set while enrolling an authenticator (role requires 2FA, user not enrolled yet)
+ enrollment?: { secret: string; otpauth_uri: string };
+}
+
+const getErrorMessage = (err: any, fallback: string) => {
+ const serverError = err.response?.data?.error;
+ if (typeof serverError === 'string') {
+ return serverError;
+ } else if (serverError && typeof serverError === 'object' && serverError.message) {
+ return serverError.message;
+ } else if (err.message) {
+ return err.message;
+ }
+ return fallback;
+};
+
 export const useLogin = () => {
  const [email, setEmail] = useState('');
  const [password, setPassword] = useState('');
  const [isLoading, setIsLoading] = useState(false);
  const [error, setError] = useState('');
  const [showSupport, setShowSupport] = useState(false);
+ const [mfa, setMfa] = useState<MfaStep | null>(null);
+ const [mfaCode, setMfaCode] = useState('');
+ const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
  const navigate = useNavigate();
 
+ const goHome = (mustChangePassword: boolean) => {
+ // Admin-created/reset accounts must set their own password before using the app
+ if (mustChangePassword) {
+ sessionStorage.setItem('must_change_password', 'true');
+ navigate('/user/settings');
+ return;
+ }
+ navigate('/home');
+ };
+
+ const completeLogin = (data: any) => {
+ const { token, refresh_token, user, must_change_password, recovery_codes } = data;
+
+ sessionStorage.setItem('token', token);
+ sessionStorage.setItem('refresh_token', refresh_token);
+ sessionStorage.setItem('user', JSON.stringify(user));
+
+ if (must_change_password) {
+ sessionStorage.setItem('must_change_password', 'true');
+ }
+ // Freshly enrolled: show the recovery codes once before leaving the login page
+ if (recovery_codes) {
+ setRecoveryCodes(recovery_codes);
+ return;
+ }
+ goHome(must_change_password);
+ };
+
  const handleLogin = async (e: React.FormEvent) => {
  e.preventDefault();
  setIsLoading(true);
  setError('');
 
  try {
- const data = await authService.login(email, password);
-
- const { token, user } = data;
-
- sessionStorage.setItem('token', token);
- sessionStorage.setItem('user', JSON.stringify(user));
+ if (mfa) {
+ completeLogin(await authService.verifyMfa(mfa.challenge, mfaCode));
+ return;
+ }
 
- navigate('/home');
+ const data = await authService.login(email, password);
+ if (data.mfa_required) {
+ const step: MfaStep = { challenge: data.challenge };
+ if (data.enrollment_required) {
+ step.enrollment = await authService.enrollMfa(data.challenge);
+ }
+ setMfaCode('');
+ setMfa(step);
+ return;
+ }
+ completeLogin(data);
  } catch (err: any) {
  console.error('Login failed:', err);
- const serverError = err.response?.data?.error;
- let errorMessage = 'Đăng nhập thất bại. Vui lòng kiểm tra lại thông tin.';
-
- if (typeof serverError === 'string') {
- errorMessage = serverError;
- } else if (serverError && typeof serverError === 'object' && serverError.message) {
- errorMessage = serverError.message;
- } else if (err.message) {
- errorMessage = err.message;
- }
-
- setError(errorMessage);
+ setError(getErrorMessage(err, 'Đăng nhập thất bại. Vui lòng kiểm tra lại thông tin.'));
  } finally {
  setIsLoading(false);
  }
  };
 
+ const cancelMfa = () => {
+ setMfa(null);
+ setMfaCode('');
+ setError('');
+ };
+
+ const finishEnrollment = () => {
+ setRecoveryCodes(null);
+ goHome(sessionStorage.getItem('must_change_password') === 'true');
+ };
+
  return {
  email,
  setEmail,
@@ -52,6 +110,12 @@ export const useLogin = () => {
  error,
  showSupport,
  setShowSupport,
- handleLogin
+ handleLogin,
+ mfa,
+ mfaCode,
+ setMfaCode,
+ cancelMfa,
+ recoveryCodes,
+ finishEnrollment
  };
 };
@@ -0,0 +1,81 @@
+import React from 'react';
+import { ShieldCheck, ArrowRight, KeyRound } from 'lucide-react';
+import type { MfaStep } from '../../hooks/useLogin';
+
+interface MfaCodeStepProps {
+    mfa: MfaStep;
+    code: string;
+    setCode: (code: string) => void;
+    isLoading: boolean;
+    onSubmit: (e: React.FormEvent) => void;
+    onCancel: () => void;
+}
+
+// Second login step shared by the desktop and mobile login pages
+export const MfaCodeStep = ({ mfa, code, setCode, isLoading, onSubmit, onCancel }: MfaCodeStepProps) => (
+    <form onSubmit={onSubmit} className="space-y-5">
+        <div className="flex items-center gap-3 text-slate-700">
+            <ShieldCheck className="w-6 h-6 text-primary-600" />
+            <h3 className="font-bold text-lg">Xác thực hai lớp</h3>
+        </div>
+
+        {mfa.enrollment ? (
+            <div className="space-y-3 text-sm text-slate-600">
+                <p>Vai trò của bạn yêu cầu xác thực hai lớp. Thêm tài khoản vào ứng dụng xác thực (Google Authenticator, Microsoft Authenticator...) bằng khóa sau, rồi nhập mã 6 số:</p>
+                <code className="block p-3 bg-slate-100 rounded-xl font-mono text-base tracking-wider break-all select-all">{mfa.enrollment.secret}</code>
+                <a href={mfa.enrollment.otpauth_uri} className="inline-block text-primary-600 font-medium hover:underline">Mở bằng ứng dụng xác thực trên thiết bị này</a>
+            </div>
+        ) : (
+            <p className="text-sm text-slate-600">Nhập mã 6 số từ ứng dụng xác thực, hoặc một mã khôi phục.</p>
+        )}
+
+        <div className="relative group">
+            <div className="absolute inset-y-0 left-0 pl-4 flex items-center pointer-events-none text-slate-400 group-focus-within:text-primary-500">
+                <KeyRound className="h-5 w-5" />
+            </div>
+            <input
+                type="text"
+                inputMode={mfa.enrollment ? 'numeric' : 'text'}
+                autoComplete="one-time-code"
+                autoFocus
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                className="block w-full pl-11 pr-4 py-3.5 bg-white/60 border border-slate-200 rounded-xl text-slate-800 tracking-widest focus:ring-2 focus:ring-primary-500/20 focus:border-primary-500 outline-none"
+                placeholder="123456"
+                required
+            />
+        </div>
+
+        <button
+            type="submit"
+            disabled={isLoading}
+            className={`w-full flex items-center justify-center gap-2 py-3.5 px-4 rounded-xl text-white font-bold bg-gradient-to-r from-primary-600 to-indigo-600 ${isLoading ? 'opacity-80 cursor-wait' : ''}`}
+        >
+            {isLoading ? 'Đang xử lý...' : <>Xác nhận <ArrowRight className="w-5 h-5" /></>}
+        </button>
+        <button type="button" onClick={onCancel} className="w-full text-sm text-slate-500 hover:text-primary-600 font-medium">
+            Quay lại đăng nhập
+        </button>
+    </form>
+);
+
+// Shown once after enrolling during login
+export const RecoveryCodesPanel = ({ codes, onDone }: { codes: string[]; onDone: () => void }) => (
+    <div className="space-y-5">
+        <div className="flex items-center gap-3 text-slate-700">
+            <ShieldCheck className="w-6 h-6 text-emerald-600" />
+            <h3 className="font-bold text-lg">Đã bật xác thực hai lớp</h3>
+        </div>
+        <p className="text-sm text-slate-600">Lưu các mã khôi phục dưới đây ở nơi an toàn. Mỗi mã chỉ dùng được một lần khi bạn mất thiết bị xác thực. Các mã sẽ không được hiển thị lại.</p>
+        <div className="grid grid-cols-2 gap-2 p-4 bg-slate-100 rounded-xl font-mono text-sm select-all">
+            {codes.map((c) => <span key={c}>{c}</span>)}
+        </div>
+        <button
+            type="button"
+            onClick={onDone}
+            className="w-full py-3.5 px-4 rounded-xl text-white font-bold bg-gradient-to-r from-primary-600 to-indigo-600"
+        >
+            Tôi đã lưu mã, tiếp tục
+        </button>
+    </div>
+);
@@ -3,6 +3,7 @@ import { motion, AnimatePresence } from 'framer-motion';
 import { User, Lock, ArrowRight, Mail, Phone, MessageCircle, X, HelpCircle, Sun, ShieldCheck } from 'lucide-react';
 import logo from '../../../assets/logo1.png';
 import { useLogin } from '../../../hooks/useLogin';
+import { MfaCodeStep, RecoveryCodesPanel } from '../MfaCodeStep';
 
 const DesktopLogin = () => {
     const {
@@ -14,7 +15,13 @@ const DesktopLogin = () => {
         error,
         showSupport,
         setShowSupport,
-        handleLogin
+        handleLogin,
+        mfa,
+        mfaCode,
+        setMfaCode,
+        cancelMfa,
+        recoveryCodes,
+        finishEnrollment
     } = useLogin();
 
     const [showPassword, setShowPassword] = useState(false);
@@ -123,6 +130,11 @@ const DesktopLogin = () => {
                             </motion.div>
                         )}
 
+                        {recoveryCodes ? (
+                            <RecoveryCodesPanel codes={recoveryCodes} onDone={finishEnrollment} />
+                        ) : mfa ? (
+                            <MfaCodeStep mfa={mfa} code={mfaCode} setCode={setMfaCode} isLoading={isLoading} onSubmit={handleLogin} onCancel={cancelMfa} />
+                        ) : (
                         <form onSubmit={handleLogin} className="space-y-6">
                             <motion.div
                                 initial={{ opacity: 0, x: -10 }}
@@ -234,6 +246,7 @@ const DesktopLogin = () => {
                                 </button>
                             </motion.div>
                         </form>
+                        )}
                     </motion.div>
                 </div>
             </div>
@@ -1,4 +1,5 @@
 import { useLogin } from '../../../hooks/useLogin';
+import { MfaCodeStep, RecoveryCodesPanel } from '../MfaCodeStep';
 import React, { useState } from 'react';
 import { motion, AnimatePresence } from 'framer-motion';
 import { ArrowRight, User, Lock, X, Mail, Phone, MessageCircle, HelpCircle } from 'lucide-react';
@@ -14,7 +15,13 @@ const MobileLogin = () => {
         error,
         showSupport,
         setShowSupport,
-        handleLogin
+        handleLogin,
+        mfa,
+        mfaCode,
+        setMfaCode,
+        cancelMfa,
+        recoveryCodes,
+        finishEnrollment
     } = useLogin();
 
     const [showPassword, setShowPassword] = useState(false);
@@ -83,6 +90,11 @@ const MobileLogin = () => {
                     </motion.div>
                 )}
 
+                {recoveryCodes ? (
+                    <RecoveryCodesPanel codes={recoveryCodes} onDone={finishEnrollment} />
+                ) : mfa ? (
+                    <MfaCodeStep mfa={mfa} code={mfaCode} setCode={setMfaCode} isLoading={isLoading} onSubmit={handleLogin} onCancel={cancelMfa} />
+                ) : (
                 <form className="space-y-[2.5vh]" onSubmit={handleLogin}>
                     <motion.div
                         initial={{ opacity: 0, y: 10 }}
@@ -170,6 +182,7 @@ const MobileLogin = () => {
                         )}
                     </motion.button>
                 </form>
+                )}
             </div>
 
             {/* Bottom Info */}
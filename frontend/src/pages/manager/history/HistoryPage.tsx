@@ -14,6 +14,7 @@ import PremiumTable, { ColumnDef } from '../../../components/common/PremiumTable
 import PremiumButton from '../../../components/common/PremiumButton';
 import ModernInput from '../../../components/common/ModernInput';
 import { parseSafeDate } from '../../../utils/timeUtils';
+import { withMediaAuth } from '../../../utils/imageUtils';
 
 // ==================== INTERFACES ====================
 interface DeletedProject {
@@ -724,7 +725,7 @@ const HistoryPage = () => {
  return (
  <div key={index} className="relative cursor-pointer" onClick={() => setPreviewImage(objectKey)}>
  <div className="group relative w-10 h-10 rounded-lg overflow-hidden border border-slate-200 shadow-sm hover:ring-2 hover:ring-indigo-500 transition-all">
- <img src={`/api/media/proxy?key=${encodeURIComponent(objectKey)}`} alt={`${label} ${index + 1}`} className="w-full h-full object-cover" loading="lazy" />
+ <img src={withMediaAuth(`/api/media/proxy?key=${encodeURIComponent(objectKey)}`)} alt={`${label} ${index + 1}`} className="w-full h-full object-cover" loading="lazy" />
  </div>
  </div>
  );
@@ -1094,7 +1095,7 @@ const HistoryPage = () => {
  {previewImage && (
  <div className="fixed inset-0 z-[99999] bg-black/95 backdrop-blur-md flex items-center justify-center p-4" onClick={() => setPreviewImage(null)}>
  <motion.img initial={{ scale: 0.9, opacity: 0 }} animate={{ scale: 1, opacity: 1 }} exit={{ scale: 0.9, opacity: 0 }}
- src={`/api/media/proxy?key=${encodeURIComponent(previewImage)}`}
+ src={withMediaAuth(`/api/media/proxy?key=${encodeURIComponent(previewImage)}`)}
  className="max-w-[95vw] max-h-[95vh] rounded-lg shadow-2xl object-contain select-none"
  onClick={e => e.stopPropagation()}
  />
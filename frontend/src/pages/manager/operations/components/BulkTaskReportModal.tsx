@@ -24,10 +24,13 @@ const BulkTaskReportModal: React.FC<Props> = ({ tasks, assignId, projectName, is
  const [pushSuccess, setPushSuccess] = useState(false);
  const [localConclusion, setLocalConclusion] = useState(conclusionText || '');
  const [reportId, setReportId] = useState<string | null>(null);
+ const [shareToken, setShareToken] = useState('');
  const [isCreating, setIsCreating] = useState(false);
 
  const isReject = type === 'reject';
- const reportLink = reportId ? `${window.location.origin}/share/generated-report/${reportId}` : '';
+ const reportLink = reportId && shareToken
+ ? `${window.location.origin}/share/generated-report/${reportId}?token=${encodeURIComponent(shareToken)}`
+ : '';
 
  const generateDatabaseReport = async () => {
  setIsCreating(true);
@@ -49,6 +52,12 @@ const BulkTaskReportModal: React.FC<Props> = ({ tasks, assignId, projectName, is
  });
 
  if (res.data && res.data.data && res.data.data.id) {
+ // Public pages only open with a signed share token
+ const share = await api.post('/share-links', {
+ resource_type: 'generic_report',
+ resource_id: res.data.data.id,
+ });
+ setShareToken(share.data.token);
  setReportId(res.data.data.id);
  } else {
  alert('Tạo báo cáo thất bại, không nhận được ID.');
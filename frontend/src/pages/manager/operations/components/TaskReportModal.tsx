@@ -6,6 +6,7 @@ import { format } from 'date-fns';
 import { TaskRow } from '../types';
 import { getImageUrl } from '../../../../utils/imageUtils';
 import { parseSafeDate } from '../../../../utils/timeUtils';
+import api from '../../../../services/api';
 
 
 interface TaskReportModalProps {
@@ -38,15 +39,27 @@ const TaskReportModal: React.FC<TaskReportModalProps> = ({
  noteMap = {},
 }) => {
  const [copied, setCopied] = useState(false);
- const handleCopyLink = () => {
+ const handleCopyLink = async () => {
  const base = window.location.origin;
  const queryParams = new URLSearchParams();
  if (task.assetId) queryParams.set('asset', task.assetId);
  if (task.subWorkId) queryParams.set('sub', task.subWorkId);
- 
- const queryString = queryParams.toString() ? `?${queryParams.toString()}` : '';
- const url = `${base}/share/report/${task.assignId}${queryString}`;
- 
+
+ try {
+ // Signed link scoped to this asset / sub-work only
+ const share = await api.post('/share-links', {
+ resource_type: 'assign_report',
+ resource_id: task.assignId,
+ filters: { asset: task.assetId || '', sub: task.subWorkId || '' },
+ });
+ queryParams.set('token', share.data.token);
+ } catch {
+ alert('Không tạo được link chia sẻ. Vui lòng thử lại.');
+ return;
+ }
+
+ const url = `${base}/share/report/${task.assignId}?${queryParams.toString()}`;
+
  navigator.clipboard.writeText(url).then(() => {
  setCopied(true);
  setTimeout(() => setCopied(false), 2500);
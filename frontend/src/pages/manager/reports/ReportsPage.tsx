@@ -2,6 +2,7 @@ import React, { useEffect, useState, useMemo } from 'react';
 import { createPortal } from 'react-dom';
 import api from '../../../services/api';
 import { parseSafeDate } from '../../../utils/timeUtils';
+import { withMediaAuth } from '../../../utils/imageUtils';
 import { addHours } from 'date-fns';
 import {
  Folder,
@@ -83,11 +84,11 @@ const getImageSrc = (path: string) => {
  const urlObj = new URL(path);
  let key = urlObj.pathname.replace(/^\//, ''); // remove leading /
  if (key.startsWith('dev/')) key = key.substring(4); // strip bucket
- return `/api/media/proxy?key=${encodeURIComponent(key)}`;
+ return withMediaAuth(`/api/media/proxy?key=${encodeURIComponent(key)}`);
  } catch { /* use as-is */ }
  }
  if (path.startsWith('http://') || path.startsWith('https://')) return path;
- return `/api/media/proxy?key=${encodeURIComponent(path)}`;
+ return withMediaAuth(`/api/media/proxy?key=${encodeURIComponent(path)}`);
 };
 
 // --- Helper: Parse JSON array safely ---
@@ -403,7 +404,7 @@ const ReportsPage = () => {
  } else {
  const prefix = [...currentPath, node.name].join('/') + '/';
  const link = document.createElement('a');
- link.href = `/api/media/download-zip?prefix=${encodeURIComponent(prefix)}`;
+ link.href = withMediaAuth(`/api/media/download-zip?prefix=${encodeURIComponent(prefix)}`);
  link.download = `${node.name}.zip`;
  document.body.appendChild(link);
  link.click();
@@ -416,7 +417,7 @@ const ReportsPage = () => {
  const prefix = currentPath.join('/') + '/';
  const name = currentPath[currentPath.length - 1];
  const link = document.createElement('a');
- link.href = `/api/media/download-zip?prefix=${encodeURIComponent(prefix)}`;
+ link.href = withMediaAuth(`/api/media/download-zip?prefix=${encodeURIComponent(prefix)}`);
  link.download = `${name}.zip`;
  document.body.appendChild(link);
  link.click();
@@ -104,6 +104,7 @@ const PublicBulkReportPage: React.FC = () => {
  const itemsParam = searchParams.get('items'); // Fallback for old URL
  const reportType = searchParams.get('type') || 'approve'; // Fallback
  const commentParam = searchParams.get('comment') || '';
+ const shareHeaders = { 'X-Share-Token': searchParams.get('token') || '' };
  
  const [dynamicIsReject, setDynamicIsReject] = useState(reportType === 'reject');
  const [conclusionText, setConclusionText] = useState(commentParam);
@@ -124,7 +125,8 @@ const PublicBulkReportPage: React.FC = () => {
  let rType = reportType;
 
  if (reportId) {
- const metaRes = await fetch(`${API_BASE}/api/public/generic-report/${reportId}`);
+ const metaRes = await fetch(`${API_BASE}/api/public/generic-report/${reportId}`, { headers: shareHeaders });
+ if (metaRes.status === 401) throw new Error('Link đã hết hạn hoặc đã bị thu hồi.');
  if (!metaRes.ok) throw new Error('Báo cáo không tồn tại hoặc đã bị xóa.');
  const metaBody = await metaRes.json();
  if (!metaBody.success) throw new Error('Báo cáo không tồn tại.');
@@ -152,7 +154,7 @@ const PublicBulkReportPage: React.FC = () => {
  let currentIsReject = rType === 'reject';
 
  // 1. Fetch Assign Data
- const res = await fetch(`${API_BASE}/api/public/report/${rAssignId}`);
+ const res = await fetch(`${API_BASE}/api/public/report/${rAssignId}`, { headers: shareHeaders });
  if (!res.ok) throw new Error('Không tìm thấy báo cáo phân công này.');
  const assignData = await res.json();
 
@@ -284,7 +286,7 @@ const PublicBulkReportPage: React.FC = () => {
  const params = new URLSearchParams({ assign_id: rAssignId || '' });
  datesArray.forEach(d => params.append('dates[]', d));
 
- const attRes = await fetch(`${API_BASE}/api/public/attendance-by-assign?${params.toString()}`);
+ const attRes = await fetch(`${API_BASE}/api/public/attendance-by-assign?${params.toString()}`, { headers: shareHeaders });
  if (attRes.ok) {
  const attData = await attRes.json();
  setAttendances(attData || []);
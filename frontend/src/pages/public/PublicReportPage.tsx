@@ -106,6 +106,7 @@ const PublicReportPage: React.FC = () => {
     const assetId = searchParams.get('asset');
     const isRejectReport = searchParams.get('type') === 'reject';
     const isSubmitReport = searchParams.get('type') === 'submit';
+    const shareToken = searchParams.get('token') || '';
 
     const [data, setData] = useState<any>(null);
     const [loading, setLoading] = useState(true);
@@ -114,15 +115,16 @@ const PublicReportPage: React.FC = () => {
 
     useEffect(() => {
         if (!assignId) { setError('Link không hợp lệ.'); setLoading(false); return; }
-        fetch(`${API_BASE}/api/public/report/${assignId}`)
+        fetch(`${API_BASE}/api/public/report/${assignId}`, { headers: { 'X-Share-Token': shareToken } })
             .then(r => {
+                if (r.status === 401) throw new Error('Link đã hết hạn hoặc đã bị thu hồi.');
                 if (!r.ok) throw new Error('Không tìm thấy báo cáo.');
                 return r.json();
             })
             .then(setData)
             .catch((e) => setError(e.message || 'Lỗi tải báo cáo.'))
             .finally(() => setLoading(false));
-    }, [assignId]);
+    }, [assignId, shareToken]);
 
     if (loading) return (
         <div className="min-h-screen flex items-center justify-center bg-slate-100">
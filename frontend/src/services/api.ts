@@ -25,14 +25,51 @@ api.interceptors.request.use(
  }
 );
 
-// Handle 401 errors (token expired or invalid)
+const clearSession = () => {
+ sessionStorage.removeItem('token');
+ sessionStorage.removeItem('refresh_token');
+ sessionStorage.removeItem('user');
+};
+
+// Single in-flight refresh shared by all requests that hit 401 at the same time
+let refreshPromise: Promise<string> | null = null;
+
+export const refreshAccessToken = (): Promise<string> => {
+ if (!refreshPromise) {
+ const refreshToken = sessionStorage.getItem('refresh_token');
+ refreshPromise = (refreshToken
+ ? axios.post(`${api.defaults.baseURL}/auth/refresh`, { refresh_token: refreshToken }).then((res) => {
+ sessionStorage.setItem('token', res.data.token);
+ sessionStorage.setItem('refresh_token', res.data.refresh_token);
+ return res.data.token as string;
+ })
+ : Promise.reject(new Error('no refresh token'))
+ ).finally(() => {
+ refreshPromise = null;
+ });
+ }
+ return refreshPromise;
+};
+
+// Handle 401 errors: refresh the access token once, otherwise send back to login
 api.interceptors.response.use(
  (response) => response,
- (error) => {
+ async (error) => {
+ const original = error.config;
+ const url: string = original?.url || '';
+ if (error.response?.status === 401 && original && !original._retry && !url.includes('/auth/')) {
+ original._retry = true;
+ try {
+ const token = await refreshAccessToken();
+ original.headers.Authorization = `Bearer ${token}`;
+ return api(original);
+ } catch {
+ // fall through to logout
+ }
+ }
  if (error.response?.status === 401) {
  // Clear invalid token
- sessionStorage.removeItem('token');
- sessionStorage.removeItem('user');
+ clearSession();
  // Only redirect if NOT on the login page AND NOT on a public share page
  const path = window.location.pathname;
  if (!path.includes('/login') && !path.includes('/share/')) {
@@ -1,17 +1,54 @@
-import api from './api';
+import api, { refreshAccessToken } from './api';
 import { syncQueue } from './offline';
 
+// Stable per-browser id so admins can revoke this device's sessions
+const getDeviceId = () => {
+ let id = localStorage.getItem('device_id');
+ if (!id) {
+ id = crypto.randomUUID();
+ localStorage.setItem('device_id', id);
+ }
+ return id;
+};
+
 export const authService = {
  login: async (email: string, password: string) => {
  const response = await api.post('/auth/login', {
  email,
- password
+ password,
+ device_id: getDeviceId(),
+ device_name: navigator.platform || 'web'
  });
  // Clear the token-expired flag so the offline sync queue resumes automatically
  syncQueue.resetTokenExpired();
  return response.data;
  },
 
+ // Second login step: exchange the challenge and a TOTP/recovery code for tokens
+ verifyMfa: async (challenge: string, code: string) => {
+ const response = await api.post('/auth/2fa/verify', {
+ challenge,
+ code,
+ device_id: getDeviceId(),
+ device_name: navigator.platform || 'web'
+ });
+ syncQueue.resetTokenExpired();
+ return response.data;
+ },
+
+ // Start authenticator enrolment for a login challenge with enrollment_required
+ enrollMfa: async (challenge: string) => {
+ const response = await api.post('/auth/2fa/enroll', { challenge });
+ return response.data as { secret: string; otpauth_uri: string };
+ },
+
+ // Change own password, then refresh so the new access token drops the must-change flag
+ changePassword: async (userId: string, oldPassword: string, newPassword: string) => {
+ await api.put(`/users/${userId}/password`, { old_password: oldPassword, new_password: newPassword });
+ sessionStorage.removeItem('must_change_password');
+ await refreshAccessToken();
+ },
+
  logout: async () => {
  try {
  await api.post('/auth/logout');
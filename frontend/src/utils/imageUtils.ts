@@ -1,4 +1,19 @@
-export const getImageUrl = (item: string | Blob | undefined | null): string => {
+// Media routes need the share token (on /share pages) or the access token, and
+// <img> tags and download links cannot send headers, so it rides in the query string
+export const withMediaAuth = (url: string): string => {
+ if (!url.includes('/api/media/') || /[?&](token|access_token)=/.test(url)) return url;
+ const sep = url.includes('?') ? '&' : '?';
+ const shareToken = window.location.pathname.startsWith('/share/')
+ ? new URLSearchParams(window.location.search).get('token')
+ : null;
+ if (shareToken) return `${url}${sep}token=${encodeURIComponent(shareToken)}`;
+ const accessToken = sessionStorage.getItem('token');
+ return accessToken ? `${url}${sep}access_token=${encodeURIComponent(accessToken)}` : url;
+};
+
+export const getImageUrl = (item: string | Blob | undefined | null): string => withMediaAuth(resolveImageUrl(item));
+
+const resolveImageUrl = (item: string | Blob | undefined | null): string => {
  if (!item) return '';
  if (item instanceof Blob) {
  const url = URL.createObjectURL(item);